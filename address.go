@@ -0,0 +1,118 @@
+package registry_center
+
+import (
+	"strings"
+)
+
+// Endpoint 结构化的服务实例地址
+type Endpoint struct {
+	Scheme string `json:"scheme"`         // 协议，如 http、grpc，地址未带协议时为空
+	Addr   string `json:"addr"`           // host:port
+	Secure bool   `json:"secure"`         // 是否为 TLS 加密地址
+	Name   string `json:"name,omitempty"` // 端口名，如 http、admin，注册时由 port_names 指定
+}
+
+// 默认视为 TLS 加密的协议
+var secureSchemes = map[string]bool{
+	"https": true,
+	"grpcs": true,
+	"wss":   true,
+	"tls":   true,
+}
+
+// String 还原为 scheme://addr 形式
+func (e Endpoint) String() string {
+	if e.Scheme == "" {
+		return e.Addr
+	}
+	return e.Scheme + "://" + e.Addr
+}
+
+// ParseEndpoint 解析 scheme://host:port 形式的地址，路径部分会被丢弃
+func ParseEndpoint(addr string) Endpoint {
+	var ep Endpoint
+	if i := strings.Index(addr, "://"); i >= 0 {
+		ep.Scheme = strings.ToLower(addr[:i])
+		ep.Secure = secureSchemes[ep.Scheme]
+		addr = addr[i+3:]
+	}
+	if i := strings.IndexAny(addr, "/?#"); i >= 0 {
+		addr = addr[:i]
+	}
+	ep.Addr = addr
+	return ep
+}
+
+// parseEndpoints 由注册地址生成结构化地址，secureAddrs 中的地址无论协议都标记为加密地址，
+// 返回的 Addrs 与 Endpoints 一一对应
+func parseEndpoints(addrs, secureAddrs []string) ([]string, []Endpoint) {
+	all := make([]string, 0, len(addrs)+len(secureAddrs))
+	eps := make([]Endpoint, 0, len(addrs)+len(secureAddrs))
+	for _, addr := range addrs {
+		all = append(all, addr)
+		eps = append(eps, ParseEndpoint(addr))
+	}
+	for _, addr := range secureAddrs {
+		ep := ParseEndpoint(addr)
+		ep.Secure = true
+		all = append(all, addr)
+		eps = append(eps, ep)
+	}
+	return all, eps
+}
+
+// filterEndpoints 只保留满足条件的地址，没有可用地址的实例被剔除
+func filterEndpoints(instances []*Instance, keep func(ep Endpoint) bool) []*Instance {
+	rs := make([]*Instance, 0, len(instances))
+	for _, in := range instances {
+		var addrs []string
+		var eps []Endpoint
+		parallel := len(in.Addrs) == len(in.Endpoints)
+		for i, ep := range in.Endpoints {
+			if keep(ep) {
+				eps = append(eps, ep)
+				if parallel {
+					addrs = append(addrs, in.Addrs[i])
+				}
+			}
+		}
+		if !parallel {
+			// 地址与结构化地址没有一一对应时按地址重新解析，加密标记以结构化地址为准
+			known := make(map[string]Endpoint, len(in.Endpoints))
+			for _, ep := range in.Endpoints {
+				known[ep.String()] = ep
+			}
+			for _, addr := range in.Addrs {
+				ep := ParseEndpoint(addr)
+				if k, ok := known[ep.String()]; ok {
+					ep.Secure = k.Secure
+				}
+				if keep(ep) {
+					addrs = append(addrs, addr)
+				}
+			}
+		}
+		if len(eps) == 0 && len(addrs) == 0 {
+			continue
+		}
+		in.Addrs = addrs
+		in.Endpoints = eps
+		rs = append(rs, in)
+	}
+	return rs
+}
+
+// filterScheme 只保留指定协议的地址
+func filterScheme(instances []*Instance, scheme string) []*Instance {
+	scheme = strings.ToLower(scheme)
+	return filterEndpoints(instances, func(ep Endpoint) bool {
+		return ep.Scheme == scheme
+	})
+}
+
+// filterSecure 只保留 TLS 加密地址
+func filterSecure(instances []*Instance) []*Instance {
+	return filterEndpoints(instances, func(ep Endpoint) bool {
+		return ep.Secure
+	})
+}
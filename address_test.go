@@ -0,0 +1,63 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchScheme(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.addr", Hostname: "mixed", Status: StatusUP,
+		Addrs: []string{"http://10.0.0.1:8080/api", "grpc://10.0.0.1:9000"}}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.addr", Hostname: "http-only", Status: StatusUP,
+		Addrs: []string{"http://10.0.0.2:8080"}}), time.Now().UnixNano())
+
+	data, err := r.FetchWithOptions("test", "com.xx.addr", StatusUP, 0, FetchOptions{Scheme: "grpc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Instances) != 1 {
+		t.Fatalf("expect 1 grpc instance, got %d", len(data.Instances))
+	}
+	in := data.Instances[0]
+	if len(in.Addrs) != 1 || in.Addrs[0] != "grpc://10.0.0.1:9000" {
+		t.Fatalf("unexpected addrs: %v", in.Addrs)
+	}
+	if len(in.Endpoints) != 1 || in.Endpoints[0] != (Endpoint{Scheme: "grpc", Addr: "10.0.0.1:9000"}) {
+		t.Fatalf("unexpected endpoints: %v", in.Endpoints)
+	}
+}
+
+func TestFetchSecureOnly(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.tls", Hostname: "both", Status: StatusUP,
+		Addrs: []string{"grpc://10.0.0.1:9000"}, SecureAddrs: []string{"grpc://10.0.0.1:9443"}}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.tls", Hostname: "plain", Status: StatusUP,
+		Addrs: []string{"http://10.0.0.2:8080"}}), time.Now().UnixNano())
+
+	data, err := r.FetchWithOptions("test", "com.xx.tls", StatusUP, 0, FetchOptions{SecureOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Instances) != 1 || len(data.Instances[0].Addrs) != 1 || data.Instances[0].Addrs[0] != "grpc://10.0.0.1:9443" {
+		t.Fatalf("unexpected secure instances: %v", data.Instances)
+	}
+	if !data.Instances[0].Endpoints[0].Secure {
+		t.Fatal("endpoint should be marked secure")
+	}
+}
+
+func TestFilterSecureKeepsFlagWithoutParallelAddrs(t *testing.T) {
+	// 地址与结构化地址数量不一致时，加密标记仍以结构化地址为准
+	in := &Instance{Hostname: "h1",
+		Addrs:     []string{"grpc://10.0.0.1:9443", "http://10.0.0.1:8080", "10.0.0.1:7000"},
+		Endpoints: []Endpoint{{Scheme: "grpc", Addr: "10.0.0.1:9443", Secure: true}, {Scheme: "http", Addr: "10.0.0.1:8080"}},
+	}
+	rs := filterSecure([]*Instance{in})
+	if len(rs) != 1 || len(rs[0].Addrs) != 1 || rs[0].Addrs[0] != "grpc://10.0.0.1:9443" {
+		t.Fatalf("unexpected secure addrs: %v", rs)
+	}
+	if len(rs[0].Endpoints) != 1 || !rs[0].Endpoints[0].Secure {
+		t.Fatalf("endpoint should stay secure: %v", rs[0].Endpoints)
+	}
+}
@@ -0,0 +1,163 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded 服务端过载，请求被准入控制拒绝
+var ErrOverloaded = errors.New("registry overloaded")
+
+// Priority 请求优先级，数值越小优先级越高
+type Priority int
+
+const (
+	PriorityRenew Priority = iota
+	PriorityRegister
+	PriorityCancel
+	PriorityFetch
+	PriorityAdmin
+	numPriorities
+)
+
+var priorityNames = [numPriorities]string{"renew", "register", "cancel", "fetch", "admin"}
+
+func (p Priority) String() string {
+	if p < 0 || p >= numPriorities {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+// AdmissionConfig 准入控制配置：同时处理的请求数达到 MaxConcurrent 后，
+// 请求按优先级排队，某一类的排队数达到上限或等待超过 MaxWait 时直接拒绝
+type AdmissionConfig struct {
+	MaxConcurrent int              // 最大并发处理数
+	MaxQueueDepth map[Priority]int // 各优先级的最大排队数，未配置的优先级不排队
+	MaxWait       time.Duration    // 排队最长等待时间，0 表示不限
+}
+
+// admission 按优先级排队的准入控制，释放的处理槽位总是优先交给高优先级的排队请求
+type admission struct {
+	cfg     AdmissionConfig
+	lock    sync.Mutex
+	running int
+	queues  [numPriorities][]chan struct{}
+}
+
+func newAdmission(cfg AdmissionConfig) *admission {
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 1
+	}
+	return &admission{cfg: cfg}
+}
+
+// acquire 获取处理槽位，成功后必须调用返回的 release。排队期间 ctx 结束时放弃排队
+func (a *admission) acquire(ctx context.Context, p Priority) (func(), error) {
+	a.lock.Lock()
+	if a.running < a.cfg.MaxConcurrent && !a.hasWaiters(p) {
+		a.running++
+		a.lock.Unlock()
+		return a.release, nil
+	}
+	if len(a.queues[p]) >= a.cfg.MaxQueueDepth[p] {
+		a.lock.Unlock()
+		return nil, a.reject()
+	}
+	ch := make(chan struct{})
+	a.queues[p] = append(a.queues[p], ch)
+	a.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if a.cfg.MaxWait > 0 {
+		timer := time.NewTimer(a.cfg.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-ch:
+		return a.release, nil
+	case <-timeout:
+		err = a.reject()
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for i, c := range a.queues[p] {
+		if c == ch {
+			a.queues[p] = append(a.queues[p][:i], a.queues[p][i+1:]...)
+			return nil, err
+		}
+	}
+	// 超时的同时已被分配到槽位
+	return a.release, nil
+}
+
+// hasWaiters 是否有优先级不低于 p 的请求在排队
+func (a *admission) hasWaiters(p Priority) bool {
+	for i := Priority(0); i <= p; i++ {
+		if len(a.queues[i]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// release 释放槽位，优先交给排队中优先级最高的请求
+func (a *admission) release() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for i := range a.queues {
+		if len(a.queues[i]) > 0 {
+			ch := a.queues[i][0]
+			a.queues[i] = a.queues[i][1:]
+			close(ch)
+			return
+		}
+	}
+	a.running--
+}
+
+// depth 当前排队的请求数
+func (a *admission) depth() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var n int
+	for _, q := range a.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (a *admission) reject() error {
+	retryAfter := a.cfg.MaxWait
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	return &BackpressureError{Err: ErrOverloaded, RetryAfter: retryAfter}
+}
+
+// Admit 以优先级 p 申请处理槽位，未开启准入控制时直接通过。
+// 供 API 层包装管理类等请求，成功后必须调用返回的 release
+func (r *Registry) Admit(p Priority) (release func(), err error) {
+	return r.AdmitContext(context.Background(), p)
+}
+
+// AdmitContext 同 Admit，ctx 结束时放弃排队并返回 StageTimeoutError
+func (r *Registry) AdmitContext(ctx context.Context, p Priority) (release func(), err error) {
+	if err := r.checkDeadline(ctx, StageAdmission); err != nil {
+		return nil, err
+	}
+	if r.admission == nil {
+		return func() {}, nil
+	}
+	release, err = r.admission.acquire(ctx, p)
+	if err != nil && ctx.Err() != nil {
+		return nil, r.stageTimeout(StageAdmission, err)
+	}
+	return release, err
+}
@@ -0,0 +1,45 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdmissionPriority(t *testing.T) {
+	a := newAdmission(AdmissionConfig{
+		MaxConcurrent: 1,
+		MaxQueueDepth: map[Priority]int{PriorityRenew: 1, PriorityFetch: 1},
+	})
+	release, err := a.acquire(context.Background(), PriorityFetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := make(chan Priority, 2)
+	for _, p := range []Priority{PriorityFetch, PriorityRenew} {
+		go func(p Priority) {
+			rel, err := a.acquire(context.Background(), p)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- p
+			rel()
+		}(p)
+		time.Sleep(10 * time.Millisecond)
+	}
+	// fetch 队列已满，直接拒绝
+	if _, err := a.acquire(context.Background(), PriorityFetch); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expect overloaded, got %v", err)
+	}
+	// admin 不允许排队
+	if _, err := a.acquire(context.Background(), PriorityAdmin); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expect overloaded, got %v", err)
+	}
+	release()
+	if first := <-order; first != PriorityRenew {
+		t.Fatalf("renew should be admitted before fetch, got %s", first)
+	}
+	<-order
+}
@@ -0,0 +1,38 @@
+package registry_center
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// OrderByAffinity 按会合哈希（rendezvous hashing）对实例排序，同一 key 总是得到相同的顺序，
+// 调用方优先选第一个实例即可实现粘性路由。实例增减只影响原本排在首位的 key，权重越大越容易排在前面，
+// 权重为 0 的实例排在最后。客户端负载均衡也可以直接使用
+func OrderByAffinity(instances []*Instance, key string) []*Instance {
+	scores := make(map[*Instance]float64, len(instances))
+	for _, in := range instances {
+		scores[in] = affinityScore(key, in)
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		si, sj := scores[instances[i]], scores[instances[j]]
+		if si != sj {
+			return si > sj
+		}
+		return instances[i].Hostname < instances[j].Hostname
+	})
+	return instances
+}
+
+// affinityScore 加权会合哈希得分：-weight/ln(u)，u 为 key 与实例的哈希映射到 (0,1) 的值
+func affinityScore(key string, in *Instance) float64 {
+	if in.Weight == 0 {
+		return math.Inf(-1)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(in.Hostname))
+	u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+	return -float64(in.Weight) / math.Log(u)
+}
@@ -0,0 +1,43 @@
+package registry_center
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOrderByAffinity(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	for i := 0; i < 5; i++ {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: fmt.Sprintf("h%d", i), Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano())
+	}
+	first := func(key string) string {
+		c, err := r.FetchWithOptions("test", "com.xx.app", StatusUP, 0, FetchOptions{AffinityKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.Instances[0].Hostname
+	}
+	picks := make(map[string]string)
+	hosts := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		picks[key] = first(key)
+		hosts[picks[key]] = true
+		if first(key) != picks[key] {
+			t.Fatal("same key should pick the same instance")
+		}
+	}
+	if len(hosts) < 3 {
+		t.Fatalf("keys should spread across instances, got %v", hosts)
+	}
+
+	// 下线一个实例只影响原本选中它的 key
+	r.Cancel("test", "com.xx.app", "h0", time.Now().UnixNano())
+	for key, host := range picks {
+		if host != "h0" && first(key) != host {
+			t.Fatalf("key %s moved from %s after unrelated instance left", key, host)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package registry_center
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内置告警
+const (
+	AlertSelfPreservation = "self_preservation" // 过期实例超过剔除上限，进入自我保护
+	AlertMassEviction     = "mass_eviction"     // 一轮剔除的实例数超过阈值
+	AlertPeerUnreachable  = "peer_unreachable"  // 复制目标不可达
+	AlertStorageFailure   = "storage_failure"   // 状态文件或外部存储写入失败
+	AlertAddrConflict     = "addr_conflict"     // 注册的地址已被其他应用占用
+	AlertQuotaWarning     = "quota_warning"     // 应用的实例数或 Fetch 限流用量达到软阈值
+	AlertZoneOutage       = "zone_outage"       // 应用在某个可用区的实例全部不可用
+)
+
+// Alert 一条告警
+type Alert struct {
+	Name      string            `json:"name"`
+	Summary   string            `json:"summary"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+}
+
+// AlertSink 告警发送目标
+type AlertSink interface {
+	Send(ctx context.Context, a Alert) error
+}
+
+// AlertConfig 告警配置
+type AlertConfig struct {
+	Sinks        []AlertSink
+	MinInterval  time.Duration // 同名同标签的告警在该时长内只发送一次，默认 5 分钟
+	MassEviction int           // 一轮剔除的实例数达到该值时告警，0 表示不告警
+}
+
+// alerter 告警去重并异步发送
+type alerter struct {
+	cfg  AlertConfig
+	lock sync.Mutex
+	last map[string]int64 // key: 告警名及标签，value: 上次发送时间
+}
+
+func newAlerter(cfg AlertConfig) *alerter {
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = 5 * time.Minute
+	}
+	return &alerter{cfg: cfg, last: make(map[string]int64)}
+}
+
+func alertKey(a Alert) string {
+	keys := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(a.Name)
+	for _, k := range keys {
+		b.WriteString("," + k + "=" + a.Labels[k])
+	}
+	return b.String()
+}
+
+// raise 发送告警，MinInterval 内重复的告警被丢弃
+func (al *alerter) raise(a Alert) {
+	a.Timestamp = time.Now().UnixNano()
+	key := alertKey(a)
+	al.lock.Lock()
+	if last, ok := al.last[key]; ok && a.Timestamp-last < int64(al.cfg.MinInterval) {
+		al.lock.Unlock()
+		return
+	}
+	al.last[key] = a.Timestamp
+	al.lock.Unlock()
+	for _, sink := range al.cfg.Sinks {
+		go func(sink AlertSink) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := sink.Send(ctx, a); err != nil {
+				log.Printf("send alert %s failed: %v", a.Name, err)
+			}
+		}(sink)
+	}
+}
+
+// raiseAlert 未配置告警时不做任何事
+func (r *Registry) raiseAlert(a Alert) {
+	if r.alerter != nil {
+		r.alerter.raise(a)
+	}
+}
+
+// WebhookSink 以 JSON 格式把 Alert POST 到 URL
+type WebhookSink struct {
+	URL    string
+	Client *http.Client // 为空时使用 http.DefaultClient
+}
+
+func (s *WebhookSink) Send(ctx context.Context, a Alert) error {
+	return postJSON(ctx, s.Client, s.URL, a)
+}
+
+// SlackSink 发送到 Slack 兼容的 incoming webhook
+type SlackSink struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+func (s *SlackSink) Send(ctx context.Context, a Alert) error {
+	text := fmt.Sprintf(":rotating_light: *%s* %s", a.Name, a.Summary)
+	keys := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		text += fmt.Sprintf("\n• %s: %s", k, a.Labels[k])
+	}
+	return postJSON(ctx, s.Client, s.WebhookURL, map[string]string{"text": text})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
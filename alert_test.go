@@ -0,0 +1,87 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type chanSink chan Alert
+
+func (c chanSink) Send(ctx context.Context, a Alert) error {
+	c <- a
+	return nil
+}
+
+func TestAlertDedup(t *testing.T) {
+	sink := make(chanSink, 10)
+	r := NewRegistry(WithAlerts(AlertConfig{Sinks: []AlertSink{sink}, MinInterval: time.Hour}))
+	r.ObserveReplication("node-2", time.Millisecond, 10, errors.New("refused"))
+	r.ObserveReplication("node-2", time.Millisecond, 10, errors.New("refused"))
+	r.ObserveReplication("node-3", time.Millisecond, 10, errors.New("refused"))
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case a := <-sink:
+			if a.Name != AlertPeerUnreachable {
+				t.Fatalf("unexpected alert %+v", a)
+			}
+			got[a.Labels["target"]] = true
+		case <-time.After(time.Second):
+			t.Fatal("alert not sent")
+		}
+	}
+	select {
+	case a := <-sink:
+		t.Fatalf("duplicate alert should be suppressed: %+v", a)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !got["node-2"] || !got["node-3"] {
+		t.Fatalf("expect alerts for both targets, got %v", got)
+	}
+}
+
+func TestSelfPreservationAlert(t *testing.T) {
+	sink := make(chanSink, 10)
+	r := NewRegistry(WithAlerts(AlertConfig{Sinks: []AlertSink{sink}, MassEviction: 3}))
+	for i := 0; i < 20; i++ {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: string(rune('a' + i)), Status: StatusUP}), time.Now().UnixNano())
+	}
+	app, _ := r.getApplication("com.xx.app", "test")
+	for _, in := range app.instances {
+		in.RenewTimestamp = 0
+	}
+	r.evict()
+	names := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case a := <-sink:
+			names[a.Name] = true
+		case <-time.After(time.Second):
+			t.Fatal("alert not sent")
+		}
+	}
+	if !names[AlertSelfPreservation] || !names[AlertMassEviction] {
+		t.Fatalf("unexpected alerts %v", names)
+	}
+}
+
+func TestSlackSink(t *testing.T) {
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+	sink := &SlackSink{WebhookURL: srv.URL}
+	if err := sink.Send(context.Background(), Alert{Name: AlertStorageFailure, Summary: "disk full", Labels: map[string]string{"path": "/data"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body["text"], "storage_failure") || !strings.Contains(body["text"], "path: /data") {
+		t.Fatalf("unexpected slack payload %v", body)
+	}
+}
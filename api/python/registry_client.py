@@ -0,0 +1,192 @@
+"""注册中心的 Python 参考客户端，只依赖标准库。
+
+行为与 Go 客户端一致：定期续约，实例不存在时重新注册，按 revision 增量拉取，
+按 error.retryable 与 Retry-After 重试并在节点间切换。报文结构见 api/openapi.json。
+"""
+
+import json
+import threading
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+
+VERSION = "registry-python/0.1.0"
+RENEW_INTERVAL = 30  # 秒，超过 90 秒未续约的实例会被剔除
+
+# 续约返回这些错误码时应重新注册
+REREGISTER_CODES = ("INSTANCE_NOT_FOUND", "APP_NOT_FOUND")
+
+
+class RegistryError(Exception):
+    """注册中心返回的错误，code 为机器可读的错误码"""
+
+    def __init__(self, status, code, message, retryable=False, retry_after=0):
+        super().__init__("registry: %d %s %s" % (status, code, message))
+        self.status = status
+        self.code = code
+        self.retryable = retryable
+        self.retry_after = retry_after
+
+
+class NotModified(Exception):
+    """数据没有变化"""
+
+
+class Client:
+    def __init__(self, nodes, timeout=10):
+        if not nodes:
+            raise ValueError("no registry nodes")
+        self.nodes = [n.rstrip("/") for n in nodes]
+        self.timeout = timeout
+        self._next = 0
+
+    def _do(self, method, path, params):
+        query = urllib.parse.urlencode(params, doseq=True)
+        err = None
+        for i in range(len(self.nodes)):
+            node = self.nodes[(self._next + i) % len(self.nodes)]
+            url, data = node + path, None
+            if method == "GET":
+                url += "?" + query
+            else:
+                data = query.encode()
+            req = urllib.request.Request(url, data=data, method=method)
+            req.add_header("X-Registry-Client", VERSION)
+            if data is not None:
+                req.add_header("Content-Type", "application/x-www-form-urlencoded")
+            try:
+                with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                    self._next = (self._next + i + 1) % len(self.nodes)
+                    return json.load(resp).get("data")
+            except urllib.error.HTTPError as e:
+                if e.code == 304:
+                    raise NotModified()
+                err = _error(e)
+                if e.code < 500:
+                    raise err
+            except OSError as e:
+                err = e
+        raise err
+
+    def register(self, env, appid, hostname, addrs, **kwargs):
+        params = dict(kwargs, env=env, appid=appid, hostname=hostname, addrs=addrs)
+        return self._do("POST", "/api/register", params)
+
+    def renew(self, env, appid, hostname, secret=None):
+        params = {"env": env, "appid": appid, "hostname": hostname}
+        if secret:
+            params["secret"] = secret
+        return self._do("POST", "/api/renew", params)
+
+    def cancel(self, env, appid, hostname):
+        return self._do("POST", "/api/cancel", {"env": env, "appid": appid, "hostname": hostname})
+
+    def fetch(self, env, appid, latest_timestamp=0):
+        """返回 FetchData，数据没有变化时抛出 NotModified"""
+        params = {"env": env, "appid": appid}
+        if latest_timestamp:
+            params["latest_timestamp"] = latest_timestamp
+        return self._do("GET", "/api/fetch", params)
+
+    def fetch_delta(self, env, appid, revision=0):
+        return self._do("GET", "/api/fetch/delta", {"env": env, "appid": appid, "revision": revision})
+
+
+def _error(e):
+    try:
+        body = json.load(e)
+    except ValueError:
+        body = {}
+    info = body.get("error") or {}
+    retry_after = int(e.headers.get("Retry-After") or 0)
+    return RegistryError(e.code, info.get("code", ""), body.get("message", ""), info.get("retryable", False), retry_after)
+
+
+class Registration:
+    """注册实例并在后台续约，实例被剔除后自动重新注册"""
+
+    def __init__(self, client, env, appid, hostname, addrs, **kwargs):
+        self.client = client
+        self.args = (env, appid, hostname, addrs)
+        self.kwargs = kwargs
+        self.secret = None
+        self._stop = threading.Event()
+
+    def _register(self):
+        reply = self.client.register(*self.args, **self.kwargs)
+        self.secret = ((reply or {}).get("credential") or {}).get("secret")
+
+    def start(self):
+        self._register()
+        threading.Thread(target=self._run, daemon=True).start()
+
+    def _run(self):
+        env, appid, hostname, _ = self.args
+        wait = RENEW_INTERVAL
+        while not self._stop.wait(wait):
+            wait = RENEW_INTERVAL
+            try:
+                reply = self.client.renew(env, appid, hostname, self.secret)
+                cred = (reply or {}).get("credential")
+                if cred:
+                    self.secret = cred.get("secret")
+            except RegistryError as e:
+                if e.code in REREGISTER_CODES:
+                    self._try(self._register)
+                elif e.retry_after:
+                    wait = min(e.retry_after, RENEW_INTERVAL)
+            except OSError:
+                pass
+
+    def _try(self, fn):
+        try:
+            fn()
+        except (RegistryError, OSError):
+            pass
+
+    def close(self):
+        self._stop.set()
+        env, appid, hostname, _ = self.args
+        self._try(lambda: self.client.cancel(env, appid, hostname))
+
+
+class Watcher:
+    """按 revision 增量拉取应用实例，instances 为 hostname 到实例的映射"""
+
+    def __init__(self, client, env, appid):
+        self.client = client
+        self.env = env
+        self.appid = appid
+        self.revision = 0
+        self.instances = {}
+        self.poll_interval = RENEW_INTERVAL
+
+    def poll(self):
+        data = self.client.fetch_delta(self.env, self.appid, self.revision)
+        if data.get("full"):
+            self.instances = {}
+        for change in data.get("changes") or []:
+            if change["action"] == "delete":
+                self.instances.pop(change["hostname"], None)
+            else:
+                self.instances[change["hostname"]] = change["instance"]
+        self.revision = data["revision"]
+        hints = data.get("hints") or {}
+        if hints.get("poll_interval"):
+            self.poll_interval = hints["poll_interval"] / 1e9
+        return self.instances
+
+    def run(self, on_change, stop):
+        while not stop.is_set():
+            revision = self.revision
+            try:
+                instances = self.poll()
+                if self.revision != revision:
+                    on_change(instances)
+            except RegistryError as e:
+                if e.retry_after:
+                    stop.wait(e.retry_after)
+            except OSError:
+                pass
+            stop.wait(self.poll_interval)
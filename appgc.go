@@ -0,0 +1,64 @@
+package registry_center
+
+import (
+	"context"
+	"time"
+)
+
+// TaskAppGC 删除超过保留时长的空应用的后台任务
+const TaskAppGC = "app_gc"
+
+// EventAppRemoved 空应用超过保留时长被删除
+const EventAppRemoved EventType = "app_removed"
+
+// EmptyApp 没有实例但仍被保留的应用
+type EmptyApp struct {
+	Env             string `json:"env"`
+	AppId           string `json:"appId"`
+	LatestTimestamp int64  `json:"latest_timestamp"`
+	EmptySince      int64  `json:"empty_since"` // 最后一个实例下线的时间
+}
+
+// EmptyApps 返回没有实例但仍在保留期内的应用
+func (r *Registry) EmptyApps() []EmptyApp {
+	var rs []EmptyApp
+	for _, app := range r.getAllApplications() {
+		app.lock.RLock()
+		if len(app.instances) == 0 {
+			rs = append(rs, EmptyApp{Env: app.env, AppId: app.appId, LatestTimestamp: app.latestTimestamp, EmptySince: app.emptySince})
+		}
+		app.lock.RUnlock()
+	}
+	return rs
+}
+
+// collectEmptyApps 删除空置超过保留时长的应用
+func (r *Registry) collectEmptyApps(ctx context.Context) error {
+	deadline := time.Now().Add(-r.appRetention).UnixNano()
+	for _, app := range r.getAllApplications() {
+		if r.removeEmptyApp(app, deadline) {
+			key := getKey(app.appId, app.env)
+			if r.overload != nil {
+				r.overload.purge(key)
+			}
+			logf(ctx, "remove empty app %s", key)
+			r.events.append(Event{Type: EventAppRemoved, Env: app.env, AppId: app.appId, RequestID: RequestIDFromContext(ctx)})
+		}
+	}
+	return nil
+}
+
+// removeEmptyApp 应用仍没有实例且在 before 之前就已空置时删除，before 为 0 表示不检查空置时长。
+// 删除时持有应用锁，避免与并发的注册冲突
+func (r *Registry) removeEmptyApp(app *Application, before int64) bool {
+	key := getKey(app.appId, app.env)
+	r.lock.Lock()
+	app.lock.RLock()
+	removed := len(app.instances) == 0 && (before == 0 || app.emptySince < before) && r.apps[key] == app
+	if removed {
+		delete(r.apps, key)
+	}
+	app.lock.RUnlock()
+	r.lock.Unlock()
+	return removed
+}
@@ -0,0 +1,42 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAppRetention(t *testing.T) {
+	r := NewRegistry(WithAppRetention(time.Hour))
+	defer r.Close()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), 100)
+	r.Cancel("test", "com.xx.app", "a", 200)
+
+	empty := r.EmptyApps()
+	if len(empty) != 1 || empty[0].LatestTimestamp != 200 || empty[0].EmptySince == 0 {
+		t.Fatalf("empty app should be retained: %+v", empty)
+	}
+	// 保留期内不删除
+	r.collectEmptyApps(context.Background())
+	if len(r.EmptyApps()) != 1 {
+		t.Fatal("app within retention should not be removed")
+	}
+	// 重新注册后不再是空应用
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Addrs: []string{"http://127.0.0.1:81"}, Status: StatusUP}), 300)
+	if len(r.EmptyApps()) != 0 {
+		t.Fatal("app with instances should not be empty")
+	}
+	r.Cancel("test", "com.xx.app", "b", 400)
+
+	r.appRetention = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	seq := r.LastEventSeq()
+	r.collectEmptyApps(context.Background())
+	if len(r.EmptyApps()) != 0 {
+		t.Fatal("expired empty app should be removed")
+	}
+	events, _ := r.Replay(seq, 0)
+	if len(events) != 1 || events[0].Type != EventAppRemoved || events[0].AppId != "com.xx.app" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+}
@@ -0,0 +1,121 @@
+package registry_center
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// AppKey 应用的结构化标识，注册表内部以它为键，appId 或 env 中的字符不会造成不同应用相互混淆。
+// 以后增加租户、地域等维度时在这里加字段
+type AppKey struct {
+	Env   string `json:"env"`
+	AppId string `json:"appId"`
+}
+
+// NewAppKey 创建应用标识
+func NewAppKey(env, appid string) AppKey {
+	return AppKey{Env: env, AppId: appid}
+}
+
+// String 按 DefaultKeyEncoder 编码，用于日志
+func (k AppKey) String() string {
+	return DefaultKeyEncoder.Encode(k)
+}
+
+// Key 实例所属应用的标识
+func (in *Instance) Key() AppKey {
+	return AppKey{Env: in.Env, AppId: in.AppId}
+}
+
+// Key 复制操作所属应用的标识
+func (op ReplicationOp) Key() AppKey {
+	return AppKey{Env: op.Env, AppId: op.AppId}
+}
+
+// getKey 应用在注册表内部的键
+func getKey(appid, env string) AppKey {
+	return AppKey{Env: env, AppId: appid}
+}
+
+// instanceKey 实例在注册表内部的键
+type instanceKey struct {
+	app      AppKey
+	hostname string
+}
+
+func getInstanceKey(appid, env, hostname string) instanceKey {
+	return instanceKey{app: getKey(appid, env), hostname: hostname}
+}
+
+// ErrInvalidKey 字符串无法解码为 AppKey
+var ErrInvalidKey = errors.New("invalid app key")
+
+// KeyEncoder 把 AppKey 编码为字符串，用于状态文件、批量操作结果等需要字符串键的地方。
+// Decode(Encode(k)) 必须还原 k
+type KeyEncoder interface {
+	Encode(k AppKey) string
+	Decode(s string) (AppKey, error)
+}
+
+// DefaultKeyEncoder 默认的键编码，见 PathKeyEncoder
+var DefaultKeyEncoder KeyEncoder = PathKeyEncoder{}
+
+// PathKeyEncoder 编码为 env/appId，各部分按 URL 路径转义，不会产生歧义
+type PathKeyEncoder struct{}
+
+func (PathKeyEncoder) Encode(k AppKey) string {
+	return url.PathEscape(k.Env) + "/" + url.PathEscape(k.AppId)
+}
+
+func (PathKeyEncoder) Decode(s string) (AppKey, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return AppKey{}, ErrInvalidKey
+	}
+	env, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return AppKey{}, ErrInvalidKey
+	}
+	appid, err := url.PathUnescape(parts[1])
+	if err != nil {
+		return AppKey{}, ErrInvalidKey
+	}
+	return AppKey{Env: env, AppId: appid}, nil
+}
+
+// LegacyKeyEncoder 旧版本的 appId-env 编码，用于读取旧版本写出的状态文件。
+// appId 含 - 时有歧义，解码时按最后一个 - 拆分
+type LegacyKeyEncoder struct{}
+
+func (LegacyKeyEncoder) Encode(k AppKey) string {
+	return k.AppId + "-" + k.Env
+}
+
+func (LegacyKeyEncoder) Decode(s string) (AppKey, error) {
+	i := strings.LastIndex(s, "-")
+	if i <= 0 || i == len(s)-1 {
+		return AppKey{}, ErrInvalidKey
+	}
+	return AppKey{Env: s[i+1:], AppId: s[:i]}, nil
+}
+
+// encodeInstanceKey 实例的字符串键，应用部分由 enc 编码，之后是 /hostname
+func encodeInstanceKey(enc KeyEncoder, k instanceKey) string {
+	return enc.Encode(k.app) + "/" + k.hostname
+}
+
+// decodeInstanceKey 从左到右找到第一个能由 enc 解码的应用部分，hostname 中可以含 /
+func decodeInstanceKey(enc KeyEncoder, s string) (instanceKey, error) {
+	for i := strings.Index(s, "/"); i >= 0; {
+		if app, err := enc.Decode(s[:i]); err == nil {
+			return instanceKey{app: app, hostname: s[i+1:]}, nil
+		}
+		next := strings.Index(s[i+1:], "/")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return instanceKey{}, ErrInvalidKey
+}
@@ -0,0 +1,37 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppKey(t *testing.T) {
+	// 旧的 appId-env 拼接下这两个应用的键相同
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "b-c", AppId: "a", Hostname: "h1", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "c", AppId: "a-b", Hostname: "h2", Status: StatusUP}), time.Now().UnixNano())
+	for env, appid := range map[string]string{"b-c": "a", "c": "a-b"} {
+		if d, err := r.Fetch(env, appid, StatusUP, 0); err != nil || len(d.Instances) != 1 {
+			t.Fatalf("%s/%s should have its own instance: %+v %v", env, appid, d, err)
+		}
+	}
+
+	for _, k := range []AppKey{{Env: "test", AppId: "com.xx.app"}, {Env: "b-c", AppId: "a/b"}, {Env: "%", AppId: " "}} {
+		got, err := DefaultKeyEncoder.Decode(DefaultKeyEncoder.Encode(k))
+		if err != nil || got != k {
+			t.Fatalf("round trip of %+v failed: %+v %v", k, got, err)
+		}
+	}
+	if _, err := DefaultKeyEncoder.Decode("com.xx.app-test"); err == nil {
+		t.Fatal("legacy key should not decode with the default encoder")
+	}
+
+	// 状态文件中的实例键，hostname 可以含 /
+	ik := getInstanceKey("a/b", "test", "host/1")
+	if got, err := decodeInstanceKey(DefaultKeyEncoder, encodeInstanceKey(DefaultKeyEncoder, ik)); err != nil || got != ik {
+		t.Fatalf("unexpected instance key %+v %v", got, err)
+	}
+	if got, err := decodeInstanceKey(LegacyKeyEncoder{}, "com.xx.app-test/h1"); err != nil || got != getInstanceKey("com.xx.app", "test", "h1") {
+		t.Fatalf("unexpected legacy instance key %+v %v", got, err)
+	}
+}
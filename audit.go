@@ -0,0 +1,78 @@
+package registry_center
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// 审计事件
+const (
+	AuditReplicationRejected = "replication_rejected" // 拒绝了未知或签名错误的对端复制
+	AuditFetchDenied         = "fetch_denied"         // 调用方获取或订阅了对其不可见的应用
+	AuditTokenIssued         = "token_issued"         // 签发了只读令牌
+	AuditTokenRevoked        = "token_revoked"        // 吊销了只读令牌
+)
+
+// DefaultAuditCapacity 内存中保留的审计记录数
+const DefaultAuditCapacity = 1000
+
+// AuditEntry 一条审计记录
+type AuditEntry struct {
+	Timestamp int64  `json:"timestamp"`
+	Action    string `json:"action"`
+	Peer      string `json:"peer,omitempty"`      // 请求声明的对端节点
+	Caller    string `json:"caller,omitempty"`    // 经过认证的调用方身份
+	ClientIP  string `json:"client_ip,omitempty"` // 请求的来源地址
+	Env       string `json:"env,omitempty"`
+	AppId     string `json:"appId,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Reason    string `json:"reason"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// auditLog 最近的审计记录，超出容量时丢弃最早的
+type auditLog struct {
+	lock    sync.Mutex
+	entries []AuditEntry
+	next    int
+	full    bool
+}
+
+func newAuditLog(capacity int) *auditLog {
+	return &auditLog{entries: make([]AuditEntry, capacity)}
+}
+
+// audit 记录审计事件并写日志
+func (r *Registry) audit(ctx context.Context, e AuditEntry) {
+	e.Timestamp = time.Now().UnixNano()
+	if e.RequestID == "" {
+		e.RequestID = RequestIDFromContext(ctx)
+	}
+	if e.ClientIP == "" {
+		e.ClientIP = r.requestSource(ctx).ClientIP
+	}
+	log.Printf("audit %s peer=%q caller=%q client=%s %s/%s: %s", e.Action, e.Peer, e.Caller, e.ClientIP, getKey(e.AppId, e.Env), e.Hostname, e.Reason)
+	a := r.auditLog
+	a.lock.Lock()
+	a.entries[a.next] = e
+	a.next = (a.next + 1) % len(a.entries)
+	if a.next == 0 {
+		a.full = true
+	}
+	a.lock.Unlock()
+}
+
+// AuditLog 返回最近的审计记录，按时间从早到晚排列
+func (r *Registry) AuditLog() []AuditEntry {
+	a := r.auditLog
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if !a.full {
+		return append([]AuditEntry(nil), a.entries[:a.next]...)
+	}
+	rs := make([]AuditEntry, 0, len(a.entries))
+	rs = append(rs, a.entries[a.next:]...)
+	return append(rs, a.entries[:a.next]...)
+}
@@ -0,0 +1,53 @@
+package registry_center
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BackpressureError 服务端限流或过载时返回的结构化背压响应，
+// 客户端应至少等待 RetryAfter 后重试，并把轮询/心跳间隔放宽到 PollInterval
+type BackpressureError struct {
+	Err          error         // 背压原因，如 ErrRateLimited
+	RetryAfter   time.Duration // 建议的重试等待时长
+	PollInterval time.Duration // 建议的轮询/心跳间隔，0 表示不调整
+}
+
+func (e *BackpressureError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *BackpressureError) Unwrap() error {
+	return e.Err
+}
+
+// AsBackpressure 判断 err 是否为背压响应
+func AsBackpressure(err error) (*BackpressureError, bool) {
+	var bp *BackpressureError
+	if errors.As(err, &bp) {
+		return bp, true
+	}
+	return nil, false
+}
+
+// StretchInterval 按背压响应调整客户端的轮询/心跳间隔：取当前间隔与服务端建议的较大值，
+// 并限制在 [min, max] 之间，max 应小于租约过期时长以免心跳被拉长导致实例被剔除
+func StretchInterval(current, min, max time.Duration, err error) time.Duration {
+	interval := current
+	if bp, ok := AsBackpressure(err); ok {
+		if bp.RetryAfter > interval {
+			interval = bp.RetryAfter
+		}
+		if bp.PollInterval > interval {
+			interval = bp.PollInterval
+		}
+	}
+	if interval < min {
+		interval = min
+	}
+	if max > 0 && interval > max {
+		interval = max
+	}
+	return interval
+}
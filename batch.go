@@ -0,0 +1,44 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FetchBatch 一次获取多个应用的实例，latestTimestamps 为调用方持有的各应用 LatestTimestamp，
+// 只返回数据有变化的应用，没有变化、不存在、不可见或没有可用实例的应用不出现在结果中
+func (r *Registry) FetchBatch(env string, appIds []string, latestTimestamps map[string]int64) (map[string]*FetchData, error) {
+	return r.FetchBatchContext(context.Background(), env, appIds, latestTimestamps, StatusUP, FetchOptions{})
+}
+
+// FetchBatchContext 同 FetchBatch，整批请求只排队一次，限流按应用分别计算
+func (r *Registry) FetchBatchContext(ctx context.Context, env string, appIds []string, latestTimestamps map[string]int64, status uint32, opts FetchOptions) (rs map[string]*FetchData, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	rs = make(map[string]*FetchData)
+	for _, appid := range appIds {
+		if err := r.checkDeadline(ctx, StageCache); err != nil {
+			return nil, err
+		}
+		if !r.Visible(ctx, env, appid) {
+			continue
+		}
+		if err := r.allowFetch(ctx, env, appid, opts.Consumer); err != nil {
+			return nil, err
+		}
+		c, err := r.fetch(env, appid, status, latestTimestamps[appid], opts)
+		switch {
+		case err == nil:
+			rs[appid] = c
+		case errors.Is(err, ErrNotModified), errors.Is(err, ErrAppNotFound), errors.Is(err, ErrNoInstances):
+		default:
+			return nil, err
+		}
+	}
+	return rs, nil
+}
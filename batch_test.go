@@ -0,0 +1,29 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchBatch(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	for _, app := range []string{"com.xx.a", "com.xx.b", "com.xx.c"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: app, Hostname: "h1", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano())
+	}
+	apps := []string{"com.xx.a", "com.xx.b", "com.xx.c", "com.xx.missing"}
+	rs, err := r.FetchBatch("test", apps, nil)
+	if err != nil || len(rs) != 3 {
+		t.Fatalf("unexpected batch %v %v", rs, err)
+	}
+	latest := make(map[string]int64)
+	for app, c := range rs {
+		latest[app] = c.LatestTimestamp
+	}
+
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.b", Hostname: "h2", Addrs: []string{"http://127.0.0.1:81"}, Status: StatusUP}), time.Now().UnixNano())
+	rs, err = r.FetchBatch("test", apps, latest)
+	if err != nil || len(rs) != 1 || len(rs["com.xx.b"].Instances) != 2 {
+		t.Fatalf("only changed apps should be returned, got %v %v", rs, err)
+	}
+}
@@ -0,0 +1,468 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/client"
+)
+
+// MetadataBridgedFrom 桥接过去的实例带有该元数据，值为源集群名。
+// 桥接不会再同步带有该元数据的实例，双向桥接时不会来回复制
+const MetadataBridgedFrom = "bridged_from"
+
+// RegistryTarget 目标注册中心集群
+type RegistryTarget interface {
+	Register(ctx context.Context, in *registry.Instance) error
+	// Renew 实例不存在时返回 registry.ErrInstanceNotFound，桥接会重新注册
+	Renew(ctx context.Context, env, appid, hostname string) error
+	Cancel(ctx context.Context, env, appid, hostname string) error
+}
+
+// AddrRewrite 把地址中以 From 结尾的主机名后缀替换为 To
+type AddrRewrite struct {
+	From string `json:"from"` // 如 .svc.cluster.local
+	To   string `json:"to"`   // 如 .corp.example.com
+}
+
+// BridgeRule 选择要桥接的应用及转换规则
+type BridgeRule struct {
+	Env           string        `json:"env"`                      // 源环境
+	AppIds        []string      `json:"app_ids,omitempty"`        // 桥接的应用，以 * 结尾表示前缀匹配，为空表示环境下所有应用
+	TargetEnv     string        `json:"target_env,omitempty"`     // 目标环境，为空时与源环境相同
+	AddrRewrites  []AddrRewrite `json:"addr_rewrites,omitempty"`  // 按顺序使用第一个匹配的规则
+	StripMetadata []string      `json:"strip_metadata,omitempty"` // 删除的元数据，* 表示删除全部
+}
+
+// Match 应用是否需要桥接
+func (rule *BridgeRule) Match(env, appid string) bool {
+	if env != rule.Env {
+		return false
+	}
+	if len(rule.AppIds) == 0 {
+		return true
+	}
+	for _, id := range rule.AppIds {
+		if id == appid || (strings.HasSuffix(id, "*") && strings.HasPrefix(appid, strings.TrimSuffix(id, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetEnv 目标环境
+func (rule *BridgeRule) targetEnv(env string) string {
+	if rule == nil || rule.TargetEnv == "" {
+		return env
+	}
+	return rule.TargetEnv
+}
+
+// Transform 返回转换后的实例副本
+func (rule *BridgeRule) Transform(in *registry.Instance, source string) *registry.Instance {
+	out := *in
+	out.Env = rule.targetEnv(in.Env)
+	out.Addrs = make([]string, len(in.Addrs))
+	for i, addr := range in.Addrs {
+		out.Addrs[i] = rule.rewriteAddr(addr)
+	}
+	out.Endpoints = make([]registry.Endpoint, len(in.Endpoints))
+	for i, ep := range in.Endpoints {
+		ep.Addr = rule.rewriteAddr(ep.Addr)
+		out.Endpoints[i] = ep
+	}
+	out.Metadata = make(map[string]string, len(in.Metadata)+1)
+	for k, v := range in.Metadata {
+		out.Metadata[k] = v
+	}
+	for _, k := range rule.StripMetadata {
+		if k == "*" {
+			out.Metadata = make(map[string]string, 1)
+			break
+		}
+		delete(out.Metadata, k)
+	}
+	out.Metadata[MetadataBridgedFrom] = source
+	return &out
+}
+
+// rewriteAddr 替换 scheme://host:port/path 或 host:port 中的主机名后缀
+func (rule *BridgeRule) rewriteAddr(addr string) string {
+	prefix, hostport, suffix := "", addr, ""
+	if i := strings.Index(addr, "://"); i >= 0 {
+		prefix, hostport = addr[:i+3], addr[i+3:]
+	}
+	if i := strings.IndexAny(hostport, "/?#"); i >= 0 {
+		hostport, suffix = hostport[:i], hostport[i:]
+	}
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host, port = hostport, ""
+	}
+	for _, rw := range rule.AddrRewrites {
+		if rw.From != "" && strings.HasSuffix(host, rw.From) {
+			host = strings.TrimSuffix(host, rw.From) + rw.To
+			break
+		}
+	}
+	if port != "" {
+		hostport = net.JoinHostPort(host, port)
+	} else {
+		hostport = host
+	}
+	return prefix + hostport + suffix
+}
+
+// RegistryBridgeOptions 注册中心之间的桥接配置
+type RegistryBridgeOptions struct {
+	Name           string        // 源集群名，记入 MetadataBridgedFrom
+	Rules          []BridgeRule  // 应用按第一个匹配的规则桥接
+	RenewInterval  time.Duration // 为桥接的实例续约的间隔，默认 30 秒
+	ResyncInterval time.Duration // 全量对账间隔，默认 5 分钟
+}
+
+// RegistryBridge 把源注册表中选中的应用转换后同步到另一个注册中心集群，用于混合云等
+// 只有部分服务需要跨边界可见的场景。桥接进程退出后目标集群中的实例随租约过期被剔除
+type RegistryBridge struct {
+	r      *registry.Registry
+	target RegistryTarget
+	opts   RegistryBridgeOptions
+	feed   *registry.ChangeFeed
+
+	lock    sync.Mutex
+	bridged map[registry.AppKey]map[string]*bridgedInstance // 已桥接的实例，key: 源应用，hostname
+	errs    map[registry.AppKey]error                       // 最近一次同步失败的源应用
+}
+
+// bridgedInstance 已同步到目标集群的实例
+type bridgedInstance struct {
+	env, appid, hostname string // 源环境
+	targetEnv            string
+	latest               int64 // 同步时源实例的 LatestTimestamp
+}
+
+func NewRegistryBridge(r *registry.Registry, target RegistryTarget, opts RegistryBridgeOptions) (*RegistryBridge, error) {
+	if opts.Name == "" || len(opts.Rules) == 0 {
+		return nil, errors.New("bridge name and rules are required")
+	}
+	for _, rule := range opts.Rules {
+		if rule.Env == "" {
+			return nil, errors.New("bridge rule env is required")
+		}
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = 30 * time.Second
+	}
+	if opts.ResyncInterval <= 0 {
+		opts.ResyncInterval = 5 * time.Minute
+	}
+	feed, err := r.NewChangeFeed("registry-bridge-"+opts.Name, registry.NewMemoryOffsetStore())
+	if err != nil {
+		return nil, err
+	}
+	return &RegistryBridge{
+		r:       r,
+		target:  target,
+		opts:    opts,
+		feed:    feed,
+		bridged: make(map[registry.AppKey]map[string]*bridgedInstance),
+		errs:    make(map[registry.AppKey]error),
+	}, nil
+}
+
+func (b *RegistryBridge) rule(env, appid string) *BridgeRule {
+	for i := range b.opts.Rules {
+		if b.opts.Rules[i].Match(env, appid) {
+			return &b.opts.Rules[i]
+		}
+	}
+	return nil
+}
+
+// Run 全量对账后持续同步变更并定期续约，直到 ctx 结束
+func (b *RegistryBridge) Run(ctx context.Context) error {
+	if err := b.feed.Reset(); err != nil {
+		return err
+	}
+	if err := b.Resync(ctx); err != nil {
+		log.Printf("registry bridge %s resync failed: %v", b.opts.Name, err)
+	}
+	lastResync, lastRenew := time.Now(), time.Now()
+	for {
+		if time.Since(lastResync) >= b.opts.ResyncInterval {
+			if err := b.Resync(ctx); err != nil {
+				log.Printf("registry bridge %s resync failed: %v", b.opts.Name, err)
+			}
+			lastResync = time.Now()
+		}
+		if time.Since(lastRenew) >= b.opts.RenewInterval {
+			b.renew(ctx)
+			lastRenew = time.Now()
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, b.opts.RenewInterval)
+		events, err := b.feed.Next(waitCtx, 100)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+		case errors.Is(err, registry.ErrEventsTruncated):
+			b.feed.Reset()
+			lastResync = time.Time{}
+		case err != nil:
+			log.Printf("registry bridge %s: %v", b.opts.Name, err)
+		default:
+			b.apply(ctx, events)
+		}
+	}
+}
+
+// apply 同步发生变更的应用
+func (b *RegistryBridge) apply(ctx context.Context, events []registry.Event) {
+	type app struct{ env, appid string }
+	apps := make(map[app]struct{})
+	for _, e := range events {
+		if b.rule(e.Env, e.AppId) != nil {
+			apps[app{e.Env, e.AppId}] = struct{}{}
+		}
+	}
+	for a := range apps {
+		b.syncApp(ctx, a.env, a.appid)
+	}
+	b.feed.Commit(events[len(events)-1].Seq)
+}
+
+// Resync 全量对账所有规则选中的应用，包括已经从源注册表消失的应用
+func (b *RegistryBridge) Resync(ctx context.Context) error {
+	apps := make(map[[2]string]struct{})
+	envs := make(map[string]struct{})
+	for _, rule := range b.opts.Rules {
+		envs[rule.Env] = struct{}{}
+	}
+	for env := range envs {
+		all, err := b.r.FetchAllContext(ctx, env)
+		if err != nil {
+			return err
+		}
+		for appid := range all {
+			if b.rule(env, appid) != nil {
+				apps[[2]string{env, appid}] = struct{}{}
+			}
+		}
+	}
+	b.lock.Lock()
+	for _, instances := range b.bridged {
+		for _, in := range instances {
+			apps[[2]string{in.env, in.appid}] = struct{}{}
+		}
+	}
+	b.lock.Unlock()
+	var firstErr error
+	for a := range apps {
+		if err := b.syncApp(ctx, a[0], a[1]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// syncApp 把源应用的实例同步到目标集群，删除源中已经没有的实例
+func (b *RegistryBridge) syncApp(ctx context.Context, env, appid string) error {
+	key := registry.NewAppKey(env, appid)
+	rule := b.rule(env, appid)
+	desired := make(map[string]*registry.Instance)
+	if rule != nil {
+		all, err := b.r.FetchAllContext(ctx, env)
+		if err != nil {
+			return b.setErr(key, err)
+		}
+		for _, in := range all[appid] {
+			if _, ok := in.Metadata[MetadataBridgedFrom]; !ok {
+				desired[in.Hostname] = in
+			}
+		}
+	}
+	b.lock.Lock()
+	current := b.bridged[key]
+	b.lock.Unlock()
+
+	var firstErr error
+	next := make(map[string]*bridgedInstance, len(desired))
+	for hostname, bi := range current {
+		in, ok := desired[hostname]
+		if ok && rule.targetEnv(env) == bi.targetEnv {
+			continue
+		}
+		if err := b.target.Cancel(ctx, bi.targetEnv, appid, hostname); err != nil && !notFound(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if in == nil {
+				next[hostname] = bi
+			}
+		}
+	}
+	for hostname, in := range desired {
+		if bi, ok := current[hostname]; ok && bi.latest == in.LatestTimestamp && bi.targetEnv == rule.targetEnv(env) {
+			next[hostname] = bi
+			continue
+		}
+		out := rule.Transform(in, b.opts.Name)
+		if err := b.target.Register(ctx, out); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		next[hostname] = &bridgedInstance{env: env, appid: appid, hostname: hostname, targetEnv: out.Env, latest: in.LatestTimestamp}
+	}
+	b.lock.Lock()
+	if len(next) == 0 {
+		delete(b.bridged, key)
+	} else {
+		b.bridged[key] = next
+	}
+	b.lock.Unlock()
+	return b.setErr(key, firstErr)
+}
+
+func (b *RegistryBridge) setErr(key registry.AppKey, err error) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err != nil {
+		b.errs[key] = err
+	} else {
+		delete(b.errs, key)
+	}
+	return err
+}
+
+// renew 为所有桥接的实例续约，目标集群剔除或重启后丢失的实例重新注册
+func (b *RegistryBridge) renew(ctx context.Context) {
+	b.lock.Lock()
+	var targets []*bridgedInstance
+	for _, instances := range b.bridged {
+		for _, bi := range instances {
+			targets = append(targets, bi)
+		}
+	}
+	b.lock.Unlock()
+	lost := make(map[[2]string]struct{})
+	for _, bi := range targets {
+		err := b.target.Renew(ctx, bi.targetEnv, bi.appid, bi.hostname)
+		if err == nil {
+			continue
+		}
+		if !notFound(err) {
+			log.Printf("registry bridge %s renew %s/%s failed: %v", b.opts.Name, bi.appid, bi.hostname, err)
+			continue
+		}
+		b.lock.Lock()
+		if instances := b.bridged[registry.NewAppKey(bi.env, bi.appid)]; instances[bi.hostname] == bi {
+			delete(instances, bi.hostname)
+		}
+		b.lock.Unlock()
+		lost[[2]string{bi.env, bi.appid}] = struct{}{}
+	}
+	for a := range lost {
+		b.syncApp(ctx, a[0], a[1])
+	}
+}
+
+// Errors 返回最近一次同步失败的源应用及错误
+func (b *RegistryBridge) Errors() map[registry.AppKey]error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	rs := make(map[registry.AppKey]error, len(b.errs))
+	for k, err := range b.errs {
+		rs[k] = err
+	}
+	return rs
+}
+
+// Bridged 返回已桥接到目标集群的实例数
+func (b *RegistryBridge) Bridged() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	n := 0
+	for _, instances := range b.bridged {
+		n += len(instances)
+	}
+	return n
+}
+
+func notFound(err error) bool {
+	var se *client.StatusError
+	return errors.Is(err, registry.ErrInstanceNotFound) || errors.Is(err, registry.ErrAppNotFound) ||
+		(errors.As(err, &se) && se.Code == http.StatusNotFound)
+}
+
+// LocalTarget 进程内的目标注册表，用于测试或同一进程内的多个注册表
+type LocalTarget struct {
+	R *registry.Registry
+}
+
+func (t LocalTarget) Register(ctx context.Context, in *registry.Instance) error {
+	_, err := t.R.RegisterContext(ctx, registry.NewInstance(registerRequest(in)), time.Now().UnixNano())
+	return err
+}
+
+func (t LocalTarget) Renew(ctx context.Context, env, appid, hostname string) error {
+	_, err := t.R.RenewContext(ctx, env, appid, hostname)
+	return err
+}
+
+func (t LocalTarget) Cancel(ctx context.Context, env, appid, hostname string) error {
+	_, err := t.R.CancelContext(ctx, env, appid, hostname, time.Now().UnixNano())
+	return err
+}
+
+// ClientTarget 通过 HTTP 客户端访问的目标集群
+type ClientTarget struct {
+	C *client.Client
+}
+
+func (t ClientTarget) Register(ctx context.Context, in *registry.Instance) error {
+	_, err := t.C.Register(ctx, registerRequest(in))
+	return err
+}
+
+func (t ClientTarget) Renew(ctx context.Context, env, appid, hostname string) error {
+	_, err := t.C.Renew(ctx, env, appid, hostname, "")
+	return err
+}
+
+func (t ClientTarget) Cancel(ctx context.Context, env, appid, hostname string) error {
+	return t.C.Cancel(ctx, env, appid, hostname)
+}
+
+// registerRequest 由实例生成注册请求，加密地址保持加密标记
+func registerRequest(in *registry.Instance) *registry.RequestRegister {
+	req := &registry.RequestRegister{
+		Env:      in.Env,
+		AppId:    in.AppId,
+		Hostname: in.Hostname,
+		Status:   in.Status,
+		Weight:   in.Weight,
+		Version:  in.Version,
+		Group:    in.Group,
+		Metadata: in.Metadata,
+	}
+	for i, addr := range in.Addrs {
+		if i < len(in.Endpoints) && in.Endpoints[i].Secure && !registry.ParseEndpoint(addr).Secure {
+			req.SecureAddrs = append(req.SecureAddrs, addr)
+		} else {
+			req.Addrs = append(req.Addrs, addr)
+		}
+	}
+	sort.Strings(req.SecureAddrs)
+	return req
+}
@@ -0,0 +1,63 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestRegistryBridge(t *testing.T) {
+	src, dst := registry.NewRegistry(), registry.NewRegistry()
+	register := func(appid, hostname string) {
+		src.Register(registry.NewInstance(&registry.RequestRegister{Env: "online", AppId: appid, Hostname: hostname, Status: registry.StatusUP,
+			Addrs: []string{"http://" + hostname + ".svc.cluster.local:8080"}, Metadata: map[string]string{"secret": "x", "zone": "sh"}}), time.Now().UnixNano())
+	}
+	register("com.xx.pay", "a")
+	register("com.xx.internal", "b")
+	b, err := NewRegistryBridge(src, LocalTarget{R: dst}, RegistryBridgeOptions{
+		Name: "idc",
+		Rules: []BridgeRule{{
+			Env:           "online",
+			AppIds:        []string{"com.xx.pay"},
+			TargetEnv:     "cloud",
+			AddrRewrites:  []AddrRewrite{{From: ".svc.cluster.local", To: ".idc.example.com"}},
+			StripMetadata: []string{"secret"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	fetch := func(appid string) []*registry.Instance {
+		all, _ := dst.FetchAll("cloud")
+		return all[appid]
+	}
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(time.Second)
+		for !cond() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor(func() bool { return len(fetch("com.xx.pay")) == 1 })
+	ins := fetch("com.xx.pay")
+	if len(ins) != 1 || ins[0].Addrs[0] != "http://a.idc.example.com:8080" {
+		t.Fatalf("unexpected bridged instances: %+v", ins)
+	}
+	if md := ins[0].Metadata; md["secret"] != "" || md["zone"] != "sh" || md[MetadataBridgedFrom] != "idc" {
+		t.Fatalf("unexpected metadata: %v", md)
+	}
+	if len(fetch("com.xx.internal")) != 0 {
+		t.Fatal("unselected app should not be bridged")
+	}
+
+	src.Cancel("online", "com.xx.pay", "a", time.Now().UnixNano())
+	waitFor(func() bool { return len(fetch("com.xx.pay")) == 0 })
+	if ins := fetch("com.xx.pay"); len(ins) != 0 {
+		t.Fatalf("bridged instance should be cancelled: %+v", ins)
+	}
+}
@@ -0,0 +1,207 @@
+// Package bridge 把注册表中的服务实例同步到其他服务发现系统，供尚未迁移的调用方使用
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// ZNodeClient 桥接所需的 ZooKeeper 操作，由调用方基于所用的 ZooKeeper 客户端实现
+type ZNodeClient interface {
+	EnsurePath(p string) error                   // 创建持久节点路径（含父节点），已存在时不报错
+	CreateEphemeral(p string, data []byte) error // 创建临时节点，已存在时不报错
+	Delete(p string) error                       // 删除节点，不存在时不报错
+	Children(p string) ([]string, error)         // 子节点列表，节点不存在时返回空
+}
+
+// ZooKeeperOptions ZooKeeper 桥接配置
+type ZooKeeperOptions struct {
+	Env            string        // 同步的环境
+	Root           string        // 根路径，默认 /dubbo
+	ResyncInterval time.Duration // 全量对账间隔，默认 5 分钟，用于修复会话过期丢失的临时节点
+}
+
+// ZooKeeperBridge 把 UP 状态的实例以 Dubbo 风格的路径写入 ZooKeeper：
+//
+//	<root>/<appId>/providers/<url 编码的地址?application=appId&hostname=...&version=...>
+type ZooKeeperBridge struct {
+	r    *registry.Registry
+	zk   ZNodeClient
+	opts ZooKeeperOptions
+	feed *registry.ChangeFeed
+}
+
+func NewZooKeeperBridge(r *registry.Registry, zk ZNodeClient, opts ZooKeeperOptions) (*ZooKeeperBridge, error) {
+	if opts.Env == "" {
+		return nil, errors.New("env is required")
+	}
+	if opts.Root == "" {
+		opts.Root = "/dubbo"
+	}
+	if opts.ResyncInterval <= 0 {
+		opts.ResyncInterval = 5 * time.Minute
+	}
+	// 桥接状态在 ZooKeeper 中可以随时重建，消费位置无需持久化
+	feed, err := r.NewChangeFeed("zookeeper-bridge-"+opts.Env, registry.NewMemoryOffsetStore())
+	if err != nil {
+		return nil, err
+	}
+	return &ZooKeeperBridge{r: r, zk: zk, opts: opts, feed: feed}, nil
+}
+
+// Run 全量对账后持续同步变更，直到 ctx 结束
+func (b *ZooKeeperBridge) Run(ctx context.Context) error {
+	// 从最新位置开始消费，之前的状态由全量对账覆盖
+	if err := b.feed.Reset(); err != nil {
+		return err
+	}
+	if err := b.Resync(); err != nil {
+		return err
+	}
+	lastResync := time.Now()
+	for {
+		if time.Since(lastResync) >= b.opts.ResyncInterval {
+			if err := b.Resync(); err != nil {
+				log.Printf("zookeeper bridge resync failed: %v", err)
+			}
+			lastResync = time.Now()
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, b.opts.ResyncInterval)
+		events, err := b.feed.Next(waitCtx, 100)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			// 等待超时，进入下一轮全量对账
+		case errors.Is(err, registry.ErrEventsTruncated):
+			b.feed.Reset()
+			lastResync = time.Time{}
+		case err != nil:
+			log.Printf("zookeeper bridge: %v", err)
+		default:
+			b.apply(events)
+		}
+	}
+}
+
+// apply 对账发生变更的应用
+func (b *ZooKeeperBridge) apply(events []registry.Event) {
+	apps := make(map[string]struct{})
+	for _, e := range events {
+		if e.Env == b.opts.Env {
+			apps[e.AppId] = struct{}{}
+		}
+	}
+	for appId := range apps {
+		if err := b.syncApp(appId); err != nil {
+			log.Printf("zookeeper bridge sync %s failed: %v", appId, err)
+		}
+	}
+	b.feed.Commit(events[len(events)-1].Seq)
+}
+
+// Resync 全量对账：写入所有应用的实例节点并删除多余节点
+func (b *ZooKeeperBridge) Resync() error {
+	apps := make(map[string]struct{})
+	for _, app := range b.r.Snapshot().Apps {
+		if app.Env == b.opts.Env {
+			apps[app.AppId] = struct{}{}
+		}
+	}
+	// ZooKeeper 中已有但注册表中已经没有的应用
+	existing, err := b.zk.Children(b.opts.Root)
+	if err != nil {
+		return err
+	}
+	for _, name := range existing {
+		if appId, err := url.PathUnescape(name); err == nil {
+			apps[appId] = struct{}{}
+		}
+	}
+	var firstErr error
+	for appId := range apps {
+		if err := b.syncApp(appId); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// syncApp 对账单个应用的 providers 子节点
+func (b *ZooKeeperBridge) syncApp(appId string) error {
+	dir := path.Join(b.opts.Root, url.PathEscape(appId), "providers")
+	desired := make(map[string][]byte)
+	data, err := b.r.FetchWithOptions(b.opts.Env, appId, registry.StatusUP, 0, registry.FetchOptions{Consumer: "zookeeper-bridge"})
+	if _, ok := registry.AsBackpressure(err); ok {
+		return err
+	}
+	if err == nil {
+		for _, in := range data.Instances {
+			payload, err := json.Marshal(in)
+			if err != nil {
+				return err
+			}
+			for _, node := range ProviderNodes(in) {
+				desired[node] = payload
+			}
+		}
+	}
+	if len(desired) > 0 {
+		if err := b.zk.EnsurePath(dir); err != nil {
+			return err
+		}
+	}
+	children, err := b.zk.Children(dir)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if _, ok := desired[child]; ok {
+			delete(desired, child)
+			continue
+		}
+		if err := b.zk.Delete(path.Join(dir, child)); err != nil {
+			return err
+		}
+	}
+	for node, payload := range desired {
+		if err := b.zk.CreateEphemeral(path.Join(dir, node), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProviderNodes 返回实例每个地址对应的 Dubbo 风格 provider 节点名
+func ProviderNodes(in *registry.Instance) []string {
+	q := url.Values{}
+	q.Set("application", in.AppId)
+	q.Set("hostname", in.Hostname)
+	q.Set("side", "provider")
+	if in.Version != "" {
+		q.Set("version", in.Version)
+	}
+	if in.Weight > 0 {
+		q.Set("weight", strconv.FormatUint(uint64(in.Weight), 10))
+	}
+	nodes := make([]string, 0, len(in.Addrs))
+	for _, addr := range in.Addrs {
+		ep := registry.ParseEndpoint(addr)
+		if ep.Scheme == "" {
+			ep.Scheme = "dubbo"
+		}
+		nodes = append(nodes, url.QueryEscape(ep.Scheme+"://"+ep.Addr+"/"+in.AppId+"?"+q.Encode()))
+	}
+	sort.Strings(nodes)
+	return nodes
+}
@@ -0,0 +1,93 @@
+package bridge
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// memoryZK 内存中的 ZooKeeper 节点树
+type memoryZK struct {
+	lock  sync.Mutex
+	nodes map[string][]byte
+}
+
+func (z *memoryZK) EnsurePath(p string) error {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+	for p != "/" {
+		if _, ok := z.nodes[p]; !ok {
+			z.nodes[p] = nil
+		}
+		p = path.Dir(p)
+	}
+	return nil
+}
+
+func (z *memoryZK) CreateEphemeral(p string, data []byte) error {
+	z.lock.Lock()
+	z.nodes[p] = data
+	z.lock.Unlock()
+	return nil
+}
+
+func (z *memoryZK) Delete(p string) error {
+	z.lock.Lock()
+	delete(z.nodes, p)
+	z.lock.Unlock()
+	return nil
+}
+
+func (z *memoryZK) Children(p string) ([]string, error) {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+	var rs []string
+	for node := range z.nodes {
+		if path.Dir(node) == p {
+			rs = append(rs, path.Base(node))
+		}
+	}
+	return rs, nil
+}
+
+func (z *memoryZK) providers(appId string) []string {
+	children, _ := z.Children("/dubbo/" + appId + "/providers")
+	return children
+}
+
+func TestZooKeeperBridge(t *testing.T) {
+	r := registry.NewRegistry()
+	r.Register(registry.NewInstance(&registry.RequestRegister{Env: "online", AppId: "com.xx.pay", Hostname: "a", Status: registry.StatusUP,
+		Addrs: []string{"10.0.0.1:20880"}}), time.Now().UnixNano())
+	zk := &memoryZK{nodes: make(map[string][]byte)}
+	b, err := NewZooKeeperBridge(r, zk, ZooKeeperOptions{Env: "online"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx)
+
+	waitFor := func(cond func() bool) {
+		deadline := time.Now().Add(time.Second)
+		for !cond() && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitFor(func() bool { return len(zk.providers("com.xx.pay")) == 1 })
+	nodes := zk.providers("com.xx.pay")
+	if len(nodes) != 1 || !strings.HasPrefix(nodes[0], "dubbo%3A%2F%2F10.0.0.1%3A20880%2Fcom.xx.pay%3F") {
+		t.Fatalf("unexpected provider nodes: %v", nodes)
+	}
+
+	r.Cancel("online", "com.xx.pay", "a", time.Now().UnixNano())
+	waitFor(func() bool { return len(zk.providers("com.xx.pay")) == 0 })
+	if nodes := zk.providers("com.xx.pay"); len(nodes) != 0 {
+		t.Fatalf("provider should be removed after cancel: %v", nodes)
+	}
+}
@@ -0,0 +1,132 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// InstanceSelector 按条件选择实例，各条件同时满足才选中
+type InstanceSelector struct {
+	Env    string      `json:"env"`             // 必填
+	AppId  string      `json:"appId,omitempty"` // 为空表示环境下所有应用
+	Host   string      `json:"host,omitempty"`  // 实例 hostname 或任一地址的主机部分
+	Filter WatchFilter `json:"filter"`          // 状态、可用区、元数据、版本约束
+}
+
+// Match 实例是否被选中
+func (s InstanceSelector) Match(in *Instance) bool {
+	if in.Env != s.Env || (s.AppId != "" && in.AppId != s.AppId) {
+		return false
+	}
+	if s.Host != "" && !onHost(in, s.Host) {
+		return false
+	}
+	return s.Filter.Match(in)
+}
+
+func onHost(in *Instance, host string) bool {
+	if in.Hostname == host {
+		return true
+	}
+	for _, addr := range instanceAddrs(in) {
+		h, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			h = addr
+		}
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// BulkStatusRequest 批量修改实例状态或摘流量
+type BulkStatusRequest struct {
+	Selector InstanceSelector `json:"selector"`
+	Status   uint32           `json:"status,omitempty"`  // 目标状态，0 表示不修改
+	Drain    bool             `json:"drain,omitempty"`   // 摘流量：权重置为 0，实例保持注册
+	Preview  bool             `json:"preview,omitempty"` // 只返回会被修改的实例，不实际修改
+}
+
+// BulkStatusResult 批量操作的结果
+type BulkStatusResult struct {
+	Matched []*Instance       `json:"matched"` // 选中的实例，预览时为修改前的状态
+	Applied int               `json:"applied"`
+	Errors  map[string]string `json:"errors,omitempty"` // key: 按 KeyEncoder 编码的应用/hostname
+}
+
+// BulkSetStatus 按选择器批量修改实例状态或摘流量，用于故障处理时一次性处理整台机器或整个版本。
+// 修改不会使实例的续约凭证失效，并会复制给其他节点
+func (r *Registry) BulkSetStatus(ctx context.Context, req BulkStatusRequest) (*BulkStatusResult, error) {
+	if req.Selector.Env == "" {
+		return nil, errors.New("selector env is required")
+	}
+	if req.Status == 0 && !req.Drain {
+		return nil, errors.New("nothing to change")
+	}
+	if err := checkStatus(req.Status); err != nil {
+		return nil, err
+	}
+	if err := req.Selector.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	if !req.Preview {
+		if err := r.checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+	release, err := r.Admit(PriorityAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	ctx = ensureRequestID(ctx)
+
+	res := &BulkStatusResult{Matched: make([]*Instance, 0)}
+	for _, app := range r.getAllApplications() {
+		for _, in := range app.GetAllInstances() {
+			if req.Selector.Match(in) {
+				res.Matched = append(res.Matched, in)
+			}
+		}
+	}
+	if req.Preview {
+		return res, nil
+	}
+	for _, in := range res.Matched {
+		up := copyInstance(in)
+		if req.Status != 0 {
+			up.Status = req.Status
+		}
+		if req.Drain {
+			up.Weight = 0
+		}
+		if _, err := r.update(ctx, up); err != nil {
+			if res.Errors == nil {
+				res.Errors = make(map[string]string)
+			}
+			res.Errors[encodeInstanceKey(r.keys, getInstanceKey(in.AppId, in.Env, in.Hostname))] = err.Error()
+			continue
+		}
+		res.Applied++
+	}
+	return res, nil
+}
+
+// update 保存修改后的实例副本并复制给其他节点，实例的续约凭证保持有效
+func (r *Registry) update(ctx context.Context, up *Instance) (*Instance, error) {
+	if err := r.checkFrozen(up.Env, up.AppId); err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixNano()
+	up.DirtyTimestamp = now
+	up.Clock = HLC{}
+	_, stored, _, err := r.store(ctx, up, now)
+	if err != nil {
+		return nil, err
+	}
+	r.replicate(ctx, ReplicationOp{Action: ActionRegister, Env: stored.Env, AppId: stored.AppId, Hostname: stored.Hostname, Instance: stored, LatestTimestamp: now})
+	return stored, nil
+}
@@ -0,0 +1,48 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBulkSetStatus(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	now := time.Now().UnixNano()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "a1", Addrs: []string{"http://10.0.0.1:80"}, Version: "v1.3.2", Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.b", Hostname: "b1", Addrs: []string{"http://10.0.0.1:81"}, Version: "v2.0.0", Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "a2", Addrs: []string{"http://10.0.0.2:80"}, Version: "v1.3.2", Status: StatusUP}), now)
+	cred, _ := r.HeartbeatCredential("test", "com.xx.a", "a1")
+
+	if _, err := r.BulkSetStatus(context.Background(), BulkStatusRequest{Selector: InstanceSelector{Env: "test"}, Status: 7}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("unknown status should be rejected, got %v", err)
+	}
+	host := BulkStatusRequest{Selector: InstanceSelector{Env: "test", Host: "10.0.0.1"}, Status: StatusDown, Preview: true}
+	res, err := r.BulkSetStatus(context.Background(), host)
+	if err != nil || len(res.Matched) != 2 || res.Applied != 0 {
+		t.Fatalf("unexpected preview %+v %v", res, err)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.a", "a1"); d.Instance.Status != StatusUP {
+		t.Fatal("preview should not change instances")
+	}
+
+	host.Preview = false
+	if res, err = r.BulkSetStatus(context.Background(), host); err != nil || res.Applied != 2 {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.b", "b1"); d.Instance.Status != StatusDown {
+		t.Fatal("instances on host should be down")
+	}
+	if _, _, err := r.RenewWithCredential("test", "com.xx.a", "a1", cred.Secret); err != nil {
+		t.Fatalf("bulk change should keep heartbeat credentials, got %v", err)
+	}
+
+	drain := BulkStatusRequest{Selector: InstanceSelector{Env: "test", AppId: "com.xx.a", Filter: WatchFilter{Version: "v1.3.2"}}, Drain: true}
+	if res, _ = r.BulkSetStatus(context.Background(), drain); res.Applied != 2 {
+		t.Fatalf("expect 2 drained instances, got %+v", res)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.a", "a2"); d.Instance.Weight != 0 || d.Instance.Status != StatusUP {
+		t.Fatalf("drained instance should keep status with zero weight: %+v", d.Instance)
+	}
+}
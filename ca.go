@@ -0,0 +1,286 @@
+package registry_center
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCertificateTTL 工作负载证书默认有效期
+const DefaultCertificateTTL = 24 * time.Hour
+
+// CertificateAuthority 轻量 CA，为已注册的服务实例签发短期 TLS 证书，
+// 证书绑定实例的 SPIFFE ID（未声明时由 env/appId/hostname 生成）和 hostname，只为身份经过校验的实例签发，见 certifiable
+type CertificateAuthority struct {
+	trustDomain string
+	ttl         time.Duration
+	cert        *x509.Certificate
+	certPEM     []byte
+	key         crypto.Signer
+}
+
+// IssuedCertificate 签发给服务实例的证书，PEM 编码
+type IssuedCertificate struct {
+	CertPEM  []byte `json:"cert_pem"`
+	KeyPEM   []byte `json:"key_pem"`
+	CAPEM    []byte `json:"ca_pem"`
+	NotAfter int64  `json:"not_after"` // 过期时间
+}
+
+// NewCertificateAuthority 生成自签名根证书的 CA，ttl 为签发证书的有效期
+func NewCertificateAuthority(trustDomain string, ttl time.Duration) (*CertificateAuthority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          newSerialNumber(),
+		Subject:               pkix.Name{CommonName: trustDomain + " registry ca"},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return LoadCertificateAuthority(trustDomain, ttl,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+// LoadCertificateAuthority 使用已有的 CA 证书和私钥（PEM 编码）
+func LoadCertificateAuthority(trustDomain string, ttl time.Duration, certPEM, keyPEM []byte) (*CertificateAuthority, error) {
+	if trustDomain == "" {
+		return nil, errors.New("trust domain is required")
+	}
+	if ttl <= 0 {
+		ttl = DefaultCertificateTTL
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("invalid ca certificate pem")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("invalid ca key pem")
+	}
+	var key crypto.Signer
+	if k, err := x509.ParseECPrivateKey(keyBlock.Bytes); err == nil {
+		key = k
+	} else if k, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes); err == nil {
+		signer, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported ca key type")
+		}
+		key = signer
+	} else {
+		return nil, err
+	}
+	return &CertificateAuthority{
+		trustDomain: trustDomain,
+		ttl:         ttl,
+		cert:        cert,
+		certPEM:     certPEM,
+		key:         key,
+	}, nil
+}
+
+// RootPEM 返回 CA 根证书，客户端用于校验服务实例证书
+func (ca *CertificateAuthority) RootPEM() []byte {
+	return ca.certPEM
+}
+
+// Issue 为服务实例签发证书
+func (ca *CertificateAuthority) Issue(in *Instance) (*IssuedCertificate, error) {
+	id := in.SpiffeID
+	if id == "" {
+		id = fmt.Sprintf("spiffe://%s/%s/%s/%s", ca.trustDomain, in.Env, in.AppId, in.Hostname)
+	}
+	uri, err := ParseSpiffeID(id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerialNumber(),
+		Subject:      pkix.Name{CommonName: in.AppId},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(ca.ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{uri},
+		DNSNames:     []string{in.Hostname},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedCertificate{
+		CertPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		CAPEM:    ca.certPEM,
+		NotAfter: tmpl.NotAfter.UnixNano(),
+	}, nil
+}
+
+func newSerialNumber() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
+
+// workloadCerts 记录已签发给服务实例的证书
+type workloadCerts struct {
+	certs map[instanceKey]*IssuedCertificate
+	lock  sync.RWMutex
+}
+
+func newWorkloadCerts() *workloadCerts {
+	return &workloadCerts{
+		certs: make(map[instanceKey]*IssuedCertificate),
+	}
+}
+
+// issue 签发证书，force 为 false 时仅在证书剩余有效期不足一半时重新签发
+func (w *workloadCerts) issue(ca *CertificateAuthority, in *Instance, force bool) error {
+	key := getInstanceKey(in.AppId, in.Env, in.Hostname)
+	now := time.Now().UnixNano()
+	if !force {
+		w.lock.RLock()
+		cur, ok := w.certs[key]
+		w.lock.RUnlock()
+		if ok && cur.NotAfter-now > int64(ca.ttl)/2 {
+			return nil
+		}
+	}
+	cert, err := ca.Issue(in)
+	if err != nil {
+		return err
+	}
+	w.lock.Lock()
+	w.certs[key] = cert
+	w.lock.Unlock()
+	return nil
+}
+
+// CertificateBinding 管理员批准的应用绑定，Callers 注册该应用未声明 SpiffeID 的实例时可以获得证书
+type CertificateBinding struct {
+	Env     string
+	AppId   string
+	Callers []string // 注册方客户端证书的 SPIFFE ID，末尾为 * 时按前缀匹配
+}
+
+// certifiable 实例的身份是否可以签发证书：声明的 SpiffeID 须已通过 mTLS 客户端证书校验；
+// 未声明时使用生成的 ID，须开启续约凭证，且注册方须为管理员、进程内调用或绑定了该应用的调用方，
+// 证书只随注册应答或出示凭证的续约应答下发
+func (r *Registry) certifiable(ctx context.Context, in *Instance) bool {
+	if in.SpiffeID != "" {
+		return in.SpiffeVerified
+	}
+	if r.credentials == nil {
+		return false
+	}
+	if isAdmin(ctx) {
+		return true
+	}
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return true
+	}
+	return caller != "" && r.certificateBound(caller, in.Env, in.AppId)
+}
+
+// certificateBound 调用方是否绑定了应用
+func (r *Registry) certificateBound(caller, env, appid string) bool {
+	for _, b := range r.certBindings {
+		if b.Env != env || b.AppId != appid {
+			continue
+		}
+		for _, id := range b.Callers {
+			if id == caller || strings.HasSuffix(id, "*") && strings.HasPrefix(caller, strings.TrimSuffix(id, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// issueCertificate 为身份经过校验的实例签发证书，身份未校验时作废之前签发的证书
+func (r *Registry) issueCertificate(ctx context.Context, in *Instance, force bool) {
+	if r.ca == nil {
+		return
+	}
+	if !r.certifiable(ctx, in) {
+		r.workloadCerts.remove(in.Env, in.AppId, in.Hostname)
+		return
+	}
+	if err := r.workloadCerts.issue(r.ca, in, force); err != nil {
+		logf(ctx, "issue certificate for %s/%s failed: %v", in.Key(), in.Hostname, err)
+	}
+}
+
+// renewCertificate 续约时为已持有证书的实例续签，是否可以签发在注册时确定，与续约方无关
+func (r *Registry) renewCertificate(ctx context.Context, in *Instance) {
+	if r.ca == nil {
+		return
+	}
+	if _, ok := r.IssuedCertificate(in.Env, in.AppId, in.Hostname); !ok {
+		return
+	}
+	if err := r.workloadCerts.issue(r.ca, in, false); err != nil {
+		logf(ctx, "issue certificate for %s/%s failed: %v", in.Key(), in.Hostname, err)
+	}
+}
+
+func (w *workloadCerts) remove(env, appid, hostname string) {
+	w.lock.Lock()
+	delete(w.certs, getInstanceKey(appid, env, hostname))
+	w.lock.Unlock()
+}
+
+// IssuedCertificate 获取签发给服务实例的当前证书，未开启 CA 或实例不存在时返回 false
+func (r *Registry) IssuedCertificate(env, appid, hostname string) (*IssuedCertificate, bool) {
+	w := r.workloadCerts
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	cert, ok := w.certs[getInstanceKey(appid, env, hostname)]
+	if !ok {
+		return nil, false
+	}
+	out := *cert
+	return &out, true
+}
@@ -0,0 +1,122 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestCertificateAuthority(t *testing.T) {
+	ca, err := NewCertificateAuthority("example.org", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(WithCertificateAuthority(ca), WithHeartbeatCredentials(time.Hour, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ca", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+
+	issued, ok := r.IssuedCertificate("test", "com.xx.ca", "webapi")
+	if !ok {
+		t.Fatal("certificate should be issued on register")
+	}
+	if _, err := tls.X509KeyPair(issued.CertPEM, issued.KeyPEM); err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(issued.CertPEM)
+	cert, _ := x509.ParseCertificate(block.Bytes)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.RootPEM())
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySpiffeID("spiffe://example.org/test/com.xx.ca/webapi", []*x509.Certificate{cert}); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Cancel("test", "com.xx.ca", "webapi", time.Now().UnixNano())
+	if _, ok := r.IssuedCertificate("test", "com.xx.ca", "webapi"); ok {
+		t.Fatal("certificate should be dropped on cancel")
+	}
+}
+
+func TestCertificateRequiresVerifiedIdentity(t *testing.T) {
+	ca, err := NewCertificateAuthority("example.org", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	register := func(r *Registry, host, spiffeID string, certs []*x509.Certificate) {
+		t.Helper()
+		in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ca", Hostname: host, Addrs: []string{"http://" + host + ":80"}, SpiffeID: spiffeID})
+		if _, err := r.RegisterWithPeerCertificates(in, time.Now().UnixNano(), certs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 未开启续约凭证时，生成的 ID 及未经校验的 SpiffeID 都不签发
+	r := NewRegistry(WithCertificateAuthority(ca))
+	register(r, "plain", "", nil)
+	register(r, "claimed", "spiffe://example.org/payments", nil)
+	for _, host := range []string{"plain", "claimed"} {
+		if _, ok := r.IssuedCertificate("test", "com.xx.ca", host); ok {
+			t.Fatalf("%s: certificate issued for an unverified identity", host)
+		}
+	}
+
+	// 出示与 SpiffeID 匹配的客户端证书后签发
+	r2 := NewRegistry(WithCertificateAuthority(ca), WithHeartbeatCredentials(time.Hour, time.Minute))
+	register(r2, "peer", "", nil)
+	issued, ok := r2.IssuedCertificate("test", "com.xx.ca", "peer")
+	if !ok {
+		t.Fatal("credential-bound identity should be certified")
+	}
+	block, _ := pem.Decode(issued.CertPEM)
+	cert, _ := x509.ParseCertificate(block.Bytes)
+	register(r, "verified", "spiffe://example.org/test/com.xx.ca/peer", []*x509.Certificate{cert})
+	if _, ok := r.IssuedCertificate("test", "com.xx.ca", "verified"); !ok {
+		t.Fatal("verified spiffe id should be certified")
+	}
+	// 同一主机改为未经校验的身份后作废之前的证书
+	register(r, "verified", "spiffe://example.org/test/com.xx.ca/peer", nil)
+	if _, ok := r.IssuedCertificate("test", "com.xx.ca", "verified"); ok {
+		t.Fatal("certificate should be revoked once the identity is no longer verified")
+	}
+}
+
+func TestCertificateRequiresAppBinding(t *testing.T) {
+	ca, err := NewCertificateAuthority("example.org", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(WithCertificateAuthority(ca), WithHeartbeatCredentials(time.Hour, time.Minute),
+		WithCertificateBindings(CertificateBinding{Env: "test", AppId: "com.xx.ca", Callers: []string{"spiffe://example.org/deployer/*"}}))
+	register := func(ctx context.Context, host string) bool {
+		t.Helper()
+		in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ca", Hostname: host, Addrs: []string{"http://" + host + ":80"}})
+		if _, err := r.RegisterContext(ctx, in, time.Now().UnixNano()); err != nil {
+			t.Fatal(err)
+		}
+		_, ok := r.IssuedCertificate("test", "com.xx.ca", host)
+		return ok
+	}
+	// 远程注册方未认证或未绑定该应用时不签发
+	if register(ContextWithCaller(context.Background(), ""), "anonymous") {
+		t.Fatal("anonymous registrant must not be certified")
+	}
+	if register(ContextWithCaller(context.Background(), "spiffe://example.org/other"), "other") {
+		t.Fatal("unbound registrant must not be certified")
+	}
+	if !register(ContextWithCaller(context.Background(), "spiffe://example.org/deployer/ci"), "bound") {
+		t.Fatal("bound registrant should be certified")
+	}
+	if !register(ContextWithAdmin(ContextWithCaller(context.Background(), "")), "admin") {
+		t.Fatal("admin registrant should be certified")
+	}
+	// 续约不重新判断续约方，已签发的证书保留
+	if _, err := r.RenewContext(ContextWithCaller(context.Background(), ""), "test", "com.xx.ca", "bound"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.IssuedCertificate("test", "com.xx.ca", "bound"); !ok {
+		t.Fatal("renewal should keep the certificate")
+	}
+}
@@ -0,0 +1,146 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultHeadroomThreshold 应用整体利用率低于该值时认为还有余量
+const DefaultHeadroomThreshold = 0.8
+
+// Capacity 实例上报的容量指标，随续约一起上报
+type Capacity struct {
+	MaxConcurrent int64   `json:"max_concurrent"`        // 最大并发请求数，0 表示未知
+	InFlight      int64   `json:"in_flight"`             // 当前并发请求数
+	Utilization   float64 `json:"utilization,omitempty"` // 实例自己计算的利用率 0-1，为 0 时按 InFlight/MaxConcurrent 计算
+	ReportedAt    int64   `json:"reported_at"`           // 上报时间，由注册中心设置
+}
+
+// utilization 实例的利用率
+func (c Capacity) utilization() float64 {
+	if c.Utilization > 0 || c.MaxConcurrent <= 0 {
+		return c.Utilization
+	}
+	return float64(c.InFlight) / float64(c.MaxConcurrent)
+}
+
+func (c Capacity) validate() error {
+	if c.MaxConcurrent < 0 || c.InFlight < 0 || c.Utilization < 0 {
+		return errors.New("invalid capacity")
+	}
+	return nil
+}
+
+// AppCapacity 应用所有可用实例的容量汇总
+type AppCapacity struct {
+	Env           string  `json:"env"`
+	AppId         string  `json:"appId"`
+	Instances     int     `json:"instances"`      // 可用实例数
+	Reporting     int     `json:"reporting"`      // 最近上报过容量的可用实例数
+	MaxConcurrent int64   `json:"max_concurrent"` // 上报实例的最大并发之和
+	InFlight      int64   `json:"in_flight"`      // 上报实例的当前并发之和
+	Utilization   float64 `json:"utilization"`    // 上报实例的平均利用率
+	Threshold     float64 `json:"threshold"`
+	Headroom      bool    `json:"headroom"` // 是否还有余量，没有实例上报时为 false
+}
+
+type capacityKey struct{}
+
+// ContextWithCapacity 把实例上报的容量放入 ctx，续约时一起记录并复制给对端
+func ContextWithCapacity(ctx context.Context, c Capacity) context.Context {
+	return context.WithValue(ctx, capacityKey{}, c)
+}
+
+func capacityFromContext(ctx context.Context) *Capacity {
+	c, ok := ctx.Value(capacityKey{}).(Capacity)
+	if !ok {
+		return nil
+	}
+	return &c
+}
+
+// capacityTracker 各实例最近一次上报的容量
+type capacityTracker struct {
+	lock      sync.RWMutex
+	threshold float64
+	instances map[instanceKey]Capacity
+}
+
+func newCapacityTracker() *capacityTracker {
+	return &capacityTracker{threshold: DefaultHeadroomThreshold, instances: make(map[instanceKey]Capacity)}
+}
+
+func (t *capacityTracker) report(key instanceKey, c Capacity) {
+	if c.ReportedAt == 0 {
+		c.ReportedAt = time.Now().UnixNano()
+	}
+	t.lock.Lock()
+	t.instances[key] = c
+	t.lock.Unlock()
+}
+
+func (t *capacityTracker) remove(key instanceKey) {
+	t.lock.Lock()
+	delete(t.instances, key)
+	t.lock.Unlock()
+}
+
+// purge 删除应用所有实例的容量
+func (t *capacityTracker) purge(appKey AppKey) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key := range t.instances {
+		if key.app == appKey {
+			delete(t.instances, key)
+		}
+	}
+}
+
+// ReportCapacity 记录实例的容量，不续约。通常通过 ContextWithCapacity 随续约上报
+func (r *Registry) ReportCapacity(env, appid, hostname string, c Capacity) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if _, ok := r.getInstance(env, appid, hostname); !ok {
+		return ErrInstanceNotFound
+	}
+	r.capacity.report(getInstanceKey(appid, env, hostname), c)
+	return nil
+}
+
+// AppCapacity 汇总应用可用实例的容量，超过剔除阈值未上报的实例不参与汇总，
+// 用于自动扩缩容和调用方准入控制判断应用是否还有余量
+func (r *Registry) AppCapacity(env, appid string) (*AppCapacity, error) {
+	app, ok := r.getApplication(appid, env)
+	if !ok {
+		return nil, ErrAppNotFound
+	}
+	t := r.capacity
+	rs := &AppCapacity{Env: env, AppId: appid}
+	expire := time.Now().Add(-leaseExpiry).UnixNano()
+	var sum float64
+	t.lock.RLock()
+	rs.Threshold = t.threshold
+	for _, in := range app.GetAllInstances() {
+		if in.Status&StatusUP == 0 {
+			continue
+		}
+		rs.Instances++
+		c, ok := t.instances[getInstanceKey(appid, env, in.Hostname)]
+		if !ok || c.ReportedAt < expire {
+			continue
+		}
+		rs.Reporting++
+		rs.MaxConcurrent += c.MaxConcurrent
+		rs.InFlight += c.InFlight
+		sum += c.utilization()
+	}
+	t.lock.RUnlock()
+	if rs.Reporting > 0 {
+		rs.Utilization = sum / float64(rs.Reporting)
+		rs.Headroom = rs.Utilization < rs.Threshold
+	}
+	return rs, nil
+}
@@ -0,0 +1,51 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAppCapacity(t *testing.T) {
+	r := NewRegistry(WithHeadroomThreshold(0.5))
+	defer r.Close()
+	for _, h := range []string{"a", "b", "c"} {
+		if _, err := r.Register(&Instance{Env: "test", AppId: "com.xx.cap", Hostname: h, Status: StatusUP}, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, err := r.AppCapacity("test", "com.xx.cap"); err != nil || c.Instances != 3 || c.Reporting != 0 || c.Headroom {
+		t.Fatalf("unexpected capacity without reports %+v %v", c, err)
+	}
+
+	ctx := ContextWithCapacity(context.Background(), Capacity{MaxConcurrent: 100, InFlight: 20})
+	if _, err := r.RenewContext(ctx, "test", "com.xx.cap", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ReportCapacity("test", "com.xx.cap", "b", Capacity{Utilization: 0.6}); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := r.AppCapacity("test", "com.xx.cap")
+	if c.Reporting != 2 || c.MaxConcurrent != 100 || c.InFlight != 20 || c.Utilization != 0.4 || !c.Headroom {
+		t.Fatalf("unexpected capacity %+v", c)
+	}
+
+	r.ReportCapacity("test", "com.xx.cap", "a", Capacity{MaxConcurrent: 100, InFlight: 80})
+	if c, _ := r.AppCapacity("test", "com.xx.cap"); c.Headroom {
+		t.Fatalf("expect no headroom %+v", c)
+	}
+	r.Cancel("test", "com.xx.cap", "a", 2)
+	if c, _ := r.AppCapacity("test", "com.xx.cap"); c.Reporting != 1 || c.Headroom {
+		t.Fatalf("cancelled instance should not be counted %+v", c)
+	}
+	if err := r.ReportCapacity("test", "com.xx.cap", "a", Capacity{}); err != ErrInstanceNotFound {
+		t.Fatalf("expect instance not found, got %v", err)
+	}
+
+	op := ReplicationOp{Action: ActionRenew, Env: "test", AppId: "com.xx.cap", Hostname: "c", Capacity: &Capacity{MaxConcurrent: 10, InFlight: 1}}
+	if err := r.ApplyReplication(op); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := r.AppCapacity("test", "com.xx.cap"); c.Reporting != 2 || c.MaxConcurrent != 10 {
+		t.Fatalf("replicated capacity should be recorded %+v", c)
+	}
+}
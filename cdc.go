@@ -0,0 +1,262 @@
+package registry_center
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// CDCSink 变更数据捕获的目标，注册表状态通过它镜像到外部存储
+type CDCSink interface {
+	// Apply 按顺序应用一批变更事件，必须是幂等的。应用级事件（EventPurge、EventAppRemoved）删除该应用的全部数据，
+	// 无法识别的事件类型应返回错误而不是忽略
+	Apply(ctx context.Context, events []Event) error
+	// Sync 用全量实例替换外部存储中的全部数据
+	Sync(ctx context.Context, instances []*Instance) error
+}
+
+// CDCExporter 把注册表变更持续导出到 CDCSink，外部存储与注册表最终一致
+type CDCExporter struct {
+	r         *Registry
+	feed      *ChangeFeed
+	sink      CDCSink
+	batchSize int
+	retry     time.Duration
+}
+
+// NewCDCExporter 创建导出器，name 作为变更订阅者名字，消费位置保存在 store 中
+func (r *Registry) NewCDCExporter(name string, store OffsetStore, sink CDCSink) (*CDCExporter, error) {
+	feed, err := r.NewChangeFeed(name, store)
+	if err != nil {
+		return nil, err
+	}
+	return &CDCExporter{
+		r:         r,
+		feed:      feed,
+		sink:      sink,
+		batchSize: 100,
+		retry:     5 * time.Second,
+	}, nil
+}
+
+// Run 持续导出直到 ctx 结束。首次运行、订阅者落后超出事件日志保留范围，
+// 或保存的位置来自注册中心重启前的事件日志时，先做一次全量同步
+func (e *CDCExporter) Run(ctx context.Context) error {
+	if e.feed.Offset() == 0 || e.feed.Truncated() {
+		if err := e.resync(ctx); err != nil {
+			return err
+		}
+	}
+	for {
+		events, err := e.feed.Next(ctx, e.batchSize)
+		if errors.Is(err, ErrEventsTruncated) {
+			err = e.resync(ctx)
+			if err == nil {
+				continue
+			}
+		}
+		if err == nil {
+			if err = e.sink.Apply(ctx, events); err == nil {
+				err = e.feed.Commit(events[len(events)-1].Seq)
+			}
+		}
+		if err == nil {
+			e.r.storageSucceeded(e.feed.name, e.pending())
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("cdc export failed: %v", err)
+		e.r.storageFailed(e.feed.name, err, e.pending())
+		e.r.raiseAlert(Alert{
+			Name:    AlertStorageFailure,
+			Summary: "cdc export failed: " + err.Error(),
+			Labels:  map[string]string{"exporter": e.feed.name},
+		})
+		e.feed.Rewind()
+		select {
+		case <-time.After(e.retry):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pending 还没导出的事件数
+func (e *CDCExporter) pending() uint64 {
+	last, offset := e.r.LastEventSeq(), e.feed.Offset()
+	if last <= offset {
+		return 0
+	}
+	return last - offset
+}
+
+// resync 全量同步。先记下事件位置再取快照，之后从该位置重放，依赖 Apply 的幂等性保证一致
+func (e *CDCExporter) resync(ctx context.Context) error {
+	seq := e.r.LastEventSeq()
+	if err := e.sink.Sync(ctx, e.r.allInstances()); err != nil {
+		return err
+	}
+	return e.feed.resetTo(seq)
+}
+
+// allInstances 返回注册表中所有实例的副本
+func (r *Registry) allInstances() []*Instance {
+	var rs []*Instance
+	for _, app := range r.getAllApplications() {
+		rs = append(rs, app.GetAllInstances()...)
+	}
+	return rs
+}
+
+// SQLDialect SQL 方言
+type SQLDialect int
+
+const (
+	DialectMySQL SQLDialect = iota
+	DialectPostgres
+)
+
+// SQLSink 把实例镜像到 MySQL/Postgres 表中，表结构：
+//
+//	env, app_id, hostname 为联合主键，version, status, addrs, data(实例 JSON), latest_timestamp 为普通列
+type SQLSink struct {
+	db      *sql.DB
+	dialect SQLDialect
+	table   string
+}
+
+// NewSQLSink 使用调用方打开的 *sql.DB，驱动由调用方引入
+func NewSQLSink(db *sql.DB, dialect SQLDialect, table string) *SQLSink {
+	return &SQLSink{db: db, dialect: dialect, table: table}
+}
+
+func (s *SQLSink) placeholders(n int) []string {
+	ps := make([]string, n)
+	for i := range ps {
+		if s.dialect == DialectPostgres {
+			ps[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			ps[i] = "?"
+		}
+	}
+	return ps
+}
+
+func (s *SQLSink) upsertSQL() string {
+	cols := []string{"env", "app_id", "hostname", "version", "status", "addrs", "data", "latest_timestamp"}
+	ps := s.placeholders(len(cols))
+	var sets []string
+	for _, c := range cols[3:] {
+		if s.dialect == DialectPostgres {
+			sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		} else {
+			sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", c, c))
+		}
+	}
+	conflict := "ON DUPLICATE KEY UPDATE"
+	if s.dialect == DialectPostgres {
+		conflict = "ON CONFLICT (env, app_id, hostname) DO UPDATE SET"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s %s",
+		s.table, strings.Join(cols, ", "), strings.Join(ps, ", "), conflict, strings.Join(sets, ", "))
+}
+
+func (s *SQLSink) deleteSQL() string {
+	ps := s.placeholders(3)
+	return fmt.Sprintf("DELETE FROM %s WHERE env = %s AND app_id = %s AND hostname = %s", s.table, ps[0], ps[1], ps[2])
+}
+
+func (s *SQLSink) deleteAppSQL() string {
+	ps := s.placeholders(2)
+	return fmt.Sprintf("DELETE FROM %s WHERE env = %s AND app_id = %s", s.table, ps[0], ps[1])
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func upsertArgs(in *Instance) ([]interface{}, error) {
+	addrs, err := json.Marshal(in.Addrs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{in.Env, in.AppId, in.Hostname, in.Version, in.Status, string(addrs), string(data), in.LatestTimestamp}, nil
+}
+
+func (s *SQLSink) upsert(ctx context.Context, db execer, in *Instance) error {
+	args, err := upsertArgs(in)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, s.upsertSQL(), args...)
+	return err
+}
+
+// statement 事件对应的 SQL。可用区事件不改变实例，返回空语句；无法识别的事件返回错误，
+// 导出器会停在该事件并告警，避免新增的事件类型被静默丢弃导致镜像数据不一致
+func (s *SQLSink) statement(e Event) (string, []interface{}, error) {
+	switch e.Type {
+	case EventRegister:
+		if e.Instance == nil {
+			return "", nil, fmt.Errorf("cdc: register event %d without instance", e.Seq)
+		}
+		args, err := upsertArgs(e.Instance)
+		return s.upsertSQL(), args, err
+	case EventCancel, EventEvict:
+		return s.deleteSQL(), []interface{}{e.Env, e.AppId, e.Hostname}, nil
+	case EventPurge, EventAppRemoved:
+		return s.deleteAppSQL(), []interface{}{e.Env, e.AppId}, nil
+	case EventZoneDegraded, EventZoneRecovered:
+		return "", nil, nil
+	}
+	return "", nil, fmt.Errorf("cdc: unknown event type %q (seq %d)", e.Type, e.Seq)
+}
+
+// Apply 在一个事务中应用一批事件，遇到无法识别的事件时整批回滚
+func (s *SQLSink) Apply(ctx context.Context, events []Event) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		query, args, err := s.statement(e)
+		if err == nil && query != "" {
+			_, err = tx.ExecContext(ctx, query, args...)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Sync 在一个事务中清空表并写入全量实例
+func (s *SQLSink) Sync(ctx context.Context, instances []*Instance) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+s.table); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, in := range instances {
+		if err := s.upsert(ctx, tx, in); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,143 @@
+package registry_center
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memorySink struct {
+	lock sync.Mutex
+	rows map[string]*Instance
+}
+
+func (s *memorySink) Apply(ctx context.Context, events []Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, e := range events {
+		switch e.Type {
+		case EventRegister:
+			s.rows[e.Hostname] = e.Instance
+		case EventCancel, EventEvict:
+			delete(s.rows, e.Hostname)
+		case EventPurge, EventAppRemoved:
+			s.rows = make(map[string]*Instance)
+		}
+	}
+	return nil
+}
+
+func (s *memorySink) Sync(ctx context.Context, instances []*Instance) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rows = make(map[string]*Instance)
+	for _, in := range instances {
+		s.rows[in.Hostname] = in
+	}
+	return nil
+}
+
+func (s *memorySink) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.rows)
+}
+
+func TestCDCExporter(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cdc", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	sink := &memorySink{}
+	exporter, err := r.NewCDCExporter("bi", NewMemoryOffsetStore(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go exporter.Run(ctx)
+
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cdc", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	r.Cancel("test", "com.xx.cdc", "a", time.Now().UnixNano())
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && (sink.len() != 1 || exporter.feed.Offset() != r.LastEventSeq()) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if _, ok := sink.rows["b"]; !ok || len(sink.rows) != 1 {
+		t.Fatalf("unexpected sink rows: %v", sink.rows)
+	}
+}
+
+func TestCDCExporterAfterRestart(t *testing.T) {
+	store := NewMemoryOffsetStore()
+	r := NewRegistry()
+	for _, h := range []string{"a", "b", "c"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cdc", Hostname: h, Status: StatusUP}), time.Now().UnixNano())
+	}
+	sink := &memorySink{}
+	exporter, _ := r.NewCDCExporter("bi", store, sink)
+	ctx, cancel := context.WithCancel(context.Background())
+	go exporter.Run(ctx)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && sink.len() != 3 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	// 重启后只剩一个实例，保存的位置大于新的事件序号，需要全量同步而不是等待新事件
+	restarted := NewRegistry()
+	restarted.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cdc", Hostname: "x", Status: StatusUP}), time.Now().UnixNano())
+	exporter, _ = restarted.NewCDCExporter("bi", store, sink)
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go exporter.Run(ctx)
+	deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && sink.len() != 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	sink.lock.Lock()
+	defer sink.lock.Unlock()
+	if _, ok := sink.rows["x"]; !ok || len(sink.rows) != 1 {
+		t.Fatalf("stale rows should be replaced after restart: %v", sink.rows)
+	}
+}
+
+func TestSQLSinkStatements(t *testing.T) {
+	pg := NewSQLSink(nil, DialectPostgres, "instances")
+	if got := pg.deleteSQL(); got != "DELETE FROM instances WHERE env = $1 AND app_id = $2 AND hostname = $3" {
+		t.Fatalf("unexpected postgres delete: %s", got)
+	}
+	my := NewSQLSink(nil, DialectMySQL, "instances")
+	want := "INSERT INTO instances (env, app_id, hostname, version, status, addrs, data, latest_timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE version = VALUES(version), status = VALUES(status), addrs = VALUES(addrs), data = VALUES(data), latest_timestamp = VALUES(latest_timestamp)"
+	if got := my.upsertSQL(); got != want {
+		t.Fatalf("unexpected mysql upsert: %s", got)
+	}
+}
+
+func TestSQLSinkEventStatements(t *testing.T) {
+	s := NewSQLSink(nil, DialectMySQL, "instances")
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cdc", Hostname: "a", Status: StatusUP})
+	for _, c := range []struct {
+		e     Event
+		query string
+		args  int
+	}{
+		{Event{Type: EventRegister, Instance: in}, s.upsertSQL(), 8},
+		{Event{Type: EventEvict, Env: "test", AppId: "com.xx.cdc", Hostname: "a"}, s.deleteSQL(), 3},
+		{Event{Type: EventPurge, Env: "test", AppId: "com.xx.cdc"}, "DELETE FROM instances WHERE env = ? AND app_id = ?", 2},
+		{Event{Type: EventAppRemoved, Env: "test", AppId: "com.xx.cdc"}, s.deleteAppSQL(), 2},
+		{Event{Type: EventZoneDegraded, Env: "test", AppId: "com.xx.cdc", Zone: "z1"}, "", 0},
+	} {
+		query, args, err := s.statement(c.e)
+		if err != nil || query != c.query || len(args) != c.args {
+			t.Fatalf("%s: unexpected statement %q %v %v", c.e.Type, query, args, err)
+		}
+	}
+	for _, e := range []Event{{Type: EventRegister}, {Type: "renamed"}} {
+		if _, _, err := s.statement(e); err == nil {
+			t.Fatalf("%q event should be rejected", e.Type)
+		}
+	}
+}
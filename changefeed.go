@@ -0,0 +1,232 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Offset 变更订阅者的消费位置，Epoch 为事件日志的标识，见 Registry.EventEpoch
+type Offset struct {
+	Epoch string `json:"epoch"`
+	Seq   uint64 `json:"seq"`
+}
+
+// OffsetStore 持久化变更订阅者的消费位置
+type OffsetStore interface {
+	Load(consumer string) (Offset, error) // 未保存过时返回零值
+	Save(consumer string, offset Offset) error
+}
+
+// MemoryOffsetStore 内存中的消费位置，进程重启后丢失，适用于测试
+type MemoryOffsetStore struct {
+	offsets map[string]Offset
+	lock    sync.Mutex
+}
+
+func NewMemoryOffsetStore() *MemoryOffsetStore {
+	return &MemoryOffsetStore{offsets: make(map[string]Offset)}
+}
+
+func (s *MemoryOffsetStore) Load(consumer string) (Offset, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.offsets[consumer], nil
+}
+
+func (s *MemoryOffsetStore) Save(consumer string, offset Offset) error {
+	s.lock.Lock()
+	s.offsets[consumer] = offset
+	s.lock.Unlock()
+	return nil
+}
+
+// FileOffsetStore 每个订阅者一个文件保存消费位置，内容为 "<epoch> <seq>"，写入时先写临时文件再重命名。
+// 只有序号的旧文件视为标识未知
+type FileOffsetStore struct {
+	dir string
+}
+
+func NewFileOffsetStore(dir string) (*FileOffsetStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileOffsetStore{dir: dir}, nil
+}
+
+func (s *FileOffsetStore) path(consumer string) string {
+	return filepath.Join(s.dir, fileSafeName(consumer)+".offset")
+}
+
+func (s *FileOffsetStore) Load(consumer string) (Offset, error) {
+	b, err := os.ReadFile(s.path(consumer))
+	if errors.Is(err, os.ErrNotExist) {
+		return Offset{}, nil
+	}
+	if err != nil {
+		return Offset{}, err
+	}
+	var offset Offset
+	seq := strings.TrimSpace(string(b))
+	if epoch, rest, ok := strings.Cut(seq, " "); ok {
+		offset.Epoch, seq = epoch, rest
+	}
+	offset.Seq, err = strconv.ParseUint(seq, 10, 64)
+	return offset, err
+}
+
+func (s *FileOffsetStore) Save(consumer string, offset Offset) error {
+	tmp, err := os.CreateTemp(s.dir, ".offset-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(offset.Epoch + " " + strconv.FormatUint(offset.Seq, 10)); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(consumer))
+}
+
+// fileSafeName 把订阅者名字转换为可作为文件名的形式
+func fileSafeName(name string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			return c
+		}
+		return '_'
+	}, name)
+}
+
+// ChangeFeed 变更日志订阅者，消费位置持久化在 OffsetStore 中。
+// 处理完事件后调用 Commit 提交，重启后从已提交的位置继续，至少投递一次。
+// 保存的位置来自注册中心重启前的事件日志时，之后的事件已无法重放，Next 返回 ErrEventsTruncated
+type ChangeFeed struct {
+	r        *Registry
+	name     string
+	store    OffsetStore
+	lock     sync.Mutex
+	position uint64 // 已投递的位置
+	offset   uint64 // 已提交的位置
+	stale    bool   // 保存的位置属于其他事件日志，Reset 前不能继续消费
+}
+
+// NewChangeFeed 创建变更订阅者，从 store 中保存的位置开始消费，未保存过位置时从事件日志的开头开始
+func (r *Registry) NewChangeFeed(name string, store OffsetStore) (*ChangeFeed, error) {
+	if name == "" {
+		return nil, errors.New("change feed name is required")
+	}
+	offset, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return &ChangeFeed{
+		r:        r,
+		name:     name,
+		store:    store,
+		position: offset.Seq,
+		offset:   offset.Seq,
+		stale:    offset != Offset{} && offset.Epoch != r.EventEpoch(),
+	}, nil
+}
+
+// Next 返回下一批最多 max 条事件，没有新事件时阻塞直到有新事件或 ctx 结束，不支持并发调用。
+// 返回 ErrEventsTruncated 表示订阅者落后太多或位置来自重启前的事件日志，需要全量同步后调用 Reset
+func (f *ChangeFeed) Next(ctx context.Context, max int) ([]Event, error) {
+	for {
+		wait := f.r.events.wait()
+		f.lock.Lock()
+		if f.stale {
+			f.lock.Unlock()
+			return nil, ErrEventsTruncated
+		}
+		events, err := f.r.events.since(f.position, max)
+		if err == nil && len(events) > 0 {
+			f.position = events[len(events)-1].Seq
+		}
+		f.lock.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			return events, nil
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Commit 提交消费位置，seq 之前（含）的事件不会再投递。seq 超过最新序号时返回 ErrEventsTruncated
+func (f *ChangeFeed) Commit(seq uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.stale || seq > f.r.LastEventSeq() {
+		return ErrEventsTruncated
+	}
+	if seq <= f.offset {
+		return nil
+	}
+	if err := f.store.Save(f.name, Offset{Epoch: f.r.EventEpoch(), Seq: seq}); err != nil {
+		return err
+	}
+	f.offset = seq
+	return nil
+}
+
+// Offset 已提交的消费位置
+func (f *ChangeFeed) Offset() uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.offset
+}
+
+// Truncated 已提交的位置是否已无法继续消费：来自重启前的事件日志、超过最新序号或早于保留范围
+func (f *ChangeFeed) Truncated() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.stale {
+		return true
+	}
+	_, err := f.r.events.since(f.offset, 1)
+	return err != nil
+}
+
+// Rewind 回到已提交的位置，重新投递之后的事件，处理失败时使用
+func (f *ChangeFeed) Rewind() {
+	f.lock.Lock()
+	f.position = f.offset
+	f.lock.Unlock()
+}
+
+// Reset 跳到最新位置并提交，全量同步完成后使用
+func (f *ChangeFeed) Reset() error {
+	return f.resetTo(f.r.LastEventSeq())
+}
+
+func (f *ChangeFeed) resetTo(seq uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if err := f.store.Save(f.name, Offset{Epoch: f.r.EventEpoch(), Seq: seq}); err != nil {
+		return err
+	}
+	f.position = seq
+	f.offset = seq
+	f.stale = false
+	return nil
+}
@@ -0,0 +1,95 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChangeFeed(t *testing.T) {
+	r := NewRegistry()
+	store, err := NewFileOffsetStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := r.NewChangeFeed("cmdb-sync", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.feed", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	events, err := feed.Next(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventRegister {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if err := feed.Commit(events[0].Seq); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后从已提交的位置继续
+	r.Cancel("test", "com.xx.feed", "a", time.Now().UnixNano())
+	feed2, _ := r.NewChangeFeed("cmdb-sync", store)
+	events, err = feed2.Next(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventCancel {
+		t.Fatalf("unexpected events after restart: %+v", events)
+	}
+}
+
+func TestChangeFeedAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileOffsetStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry()
+	for _, h := range []string{"a", "b", "c", "d", "e"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.feed", Hostname: h, Status: StatusUP}), time.Now().UnixNano())
+	}
+	feed, _ := r.NewChangeFeed("cmdb-sync", store)
+	if err := feed.Commit(5); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后序号从 1 开始，保存的位置属于之前的事件日志
+	restarted := NewRegistry()
+	restarted.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.feed", Hostname: "f", Status: StatusUP}), time.Now().UnixNano())
+	feed, _ = restarted.NewChangeFeed("cmdb-sync", store)
+	if !feed.Truncated() {
+		t.Fatal("offset from before the restart should be truncated")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := feed.Next(ctx, 10); !errors.Is(err, ErrEventsTruncated) {
+		t.Fatalf("expect truncated, got %v", err)
+	}
+	if err := feed.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	restarted.Cancel("test", "com.xx.feed", "f", time.Now().UnixNano())
+	if events, err := feed.Next(ctx, 10); err != nil || len(events) != 1 || events[0].Type != EventCancel {
+		t.Fatalf("unexpected events after reset: %+v %v", events, err)
+	}
+
+	// 只有序号的旧文件、超过最新序号的位置同样视为失效
+	os.WriteFile(filepath.Join(dir, "legacy.offset"), []byte("1"), 0o644)
+	if feed, _ := restarted.NewChangeFeed("legacy", store); !feed.Truncated() {
+		t.Fatal("offset without epoch should be truncated")
+	}
+	store.Save("ahead", Offset{Epoch: restarted.EventEpoch(), Seq: 100})
+	if feed, _ := restarted.NewChangeFeed("ahead", store); !feed.Truncated() {
+		t.Fatal("offset beyond the last seq should be truncated")
+	}
+}
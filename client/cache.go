@@ -0,0 +1,189 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// ErrStale 注册中心不可达的时间超过了 CacheConfig.MaxStale，缓存的数据不再返回
+var ErrStale = errors.New("cached discovery data is stale")
+
+// CacheConfig 本地服务发现缓存配置
+type CacheConfig struct {
+	Env           string
+	PollTimeout   time.Duration // 后台长轮询的等待时长，默认 5 秒，HTTPClient 的超时需要大于该值
+	RetryInterval time.Duration // 刷新失败后的首次重试间隔，默认 1 秒，连续失败时翻倍，最长 30 秒
+	MaxStale      time.Duration // 刷新失败时最多继续返回多久以前确认过的数据，0 表示一直返回
+}
+
+// Cache 本地服务发现缓存。首次获取应用时同步拉取，之后在后台长轮询刷新，
+// 注册中心短暂不可达时继续返回最近一次的数据
+type Cache struct {
+	c      *Client
+	cfg    CacheConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	lock sync.RWMutex
+	apps map[string]*cachedApp // key: appid
+}
+
+type cachedApp struct {
+	data     *registry.FetchData
+	syncedAt time.Time // 最近一次确认数据为最新的时间
+	err      error     // 最近一次刷新的错误，成功时为空
+	gone     error     // 注册中心确认应用已不存在或没有实例时的错误
+}
+
+// CacheStatus 缓存中一个应用的状态
+type CacheStatus struct {
+	AppId           string
+	LatestTimestamp int64
+	SyncedAt        time.Time
+	Err             error // 最近一次刷新的错误
+}
+
+// NewCache 创建本地服务发现缓存，不再使用时调用 Close 停止后台刷新
+func (c *Client) NewCache(cfg CacheConfig) *Cache {
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = 5 * time.Second
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cache{c: c, cfg: cfg, ctx: ctx, cancel: cancel, apps: make(map[string]*cachedApp)}
+}
+
+// Fetch 返回应用的实例，返回的数据为缓存共享，调用方不能修改。
+// 应用首次获取时同步请求注册中心，失败时直接返回错误且不缓存；
+// 之后刷新失败超过 MaxStale 时返回包装了最近一次错误的 ErrStale
+func (cc *Cache) Fetch(ctx context.Context, appid string) (*registry.FetchData, error) {
+	cc.lock.RLock()
+	a, ok := cc.apps[appid]
+	var data *registry.FetchData
+	var syncedAt time.Time
+	var lastErr, goneErr error
+	if ok {
+		data, syncedAt, lastErr, goneErr = a.data, a.syncedAt, a.err, a.gone
+	}
+	cc.lock.RUnlock()
+	if ok {
+		if goneErr != nil {
+			return nil, goneErr
+		}
+		if lastErr != nil && cc.cfg.MaxStale > 0 && time.Since(syncedAt) > cc.cfg.MaxStale {
+			return nil, fmt.Errorf("%w: %v", ErrStale, lastErr)
+		}
+		return data, nil
+	}
+	if err := cc.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := cc.c.Fetch(ctx, cc.cfg.Env, appid, 0)
+	if err != nil {
+		return nil, err
+	}
+	cc.lock.Lock()
+	if a, ok := cc.apps[appid]; ok {
+		// 并发的首次获取已经开始刷新
+		data = a.data
+		cc.lock.Unlock()
+		return data, nil
+	}
+	cc.apps[appid] = &cachedApp{data: data, syncedAt: time.Now()}
+	cc.wg.Add(1)
+	cc.lock.Unlock()
+	go cc.refresh(appid, data.LatestTimestamp)
+	return data, nil
+}
+
+// refresh 长轮询刷新应用，直到 Close
+func (cc *Cache) refresh(appid string, latest int64) {
+	defer cc.wg.Done()
+	backoff := cc.cfg.RetryInterval
+	for {
+		data, err := cc.c.LongPoll(cc.ctx, cc.cfg.Env, appid, latest, cc.cfg.PollTimeout)
+		if cc.ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err == nil:
+			latest = data.LatestTimestamp
+			cc.update(appid, data, nil)
+		case errors.Is(err, registry.ErrNotModified):
+			cc.update(appid, nil, nil)
+		default:
+			if gone(err) {
+				// 不再返回旧数据，应用恢复后重新全量获取
+				latest = 0
+			}
+			cc.update(appid, nil, err)
+			wait := backoff
+			var se *StatusError
+			if errors.As(err, &se) && se.RetryAfter > wait {
+				wait = se.RetryAfter
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			select {
+			case <-time.After(wait):
+			case <-cc.ctx.Done():
+				return
+			}
+			continue
+		}
+		backoff = cc.cfg.RetryInterval
+	}
+}
+
+// update 记录一次刷新结果，data 为空表示数据没有变化
+func (cc *Cache) update(appid string, data *registry.FetchData, err error) {
+	cc.lock.Lock()
+	defer cc.lock.Unlock()
+	a := cc.apps[appid]
+	switch {
+	case gone(err):
+		a.err, a.gone, a.syncedAt = nil, err, time.Now()
+	case err != nil:
+		a.err = err
+	default:
+		if data != nil {
+			a.data = data
+		}
+		a.err, a.gone, a.syncedAt = nil, nil, time.Now()
+	}
+}
+
+// gone 注册中心是否明确回答了应用不存在或没有实例
+func gone(err error) bool {
+	return errors.Is(err, registry.ErrAppNotFound) || errors.Is(err, registry.ErrNoInstances)
+}
+
+// Status 返回缓存中各应用的状态
+func (cc *Cache) Status() []CacheStatus {
+	cc.lock.RLock()
+	defer cc.lock.RUnlock()
+	rs := make([]CacheStatus, 0, len(cc.apps))
+	for appid, a := range cc.apps {
+		rs = append(rs, CacheStatus{AppId: appid, LatestTimestamp: a.data.LatestTimestamp, SyncedAt: a.syncedAt, Err: a.err})
+		if a.gone != nil {
+			rs[len(rs)-1].Err = a.gone
+		}
+	}
+	return rs
+}
+
+// Close 停止后台刷新，之后只能获取已缓存的应用
+func (cc *Cache) Close() {
+	cc.cancel()
+	cc.wg.Wait()
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	r, c := newCluster(t)
+	register := func(hostname, addr string) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: hostname, Addrs: []string{addr}, Status: registry.StatusUP}), time.Now().UnixNano())
+	}
+	register("a", "http://127.0.0.1:80")
+	cache := c.NewCache(CacheConfig{Env: "test", PollTimeout: 100 * time.Millisecond, RetryInterval: 10 * time.Millisecond, MaxStale: 300 * time.Millisecond})
+	defer cache.Close()
+
+	if _, err := cache.Fetch(ctx, "com.xx.none"); !errors.Is(err, registry.ErrAppNotFound) {
+		t.Fatalf("expect app not found, got %v", err)
+	}
+	d, err := cache.Fetch(ctx, "com.xx.app")
+	if err != nil || len(d.Instances) != 1 {
+		t.Fatalf("fetch failed: %+v %v", d, err)
+	}
+	// 后台长轮询发现新实例
+	register("b", "http://127.0.0.1:81")
+	deadline := time.Now().Add(2 * time.Second)
+	for d, _ = cache.Fetch(ctx, "com.xx.app"); len(d.Instances) != 2; d, _ = cache.Fetch(ctx, "com.xx.app") {
+		if time.Now().After(deadline) {
+			t.Fatal("cache should refresh in background")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 注册中心不可达时在 MaxStale 内返回旧数据，之后返回 ErrStale
+	dead := httptest.NewServer(nil)
+	dead.Close()
+	c.lock.Lock()
+	c.nodes = []string{dead.URL}
+	c.lock.Unlock()
+	if d, err = cache.Fetch(ctx, "com.xx.app"); err != nil || len(d.Instances) != 2 {
+		t.Fatalf("expect stale data, got %+v %v", d, err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for _, err = cache.Fetch(ctx, "com.xx.app"); !errors.Is(err, ErrStale); _, err = cache.Fetch(ctx, "com.xx.app") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect stale error, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if st := cache.Status(); len(st) != 1 || st[0].Err == nil {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
@@ -0,0 +1,410 @@
+// Package client 注册中心 HTTP 接口的客户端
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+// Version 客户端 SDK 版本，随请求发送给注册中心用于记录注册来源
+const Version = "registry-go/0.1.0"
+
+// Config 单个注册中心集群的客户端配置
+type Config struct {
+	Nodes      []string     // 集群节点地址，如 http://10.0.0.1:7171，请求失败时依次尝试下一个节点
+	HTTPClient *http.Client // 为空时使用 10 秒超时的默认客户端
+	Agent      bool         // 是否作为节点代理代其他服务注册
+	Protobuf   bool         // Fetch、FetchPage、LongPoll 请求 protobuf 编码的响应，实例较多时减小报文和解码开销
+	Token      string       // 只读令牌，设置后只能调用读接口并只能读取令牌范围内的应用，见 registry.IssueToken；也可以是管理令牌
+	DNS        *DNSFallback // 所有节点都不可达时 Fetch 改用 DNS 解析，为空时不启用
+}
+
+// Client 单个注册中心集群的客户端
+type Client struct {
+	lock     sync.RWMutex
+	nodes    []string
+	next     uint32 // 下次请求的起始节点，请求在节点间轮转
+	http     *http.Client
+	agent    bool
+	protobuf bool
+	token    string
+	dns      *DNSFallback
+}
+
+// New 创建客户端
+func New(cfg Config) (*Client, error) {
+	if len(cfg.Nodes) == 0 {
+		return nil, errors.New("no registry nodes")
+	}
+	c := &Client{http: cfg.HTTPClient, agent: cfg.Agent, protobuf: cfg.Protobuf, token: cfg.Token, dns: cfg.DNS}
+	for _, n := range cfg.Nodes {
+		c.nodes = append(c.nodes, strings.TrimRight(n, "/"))
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 10 * time.Second}
+	}
+	return c, nil
+}
+
+// StatusError 注册中心返回的错误响应
+type StatusError struct {
+	Code       int
+	Message    string
+	RetryAfter time.Duration       // 限流或过载时服务端建议的重试等待时长
+	Info       *registry.ErrorInfo // 机器可读的错误，旧版本服务端没有
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("registry: %d %s", e.Code, e.Message)
+}
+
+// Unwrap 按错误码还原注册中心的哨兵错误，如 errors.Is(err, registry.ErrInstanceNotFound)
+func (e *StatusError) Unwrap() error {
+	if e.Info == nil {
+		return nil
+	}
+	return e.Info.Err()
+}
+
+// Retryable 原样重试是否可能成功，旧版本服务端按状态码判断
+func (e *StatusError) Retryable() bool {
+	if e.Info != nil {
+		return e.Info.Retryable
+	}
+	return e.Code == http.StatusTooManyRequests || e.Code >= 500
+}
+
+// Register 服务注册，开启续约凭证时返回凭证
+func (c *Client) Register(ctx context.Context, req *registry.RequestRegister) (*server.RegisterReply, error) {
+	form := url.Values{
+		"env":      {req.Env},
+		"appid":    {req.AppId},
+		"hostname": {req.Hostname},
+		"addrs":    req.Addrs,
+		"version":  {req.Version},
+		"group":    {req.Group},
+	}
+	if len(req.SecureAddrs) > 0 {
+		form["secure_addrs"] = req.SecureAddrs
+	}
+	if req.Status != 0 {
+		form.Set("status", strconv.FormatUint(uint64(req.Status), 10))
+	}
+	if req.Weight != 0 {
+		form.Set("weight", strconv.FormatUint(uint64(req.Weight), 10))
+	}
+	if len(req.Metadata) > 0 {
+		md, _ := json.Marshal(req.Metadata)
+		form.Set("metadata", string(md))
+	}
+	var reply server.RegisterReply
+	if err := c.do(ctx, http.MethodPost, server.PathRegister, form, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// Renew 服务续约，secret 为空时不校验凭证
+func (c *Client) Renew(ctx context.Context, env, appid, hostname, secret string) (*server.RenewReply, error) {
+	form := url.Values{"env": {env}, "appid": {appid}, "hostname": {hostname}}
+	if secret != "" {
+		form.Set("secret", secret)
+	}
+	var reply server.RenewReply
+	if err := c.do(ctx, http.MethodPost, server.PathRenew, form, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// RenewWithCapacity 服务续约并上报实例当前的容量
+func (c *Client) RenewWithCapacity(ctx context.Context, env, appid, hostname, secret string, capacity registry.Capacity) (*server.RenewReply, error) {
+	form := url.Values{
+		"env":            {env},
+		"appid":          {appid},
+		"hostname":       {hostname},
+		"max_concurrent": {strconv.FormatInt(capacity.MaxConcurrent, 10)},
+		"in_flight":      {strconv.FormatInt(capacity.InFlight, 10)},
+	}
+	if capacity.Utilization > 0 {
+		form.Set("utilization", strconv.FormatFloat(capacity.Utilization, 'f', -1, 64))
+	}
+	if secret != "" {
+		form.Set("secret", secret)
+	}
+	var reply server.RenewReply
+	if err := c.do(ctx, http.MethodPost, server.PathRenew, form, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// Capacity 获取应用的容量汇总，判断应用是否还有余量
+func (c *Client) Capacity(ctx context.Context, env, appid string) (*registry.AppCapacity, error) {
+	var rs registry.AppCapacity
+	if err := c.do(ctx, http.MethodGet, server.PathCapacity, url.Values{"env": {env}, "appid": {appid}}, &rs); err != nil {
+		return nil, err
+	}
+	return &rs, nil
+}
+
+// Cancel 服务下线
+func (c *Client) Cancel(ctx context.Context, env, appid, hostname string) error {
+	form := url.Values{"env": {env}, "appid": {appid}, "hostname": {hostname}}
+	return c.do(ctx, http.MethodPost, server.PathCancel, form, nil)
+}
+
+// Fetch 服务获取，数据没有变化时返回 registry.ErrNotModified。
+// 配置了 DNS 兜底时，所有节点都不可达则返回 DNS 解析的实例
+func (c *Client) Fetch(ctx context.Context, env, appid string, latestTimestamp int64) (*registry.FetchData, error) {
+	form := url.Values{"env": {env}, "appid": {appid}}
+	if latestTimestamp > 0 {
+		form.Set("latest_timestamp", strconv.FormatInt(latestTimestamp, 10))
+	}
+	var data registry.FetchData
+	if err := c.do(ctx, http.MethodGet, server.PathFetch, form, &data); err != nil {
+		return c.fetchDNS(ctx, env, appid, err)
+	}
+	return &data, nil
+}
+
+// FetchPage 分页获取服务，cursor 为上一页返回的 NextCursor，第一页为空
+func (c *Client) FetchPage(ctx context.Context, env, appid string, limit int, cursor string) (*registry.FetchData, error) {
+	form := url.Values{"env": {env}, "appid": {appid}, "limit": {strconv.Itoa(limit)}}
+	if cursor != "" {
+		form.Set("cursor", cursor)
+	}
+	var data registry.FetchData
+	if err := c.do(ctx, http.MethodGet, server.PathFetch, form, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// LongPoll 长轮询获取服务，数据在 timeout 内没有变化时返回 registry.ErrNotModified。
+// HTTPClient 的超时需要大于 timeout
+func (c *Client) LongPoll(ctx context.Context, env, appid string, latestTimestamp int64, timeout time.Duration) (*registry.FetchData, error) {
+	form := url.Values{"env": {env}, "appid": {appid}, "timeout": {timeout.String()}}
+	if latestTimestamp > 0 {
+		form.Set("latest_timestamp", strconv.FormatInt(latestTimestamp, 10))
+	}
+	var data registry.FetchData
+	if err := c.do(ctx, http.MethodGet, server.PathPoll, form, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// FetchDelta 增量获取应用实例，revision 为上次返回的 DeltaData.Revision，首次获取传 0
+func (c *Client) FetchDelta(ctx context.Context, env, appid string, revision uint64) (*registry.DeltaData, error) {
+	form := url.Values{"env": {env}, "appid": {appid}, "revision": {strconv.FormatUint(revision, 10)}}
+	var data registry.DeltaData
+	if err := c.do(ctx, http.MethodGet, server.PathFetchDelta, form, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// FetchAll 获取环境下所有应用的实例，key 为 appId
+func (c *Client) FetchAll(ctx context.Context, env string) (map[string][]*registry.Instance, error) {
+	var all map[string][]*registry.Instance
+	if err := c.do(ctx, http.MethodGet, server.PathFetchAll, url.Values{"env": {env}}, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// FetchAllPage 分页获取环境下所有应用的实例
+func (c *Client) FetchAllPage(ctx context.Context, env string, limit int, cursor string) (*registry.FetchAllData, error) {
+	form := url.Values{"env": {env}, "limit": {strconv.Itoa(limit)}, "cursor": {cursor}}
+	var page registry.FetchAllData
+	if err := c.do(ctx, http.MethodGet, server.PathFetchAll, form, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// FetchBatch 一次获取多个应用的实例，只返回相对 latestTimestamps 有变化的应用
+func (c *Client) FetchBatch(ctx context.Context, env string, appids []string, latestTimestamps map[string]int64) (map[string]*registry.FetchData, error) {
+	form := url.Values{"env": {env}, "appid": appids}
+	for _, appid := range appids {
+		form.Add("latest_timestamp", strconv.FormatInt(latestTimestamps[appid], 10))
+	}
+	var rs map[string]*registry.FetchData
+	if err := c.do(ctx, http.MethodGet, server.PathFetchBatch, form, &rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// FetchAt 获取应用在过去某一时刻的实例列表，超出注册中心事件日志的保留范围时返回 registry.ErrEventsTruncated
+func (c *Client) FetchAt(ctx context.Context, env, appid string, at time.Time) (*registry.HistoryData, error) {
+	form := url.Values{"env": {env}, "appid": {appid}, "at": {strconv.FormatInt(at.UnixNano(), 10)}}
+	var data registry.HistoryData
+	if err := c.do(ctx, http.MethodGet, server.PathHistory, form, &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Nodes 获取注册中心集群的所有节点
+func (c *Client) Nodes(ctx context.Context) ([]registry.Node, error) {
+	var nodes []registry.Node
+	if err := c.do(ctx, http.MethodGet, server.PathNodes, url.Values{}, &nodes); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Snapshot 获取注册表的全量快照
+func (c *Client) Snapshot(ctx context.Context) (*registry.Snapshot, error) {
+	var s registry.Snapshot
+	if err := c.do(ctx, http.MethodGet, server.PathSnapshot, url.Values{}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FetchSnapshot 获取指定节点的快照，不在节点间轮转。Client 可用作 registry.WithSnapshotFetcher，
+// 此时应配置对端的管理员身份，否则快照不包含受限应用
+func (c *Client) FetchSnapshot(ctx context.Context, peer registry.Node) (*registry.Snapshot, error) {
+	var s registry.Snapshot
+	if _, err := c.doNode(ctx, strings.TrimRight(peer.Addr, "/"), http.MethodGet, server.PathSnapshot, url.Values{}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Reconcile 让注册中心节点与对端 peer 对账，peer 为节点 ID 或地址，需要将 Token 配置为管理令牌
+func (c *Client) Reconcile(ctx context.Context, peer string) (*registry.ReconcileReport, error) {
+	var rp registry.ReconcileReport
+	if err := c.do(ctx, http.MethodGet, server.PathReconcile, url.Values{"peer": {peer}}, &rp); err != nil {
+		return nil, err
+	}
+	return &rp, nil
+}
+
+// RefreshNodes 从集群获取节点列表，之后的请求在所有非 down 的节点间轮转。
+// 客户端只需配置任一节点即可启动，定期调用以跟随集群扩缩容
+func (c *Client) RefreshNodes(ctx context.Context) error {
+	nodes, err := c.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	var addrs []string
+	for _, n := range nodes {
+		if n.Addr != "" && n.Status != registry.NodeDown {
+			addrs = append(addrs, strings.TrimRight(n.Addr, "/"))
+		}
+	}
+	if len(addrs) == 0 {
+		return errors.New("no available registry nodes")
+	}
+	c.lock.Lock()
+	c.nodes = addrs
+	c.lock.Unlock()
+	return nil
+}
+
+// do 从轮转到的节点开始依次发送请求，只有网络错误或 5xx 时才尝试下一个节点
+func (c *Client) do(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	c.lock.RLock()
+	nodes := c.nodes
+	c.lock.RUnlock()
+	start := int(atomic.AddUint32(&c.next, 1) % uint32(len(nodes)))
+	var err error
+	for i := range nodes {
+		node := nodes[(start+i)%len(nodes)]
+		var retry bool
+		if retry, err = c.doNode(ctx, node, method, path, form, out); !retry {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+func (c *Client) doNode(ctx context.Context, node, method, path string, form url.Values, out interface{}) (bool, error) {
+	var req *http.Request
+	var err error
+	if method == http.MethodGet {
+		req, err = http.NewRequestWithContext(ctx, method, node+path+"?"+form.Encode(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, node+path, strings.NewReader(form.Encode()))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	if id := registry.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(server.HeaderRequestID, id)
+	}
+	req.Header.Set(server.HeaderClient, Version)
+	// 按 v1 结构解码实例，服务端切换默认结构不影响已有客户端
+	req.Header.Set(server.HeaderInstanceSchema, registry.InstanceSchemaV1)
+	if c.agent {
+		req.Header.Set(server.HeaderAgent, "true")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	data, fetch := out.(*registry.FetchData)
+	if fetch && c.protobuf {
+		req.Header.Set("Accept", registry.ContentTypeProtobuf+", application/json;q=0.9")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return false, registry.ErrNotModified
+	}
+	if fetch && resp.StatusCode == http.StatusOK && resp.Header.Get("Content-Type") == registry.ContentTypeProtobuf {
+		return false, decodeProtobuf(resp.Body, data)
+	}
+	var body server.Response
+	body.Data = out
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return resp.StatusCode >= 500, fmt.Errorf("registry: decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &StatusError{Code: resp.StatusCode, Message: body.Message, Info: body.Error}
+		if v, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.RetryAfter = time.Duration(v) * time.Second
+		}
+		return resp.StatusCode >= 500, se
+	}
+	return false, nil
+}
+
+// decodeProtobuf 解码 protobuf 编码的实例列表
+func decodeProtobuf(r io.Reader, out *registry.FetchData) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	data, err := registry.UnmarshalFetchData(b)
+	if err != nil {
+		return fmt.Errorf("registry: decode response: %w", err)
+	}
+	*out = *data
+	return nil
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+func newCluster(t *testing.T) (*registry.Registry, *Client) {
+	t.Helper()
+	r := registry.NewRegistry()
+	srv := httptest.NewServer(server.NewHTTPServer(r, server.ServerConfig{}))
+	t.Cleanup(func() {
+		srv.Close()
+		r.Close()
+	})
+	c, err := New(Config{Nodes: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, c
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	_, c := newCluster(t)
+	dead := httptest.NewServer(nil)
+	dead.Close()
+	c.nodes = append([]string{dead.URL}, c.nodes...)
+
+	req := &registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Metadata: map[string]string{"zone": "z1"}}
+	reply, err := c.Register(ctx, req)
+	if err != nil || reply.Instance.Hostname != "a" {
+		t.Fatalf("register failed: %+v %v", reply, err)
+	}
+	if src := reply.Instance.Source; src == nil || src.Kind != registry.SourceDirect || src.SDKVersion != Version || src.ClientIP != "127.0.0.1" {
+		t.Fatalf("unexpected source %+v", src)
+	}
+	if _, err := c.Renew(ctx, "test", "com.xx.app", "a", ""); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Fetch(ctx, "test", "com.xx.app", 0)
+	if err != nil || len(data.Instances) != 1 || data.Instances[0].Metadata["zone"] != "z1" {
+		t.Fatalf("unexpected fetch %+v %v", data, err)
+	}
+	if _, err := c.Fetch(ctx, "test", "com.xx.app", data.LatestTimestamp); !errors.Is(err, registry.ErrNotModified) {
+		t.Fatalf("expect not modified, got %v", err)
+	}
+	pc, _ := New(Config{Nodes: c.nodes, Protobuf: true})
+	if pd, err := pc.Fetch(ctx, "test", "com.xx.app", 0); err != nil || len(pd.Instances) != 1 || pd.Instances[0].Metadata["zone"] != "z1" {
+		t.Fatalf("unexpected protobuf fetch %+v %v", pd, err)
+	}
+	if d, err := c.FetchDelta(ctx, "test", "com.xx.app", 0); err != nil || !d.Full || len(d.Changes) != 1 || d.Changes[0].Action != registry.DeltaAdd {
+		t.Fatalf("unexpected delta %+v %v", d, err)
+	}
+	if h, err := c.FetchAt(ctx, "test", "com.xx.app", time.Now()); err != nil || len(h.Instances) != 1 || h.Instances[0].Hostname != "a" {
+		t.Fatalf("unexpected history %+v %v", h, err)
+	}
+	if all, err := c.FetchAll(ctx, "test"); err != nil || len(all["com.xx.app"]) != 1 {
+		t.Fatalf("unexpected fetch all %v %v", all, err)
+	}
+	if page, err := c.FetchAllPage(ctx, "test", 1, ""); err != nil || page.Total != 1 || len(page.Apps["com.xx.app"]) != 1 || page.NextCursor != "" {
+		t.Fatalf("unexpected fetch all page %+v %v", page, err)
+	}
+	if page, err := c.FetchPage(ctx, "test", "com.xx.app", 1, ""); err != nil || page.Total != 1 || len(page.Instances) != 1 {
+		t.Fatalf("unexpected fetch page %+v %v", page, err)
+	}
+	if rs, err := c.FetchBatch(ctx, "test", []string{"com.xx.app"}, map[string]int64{"com.xx.app": data.LatestTimestamp}); err != nil || len(rs) != 0 {
+		t.Fatalf("unchanged app should be omitted, got %v %v", rs, err)
+	}
+	if err := c.Cancel(ctx, "test", "com.xx.app", "a"); err != nil {
+		t.Fatal(err)
+	}
+	var se *StatusError
+	if _, err := c.Renew(ctx, "test", "com.xx.app", "a", ""); !errors.As(err, &se) || se.Code != 404 {
+		t.Fatalf("expect 404 after cancel, got %v", err)
+	}
+	if _, err := c.Renew(ctx, "test", "com.xx.app", "a", ""); !errors.Is(err, registry.ErrAppNotFound) || se.Retryable() {
+		t.Fatalf("expect app not found from error code, got %v", err)
+	}
+}
+
+func TestRefreshNodes(t *testing.T) {
+	ctx := context.Background()
+	r, c := newCluster(t)
+	live := c.nodes[0]
+	r.SetPeers([]registry.Node{{ID: "n2", Addr: "http://127.0.0.1:1"}})
+	r.ObserveReplication("n2", 0, 0, errors.New("refused"))
+
+	// 本节点没有配置对外地址时不会出现在列表中，只剩 down 的对端
+	if err := c.RefreshNodes(ctx); err == nil {
+		t.Fatal("expect error without available nodes")
+	}
+	nodes, err := c.Nodes(ctx)
+	if err != nil || len(nodes) != 2 || !nodes[0].Self || nodes[1].Status != registry.NodeDown {
+		t.Fatalf("unexpected nodes %+v %v", nodes, err)
+	}
+	r.SetPeers([]registry.Node{{ID: "n3", Addr: live + "/"}})
+	if err := c.RefreshNodes(ctx); err != nil || len(c.nodes) != 1 || c.nodes[0] != live {
+		t.Fatalf("unexpected nodes %v %v", c.nodes, err)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	ctx := context.Background()
+	r, c := newCluster(t)
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.cap", Hostname: "a", Status: registry.StatusUP}, 1)
+	if _, err := c.RenewWithCapacity(ctx, "test", "com.xx.cap", "a", "", registry.Capacity{MaxConcurrent: 10, InFlight: 9}); err != nil {
+		t.Fatal(err)
+	}
+	if rs, err := c.Capacity(ctx, "test", "com.xx.cap"); err != nil || rs.Reporting != 1 || rs.Utilization != 0.9 || rs.Headroom {
+		t.Fatalf("unexpected capacity %+v %v", rs, err)
+	}
+}
+
+func TestLongPoll(t *testing.T) {
+	ctx := context.Background()
+	r, c := newCluster(t)
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.poll", Hostname: "a", Status: registry.StatusUP}, 1)
+	data, err := c.LongPoll(ctx, "test", "com.xx.poll", 0, time.Second)
+	if err != nil || len(data.Instances) != 1 {
+		t.Fatalf("unexpected poll %+v %v", data, err)
+	}
+	if _, err := c.LongPoll(ctx, "test", "com.xx.poll", data.LatestTimestamp, 20*time.Millisecond); !errors.Is(err, registry.ErrNotModified) {
+		t.Fatalf("expect not modified, got %v", err)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	peer, pc := newCluster(t)
+	fetcher, _ := New(Config{Nodes: []string{"http://127.0.0.1:1"}})
+	r := registry.NewRegistry(registry.WithSnapshotFetcher(fetcher))
+	r.SetPeers([]registry.Node{{ID: "n2", Addr: pc.nodes[0]}})
+	srv := httptest.NewServer(server.NewHTTPServer(r, server.ServerConfig{Admin: &server.AdminConfig{Token: "s3cret"}}))
+	defer srv.Close()
+	defer r.Close()
+	anonymous, _ := New(Config{Nodes: []string{srv.URL}})
+	if _, err := anonymous.Reconcile(ctx, "n2"); !errors.Is(err, registry.ErrUnauthenticated) {
+		t.Fatalf("reconcile should require admin auth, got %v", err)
+	}
+	c, _ := New(Config{Nodes: []string{srv.URL}, Token: "s3cret"})
+
+	peer.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	rp, err := c.Reconcile(ctx, "n2")
+	if err != nil || rp.Consistent || len(rp.Apps) != 1 || len(rp.Apps[0].OnlyPeer) != 1 {
+		t.Fatalf("unexpected report %+v %v", rp, err)
+	}
+	if _, err := c.Reconcile(ctx, "n3"); !errors.Is(err, registry.ErrPeerNotFound) {
+		t.Fatalf("expect peer not found, got %v", err)
+	}
+}
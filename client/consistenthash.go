@@ -0,0 +1,133 @@
+package client
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// KeyPicker 按键选取实例的负载均衡策略，同一个键尽量落到同一个实例
+type KeyPicker interface {
+	Picker
+	PickByKey(candidates []*registry.Instance, key string) *registry.Instance
+}
+
+// ConsistentHash 有界负载的一致性哈希，用于按键路由到有状态的后端以提高缓存命中。
+// 实例增减时只有少量键迁移；键落到的实例进行中的请求数超过平均值的 LoadFactor 倍时顺延到环上的下一个实例，
+// 避免热点键或实例下线后的键集中压垮单个实例。需要通过 Done 反馈请求结束
+type ConsistentHash struct {
+	Replicas   int     // 每个实例在环上的虚拟节点数，默认 100
+	LoadFactor float64 // 单个实例进行中的请求数上限为平均值的倍数，默认 1.25，需大于 1
+
+	lock    sync.Mutex
+	members string // 环对应的实例集合
+	ring    []ringNode
+	load    map[string]int // key: hostname，进行中的请求数
+	total   int
+	next    uint64
+}
+
+type ringNode struct {
+	hash     uint64
+	hostname string
+}
+
+// Pick 没有键时依次使用递增的序号作为键
+func (p *ConsistentHash) Pick(candidates []*registry.Instance) *registry.Instance {
+	p.lock.Lock()
+	p.next++
+	key := strconv.FormatUint(p.next, 10)
+	p.lock.Unlock()
+	return p.PickByKey(candidates, key)
+}
+
+// PickByKey 选取键对应的实例，并计入一个进行中的请求
+func (p *ConsistentHash) PickByKey(candidates []*registry.Instance, key string) *registry.Instance {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	byHost := p.rebuild(candidates)
+	factor := p.LoadFactor
+	if factor <= 1 {
+		factor = 1.25
+	}
+	limit := int(math.Ceil(factor * float64(p.total+1) / float64(len(byHost))))
+	h := hashKey(key)
+	start := sort.Search(len(p.ring), func(i int) bool { return p.ring[i].hash >= h })
+	for i := 0; i < len(p.ring); i++ {
+		node := p.ring[(start+i)%len(p.ring)]
+		if p.load[node.hostname] < limit {
+			p.load[node.hostname]++
+			p.total++
+			return byHost[node.hostname]
+		}
+	}
+	// 上限不小于平均值，不会所有实例都超过，这里只是兜底
+	return candidates[0]
+}
+
+// Done 请求结束，释放实例的负载
+func (p *ConsistentHash) Done(in *registry.Instance, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.load[in.Hostname] > 0 {
+		p.load[in.Hostname]--
+		p.total--
+	}
+}
+
+// rebuild 实例集合变化时重建哈希环，下线实例的负载不再计入
+func (p *ConsistentHash) rebuild(candidates []*registry.Instance) map[string]*registry.Instance {
+	byHost := make(map[string]*registry.Instance, len(candidates))
+	hosts := make([]string, 0, len(candidates))
+	for _, in := range candidates {
+		if _, ok := byHost[in.Hostname]; !ok {
+			hosts = append(hosts, in.Hostname)
+		}
+		byHost[in.Hostname] = in
+	}
+	sort.Strings(hosts)
+	members := strings.Join(hosts, "\x00")
+	if members == p.members {
+		return byHost
+	}
+	replicas := p.Replicas
+	if replicas <= 0 {
+		replicas = 100
+	}
+	p.members = members
+	p.ring = make([]ringNode, 0, len(hosts)*replicas)
+	for _, host := range hosts {
+		for i := 0; i < replicas; i++ {
+			p.ring = append(p.ring, ringNode{hashKey(host + "#" + strconv.Itoa(i)), host})
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool { return p.ring[i].hash < p.ring[j].hash })
+	load := make(map[string]int, len(hosts))
+	p.total = 0
+	for _, host := range hosts {
+		if n := p.load[host]; n > 0 {
+			load[host] = n
+			p.total += n
+		}
+	}
+	p.load = load
+	return byHost
+}
+
+// hashKey FNV-1a 后再做一次混合，相近的键（如 host#1、host#2）在环上也能分散开
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
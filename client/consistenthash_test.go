@@ -0,0 +1,70 @@
+package client
+
+import (
+	"strconv"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestConsistentHash(t *testing.T) {
+	var instances []*registry.Instance
+	for i := 0; i < 5; i++ {
+		instances = append(instances, &registry.Instance{Hostname: "host-" + strconv.Itoa(i)})
+	}
+	p := &ConsistentHash{}
+	pick := func(candidates []*registry.Instance, key string) string {
+		in := p.PickByKey(candidates, key)
+		p.Done(in, nil)
+		return in.Hostname
+	}
+	before := make(map[string]string)
+	count := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := "user-" + strconv.Itoa(i)
+		before[key] = pick(instances, key)
+		count[before[key]]++
+		if pick(instances, key) != before[key] {
+			t.Fatalf("key %s should stick to %s", key, before[key])
+		}
+	}
+	for host, n := range count {
+		if n < 100 || n > 300 {
+			t.Fatalf("keys unevenly spread: %s has %d", host, n)
+		}
+	}
+
+	// 下线一个实例，只有原本落在该实例上的键迁移
+	remaining := append([]*registry.Instance{}, instances[:4]...)
+	for key, host := range before {
+		if got := pick(remaining, key); host != "host-4" && got != host {
+			t.Fatalf("key %s moved from %s to %s", key, host, got)
+		}
+	}
+
+	// 同一个键的并发请求超过负载上限后顺延到其他实例
+	load := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		load[p.PickByKey(instances, "hot").Hostname]++
+	}
+	for host, n := range load {
+		// 上限为 ceil(1.25 * 20 / 5) = 5
+		if n > 5 {
+			t.Fatalf("%s has %d in-flight requests over the bound", host, n)
+		}
+	}
+	if len(load) < 4 {
+		t.Fatalf("hot key should spill over, got %v", load)
+	}
+
+	// 复用同一个底层数组换成其他实例时不能沿用旧的哈希环
+	buf := make([]*registry.Instance, 2)
+	copy(buf, instances[:2])
+	pick(buf, "user-1")
+	buf[0], buf[1] = &registry.Instance{Hostname: "new-0"}, &registry.Instance{Hostname: "new-1"}
+	for i := 0; i < 100; i++ {
+		if got := pick(buf, "user-"+strconv.Itoa(i)); got != "new-0" && got != "new-1" {
+			t.Fatalf("key routed to removed instance %s", got)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// SourceDNS DNS 兜底解析得到的实例的来源
+const SourceDNS = "dns"
+
+// DNSResolver DNS 查询，*net.Resolver 实现了该接口
+type DNSResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DNSFallback 注册中心所有节点都不可达时按 DNS 解析应用实例，控制面故障期间服务间调用仍能发现对端。
+// 先查询 SRV 记录，没有时查询 A/AAAA 记录并使用 Port
+type DNSFallback struct {
+	Domain   string                         // 应用的域名为 <appid>.<env>.<Domain>
+	Name     func(env, appid string) string // 自定义应用的域名，设置后忽略 Domain
+	Port     int                            // A/AAAA 记录使用的端口
+	Scheme   string                         // 实例地址的 scheme，如 http、grpc，为空时地址为 host:port
+	Resolver DNSResolver                    // 为空时使用 net.DefaultResolver
+}
+
+func (f *DNSFallback) name(env, appid string) string {
+	if f.Name != nil {
+		return f.Name(env, appid)
+	}
+	return appid + "." + env + "." + strings.TrimPrefix(f.Domain, ".")
+}
+
+// Lookup 按 DNS 解析应用的实例，实例状态均为 UP，来源为 SourceDNS。没有记录时返回 registry.ErrNoInstances
+func (f *DNSFallback) Lookup(ctx context.Context, env, appid string) (*registry.FetchData, error) {
+	res := f.Resolver
+	if res == nil {
+		res = net.DefaultResolver
+	}
+	name := f.name(env, appid)
+	type target struct {
+		host   string
+		port   int
+		weight uint32
+	}
+	var targets []target
+	if _, srvs, err := res.LookupSRV(ctx, "", "", name); err == nil {
+		for _, s := range srvs {
+			targets = append(targets, target{strings.TrimSuffix(s.Target, "."), int(s.Port), uint32(s.Weight)})
+		}
+	}
+	if len(targets) == 0 {
+		if f.Port <= 0 {
+			return nil, registry.ErrNoInstances
+		}
+		hosts, err := res.LookupHost(ctx, name)
+		var de *net.DNSError
+		if errors.As(err, &de) && de.IsNotFound {
+			return nil, registry.ErrNoInstances
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hosts {
+			targets = append(targets, target{h, f.Port, 0})
+		}
+	}
+	if len(targets) == 0 {
+		return nil, registry.ErrNoInstances
+	}
+	data := &registry.FetchData{}
+	for _, t := range targets {
+		hostport := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+		addr := hostport
+		if f.Scheme != "" {
+			addr = f.Scheme + "://" + hostport
+		}
+		weight := t.weight
+		if weight == 0 {
+			weight = registry.DefaultWeight
+		}
+		data.Instances = append(data.Instances, &registry.Instance{
+			Env:      env,
+			AppId:    appid,
+			Hostname: hostport,
+			Addrs:    []string{addr},
+			Status:   registry.StatusUP,
+			Weight:   weight,
+			Source:   &registry.RegistrationSource{Kind: SourceDNS},
+		})
+	}
+	sort.Slice(data.Instances, func(i, j int) bool { return data.Instances[i].Hostname < data.Instances[j].Hostname })
+	return data, nil
+}
+
+// unreachable 所有节点都没有响应，注册中心返回的错误不算
+func unreachable(ctx context.Context, err error) bool {
+	var se *StatusError
+	return err != nil && ctx.Err() == nil && !errors.As(err, &se) && !errors.Is(err, registry.ErrNotModified)
+}
+
+// fetchDNS 注册中心不可达时改用 DNS 兜底解析，没有配置或解析失败时返回原错误
+func (c *Client) fetchDNS(ctx context.Context, env, appid string, err error) (*registry.FetchData, error) {
+	if c.dns == nil || !unreachable(ctx, err) {
+		return nil, err
+	}
+	data, dnsErr := c.dns.Lookup(ctx, env, appid)
+	if dnsErr != nil {
+		log.Printf("registry unreachable (%v), dns fallback for %s/%s failed: %v", err, env, appid, dnsErr)
+		return nil, err
+	}
+	log.Printf("registry unreachable (%v), resolved %d instances of %s/%s by dns", err, len(data.Instances), env, appid)
+	return data, nil
+}
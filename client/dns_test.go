@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+type fakeResolver struct {
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+}
+
+func (f fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if srvs, ok := f.srv[name]; ok {
+		return name, srvs, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if hosts, ok := f.hosts[host]; ok {
+		return hosts, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestDNSFallback(t *testing.T) {
+	ctx := context.Background()
+	dead := httptest.NewServer(nil)
+	dead.Close()
+	res := fakeResolver{
+		srv:   map[string][]*net.SRV{"com.xx.a.test.svc.local": {{Target: "10.0.0.2.", Port: 9000, Weight: 10}, {Target: "10.0.0.1.", Port: 9000}}},
+		hosts: map[string][]string{"com.xx.b.test.svc.local": {"10.0.0.3"}},
+	}
+	c, _ := New(Config{Nodes: []string{dead.URL}, DNS: &DNSFallback{Domain: "svc.local", Port: 80, Scheme: "grpc", Resolver: res}})
+
+	d, err := c.Fetch(ctx, "test", "com.xx.a", 0)
+	if err != nil || len(d.Instances) != 2 || d.Instances[0].Addrs[0] != "grpc://10.0.0.1:9000" || d.Instances[0].Weight != registry.DefaultWeight ||
+		d.Instances[1].Weight != 10 || d.Instances[0].Source.Kind != SourceDNS {
+		t.Fatalf("unexpected srv fallback %+v %v", d, err)
+	}
+	if d, err = c.Fetch(ctx, "test", "com.xx.b", 0); err != nil || len(d.Instances) != 1 || d.Instances[0].Addrs[0] != "grpc://10.0.0.3:80" {
+		t.Fatalf("unexpected a record fallback %+v %v", d, err)
+	}
+	// 没有 DNS 记录时返回原来的网络错误
+	var se *StatusError
+	if _, err = c.Fetch(ctx, "test", "com.xx.c", 0); err == nil || errors.As(err, &se) {
+		t.Fatalf("expect network error, got %v", err)
+	}
+
+	// 注册中心有响应时不使用 DNS
+	_, live := newCluster(t)
+	live.dns = c.dns
+	if _, err := live.Fetch(ctx, "test", "com.xx.a", 0); !errors.Is(err, registry.ErrAppNotFound) {
+		t.Fatalf("expect registry answer, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// MergePolicy 多集群实例列表的合并方式
+type MergePolicy int
+
+const (
+	// MergeAll 合并所有集群的实例，同一实例（env+appid+hostname）在多个集群注册时以靠前的集群为准
+	MergeAll MergePolicy = iota
+	// PrimaryFallback 按顺序使用第一个有实例的集群，主集群不可用或没有该应用时才使用后面的集群
+	PrimaryFallback
+)
+
+// Cluster 一个独立的注册中心集群
+type Cluster struct {
+	Name   string
+	Client *Client
+}
+
+// FetchResult 多集群合并后的服务获取结果
+type FetchResult struct {
+	Instances []*registry.Instance `json:"instances"`
+	Sources   map[string]string    `json:"sources"` // 各实例来自的集群，key: hostname
+	Errors    map[string]error     `json:"-"`       // 获取失败的集群，key: 集群名
+}
+
+// MultiClient 同时连接多个注册中心集群的客户端，用于集群迁移或跨业务线调用，
+// 调用方不需要关心服务提供方注册在哪个集群
+type MultiClient struct {
+	clusters []Cluster // 第一个为主集群
+	policy   MergePolicy
+}
+
+// NewMulti 创建多集群客户端，clusters 按优先级排列，第一个为主集群
+func NewMulti(policy MergePolicy, clusters ...Cluster) (*MultiClient, error) {
+	if len(clusters) == 0 {
+		return nil, errors.New("no registry clusters")
+	}
+	return &MultiClient{clusters: clusters, policy: policy}, nil
+}
+
+// Fetch 并发获取各集群的实例并按合并方式合并，所有集群都失败或都没有实例时返回错误
+func (m *MultiClient) Fetch(ctx context.Context, env, appid string) (*FetchResult, error) {
+	datas := make([]*registry.FetchData, len(m.clusters))
+	errs := make([]error, len(m.clusters))
+	var wg sync.WaitGroup
+	for i, c := range m.clusters {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			datas[i], errs[i] = c.Fetch(ctx, env, appid, 0)
+		}(i, c.Client)
+	}
+	wg.Wait()
+
+	res := &FetchResult{Instances: make([]*registry.Instance, 0), Sources: make(map[string]string)}
+	var lastErr error
+	for i, c := range m.clusters {
+		if err := errs[i]; err != nil {
+			if !notFound(err) {
+				if res.Errors == nil {
+					res.Errors = make(map[string]error)
+				}
+				res.Errors[c.Name] = err
+				lastErr = err
+			}
+			continue
+		}
+		for _, in := range datas[i].Instances {
+			if _, ok := res.Sources[in.Hostname]; ok {
+				continue
+			}
+			res.Sources[in.Hostname] = c.Name
+			res.Instances = append(res.Instances, in)
+		}
+		if m.policy == PrimaryFallback && len(res.Instances) > 0 {
+			break
+		}
+	}
+	if len(res.Instances) == 0 {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, registry.ErrNoInstances
+	}
+	return res, nil
+}
+
+// notFound 集群中没有该应用或没有可用实例
+func notFound(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusNotFound
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestMultiClient(t *testing.T) {
+	primary, pc := newCluster(t)
+	secondary, sc := newCluster(t)
+	now := time.Now().UnixNano()
+	primary.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://10.0.0.1:80"}, Version: "new", Status: registry.StatusUP}), now)
+	secondary.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://10.0.0.1:80"}, Version: "old", Status: registry.StatusUP}), now)
+	secondary.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Addrs: []string{"http://10.0.0.2:80"}, Status: registry.StatusUP}), now)
+	secondary.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.legacy", Hostname: "c", Addrs: []string{"http://10.0.0.3:80"}, Status: registry.StatusUP}), now)
+	clusters := []Cluster{{Name: "primary", Client: pc}, {Name: "secondary", Client: sc}}
+
+	m, _ := NewMulti(MergeAll, clusters...)
+	res, err := m.Fetch(context.Background(), "test", "com.xx.app")
+	if err != nil || len(res.Instances) != 2 || res.Sources["a"] != "primary" || res.Sources["b"] != "secondary" || res.Instances[0].Version != "new" {
+		t.Fatalf("unexpected merged result %+v %v", res, err)
+	}
+
+	m, _ = NewMulti(PrimaryFallback, clusters...)
+	if res, _ = m.Fetch(context.Background(), "test", "com.xx.app"); len(res.Instances) != 1 {
+		t.Fatalf("primary instances should be preferred, got %+v", res)
+	}
+	// 主集群没有该应用时使用后面的集群
+	if res, err = m.Fetch(context.Background(), "test", "com.xx.legacy"); err != nil || res.Sources["c"] != "secondary" {
+		t.Fatalf("expect fallback to secondary, got %+v %v", res, err)
+	}
+}
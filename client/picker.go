@@ -0,0 +1,133 @@
+package client
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Picker 负载均衡策略，从候选实例中选取一个，候选为空时不会调用。实现需并发安全
+type Picker interface {
+	Pick(candidates []*registry.Instance) *registry.Instance
+}
+
+// Feedback 需要调用结果的 Picker 实现该接口，调用方在每次请求结束后调用 Done
+type Feedback interface {
+	Done(in *registry.Instance, err error)
+}
+
+// 内置的负载均衡策略
+const (
+	PickRoundRobin          = "round_robin"
+	PickRandom              = "random"
+	PickWeightedRandom      = "weighted_random"
+	PickLeastRecentlyFailed = "least_recently_failed"
+	PickConsistentHash      = "consistent_hash"
+)
+
+// NewPicker 按名称创建内置的负载均衡策略，名称未知时返回空
+func NewPicker(name string) Picker {
+	switch name {
+	case PickRoundRobin:
+		return &RoundRobin{}
+	case PickRandom:
+		return Random{}
+	case PickWeightedRandom:
+		return WeightedRandom{}
+	case PickLeastRecentlyFailed:
+		return &LeastRecentlyFailed{}
+	case PickConsistentHash:
+		return &ConsistentHash{}
+	}
+	return nil
+}
+
+// RoundRobin 轮询
+type RoundRobin struct {
+	next uint32
+}
+
+func (p *RoundRobin) Pick(candidates []*registry.Instance) *registry.Instance {
+	n := atomic.AddUint32(&p.next, 1) - 1
+	return candidates[n%uint32(len(candidates))]
+}
+
+// Random 随机
+type Random struct{}
+
+func (Random) Pick(candidates []*registry.Instance) *registry.Instance {
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// WeightedRandom 按实例权重随机，权重都为 0 时等概率随机
+type WeightedRandom struct{}
+
+func (WeightedRandom) Pick(candidates []*registry.Instance) *registry.Instance {
+	var total uint64
+	for _, in := range candidates {
+		total += uint64(in.Weight)
+	}
+	if total == 0 {
+		return Random{}.Pick(candidates)
+	}
+	n := uint64(rand.Int63n(int64(total)))
+	for _, in := range candidates {
+		if n < uint64(in.Weight) {
+			return in
+		}
+		n -= uint64(in.Weight)
+	}
+	return candidates[len(candidates)-1]
+}
+
+// LeastRecentlyFailed 优先选取最久没有失败过的实例，都没有失败过或失败时间相同时轮询。
+// 需要通过 Done 反馈调用结果
+type LeastRecentlyFailed struct {
+	lock   sync.Mutex
+	failed map[string]time.Time // key: hostname
+	rr     RoundRobin
+}
+
+func (p *LeastRecentlyFailed) Pick(candidates []*registry.Instance) *registry.Instance {
+	p.lock.Lock()
+	// 下线实例的失败记录不再需要
+	if len(p.failed) > 2*len(candidates) {
+		kept := make(map[string]time.Time, len(candidates))
+		for _, in := range candidates {
+			if t, ok := p.failed[in.Hostname]; ok {
+				kept[in.Hostname] = t
+			}
+		}
+		p.failed = kept
+	}
+	var (
+		oldest time.Time
+		best   []*registry.Instance
+	)
+	for _, in := range candidates {
+		t := p.failed[in.Hostname]
+		switch {
+		case len(best) == 0 || t.Before(oldest):
+			oldest, best = t, append(best[:0], in)
+		case t.Equal(oldest):
+			best = append(best, in)
+		}
+	}
+	p.lock.Unlock()
+	return p.rr.Pick(best)
+}
+
+func (p *LeastRecentlyFailed) Done(in *registry.Instance, err error) {
+	if err == nil {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.failed == nil {
+		p.failed = make(map[string]time.Time)
+	}
+	p.failed[in.Hostname] = time.Now()
+}
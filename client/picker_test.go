@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestPickers(t *testing.T) {
+	a := &registry.Instance{Hostname: "a", Weight: 100}
+	b := &registry.Instance{Hostname: "b", Weight: 0}
+	c := &registry.Instance{Hostname: "c", Weight: 100}
+	candidates := []*registry.Instance{a, b, c}
+	for _, name := range []string{PickRoundRobin, PickRandom, PickWeightedRandom, PickLeastRecentlyFailed} {
+		if NewPicker(name) == nil {
+			t.Fatalf("picker %s not found", name)
+		}
+	}
+	if NewPicker("unknown") != nil {
+		t.Fatal("unknown picker should be nil")
+	}
+
+	rr := &RoundRobin{}
+	for i, want := range []*registry.Instance{a, b, c, a} {
+		if got := rr.Pick(candidates); got != want {
+			t.Fatalf("round robin #%d picked %s", i, got.Hostname)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if (WeightedRandom{}).Pick(candidates) == b {
+			t.Fatal("instance with zero weight should not be picked")
+		}
+	}
+	if got := (WeightedRandom{}).Pick([]*registry.Instance{b}); got != b {
+		t.Fatal("all zero weights should fall back to random")
+	}
+
+	lrf := &LeastRecentlyFailed{}
+	lrf.Done(a, errors.New("refused"))
+	lrf.Done(c, nil)
+	for i := 0; i < 4; i++ {
+		if got := lrf.Pick(candidates); got == a {
+			t.Fatal("failed instance should be avoided")
+		}
+	}
+	lrf.Done(b, errors.New("refused"))
+	lrf.Done(c, errors.New("refused"))
+	if got := lrf.Pick(candidates); got != a {
+		t.Fatalf("least recently failed instance expected, got %s", got.Hostname)
+	}
+}
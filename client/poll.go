@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PollConfig 轮询配置，实际间隔优先使用服务端下发的缓存提示，并限制在 [MinInterval, MaxInterval] 之间
+type PollConfig struct {
+	Interval    time.Duration // 服务端没有下发提示时的间隔，默认 30 秒
+	MinInterval time.Duration // 默认 1 秒
+	MaxInterval time.Duration // 默认 5 分钟
+}
+
+// Poll 定期拉取应用实例直到 ctx 结束，实例变化时调用 onChange。
+// 轮询间隔跟随服务端的缓存提示，服务端限流或过载时按其建议放慢
+func (c *Client) Poll(ctx context.Context, env, appid string, cfg PollConfig, onChange func(*registry.FetchData)) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = registry.DefaultPollInterval
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = time.Second
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = 5 * time.Minute
+	}
+	var latest int64
+	interval := cfg.Interval
+	for {
+		data, err := c.Fetch(ctx, env, appid, latest)
+		if err == nil {
+			latest = data.LatestTimestamp
+			onChange(data)
+		}
+		interval = nextInterval(interval, cfg, data, err)
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// nextInterval 按服务端提示及错误计算下次轮询间隔，没有新提示时保持当前间隔
+func nextInterval(current time.Duration, cfg PollConfig, data *registry.FetchData, err error) time.Duration {
+	interval := current
+	if data != nil && data.Hints != nil && data.Hints.PollInterval > 0 {
+		interval = data.Hints.PollInterval
+	}
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > interval {
+		interval = se.RetryAfter
+	}
+	if interval < cfg.MinInterval {
+		interval = cfg.MinInterval
+	}
+	if interval > cfg.MaxInterval {
+		interval = cfg.MaxInterval
+	}
+	return interval
+}
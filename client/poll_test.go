@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestPoll(t *testing.T) {
+	r, c := newCluster(t)
+	r.SetPollInterval(20*time.Millisecond, 0)
+	r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: registry.StatusUP}), time.Now().UnixNano())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	changes := make(chan *registry.FetchData, 4)
+	go c.Poll(ctx, "test", "com.xx.app", PollConfig{Interval: time.Hour, MinInterval: time.Millisecond}, func(d *registry.FetchData) {
+		changes <- d
+	})
+	d := <-changes
+	if d.Hints == nil || d.Hints.PollInterval != 20*time.Millisecond || !d.Hints.DeltaSupported {
+		t.Fatalf("unexpected hints %+v", d.Hints)
+	}
+	// 按服务端提示的间隔轮询，很快发现新实例
+	r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Addrs: []string{"http://127.0.0.1:81"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	select {
+	case d = <-changes:
+		if len(d.Instances) != 2 {
+			t.Fatalf("unexpected instances %+v", d.Instances)
+		}
+	case <-ctx.Done():
+		t.Fatal("poll should follow the server hinted interval")
+	}
+
+	cfg := PollConfig{MinInterval: time.Second, MaxInterval: time.Minute}
+	if got := nextInterval(time.Second, cfg, nil, &StatusError{Code: 429, RetryAfter: 10 * time.Second}); got != 10*time.Second {
+		t.Fatalf("expect retry after to stretch interval, got %s", got)
+	}
+}
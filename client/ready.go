@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Target 启动时需要等待的上游依赖
+type Target struct {
+	Env   string
+	AppId string
+	MinUp int // 至少需要的 UP 实例数，0 视为 1
+}
+
+// DependencyError 等待超时时仍未就绪的依赖
+type DependencyError struct {
+	Pending map[registry.AppKey]int // value: 当前 UP 实例数
+	Err     error
+}
+
+func (e *DependencyError) Error() string {
+	var ps []string
+	for key, n := range e.Pending {
+		ps = append(ps, fmt.Sprintf("%s(%d up)", key, n))
+	}
+	return fmt.Sprintf("dependencies not ready: %s: %v", strings.Join(ps, ", "), e.Err)
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// WaitForDependencies 阻塞直到每个依赖都有足够的 UP 实例，服务可以据此推迟自身的就绪。
+// ctx 结束时返回 DependencyError，列出仍未就绪的依赖
+func (c *Client) WaitForDependencies(ctx context.Context, targets []Target) error {
+	pending := make(map[registry.AppKey]int)
+	interval := time.Second
+	for {
+		for _, t := range targets {
+			key := registry.NewAppKey(t.Env, t.AppId)
+			min := t.MinUp
+			if min <= 0 {
+				min = 1
+			}
+			var up int
+			data, err := c.Fetch(ctx, t.Env, t.AppId, 0)
+			if err == nil {
+				for _, in := range data.Instances {
+					if in.Status == registry.StatusUP {
+						up++
+					}
+				}
+			}
+			if up >= min {
+				delete(pending, key)
+			} else {
+				pending[key] = up
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &DependencyError{Pending: pending, Err: ctx.Err()}
+		case <-timer.C:
+		}
+		// 逐步放慢，依赖长时间未就绪时减少对注册中心的压力
+		if interval < 10*time.Second {
+			interval *= 2
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestWaitForDependencies(t *testing.T) {
+	r, c := newCluster(t)
+	register := func(app, host string) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: app, Hostname: host, Addrs: []string{"http://127.0.0.1:80"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	}
+	register("com.xx.db", "d1")
+	targets := []Target{{Env: "test", AppId: "com.xx.db"}, {Env: "test", AppId: "com.xx.cache", MinUp: 2}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := c.WaitForDependencies(ctx, targets)
+	var de *DependencyError
+	if !errors.As(err, &de) || len(de.Pending) != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect cache pending, got %v", err)
+	}
+
+	register("com.xx.cache", "c1")
+	register("com.xx.cache", "c2")
+	if err := c.WaitForDependencies(context.Background(), targets); err != nil {
+		t.Fatal(err)
+	}
+}
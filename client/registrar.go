@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// RegistrationState 单个注册的租约状态，各注册互不影响
+type RegistrationState struct {
+	Env        string
+	AppId      string
+	Hostname   string
+	Registered bool      // 最近一次注册或续约是否成功
+	LastRenew  time.Time // 最近一次成功注册或续约的时间
+	LastError  error     // 最近一次失败的错误，成功后清空
+	Failures   int       // 连续失败次数
+}
+
+// registration 由 Registrar 维持租约的一个实例
+type registration struct {
+	req    registry.RequestRegister
+	secret string // 开启续约凭证时下发的凭证
+	state  RegistrationState
+}
+
+// maxHeartbeatInterval 按背压拉长后的心跳间隔上限，须小于注册中心 90 秒的租约
+const maxHeartbeatInterval = time.Minute
+
+// Registrar 在一个进程内维持多个实例的注册，如网关以多个 appId 对外提供服务。
+// 所有注册共用一个客户端连接和一个心跳循环，各自记录租约状态和错误
+type Registrar struct {
+	c        *Client
+	interval time.Duration
+	next     time.Duration // 下次心跳的间隔，服务端限流或过载时拉长
+
+	lock sync.Mutex
+	regs map[registrationKey]*registration
+}
+
+// NewRegistrar 创建注册管理器，interval 为心跳间隔，默认 30 秒。调用 Run 开始心跳
+func (c *Client) NewRegistrar(interval time.Duration) *Registrar {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Registrar{c: c, interval: interval, next: interval, regs: make(map[registrationKey]*registration)}
+}
+
+type registrationKey struct {
+	app      registry.AppKey
+	hostname string
+}
+
+func newRegistrationKey(env, appid, hostname string) registrationKey {
+	return registrationKey{app: registry.NewAppKey(env, appid), hostname: hostname}
+}
+
+// Add 注册实例并由心跳循环维持租约。注册失败时仍然保留，下次心跳时重试
+func (m *Registrar) Add(ctx context.Context, req *registry.RequestRegister) error {
+	if req.Env == "" || req.AppId == "" || req.Hostname == "" {
+		return errors.New("env, appid and hostname are required")
+	}
+	reg := &registration{req: *req, state: RegistrationState{Env: req.Env, AppId: req.AppId, Hostname: req.Hostname}}
+	m.lock.Lock()
+	m.regs[newRegistrationKey(req.Env, req.AppId, req.Hostname)] = reg
+	m.lock.Unlock()
+	return m.register(ctx, reg)
+}
+
+// Remove 下线实例并停止为它续约
+func (m *Registrar) Remove(ctx context.Context, env, appid, hostname string) error {
+	key := newRegistrationKey(env, appid, hostname)
+	m.lock.Lock()
+	_, ok := m.regs[key]
+	delete(m.regs, key)
+	m.lock.Unlock()
+	if !ok {
+		return registry.ErrInstanceNotFound
+	}
+	if err := m.c.Cancel(ctx, env, appid, hostname); err != nil && !notFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Close 下线所有实例，通常在进程退出前调用，返回第一个错误
+func (m *Registrar) Close(ctx context.Context) error {
+	m.lock.Lock()
+	regs := m.regs
+	m.regs = make(map[registrationKey]*registration)
+	m.lock.Unlock()
+	var firstErr error
+	for _, reg := range regs {
+		if err := m.c.Cancel(ctx, reg.req.Env, reg.req.AppId, reg.req.Hostname); err != nil && !notFound(err) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Run 按间隔为所有实例续约直到 ctx 结束。实例在注册中心已被剔除时重新注册，
+// 服务端返回背压时按建议拉长心跳间隔，最长 maxHeartbeatInterval，恢复后回到原间隔
+func (m *Registrar) Run(ctx context.Context) error {
+	timer := time.NewTimer(m.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			m.renewAll(ctx)
+			timer.Reset(m.Interval())
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Interval 返回下次心跳的间隔
+func (m *Registrar) Interval() time.Duration {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.next
+}
+
+func (m *Registrar) renewAll(ctx context.Context) {
+	m.lock.Lock()
+	regs := make([]*registration, 0, len(m.regs))
+	for _, reg := range m.regs {
+		regs = append(regs, reg)
+	}
+	m.lock.Unlock()
+	next := m.interval
+	for _, reg := range regs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := m.renew(ctx, reg); err != nil {
+			next = m.stretch(next, err)
+		}
+	}
+	m.lock.Lock()
+	m.next = next
+	m.lock.Unlock()
+}
+
+// stretch 按背压错误拉长心跳间隔，上限为 maxHeartbeatInterval 与原间隔中的较大值
+func (m *Registrar) stretch(current time.Duration, err error) time.Duration {
+	max := maxHeartbeatInterval
+	if m.interval > max {
+		max = m.interval
+	}
+	return registry.StretchInterval(current, m.interval, max, err)
+}
+
+// renew 续约一个实例，没有注册成功或已被剔除时重新注册
+func (m *Registrar) renew(ctx context.Context, reg *registration) error {
+	m.lock.Lock()
+	registered, secret := reg.state.Registered, reg.secret
+	m.lock.Unlock()
+	if !registered {
+		return m.register(ctx, reg)
+	}
+	reply, err := m.c.Renew(ctx, reg.req.Env, reg.req.AppId, reg.req.Hostname, secret)
+	if notFound(err) {
+		return m.register(ctx, reg)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err == nil && reply.Credential != nil {
+		reg.secret = reply.Credential.Secret
+	}
+	reg.record(err)
+	return err
+}
+
+func (m *Registrar) register(ctx context.Context, reg *registration) error {
+	req := reg.req
+	req.LatestTimestamp = time.Now().UnixNano()
+	reply, err := m.c.Register(ctx, &req)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err == nil && reply.Credential != nil {
+		reg.secret = reply.Credential.Secret
+	}
+	reg.record(err)
+	return err
+}
+
+func (reg *registration) record(err error) {
+	if err != nil {
+		reg.state.Registered = false
+		reg.state.LastError = err
+		reg.state.Failures++
+		return
+	}
+	reg.state.Registered = true
+	reg.state.LastRenew = time.Now()
+	reg.state.LastError = nil
+	reg.state.Failures = 0
+}
+
+// States 返回所有注册的租约状态，按 appId、env、hostname 排列
+func (m *Registrar) States() []RegistrationState {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rs := make([]RegistrationState, 0, len(m.regs))
+	for _, reg := range m.regs {
+		rs = append(rs, reg.state)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		a, b := rs[i], rs[j]
+		if a.AppId != b.AppId {
+			return a.AppId < b.AppId
+		}
+		if a.Env != b.Env {
+			return a.Env < b.Env
+		}
+		return a.Hostname < b.Hostname
+	})
+	return rs
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+func TestRegistrar(t *testing.T) {
+	ctx := context.Background()
+	r, c := newCluster(t)
+	m := c.NewRegistrar(time.Minute)
+	for _, appid := range []string{"com.xx.api", "com.xx.admin"} {
+		req := &registry.RequestRegister{Env: "test", AppId: appid, Hostname: "gw", Addrs: []string{"http://127.0.0.1:80"}}
+		if err := m.Add(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Add(ctx, &registry.RequestRegister{Env: "test", AppId: "com.xx.bad", Hostname: "gw", Addrs: []string{"bad"}}); err == nil {
+		t.Fatal("expect invalid registration to fail")
+	}
+	states := m.States()
+	if len(states) != 3 || !states[0].Registered || !states[1].Registered || states[2].Registered || states[2].Failures != 1 {
+		t.Fatalf("unexpected states %+v", states)
+	}
+
+	// 被剔除的实例在下次心跳时重新注册，不影响其他注册
+	r.Cancel("test", "com.xx.admin", "gw", time.Now().UnixNano())
+	m.renewAll(ctx)
+	if data, err := c.Fetch(ctx, "test", "com.xx.admin", 0); err != nil || len(data.Instances) != 1 {
+		t.Fatalf("expect re-registered instance, got %+v %v", data, err)
+	}
+	if states := m.States(); !states[0].Registered || states[2].Failures != 2 {
+		t.Fatalf("unexpected states %+v", states)
+	}
+
+	if err := m.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch(ctx, "test", "com.xx.api", 0); !notFound(err) {
+		t.Fatalf("expect instance cancelled, got %v", err)
+	}
+}
+
+func TestRegistrarStretchesHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	r := registry.NewRegistry()
+	defer r.Close()
+	var overloaded int32
+	h := server.NewHTTPServer(r, server.ServerConfig{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == server.PathRenew && atomic.LoadInt32(&overloaded) == 1 {
+			err := &registry.BackpressureError{Err: registry.ErrOverloaded, RetryAfter: 45 * time.Second, PollInterval: 2 * time.Minute}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(server.Response{Code: http.StatusServiceUnavailable, Message: err.Error(), Error: registry.ErrorInfoOf(err)})
+			return
+		}
+		h.ServeHTTP(w, req)
+	}))
+	defer srv.Close()
+	c, err := New(Config{Nodes: []string{srv.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := c.NewRegistrar(10 * time.Second)
+	if err := m.Add(ctx, &registry.RequestRegister{Env: "test", AppId: "com.xx.api", Hostname: "gw", Addrs: []string{"http://127.0.0.1:80"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&overloaded, 1)
+	m.renewAll(ctx)
+	// 建议的间隔超过上限时取 maxHeartbeatInterval，避免租约过期
+	if got := m.Interval(); got != maxHeartbeatInterval {
+		t.Fatalf("heartbeat should be stretched to %s, got %s", maxHeartbeatInterval, got)
+	}
+	atomic.StoreInt32(&overloaded, 0)
+	m.renewAll(ctx)
+	if got := m.Interval(); got != 10*time.Second {
+		t.Fatalf("heartbeat should return to the configured interval, got %s", got)
+	}
+}
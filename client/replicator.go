@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+// Replicator 通过复制接口把写操作发送给客户端配置的所有节点，用作注册中心的 registry.Replicator。
+// 请求带有本节点 ID 及用共享密钥计算的签名，对端开启 registry.WithPeerAuth 时据此校验
+type Replicator struct {
+	c      *Client
+	nodeID string
+	secret string
+}
+
+// NewReplicator 创建复制器，nodeID 为本节点 ID，secret 为对端为本节点配置的共享密钥，可以为空
+func (c *Client) NewReplicator(nodeID, secret string) *Replicator {
+	return &Replicator{c: c, nodeID: nodeID, secret: secret}
+}
+
+// Replicate 异步复制，失败只记录日志
+func (p *Replicator) Replicate(op registry.ReplicationOp) {
+	go func() {
+		if err := p.ReplicateContext(context.Background(), op); err != nil {
+			log.Printf("replicate %s %s/%s failed: %v", op.Action, op.AppId, op.Hostname, err)
+		}
+	}()
+}
+
+// ReplicateContext 复制给所有节点，返回各节点的错误
+func (p *Replicator) ReplicateContext(ctx context.Context, op registry.ReplicationOp) error {
+	body, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	p.c.lock.RLock()
+	nodes := p.c.nodes
+	p.c.lock.RUnlock()
+	var errs []error
+	for _, node := range nodes {
+		if err := p.send(ctx, node, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Replicator) send(ctx context.Context, node string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, node+server.PathReplicate, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(server.HeaderClient, Version)
+	req.Header.Set(server.HeaderPeer, p.nodeID)
+	if p.secret != "" {
+		req.Header.Set(server.HeaderPeerSignature, registry.SignReplication(p.secret, body))
+	}
+	if id := registry.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(server.HeaderRequestID, id)
+	}
+	resp, err := p.c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var rs server.Response
+	json.NewDecoder(resp.Body).Decode(&rs)
+	return &StatusError{Code: resp.StatusCode, Message: rs.Message, Info: rs.Error}
+}
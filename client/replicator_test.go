@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+func TestReplicator(t *testing.T) {
+	peer := registry.NewRegistry(registry.WithPeerAuth(registry.PeerAuthConfig{Strict: true, Secrets: map[string]string{"n1": "k"}}))
+	srv := httptest.NewServer(server.NewHTTPServer(peer, server.ServerConfig{}))
+	defer srv.Close()
+	c, _ := New(Config{Nodes: []string{srv.URL}})
+
+	op := registry.ReplicationOp{Action: registry.ActionRegister, Env: "test", AppId: "com.xx.rep", Hostname: "a", LatestTimestamp: time.Now().UnixNano(),
+		Instance: &registry.Instance{Env: "test", AppId: "com.xx.rep", Hostname: "a", Status: registry.StatusUP}}
+	ctx := context.Background()
+	if err := c.NewReplicator("n9", "k").ReplicateContext(ctx, op); !errors.Is(err, registry.ErrPeerRejected) {
+		t.Fatalf("expect unknown peer rejected, got %v", err)
+	}
+	if err := c.NewReplicator("n1", "k").ReplicateContext(ctx, op); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := c.Fetch(ctx, "test", "com.xx.rep", 0); err != nil || len(data.Instances) != 1 {
+		t.Fatalf("expect replicated instance, got %+v %v", data, err)
+	}
+}
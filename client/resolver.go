@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// ResolverScheme 按应用拨号的目标地址 scheme，如 registry:///com.xx.app?env=online
+const ResolverScheme = "registry"
+
+// ResolveTarget 解析后的目标地址
+type ResolveTarget struct {
+	Env    string
+	AppId  string
+	Scheme string // 选取的实例地址协议，默认 grpc，同时接受对应的 TLS 协议 grpcs
+}
+
+// ParseTarget 解析 registry:///<appId>?env=<env>[&scheme=<scheme>]
+func ParseTarget(target string) (ResolveTarget, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return ResolveTarget{}, err
+	}
+	if u.Scheme != ResolverScheme {
+		return ResolveTarget{}, errors.New("target scheme must be " + ResolverScheme)
+	}
+	t := ResolveTarget{AppId: strings.TrimPrefix(u.Path, "/"), Env: u.Query().Get("env"), Scheme: u.Query().Get("scheme")}
+	if t.AppId == "" || t.Env == "" {
+		return ResolveTarget{}, errors.New("target requires appId and env, e.g. registry:///com.xx.app?env=online")
+	}
+	if t.Scheme == "" {
+		t.Scheme = "grpc"
+	}
+	return t, nil
+}
+
+// ResolvedAddr 交给负载均衡器的地址
+type ResolvedAddr struct {
+	Addr     string // host:port
+	Hostname string
+	Secure   bool
+	Weight   uint32
+	Metadata map[string]string
+}
+
+// Resolve 订阅 target 对应的应用直到 ctx 结束，UP 实例的地址变化时调用 update。
+// 实例有 Scheme 协议的地址时使用该地址，否则使用未带协议的 host:port 地址，都没有的实例跳过。
+// 接入 gRPC 时使用 ResolverBuilder
+func (c *Client) Resolve(ctx context.Context, target string, update func([]ResolvedAddr)) error {
+	t, err := ParseTarget(target)
+	if err != nil {
+		return err
+	}
+	return c.Watch(ctx, t.Env, t.AppId, func(instances []*registry.Instance) {
+		update(resolveAddrs(t.Scheme, instances))
+	})
+}
+
+func resolveAddrs(scheme string, instances []*registry.Instance) []ResolvedAddr {
+	rs := make([]ResolvedAddr, 0, len(instances))
+	for _, in := range instances {
+		if in.Status != registry.StatusUP {
+			continue
+		}
+		ep := endpoint(scheme, in)
+		if ep == nil {
+			continue
+		}
+		rs = append(rs, ResolvedAddr{Addr: ep.Addr, Hostname: in.Hostname, Secure: ep.Secure, Weight: in.Weight, Metadata: in.Metadata})
+	}
+	return rs
+}
+
+// endpoint 实例 scheme 协议或对应 TLS 协议的地址，没有时使用未带协议的地址
+func endpoint(scheme string, in *registry.Instance) *registry.Endpoint {
+	var ep *registry.Endpoint
+	for i, e := range in.Endpoints {
+		if e.Scheme == scheme || e.Scheme == scheme+"s" {
+			return &in.Endpoints[i]
+		}
+		if e.Scheme == "" && ep == nil {
+			ep = &in.Endpoints[i]
+		}
+	}
+	return ep
+}
+
+// ResolverBuilder 返回 gRPC 的 resolver.Builder，地址选取规则同 Resolve。通过 grpc.WithResolvers 或
+// resolver.Register 注册后即可拨号 registry:///com.xx.app?env=online，实例变化时推送新的地址列表
+func (c *Client) ResolverBuilder() resolver.Builder {
+	return &resolverBuilder{c: c}
+}
+
+type resolverBuilder struct {
+	c *Client
+}
+
+func (b *resolverBuilder) Scheme() string {
+	return ResolverScheme
+}
+
+// Build 解析目标地址并开始订阅应用，订阅在 Close 时结束
+func (b *resolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	t, err := ParseTarget(target.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &grpcResolver{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		b.c.Watch(ctx, t.Env, t.AppId, func(instances []*registry.Instance) {
+			addrs := resolveAddrs(t.Scheme, instances)
+			if len(addrs) == 0 {
+				cc.ReportError(errors.New("no " + t.Scheme + " instances of " + t.AppId + " in " + t.Env))
+				return
+			}
+			state := resolver.State{Addresses: make([]resolver.Address, len(addrs))}
+			for i, a := range addrs {
+				state.Addresses[i] = resolver.Address{Addr: a.Addr}
+			}
+			cc.UpdateState(state)
+		})
+	}()
+	return r, nil
+}
+
+// grpcResolver 订阅由 Watch 长轮询推送，ResolveNow 无需额外操作
+type grpcResolver struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *grpcResolver) Close() {
+	r.cancel()
+	<-r.done
+}
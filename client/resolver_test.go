@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestResolve(t *testing.T) {
+	for target, ok := range map[string]bool{
+		"registry:///com.xx.app?env=online": true, "registry:///com.xx.app": false, "dns:///com.xx.app?env=online": false,
+	} {
+		if _, err := ParseTarget(target); (err == nil) != ok {
+			t.Fatalf("ParseTarget(%q) = %v", target, err)
+		}
+	}
+
+	r, c := newCluster(t)
+	register := func(hostname string, status uint32, addrs ...string) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: hostname, Addrs: addrs, Status: status}), time.Now().UnixNano())
+	}
+	register("a", registry.StatusUP, "http://10.0.0.1:80", "grpc://10.0.0.1:9000")
+	register("b", registry.StatusUP, "10.0.0.2:9000")
+	register("c", registry.StatusUP, "http://10.0.0.3:80")
+	register("d", registry.StatusDown, "grpc://10.0.0.4:9000")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updates := make(chan []ResolvedAddr, 4)
+	go c.Resolve(ctx, "registry:///com.xx.app?env=test", func(addrs []ResolvedAddr) {
+		updates <- addrs
+	})
+	select {
+	case addrs := <-updates:
+		got := make(map[string]string)
+		for _, a := range addrs {
+			got[a.Hostname] = a.Addr
+		}
+		if len(got) != 2 || got["a"] != "10.0.0.1:9000" || got["b"] != "10.0.0.2:9000" {
+			t.Fatalf("unexpected addrs %+v", addrs)
+		}
+	case <-ctx.Done():
+		t.Fatal("resolver should push addrs")
+	}
+}
+
+func TestResolverBuilder(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	healthpb.RegisterHealthServer(g, health.NewServer())
+	go g.Serve(ln)
+	defer g.Stop()
+
+	r, c := newCluster(t)
+	r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.grpc", Hostname: "a",
+		Addrs: []string{"grpc://" + ln.Addr().String()}, Status: registry.StatusUP}), time.Now().UnixNano())
+
+	conn, err := grpc.NewClient("registry:///com.xx.grpc?env=test", grpc.WithResolvers(c.ResolverBuilder()),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("dial through the registry resolver failed: %v %v", resp, err)
+	}
+}
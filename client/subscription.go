@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Subscription 在后台订阅应用，按各自的负载均衡策略从最新的 UP 实例中选取，
+// 调用方不需要自己维护实例列表
+type Subscription struct {
+	env, appid string
+	picker     Picker
+	cancel     context.CancelFunc
+	done       chan struct{}
+
+	lock      sync.RWMutex
+	instances []*registry.Instance
+	ready     chan struct{} // 收到第一次结果后关闭
+	synced    bool
+}
+
+// Subscribe 订阅应用直到 Close，picker 为空时轮询
+func (c *Client) Subscribe(env, appid string, picker Picker) *Subscription {
+	if picker == nil {
+		picker = &RoundRobin{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Subscription{env: env, appid: appid, picker: picker, cancel: cancel, done: make(chan struct{}), ready: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		// 长轮询在应用不存在时会等待，先获取一次以便尽快确认没有实例
+		data, err := c.Fetch(ctx, env, appid, 0)
+		switch {
+		case err == nil:
+			s.update(data.Instances)
+		case errors.Is(err, registry.ErrAppNotFound), errors.Is(err, registry.ErrNoInstances):
+			s.update(nil)
+		}
+		c.Watch(ctx, env, appid, s.update)
+	}()
+	return s
+}
+
+func (s *Subscription) update(instances []*registry.Instance) {
+	up := make([]*registry.Instance, 0, len(instances))
+	for _, in := range instances {
+		if in.Status == registry.StatusUP {
+			up = append(up, in)
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.instances = up
+	if !s.synced {
+		s.synced = true
+		close(s.ready)
+	}
+}
+
+// Instances 返回最新的 UP 实例，调用方不能修改
+func (s *Subscription) Instances() []*registry.Instance {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.instances
+}
+
+// Pick 选取一个实例，首次订阅结果返回前阻塞到 ctx 结束。没有 UP 实例时返回 registry.ErrNoInstances
+func (s *Subscription) Pick(ctx context.Context) (*registry.Instance, error) {
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	instances := s.Instances()
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s", registry.ErrNoInstances, s.env, s.appid)
+	}
+	return s.picker.Pick(instances), nil
+}
+
+// PickByKey 按键选取实例，同一个键尽量落到同一个实例，订阅的负载均衡策略需实现 KeyPicker，如 ConsistentHash
+func (s *Subscription) PickByKey(ctx context.Context, key string) (*registry.Instance, error) {
+	kp, ok := s.picker.(KeyPicker)
+	if !ok {
+		return nil, errors.New("picker does not support pick by key")
+	}
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	instances := s.Instances()
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s", registry.ErrNoInstances, s.env, s.appid)
+	}
+	return kp.PickByKey(instances, key), nil
+}
+
+// Done 反馈对 Pick 选出实例的调用结果，负载均衡策略不需要反馈时忽略
+func (s *Subscription) Done(in *registry.Instance, err error) {
+	if fb, ok := s.picker.(Feedback); ok {
+		fb.Done(in, err)
+	}
+}
+
+// Close 停止订阅
+func (s *Subscription) Close() {
+	s.cancel()
+	<-s.done
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestSubscription(t *testing.T) {
+	r, c := newCluster(t)
+	register := func(hostname string, status uint32) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: hostname, Addrs: []string{"http://" + hostname + ":80"}, Status: status}), time.Now().UnixNano())
+	}
+	register("a", registry.StatusUP)
+	register("b", registry.StatusUP)
+	register("c", registry.StatusDown)
+
+	sub := c.Subscribe("test", "com.xx.app", NewPicker(PickLeastRecentlyFailed))
+	defer sub.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	in, err := sub.Pick(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Instances()) != 2 {
+		t.Fatalf("only UP instances should be picked from: %d", len(sub.Instances()))
+	}
+	sub.Done(in, errors.New("refused"))
+	for i := 0; i < 3; i++ {
+		got, err := sub.Pick(ctx)
+		if err != nil || got.Hostname == in.Hostname {
+			t.Fatalf("failed instance %s should be avoided, got %v %v", in.Hostname, got, err)
+		}
+	}
+
+	empty := c.Subscribe("test", "com.xx.none", nil)
+	defer empty.Close()
+	if _, err := empty.Pick(ctx); !errors.Is(err, registry.ErrNoInstances) {
+		t.Fatalf("expect no instances, got %v", err)
+	}
+}
+
+func TestSubscriptionPickByKey(t *testing.T) {
+	r, c := newCluster(t)
+	for _, host := range []string{"a", "b", "c"} {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.cache", Hostname: host, Addrs: []string{"http://" + host + ":80"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rr := c.Subscribe("test", "com.xx.cache", nil)
+	defer rr.Close()
+	if _, err := rr.PickByKey(ctx, "user-1"); err == nil {
+		t.Fatal("round robin should not support pick by key")
+	}
+	sub := c.Subscribe("test", "com.xx.cache", NewPicker(PickConsistentHash))
+	defer sub.Close()
+	first, err := sub.PickByKey(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.Done(first, nil)
+	for i := 0; i < 3; i++ {
+		in, err := sub.PickByKey(ctx, "user-1")
+		if err != nil || in.Hostname != first.Hostname {
+			t.Fatalf("key should stick to %s, got %v %v", first.Hostname, in, err)
+		}
+		sub.Done(in, nil)
+	}
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Transport 按应用发现实例的 http.RoundTripper，请求地址的主机名为 appid，如 http://com.xx.testapp/path，
+// Host 请求头保持为 appid。选取 UP 且有请求协议地址的实例，实例为 TLS 地址时改用 https；
+// 连接实例失败时换一个实例重试，请求已发出后的错误不重试。带端口或为 IP 的地址原样发送
+type Transport struct {
+	Cache       *Cache            // 获取应用的实例，环境为 Cache 的 Env
+	Base        http.RoundTripper // 实际发送请求，为空时使用 http.DefaultTransport
+	Picker      Picker            // 为空时轮询，实现了 Feedback 时反馈每次请求的结果，成功的请求在关闭响应体时反馈
+	MaxAttempts int               // 单个请求最多尝试的实例数，默认 3
+
+	rr RoundRobin
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	appid := req.URL.Host
+	if req.URL.Port() != "" || net.ParseIP(appid) != nil {
+		return base.RoundTrip(req)
+	}
+	data, err := t.Cache.Fetch(req.Context(), appid)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	var candidates []*registry.Instance
+	for _, in := range data.Instances {
+		if in.Status == registry.StatusUP && endpoint(req.URL.Scheme, in) != nil {
+			candidates = append(candidates, in)
+		}
+	}
+	picker := t.Picker
+	if picker == nil {
+		picker = &t.rr
+	}
+	attempts := t.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	for i := 0; ; i++ {
+		if len(candidates) == 0 {
+			closeBody(req)
+			if err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %s", registry.ErrNoInstances, appid)
+		}
+		in := picker.Pick(candidates)
+		out, rerr := outgoing(req, in, i > 0)
+		if rerr != nil {
+			return nil, rerr
+		}
+		var resp *http.Response
+		resp, err = base.RoundTrip(out)
+		fb, ok := picker.(Feedback)
+		if err == nil {
+			if ok {
+				// 响应体读完关闭时请求才结束，有界负载需要计入响应体的传输
+				resp.Body = &doneBody{ReadCloser: resp.Body, done: func() { fb.Done(in, nil) }}
+			}
+			return resp, nil
+		}
+		if ok {
+			fb.Done(in, err)
+		}
+		// 没有连上实例时请求未发出，可以换实例重试；请求体不能重放时无法重试
+		if !dialFailed(err) || i+1 >= attempts || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return nil, err
+		}
+		candidates = without(candidates, in)
+	}
+}
+
+// outgoing 把请求改写为发往实例的请求，重试时重放请求体
+func outgoing(req *http.Request, in *registry.Instance, retry bool) (*http.Request, error) {
+	ep := endpoint(req.URL.Scheme, in)
+	out := req.Clone(req.Context())
+	out.URL.Host = ep.Addr
+	if ep.Secure && out.URL.Scheme == "http" {
+		out.URL.Scheme = "https"
+	}
+	if out.Host == "" {
+		out.Host = req.URL.Host
+	}
+	if retry && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+	return out, nil
+}
+
+func dialFailed(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+func without(instances []*registry.Instance, in *registry.Instance) []*registry.Instance {
+	rs := make([]*registry.Instance, 0, len(instances))
+	for _, o := range instances {
+		if o != in {
+			rs = append(rs, o)
+		}
+	}
+	return rs
+}
+
+// doneBody 关闭响应体时反馈请求结束，只反馈一次
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
+
+// closeBody 按 RoundTripper 的约定，出错时也需关闭请求体
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
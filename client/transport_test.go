@@ -0,0 +1,74 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestTransport(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Host+" "+r.URL.Path+" "+string(body))
+	}))
+	defer backend.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	r, c := newCluster(t)
+	register := func(hostname, addr string) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.testapp", Hostname: hostname, Addrs: []string{addr}, Status: registry.StatusUP}), time.Now().UnixNano())
+	}
+	register("dead", dead.URL)
+	register("live", backend.URL)
+	register("grpc", "grpc://127.0.0.1:9000")
+
+	cache := c.NewCache(CacheConfig{Env: "test"})
+	defer cache.Close()
+	hc := &http.Client{Transport: &Transport{Cache: cache}}
+	// 轮询到已关闭的实例时换实例重试，请求体随之重放
+	for i := 0; i < 4; i++ {
+		resp, err := hc.Post("http://com.xx.testapp/ping", "text/plain", strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "com.xx.testapp /ping hello" {
+			t.Fatalf("unexpected response %q", body)
+		}
+	}
+
+	_, err := hc.Get("http://com.xx.unknown/ping")
+	if !errors.Is(err, registry.ErrAppNotFound) {
+		t.Fatalf("expect app not found, got %v", err)
+	}
+	// 带端口的地址原样发送
+	resp, err := hc.Get(backend.URL + "/direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// 响应体关闭后才释放实例的负载
+	ch := &ConsistentHash{}
+	hc = &http.Client{Transport: &Transport{Cache: cache, Picker: ch}}
+	resp, err = hc.Get("http://com.xx.testapp/load")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.total != 1 {
+		t.Fatalf("load should be held while the body is open, got %d", ch.total)
+	}
+	resp.Body.Close()
+	resp.Body.Close()
+	if ch.total != 0 {
+		t.Fatalf("load should be released once the body is closed, got %d", ch.total)
+	}
+}
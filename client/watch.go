@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Watch 长轮询订阅应用直到 ctx 结束，实例集合变化时以最新的实例调用 fn，应用下线或没有实例时传入空。
+// 只有续约时间、健康分等变化时内容相同，不会重复调用。网关可据此重建路由表
+func (c *Client) Watch(ctx context.Context, env, appid string, fn func(instances []*registry.Instance)) error {
+	// 长轮询时长需小于 HTTPClient 的超时
+	timeout := 30 * time.Second
+	if t := c.http.Timeout; t > 0 && t/2 < timeout {
+		timeout = t / 2
+	}
+	var (
+		latest  int64
+		last    []byte
+		started bool
+		backoff = time.Second
+	)
+	for {
+		data, err := c.LongPoll(ctx, env, appid, latest, timeout)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var instances []*registry.Instance
+		switch {
+		case err == nil:
+			latest, instances = data.LatestTimestamp, data.Instances
+		case errors.Is(err, registry.ErrNotModified):
+			continue
+		case errors.Is(err, registry.ErrAppNotFound), errors.Is(err, registry.ErrNoInstances):
+			latest = 0
+		default:
+			wait := backoff
+			var se *StatusError
+			if errors.As(err, &se) && se.RetryAfter > wait {
+				wait = se.RetryAfter
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			continue
+		}
+		backoff = time.Second
+		fp := fingerprint(instances)
+		if !started || !bytes.Equal(fp, last) {
+			started, last = true, fp
+			fn(instances)
+		}
+		if latest == 0 {
+			// 应用不存在时服务端立即返回，按轮询间隔等待
+			timer := time.NewTimer(time.Second)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// fingerprint 实例集合中影响路由的内容，不含续约时间、健康分等每次获取都可能变化的字段
+func fingerprint(instances []*registry.Instance) []byte {
+	type routed struct {
+		Hostname string
+		Addrs    []string
+		Version  string
+		Status   uint32
+		Weight   uint32
+		Group    string
+		Metadata map[string]string
+	}
+	rs := make([]routed, len(instances))
+	for i, in := range instances {
+		rs[i] = routed{in.Hostname, in.Addrs, in.Version, in.Status, in.Weight, in.Group, in.Metadata}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Hostname < rs[j].Hostname })
+	b, _ := json.Marshal(rs)
+	return b
+}
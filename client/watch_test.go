@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestWatch(t *testing.T) {
+	r, c := newCluster(t)
+	register := func(hostname string, weight uint32) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: hostname, Addrs: []string{"http://127.0.0.1:80"}, Status: registry.StatusUP, Weight: weight}), time.Now().UnixNano())
+	}
+	register("a", 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := make(chan []*registry.Instance, 8)
+	go c.Watch(ctx, "test", "com.xx.app", func(instances []*registry.Instance) {
+		changes <- instances
+	})
+	next := func() []*registry.Instance {
+		select {
+		case ins := <-changes:
+			return ins
+		case <-ctx.Done():
+			t.Fatal("watch callback not invoked")
+			return nil
+		}
+	}
+	if ins := next(); len(ins) != 1 {
+		t.Fatalf("unexpected initial instances %+v", ins)
+	}
+	// 重新注册相同内容的实例会更新 latest_timestamp，但实例集合不变，不回调
+	register("a", 100)
+	register("b", 100)
+	if ins := next(); len(ins) != 2 {
+		t.Fatalf("expect 2 instances, got %+v", ins)
+	}
+	register("b", 50)
+	if ins := next(); len(ins) != 2 || ins[0].Weight+ins[1].Weight != 150 {
+		t.Fatalf("expect weight change, got %+v", ins)
+	}
+	select {
+	case ins := <-changes:
+		t.Fatalf("unexpected duplicate callback %+v", ins)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func readSnapshot(path string) (*registry.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return registry.ReadSnapshot(f)
+}
+
+// runDiff 对比两个快照文件并输出每个应用新增、删除、变化的实例
+func runDiff(args []string) error {
+	if len(args) != 2 {
+		return errors.New("diff requires two snapshot files")
+	}
+	a, err := readSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := readSnapshot(args[1])
+	if err != nil {
+		return err
+	}
+	diffs := registry.DiffSnapshots(a, b)
+	if len(diffs) == 0 {
+		fmt.Println("no differences")
+		return nil
+	}
+	for _, d := range diffs {
+		fmt.Printf("%s %s\n", d.Env, d.AppId)
+		for _, in := range d.Added {
+			fmt.Printf("  + %s %s\n", in.Hostname, strings.Join(in.Addrs, ","))
+		}
+		for _, in := range d.Removed {
+			fmt.Printf("  - %s %s\n", in.Hostname, strings.Join(in.Addrs, ","))
+		}
+		for _, c := range d.Changed {
+			fmt.Printf("  ~ %s (%s)\n", c.Hostname, strings.Join(c.Fields, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/migrate"
+)
+
+// openSource 打开本地文件或 http(s) 地址
+func openSource(src string, accept string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.Open(src)
+	}
+	req, err := http.NewRequest(http.MethodGet, src, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// writeImported 把导入的实例写成注册表快照
+func writeImported(instances []*registry.Instance, out string) error {
+	r := registry.NewRegistry()
+	n, err := migrate.Import(r, instances)
+	if err != nil {
+		return err
+	}
+	w := os.Stdout
+	if out != "" && out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := registry.WriteSnapshot(w, r.Snapshot()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "imported %d instances\n", n)
+	return nil
+}
+
+// runImportEureka 读取 Eureka 的 /eureka/apps 输出并转换为注册表快照
+func runImportEureka(args []string) error {
+	fs := flag.NewFlagSet("import-eureka", flag.ExitOnError)
+	env := fs.String("env", "", "导入到的环境")
+	lower := fs.Bool("lower", true, "应用名转为小写")
+	out := fs.String("o", "-", "输出的快照文件")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("import-eureka requires an eureka apps url or file")
+	}
+	rc, err := openSource(fs.Arg(0), "application/json")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	instances, err := migrate.ParseEurekaApps(rc, migrate.EurekaOptions{Env: *env, LowerAppIds: *lower})
+	if err != nil {
+		return err
+	}
+	return writeImported(instances, *out)
+}
+
+// runImportConsul 读取 Consul catalog 并转换为注册表快照
+func runImportConsul(args []string) error {
+	fs := flag.NewFlagSet("import-consul", flag.ExitOnError)
+	env := fs.String("env", "", "导入到的环境")
+	addr := fs.String("addr", "http://127.0.0.1:8500", "Consul HTTP 地址")
+	token := fs.String("token", os.Getenv("CONSUL_HTTP_TOKEN"), "Consul ACL token")
+	dc := fs.String("dc", "", "数据中心")
+	out := fs.String("o", "-", "输出的快照文件")
+	fs.Parse(args)
+	instances, err := migrate.ImportConsul(context.Background(), migrate.ConsulOptions{
+		Env:        *env,
+		Addr:       *addr,
+		Token:      *token,
+		Datacenter: *dc,
+	})
+	if err != nil {
+		return err
+	}
+	return writeImported(instances, *out)
+}
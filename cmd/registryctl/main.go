@@ -0,0 +1,41 @@
+// registryctl 注册中心运维命令行工具
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: registryctl <command> [args]
+
+commands:
+  diff <old.json> <new.json>                   对比两个注册表快照
+  import-eureka -env <env> [-o out] <url|file>  把 Eureka 的 /eureka/apps 输出转换为注册表快照
+  import-consul -env <env> [-addr url] [-o out]  把 Consul catalog 转换为注册表快照
+  openapi [-o out]                             输出 HTTP 接口的 OpenAPI 文档，用于生成其他语言的客户端`)
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "import-eureka":
+		err = runImportEureka(os.Args[2:])
+	case "import-consul":
+		err = runImportConsul(os.Args[2:])
+	case "openapi":
+		err = runOpenAPI(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "registryctl:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	registry "github.com/junaozun/registry-center"
+	"github.com/junaozun/registry-center/server"
+)
+
+// runOpenAPI 输出 OpenAPI 文档，go generate ./server 用它更新 api/openapi.json
+func runOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+	out := fs.String("o", "-", "输出文件")
+	fs.Parse(args)
+	r := registry.NewRegistry()
+	defer r.Close()
+	w := os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return server.NewHTTPServer(r, server.ServerConfig{}).WriteOpenAPI(w)
+}
@@ -0,0 +1,47 @@
+package registry_center
+
+import "sort"
+
+// InstanceCount 某个环境下某种状态的实例数
+type InstanceCount struct {
+	Env    string `json:"env"`
+	Status uint32 `json:"status"`
+	Count  int    `json:"count"`
+}
+
+// CountInstances 返回应用的实例数
+func (r *Registry) CountInstances(env, appid string) int {
+	app, ok := r.getApplication(appid, env)
+	if !ok {
+		return 0
+	}
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return len(app.instances)
+}
+
+// Counts 按环境和状态汇总实例数，使用各应用维护的计数，不遍历实例
+func (r *Registry) Counts() []InstanceCount {
+	totals := make(map[InstanceCount]int)
+	for _, app := range r.getAllApplications() {
+		app.lock.RLock()
+		for status, n := range app.statusCounts {
+			if n > 0 {
+				totals[InstanceCount{Env: app.env, Status: status}] += n
+			}
+		}
+		app.lock.RUnlock()
+	}
+	rs := make([]InstanceCount, 0, len(totals))
+	for c, n := range totals {
+		c.Count = n
+		rs = append(rs, c)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Env != rs[j].Env {
+			return rs[i].Env < rs[j].Env
+		}
+		return rs[i].Status < rs[j].Status
+	})
+	return rs
+}
@@ -0,0 +1,31 @@
+package registry_center
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCounts(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now().UnixNano()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "1", Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "2", Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.b", Hostname: "3", Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "online", AppId: "com.xx.a", Hostname: "4", Status: StatusUP}), now)
+	// 重新注册改变状态
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "2", Status: StatusDown}), now+1)
+	r.Cancel("test", "com.xx.b", "3", now+2)
+
+	if n := r.CountInstances("test", "com.xx.a"); n != 2 {
+		t.Fatalf("expect 2 instances, got %d", n)
+	}
+	want := []InstanceCount{
+		{Env: "online", Status: StatusUP, Count: 1},
+		{Env: "test", Status: StatusUP, Count: 1},
+		{Env: "test", Status: StatusDown, Count: 1},
+	}
+	if got := r.Counts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected counts %+v", got)
+	}
+}
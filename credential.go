@@ -0,0 +1,172 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// HeartbeatCredential 服务实例续约凭证
+type HeartbeatCredential struct {
+	Secret   string `json:"secret"`
+	IssuedAt int64  `json:"issued_at"` // 签发时间
+}
+
+// credentialState 单个服务实例的凭证状态，轮换后旧凭证在重叠窗口内仍然有效
+type credentialState struct {
+	current        HeartbeatCredential
+	previous       string
+	previousExpiry int64
+}
+
+// heartbeatCredentials 续约凭证管理，rotateInterval 为轮换周期，overlap 为新旧凭证共存时长
+type heartbeatCredentials struct {
+	rotateInterval time.Duration
+	overlap        time.Duration
+	states         map[instanceKey]*credentialState
+	lock           sync.Mutex
+}
+
+func newHeartbeatCredentials(rotateInterval, overlap time.Duration) *heartbeatCredentials {
+	return &heartbeatCredentials{
+		rotateInterval: rotateInterval,
+		overlap:        overlap,
+		states:         make(map[instanceKey]*credentialState),
+	}
+}
+
+func newCredential(now int64) HeartbeatCredential {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return HeartbeatCredential{Secret: hex.EncodeToString(b), IssuedAt: now}
+}
+
+// issue 注册时签发新凭证，之前的凭证全部作废
+func (h *heartbeatCredentials) issue(env, appid, hostname string) HeartbeatCredential {
+	cred := newCredential(time.Now().UnixNano())
+	h.lock.Lock()
+	h.states[getInstanceKey(appid, env, hostname)] = &credentialState{current: cred}
+	h.lock.Unlock()
+	return cred
+}
+
+// verify 校验续约凭证，不修改凭证状态。出示重叠窗口内的旧凭证时返回当前凭证，
+// 客户端错过轮换响应后可以据此切换；rotate 表示当前凭证已到轮换周期，续约成功后再轮换
+func (h *heartbeatCredentials) verify(env, appid, hostname, secret string) (cred *HeartbeatCredential, rotate bool, err error) {
+	now := time.Now().UnixNano()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	st, ok := h.states[getInstanceKey(appid, env, hostname)]
+	if !ok {
+		return nil, false, errors.New("heartbeat credential not found")
+	}
+	switch {
+	case secretEqual(st.current.Secret, secret):
+		return nil, now-st.current.IssuedAt >= int64(h.rotateInterval), nil
+	case st.previous != "" && now < st.previousExpiry && secretEqual(st.previous, secret):
+		// 客户端尚未切换到新凭证，重叠窗口内继续接受旧凭证
+		current := st.current
+		return &current, false, nil
+	}
+	return nil, false, errors.New("invalid heartbeat credential")
+}
+
+// rotate 轮换凭证，secret 须仍为当前凭证，避免并发续约重复轮换。返回新凭证及轮换后的状态
+func (h *heartbeatCredentials) rotate(env, appid, hostname, secret string) (*HeartbeatCredential, *HeartbeatCredentialState) {
+	now := time.Now().UnixNano()
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	st, ok := h.states[getInstanceKey(appid, env, hostname)]
+	if !ok || !secretEqual(st.current.Secret, secret) {
+		return nil, nil
+	}
+	st.previous = st.current.Secret
+	st.previousExpiry = now + int64(h.overlap)
+	st.current = newCredential(now)
+	cred := st.current
+	return &cred, st.export()
+}
+
+// state 导出实例的凭证状态，用于复制给对端
+func (h *heartbeatCredentials) state(env, appid, hostname string) *HeartbeatCredentialState {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	st, ok := h.states[getInstanceKey(appid, env, hostname)]
+	if !ok {
+		return nil
+	}
+	return st.export()
+}
+
+// set 使用对端复制的凭证状态，替换本节点注册时签发的凭证
+func (h *heartbeatCredentials) set(env, appid, hostname string, cs HeartbeatCredentialState) {
+	h.lock.Lock()
+	h.states[getInstanceKey(appid, env, hostname)] = &credentialState{current: cs.Current, previous: cs.Previous, previousExpiry: cs.PreviousExpiry}
+	h.lock.Unlock()
+}
+
+func (st *credentialState) export() *HeartbeatCredentialState {
+	return &HeartbeatCredentialState{Current: st.current, Previous: st.previous, PreviousExpiry: st.previousExpiry}
+}
+
+func (h *heartbeatCredentials) remove(env, appid, hostname string) {
+	h.lock.Lock()
+	delete(h.states, getInstanceKey(appid, env, hostname))
+	h.lock.Unlock()
+}
+
+func secretEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// HeartbeatCredential 获取服务实例当前的续约凭证，注册成功后下发给客户端
+func (r *Registry) HeartbeatCredential(env, appid, hostname string) (*HeartbeatCredential, bool) {
+	if r.credentials == nil {
+		return nil, false
+	}
+	h := r.credentials
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	st, ok := h.states[getInstanceKey(appid, env, hostname)]
+	if !ok {
+		return nil, false
+	}
+	cred := st.current
+	return &cred, true
+}
+
+// RenewWithCredential 携带凭证的服务续约。凭证到达轮换周期时返回新凭证，出示旧凭证时返回当前凭证，
+// 客户端应在之后的续约中改用返回的凭证，旧凭证在重叠窗口结束后失效
+func (r *Registry) RenewWithCredential(env, appid, hostname, secret string) (*Instance, *HeartbeatCredential, error) {
+	return r.RenewWithCredentialContext(context.Background(), env, appid, hostname, secret)
+}
+
+// RenewWithCredentialContext 同 RenewWithCredential，ctx 的处理同 RenewContext
+func (r *Registry) RenewWithCredentialContext(ctx context.Context, env, appid, hostname, secret string) (*Instance, *HeartbeatCredential, error) {
+	if r.credentials == nil {
+		in, err := r.RenewContext(ctx, env, appid, hostname)
+		return in, nil, err
+	}
+	cred, rotate, err := r.credentials.verify(env, appid, hostname, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	in, err := r.RenewContext(ctx, env, appid, hostname)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 续约失败时不轮换，避免客户端收不到新凭证
+	if rotate {
+		var st *HeartbeatCredentialState
+		if cred, st = r.credentials.rotate(env, appid, hostname, secret); st != nil {
+			r.replicate(ctx, ReplicationOp{Action: ActionCredential, Env: env, AppId: appid, Hostname: hostname, Credential: st})
+		}
+	}
+	return in, cred, nil
+}
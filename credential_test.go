@@ -0,0 +1,76 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatCredentialRotation(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(0, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cred", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	cred, ok := r.HeartbeatCredential("test", "com.xx.cred", "webapi")
+	if !ok {
+		t.Fatal("credential should be issued on register")
+	}
+	if _, _, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", "bad"); err == nil {
+		t.Fatal("invalid credential should be rejected")
+	}
+	// 轮换周期为 0，每次续约都会下发新凭证
+	_, next, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", cred.Secret)
+	if err != nil || next == nil || next.Secret == cred.Secret {
+		t.Fatalf("expect rotated credential, got %v %v", next, err)
+	}
+	// 重叠窗口内旧凭证仍然有效
+	if _, _, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", cred.Secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", next.Secret); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeartbeatCredentialPreviousSecretGetsCurrent(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(0, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cred", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	cred, _ := r.HeartbeatCredential("test", "com.xx.cred", "webapi")
+
+	// 续约失败时不轮换，原凭证继续有效
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := r.RenewWithCredentialContext(ctx, "test", "com.xx.cred", "webapi", cred.Secret); err == nil {
+		t.Fatal("renew with a cancelled context should fail")
+	}
+	if cur, _ := r.HeartbeatCredential("test", "com.xx.cred", "webapi"); cur.Secret != cred.Secret {
+		t.Fatal("credential must not rotate when the renew fails")
+	}
+
+	// 错过轮换响应的客户端用旧凭证续约时拿到当前凭证
+	_, next, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", cred.Secret)
+	if err != nil || next == nil {
+		t.Fatalf("expect rotated credential, got %v %v", next, err)
+	}
+	_, got, err := r.RenewWithCredential("test", "com.xx.cred", "webapi", cred.Secret)
+	if err != nil || got == nil || got.Secret != next.Secret {
+		t.Fatalf("previous secret should get the current credential, got %v %v", got, err)
+	}
+}
+
+func TestHeartbeatCredentialReplication(t *testing.T) {
+	standby := NewRegistry(WithStandby(FailoverConfig{}), WithHeartbeatCredentials(0, time.Minute))
+	active := NewRegistry(WithReplicator(applyReplicator{peer: standby}), WithHeartbeatCredentials(0, time.Minute))
+	active.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.cred", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	cred, _ := active.HeartbeatCredential("test", "com.xx.cred", "webapi")
+	if peer, ok := standby.HeartbeatCredential("test", "com.xx.cred", "webapi"); !ok || peer.Secret != cred.Secret {
+		t.Fatalf("standby should keep the active's credential, got %v", peer)
+	}
+	_, next, err := active.RenewWithCredential("test", "com.xx.cred", "webapi", cred.Secret)
+	if err != nil || next == nil {
+		t.Fatal(err)
+	}
+	// 切换到备节点后客户端继续使用轮换后的凭证
+	standby.Promote()
+	if _, _, err := standby.RenewWithCredential("test", "com.xx.cred", "webapi", next.Secret); err != nil {
+		t.Fatalf("rotated credential should be replicated: %v", err)
+	}
+}
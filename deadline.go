@@ -0,0 +1,75 @@
+package registry_center
+
+import (
+	"context"
+	"fmt"
+)
+
+// 请求处理阶段，请求的 deadline 在各阶段开始前检查，超时的阶段计入指标
+const (
+	StageAdmission   = "admission"   // 准入控制排队
+	StageStore       = "store"       // 写入注册表
+	StageCache       = "cache"       // 读取注册表或 Fetch 缓存
+	StageReplication = "replication" // 复制给对端节点
+	StageStorage     = "storage"     // 持久化到磁盘
+)
+
+// StageTimeoutError 请求在某个处理阶段超过了 deadline 或被取消
+type StageTimeoutError struct {
+	Stage string
+	Err   error // context.DeadlineExceeded 或 context.Canceled
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ContextReplicator 可选接口，Replicator 实现它时复制会带上请求的 ctx，
+// 实现方应在 ctx 结束时放弃发送，避免慢的对端拖住请求
+type ContextReplicator interface {
+	ReplicateContext(ctx context.Context, op ReplicationOp) error
+}
+
+// checkDeadline 进入阶段 stage 前检查 ctx 是否已结束
+func (r *Registry) checkDeadline(ctx context.Context, stage string) error {
+	if err := ctx.Err(); err != nil {
+		return r.stageTimeout(stage, err)
+	}
+	return nil
+}
+
+// stageTimeout 记录阶段超时并返回 StageTimeoutError
+func (r *Registry) stageTimeout(stage string, err error) error {
+	m := r.metrics
+	m.lock.Lock()
+	m.stageTimeouts[stage]++
+	m.lock.Unlock()
+	return &StageTimeoutError{Stage: stage, Err: err}
+}
+
+// StageTimeouts 返回各处理阶段的超时次数，用于定位拖慢请求的环节
+func (r *Registry) StageTimeouts() map[string]uint64 {
+	m := r.metrics
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rs := make(map[string]uint64, len(m.stageTimeouts))
+	for stage, n := range m.stageTimeouts {
+		rs[stage] = n
+	}
+	return rs
+}
+
+// writeStageTimeouts 输出各处理阶段的超时次数
+func (m *metrics) writeStageTimeouts(w *errWriter) {
+	const name = "registry_stage_timeouts_total"
+	w.printf("# HELP %s Requests that ran out of deadline by processing stage.\n# TYPE %s counter\n", name, name)
+	for _, stage := range []string{StageAdmission, StageStore, StageCache, StageReplication, StageStorage} {
+		if n := m.stageTimeouts[stage]; n > 0 {
+			w.printf("%s{stage=%q} %d\n", name, stage, n)
+		}
+	}
+}
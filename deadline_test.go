@@ -0,0 +1,56 @@
+package registry_center
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type slowReplicator struct{}
+
+func (slowReplicator) Replicate(op ReplicationOp) {}
+
+func (slowReplicator) ReplicateContext(ctx context.Context, op ReplicationOp) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRequestDeadline(t *testing.T) {
+	r := NewRegistry(
+		WithAdmission(AdmissionConfig{MaxConcurrent: 1, MaxQueueDepth: map[Priority]int{PriorityRenew: 1}}),
+		WithReplicator(slowReplicator{}),
+	)
+	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// 复制超时不影响已生效的注册
+	if _, err := r.RegisterContext(ctx, NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+
+	release, _ := r.Admit(PriorityAdmin)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := r.RenewContext(ctx, "test", "com.xx.app", "a")
+	release()
+	var se *StageTimeoutError
+	if !errors.As(err, &se) || se.Stage != StageAdmission || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect admission timeout, got %v", err)
+	}
+
+	if _, err := r.FetchContext(ctx, "test", "com.xx.app", StatusUP, 0, FetchOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect fetch timeout, got %v", err)
+	}
+	timeouts := r.StageTimeouts()
+	if timeouts[StageAdmission] != 2 || timeouts[StageReplication] != 1 {
+		t.Fatalf("unexpected stage timeouts %v", timeouts)
+	}
+	var buf bytes.Buffer
+	r.WriteMetrics(&buf)
+	if !strings.Contains(buf.String(), `registry_stage_timeouts_total{stage="replication"} 1`) {
+		t.Fatalf("missing stage timeout metric:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 增量获取中实例的变更类型
+const (
+	DeltaAdd    = "add"    // 新增实例
+	DeltaUpdate = "update" // 实例信息变化
+	DeltaDelete = "delete" // 实例下线或被剔除
+)
+
+// InstanceDelta 一个实例的变更，同一实例多次变更时只返回最终结果
+type InstanceDelta struct {
+	Action   string    `json:"action"`
+	Hostname string    `json:"hostname"`
+	Instance *Instance `json:"instance,omitempty"` // delete 时为空
+}
+
+// DeltaData 增量获取的结果
+type DeltaData struct {
+	Revision uint64          `json:"revision"` // 下次增量获取时传入
+	Full     bool            `json:"full"`     // 为 true 时 Changes 是全量实例，调用方应先清空本地数据
+	Changes  []InstanceDelta `json:"changes"`
+	Hints    *CacheHints     `json:"hints,omitempty"` // 客户端缓存提示
+}
+
+// FetchDelta 增量获取应用实例，返回 revision 之后新增、变化和下线的实例。
+// revision 为 0、超出事件日志保留范围或期间应用被清除时返回全量数据
+func (r *Registry) FetchDelta(ctx context.Context, env, appid string, revision uint64) (d *DeltaData, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	defer func() {
+		if d != nil {
+			d.Hints = r.cacheHints()
+		}
+	}()
+	if err := r.CheckVisible(ctx, env, appid); err != nil {
+		return nil, err
+	}
+	if err := r.allowFetch(ctx, env, appid, ""); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageCache); err != nil {
+		return nil, err
+	}
+	if revision == 0 {
+		return r.fullDelta(env, appid), nil
+	}
+	events, err := r.events.since(revision, 0)
+	if errors.Is(err, ErrEventsTruncated) {
+		return r.fullDelta(env, appid), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d = &DeltaData{Revision: revision, Changes: make([]InstanceDelta, 0)}
+	// 按实例归并：记下区间内第一条事件判断调用方是否已知该实例，最后一条事件决定最终状态
+	type chain struct {
+		first, last *Event
+	}
+	chains := make(map[string]*chain)
+	var order []string
+	for i := range events {
+		e := &events[i]
+		d.Revision = e.Seq
+		if e.Env != env || e.AppId != appid {
+			continue
+		}
+		if e.Type == EventPurge {
+			return r.fullDelta(env, appid), nil
+		}
+		if e.Hostname == "" {
+			continue
+		}
+		c, ok := chains[e.Hostname]
+		if !ok {
+			c = &chain{first: e}
+			chains[e.Hostname] = c
+			order = append(order, e.Hostname)
+		}
+		c.last = e
+	}
+	for _, host := range order {
+		c := chains[host]
+		known := c.first.Type != EventRegister || !c.first.Created
+		switch {
+		case c.last.Type == EventRegister && known:
+			d.Changes = append(d.Changes, InstanceDelta{Action: DeltaUpdate, Hostname: host, Instance: c.last.Instance})
+		case c.last.Type == EventRegister:
+			d.Changes = append(d.Changes, InstanceDelta{Action: DeltaAdd, Hostname: host, Instance: c.last.Instance})
+		case known:
+			d.Changes = append(d.Changes, InstanceDelta{Action: DeltaDelete, Hostname: host})
+		}
+	}
+	return d, nil
+}
+
+// fullDelta 以全量实例作为增量结果。先取事件位置再取实例，之后的增量可能重复包含部分变更
+func (r *Registry) fullDelta(env, appid string) *DeltaData {
+	d := &DeltaData{Revision: r.events.lastSeq(), Full: true, Changes: make([]InstanceDelta, 0)}
+	if app, ok := r.getApplication(appid, env); ok {
+		for _, in := range app.GetAllInstances() {
+			d.Changes = append(d.Changes, InstanceDelta{Action: DeltaAdd, Hostname: in.Hostname, Instance: in})
+		}
+	}
+	return d
+}
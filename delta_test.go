@@ -0,0 +1,52 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFetchDelta(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	ctx := context.Background()
+	reg := func(host, version string) {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: host, Addrs: []string{"http://127.0.0.1:80"}, Version: version, Status: StatusUP}), time.Now().UnixNano())
+	}
+	reg("a", "v1")
+	reg("b", "v1")
+	reg("c", "v1")
+
+	full, err := r.FetchDelta(ctx, "test", "com.xx.app", 0)
+	if err != nil || !full.Full || len(full.Changes) != 3 {
+		t.Fatalf("unexpected full fetch %+v %v", full, err)
+	}
+
+	reg("a", "v2")                                             // 更新
+	r.Cancel("test", "com.xx.app", "b", time.Now().UnixNano()) // 下线
+	reg("d", "v1")                                             // 新增
+	reg("e", "v1")                                             // 新增后又下线，调用方不需要知道
+	r.Cancel("test", "com.xx.app", "e", time.Now().UnixNano())
+	reg("x", "v1")
+	d, err := r.FetchDelta(ctx, "test", "com.xx.app", full.Revision)
+	if err != nil || d.Full || d.Revision != r.LastEventSeq() {
+		t.Fatalf("unexpected delta %+v %v", d, err)
+	}
+	got := make(map[string]string)
+	for _, c := range d.Changes {
+		got[c.Hostname] = c.Action
+	}
+	want := map[string]string{"a": DeltaUpdate, "b": DeltaDelete, "d": DeltaAdd, "x": DeltaAdd}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected changes %v", got)
+	}
+	for host, action := range want {
+		if got[host] != action {
+			t.Fatalf("unexpected changes %v", got)
+		}
+	}
+
+	if d, _ = r.FetchDelta(ctx, "test", "com.xx.app", d.Revision); len(d.Changes) != 0 {
+		t.Fatalf("expect no changes, got %+v", d.Changes)
+	}
+}
@@ -0,0 +1,143 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrorCode 机器可读的错误码，各语言的 SDK 据此决定重试、重新注册或放弃，无需解析错误信息
+type ErrorCode string
+
+const (
+	CodeInvalidArgument     ErrorCode = "INVALID_ARGUMENT"      // 请求参数错误，不应重试
+	CodeUnauthenticated     ErrorCode = "UNAUTHENTICATED"       // 续约凭证或身份校验失败
+	CodePermissionDenied    ErrorCode = "PERMISSION_DENIED"     // 凭证不允许该操作
+	CodeAppNotFound         ErrorCode = "APP_NOT_FOUND"         // 应用不存在
+	CodeInstanceNotFound    ErrorCode = "INSTANCE_NOT_FOUND"    // 实例不存在，续约时应重新注册
+	CodeInstanceExists      ErrorCode = "INSTANCE_EXISTS"       // 实例已存在
+	CodeNoInstances         ErrorCode = "NO_INSTANCES"          // 没有满足条件的实例
+	CodeNotModified         ErrorCode = "NOT_MODIFIED"          // 调用方持有的数据已是最新
+	CodeStaleRegistration   ErrorCode = "STALE_REGISTRATION"    // 注册早于实例最近一次下线，不应重试
+	CodeAddrConflict        ErrorCode = "ADDR_CONFLICT"         // 地址已被其他实例注册
+	CodeReadOnly            ErrorCode = "READ_ONLY"             // 备用集群只读，应改写主集群
+	CodeRateLimited         ErrorCode = "RATE_LIMITED"          // 限流，按 retry_after_ms 等待后重试
+	CodeQuotaExceeded       ErrorCode = "QUOTA_EXCEEDED"        // 应用实例数达到配额，不应重试
+	CodeAppFrozen           ErrorCode = "APP_FROZEN"            // 应用已冻结，解除前不应重试
+	CodeOverloaded          ErrorCode = "OVERLOADED"            // 过载，按 retry_after_ms 等待后重试
+	CodeDeadlineExceeded    ErrorCode = "DEADLINE_EXCEEDED"     // 请求超时
+	CodeMaintenanceNotFound ErrorCode = "MAINTENANCE_NOT_FOUND" // 维护窗口不存在
+	CodeSessionNotFound     ErrorCode = "SESSION_NOT_FOUND"     // 会话不存在，应重新建立会话
+	CodeTaskNotFound        ErrorCode = "TASK_NOT_FOUND"        // 后台任务不存在
+	CodeTokenNotFound       ErrorCode = "TOKEN_NOT_FOUND"       // 令牌不存在或已过期
+	CodePeerRejected        ErrorCode = "PEER_REJECTED"         // 复制请求的对端未通过校验
+	CodePeerNotFound        ErrorCode = "PEER_NOT_FOUND"        // 对端节点不在集群节点列表中
+	CodeEventsTruncated     ErrorCode = "EVENTS_TRUNCATED"      // 请求的事件或历史已超出事件日志的保留范围
+	CodeInternal            ErrorCode = "INTERNAL"              // 其他错误
+)
+
+// ErrorInfo 跨协议的错误描述，HTTP 响应、会话应答和 gRPC 错误详情使用相同的结构
+type ErrorInfo struct {
+	Code      ErrorCode         `json:"code"`
+	Message   string            `json:"message"`
+	Retryable bool              `json:"retryable"`         // 原样重试是否可能成功
+	Details   map[string]string `json:"details,omitempty"` // 如 conflicting_dirty_timestamp、retry_after_ms
+}
+
+// 错误码对应的哨兵错误，客户端收到错误码后还原，调用方可以继续使用 errors.Is 判断
+var codeErrors = []struct {
+	code ErrorCode
+	err  error
+}{
+	{CodeInvalidArgument, ErrInvalidRequest},
+	{CodeUnauthenticated, ErrUnauthenticated},
+	{CodePermissionDenied, ErrPermissionDenied},
+	{CodeAppNotFound, ErrAppNotFound},
+	{CodeInstanceNotFound, ErrInstanceNotFound},
+	{CodeInstanceExists, ErrInstanceExists},
+	{CodeNoInstances, ErrNoInstances},
+	{CodeNotModified, ErrNotModified},
+	{CodeStaleRegistration, ErrStaleRegistration},
+	{CodeAddrConflict, ErrAddrConflict},
+	{CodeReadOnly, ErrReadOnly},
+	{CodeRateLimited, ErrRateLimited},
+	{CodeQuotaExceeded, ErrQuotaExceeded},
+	{CodeAppFrozen, ErrAppFrozen},
+	{CodeOverloaded, ErrOverloaded},
+	{CodeDeadlineExceeded, context.DeadlineExceeded},
+	{CodeMaintenanceNotFound, ErrMaintenanceNotFound},
+	{CodeSessionNotFound, ErrSessionNotFound},
+	{CodeTaskNotFound, ErrTaskNotFound},
+	{CodeTokenNotFound, ErrTokenNotFound},
+	{CodePeerRejected, ErrPeerRejected},
+	{CodePeerNotFound, ErrPeerNotFound},
+	{CodeEventsTruncated, ErrEventsTruncated},
+}
+
+// Err 返回错误码对应的哨兵错误，没有对应时返回 nil。带重试时长的背压错误还原为 BackpressureError，
+// 客户端可以据此用 StretchInterval 拉长轮询/心跳间隔
+func (e *ErrorInfo) Err() error {
+	for _, m := range codeErrors {
+		if m.code != e.Code {
+			continue
+		}
+		ms, err := strconv.ParseInt(e.Details["retry_after_ms"], 10, 64)
+		if err != nil {
+			return m.err
+		}
+		bp := &BackpressureError{Err: m.err, RetryAfter: time.Duration(ms) * time.Millisecond}
+		if ms, err := strconv.ParseInt(e.Details["poll_interval_ms"], 10, 64); err == nil {
+			bp.PollInterval = time.Duration(ms) * time.Millisecond
+		}
+		return bp
+	}
+	return nil
+}
+
+// ErrorInfoOf 把内部错误映射为 ErrorInfo，err 为 nil 时返回 nil
+func ErrorInfoOf(err error) *ErrorInfo {
+	if err == nil {
+		return nil
+	}
+	info := &ErrorInfo{Code: CodeInternal, Message: err.Error()}
+	for _, m := range codeErrors {
+		if errors.Is(err, m.err) {
+			info.Code = m.code
+			break
+		}
+	}
+	var (
+		ce *ConflictError
+		ae *AddrConflictError
+		se *StageTimeoutError
+		ie *InvalidRequestError
+	)
+	switch {
+	case errors.As(err, &ce):
+		info.Details = map[string]string{
+			"conflicting_dirty_timestamp": strconv.FormatInt(ce.Tombstone.DeletedAt, 10),
+		}
+	case errors.As(err, &ae):
+		o := ae.Conflict.Owner
+		info.Details = map[string]string{"addr": ae.Conflict.Addr, "owner_env": o.Env, "owner_appid": o.AppId, "owner_hostname": o.Hostname}
+	case errors.As(err, &se):
+		info.Details = map[string]string{"stage": se.Stage}
+	case errors.As(err, &ie):
+		info.Details = make(map[string]string, len(ie.Violations))
+		for _, v := range ie.Violations {
+			info.Details[v.Field] = v.Reason
+		}
+	}
+	if bp, ok := AsBackpressure(err); ok {
+		info.Details = map[string]string{"retry_after_ms": strconv.FormatInt(bp.RetryAfter.Milliseconds(), 10)}
+		if bp.PollInterval > 0 {
+			info.Details["poll_interval_ms"] = strconv.FormatInt(bp.PollInterval.Milliseconds(), 10)
+		}
+	}
+	switch info.Code {
+	case CodeRateLimited, CodeOverloaded, CodeDeadlineExceeded, CodeInternal:
+		info.Retryable = true
+	}
+	return info
+}
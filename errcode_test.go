@@ -0,0 +1,38 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestErrorInfoOf(t *testing.T) {
+	if ErrorInfoOf(nil) != nil {
+		t.Fatal("nil error should have no info")
+	}
+	conflict := fmt.Errorf("register: %w", &ConflictError{Tombstone: Tombstone{AppId: "a", Env: "test", Hostname: "h", DeletedAt: 42}})
+	info := ErrorInfoOf(conflict)
+	if info.Code != CodeStaleRegistration || info.Retryable || info.Details["conflicting_dirty_timestamp"] != "42" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if !errors.Is(info.Err(), ErrStaleRegistration) {
+		t.Fatalf("code should map back to sentinel, got %v", info.Err())
+	}
+
+	info = ErrorInfoOf(&BackpressureError{Err: ErrRateLimited, RetryAfter: 2 * time.Second})
+	if info.Code != CodeRateLimited || !info.Retryable || info.Details["retry_after_ms"] != "2000" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if bp, ok := AsBackpressure(info.Err()); !ok || !errors.Is(bp, ErrRateLimited) || bp.RetryAfter != 2*time.Second {
+		t.Fatalf("backpressure should survive the round trip, got %v", info.Err())
+	}
+	info = ErrorInfoOf(&StageTimeoutError{Stage: StageStore, Err: context.DeadlineExceeded})
+	if info.Code != CodeDeadlineExceeded || info.Details["stage"] != StageStore {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if info := ErrorInfoOf(errors.New("boom")); info.Code != CodeInternal || info.Err() != nil {
+		t.Fatalf("unexpected info %+v", info)
+	}
+}
@@ -0,0 +1,191 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrEventsTruncated 请求的事件已超出事件日志的保留范围，调用方需要全量拉取后再增量追赶
+var ErrEventsTruncated = errors.New("events truncated")
+
+// DefaultEventLogCapacity 事件日志默认保留的事件数
+const DefaultEventLogCapacity = 10000
+
+// EventType 注册表事件类型
+type EventType string
+
+const (
+	EventRegister EventType = "register" // 服务注册
+	EventCancel   EventType = "cancel"   // 服务下线
+	EventEvict    EventType = "evict"    // 服务剔除
+)
+
+// Event 注册表变更事件，Seq 单调递增
+type Event struct {
+	Seq       uint64    `json:"seq"`
+	Type      EventType `json:"type"`
+	Env       string    `json:"env"`
+	AppId     string    `json:"appId"`
+	Hostname  string    `json:"hostname"`
+	Timestamp int64     `json:"timestamp"`            // 事件写入日志的时间
+	Instance  *Instance `json:"instance,omitempty"`   // 事件发生后的实例快照
+	RequestID string    `json:"request_id,omitempty"` // 触发事件的请求 ID
+	Created   bool      `json:"created,omitempty"`    // 注册事件是否新增了实例
+	Zone      string    `json:"zone,omitempty"`       // 可用区故障事件的可用区
+
+	Source *RegistrationSource `json:"source,omitempty"` // 触发事件的请求来源
+}
+
+// eventLog 内存中的环形事件日志，events 定长，head 为最早一条事件的位置，n 为事件数。
+// 序号不持久化，进程重启后从 1 开始，epoch 用于区分不同进程的事件日志
+type eventLog struct {
+	lock     sync.RWMutex
+	epoch    string
+	events   []Event
+	head     int
+	n        int
+	capacity int
+	nextSeq  uint64
+	dropped  Event         // 因容量淘汰的最后一条事件，更早的位置无法重放
+	notify   chan struct{} // 有新事件时关闭并替换，用于唤醒等待者
+}
+
+func newEventLog(capacity int) *eventLog {
+	if capacity <= 0 {
+		capacity = DefaultEventLogCapacity
+	}
+	return &eventLog{
+		epoch:    NewRequestID(),
+		events:   make([]Event, capacity),
+		capacity: capacity,
+		nextSeq:  1,
+		notify:   make(chan struct{}),
+	}
+}
+
+// append 追加事件并分配序号
+func (l *eventLog) append(e Event) Event {
+	if e.Instance != nil {
+		e.Instance = copyInstance(e.Instance)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	// 在锁内取时间，保证事件时间与序号同序
+	e.Timestamp = time.Now().UnixNano()
+	e.Seq = l.nextSeq
+	l.nextSeq++
+	if l.n == l.capacity {
+		// 覆盖最早的事件
+		oldest := l.at(0)
+		l.dropped = Event{Seq: oldest.Seq, Timestamp: oldest.Timestamp}
+		l.events[l.head] = e
+		l.head = (l.head + 1) % l.capacity
+	} else {
+		l.events[(l.head+l.n)%l.capacity] = e
+		l.n++
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return e
+}
+
+// wait 返回在下一条事件写入时关闭的 channel
+func (l *eventLog) wait() <-chan struct{} {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.notify
+}
+
+// since 返回序号大于 seq 的事件，最多 limit 条，limit<=0 表示不限。
+// seq 大于最新序号说明来自重启前的事件日志，同样返回 ErrEventsTruncated
+func (l *eventLog) since(seq uint64, limit int) ([]Event, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if seq < l.dropped.Seq || seq >= l.nextSeq {
+		return nil, ErrEventsTruncated
+	}
+	i := sort.Search(l.n, func(i int) bool {
+		return l.at(i).Seq > seq
+	})
+	return l.collect(i, limit), nil
+}
+
+// sinceTime 返回发生时间晚于 ts 的事件，最多 limit 条
+func (l *eventLog) sinceTime(ts int64, limit int) ([]Event, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if l.dropped.Seq > 0 && ts < l.dropped.Timestamp {
+		return nil, ErrEventsTruncated
+	}
+	i := sort.Search(l.n, func(i int) bool {
+		return l.at(i).Timestamp > ts
+	})
+	return l.collect(i, limit), nil
+}
+
+// at 返回从最早一条起第 i 条事件
+func (l *eventLog) at(i int) *Event {
+	return &l.events[(l.head+i)%l.capacity]
+}
+
+func (l *eventLog) collect(i, limit int) []Event {
+	n := l.n - i
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	rs := make([]Event, n)
+	for j := range rs {
+		rs[j] = *l.at(i + j)
+		if rs[j].Instance != nil {
+			rs[j].Instance = copyInstance(rs[j].Instance)
+		}
+	}
+	return rs
+}
+
+// lastSeq 最新事件的序号
+func (l *eventLog) lastSeq() uint64 {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.nextSeq - 1
+}
+
+// Replay 重放序号大于 seq 的事件，limit<=0 表示不限。
+// 返回 ErrEventsTruncated 时调用方需要先全量拉取再从最新序号开始追赶
+func (r *Registry) Replay(seq uint64, limit int) ([]Event, error) {
+	return r.events.since(seq, limit)
+}
+
+// WaitEvents 同 Replay，没有新事件时阻塞直到有新事件或 ctx 结束
+func (r *Registry) WaitEvents(ctx context.Context, seq uint64, limit int) ([]Event, error) {
+	for {
+		wait := r.events.wait()
+		events, err := r.events.since(seq, limit)
+		if err != nil || len(events) > 0 {
+			return events, err
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ReplaySince 重放发生时间晚于 ts 的事件
+func (r *Registry) ReplaySince(ts int64, limit int) ([]Event, error) {
+	return r.events.sinceTime(ts, limit)
+}
+
+// LastEventSeq 最新事件的序号
+func (r *Registry) LastEventSeq() uint64 {
+	return r.events.lastSeq()
+}
+
+// EventEpoch 事件日志的标识，每次启动随机生成。持久化的事件序号需连同它一起保存，标识不同时序号已失效
+func (r *Registry) EventEpoch() string {
+	return r.events.epoch
+}
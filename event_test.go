@@ -0,0 +1,54 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	r := NewRegistry(WithEventLogCapacity(2))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.event", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.event", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	r.Cancel("test", "com.xx.event", "a", time.Now().UnixNano())
+
+	events, err := r.Replay(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Seq != 2 || events[1].Type != EventCancel || events[1].Hostname != "a" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	// 第一条事件已被淘汰
+	if _, err := r.Replay(0, 0); err != ErrEventsTruncated {
+		t.Fatalf("expect truncated, got %v", err)
+	}
+	events, _ = r.ReplaySince(events[0].Timestamp, 0)
+	if len(events) != 1 || events[0].Seq != r.LastEventSeq() {
+		t.Fatalf("unexpected events since timestamp: %+v", events)
+	}
+}
+
+func TestEventLogWrapsAround(t *testing.T) {
+	l := newEventLog(3)
+	for i := 0; i < 7; i++ {
+		app := "com.xx.a"
+		if i%2 == 1 {
+			app = "com.xx.b"
+		}
+		l.append(Event{Type: EventRegister, Env: "test", AppId: app})
+	}
+	events, err := l.since(4, 0)
+	if err != nil || len(events) != 3 || events[0].Seq != 5 || events[2].Seq != 7 {
+		t.Fatalf("unexpected events after wrap: %+v %v", events, err)
+	}
+	if events, _ := l.since(5, 1); len(events) != 1 || events[0].Seq != 6 {
+		t.Fatalf("limit should apply across the wrap: %+v", events)
+	}
+	// 清除应用后其余事件保持顺序，之后继续追加
+	l.purge("test", "com.xx.b")
+	l.append(Event{Type: EventCancel, Env: "test", AppId: "com.xx.a"})
+	events, _ = l.since(4, 0)
+	if len(events) != 3 || events[0].Seq != 5 || events[1].Seq != 7 || events[2].Seq != 8 {
+		t.Fatalf("unexpected events after purge: %+v", events)
+	}
+}
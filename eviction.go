@@ -0,0 +1,49 @@
+package registry_center
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// EvictionCandidate 待剔除的过期实例
+type EvictionCandidate struct {
+	Instance    *Instance
+	SignalScore int // 除续约新鲜度外的健康信号得分 0-100，见 HealthScore
+}
+
+// EvictionStats 一轮剔除时的注册表统计
+type EvictionStats struct {
+	Now     int64 // 本轮剔除的时间
+	Total   int   // 注册表中的实例总数
+	Expired int   // 过期实例数
+	Limit   int   // 本轮最多剔除的实例数，用于防止网络分区时大量误剔除
+}
+
+// EvictionStrategy 从过期实例中选出本轮要剔除的实例，返回超过 Limit 的部分会被忽略
+type EvictionStrategy interface {
+	Select(candidates []EvictionCandidate, stats EvictionStats) []EvictionCandidate
+}
+
+// RandomEviction 随机选择过期实例
+type RandomEviction struct{}
+
+func (RandomEviction) Select(candidates []EvictionCandidate, stats EvictionStats) []EvictionCandidate {
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	return candidates
+}
+
+// HealthAwareEviction 优先剔除健康信号差的实例，相同时先剔除续约最久远的，默认策略
+type HealthAwareEviction struct{}
+
+func (HealthAwareEviction) Select(candidates []EvictionCandidate, stats EvictionStats) []EvictionCandidate {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.SignalScore != b.SignalScore {
+			return a.SignalScore < b.SignalScore
+		}
+		return a.Instance.RenewTimestamp < b.Instance.RenewTimestamp
+	})
+	return candidates
+}
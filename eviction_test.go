@@ -0,0 +1,56 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictionPrefersUnhealthy(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		host := string(rune('a' + i))
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: host, Status: StatusUP}), now.UnixNano())
+	}
+	app, _ := r.getApplication("com.xx.app", "test")
+	// a-e 过期，a 最久远，e 健康检查失败
+	for i, host := range []string{"a", "b", "c", "d", "e"} {
+		app.instances[host].RenewTimestamp = now.Add(-time.Duration(200-i) * time.Second).UnixNano()
+	}
+	r.ReportHealthCheck("test", "com.xx.app", "e", false)
+
+	// 剔除上限为 3 个
+	r.evict()
+	for _, host := range []string{"e", "a", "b"} {
+		if _, ok := r.HealthScore("test", "com.xx.app", host); ok {
+			t.Fatalf("%s should be evicted", host)
+		}
+	}
+	for _, host := range []string{"c", "d"} {
+		if _, ok := r.HealthScore("test", "com.xx.app", host); !ok {
+			t.Fatalf("%s should be kept by eviction limit", host)
+		}
+	}
+}
+
+type evictAll struct{}
+
+func (evictAll) Select(candidates []EvictionCandidate, stats EvictionStats) []EvictionCandidate {
+	return candidates
+}
+
+func TestEvictionStrategyLimit(t *testing.T) {
+	r := NewRegistry(WithEvictionStrategy(evictAll{}))
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: string(rune('a' + i)), Status: StatusUP}), now.UnixNano())
+	}
+	app, _ := r.getApplication("com.xx.app", "test")
+	for _, in := range app.instances {
+		in.RenewTimestamp = now.Add(-time.Hour).UnixNano()
+	}
+	r.evict()
+	if n := len(app.GetAllInstances()); n != 17 {
+		t.Fatalf("strategy result should be clamped to eviction limit, %d instances left", n)
+	}
+}
@@ -0,0 +1,123 @@
+package registry_center
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// Experiment A/B 实验定义：调用方按 ClientKey 稳定哈希到某个分组，
+// 只获取该分组（Instance.Group）的实例，未命中任何分组的调用方使用对照组（未打分组标签的实例）
+type Experiment struct {
+	Env    string            `json:"env"`
+	AppId  string            `json:"appId"`
+	Name   string            `json:"name"`   // 实验名，参与哈希，修改后分组结果会重新打散
+	Groups []ExperimentGroup `json:"groups"` // 实验分组，流量占比之和不超过 100
+}
+
+type ExperimentGroup struct {
+	Name    string `json:"name"`    // 分组名，与 Instance.Group 对应
+	Percent int    `json:"percent"` // 流量占比，0~100
+}
+
+type experimentController struct {
+	experiments map[AppKey]*Experiment
+	lock        sync.RWMutex
+}
+
+func newExperimentController() *experimentController {
+	return &experimentController{
+		experiments: make(map[AppKey]*Experiment),
+	}
+}
+
+// SetExperiment 设置应用的 A/B 实验，覆盖已有实验
+func (r *Registry) SetExperiment(exp Experiment) error {
+	if exp.Env == "" || exp.AppId == "" || exp.Name == "" {
+		return errors.New("experiment env, appId and name are required")
+	}
+	var total int
+	for _, g := range exp.Groups {
+		if g.Name == "" || g.Percent < 0 {
+			return errors.New("invalid experiment group")
+		}
+		total += g.Percent
+	}
+	if total > 100 {
+		return errors.New("experiment groups exceed 100 percent")
+	}
+	exp.Groups = append([]ExperimentGroup(nil), exp.Groups...)
+	c := r.experiments
+	c.lock.Lock()
+	c.experiments[getKey(exp.AppId, exp.Env)] = &exp
+	c.lock.Unlock()
+	return nil
+}
+
+// GetExperiment 获取应用的 A/B 实验
+func (r *Registry) GetExperiment(env, appid string) (*Experiment, bool) {
+	c := r.experiments
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	exp, ok := c.experiments[getKey(appid, env)]
+	if !ok {
+		return nil, false
+	}
+	out := *exp
+	out.Groups = append([]ExperimentGroup(nil), exp.Groups...)
+	return &out, true
+}
+
+// DeleteExperiment 删除应用的 A/B 实验
+func (r *Registry) DeleteExperiment(env, appid string) {
+	c := r.experiments
+	c.lock.Lock()
+	delete(c.experiments, getKey(appid, env))
+	c.lock.Unlock()
+}
+
+// AssignGroup 返回 clientKey 在实验中被分配到的分组，空表示对照组
+func (exp *Experiment) AssignGroup(clientKey string) string {
+	h := fnv.New32a()
+	h.Write([]byte(exp.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(clientKey))
+	bucket := int(h.Sum32() % 100)
+	for _, g := range exp.Groups {
+		if bucket < g.Percent {
+			return g.Name
+		}
+		bucket -= g.Percent
+	}
+	return ""
+}
+
+// selectGroup 返回调用方所在分组及该分组的实例，分组内没有实例时退回对照组
+func (c *experimentController) selectGroup(env, appid, clientKey string, instances []*Instance) (string, []*Instance) {
+	c.lock.RLock()
+	exp, ok := c.experiments[getKey(appid, env)]
+	var group string
+	if ok {
+		group = exp.AssignGroup(clientKey)
+	}
+	c.lock.RUnlock()
+	if !ok {
+		return "", instances
+	}
+	if group != "" {
+		if rs := filterGroup(instances, group); len(rs) > 0 {
+			return group, rs
+		}
+	}
+	return "", filterGroup(instances, "")
+}
+
+func filterGroup(instances []*Instance, group string) []*Instance {
+	rs := make([]*Instance, 0, len(instances))
+	for _, in := range instances {
+		if in.Group == group {
+			rs = append(rs, in)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,40 @@
+package registry_center
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExperimentFetch(t *testing.T) {
+	r := NewRegistry()
+	for _, g := range []string{"", "blue"} {
+		in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ab", Hostname: "host-" + g, Status: StatusUP, Group: g})
+		r.Register(in, time.Now().UnixNano())
+	}
+	exp := Experiment{Env: "test", AppId: "com.xx.ab", Name: "new-ui", Groups: []ExperimentGroup{{Name: "blue", Percent: 50}}}
+	if err := r.SetExperiment(exp); err != nil {
+		t.Fatal(err)
+	}
+	var blue int
+	for i := 0; i < 200; i++ {
+		key := "user-" + strconv.Itoa(i)
+		data, err := r.FetchWithOptions("test", "com.xx.ab", StatusUP, 0, FetchOptions{ClientKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Instances) != 1 || data.Instances[0].Group != data.Group {
+			t.Fatalf("unexpected instances for group %q: %v", data.Group, data.Instances)
+		}
+		// 同一 clientKey 的分组结果稳定
+		if data.Group != exp.AssignGroup(key) {
+			t.Fatalf("assignment of %s is not stable", key)
+		}
+		if data.Group == "blue" {
+			blue++
+		}
+	}
+	if blue == 0 || blue == 200 {
+		t.Fatalf("unexpected distribution: %d/200 in blue", blue)
+	}
+}
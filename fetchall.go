@@ -0,0 +1,35 @@
+package registry_center
+
+import (
+	"context"
+	"time"
+)
+
+// FetchAll 返回环境下所有应用的实例，key 为 appId，供网关、控制台一次获取整个环境。
+// 实例带有健康分，不经过影子流量、实验分组和灰度权重处理
+func (r *Registry) FetchAll(env string) (map[string][]*Instance, error) {
+	return r.FetchAllContext(context.Background(), env)
+}
+
+// FetchAllContext 同 FetchAll，ctx 的 deadline 在排队及读取前检查
+func (r *Registry) FetchAllContext(ctx context.Context, env string) (rs map[string][]*Instance, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageCache); err != nil {
+		return nil, err
+	}
+	rs = make(map[string][]*Instance)
+	for _, app := range r.getAllApplications() {
+		if app.env != env || !r.Visible(ctx, env, app.appId) {
+			continue
+		}
+		if instances := r.applyHealthScores(app.GetAllInstances(), 0); len(instances) > 0 {
+			rs[app.appId] = instances
+		}
+	}
+	return rs, nil
+}
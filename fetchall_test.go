@@ -0,0 +1,27 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchAll(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	now := time.Now().UnixNano()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "a1", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "a2", Addrs: []string{"http://127.0.0.1:81"}, Status: StatusDown}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.b", Hostname: "b1", Addrs: []string{"http://127.0.0.1:82"}, Status: StatusUP}), now)
+	r.Register(NewInstance(&RequestRegister{Env: "online", AppId: "com.xx.c", Hostname: "c1", Addrs: []string{"http://127.0.0.1:83"}, Status: StatusUP}), now)
+
+	all, err := r.FetchAll("test")
+	if err != nil || len(all) != 2 || len(all["com.xx.a"]) != 2 || len(all["com.xx.b"]) != 1 {
+		t.Fatalf("unexpected result %v %v", all, err)
+	}
+	if all["com.xx.a"][0].HealthScore == 0 {
+		t.Fatal("instances should carry health scores")
+	}
+	if all, _ = r.FetchAll("dev"); len(all) != 0 {
+		t.Fatalf("unknown env should be empty, got %v", all)
+	}
+}
@@ -0,0 +1,129 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	register := func(host string) error {
+		_, err := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: host, Addrs: []string{"http://" + host + ":80"}, Status: StatusUP}), time.Now().UnixNano())
+		return err
+	}
+	if err := register("a"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := r.Freeze(ctx, AppFreeze{Env: "test", AppId: "com.xx.pay"}); err == nil {
+		t.Fatal("freeze without reason should be rejected")
+	}
+	if err := r.Freeze(ctx, AppFreeze{Env: "test", AppId: "com.xx.pay", Reason: "incident 42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mutations := map[string]error{
+		"register": register("b"),
+		"cancel":   func() error { _, err := r.Cancel("test", "com.xx.pay", "a", time.Now().UnixNano()); return err }(),
+		"set": func() error {
+			_, err := r.Set(ctx, SetRequest{Env: "test", AppId: "com.xx.pay", Hostnames: []string{"a"}, Status: StatusDown})
+			return err
+		}(),
+		"transfer": func() error {
+			_, err := r.Transfer(ctx, TransferRequest{Env: "test", AppId: "com.xx.pay", From: "a", To: "c"})
+			return err
+		}(),
+		"purge": func() error { _, err := r.Purge("test", "com.xx.pay", "com.xx.pay"); return err }(),
+	}
+	for name, err := range mutations {
+		if !errors.Is(err, ErrAppFrozen) {
+			t.Fatalf("%s should be rejected while frozen, got %v", name, err)
+		}
+	}
+	// 读取、续约照常，其他应用不受影响
+	if _, err := r.Renew("test", "com.xx.pay", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := r.Fetch("test", "com.xx.pay", StatusUP, 0); err != nil || len(data.Instances) != 1 {
+		t.Fatalf("fetch should be served: %v", err)
+	}
+	if _, err := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.other", Hostname: "a", Status: StatusUP}), time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	// 过期实例不剔除
+	app, _ := r.getApplication("com.xx.pay", "test")
+	app.lock.Lock()
+	app.instances["a"].RenewTimestamp -= int64(time.Hour)
+	app.lock.Unlock()
+	r.evict()
+	if _, ok := r.getInstance("test", "com.xx.pay", "a"); !ok {
+		t.Fatal("frozen app should not be evicted")
+	}
+
+	if fs := r.Frozen(); len(fs) != 1 || fs[0].Reason != "incident 42" || fs[0].Since == 0 {
+		t.Fatalf("unexpected frozen apps %+v", fs)
+	}
+	if !r.Unfreeze(ctx, "test", "com.xx.pay") || r.Unfreeze(ctx, "test", "com.xx.pay") {
+		t.Fatal("unfreeze should report whether the app was frozen")
+	}
+	if err := register("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 到期自动解除
+	if err := r.Freeze(ctx, AppFreeze{Env: "test", AppId: "com.xx.pay", Reason: "deploy", Until: time.Now().Add(time.Millisecond).UnixNano()}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if err := register("c"); err != nil {
+		t.Fatalf("expired freeze should not reject: %v", err)
+	}
+	if fs := r.Frozen(); len(fs) != 0 {
+		t.Fatalf("expired freeze should be dropped: %+v", fs)
+	}
+}
+
+func TestFreezeReplication(t *testing.T) {
+	peer := NewRegistry(WithStandby(FailoverConfig{}))
+	r := NewRegistry(WithReplicator(applyReplicator{peer: peer}))
+	ctx := context.Background()
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: "a", Addrs: []string{"http://10.0.0.1:80"}, Status: StatusUP})
+	if _, err := r.Register(in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Freeze(ctx, AppFreeze{Env: "test", AppId: "com.xx.pay", Reason: "incident 42"}); err != nil {
+		t.Fatal(err)
+	}
+	if frozen := peer.Frozen(); len(frozen) != 1 || frozen[0].Reason != "incident 42" {
+		t.Fatalf("freeze should be replicated, got %+v", frozen)
+	}
+	other := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: "b", Addrs: []string{"http://10.0.0.2:80"}, Status: StatusUP})
+	for _, op := range []ReplicationOp{
+		{Action: ActionRegister, Env: "test", AppId: "com.xx.pay", Hostname: "b", Instance: other, LatestTimestamp: time.Now().UnixNano()},
+		{Action: ActionCancel, Env: "test", AppId: "com.xx.pay", Hostname: "a", LatestTimestamp: time.Now().UnixNano()},
+		{Action: ActionPurge, Env: "test", AppId: "com.xx.pay"},
+	} {
+		if err := peer.ApplyReplication(op); !errors.Is(err, ErrAppFrozen) {
+			t.Fatalf("replicated %s should be rejected on a frozen app, got %v", op.Action, err)
+		}
+	}
+	if err := peer.ApplyReplication(ReplicationOp{Action: ActionRenew, Env: "test", AppId: "com.xx.pay", Hostname: "a"}); err != nil {
+		t.Fatalf("renew should be applied while frozen, got %v", err)
+	}
+
+	if !r.Unfreeze(ctx, "test", "com.xx.pay") {
+		t.Fatal("app should be unfrozen")
+	}
+	if frozen := peer.Frozen(); len(frozen) != 0 {
+		t.Fatalf("unfreeze should be replicated, got %+v", frozen)
+	}
+	if _, err := r.Cancel("test", "com.xx.pay", "a", time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := peer.Fetch("test", "com.xx.pay", StatusUP, 0); err == nil {
+		t.Fatal("cancel after unfreeze should reach the peer")
+	}
+}
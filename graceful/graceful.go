@@ -0,0 +1,93 @@
+// Package graceful 支持注册中心不停机重启：新进程继承监听 socket 并加载旧进程交接的租约状态
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	envListenFD  = "REGISTRY_LISTEN_FD"  // 继承的监听 socket 文件描述符
+	envStateFile = "REGISTRY_STATE_FILE" // 旧进程交接的状态文件
+)
+
+// Listen 监听地址，由旧进程拉起时直接使用继承的 socket
+func Listen(network, addr string) (net.Listener, error) {
+	if os.Getenv(envListenFD) == "" {
+		return net.Listen(network, addr)
+	}
+	var fd uintptr
+	if _, err := fmt.Sscan(os.Getenv(envListenFD), &fd); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", envListenFD, err)
+	}
+	f := os.NewFile(fd, "listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// StateFile 旧进程交接的状态文件，不是由旧进程拉起时返回空
+func StateFile() string {
+	return os.Getenv(envStateFile)
+}
+
+// HandoffOptions 交接配置
+type HandoffOptions struct {
+	StateFile string                          // 状态文件路径
+	Drain     func(ctx context.Context) error // 停止接受新请求并等待处理中的请求完成，如 http.Server.Shutdown
+	SaveState func(path string) error         // 保存状态，如 Registry.SaveState
+	Timeout   time.Duration                   // 排空请求的最长时间
+	Args      []string                        // 新进程的参数，默认与当前进程相同
+}
+
+// Handoff 把监听 socket 交给新进程：先复制 socket，再排空当前请求并保存状态，最后拉起新进程。
+// 排空期间 socket 保持打开，新连接在内核队列中等待新进程 accept，客户端不会被拒绝
+func Handoff(ln net.Listener, opts HandoffOptions) (*os.Process, error) {
+	fl, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("listener does not support file handoff")
+	}
+	f, err := fl.File()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Drain != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		err := opts.Drain(ctx)
+		cancel()
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+	}
+	if opts.SaveState != nil {
+		if err := opts.SaveState(opts.StateFile); err != nil {
+			return nil, err
+		}
+	}
+	args := opts.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles 中的第一个文件在新进程中的描述符为 3
+	cmd.ExtraFiles = []*os.File{f}
+	cmd.Env = append(os.Environ(), envListenFD+"=3", envStateFile+"="+opts.StateFile)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd.Process, nil
+}
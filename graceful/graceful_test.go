@@ -0,0 +1,104 @@
+package graceful
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestListen(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// 继承的 socket 优先于地址参数
+	t.Setenv(envListenFD, strconv.Itoa(int(f.Fd())))
+	inherited, err := Listen("tcp", "127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inherited.Close()
+	if inherited.Addr().String() != ln.Addr().String() {
+		t.Fatalf("expect inherited listener on %s, got %s", ln.Addr(), inherited.Addr())
+	}
+
+	t.Setenv(envListenFD, "fd")
+	if _, err := Listen("tcp", "127.0.0.1:0"); err == nil {
+		t.Fatal("expect error for an invalid fd")
+	}
+}
+
+// TestHandoffChild 由 TestHandoff 拉起的新进程：在继承的 socket 上接受一个连接并回写交接的状态
+func TestHandoffChild(t *testing.T) {
+	if os.Getenv(envListenFD) == "" {
+		t.Skip("only runs as the child of TestHandoff")
+	}
+	ln, err := Listen("tcp", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	ln.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	state, err := os.ReadFile(StateFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write(state)
+}
+
+func TestHandoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drained bool
+	proc, err := Handoff(ln, HandoffOptions{
+		StateFile: filepath.Join(t.TempDir(), "state.json"),
+		Drain: func(ctx context.Context) error {
+			drained = true
+			return nil
+		},
+		SaveState: func(path string) error {
+			if !drained {
+				t.Error("state saved before draining")
+			}
+			return os.WriteFile(path, []byte("leases"), 0o600)
+		},
+		Args: []string{"-test.run=^TestHandoffChild$"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧进程关闭监听后，新连接由新进程继承的 socket 接受
+	ln.Close()
+	conn, err := net.DialTimeout("tcp", ln.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	state, err := io.ReadAll(conn)
+	if err != nil || string(state) != "leases" {
+		t.Fatalf("child should serve the handed off state, got %q %v", state, err)
+	}
+	if ps, err := proc.Wait(); err != nil || !ps.Success() {
+		t.Fatalf("child exited with %v %v", ps, err)
+	}
+}
@@ -0,0 +1,174 @@
+package registry_center
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// 健康分的计算参数
+const (
+	MaxHealthScore   = 100
+	leaseFreshPeriod = 30 * time.Second // 续约间隔内视为完全新鲜
+	leaseExpiry      = 90 * time.Second // 超过该时长未续约的实例会被剔除
+	feedbackDecay    = 0.9              // 调用方反馈错误率的指数衰减系数
+)
+
+// instanceHealth 单个实例的健康信号
+type instanceHealth struct {
+	checkFailures int      // 主动健康检查连续失败次数，0 表示最近一次通过或尚未检查
+	errorRate     float64  // 调用方反馈的错误率，指数加权移动平均
+	override      *float64 // 人工指定的健康分，设置后忽略其他信号
+}
+
+// healthController 实例健康分，综合续约新鲜度、主动健康检查结果、调用方错误反馈和人工指定
+type healthController struct {
+	lock      sync.RWMutex
+	instances map[instanceKey]*instanceHealth
+}
+
+func newHealthController() *healthController {
+	return &healthController{instances: make(map[instanceKey]*instanceHealth)}
+}
+
+func (c *healthController) get(key instanceKey) *instanceHealth {
+	h, ok := c.instances[key]
+	if !ok {
+		h = &instanceHealth{}
+		c.instances[key] = h
+	}
+	return h
+}
+
+// score 计算实例健康分，各信号的得分相乘：任一信号变差都会拉低总分
+func (c *healthController) score(in *Instance, now int64) int {
+	s, override := c.signalScore(in)
+	if !override {
+		s *= leaseScore(now - in.RenewTimestamp)
+	}
+	return int(s*MaxHealthScore + 0.5)
+}
+
+// signalScore 除续约新鲜度外的健康信号得分，范围 0-1，override 表示使用了人工指定的健康分
+func (c *healthController) signalScore(in *Instance) (s float64, override bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	h, ok := c.instances[getInstanceKey(in.AppId, in.Env, in.Hostname)]
+	if !ok {
+		return 1, false
+	}
+	if h.override != nil {
+		return *h.override / MaxHealthScore, true
+	}
+	return 1 / float64(1+h.checkFailures) * (1 - h.errorRate), false
+}
+
+// leaseScore 续约新鲜度得分，续约间隔内为 1，之后线性下降，到剔除阈值时为 0
+func leaseScore(age int64) float64 {
+	switch {
+	case age <= int64(leaseFreshPeriod):
+		return 1
+	case age >= int64(leaseExpiry):
+		return 0
+	}
+	return 1 - float64(age-int64(leaseFreshPeriod))/float64(leaseExpiry-leaseFreshPeriod)
+}
+
+func (c *healthController) remove(key instanceKey) {
+	c.lock.Lock()
+	delete(c.instances, key)
+	c.lock.Unlock()
+}
+
+// move 把人工指定的健康分转给替换原实例的新实例，其余信号属于原主机，一并删除
+func (c *healthController) move(from, to instanceKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h, ok := c.instances[from]
+	delete(c.instances, from)
+	if ok && h.override != nil {
+		c.get(to).override = h.override
+	}
+}
+
+// purge 删除应用所有实例的健康信号
+func (c *healthController) purge(appKey AppKey) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key := range c.instances {
+		if key.app == appKey {
+			delete(c.instances, key)
+		}
+	}
+}
+
+// ReportHealthCheck 记录一次对实例的主动健康检查结果
+func (r *Registry) ReportHealthCheck(env, appid, hostname string, passed bool) {
+	c := r.health
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h := c.get(getInstanceKey(appid, env, hostname))
+	if passed {
+		h.checkFailures = 0
+	} else {
+		h.checkFailures++
+	}
+}
+
+// ReportFeedback 记录调用方对实例的一次调用结果
+func (r *Registry) ReportFeedback(env, appid, hostname string, success bool) {
+	c := r.health
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h := c.get(getInstanceKey(appid, env, hostname))
+	var e float64
+	if !success {
+		e = 1
+	}
+	h.errorRate = feedbackDecay*h.errorRate + (1-feedbackDecay)*e
+}
+
+// SetHealthOverride 人工指定实例的健康分，用于排障时摘除或强制保留实例
+func (r *Registry) SetHealthOverride(env, appid, hostname string, score int) error {
+	if score < 0 || score > MaxHealthScore {
+		return errors.New("invalid health score")
+	}
+	c := r.health
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s := float64(score)
+	c.get(getInstanceKey(appid, env, hostname)).override = &s
+	return nil
+}
+
+// ClearHealthOverride 取消人工指定的健康分
+func (r *Registry) ClearHealthOverride(env, appid, hostname string) {
+	c := r.health
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if h, ok := c.instances[getInstanceKey(appid, env, hostname)]; ok {
+		h.override = nil
+	}
+}
+
+// HealthScore 返回实例当前的健康分，范围 0-100
+func (r *Registry) HealthScore(env, appid, hostname string) (int, bool) {
+	in, ok := r.getInstance(env, appid, hostname)
+	if !ok {
+		return 0, false
+	}
+	return r.health.score(in, time.Now().UnixNano()), true
+}
+
+// applyHealthScores 填充实例健康分并过滤掉低于 minScore 的实例
+func (r *Registry) applyHealthScores(instances []*Instance, minScore int) []*Instance {
+	now := time.Now().UnixNano()
+	rs := instances[:0]
+	for _, in := range instances {
+		in.HealthScore = r.health.score(in, now)
+		if in.HealthScore >= minScore {
+			rs = append(rs, in)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,50 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthScore(t *testing.T) {
+	r := NewRegistry()
+	for _, host := range []string{"a", "b", "c"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: host, Status: StatusUP}), time.Now().UnixNano())
+	}
+	if s, _ := r.HealthScore("test", "com.xx.app", "a"); s != MaxHealthScore {
+		t.Fatalf("fresh instance should be fully healthy, got %d", s)
+	}
+	r.ReportHealthCheck("test", "com.xx.app", "b", false)
+	if s, _ := r.HealthScore("test", "com.xx.app", "b"); s != 50 {
+		t.Fatalf("failing health check should halve score, got %d", s)
+	}
+	for i := 0; i < 10; i++ {
+		r.ReportFeedback("test", "com.xx.app", "c", false)
+	}
+	if s, _ := r.HealthScore("test", "com.xx.app", "c"); s >= 50 {
+		t.Fatalf("client errors should lower score, got %d", s)
+	}
+
+	c, err := r.FetchWithOptions("test", "com.xx.app", StatusUP, 0, FetchOptions{MinHealthScore: 80})
+	if err != nil || len(c.Instances) != 1 || c.Instances[0].Hostname != "a" || c.Instances[0].HealthScore != MaxHealthScore {
+		t.Fatalf("unexpected filtered instances %+v %v", c, err)
+	}
+
+	r.SetHealthOverride("test", "com.xx.app", "b", 90)
+	c, _ = r.FetchWithOptions("test", "com.xx.app", StatusUP, 0, FetchOptions{MinHealthScore: 80})
+	if len(c.Instances) != 2 {
+		t.Fatalf("override should take precedence, got %d instances", len(c.Instances))
+	}
+	r.ClearHealthOverride("test", "com.xx.app", "b")
+	if s, _ := r.HealthScore("test", "com.xx.app", "b"); s != 50 {
+		t.Fatalf("cleared override should restore computed score, got %d", s)
+	}
+}
+
+func TestLeaseScore(t *testing.T) {
+	if leaseScore(int64(10*time.Second)) != 1 || leaseScore(int64(90*time.Second)) != 0 {
+		t.Fatal("unexpected lease score bounds")
+	}
+	if s := leaseScore(int64(60 * time.Second)); s != 0.5 {
+		t.Fatalf("expect half score at 60s, got %v", s)
+	}
+}
@@ -0,0 +1,142 @@
+package registry_center
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 活动热力图的默认分桶
+const (
+	DefaultHeatmapBucket = 5 * time.Second
+	DefaultHeatmapWindow = 15 * time.Minute
+)
+
+// 活动类型
+const (
+	activityRenew = iota
+	activityRegister
+	activityCancel
+	activityEvict
+	activityKinds
+)
+
+// HeatmapQuery 热力图查询条件
+type HeatmapQuery struct {
+	Env    string
+	AppId  string        // 为空时返回环境下所有有活动的应用
+	Window time.Duration // 最近多长时间，0 或超过保留时长时返回全部保留的分桶
+}
+
+// Heatmap 按时间分桶的续约、注册、下线次数，各应用的数组与分桶一一对应，最后一个分桶为当前分桶
+type Heatmap struct {
+	Env     string        `json:"env"`
+	Start   int64         `json:"start"`  // 第一个分桶的开始时间
+	Bucket  time.Duration `json:"bucket"` // 分桶宽度
+	Buckets int           `json:"buckets"`
+	Apps    []AppHeatmap  `json:"apps"` // 按 appId 排序
+}
+
+// AppHeatmap 一个应用在各分桶内的活动次数
+type AppHeatmap struct {
+	AppId         string   `json:"appId"`
+	Renewals      []uint32 `json:"renewals"`
+	Registrations []uint32 `json:"registrations"`
+	Cancellations []uint32 `json:"cancellations"`
+	Evictions     []uint32 `json:"evictions"` // 未续约被剔除
+}
+
+// heatmapSlot 环形数组中的一个分桶
+type heatmapSlot struct {
+	start  int64 // 分桶开始时间，与查询的分桶不一致时视为空
+	counts map[AppKey]*[activityKinds]uint32
+}
+
+// activityTracker 最近一段时间各应用的活动次数，按固定宽度分桶的环形数组，只统计本节点处理的请求
+type activityTracker struct {
+	lock   sync.Mutex
+	bucket int64
+	slots  []heatmapSlot
+}
+
+func newActivityTracker(bucket, window time.Duration) *activityTracker {
+	if bucket <= 0 {
+		bucket = DefaultHeatmapBucket
+	}
+	if window < bucket {
+		window = DefaultHeatmapWindow
+	}
+	return &activityTracker{
+		bucket: int64(bucket),
+		slots:  make([]heatmapSlot, int(window/bucket)),
+	}
+}
+
+func (t *activityTracker) slot(start int64) *heatmapSlot {
+	return &t.slots[(start/t.bucket)%int64(len(t.slots))]
+}
+
+// record 记录一次活动
+func (t *activityTracker) record(env, appid string, kind int) {
+	start := time.Now().UnixNano() / t.bucket * t.bucket
+	key := getKey(appid, env)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	s := t.slot(start)
+	if s.start != start {
+		s.start, s.counts = start, make(map[AppKey]*[activityKinds]uint32)
+	}
+	c, ok := s.counts[key]
+	if !ok {
+		c = new([activityKinds]uint32)
+		s.counts[key] = c
+	}
+	c[kind]++
+}
+
+// Heatmap 返回最近一段时间内环境下各应用按时间分桶的续约、注册、下线及剔除次数，用于对照心跳中断与网络、发布事件。
+// 只包含对 ctx 中调用方可见的应用
+func (r *Registry) Heatmap(ctx context.Context, q HeatmapQuery) *Heatmap {
+	t := r.activity
+	n := len(t.slots)
+	if q.Window > 0 && int64(q.Window) < int64(n)*t.bucket {
+		n = int((int64(q.Window) + t.bucket - 1) / t.bucket)
+	}
+	last := time.Now().UnixNano() / t.bucket * t.bucket
+	first := last - int64(n-1)*t.bucket
+	h := &Heatmap{Env: q.Env, Start: first, Bucket: time.Duration(t.bucket), Buckets: n, Apps: make([]AppHeatmap, 0)}
+	rows := make(map[AppKey]*AppHeatmap)
+	t.lock.Lock()
+	for i := 0; i < n; i++ {
+		s := t.slot(first + int64(i)*t.bucket)
+		if s.start != first+int64(i)*t.bucket {
+			continue
+		}
+		for key, c := range s.counts {
+			if key.Env != q.Env || q.AppId != "" && key.AppId != q.AppId {
+				continue
+			}
+			row, ok := rows[key]
+			if !ok {
+				row = &AppHeatmap{AppId: key.AppId, Renewals: make([]uint32, n), Registrations: make([]uint32, n),
+					Cancellations: make([]uint32, n), Evictions: make([]uint32, n)}
+				rows[key] = row
+			}
+			row.Renewals[i] = c[activityRenew]
+			row.Registrations[i] = c[activityRegister]
+			row.Cancellations[i] = c[activityCancel]
+			row.Evictions[i] = c[activityEvict]
+		}
+	}
+	t.lock.Unlock()
+	for _, row := range rows {
+		if r.Visible(ctx, q.Env, row.AppId) {
+			h.Apps = append(h.Apps, *row)
+		}
+	}
+	sort.Slice(h.Apps, func(i, j int) bool {
+		return h.Apps[i].AppId < h.Apps[j].AppId
+	})
+	return h
+}
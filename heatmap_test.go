@@ -0,0 +1,47 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHeatmap(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(WithHeatmap(time.Second, time.Minute))
+	defer r.Close()
+	r.Register(&Instance{Env: "test", AppId: "com.xx.a", Hostname: "h1", Status: StatusUP}, 1)
+	r.Register(&Instance{Env: "test", AppId: "com.xx.a", Hostname: "h2", Status: StatusUP}, 2)
+	r.Register(&Instance{Env: "test", AppId: "com.xx.b", Hostname: "h1", Status: StatusUP}, 3)
+	r.Register(&Instance{Env: "prod", AppId: "com.xx.a", Hostname: "h1", Status: StatusUP}, 4)
+	for i := 0; i < 3; i++ {
+		r.Renew("test", "com.xx.a", "h1")
+	}
+	r.Cancel("test", "com.xx.a", "h2", 5)
+
+	h := r.Heatmap(ctx, HeatmapQuery{Env: "test"})
+	if h.Buckets != 60 || h.Bucket != time.Second || len(h.Apps) != 2 || h.Apps[0].AppId != "com.xx.a" {
+		t.Fatalf("unexpected heatmap %+v", h)
+	}
+	sum := func(vs []uint32) (n uint32) {
+		for _, v := range vs {
+			n += v
+		}
+		return n
+	}
+	a := h.Apps[0]
+	if len(a.Renewals) != h.Buckets || sum(a.Renewals) != 3 || sum(a.Registrations) != 2 || sum(a.Cancellations) != 1 || sum(a.Evictions) != 0 {
+		t.Fatalf("unexpected counts %+v", a)
+	}
+	if h := r.Heatmap(ctx, HeatmapQuery{Env: "test", AppId: "com.xx.b", Window: 10 * time.Second}); h.Buckets != 10 || len(h.Apps) != 1 || sum(h.Apps[0].Registrations) != 1 {
+		t.Fatalf("unexpected filtered heatmap %+v", h)
+	}
+
+	// 超过保留时长的分桶不再计入
+	old := time.Now().Add(-2*time.Minute).UnixNano() / int64(time.Second) * int64(time.Second)
+	s := r.activity.slot(old)
+	s.start, s.counts = old, map[AppKey]*[activityKinds]uint32{getKey("com.xx.c", "test"): {5}}
+	if h := r.Heatmap(ctx, HeatmapQuery{Env: "test", AppId: "com.xx.c"}); len(h.Apps) != 0 {
+		t.Fatalf("expired bucket should be ignored, got %+v", h.Apps)
+	}
+}
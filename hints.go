@@ -0,0 +1,57 @@
+package registry_center
+
+import (
+	"sync"
+	"time"
+)
+
+// 默认建议的客户端轮询间隔
+const (
+	DefaultPollInterval         = 30 * time.Second
+	DefaultDegradedPollInterval = 2 * time.Minute
+)
+
+// CacheHints 随 Fetch 响应下发给客户端的缓存提示，客户端据此调整轮询频率
+type CacheHints struct {
+	PollInterval   time.Duration `json:"poll_interval"`   // 建议的轮询间隔
+	DeltaSupported bool          `json:"delta_supported"` // 是否支持 FetchDelta 增量获取
+	Load           string        `json:"load"`            // 服务端运行模式，normal 或 degraded
+}
+
+// pollHints 运维统一调整的轮询间隔
+type pollHints struct {
+	lock     sync.RWMutex
+	normal   time.Duration
+	degraded time.Duration
+}
+
+func newPollHints() *pollHints {
+	return &pollHints{normal: DefaultPollInterval, degraded: DefaultDegradedPollInterval}
+}
+
+// SetPollInterval 设置下发给客户端的建议轮询间隔，degraded 为过载降级时使用的间隔，
+// 用于在服务端统一调节整个集群的轮询压力
+func (r *Registry) SetPollInterval(normal, degraded time.Duration) {
+	h := r.pollHints
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if normal > 0 {
+		h.normal = normal
+	}
+	if degraded > 0 {
+		h.degraded = degraded
+	}
+}
+
+// cacheHints 按当前运行模式生成缓存提示
+func (r *Registry) cacheHints() *CacheHints {
+	h := r.pollHints
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	hints := &CacheHints{PollInterval: h.normal, DeltaSupported: true, Load: ModeNormal}
+	if r.Degraded() {
+		hints.PollInterval = h.degraded
+		hints.Load = ModeDegraded
+	}
+	return hints
+}
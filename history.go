@@ -0,0 +1,105 @@
+package registry_center
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// HistoryData 应用在过去某一时刻的实例列表，由事件日志重建
+type HistoryData struct {
+	At        int64       `json:"at"` // 查询的时刻，纳秒时间戳
+	Instances []*Instance `json:"instances"`
+	Seq       uint64      `json:"seq"` // 该时刻之前的最后一个事件序号，可用 Replay 从此处重放之后的变化
+	// Complete 为 false 表示部分早期事件已被淘汰或随 Purge 清除，
+	// 此前注册、之后才有变化的实例按其之后的第一条事件近似还原，可能缺失或不准确
+	Complete bool `json:"complete"`
+}
+
+// retained 返回日志中保留的所有事件及最后一条被淘汰的事件
+func (l *eventLog) retained() ([]Event, Event) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return l.collect(0, 0), l.dropped
+}
+
+// FetchAt 重建应用在 at（纳秒时间戳）时刻的实例列表，用于事后复盘。
+// at 早于事件日志的保留范围时返回 ErrEventsTruncated
+func (r *Registry) FetchAt(ctx context.Context, env, appid string, at int64) (rs *HistoryData, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageCache); err != nil {
+		return nil, err
+	}
+	if err := r.CheckVisible(ctx, env, appid); err != nil {
+		return nil, err
+	}
+	events, dropped := r.events.retained()
+	if dropped.Seq > 0 && at < dropped.Timestamp {
+		return nil, ErrEventsTruncated
+	}
+	rs = &HistoryData{At: at, Instances: make([]*Instance, 0), Complete: dropped.Seq == 0}
+	state := make(map[string]*Instance)
+	known := make(map[string]bool)   // at 之前有事件的实例，状态由事件确定
+	after := make(map[string]*Event) // at 之后实例的第一条事件
+	reset := false                   // at 之前应用被清除过，之后出现的实例都有事件记录
+	for i := range events {
+		e := &events[i]
+		if e.Timestamp <= at {
+			rs.Seq = e.Seq
+		}
+		if e.Env != env || e.AppId != appid {
+			continue
+		}
+		if e.Timestamp > at {
+			if e.Type == EventPurge {
+				rs.Complete = false
+			}
+			if e.Hostname != "" && !known[e.Hostname] && after[e.Hostname] == nil {
+				after[e.Hostname] = e
+			}
+			continue
+		}
+		switch e.Type {
+		case EventRegister:
+			state[e.Hostname] = e.Instance
+			known[e.Hostname] = true
+		case EventCancel, EventEvict:
+			delete(state, e.Hostname)
+			known[e.Hostname] = true
+		case EventPurge, EventAppRemoved:
+			state = make(map[string]*Instance)
+			reset = true
+		}
+	}
+	if !reset {
+		// 保留范围内没有 at 之前事件的实例：之后首次注册的在 at 时不存在，
+		// 其余的在 at 时已存在，用之后第一条事件或当前状态近似
+		var current []*Instance
+		if app, ok := r.getApplication(appid, env); ok {
+			current = app.GetAllInstances()
+		}
+		for _, in := range current {
+			if !known[in.Hostname] && after[in.Hostname] == nil && in.RegTimestamp <= at {
+				state[in.Hostname] = in
+			}
+		}
+		for host, e := range after {
+			if e.Instance != nil && (e.Type != EventRegister || !e.Created) {
+				state[host] = e.Instance
+				rs.Complete = false
+			}
+		}
+	}
+	for _, in := range state {
+		rs.Instances = append(rs.Instances, copyInstance(in))
+	}
+	sort.Slice(rs.Instances, func(i, j int) bool {
+		return rs.Instances[i].Hostname < rs.Instances[j].Hostname
+	})
+	return rs, nil
+}
@@ -0,0 +1,49 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFetchAt(t *testing.T) {
+	r := NewRegistry(WithEventLogCapacity(4))
+	register := func(host string) {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.hist", Hostname: host, Status: StatusUP}), time.Now().UnixNano())
+	}
+	hosts := func(d *HistoryData) (rs []string) {
+		for _, in := range d.Instances {
+			rs = append(rs, in.Hostname)
+		}
+		return rs
+	}
+	ctx := context.Background()
+	before := time.Now().UnixNano()
+	register("a")
+	register("b")
+	t1 := time.Now().UnixNano()
+	r.Cancel("test", "com.xx.hist", "a", time.Now().UnixNano())
+	register("c")
+	t2 := time.Now().UnixNano()
+
+	for _, c := range []struct {
+		at   int64
+		want string
+	}{{before, "[]"}, {t1, "[a b]"}, {t2, "[b c]"}} {
+		d, err := r.FetchAt(ctx, "test", "com.xx.hist", c.at)
+		if err != nil || !d.Complete || fmt.Sprint(hosts(d)) != c.want {
+			t.Fatalf("at %d: expect %s, got %v %+v %v", c.at, c.want, hosts(d), d, err)
+		}
+	}
+
+	// 淘汰最早的事件后，保留范围之前的时刻无法重建
+	register("d")
+	if _, err := r.FetchAt(ctx, "test", "com.xx.hist", before); !errors.Is(err, ErrEventsTruncated) {
+		t.Fatalf("expect truncated, got %v", err)
+	}
+	if d, err := r.FetchAt(ctx, "test", "com.xx.hist", t2); err != nil || fmt.Sprint(hosts(d)) != "[b c]" {
+		t.Fatalf("unexpected history %v %v", hosts(d), err)
+	}
+}
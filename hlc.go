@@ -0,0 +1,90 @@
+package registry_center
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// HLC 混合逻辑时钟，用于在节点间时钟存在偏差时确定性地解决复制冲突
+type HLC struct {
+	WallTime int64  `json:"wall_time"` // 物理时间，单调不减
+	Logical  uint32 `json:"logical"`   // 物理时间相同时的逻辑计数
+	Node     string `json:"node"`      // 产生该时间戳的节点，前两者都相同时用于打破平局
+}
+
+// IsZero 是否未设置
+func (c HLC) IsZero() bool {
+	return c.WallTime == 0 && c.Logical == 0
+}
+
+// Compare 比较两个时间戳，返回 -1、0、1
+func (c HLC) Compare(o HLC) int {
+	switch {
+	case c.WallTime != o.WallTime:
+		return cmpInt64(c.WallTime, o.WallTime)
+	case c.Logical != o.Logical:
+		return cmpInt64(int64(c.Logical), int64(o.Logical))
+	case c.Node < o.Node:
+		return -1
+	case c.Node > o.Node:
+		return 1
+	}
+	return 0
+}
+
+func cmpInt64(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// hlcClock 本节点的混合逻辑时钟
+type hlcClock struct {
+	lock sync.Mutex
+	node string
+	last HLC
+}
+
+func newHLCClock(node string) *hlcClock {
+	if node == "" {
+		node, _ = os.Hostname()
+	}
+	return &hlcClock{node: node}
+}
+
+// now 为本地事件生成时间戳
+func (c *hlcClock) now() HLC {
+	wall := time.Now().UnixNano()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if wall > c.last.WallTime {
+		c.last = HLC{WallTime: wall}
+	} else {
+		c.last.Logical++
+	}
+	c.last.Node = c.node
+	return c.last
+}
+
+// update 收到对端的时间戳后推进本地时钟，保证之后生成的时间戳大于已见过的所有时间戳
+func (c *hlcClock) update(remote HLC) {
+	wall := time.Now().UnixNano()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	switch {
+	case wall > c.last.WallTime && wall > remote.WallTime:
+		c.last = HLC{WallTime: wall}
+	case remote.WallTime > c.last.WallTime:
+		c.last = HLC{WallTime: remote.WallTime, Logical: remote.Logical + 1}
+	case remote.WallTime == c.last.WallTime && remote.Logical >= c.last.Logical:
+		c.last.Logical = remote.Logical + 1
+	default:
+		c.last.Logical++
+	}
+	c.last.Node = c.node
+}
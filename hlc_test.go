@@ -0,0 +1,50 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHLCClock(t *testing.T) {
+	c := newHLCClock("b")
+	a := c.now()
+	if b := c.now(); b.Compare(a) <= 0 {
+		t.Fatalf("clock should be monotonic: %+v <= %+v", b, a)
+	}
+	// 对端时钟快一小时
+	remote := HLC{WallTime: time.Now().Add(time.Hour).UnixNano(), Logical: 3, Node: "a"}
+	c.update(remote)
+	if next := c.now(); next.Compare(remote) <= 0 {
+		t.Fatalf("clock should move past remote timestamp: %+v <= %+v", next, remote)
+	}
+	if (HLC{WallTime: 1, Node: "a"}).Compare(HLC{WallTime: 1, Node: "b"}) >= 0 {
+		t.Fatal("node id should break ties")
+	}
+}
+
+func TestReplicationConflictWithClockSkew(t *testing.T) {
+	r := NewRegistry(WithNodeID("b"))
+	// 时钟快的节点 a 上的注册先复制过来
+	remote := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "h", Version: "v1", Status: StatusUP})
+	remote.Clock = HLC{WallTime: time.Now().Add(time.Hour).UnixNano(), Node: "a"}
+	remote.DirtyTimestamp = remote.Clock.WallTime
+	if err := r.ApplyReplication(ReplicationOp{Action: ActionRegister, Instance: remote, LatestTimestamp: 1}); err != nil {
+		t.Fatal(err)
+	}
+	// 之后本节点收到的注册虽然墙上时间更早，但因果上更新，应当胜出
+	local := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "h", Version: "v2", Status: StatusUP})
+	r.Register(local, 2)
+	in, _ := r.getInstance("test", "com.xx.app", "h")
+	if in.Version != "v2" {
+		t.Fatalf("causally later register should win, got %s", in.Version)
+	}
+
+	// 比当前注册更早的下线请求乱序到达时被忽略
+	stale := ReplicationOp{Action: ActionCancel, Env: "test", AppId: "com.xx.app", Hostname: "h", Clock: remote.Clock, LatestTimestamp: 3}
+	if err := r.ApplyReplication(stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.getInstance("test", "com.xx.app", "h"); !ok {
+		t.Fatal("stale cancel should not remove newer registration")
+	}
+}
@@ -0,0 +1,335 @@
+package registry_center
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// 实例结构版本。注册表始终以 v1 的 Instance 存储，v2 结构由其转换得到。注册产生的实例 Addrs 与 Endpoints 一一对应，
+// 两者可无损互转，原始地址（含路径、协议大小写）保留在端口中。由开关决定 API 默认输出哪一种，客户端也可按请求指定，大规模集群可以逐个应用迁移而不必同时切换
+const (
+	InstanceSchemaV1 = "v1"
+	InstanceSchemaV2 = "v2"
+)
+
+// 实例状态在 v2 结构中的名称
+const (
+	StatusNameUP   = "UP"
+	StatusNameDown = "DOWN"
+)
+
+// InstanceV2 v2 结构的服务实例：带实例 ID，地址拆分为带名称的端口，可用区单独成字段，评分归入 Scores
+type InstanceV2 struct {
+	InstanceID string              `json:"instance_id"` // 未指定时为 env/appid/hostname
+	Env        string              `json:"env"`
+	AppId      string              `json:"appId"`
+	Hostname   string              `json:"hostname"`
+	Version    string              `json:"version"`
+	Status     string              `json:"status"` // UP、DOWN，其他状态为数字
+	Weight     uint32              `json:"weight"`
+	Group      string              `json:"group,omitempty"`
+	Zone       string              `json:"zone,omitempty"` // 对应 v1 元数据中的 zone
+	Ports      []Port              `json:"ports"`
+	Metadata   map[string]string   `json:"metadata,omitempty"` // 不含 zone
+	Scores     Scores              `json:"scores"`
+	Identity   *Identity           `json:"identity,omitempty"`
+	Clock      HLC                 `json:"clock"`
+	Source     *RegistrationSource `json:"source,omitempty"`
+	Timestamps InstanceTimestamps  `json:"timestamps"`
+}
+
+// Port 实例的一个端口，对应 v1 的一个 Endpoint 及其原始地址
+type Port struct {
+	Name   string `json:"name,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+	Host   string `json:"host"`
+	Port   int    `json:"port,omitempty"` // 地址未带端口时为 0
+	Secure bool   `json:"secure"`
+	Addr   string `json:"addr,omitempty"` // 注册时的原始地址，与 scheme://host:port 相同（不带路径等）时为空
+}
+
+// Scores 实例的评分
+type Scores struct {
+	Health int `json:"health"` // 健康分 0-100
+}
+
+// Identity 实例的 SPIFFE 身份
+type Identity struct {
+	SpiffeID string `json:"spiffe_id"`
+	Verified bool   `json:"verified"` // 是否已通过 mTLS 客户端证书校验
+}
+
+// InstanceTimestamps 实例的各项时间
+type InstanceTimestamps struct {
+	Registered int64 `json:"registered"`
+	Up         int64 `json:"up"`
+	Renewed    int64 `json:"renewed"`
+	Dirty      int64 `json:"dirty"`
+	Latest     int64 `json:"latest"`
+}
+
+// FetchDataV2 实例为 v2 结构的 FetchData
+type FetchDataV2 struct {
+	Instances       []*InstanceV2     `json:"instances"`
+	LatestTimestamp int64             `json:"latest_timestamp"`
+	Group           string            `json:"group,omitempty"`
+	Shadows         []*InstanceV2     `json:"shadows,omitempty"`
+	ShadowPercent   int               `json:"shadow_percent,omitempty"`
+	Hints           *CacheHints       `json:"hints,omitempty"`
+	Total           int               `json:"total,omitempty"`
+	NextCursor      string            `json:"next_cursor,omitempty"`
+	ZoneFailover    *ZoneFailoverHint `json:"zone_failover,omitempty"`
+}
+
+// V2 转换为 v2 结构，不共享可修改的字段
+func (in *Instance) V2() *InstanceV2 {
+	v := &InstanceV2{
+		InstanceID: in.InstanceID,
+		Env:        in.Env,
+		AppId:      in.AppId,
+		Hostname:   in.Hostname,
+		Version:    in.Version,
+		Status:     statusName(in.Status),
+		Weight:     in.Weight,
+		Group:      in.Group,
+		Zone:       in.Metadata[MetadataZone],
+		Ports:      make([]Port, 0, len(in.Endpoints)),
+		Scores:     Scores{Health: in.HealthScore},
+		Clock:      in.Clock,
+		Timestamps: InstanceTimestamps{
+			Registered: in.RegTimestamp,
+			Up:         in.UpTimestamp,
+			Renewed:    in.RenewTimestamp,
+			Dirty:      in.DirtyTimestamp,
+			Latest:     in.LatestTimestamp,
+		},
+	}
+	if v.InstanceID == "" {
+		v.InstanceID = defaultInstanceID(in.Env, in.AppId, in.Hostname)
+	}
+	parallel := len(in.Addrs) == len(in.Endpoints)
+	for i, ep := range in.Endpoints {
+		p := endpointPort(ep)
+		if parallel && in.Addrs[i] != ep.String() {
+			p.Addr = in.Addrs[i]
+		}
+		v.Ports = append(v.Ports, p)
+	}
+	for k, val := range in.Metadata {
+		if k == MetadataZone {
+			continue
+		}
+		if v.Metadata == nil {
+			v.Metadata = make(map[string]string, len(in.Metadata))
+		}
+		v.Metadata[k] = val
+	}
+	if in.SpiffeID != "" || in.SpiffeVerified {
+		v.Identity = &Identity{SpiffeID: in.SpiffeID, Verified: in.SpiffeVerified}
+	}
+	if in.Source != nil {
+		src := *in.Source
+		v.Source = &src
+	}
+	return v
+}
+
+// V1 转换为 v1 结构，Addrs 为端口的原始地址，没有时由端口拼出。实例 ID 为默认值时不保存，状态无法识别时为 0
+func (v *InstanceV2) V1() *Instance {
+	in := &Instance{
+		Env:             v.Env,
+		AppId:           v.AppId,
+		Hostname:        v.Hostname,
+		Addrs:           make([]string, 0, len(v.Ports)),
+		Endpoints:       make([]Endpoint, 0, len(v.Ports)),
+		Version:         v.Version,
+		Weight:          v.Weight,
+		Group:           v.Group,
+		HealthScore:     v.Scores.Health,
+		Clock:           v.Clock,
+		RegTimestamp:    v.Timestamps.Registered,
+		UpTimestamp:     v.Timestamps.Up,
+		RenewTimestamp:  v.Timestamps.Renewed,
+		DirtyTimestamp:  v.Timestamps.Dirty,
+		LatestTimestamp: v.Timestamps.Latest,
+	}
+	in.Status, _ = parseStatusName(v.Status)
+	if v.InstanceID != defaultInstanceID(v.Env, v.AppId, v.Hostname) {
+		in.InstanceID = v.InstanceID
+	}
+	for _, p := range v.Ports {
+		in.Endpoints = append(in.Endpoints, p.endpoint())
+		in.Addrs = append(in.Addrs, p.addr())
+	}
+	in.Metadata = v.metadata()
+	if v.Identity != nil {
+		in.SpiffeID, in.SpiffeVerified = v.Identity.SpiffeID, v.Identity.Verified
+	}
+	if v.Source != nil {
+		src := *v.Source
+		in.Source = &src
+	}
+	return in
+}
+
+// RequestRegister 转换为注册请求，与 v1 的注册走同一流程。加密标记与协议默认值不同的端口放入 SecureAddrs
+func (v *InstanceV2) RequestRegister() (*RequestRegister, error) {
+	status, ok := parseStatusName(v.Status)
+	if !ok {
+		return nil, &InvalidRequestError{Violations: []FieldViolation{{Field: "status", Reason: "must be one of UP, DOWN"}}}
+	}
+	req := &RequestRegister{
+		Env:             v.Env,
+		AppId:           v.AppId,
+		Hostname:        v.Hostname,
+		Status:          status,
+		Weight:          v.Weight,
+		Version:         v.Version,
+		Group:           v.Group,
+		LatestTimestamp: v.Timestamps.Latest,
+		DirtyTimestamp:  v.Timestamps.Dirty,
+	}
+	if v.InstanceID != defaultInstanceID(v.Env, v.AppId, v.Hostname) {
+		req.InstanceID = v.InstanceID
+	}
+	for _, p := range v.Ports {
+		ep, addr := p.endpoint(), p.addr()
+		if ep.Secure && !secureSchemes[ep.Scheme] {
+			req.SecureAddrs = append(req.SecureAddrs, addr)
+		} else {
+			req.Addrs = append(req.Addrs, addr)
+		}
+		if p.Name != "" {
+			if req.PortNames == nil {
+				req.PortNames = make(map[string]string)
+			}
+			req.PortNames[addr] = p.Name
+		}
+	}
+	req.Metadata = v.metadata()
+	if v.Identity != nil {
+		req.SpiffeID = v.Identity.SpiffeID
+	}
+	return req, nil
+}
+
+// V2 转换为实例为 v2 结构的 FetchData
+func (d *FetchData) V2() *FetchDataV2 {
+	return &FetchDataV2{
+		Instances:       instancesV2(d.Instances),
+		LatestTimestamp: d.LatestTimestamp,
+		Group:           d.Group,
+		Shadows:         instancesV2(d.Shadows),
+		ShadowPercent:   d.ShadowPercent,
+		Hints:           d.Hints,
+		Total:           d.Total,
+		NextCursor:      d.NextCursor,
+		ZoneFailover:    d.ZoneFailover,
+	}
+}
+
+// metadata v1 的元数据，可用区写回 zone
+func (v *InstanceV2) metadata() map[string]string {
+	if len(v.Metadata) == 0 && v.Zone == "" {
+		return nil
+	}
+	md := make(map[string]string, len(v.Metadata)+1)
+	for k, val := range v.Metadata {
+		md[k] = val
+	}
+	if v.Zone != "" {
+		md[MetadataZone] = v.Zone
+	}
+	return md
+}
+
+func instancesV2(ins []*Instance) []*InstanceV2 {
+	if ins == nil {
+		return nil
+	}
+	rs := make([]*InstanceV2, len(ins))
+	for i, in := range ins {
+		rs[i] = in.V2()
+	}
+	return rs
+}
+
+func defaultInstanceID(env, appid, hostname string) string {
+	return env + "/" + appid + "/" + hostname
+}
+
+func statusName(status uint32) string {
+	switch status {
+	case StatusUP:
+		return StatusNameUP
+	case StatusDown:
+		return StatusNameDown
+	}
+	return strconv.FormatUint(uint64(status), 10)
+}
+
+// parseStatusName 空字符串为 0，即注册时的默认状态
+func parseStatusName(name string) (uint32, bool) {
+	switch name {
+	case "":
+		return 0, true
+	case StatusNameUP:
+		return StatusUP, true
+	case StatusNameDown:
+		return StatusDown, true
+	}
+	n, err := strconv.ParseUint(name, 10, 32)
+	return uint32(n), err == nil
+}
+
+func endpointPort(ep Endpoint) Port {
+	p := Port{Name: ep.Name, Scheme: ep.Scheme, Host: ep.Addr, Secure: ep.Secure}
+	if host, port, err := net.SplitHostPort(ep.Addr); err == nil {
+		if n, err := strconv.Atoi(port); err == nil && n > 0 {
+			p.Host, p.Port = host, n
+		}
+	}
+	return p
+}
+
+func (p Port) endpoint() Endpoint {
+	ep := Endpoint{Scheme: p.Scheme, Addr: p.Host, Secure: p.Secure, Name: p.Name}
+	if p.Port > 0 {
+		ep.Addr = net.JoinHostPort(p.Host, strconv.Itoa(p.Port))
+	}
+	return ep
+}
+
+// addr 端口对应的 v1 地址
+func (p Port) addr() string {
+	if p.Addr != "" {
+		return p.Addr
+	}
+	return p.endpoint().String()
+}
+
+// schemaFlag 实例结构开关
+type schemaFlag struct {
+	lock   sync.RWMutex
+	schema string
+}
+
+// SetInstanceSchema 设置 API 默认输出的实例结构，运行中可随时切换
+func (r *Registry) SetInstanceSchema(schema string) error {
+	if schema != InstanceSchemaV1 && schema != InstanceSchemaV2 {
+		return errors.New("instance schema must be v1 or v2")
+	}
+	r.schema.lock.Lock()
+	r.schema.schema = schema
+	r.schema.lock.Unlock()
+	return nil
+}
+
+// InstanceSchema 返回 API 默认输出的实例结构，默认 v1
+func (r *Registry) InstanceSchema() string {
+	r.schema.lock.RLock()
+	defer r.schema.lock.RUnlock()
+	return r.schema.schema
+}
@@ -0,0 +1,121 @@
+package registry_center
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInstanceV2RoundTrip(t *testing.T) {
+	in := NewInstance(&RequestRegister{
+		Env: "test", AppId: "com.xx.v2", Hostname: "h1",
+		Addrs:       []string{"http://10.0.0.1:8080", "[::1]:9000", "unix-sock", "HTTP://10.0.0.1:8081/metrics"},
+		SecureAddrs: []string{"grpc://10.0.0.1:9090"},
+		PortNames:   map[string]string{"http://10.0.0.1:8080": "http", "grpc://10.0.0.1:9090": "rpc"},
+		Status:      StatusUP, Version: "1.2.0", SpiffeID: "spiffe://example.org/v2",
+		Metadata: map[string]string{MetadataZone: "sh-a", "owner": "pay"},
+	})
+	in.HealthScore = 87
+	in.Source = &RegistrationSource{Kind: SourceDirect, Node: "n1"}
+
+	v := in.V2()
+	if v.InstanceID != "test/com.xx.v2/h1" || v.Status != StatusNameUP || v.Zone != "sh-a" || v.Scores.Health != 87 {
+		t.Fatalf("unexpected v2 %+v", v)
+	}
+	if _, ok := v.Metadata[MetadataZone]; ok {
+		t.Fatal("zone should move out of v2 metadata")
+	}
+	want := []Port{
+		{Name: "http", Scheme: "http", Host: "10.0.0.1", Port: 8080},
+		{Host: "::1", Port: 9000},
+		{Host: "unix-sock"},
+		{Scheme: "http", Host: "10.0.0.1", Port: 8081, Addr: "HTTP://10.0.0.1:8081/metrics"},
+		{Name: "rpc", Scheme: "grpc", Host: "10.0.0.1", Port: 9090, Secure: true},
+	}
+	if !reflect.DeepEqual(v.Ports, want) {
+		t.Fatalf("ports %+v, want %+v", v.Ports, want)
+	}
+	if back := v.V1(); !reflect.DeepEqual(back, in) {
+		t.Fatalf("round trip changed instance:\n%+v\n%+v", back, in)
+	}
+
+	req, err := v.RequestRegister()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back := NewInstance(req); !reflect.DeepEqual(back.Addrs, in.Addrs) || !reflect.DeepEqual(back.Endpoints, in.Endpoints) {
+		t.Fatalf("register from v2 changed addrs:\n%+v\n%+v", back.Addrs, in.Addrs)
+	}
+
+	v.InstanceID = "pay-7f3c"
+	if back := v.V1(); back.InstanceID != "pay-7f3c" || !reflect.DeepEqual(back.V2(), v) {
+		t.Fatalf("custom instance id lost: %+v", back)
+	}
+	v.Status = "9"
+	if back := v.V1(); back.Status != 9 || back.V2().Status != "9" {
+		t.Fatalf("numeric status lost: %+v", back)
+	}
+}
+
+func TestInstanceV2Register(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	v := &InstanceV2{
+		InstanceID: "pay-1", Env: "test", AppId: "com.xx.v2", Hostname: "h1", Status: StatusNameDown, Zone: "sh-b",
+		Ports: []Port{{Name: "admin", Scheme: "http", Host: "10.0.0.2", Port: 8081, Secure: true}, {Name: "web", Scheme: "https", Host: "10.0.0.2", Port: 443, Secure: true}},
+	}
+	req, err := v.RequestRegister()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.SecureAddrs) != 1 || req.SecureAddrs[0] != "http://10.0.0.2:8081" {
+		t.Fatalf("secure http port should go to secure addrs: %+v", req)
+	}
+	if _, err := r.Register(NewInstance(req), time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.Fetch("test", "com.xx.v2", StatusDown, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := data.V2().Instances[0]
+	if got.InstanceID != "pay-1" || got.Status != StatusNameDown || got.Zone != "sh-b" || len(got.Ports) != 2 {
+		t.Fatalf("unexpected registered instance %+v", got)
+	}
+	names := map[string]bool{}
+	for _, p := range got.Ports {
+		if !p.Secure {
+			t.Fatalf("port %+v should stay secure", p)
+		}
+		names[p.Name] = true
+	}
+	if !names["admin"] || !names["web"] {
+		t.Fatalf("port names lost: %+v", got.Ports)
+	}
+
+	v.Status = "STARTING"
+	if _, err := v.RequestRegister(); err == nil {
+		t.Fatal("unknown status should be rejected")
+	}
+	bad := &RequestRegister{Env: "test", AppId: "com.xx.v2", Hostname: "h2", Addrs: []string{"http://10.0.0.3:80"}, PortNames: map[string]string{"http://10.0.0.4:80": "web"}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("port name of an unregistered addr should be rejected")
+	}
+}
+
+func TestInstanceSchemaFlag(t *testing.T) {
+	r := NewRegistry(WithInstanceSchema(InstanceSchemaV2))
+	defer r.Close()
+	if r.InstanceSchema() != InstanceSchemaV2 {
+		t.Fatalf("expect v2, got %s", r.InstanceSchema())
+	}
+	if err := r.SetInstanceSchema("v3"); err == nil || r.InstanceSchema() != InstanceSchemaV2 {
+		t.Fatal("unknown schema should be rejected")
+	}
+	if err := r.SetInstanceSchema(InstanceSchemaV1); err != nil || r.InstanceSchema() != InstanceSchemaV1 {
+		t.Fatalf("switch back to v1: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package registry_center
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// LeaderLock 集群范围的租约锁，可以由 etcd、Consul、数据库等外部存储实现
+type LeaderLock interface {
+	// TryAcquire 尝试以 id 获取或续期租约，租约在 ttl 后过期，返回是否持有租约
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	// Release 释放 id 持有的租约
+	Release(ctx context.Context, id string) error
+}
+
+// SingletonTask 只在 leader 节点运行的任务，失去 leader 身份时 ctx 被取消
+type SingletonTask func(ctx context.Context)
+
+// Elector 节点间选主，leader 节点负责运行集群单例任务，
+// 失去租约时停止任务，由新 leader 接管
+type Elector struct {
+	id   string
+	lock LeaderLock
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	leader bool
+	tasks  map[string]SingletonTask
+	cancel context.CancelFunc // 取消当前任期内运行的任务
+	wg     sync.WaitGroup
+}
+
+// NewElector 创建选主器，id 为本节点唯一标识，ttl 为租约时长
+func NewElector(id string, lock LeaderLock, ttl time.Duration) *Elector {
+	return &Elector{
+		id:    id,
+		lock:  lock,
+		ttl:   ttl,
+		tasks: make(map[string]SingletonTask),
+	}
+}
+
+// AddTask 注册集群单例任务，需在 Run 之前调用
+func (e *Elector) AddTask(name string, task SingletonTask) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.tasks[name] = task
+}
+
+// IsLeader 本节点当前是否为 leader
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leader
+}
+
+// Run 每隔 ttl/3 获取或续期租约直到 ctx 结束，退出时停止任务并释放租约
+func (e *Elector) Run(ctx context.Context) error {
+	tick := time.NewTicker(e.ttl / 3)
+	defer tick.Stop()
+	for {
+		e.campaign(ctx)
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			e.stepDown()
+			if err := e.lock.Release(context.Background(), e.id); err != nil {
+				log.Printf("release leader lock failed: %v", err)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+func (e *Elector) campaign(ctx context.Context) {
+	acquireCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+	ok, err := e.lock.TryAcquire(acquireCtx, e.id, e.ttl)
+	cancel()
+	if err != nil {
+		// 无法确认租约时按失去租约处理，避免出现两个 leader
+		log.Printf("acquire leader lock failed: %v", err)
+		ok = false
+	}
+	if ok {
+		e.elected()
+	} else {
+		e.stepDown()
+	}
+}
+
+// elected 成为 leader 后启动所有单例任务
+func (e *Elector) elected() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leader {
+		return
+	}
+	log.Printf("%s became leader", e.id)
+	e.leader = true
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	for name, task := range e.tasks {
+		e.wg.Add(1)
+		go func(name string, task SingletonTask) {
+			defer e.wg.Done()
+			task(ctx)
+			log.Printf("singleton task %s stopped", name)
+		}(name, task)
+	}
+}
+
+// stepDown 失去 leader 身份，等待所有单例任务退出
+func (e *Elector) stepDown() {
+	e.mu.Lock()
+	if !e.leader {
+		e.mu.Unlock()
+		return
+	}
+	log.Printf("%s lost leadership", e.id)
+	e.leader = false
+	e.cancel()
+	e.mu.Unlock()
+	e.wg.Wait()
+}
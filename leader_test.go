@@ -0,0 +1,88 @@
+package registry_center
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryLock struct {
+	lock    sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+func (m *memoryLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.holder != "" && m.holder != id && time.Now().Before(m.expires) {
+		return false, nil
+	}
+	m.holder, m.expires = id, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (m *memoryLock) Release(ctx context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.holder == id {
+		m.holder = ""
+	}
+	return nil
+}
+
+func TestElectorHandover(t *testing.T) {
+	lock := &memoryLock{}
+	var mu sync.Mutex
+	running := make(map[string]bool)
+	newElector := func(id string) *Elector {
+		e := NewElector(id, lock, 30*time.Millisecond)
+		e.AddTask("snapshot", func(ctx context.Context) {
+			mu.Lock()
+			running[id] = true
+			mu.Unlock()
+			<-ctx.Done()
+			mu.Lock()
+			running[id] = false
+			mu.Unlock()
+		})
+		return e
+	}
+	isRunning := func(id string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running[id]
+	}
+	waitFor := func(cond func() bool, msg string) {
+		deadline := time.Now().Add(time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatal(msg)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	a, b := newElector("a"), newElector("b")
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { a.Run(ctxA); close(doneA) }()
+	waitFor(a.IsLeader, "a should become leader")
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	go b.Run(ctxB)
+	waitFor(func() bool { return isRunning("a") }, "task should run on leader")
+
+	time.Sleep(50 * time.Millisecond)
+	if b.IsLeader() || isRunning("b") {
+		t.Fatal("only one node should be leader")
+	}
+
+	stopA()
+	<-doneA
+	if isRunning("a") {
+		t.Fatal("task should stop when leadership is lost")
+	}
+	waitFor(func() bool { return b.IsLeader() && isRunning("b") }, "b should take over tasks")
+}
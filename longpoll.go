@@ -0,0 +1,61 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 长轮询的等待时长
+const (
+	DefaultLongPollTimeout = 30 * time.Second
+	MaxLongPollTimeout     = 5 * time.Minute
+)
+
+// Poll 长轮询获取服务，latestTimestamp 已过期时立即返回，否则阻塞到应用数据变化或 timeout，
+// 超时仍没有变化时返回 ErrNotModified，调用方可以立即发起下一次长轮询
+func (r *Registry) Poll(env, appid string, latestTimestamp int64, timeout time.Duration) (*FetchData, error) {
+	return r.PollContext(context.Background(), env, appid, StatusUP, latestTimestamp, timeout, FetchOptions{})
+}
+
+// PollContext 同 Poll，等待时长不超过 ctx 的 deadline，以便在 deadline 前返回 ErrNotModified
+func (r *Registry) PollContext(ctx context.Context, env, appid string, status uint32, latestTimestamp int64, timeout time.Duration, opts FetchOptions) (*FetchData, error) {
+	// 不可见的应用立即返回，不等待
+	if err := r.CheckVisible(ctx, env, appid); err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultLongPollTimeout
+	}
+	if timeout > MaxLongPollTimeout {
+		timeout = MaxLongPollTimeout
+	}
+	if d, ok := ctx.Deadline(); ok {
+		// 留出写响应的时间
+		if left := time.Until(d) * 9 / 10; left < timeout {
+			timeout = left
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// 先取通知再读取，读取后写入的事件一定会唤醒等待
+		notify := r.events.wait()
+		data, err := r.FetchContext(ctx, env, appid, status, latestTimestamp, opts)
+		if !waitable(err) {
+			return data, err
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, err
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// waitable 应用数据没有变化或还没有实例，需要继续等待
+func waitable(err error) bool {
+	return errors.Is(err, ErrNotModified) || errors.Is(err, ErrAppNotFound) || errors.Is(err, ErrNoInstances)
+}
@@ -0,0 +1,41 @@
+package registry_center
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	r.Register(&Instance{Env: "test", AppId: "com.xx.poll", Hostname: "a", Status: StatusUP}, 1)
+
+	// 数据已过期时立即返回
+	start := time.Now()
+	data, err := r.Poll("test", "com.xx.poll", 0, time.Second)
+	if err != nil || len(data.Instances) != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("stale poll should return immediately: %+v %v", data, err)
+	}
+	if _, err := r.Poll("test", "com.xx.poll", data.LatestTimestamp, 20*time.Millisecond); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expect not modified after timeout, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Register(&Instance{Env: "test", AppId: "com.xx.poll", Hostname: "b", Status: StatusUP}, 2)
+	}()
+	changed, err := r.Poll("test", "com.xx.poll", data.LatestTimestamp, 5*time.Second)
+	if err != nil || len(changed.Instances) != 2 {
+		t.Fatalf("expect change notification, got %+v %v", changed, err)
+	}
+
+	// 应用还不存在时等待注册
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		r.Register(&Instance{Env: "test", AppId: "com.xx.new", Hostname: "a", Status: StatusUP}, 1)
+	}()
+	if data, err := r.Poll("test", "com.xx.new", 0, 5*time.Second); err != nil || len(data.Instances) != 1 {
+		t.Fatalf("expect new app, got %+v %v", data, err)
+	}
+}
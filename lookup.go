@@ -0,0 +1,80 @@
+package registry_center
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInstanceNotFound 服务实例不存在
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// HealthDetail 实例健康分的各项信号
+type HealthDetail struct {
+	Score         int     `json:"score"`              // 综合健康分 0-100
+	CheckFailures int     `json:"check_failures"`     // 主动健康检查连续失败次数
+	ErrorRate     float64 `json:"error_rate"`         // 调用方反馈的错误率
+	Override      *int    `json:"override,omitempty"` // 人工指定的健康分
+}
+
+// InstanceDetail 单个实例的完整记录
+type InstanceDetail struct {
+	Instance       *Instance     `json:"instance"`
+	Health         HealthDetail  `json:"health"`
+	LeaseRemaining time.Duration `json:"lease_remaining"` // 距离因未续约被剔除的剩余时间
+}
+
+// GetInstance 获取单个实例的完整记录，用于客户端确认自己的注册状态及控制台展示
+func (r *Registry) GetInstance(env, appid, hostname string) (*InstanceDetail, error) {
+	in, ok := r.getInstance(env, appid, hostname)
+	if !ok {
+		return nil, ErrInstanceNotFound
+	}
+	now := time.Now().UnixNano()
+	in.HealthScore = r.health.score(in, now)
+	return &InstanceDetail{
+		Instance:       in,
+		Health:         r.health.detail(in),
+		LeaseRemaining: leaseRemaining(in.RenewTimestamp, now),
+	}, nil
+}
+
+func leaseRemaining(renewTimestamp, now int64) time.Duration {
+	remaining := time.Duration(renewTimestamp + int64(leaseExpiry) - now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// detail 返回实例健康分的各项信号，Score 取实例上已计算的健康分
+func (c *healthController) detail(in *Instance) HealthDetail {
+	d := HealthDetail{Score: in.HealthScore}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	h, ok := c.instances[getInstanceKey(in.AppId, in.Env, in.Hostname)]
+	if !ok {
+		return d
+	}
+	d.CheckFailures = h.checkFailures
+	d.ErrorRate = h.errorRate
+	if h.override != nil {
+		o := int(*h.override)
+		d.Override = &o
+	}
+	return d
+}
+
+// Exists 检查实例是否存在并返回剩余租约时长，不复制实例数据，适合部署工具高频轮询
+func (r *Registry) Exists(env, appid, hostname string) (bool, time.Duration) {
+	app, ok := r.getApplication(appid, env)
+	if !ok {
+		return false, 0
+	}
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	in, ok := app.instances[hostname]
+	if !ok {
+		return false, 0
+	}
+	return true, leaseRemaining(in.RenewTimestamp, time.Now().UnixNano())
+}
@@ -0,0 +1,42 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetInstance(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Version: "v1", Status: StatusUP}), time.Now().UnixNano())
+	r.ReportHealthCheck("test", "com.xx.app", "a", false)
+	r.SetHealthOverride("test", "com.xx.app", "a", 70)
+
+	d, err := r.GetInstance("test", "com.xx.app", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Instance.Version != "v1" || d.Health.Score != 70 || d.Health.CheckFailures != 1 ||
+		d.Health.Override == nil || *d.Health.Override != 70 {
+		t.Fatalf("unexpected instance detail %+v", d)
+	}
+	if d.LeaseRemaining <= 80*time.Second || d.LeaseRemaining > 90*time.Second {
+		t.Fatalf("unexpected lease remaining %s", d.LeaseRemaining)
+	}
+	if _, err := r.GetInstance("test", "com.xx.app", "missing"); err != ErrInstanceNotFound {
+		t.Fatalf("expect not found, got %v", err)
+	}
+}
+
+func TestExists(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	if ok, lease := r.Exists("test", "com.xx.app", "a"); !ok || lease <= 0 {
+		t.Fatalf("instance should exist with remaining lease, got %v %s", ok, lease)
+	}
+	if ok, _ := r.Exists("test", "com.xx.app", "b"); ok {
+		t.Fatal("unknown instance should not exist")
+	}
+	if ok, _ := r.Exists("test", "com.xx.other", "a"); ok {
+		t.Fatal("unknown app should not exist")
+	}
+}
@@ -0,0 +1,202 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrMaintenanceNotFound 维护窗口不存在
+var ErrMaintenanceNotFound = errors.New("maintenance window not found")
+
+// TaskMaintenance 按维护窗口摘流量及恢复的后台任务
+const TaskMaintenance = "maintenance"
+
+// 维护窗口状态
+const (
+	MaintenancePending = "pending" // 还没开始
+	MaintenanceActive  = "active"  // 进行中，选中的实例已摘流量
+)
+
+// MaintenanceWindow 维护窗口：开始时自动摘掉选中实例的流量，结束时恢复原来的权重，
+// 窗口内的实例过期不计入剔除告警
+type MaintenanceWindow struct {
+	ID       string           `json:"id"`
+	Selector InstanceSelector `json:"selector"`        // 选择应用、可用区等
+	Hosts    []string         `json:"hosts,omitempty"` // 只选择这些机器，为空时不限
+	Start    int64            `json:"start"`           // 开始时间
+	End      int64            `json:"end"`             // 结束时间
+	Reason   string           `json:"reason,omitempty"`
+	State    string           `json:"state"`
+	Drained  int              `json:"drained"` // 已摘流量的实例数
+
+	weights map[instanceKey]uint32 // 摘流量前的权重
+}
+
+// Match 实例是否在维护窗口内
+func (w *MaintenanceWindow) Match(in *Instance) bool {
+	if !w.Selector.Match(in) {
+		return false
+	}
+	if len(w.Hosts) == 0 {
+		return true
+	}
+	for _, h := range w.Hosts {
+		if onHost(in, h) {
+			return true
+		}
+	}
+	return false
+}
+
+type maintenanceController struct {
+	lock    sync.Mutex
+	seq     uint64
+	windows map[string]*MaintenanceWindow
+}
+
+func newMaintenanceController() *maintenanceController {
+	return &maintenanceController{windows: make(map[string]*MaintenanceWindow)}
+}
+
+// ScheduleMaintenance 安排维护窗口，返回分配了 ID 的窗口
+func (r *Registry) ScheduleMaintenance(w MaintenanceWindow) (*MaintenanceWindow, error) {
+	if w.Selector.Env == "" {
+		return nil, errors.New("selector env is required")
+	}
+	if w.End <= w.Start || w.End <= time.Now().UnixNano() {
+		return nil, errors.New("invalid maintenance window time")
+	}
+	if err := w.Selector.Filter.Validate(); err != nil {
+		return nil, err
+	}
+	c := r.maintenance
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.seq++
+	w.ID = fmt.Sprintf("mw-%d", c.seq)
+	w.State = MaintenancePending
+	w.Drained = 0
+	w.weights = make(map[instanceKey]uint32)
+	c.windows[w.ID] = &w
+	out := w
+	out.weights = nil
+	return &out, nil
+}
+
+// MaintenanceWindows 返回未结束的维护窗口，按开始时间排列
+func (r *Registry) MaintenanceWindows() []MaintenanceWindow {
+	c := r.maintenance
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rs := make([]MaintenanceWindow, 0, len(c.windows))
+	for _, w := range c.windows {
+		out := *w
+		out.weights = nil
+		rs = append(rs, out)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Start != rs[j].Start {
+			return rs[i].Start < rs[j].Start
+		}
+		return rs[i].ID < rs[j].ID
+	})
+	return rs
+}
+
+// CancelMaintenance 取消维护窗口，进行中的窗口立即恢复实例权重
+func (r *Registry) CancelMaintenance(ctx context.Context, id string) error {
+	c := r.maintenance
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	w, ok := c.windows[id]
+	if !ok {
+		return ErrMaintenanceNotFound
+	}
+	delete(c.windows, id)
+	r.restoreMaintenance(ctx, w)
+	return nil
+}
+
+// outsideMaintenance 不在进行中的维护窗口内的实例数
+func (r *Registry) outsideMaintenance(instances []*Instance) int {
+	c := r.maintenance
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	n := 0
+	for _, in := range instances {
+		matched := false
+		for _, w := range c.windows {
+			if w.State == MaintenanceActive && w.Match(in) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			n++
+		}
+	}
+	return n
+}
+
+// checkMaintenance 开始到期的维护窗口并恢复已结束的窗口。
+// 进行中的窗口每轮都会检查，维护期间重新注册的实例也会被摘流量
+func (r *Registry) checkMaintenance(ctx context.Context) error {
+	if r.Role() != RoleActive {
+		return nil
+	}
+	c := r.maintenance
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now().UnixNano()
+	for id, w := range c.windows {
+		switch {
+		case now >= w.End:
+			delete(c.windows, id)
+			r.restoreMaintenance(ctx, w)
+		case now >= w.Start:
+			w.State = MaintenanceActive
+			r.drainMaintenance(ctx, w)
+		}
+	}
+	return nil
+}
+
+// drainMaintenance 把窗口内权重不为 0 的实例摘流量，记录原来的权重
+func (r *Registry) drainMaintenance(ctx context.Context, w *MaintenanceWindow) {
+	for _, app := range r.getAllApplications() {
+		for _, in := range app.GetAllInstances() {
+			if in.Weight == 0 || !w.Match(in) {
+				continue
+			}
+			up := copyInstance(in)
+			up.Weight = 0
+			if _, err := r.update(ctx, up); err != nil {
+				logf(ctx, "drain %s/%s for maintenance %s failed: %v", in.Key(), in.Hostname, w.ID, err)
+				continue
+			}
+			w.weights[getInstanceKey(in.AppId, in.Env, in.Hostname)] = in.Weight
+			w.Drained = len(w.weights)
+		}
+	}
+}
+
+// restoreMaintenance 恢复窗口内被摘流量的实例权重，维护期间被人工修改过权重的实例不恢复
+func (r *Registry) restoreMaintenance(ctx context.Context, w *MaintenanceWindow) {
+	for _, app := range r.getAllApplications() {
+		for _, in := range app.GetAllInstances() {
+			weight, ok := w.weights[getInstanceKey(in.AppId, in.Env, in.Hostname)]
+			if !ok || in.Weight != 0 {
+				continue
+			}
+			up := copyInstance(in)
+			up.Weight = weight
+			if _, err := r.update(ctx, up); err != nil {
+				logf(ctx, "restore %s/%s after maintenance %s failed: %v", in.Key(), in.Hostname, w.ID, err)
+			}
+		}
+	}
+}
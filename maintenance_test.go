@@ -0,0 +1,48 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	ctx := context.Background()
+	for _, h := range []string{"a", "b"} {
+		r.Register(&Instance{Env: "test", AppId: "com.xx.mw", Hostname: h, Addrs: []string{"http://" + h + ".local:80"}, Status: StatusUP, Weight: 50}, 1)
+	}
+
+	now := time.Now()
+	if _, err := r.ScheduleMaintenance(MaintenanceWindow{Selector: InstanceSelector{Env: "test"}, Start: now.UnixNano(), End: now.Add(-time.Second).UnixNano()}); err == nil {
+		t.Fatal("expect invalid window time")
+	}
+	mw, err := r.ScheduleMaintenance(MaintenanceWindow{Selector: InstanceSelector{Env: "test", AppId: "com.xx.mw"}, Hosts: []string{"a.local"}, Start: now.UnixNano(), End: now.Add(time.Hour).UnixNano()})
+	if err != nil || mw.ID == "" || mw.State != MaintenancePending {
+		t.Fatalf("schedule failed: %+v %v", mw, err)
+	}
+
+	r.checkMaintenance(ctx)
+	a, _ := r.getInstance("test", "com.xx.mw", "a")
+	b, _ := r.getInstance("test", "com.xx.mw", "b")
+	if a.Weight != 0 || b.Weight != 50 {
+		t.Fatalf("only a should be drained, got a=%d b=%d", a.Weight, b.Weight)
+	}
+	if ws := r.MaintenanceWindows(); len(ws) != 1 || ws[0].State != MaintenanceActive || ws[0].Drained != 1 {
+		t.Fatalf("unexpected windows %+v", ws)
+	}
+	if n := r.outsideMaintenance([]*Instance{a, b}); n != 1 {
+		t.Fatalf("a should be in maintenance, got %d outside", n)
+	}
+
+	if err := r.CancelMaintenance(ctx, mw.ID); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := r.getInstance("test", "com.xx.mw", "a"); a.Weight != 50 {
+		t.Fatalf("weight should be restored, got %d", a.Weight)
+	}
+	if err := r.CancelMaintenance(ctx, mw.ID); err != ErrMaintenanceNotFound {
+		t.Fatalf("expect not found, got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// API 名称，用于按接口统计
+const (
+	EndpointRegister = "register"
+	EndpointRenew    = "renew"
+	EndpointCancel   = "cancel"
+	EndpointFetch    = "fetch"
+)
+
+var (
+	// 请求耗时直方图的分桶，单位秒
+	latencyBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+	// 复制数据大小直方图的分桶，单位字节
+	sizeBuckets = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+)
+
+// histogram 累积分桶直方图
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// labeled 一组以标签值区分的计数
+type labeled struct {
+	hists  map[string]*histogram // key: 标签值
+	errors map[[2]string]uint64  // key: 标签值, 错误类型
+}
+
+func newLabeled() *labeled {
+	return &labeled{hists: make(map[string]*histogram), errors: make(map[[2]string]uint64)}
+}
+
+// metrics 注册中心自身的指标，按接口和复制目标统计耗时、数据大小及错误类型
+type metrics struct {
+	lock             sync.Mutex
+	requests         *labeled              // 按接口
+	replications     *labeled              // 按复制目标
+	replicationSizes map[string]*histogram // 按复制目标
+	stageTimeouts    map[string]uint64     // 按处理阶段
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:         newLabeled(),
+		replications:     newLabeled(),
+		replicationSizes: make(map[string]*histogram),
+		stageTimeouts:    make(map[string]uint64),
+	}
+}
+
+func (m *metrics) observe(l *labeled, label string, cost time.Duration, err error) {
+	h, ok := l.hists[label]
+	if !ok {
+		h = newHistogram(latencyBuckets)
+		l.hists[label] = h
+	}
+	h.observe(cost.Seconds())
+	if err != nil {
+		l.errors[[2]string{label, errorClass(err)}]++
+	}
+}
+
+// errorClass 错误分类，作为指标标签
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, ErrOverloaded):
+		return "overloaded"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrReadOnly):
+		return "read_only"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "timeout"
+	}
+	return "error"
+}
+
+// observeRequest 记录一次 API 请求的耗时和结果，并用于过载检测
+func (r *Registry) observeRequest(endpoint string, start time.Time, err *error) {
+	cost := time.Since(start)
+	r.metrics.lock.Lock()
+	r.metrics.observe(r.metrics.requests, endpoint, cost, *err)
+	r.metrics.lock.Unlock()
+	if r.overload != nil {
+		r.overload.observe(cost, r.queueDepth())
+	}
+}
+
+// ObserveReplication 记录一次向复制目标 target 发送数据的耗时、大小和结果，由 Replicator 实现调用
+func (r *Registry) ObserveReplication(target string, cost time.Duration, size int, err error) {
+	r.nodes.observe(target, err)
+	if err != nil {
+		r.raiseAlert(Alert{
+			Name:    AlertPeerUnreachable,
+			Summary: "replication to " + target + " failed: " + err.Error(),
+			Labels:  map[string]string{"target": target},
+		})
+	}
+	m := r.metrics
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.observe(m.replications, target, cost, err)
+	h, ok := m.replicationSizes[target]
+	if !ok {
+		h = newHistogram(sizeBuckets)
+		m.replicationSizes[target] = h
+	}
+	h.observe(float64(size))
+}
+
+// WriteMetrics 以 Prometheus 文本格式输出指标
+func (r *Registry) WriteMetrics(w io.Writer) error {
+	m := r.metrics
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ew := &errWriter{w: w}
+	writeHistograms(ew, "registry_request_duration_seconds", "API request latency.", "endpoint", m.requests.hists)
+	writeErrors(ew, "registry_request_errors_total", "API request errors by class.", "endpoint", m.requests.errors)
+	writeHistograms(ew, "registry_replication_duration_seconds", "Replication latency per target.", "target", m.replications.hists)
+	writeHistograms(ew, "registry_replication_payload_bytes", "Replication payload size per target.", "target", m.replicationSizes)
+	writeErrors(ew, "registry_replication_errors_total", "Replication errors per target by class.", "target", m.replications.errors)
+	m.writeStageTimeouts(ew)
+	r.writeTimestampConflicts(ew)
+	return ew.err
+}
+
+// writeTimestampConflicts 输出各应用 latestTimestamp 的乱序冲突次数
+func (r *Registry) writeTimestampConflicts(w *errWriter) {
+	const name = "registry_timestamp_conflicts_total"
+	w.printf("# HELP %s Out-of-order writes that tried to move an app's latest timestamp backwards.\n# TYPE %s counter\n", name, name)
+	r.lock.RLock()
+	keys := make([]AppKey, 0, len(r.apps))
+	for key := range r.apps {
+		keys = append(keys, key)
+	}
+	r.lock.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		return r.keys.Encode(keys[i]) < r.keys.Encode(keys[j])
+	})
+	for _, key := range keys {
+		r.lock.RLock()
+		app, ok := r.apps[key]
+		r.lock.RUnlock()
+		if !ok {
+			continue
+		}
+		if n := app.TimestampConflicts(); n > 0 {
+			w.printf("%s{app=%q} %d\n", name, r.keys.Encode(key), n)
+		}
+	}
+}
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...interface{}) {
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, args...)
+	}
+}
+
+func writeHistograms(w *errWriter, name, help, label string, hists map[string]*histogram) {
+	w.printf("# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	values := make([]string, 0, len(hists))
+	for v := range hists {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		h := hists[v]
+		for i, b := range h.buckets {
+			w.printf("%s_bucket{%s=%q,le=%q} %d\n", name, label, v, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+		}
+		w.printf("%s_bucket{%s=%q,le=\"+Inf\"} %d\n", name, label, v, h.count)
+		w.printf("%s_sum{%s=%q} %s\n", name, label, v, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		w.printf("%s_count{%s=%q} %d\n", name, label, v, h.count)
+	}
+}
+
+func writeErrors(w *errWriter, name, help, label string, errs map[[2]string]uint64) {
+	w.printf("# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	keys := make([][2]string, 0, len(errs))
+	for k := range errs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, k := range keys {
+		w.printf("%s{%s=%q,class=%q} %d\n", name, label, k[0], k[1], errs[k])
+	}
+}
@@ -0,0 +1,35 @@
+package registry_center
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Renew("test", "com.xx.app", "missing")
+	r.ObserveReplication("node-2", 3*time.Millisecond, 2000, errors.New("connection refused"))
+
+	var buf bytes.Buffer
+	if err := r.WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`registry_request_duration_seconds_count{endpoint="register"} 1`,
+		`registry_request_duration_seconds_bucket{endpoint="renew",le="+Inf"} 1`,
+		`registry_request_errors_total{endpoint="renew",class="error"} 1`,
+		`registry_replication_duration_seconds_bucket{target="node-2",le="0.005"} 1`,
+		`registry_replication_payload_bytes_bucket{target="node-2",le="1024"} 0`,
+		`registry_replication_payload_bytes_bucket{target="node-2",le="4096"} 1`,
+		`registry_replication_errors_total{target="node-2",class="error"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("metrics missing %s:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package registry_center
+
+import "context"
+
+// 接口调用的协议
+const (
+	ProtocolHTTP    = "http"    // HTTP 接口
+	ProtocolSession = "session" // 会话消息，包括 TCP 连接及以 SessionStream 接入的 gRPC 双向流等
+	ProtocolGRPC    = "grpc"    // gRPC 一元调用，Operation 为完整方法名，如 /registry.v1.RegistryService/Register
+)
+
+// CallInfo 一次接口调用的描述，中间件据此做认证、日志、限流、指标等通用处理
+type CallInfo struct {
+	Protocol  string // ProtocolHTTP、ProtocolSession 或 ProtocolGRPC
+	Method    string // HTTP 方法，会话消息为空
+	Operation string // HTTP 为请求路径，如 /api/register；会话为消息的 op，如 register
+	Env       string // 调用涉及的环境，无法从请求参数得到时为空
+	AppId     string // 调用涉及的应用，无法从请求参数得到时为空
+}
+
+// Handler 处理一次接口调用，返回的错误即调用方收到的错误
+type Handler func(ctx context.Context, call *CallInfo) error
+
+// Middleware 包装 Handler。不调用 next 并返回错误即拒绝请求，错误按协议转换为 HTTP 状态码或会话应答中的
+// ErrorInfo；调用 next 时传入的 ctx 会传递给接口处理，如附加认证得到的调用方身份
+type Middleware func(next Handler) Handler
+
+// Chain 把多个中间件组合为一个，第一个在最外层
+func Chain(mws ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+type middlewareKey struct{}
+
+// ContextWithMiddleware 返回携带中间件的 ctx，传给 ServeSessionStreamContext 后每条会话消息都经过 mw 处理
+func ContextWithMiddleware(ctx context.Context, mw Middleware) context.Context {
+	return context.WithValue(ctx, middlewareKey{}, mw)
+}
+
+func middlewareFromContext(ctx context.Context) Middleware {
+	mw, _ := ctx.Value(middlewareKey{}).(Middleware)
+	return mw
+}
@@ -0,0 +1,61 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call *CallInfo) error {
+				order = append(order, name)
+				return next(ctx, call)
+			}
+		}
+	}
+	Chain(mw("a"), mw("b"))(func(ctx context.Context, call *CallInfo) error {
+		order = append(order, "handler")
+		return nil
+	})(context.Background(), &CallInfo{})
+	if len(order) != 3 || order[0] != "a" || order[1] != "b" {
+		t.Fatalf("unexpected order %v", order)
+	}
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	r := NewRegistry(WithSessionGrace(time.Hour))
+	defer r.Close()
+	var seen []CallInfo
+	deny := func(next Handler) Handler {
+		return func(ctx context.Context, call *CallInfo) error {
+			seen = append(seen, *call)
+			if call.AppId == "com.xx.denied" {
+				return ErrUnauthenticated
+			}
+			return next(ctx, call)
+		}
+	}
+	s := &chanStream{in: make(chan SessionMessage), out: make(chan interface{}, 8)}
+	done := make(chan struct{})
+	go func() {
+		r.ServeSessionStreamContext(ContextWithMiddleware(context.Background(), deny), s)
+		close(done)
+	}()
+	s.in <- SessionMessage{Op: SessionOpRegister, Register: &RequestRegister{Env: "test", AppId: "com.xx.denied", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}}}
+	for frame := range s.out {
+		if rep, ok := frame.(SessionReply); ok {
+			if rep.ErrorInfo == nil || rep.ErrorInfo.Code != CodeUnauthenticated {
+				t.Fatalf("expect rejected by middleware, got %+v", rep)
+			}
+			break
+		}
+	}
+	close(s.in)
+	<-done
+	if ok, _ := r.Exists("test", "com.xx.denied", "a"); ok || len(seen) != 1 || seen[0].Protocol != ProtocolSession || seen[0].Operation != SessionOpRegister {
+		t.Fatalf("unexpected calls %+v", seen)
+	}
+}
@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// consulServiceEntry Consul /v1/health/service/<name> 返回的条目
+type consulServiceEntry struct {
+	Node struct {
+		Node       string `json:"Node"`
+		Address    string `json:"Address"`
+		Datacenter string `json:"Datacenter"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Service string            `json:"Service"`
+		Tags    []string          `json:"Tags"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+	Checks []struct {
+		Status string `json:"Status"`
+	} `json:"Checks"`
+}
+
+// ConsulOptions Consul 导入选项
+type ConsulOptions struct {
+	Env        string       // 导入到的环境
+	Addr       string       // Consul HTTP 地址，如 http://127.0.0.1:8500
+	Token      string       // ACL token
+	Datacenter string       // 数据中心，为空时使用 agent 所在数据中心
+	Client     *http.Client // 为空时使用 http.DefaultClient
+}
+
+// ImportConsul 读取 Consul catalog 中的所有服务及其健康状态，转换为注册实例
+func ImportConsul(ctx context.Context, opts ConsulOptions) ([]*registry.Instance, error) {
+	if opts.Env == "" || opts.Addr == "" {
+		return nil, errors.New("env and consul addr are required")
+	}
+	var services map[string][]string
+	if err := consulGet(ctx, opts, "/v1/catalog/services", &services); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		// consul 自身不导入
+		if name != "consul" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var rs []*registry.Instance
+	for _, name := range names {
+		var entries []consulServiceEntry
+		if err := consulGet(ctx, opts, "/v1/health/service/"+url.PathEscape(name), &entries); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			rs = append(rs, consulToInstance(e, opts.Env))
+		}
+	}
+	return rs, nil
+}
+
+// ParseConsulHealth 解析 /v1/health/service/<name> 的输出（或其备份文件）
+func ParseConsulHealth(rd io.Reader, env string) ([]*registry.Instance, error) {
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(rd).Decode(&entries); err != nil {
+		return nil, err
+	}
+	rs := make([]*registry.Instance, 0, len(entries))
+	for _, e := range entries {
+		rs = append(rs, consulToInstance(e, env))
+	}
+	return rs, nil
+}
+
+func consulGet(ctx context.Context, opts ConsulOptions, path string, out interface{}) error {
+	u := strings.TrimRight(opts.Addr, "/") + path
+	if opts.Datacenter != "" {
+		u += "?dc=" + url.QueryEscape(opts.Datacenter)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if opts.Token != "" {
+		req.Header.Set("X-Consul-Token", opts.Token)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// consulToInstance 转换 Consul 服务实例：key=value 形式的 tag 转为元数据，其余 tag 以逗号拼接存入 tags，
+// 服务的 Meta 原样并入元数据，所有健康检查通过时为 UP
+func consulToInstance(e consulServiceEntry, env string) *registry.Instance {
+	meta := make(map[string]string)
+	var tags []string
+	for _, tag := range e.Service.Tags {
+		if k, v, ok := strings.Cut(tag, "="); ok && k != "" {
+			meta[k] = v
+		} else {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > 0 {
+		meta["tags"] = strings.Join(tags, ",")
+	}
+	for k, v := range e.Service.Meta {
+		meta[k] = v
+	}
+	if e.Node.Datacenter != "" {
+		meta["datacenter"] = e.Node.Datacenter
+	}
+	host := e.Service.Address
+	if host == "" {
+		host = e.Node.Address
+	}
+	scheme := meta["scheme"]
+	if scheme == "" {
+		scheme = "http"
+	}
+	req := &registry.RequestRegister{
+		Env:      env,
+		AppId:    e.Service.Service,
+		Hostname: e.Node.Node + ":" + e.Service.ID,
+		Addrs:    []string{scheme + "://" + host + ":" + strconv.Itoa(e.Service.Port)},
+		Version:  meta["version"],
+		Status:   registry.StatusUP,
+		Metadata: meta,
+	}
+	for _, c := range e.Checks {
+		if c.Status != "passing" {
+			req.Status = registry.StatusDown
+			break
+		}
+	}
+	return registry.NewInstance(req)
+}
@@ -0,0 +1,42 @@
+package migrate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestImportConsul(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/services", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"consul":[],"web":["v2","zone=az1"]}`))
+	})
+	mux.HandleFunc("/v1/health/service/web", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+{"Node":{"Node":"node-1","Address":"10.0.0.1","Datacenter":"dc1"},"Service":{"ID":"web-1","Service":"web","Tags":["v2","zone=az1"],"Port":8080,"Meta":{"version":"2.0"}},"Checks":[{"Status":"passing"}]},
+{"Node":{"Node":"node-2","Address":"10.0.0.2","Datacenter":"dc1"},"Service":{"ID":"web-1","Service":"web","Address":"10.0.1.2","Port":8080},"Checks":[{"Status":"passing"},{"Status":"critical"}]}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	instances, err := ImportConsul(context.Background(), ConsulOptions{Env: "online", Addr: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expect 2 instances, got %d", len(instances))
+	}
+	a, b := instances[0], instances[1]
+	if a.Hostname != "node-1:web-1" || a.Status != registry.StatusUP || a.Version != "2.0" || a.Addrs[0] != "http://10.0.0.1:8080" {
+		t.Fatalf("unexpected instance: %+v", a)
+	}
+	if a.Metadata["zone"] != "az1" || a.Metadata["tags"] != "v2" || a.Metadata["datacenter"] != "dc1" {
+		t.Fatalf("unexpected metadata: %v", a.Metadata)
+	}
+	if b.Status != registry.StatusDown || b.Addrs[0] != "http://10.0.1.2:8080" {
+		t.Fatalf("unexpected instance: %+v", b)
+	}
+}
@@ -0,0 +1,198 @@
+// Package migrate 从其他注册中心导入服务实例
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// eurekaPort Eureka 端口，JSON 格式为 {"$": 8080, "@enabled": "true"}，XML 格式为 <port enabled="true">8080</port>
+type eurekaPort struct {
+	Port    int    `json:"$" xml:",chardata"`
+	Enabled string `json:"@enabled" xml:"enabled,attr"`
+}
+
+type eurekaInstance struct {
+	InstanceId    string         `json:"instanceId" xml:"instanceId"`
+	HostName      string         `json:"hostName" xml:"hostName"`
+	App           string         `json:"app" xml:"app"`
+	IpAddr        string         `json:"ipAddr" xml:"ipAddr"`
+	Status        string         `json:"status" xml:"status"`
+	Port          eurekaPort     `json:"port" xml:"port"`
+	SecurePort    eurekaPort     `json:"securePort" xml:"securePort"`
+	Metadata      eurekaMetadata `json:"metadata" xml:"metadata"`
+	LastDirtyTime int64          `json:"lastDirtyTimestamp,string" xml:"lastDirtyTimestamp"`
+}
+
+// eurekaMetadata 实例元数据，XML 格式为 <metadata><version>1.0</version></metadata>
+type eurekaMetadata map[string]string
+
+func (m *eurekaMetadata) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var doc struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	if err := d.DecodeElement(&doc, &start); err != nil {
+		return err
+	}
+	*m = make(eurekaMetadata, len(doc.Entries))
+	for _, e := range doc.Entries {
+		(*m)[e.XMLName.Local] = e.Value
+	}
+	return nil
+}
+
+type eurekaApplication struct {
+	Name      string           `xml:"name"`
+	Instances []eurekaInstance `xml:"instance"`
+}
+
+// UnmarshalJSON 兼容单个实例时 instance 字段不是数组的情况
+func (a *eurekaApplication) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Name     string          `json:"name"`
+		Instance json.RawMessage `json:"instance"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	a.Name = raw.Name
+	return unmarshalList(raw.Instance, &a.Instances)
+}
+
+// unmarshalList 解析可能是单个对象也可能是数组的字段
+func unmarshalList[T any](b json.RawMessage, out *[]T) error {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+	if b[0] == '[' {
+		return json.Unmarshal(b, out)
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*out = append(*out, v)
+	return nil
+}
+
+// EurekaOptions Eureka 导入选项
+type EurekaOptions struct {
+	Env         string // 导入到的环境
+	LowerAppIds bool   // Eureka 应用名默认大写，为 true 时转为小写
+}
+
+// ParseEurekaApps 解析 Eureka /eureka/apps 的 JSON 或 XML 输出（或其备份文件），转换为注册实例
+func ParseEurekaApps(rd io.Reader, opts EurekaOptions) ([]*registry.Instance, error) {
+	if opts.Env == "" {
+		return nil, errors.New("env is required")
+	}
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("empty eureka apps document")
+	}
+	var apps []eurekaApplication
+	if data[0] == '<' {
+		var doc struct {
+			Applications []eurekaApplication `xml:"application"`
+		}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		apps = doc.Applications
+	} else {
+		var doc struct {
+			Applications struct {
+				Application json.RawMessage `json:"application"`
+			} `json:"applications"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		if err := unmarshalList(doc.Applications.Application, &apps); err != nil {
+			return nil, err
+		}
+	}
+	var rs []*registry.Instance
+	for _, app := range apps {
+		for _, ei := range app.Instances {
+			rs = append(rs, eurekaToInstance(app.Name, ei, opts))
+		}
+	}
+	return rs, nil
+}
+
+func eurekaToInstance(appName string, ei eurekaInstance, opts EurekaOptions) *registry.Instance {
+	if ei.App != "" {
+		appName = ei.App
+	}
+	if opts.LowerAppIds {
+		appName = strings.ToLower(appName)
+	}
+	hostname := ei.InstanceId
+	if hostname == "" {
+		hostname = ei.HostName
+	}
+	host := ei.IpAddr
+	if host == "" {
+		host = ei.HostName
+	}
+	req := &registry.RequestRegister{
+		Env:      opts.Env,
+		AppId:    appName,
+		Hostname: hostname,
+		Version:  ei.Metadata["version"],
+		Status:   registry.StatusDown,
+	}
+	// 去掉 Jackson 序列化带来的 @class 等字段
+	for k, v := range ei.Metadata {
+		if !strings.HasPrefix(k, "@") {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]string)
+			}
+			req.Metadata[k] = v
+		}
+	}
+	if strings.EqualFold(ei.Status, "UP") {
+		req.Status = registry.StatusUP
+	}
+	if ei.Port.Enabled == "true" && ei.Port.Port > 0 {
+		req.Addrs = append(req.Addrs, fmt.Sprintf("http://%s:%d", host, ei.Port.Port))
+	}
+	if ei.SecurePort.Enabled == "true" && ei.SecurePort.Port > 0 {
+		req.Addrs = append(req.Addrs, fmt.Sprintf("https://%s:%d", host, ei.SecurePort.Port))
+	}
+	in := registry.NewInstance(req)
+	if ei.LastDirtyTime > 0 {
+		in.DirtyTimestamp = ei.LastDirtyTime * int64(time.Millisecond)
+	}
+	return in
+}
+
+// Import 把实例导入注册表，返回导入的实例数
+func Import(r *registry.Registry, instances []*registry.Instance) (int, error) {
+	var n int
+	now := time.Now().UnixNano()
+	for _, in := range instances {
+		if _, err := r.Register(in, now); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
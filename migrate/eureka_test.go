@@ -0,0 +1,55 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+const eurekaJSON = `{"applications":{"versions__delta":"1","apps__hashcode":"UP_2_","application":[
+{"name":"PAYMENT","instance":{"instanceId":"pay-1","hostName":"pay-1.local","app":"PAYMENT","ipAddr":"10.0.0.1","status":"UP",
+ "port":{"$":8080,"@enabled":"true"},"securePort":{"$":8443,"@enabled":"true"},"metadata":{"version":"v1.2.0"},"lastDirtyTimestamp":"1600000000000"}},
+{"name":"ORDER","instance":[{"instanceId":"order-1","hostName":"order-1.local","app":"ORDER","ipAddr":"10.0.0.2","status":"DOWN",
+ "port":{"$":9090,"@enabled":"true"},"securePort":{"$":443,"@enabled":"false"}}]}]}}`
+
+const eurekaXML = `<applications><versions__delta>1</versions__delta><application><name>PAYMENT</name>
+<instance><instanceId>pay-1</instanceId><hostName>pay-1.local</hostName><app>PAYMENT</app><ipAddr>10.0.0.1</ipAddr><status>UP</status>
+<port enabled="true">8080</port><securePort enabled="false">443</securePort><metadata><version>v1.2.0</version></metadata></instance>
+</application></applications>`
+
+func TestParseEurekaApps(t *testing.T) {
+	instances, err := ParseEurekaApps(strings.NewReader(eurekaJSON), EurekaOptions{Env: "online", LowerAppIds: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("expect 2 instances, got %d", len(instances))
+	}
+	pay := instances[0]
+	if pay.AppId != "payment" || pay.Hostname != "pay-1" || pay.Version != "v1.2.0" || pay.Status != registry.StatusUP {
+		t.Fatalf("unexpected instance: %+v", pay)
+	}
+	if len(pay.Addrs) != 2 || pay.Addrs[1] != "https://10.0.0.1:8443" || !pay.Endpoints[1].Secure {
+		t.Fatalf("unexpected addrs: %v", pay.Addrs)
+	}
+	if instances[1].Status != registry.StatusDown || len(instances[1].Addrs) != 1 {
+		t.Fatalf("unexpected instance: %+v", instances[1])
+	}
+
+	xmlInstances, err := ParseEurekaApps(strings.NewReader(eurekaXML), EurekaOptions{Env: "online"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xmlInstances) != 1 || xmlInstances[0].AppId != "PAYMENT" || xmlInstances[0].Version != "v1.2.0" || len(xmlInstances[0].Addrs) != 1 {
+		t.Fatalf("unexpected xml instances: %+v", xmlInstances)
+	}
+
+	r := registry.NewRegistry()
+	if n, err := Import(r, instances); err != nil || n != 2 {
+		t.Fatalf("unexpected import result %d %v", n, err)
+	}
+	if _, err := r.Fetch("online", "payment", registry.StatusUP, 0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package registry_center
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 注册中心节点状态
+const (
+	NodeUp      = "up"
+	NodeDown    = "down"
+	NodeUnknown = "unknown" // 还没有与该节点通信过
+)
+
+// Node 注册中心集群中的一个节点
+type Node struct {
+	ID       string `json:"id"`
+	Addr     string `json:"addr"` // 对外服务地址，如 http://10.0.0.1:7171
+	Self     bool   `json:"self"`
+	Status   string `json:"status"`
+	Role     string `json:"role,omitempty"`      // 主备角色，只有本节点已知
+	Mode     string `json:"mode,omitempty"`      // 运行模式，只有本节点已知
+	LastSeen int64  `json:"last_seen,omitempty"` // 最后一次与该节点通信成功的时间
+}
+
+// nodeSet 本节点及已知的对端节点
+type nodeSet struct {
+	lock  sync.RWMutex
+	addr  string           // 本节点地址
+	peers map[string]*Node // key: 节点 ID
+}
+
+func newNodeSet() *nodeSet {
+	return &nodeSet{peers: make(map[string]*Node)}
+}
+
+// observe 按与对端通信的结果更新节点状态，target 可以是节点 ID 或地址
+func (s *nodeSet) observe(target string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, n := range s.peers {
+		if n.ID != target && n.Addr != target {
+			continue
+		}
+		if err != nil {
+			n.Status = NodeDown
+		} else {
+			n.Status = NodeUp
+			n.LastSeen = time.Now().UnixNano()
+		}
+	}
+}
+
+// SetPeers 设置集群中的对端节点，已知节点的状态保留
+func (r *Registry) SetPeers(peers []Node) {
+	s := r.nodes
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	old := s.peers
+	s.peers = make(map[string]*Node, len(peers))
+	for _, p := range peers {
+		n := &Node{ID: p.ID, Addr: p.Addr, Status: NodeUnknown}
+		if o, ok := old[p.ID]; ok {
+			n.Status, n.LastSeen = o.Status, o.LastSeen
+		}
+		s.peers[p.ID] = n
+	}
+}
+
+// Nodes 返回集群中的所有节点，本节点在前，客户端可从任一节点获取后在集群内负载均衡
+func (r *Registry) Nodes() []Node {
+	hs := r.Health()
+	s := r.nodes
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	rs := []Node{{ID: r.clock.node, Addr: s.addr, Self: true, Status: NodeUp, Role: hs.Role, Mode: hs.Mode, LastSeen: time.Now().UnixNano()}}
+	for _, n := range s.peers {
+		rs = append(rs, *n)
+	}
+	sort.Slice(rs[1:], func(i, j int) bool {
+		return rs[i+1].ID < rs[j+1].ID
+	})
+	return rs
+}
@@ -0,0 +1,32 @@
+package registry_center
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNodes(t *testing.T) {
+	r := NewRegistry(WithAdvertiseAddr("http://10.0.0.1:7171"), WithPeers(Node{ID: "n3", Addr: "http://10.0.0.3:7171"}, Node{ID: "n2", Addr: "http://10.0.0.2:7171"}))
+	defer r.Close()
+
+	ns := r.Nodes()
+	if len(ns) != 3 || !ns[0].Self || ns[0].Addr != "http://10.0.0.1:7171" || ns[0].Status != NodeUp {
+		t.Fatalf("self should come first, got %+v", ns)
+	}
+	if ns[1].ID != "n2" || ns[2].ID != "n3" || ns[1].Status != NodeUnknown {
+		t.Fatalf("peers should be sorted by id, got %+v", ns)
+	}
+
+	r.ObserveReplication("n2", time.Millisecond, 10, nil)
+	r.ObserveReplication("http://10.0.0.3:7171", time.Millisecond, 10, errors.New("refused"))
+	ns = r.Nodes()
+	if ns[1].Status != NodeUp || ns[1].LastSeen == 0 || ns[2].Status != NodeDown {
+		t.Fatalf("unexpected peer status %+v", ns)
+	}
+
+	r.SetPeers([]Node{{ID: "n2", Addr: "http://10.0.0.2:7171"}})
+	if ns = r.Nodes(); len(ns) != 2 || ns[1].Status != NodeUp {
+		t.Fatalf("known peer status should be kept, got %+v", ns)
+	}
+}
@@ -0,0 +1,314 @@
+// Package nomad 监听 Nomad allocation，把声明了注册信息的任务自动注册到注册中心
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// 任务声明注册信息的 meta key，task group 的 meta 覆盖 job 的 meta
+const (
+	MetaAppId   = "registry.appid"   // 应用名，未声明的 allocation 不注册
+	MetaEnv     = "registry.env"     // 环境，未声明时使用 Options.Env
+	MetaPort    = "registry.port"    // 注册的端口 label，未声明时注册所有端口
+	MetaScheme  = "registry.scheme"  // 地址协议，默认 http
+	MetaVersion = "registry.version" // 版本
+)
+
+// Options Nomad 同步配置
+type Options struct {
+	Addr          string        // Nomad HTTP 地址，如 http://127.0.0.1:4646
+	Token         string        // ACL token
+	Namespace     string        // 命名空间，为空时使用 default
+	Env           string        // 默认环境
+	RenewInterval time.Duration // 续约间隔，默认 30 秒
+	WaitTime      time.Duration // 阻塞查询等待时长，默认 5 分钟
+	Client        *http.Client
+}
+
+// allocStub /v1/allocations 返回的 allocation 摘要
+type allocStub struct {
+	ID           string `json:"ID"`
+	ClientStatus string `json:"ClientStatus"`
+	ModifyIndex  uint64 `json:"ModifyIndex"`
+}
+
+type port struct {
+	Label  string `json:"Label"`
+	Value  int    `json:"Value"`
+	HostIP string `json:"HostIP"`
+}
+
+// allocation /v1/allocation/<id> 返回的 allocation 详情中用到的字段
+type allocation struct {
+	ID        string `json:"ID"`
+	Name      string `json:"Name"`
+	TaskGroup string `json:"TaskGroup"`
+	Job       struct {
+		Meta       map[string]string `json:"Meta"`
+		TaskGroups []struct {
+			Name string            `json:"Name"`
+			Meta map[string]string `json:"Meta"`
+		} `json:"TaskGroups"`
+	} `json:"Job"`
+	AllocatedResources struct {
+		Shared struct {
+			Ports    []port `json:"Ports"`
+			Networks []struct {
+				IP            string `json:"IP"`
+				ReservedPorts []port `json:"ReservedPorts"`
+				DynamicPorts  []port `json:"DynamicPorts"`
+			} `json:"Networks"`
+		} `json:"Shared"`
+	} `json:"AllocatedResources"`
+}
+
+// registration 已注册的 allocation
+type registration struct {
+	env, appId, hostname string
+	modifyIndex          uint64
+}
+
+// Syncer 监听 Nomad allocation 的变化：运行中的 allocation 注册并定期续约，停止或消失的 allocation 下线
+type Syncer struct {
+	r    *registry.Registry
+	opts Options
+
+	lock       sync.Mutex
+	registered map[string]*registration // key: allocation ID
+}
+
+func NewSyncer(r *registry.Registry, opts Options) (*Syncer, error) {
+	if opts.Addr == "" || opts.Env == "" {
+		return nil, errors.New("nomad addr and env are required")
+	}
+	if opts.RenewInterval <= 0 {
+		opts.RenewInterval = 30 * time.Second
+	}
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &Syncer{r: r, opts: opts, registered: make(map[string]*registration)}, nil
+}
+
+// Run 持续同步直到 ctx 结束，退出时下线所有由它注册的实例
+func (s *Syncer) Run(ctx context.Context) error {
+	defer s.deregisterAll()
+	go s.renewLoop(ctx)
+	var index uint64
+	for {
+		stubs, next, err := s.listAllocations(ctx, index)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Printf("nomad list allocations failed: %v", err)
+			select {
+			case <-time.After(5 * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		// 索引回退说明 Nomad 集群重建，从头开始
+		if next < index {
+			next = 0
+		}
+		index = next
+		s.Sync(ctx, stubs)
+	}
+}
+
+// Sync 按 allocation 列表注册和下线实例
+func (s *Syncer) Sync(ctx context.Context, stubs []allocStub) {
+	running := make(map[string]struct{})
+	for _, stub := range stubs {
+		if stub.ClientStatus != "running" {
+			continue
+		}
+		running[stub.ID] = struct{}{}
+		s.lock.Lock()
+		reg, ok := s.registered[stub.ID]
+		s.lock.Unlock()
+		if ok && reg.modifyIndex == stub.ModifyIndex {
+			continue
+		}
+		alloc, err := s.getAllocation(ctx, stub.ID)
+		if err != nil {
+			log.Printf("nomad get allocation %s failed: %v", stub.ID, err)
+			continue
+		}
+		in := s.toInstance(alloc)
+		if in == nil {
+			continue
+		}
+		if _, err := s.r.Register(in, time.Now().UnixNano()); err != nil {
+			log.Printf("register nomad allocation %s failed: %v", stub.ID, err)
+			continue
+		}
+		s.lock.Lock()
+		s.registered[stub.ID] = &registration{env: in.Env, appId: in.AppId, hostname: in.Hostname, modifyIndex: stub.ModifyIndex}
+		s.lock.Unlock()
+	}
+	s.lock.Lock()
+	var stale []*registration
+	for id, reg := range s.registered {
+		if _, ok := running[id]; !ok {
+			stale = append(stale, reg)
+			delete(s.registered, id)
+		}
+	}
+	s.lock.Unlock()
+	for _, reg := range stale {
+		s.r.Cancel(reg.env, reg.appId, reg.hostname, time.Now().UnixNano())
+	}
+}
+
+// toInstance 由 allocation 生成注册实例，未声明 registry.appid 时返回 nil
+func (s *Syncer) toInstance(alloc *allocation) *registry.Instance {
+	meta := make(map[string]string)
+	for k, v := range alloc.Job.Meta {
+		meta[k] = v
+	}
+	for _, tg := range alloc.Job.TaskGroups {
+		if tg.Name == alloc.TaskGroup {
+			for k, v := range tg.Meta {
+				meta[k] = v
+			}
+		}
+	}
+	appId := meta[MetaAppId]
+	if appId == "" {
+		return nil
+	}
+	env := meta[MetaEnv]
+	if env == "" {
+		env = s.opts.Env
+	}
+	scheme := meta[MetaScheme]
+	if scheme == "" {
+		scheme = "http"
+	}
+	var addrs []string
+	addPort := func(ip string, p port) {
+		if ip == "" || (meta[MetaPort] != "" && meta[MetaPort] != p.Label) {
+			return
+		}
+		addrs = append(addrs, fmt.Sprintf("%s://%s:%d", scheme, ip, p.Value))
+	}
+	shared := alloc.AllocatedResources.Shared
+	for _, p := range shared.Ports {
+		addPort(p.HostIP, p)
+	}
+	if len(addrs) == 0 {
+		for _, n := range shared.Networks {
+			for _, p := range append(n.ReservedPorts, n.DynamicPorts...) {
+				addPort(n.IP, p)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return nil
+	}
+	return registry.NewInstance(&registry.RequestRegister{
+		Env:      env,
+		AppId:    appId,
+		Hostname: alloc.ID,
+		Addrs:    addrs,
+		Version:  meta[MetaVersion],
+		Status:   registry.StatusUP,
+		Metadata: map[string]string{"nomad_alloc": alloc.Name},
+	})
+}
+
+func (s *Syncer) renewLoop(ctx context.Context) {
+	tick := time.NewTicker(s.opts.RenewInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			s.lock.Lock()
+			regs := make([]registration, 0, len(s.registered))
+			for _, reg := range s.registered {
+				regs = append(regs, *reg)
+			}
+			s.lock.Unlock()
+			for _, reg := range regs {
+				if _, err := s.r.Renew(reg.env, reg.appId, reg.hostname); err != nil {
+					log.Printf("renew nomad allocation %s failed: %v", reg.hostname, err)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Syncer) deregisterAll() {
+	s.lock.Lock()
+	regs := s.registered
+	s.registered = make(map[string]*registration)
+	s.lock.Unlock()
+	for _, reg := range regs {
+		s.r.Cancel(reg.env, reg.appId, reg.hostname, time.Now().UnixNano())
+	}
+}
+
+// listAllocations 阻塞查询 allocation 列表，返回新的索引
+func (s *Syncer) listAllocations(ctx context.Context, index uint64) ([]allocStub, uint64, error) {
+	q := url.Values{}
+	q.Set("index", strconv.FormatUint(index, 10))
+	q.Set("wait", strconv.Itoa(int(s.opts.WaitTime.Seconds()))+"s")
+	var stubs []allocStub
+	header, err := s.get(ctx, "/v1/allocations", q, &stubs)
+	if err != nil {
+		return nil, index, err
+	}
+	next, _ := strconv.ParseUint(header.Get("X-Nomad-Index"), 10, 64)
+	return stubs, next, nil
+}
+
+func (s *Syncer) getAllocation(ctx context.Context, id string) (*allocation, error) {
+	var alloc allocation
+	if _, err := s.get(ctx, "/v1/allocation/"+url.PathEscape(id), url.Values{}, &alloc); err != nil {
+		return nil, err
+	}
+	return &alloc, nil
+}
+
+func (s *Syncer) get(ctx context.Context, path string, q url.Values, out interface{}) (http.Header, error) {
+	if s.opts.Namespace != "" {
+		q.Set("namespace", s.opts.Namespace)
+	}
+	u := strings.TrimRight(s.opts.Addr, "/") + path + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.Token != "" {
+		req.Header.Set("X-Nomad-Token", s.opts.Token)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return resp.Header, json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,45 @@
+package nomad
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestSyncer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/allocation/a1", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ID":"a1","Name":"web.api[0]","TaskGroup":"api",
+"Job":{"Meta":{"registry.appid":"com.xx.web","registry.version":"v1"},"TaskGroups":[{"Name":"api","Meta":{"registry.port":"http"}}]},
+"AllocatedResources":{"Shared":{"Ports":[{"Label":"http","Value":23456,"HostIP":"10.0.0.5"},{"Label":"metrics","Value":23457,"HostIP":"10.0.0.5"}]}}}`))
+	})
+	mux.HandleFunc("/v1/allocation/a2", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ID":"a2","TaskGroup":"batch","Job":{"Meta":{}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r := registry.NewRegistry()
+	s, err := NewSyncer(r, Options{Addr: srv.URL, Env: "online"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Sync(context.Background(), []allocStub{{ID: "a1", ClientStatus: "running", ModifyIndex: 1}, {ID: "a2", ClientStatus: "running", ModifyIndex: 1}})
+	c, err := r.Fetch("online", "com.xx.web", registry.StatusUP, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := c.Instances
+	if len(instances) != 1 || instances[0].Hostname != "a1" || instances[0].Version != "v1" ||
+		len(instances[0].Addrs) != 1 || instances[0].Addrs[0] != "http://10.0.0.5:23456" {
+		t.Fatalf("unexpected instances: %+v", instances)
+	}
+
+	s.Sync(context.Background(), []allocStub{{ID: "a1", ClientStatus: "complete", ModifyIndex: 2}})
+	if _, err := r.Fetch("online", "com.xx.web", registry.StatusUP, 0); err == nil {
+		t.Fatal("stopped allocation should be deregistered")
+	}
+}
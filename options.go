@@ -0,0 +1,235 @@
+package registry_center
+
+import "time"
+
+// Option 注册中心可选配置
+type Option func(r *Registry)
+
+// WithSpiffeVerification 要求携带 SpiffeID 的注册必须通过 mTLS 客户端证书校验，
+// 未提供证书的注册会被拒绝
+func WithSpiffeVerification() Option {
+	return func(r *Registry) {
+		r.requireSpiffeVerification = true
+	}
+}
+
+// WithCertificateAuthority 开启工作负载证书签发，服务实例注册及续约时由 ca 签发短期证书
+func WithCertificateAuthority(ca *CertificateAuthority) Option {
+	return func(r *Registry) {
+		r.ca = ca
+	}
+}
+
+// WithCertificateBindings 设置管理员批准的应用绑定，远程调用方只能为绑定的应用申请生成 SPIFFE ID 的证书
+func WithCertificateBindings(bindings ...CertificateBinding) Option {
+	return func(r *Registry) {
+		r.certBindings = append(r.certBindings, bindings...)
+	}
+}
+
+// WithHeartbeatCredentials 开启续约凭证校验，凭证每隔 rotateInterval 在续约响应中轮换，
+// 旧凭证在 overlap 时长内仍然有效
+func WithHeartbeatCredentials(rotateInterval, overlap time.Duration) Option {
+	return func(r *Registry) {
+		r.credentials = newHeartbeatCredentials(rotateInterval, overlap)
+	}
+}
+
+// WithAdmission 开启按优先级排队的准入控制，优先级 renew > register > cancel > fetch > admin，
+// 保证 fetch 风暴下心跳仍能被及时处理
+func WithAdmission(cfg AdmissionConfig) Option {
+	return func(r *Registry) {
+		r.admission = newAdmission(cfg)
+	}
+}
+
+// WithOverloadDetection 开启过载检测，过载时进入降级模式：Fetch 只返回缓存数据，续约照常处理
+func WithOverloadDetection(cfg OverloadConfig) Option {
+	return func(r *Registry) {
+		r.overload = newOverloadDetector(cfg)
+	}
+}
+
+// WithEventLogCapacity 设置事件日志保留的事件数
+func WithEventLogCapacity(capacity int) Option {
+	return func(r *Registry) {
+		r.events = newEventLog(capacity)
+	}
+}
+
+// WithReplicator 主节点处理写操作成功后通过 rep 复制给备节点
+func WithReplicator(rep Replicator) Option {
+	return func(r *Registry) {
+		r.replicator = rep
+	}
+}
+
+// WithSoftQuotaRatio 设置配额预警的软阈值，用量达到硬上限的 ratio 时发出预警，默认 DefaultSoftQuotaRatio
+func WithSoftQuotaRatio(ratio float64) Option {
+	return func(r *Registry) {
+		if ratio > 0 && ratio <= 1 {
+			r.quotas.softRatio = ratio
+		}
+	}
+}
+
+// WithKeyEncoder 设置应用标识的字符串编码，用于状态文件、批量操作结果及指标标签，
+// 默认 DefaultKeyEncoder。需要沿用旧版本 appId-env 格式时使用 LegacyKeyEncoder
+func WithKeyEncoder(enc KeyEncoder) Option {
+	return func(r *Registry) {
+		r.keys = enc
+	}
+}
+
+// WithSnapshotFetcher 开启与对端节点的对账，对端快照通过 f 以对端的管理员身份获取，见 Registry.ReconcileWith
+func WithSnapshotFetcher(f SnapshotFetcher) Option {
+	return func(r *Registry) {
+		r.snapshotFetcher = f
+	}
+}
+
+// WithStandby 以备节点启动：只读，只接收主节点的复制，
+// 配置了 CheckActive 时在主节点连续故障后自动升主
+func WithStandby(cfg FailoverConfig) Option {
+	return func(r *Registry) {
+		if cfg.Interval <= 0 {
+			cfg.Interval = 5 * time.Second
+		}
+		if cfg.FailureThreshold <= 0 {
+			cfg.FailureThreshold = 3
+		}
+		r.failover.role = RoleStandby
+		r.failover.cfg = cfg
+	}
+}
+
+// WithTaskConfig 设置后台任务的启停及运行间隔，如 WithTaskConfig(TaskEvict, TaskConfig{Interval: 30 * time.Second})
+func WithTaskConfig(name string, cfg TaskConfig) Option {
+	return func(r *Registry) {
+		r.tasks.configure(name, cfg)
+	}
+}
+
+// WithTracer 使用 t 记录复制的发送和应用 span
+func WithTracer(t Tracer) Option {
+	return func(r *Registry) {
+		r.tracer = t
+	}
+}
+
+// WithEvictionStrategy 设置过期实例剔除策略，默认 HealthAwareEviction
+func WithEvictionStrategy(s EvictionStrategy) Option {
+	return func(r *Registry) {
+		r.evictionStrategy = s
+	}
+}
+
+// WithNodeID 设置本节点标识，用于混合逻辑时钟打破平局，默认使用主机名
+func WithNodeID(id string) Option {
+	return func(r *Registry) {
+		r.clock = newHLCClock(id)
+	}
+}
+
+// WithSessionGrace 设置长连接会话断开后保留注册的宽限期
+func WithSessionGrace(grace time.Duration) Option {
+	return func(r *Registry) {
+		r.sessions.grace = grace
+	}
+}
+
+// WithAlerts 开启告警，自我保护、大量剔除、复制目标不可达及存储写入失败时通知 cfg.Sinks
+func WithAlerts(cfg AlertConfig) Option {
+	return func(r *Registry) {
+		r.alerter = newAlerter(cfg)
+	}
+}
+
+// WithTombstoneTTL 设置实例下线后墓碑的保留时长
+func WithTombstoneTTL(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.tombstones.ttl = ttl
+	}
+}
+
+// WithZoneOutageDetection 开启可用区故障检测，实例按元数据 zone 划分可用区，
+// 故障期间 Fetch 响应带上 ZoneFailoverHint
+func WithZoneOutageDetection(cfg ZoneOutageConfig) Option {
+	return func(r *Registry) {
+		r.zones = newZoneDetector(cfg)
+	}
+}
+
+// WithAppRetention 最后一个实例下线后应用保留 ttl 再删除，期间应用的 latestTimestamp 不丢失，
+// 实例重新注册后调用方可继续增量拉取
+func WithAppRetention(ttl time.Duration) Option {
+	return func(r *Registry) {
+		r.appRetention = ttl
+	}
+}
+
+// WithPollInterval 设置下发给客户端的建议轮询间隔，运行中可通过 SetPollInterval 调整
+func WithPollInterval(normal, degraded time.Duration) Option {
+	return func(r *Registry) {
+		r.SetPollInterval(normal, degraded)
+	}
+}
+
+// WithAdvertiseAddr 设置本节点对外的服务地址，通过 Nodes 告知客户端
+func WithAdvertiseAddr(addr string) Option {
+	return func(r *Registry) {
+		r.nodes.addr = addr
+	}
+}
+
+// WithPeers 设置集群中的对端节点，节点状态根据 ObserveReplication 上报的复制结果更新
+func WithPeers(peers ...Node) Option {
+	return func(r *Registry) {
+		r.SetPeers(peers)
+	}
+}
+
+// WithHeadroomThreshold 设置判断应用是否还有余量的利用率阈值，范围 0-1
+func WithHeadroomThreshold(threshold float64) Option {
+	return func(r *Registry) {
+		if threshold > 0 && threshold <= 1 {
+			r.capacity.threshold = threshold
+		}
+	}
+}
+
+// WithUniquenessPolicy 设置地址唯一性约束，冲突的注册被拒绝并可通过 AddrConflicts 查询
+func WithUniquenessPolicy(p UniquenessPolicy) Option {
+	return func(r *Registry) {
+		r.SetUniquenessPolicy(p)
+	}
+}
+
+// WithTokenKey 设置只读令牌的签名密钥，集群各节点使用相同的密钥时令牌在任一节点有效，
+// 未设置时每次启动随机生成
+func WithTokenKey(key []byte) Option {
+	return func(r *Registry) {
+		r.tokens = newTokenIssuer(key)
+	}
+}
+
+// WithHeatmap 设置活动热力图的分桶宽度及保留时长，默认 5 秒一个分桶、保留 15 分钟
+func WithHeatmap(bucket, window time.Duration) Option {
+	return func(r *Registry) {
+		r.activity = newActivityTracker(bucket, window)
+	}
+}
+
+// WithPeerAuth 校验复制请求的对端节点及签名，Strict 时拒绝白名单外节点的复制
+func WithPeerAuth(cfg PeerAuthConfig) Option {
+	return func(r *Registry) {
+		r.peerAuth = cfg
+	}
+}
+
+// WithInstanceSchema 设置 API 默认输出的实例结构 InstanceSchemaV1 或 InstanceSchemaV2，默认 v1
+func WithInstanceSchema(schema string) Option {
+	return func(r *Registry) {
+		r.SetInstanceSchema(schema)
+	}
+}
@@ -0,0 +1,148 @@
+package registry_center
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// 服务端运行模式
+const (
+	ModeNormal   = "normal"   // 正常
+	ModeDegraded = "degraded" // 过载降级：Fetch 只返回缓存数据，后台同步任务暂停，续约照常处理
+)
+
+// OverloadConfig 过载检测配置，请求平均耗时或准入排队数超过阈值时进入降级模式，
+// 持续 RecoverAfter 低于阈值后恢复
+type OverloadConfig struct {
+	MaxLatency    time.Duration // 请求平均耗时阈值，0 表示不检测
+	MaxQueueDepth int           // 准入控制排队数阈值，0 表示不检测
+	RecoverAfter  time.Duration // 恢复正常前需要持续低于阈值的时长
+}
+
+// HealthStatus 注册中心自身的健康状况
+type HealthStatus struct {
+	Mode          string        `json:"mode"`           // 运行模式
+	Role          string        `json:"role"`           // 主备角色
+	Latency       time.Duration `json:"latency"`        // 请求平均耗时
+	QueueDepth    int           `json:"queue_depth"`    // 准入控制排队数
+	DegradedSince int64         `json:"degraded_since"` // 进入降级模式的时间
+
+	StorageDegraded bool            `json:"storage_degraded"`  // 有外部存储不可用，读写由内存数据提供
+	Storage         []StorageStatus `json:"storage,omitempty"` // 各外部存储的状态
+}
+
+// overloadDetector 过载检测，请求耗时使用指数加权移动平均
+type overloadDetector struct {
+	cfg           OverloadConfig
+	lock          sync.Mutex
+	latency       float64
+	degradedSince int64
+	healthySince  int64
+	cache         map[fetchCacheKey]*FetchData // 降级模式下使用的 Fetch 缓存
+}
+
+func newOverloadDetector(cfg OverloadConfig) *overloadDetector {
+	return &overloadDetector{
+		cfg:   cfg,
+		cache: make(map[fetchCacheKey]*FetchData),
+	}
+}
+
+// observe 记录一次请求耗时并重新判断是否过载
+func (d *overloadDetector) observe(cost time.Duration, queueDepth int) {
+	now := time.Now().UnixNano()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.latency = 0.9*d.latency + 0.1*float64(cost)
+	overloaded := (d.cfg.MaxLatency > 0 && d.latency > float64(d.cfg.MaxLatency)) ||
+		(d.cfg.MaxQueueDepth > 0 && queueDepth > d.cfg.MaxQueueDepth)
+	switch {
+	case overloaded:
+		d.healthySince = 0
+		if d.degradedSince == 0 {
+			d.degradedSince = now
+			log.Printf("registry overloaded, enter degraded mode: latency=%s queue=%d", time.Duration(d.latency), queueDepth)
+		}
+	case d.degradedSince != 0:
+		if d.healthySince == 0 {
+			d.healthySince = now
+		}
+		if now-d.healthySince >= int64(d.cfg.RecoverAfter) {
+			d.degradedSince = 0
+			d.healthySince = 0
+			log.Println("registry recovered, leave degraded mode")
+		}
+	}
+}
+
+func (d *overloadDetector) degraded() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.degradedSince != 0
+}
+
+func (d *overloadDetector) storeFetch(key AppKey, status uint32, data *FetchData) {
+	d.lock.Lock()
+	d.cache[fetchCacheKey{key, status}] = copyFetchData(data)
+	d.lock.Unlock()
+}
+
+func (d *overloadDetector) loadFetch(key AppKey, status uint32) (*FetchData, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	data, ok := d.cache[fetchCacheKey{key, status}]
+	if !ok {
+		return nil, false
+	}
+	return copyFetchData(data), true
+}
+
+type fetchCacheKey struct {
+	app    AppKey
+	status uint32
+}
+
+func copyFetchData(src *FetchData) *FetchData {
+	dst := *src
+	dst.payload = nil
+	dst.Instances = make([]*Instance, len(src.Instances))
+	for i, in := range src.Instances {
+		dst.Instances[i] = copyInstance(in)
+	}
+	return &dst
+}
+
+func (r *Registry) queueDepth() int {
+	if r.admission == nil {
+		return 0
+	}
+	return r.admission.depth()
+}
+
+// Degraded 是否处于过载降级模式，后台同步类任务应在降级时暂停
+func (r *Registry) Degraded() bool {
+	return r.overload != nil && r.overload.degraded()
+}
+
+// Health 返回注册中心自身的健康状况
+func (r *Registry) Health() HealthStatus {
+	hs := HealthStatus{Mode: ModeNormal, Role: r.Role(), QueueDepth: r.queueDepth(), Storage: r.StorageStatus()}
+	for _, st := range hs.Storage {
+		if !st.Available {
+			hs.StorageDegraded = true
+		}
+	}
+	if r.overload == nil {
+		return hs
+	}
+	d := r.overload
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	hs.Latency = time.Duration(d.latency)
+	if d.degradedSince != 0 {
+		hs.Mode = ModeDegraded
+		hs.DegradedSince = d.degradedSince
+	}
+	return hs
+}
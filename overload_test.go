@@ -0,0 +1,33 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDegradedModeServesCachedFetch(t *testing.T) {
+	r := NewRegistry(WithOverloadDetection(OverloadConfig{MaxLatency: time.Second, RecoverAfter: time.Hour}))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.overload", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	if _, err := r.Fetch("test", "com.xx.overload", StatusUP, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟请求耗时飙升
+	for i := 0; i < 50; i++ {
+		r.overload.observe(time.Minute, 0)
+	}
+	if hs := r.Health(); hs.Mode != ModeDegraded {
+		t.Fatalf("expect degraded mode, got %+v", hs)
+	}
+	// 降级期间新注册的实例不会出现在 Fetch 结果中
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.overload", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	c, err := r.Fetch("test", "com.xx.overload", StatusUP, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Instances) != 1 {
+		t.Fatalf("expect cached response with 1 instance, got %d", len(c.Instances))
+	}
+	if _, err := r.Renew("test", "com.xx.overload", "b"); err != nil {
+		t.Fatalf("renew should be accepted in degraded mode: %v", err)
+	}
+}
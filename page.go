@@ -0,0 +1,132 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor 分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// MaxFetchLimit 单页实例数上限，更大的 limit 按该值处理
+const MaxFetchLimit = 5000
+
+// FetchAllData 分页获取环境下所有应用的实例
+type FetchAllData struct {
+	Apps       map[string][]*Instance `json:"apps"`                  // key: appId
+	Total      int                    `json:"total"`                 // 环境下的实例总数
+	NextCursor string                 `json:"next_cursor,omitempty"` // 为空表示已是最后一页
+}
+
+// pageLimit 规范化每页实例数，0 表示不分页
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	if limit > MaxFetchLimit {
+		return MaxFetchLimit
+	}
+	return limit
+}
+
+// cursorSep 分隔游标中的 appId 与 hostname
+const cursorSep = "\x00"
+
+func encodeCursor(key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(b), nil
+}
+
+// paginate 按 hostname 排序后返回游标之后的一页实例。游标记录上一页最后一个实例，
+// 翻页期间实例增减不会导致重复或跳过其余实例
+func paginate(instances []*Instance, limit int, cursor string) ([]*Instance, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	sorted := make([]*Instance, len(instances))
+	copy(sorted, instances)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Hostname < sorted[j].Hostname
+	})
+	rs := sorted[sort.Search(len(sorted), func(i int) bool {
+		return sorted[i].Hostname > after
+	}):]
+	if limit > 0 && len(rs) > limit {
+		rs = rs[:limit]
+		return rs, encodeCursor(rs[limit-1].Hostname), nil
+	}
+	return rs, "", nil
+}
+
+// FetchAllPageContext 分页获取环境下所有应用的实例，按 appId、hostname 排序，
+// cursor 为上一页返回的 NextCursor，第一页为空
+func (r *Registry) FetchAllPageContext(ctx context.Context, env string, limit int, cursor string) (rs *FetchAllData, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	afterApp, afterHost, _ := strings.Cut(after, cursorSep)
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageCache); err != nil {
+		return nil, err
+	}
+	var apps []*Application
+	for _, app := range r.getAllApplications() {
+		if app.env == env && r.Visible(ctx, env, app.appId) {
+			apps = append(apps, app)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].appId < apps[j].appId
+	})
+	type entry struct {
+		appId string
+		in    *Instance
+	}
+	rs = &FetchAllData{Apps: make(map[string][]*Instance)}
+	var rest []entry
+	for _, app := range apps {
+		instances, _, _ := paginate(app.GetAllInstances(), 0, "")
+		rs.Total += len(instances)
+		if app.appId < afterApp {
+			continue
+		}
+		for _, in := range instances {
+			if app.appId > afterApp || in.Hostname > afterHost {
+				rest = append(rest, entry{app.appId, in})
+			}
+		}
+	}
+	if limit = pageLimit(limit); limit > 0 && len(rest) > limit {
+		rest = rest[:limit]
+		last := rest[limit-1]
+		rs.NextCursor = encodeCursor(last.appId + cursorSep + last.in.Hostname)
+	}
+	for _, e := range rest {
+		rs.Apps[e.appId] = append(rs.Apps[e.appId], e.in)
+	}
+	for appId, instances := range rs.Apps {
+		rs.Apps[appId] = r.applyHealthScores(instances, 0)
+	}
+	return rs, nil
+}
@@ -0,0 +1,58 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFetchPagination(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	now := time.Now().UnixNano()
+	for _, app := range []string{"com.xx.a", "com.xx.b"} {
+		for i := 0; i < 5; i++ {
+			r.Register(NewInstance(&RequestRegister{Env: "test", AppId: app, Hostname: fmt.Sprintf("h%d", i), Status: StatusUP,
+				Addrs: []string{fmt.Sprintf("http://127.0.0.1:%d", 8000+i)}}), now)
+		}
+	}
+	var hosts []string
+	cursor := ""
+	for page := 0; ; page++ {
+		data, err := r.FetchWithOptions("test", "com.xx.a", StatusUP, 0, FetchOptions{Limit: 2, Cursor: cursor})
+		if err != nil || data.Total != 5 || len(data.Instances) > 2 {
+			t.Fatalf("unexpected page %+v %v", data, err)
+		}
+		for _, in := range data.Instances {
+			hosts = append(hosts, in.Hostname)
+		}
+		if cursor = data.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if fmt.Sprint(hosts) != "[h0 h1 h2 h3 h4]" {
+		t.Fatalf("unexpected hosts %v", hosts)
+	}
+	if _, err := r.FetchWithOptions("test", "com.xx.a", StatusUP, 0, FetchOptions{Cursor: "!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expect invalid cursor, got %v", err)
+	}
+
+	n, cursor := 0, ""
+	for {
+		page, err := r.FetchAllPageContext(context.Background(), "test", 3, cursor)
+		if err != nil || page.Total != 10 {
+			t.Fatalf("unexpected page %+v %v", page, err)
+		}
+		for _, instances := range page.Apps {
+			n += len(instances)
+		}
+		if cursor = page.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if n != 10 {
+		t.Fatalf("expect all 10 instances across pages, got %d", n)
+	}
+}
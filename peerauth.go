@@ -0,0 +1,87 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrPeerRejected 复制请求来自未在白名单中的节点或签名不正确
+var ErrPeerRejected = errors.New("replication peer rejected")
+
+// PeerAuthConfig 复制请求的对端校验
+type PeerAuthConfig struct {
+	// Strict 只接受 Secrets 中列出的节点的复制，关闭时未列出的节点不校验，
+	// 列出的节点仍需签名正确
+	Strict bool
+	// Secrets 对端节点 ID 到共享密钥，对端用该密钥对复制请求签名，密钥为空表示只校验节点 ID
+	Secrets map[string]string
+}
+
+// PeerRejectedError 被拒绝的复制请求
+type PeerRejectedError struct {
+	Peer   string
+	Reason string
+}
+
+func (e *PeerRejectedError) Error() string {
+	return fmt.Sprintf("%v: %q %s", ErrPeerRejected, e.Peer, e.Reason)
+}
+
+func (e *PeerRejectedError) Unwrap() error {
+	return ErrPeerRejected
+}
+
+// SignReplication 用共享密钥对复制请求体签名，返回十六进制的 HMAC-SHA256
+func SignReplication(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkPeer 校验复制请求的对端及签名，通过时返回 nil
+func (r *Registry) checkPeer(peer, signature string, body []byte) error {
+	cfg := r.peerAuth
+	secret, known := cfg.Secrets[peer]
+	switch {
+	case !known && cfg.Strict:
+		return &PeerRejectedError{Peer: peer, Reason: "not in allow list"}
+	case !known || secret == "":
+		return nil
+	case signature == "":
+		return &PeerRejectedError{Peer: peer, Reason: "missing signature"}
+	case !hmac.Equal([]byte(signature), []byte(SignReplication(secret, body))):
+		return &PeerRejectedError{Peer: peer, Reason: "invalid signature"}
+	}
+	return nil
+}
+
+// ApplyPeerReplication 校验对端身份后应用 JSON 格式的 ReplicationOp，供复制接口调用。
+// peer 为请求声明的节点 ID，signature 为 SignReplication 对 body 的签名。
+// 被拒绝的请求记入审计日志并返回 PeerRejectedError
+func (r *Registry) ApplyPeerReplication(ctx context.Context, peer, signature string, body []byte) error {
+	var op ReplicationOp
+	decodeErr := json.Unmarshal(body, &op)
+	if err := r.checkPeer(peer, signature, body); err != nil {
+		r.audit(ctx, AuditEntry{
+			Action:   AuditReplicationRejected,
+			Peer:     peer,
+			Env:      op.Env,
+			AppId:    op.AppId,
+			Hostname: op.Hostname,
+			Reason:   err.(*PeerRejectedError).Reason,
+		})
+		return err
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	if op.Origin == "" {
+		op.Origin = peer
+	}
+	return r.ApplyReplication(op)
+}
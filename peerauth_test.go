@@ -0,0 +1,35 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPeerAuth(t *testing.T) {
+	r := NewRegistry(WithPeerAuth(PeerAuthConfig{Strict: true, Secrets: map[string]string{"n2": "s3cret"}}))
+	op := ReplicationOp{Action: ActionRegister, Env: "test", AppId: "com.xx.peer", Hostname: "a", LatestTimestamp: 1,
+		Instance: &Instance{Env: "test", AppId: "com.xx.peer", Hostname: "a", Status: StatusUP}}
+	body, _ := json.Marshal(op)
+	ctx := context.Background()
+
+	for peer, sig := range map[string]string{"rogue": SignReplication("s3cret", body), "n2": SignReplication("wrong", body)} {
+		if err := r.ApplyPeerReplication(ctx, peer, sig, body); !errors.Is(err, ErrPeerRejected) {
+			t.Fatalf("expect %s rejected, got %v", peer, err)
+		}
+	}
+	if _, ok := r.getInstance("test", "com.xx.peer", "a"); ok {
+		t.Fatal("rejected replication should not be applied")
+	}
+	if audit := r.AuditLog(); len(audit) != 2 || audit[0].Action != AuditReplicationRejected || audit[0].AppId != "com.xx.peer" {
+		t.Fatalf("unexpected audit log %+v", audit)
+	}
+
+	if err := r.ApplyPeerReplication(ctx, "n2", SignReplication("s3cret", body), body); err != nil {
+		t.Fatal(err)
+	}
+	if in, ok := r.getInstance("test", "com.xx.peer", "a"); !ok || in.Source.Node != "n2" {
+		t.Fatalf("unexpected replicated instance %+v", in)
+	}
+}
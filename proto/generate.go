@@ -0,0 +1,4 @@
+// Package registrypb 注册中心 gRPC 接口的生成代码，修改 registry.proto 后执行 go generate 重新生成
+package registrypb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative registry.proto
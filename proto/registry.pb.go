@@ -0,0 +1,2326 @@
+// 注册中心 gRPC 接口定义，字段含义与 registry_center 包中的同名结构一致
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: registry.proto
+
+package registrypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ErrorInfo 机器可读的错误，失败的 RPC 把它作为 google.rpc.Status 的 details 返回，
+// code 取值见 registry_center.ErrorCode，如 INSTANCE_NOT_FOUND 时应重新注册
+type ErrorInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Retryable     bool                   `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	Details       map[string]string      `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // 如 conflicting_dirty_timestamp、retry_after_ms
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorInfo) Reset() {
+	*x = ErrorInfo{}
+	mi := &file_registry_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorInfo) ProtoMessage() {}
+
+func (x *ErrorInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorInfo.ProtoReflect.Descriptor instead.
+func (*ErrorInfo) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ErrorInfo) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *ErrorInfo) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ErrorInfo) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+func (x *ErrorInfo) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+type Endpoint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scheme        string                 `protobuf:"bytes,1,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	Addr          string                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Secure        bool                   `protobuf:"varint,3,opt,name=secure,proto3" json:"secure,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // 端口名
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Endpoint) Reset() {
+	*x = Endpoint{}
+	mi := &file_registry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Endpoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Endpoint) ProtoMessage() {}
+
+func (x *Endpoint) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Endpoint.ProtoReflect.Descriptor instead.
+func (*Endpoint) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Endpoint) GetScheme() string {
+	if x != nil {
+		return x.Scheme
+	}
+	return ""
+}
+
+func (x *Endpoint) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *Endpoint) GetSecure() bool {
+	if x != nil {
+		return x.Secure
+	}
+	return false
+}
+
+func (x *Endpoint) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type HLC struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WallTime      int64                  `protobuf:"varint,1,opt,name=wall_time,json=wallTime,proto3" json:"wall_time,omitempty"`
+	Logical       uint32                 `protobuf:"varint,2,opt,name=logical,proto3" json:"logical,omitempty"`
+	Node          string                 `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HLC) Reset() {
+	*x = HLC{}
+	mi := &file_registry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HLC) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HLC) ProtoMessage() {}
+
+func (x *HLC) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HLC.ProtoReflect.Descriptor instead.
+func (*HLC) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *HLC) GetWallTime() int64 {
+	if x != nil {
+		return x.WallTime
+	}
+	return 0
+}
+
+func (x *HLC) GetLogical() uint32 {
+	if x != nil {
+		return x.Logical
+	}
+	return 0
+}
+
+func (x *HLC) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type Instance struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Env             string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"`
+	AppId           string                 `protobuf:"bytes,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname        string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Addrs           []string               `protobuf:"bytes,4,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	Endpoints       []*Endpoint            `protobuf:"bytes,5,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	Version         string                 `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`
+	Status          uint32                 `protobuf:"varint,7,opt,name=status,proto3" json:"status,omitempty"`
+	Weight          uint32                 `protobuf:"varint,8,opt,name=weight,proto3" json:"weight,omitempty"`
+	Group           string                 `protobuf:"bytes,9,opt,name=group,proto3" json:"group,omitempty"`
+	HealthScore     int32                  `protobuf:"varint,10,opt,name=health_score,json=healthScore,proto3" json:"health_score,omitempty"`
+	Metadata        map[string]string      `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SpiffeId        string                 `protobuf:"bytes,12,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	SpiffeVerified  bool                   `protobuf:"varint,13,opt,name=spiffe_verified,json=spiffeVerified,proto3" json:"spiffe_verified,omitempty"`
+	Clock           *HLC                   `protobuf:"bytes,14,opt,name=clock,proto3" json:"clock,omitempty"`
+	RegTimestamp    int64                  `protobuf:"varint,15,opt,name=reg_timestamp,json=regTimestamp,proto3" json:"reg_timestamp,omitempty"`
+	UpTimestamp     int64                  `protobuf:"varint,16,opt,name=up_timestamp,json=upTimestamp,proto3" json:"up_timestamp,omitempty"`
+	RenewTimestamp  int64                  `protobuf:"varint,17,opt,name=renew_timestamp,json=renewTimestamp,proto3" json:"renew_timestamp,omitempty"`
+	DirtyTimestamp  int64                  `protobuf:"varint,18,opt,name=dirty_timestamp,json=dirtyTimestamp,proto3" json:"dirty_timestamp,omitempty"`
+	LatestTimestamp int64                  `protobuf:"varint,19,opt,name=latest_timestamp,json=latestTimestamp,proto3" json:"latest_timestamp,omitempty"`
+	Source          *RegistrationSource    `protobuf:"bytes,20,opt,name=source,proto3" json:"source,omitempty"`
+	InstanceId      string                 `protobuf:"bytes,21,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"` // 为空时 v2 结构使用 env/app_id/hostname
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Instance) Reset() {
+	*x = Instance{}
+	mi := &file_registry_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Instance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Instance) ProtoMessage() {}
+
+func (x *Instance) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Instance.ProtoReflect.Descriptor instead.
+func (*Instance) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Instance) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *Instance) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *Instance) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Instance) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *Instance) GetEndpoints() []*Endpoint {
+	if x != nil {
+		return x.Endpoints
+	}
+	return nil
+}
+
+func (x *Instance) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Instance) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *Instance) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *Instance) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Instance) GetHealthScore() int32 {
+	if x != nil {
+		return x.HealthScore
+	}
+	return 0
+}
+
+func (x *Instance) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Instance) GetSpiffeId() string {
+	if x != nil {
+		return x.SpiffeId
+	}
+	return ""
+}
+
+func (x *Instance) GetSpiffeVerified() bool {
+	if x != nil {
+		return x.SpiffeVerified
+	}
+	return false
+}
+
+func (x *Instance) GetClock() *HLC {
+	if x != nil {
+		return x.Clock
+	}
+	return nil
+}
+
+func (x *Instance) GetRegTimestamp() int64 {
+	if x != nil {
+		return x.RegTimestamp
+	}
+	return 0
+}
+
+func (x *Instance) GetUpTimestamp() int64 {
+	if x != nil {
+		return x.UpTimestamp
+	}
+	return 0
+}
+
+func (x *Instance) GetRenewTimestamp() int64 {
+	if x != nil {
+		return x.RenewTimestamp
+	}
+	return 0
+}
+
+func (x *Instance) GetDirtyTimestamp() int64 {
+	if x != nil {
+		return x.DirtyTimestamp
+	}
+	return 0
+}
+
+func (x *Instance) GetLatestTimestamp() int64 {
+	if x != nil {
+		return x.LatestTimestamp
+	}
+	return 0
+}
+
+func (x *Instance) GetSource() *RegistrationSource {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *Instance) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+type RegistrationSource struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"` // direct, agent, session, replication, inprocess
+	ClientIp      string                 `protobuf:"bytes,2,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	SdkVersion    string                 `protobuf:"bytes,3,opt,name=sdk_version,json=sdkVersion,proto3" json:"sdk_version,omitempty"`
+	Node          string                 `protobuf:"bytes,4,opt,name=node,proto3" json:"node,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegistrationSource) Reset() {
+	*x = RegistrationSource{}
+	mi := &file_registry_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegistrationSource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegistrationSource) ProtoMessage() {}
+
+func (x *RegistrationSource) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegistrationSource.ProtoReflect.Descriptor instead.
+func (*RegistrationSource) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RegistrationSource) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *RegistrationSource) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *RegistrationSource) GetSdkVersion() string {
+	if x != nil {
+		return x.SdkVersion
+	}
+	return ""
+}
+
+func (x *RegistrationSource) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+type HeartbeatCredential struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secret        string                 `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	IssuedAt      int64                  `protobuf:"varint,2,opt,name=issued_at,json=issuedAt,proto3" json:"issued_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatCredential) Reset() {
+	*x = HeartbeatCredential{}
+	mi := &file_registry_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatCredential) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatCredential) ProtoMessage() {}
+
+func (x *HeartbeatCredential) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatCredential.ProtoReflect.Descriptor instead.
+func (*HeartbeatCredential) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HeartbeatCredential) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *HeartbeatCredential) GetIssuedAt() int64 {
+	if x != nil {
+		return x.IssuedAt
+	}
+	return 0
+}
+
+// RegisterRequest 对应 RequestRegister
+type RegisterRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Env             string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"`
+	AppId           string                 `protobuf:"bytes,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname        string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Addrs           []string               `protobuf:"bytes,4,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	SecureAddrs     []string               `protobuf:"bytes,5,rep,name=secure_addrs,json=secureAddrs,proto3" json:"secure_addrs,omitempty"`
+	Status          uint32                 `protobuf:"varint,6,opt,name=status,proto3" json:"status,omitempty"`
+	Weight          uint32                 `protobuf:"varint,7,opt,name=weight,proto3" json:"weight,omitempty"`
+	Version         string                 `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
+	Group           string                 `protobuf:"bytes,9,opt,name=group,proto3" json:"group,omitempty"`
+	SpiffeId        string                 `protobuf:"bytes,10,opt,name=spiffe_id,json=spiffeId,proto3" json:"spiffe_id,omitempty"`
+	Metadata        map[string]string      `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	LatestTimestamp int64                  `protobuf:"varint,12,opt,name=latest_timestamp,json=latestTimestamp,proto3" json:"latest_timestamp,omitempty"`
+	DirtyTimestamp  int64                  `protobuf:"varint,13,opt,name=dirty_timestamp,json=dirtyTimestamp,proto3" json:"dirty_timestamp,omitempty"`
+	InstanceId      string                 `protobuf:"bytes,14,opt,name=instance_id,json=instanceId,proto3" json:"instance_id,omitempty"`
+	PortNames       map[string]string      `protobuf:"bytes,15,rep,name=port_names,json=portNames,proto3" json:"port_names,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // key: 地址
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_registry_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RegisterRequest) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetAddrs() []string {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetSecureAddrs() []string {
+	if x != nil {
+		return x.SecureAddrs
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetSpiffeId() string {
+	if x != nil {
+		return x.SpiffeId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *RegisterRequest) GetLatestTimestamp() int64 {
+	if x != nil {
+		return x.LatestTimestamp
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetDirtyTimestamp() int64 {
+	if x != nil {
+		return x.DirtyTimestamp
+	}
+	return 0
+}
+
+func (x *RegisterRequest) GetInstanceId() string {
+	if x != nil {
+		return x.InstanceId
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetPortNames() map[string]string {
+	if x != nil {
+		return x.PortNames
+	}
+	return nil
+}
+
+type RegisterReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *Instance              `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	Credential    *HeartbeatCredential   `protobuf:"bytes,2,opt,name=credential,proto3" json:"credential,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterReply) Reset() {
+	*x = RegisterReply{}
+	mi := &file_registry_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterReply) ProtoMessage() {}
+
+func (x *RegisterReply) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterReply.ProtoReflect.Descriptor instead.
+func (*RegisterReply) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RegisterReply) GetInstance() *Instance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+func (x *RegisterReply) GetCredential() *HeartbeatCredential {
+	if x != nil {
+		return x.Credential
+	}
+	return nil
+}
+
+type RenewRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Env           string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"`
+	AppId         string                 `protobuf:"bytes,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Secret        string                 `protobuf:"bytes,4,opt,name=secret,proto3" json:"secret,omitempty"`
+	Capacity      *Capacity              `protobuf:"bytes,5,opt,name=capacity,proto3" json:"capacity,omitempty"` // 随续约上报的容量，可省略
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewRequest) Reset() {
+	*x = RenewRequest{}
+	mi := &file_registry_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewRequest) ProtoMessage() {}
+
+func (x *RenewRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewRequest.ProtoReflect.Descriptor instead.
+func (*RenewRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RenewRequest) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *RenewRequest) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *RenewRequest) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *RenewRequest) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *RenewRequest) GetCapacity() *Capacity {
+	if x != nil {
+		return x.Capacity
+	}
+	return nil
+}
+
+type Capacity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxConcurrent int64                  `protobuf:"varint,1,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"`
+	InFlight      int64                  `protobuf:"varint,2,opt,name=in_flight,json=inFlight,proto3" json:"in_flight,omitempty"`
+	Utilization   float64                `protobuf:"fixed64,3,opt,name=utilization,proto3" json:"utilization,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Capacity) Reset() {
+	*x = Capacity{}
+	mi := &file_registry_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Capacity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capacity) ProtoMessage() {}
+
+func (x *Capacity) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capacity.ProtoReflect.Descriptor instead.
+func (*Capacity) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Capacity) GetMaxConcurrent() int64 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
+func (x *Capacity) GetInFlight() int64 {
+	if x != nil {
+		return x.InFlight
+	}
+	return 0
+}
+
+func (x *Capacity) GetUtilization() float64 {
+	if x != nil {
+		return x.Utilization
+	}
+	return 0
+}
+
+type RenewReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *Instance              `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	Credential    *HeartbeatCredential   `protobuf:"bytes,2,opt,name=credential,proto3" json:"credential,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenewReply) Reset() {
+	*x = RenewReply{}
+	mi := &file_registry_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenewReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewReply) ProtoMessage() {}
+
+func (x *RenewReply) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewReply.ProtoReflect.Descriptor instead.
+func (*RenewReply) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RenewReply) GetInstance() *Instance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+func (x *RenewReply) GetCredential() *HeartbeatCredential {
+	if x != nil {
+		return x.Credential
+	}
+	return nil
+}
+
+type CancelRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Env             string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"`
+	AppId           string                 `protobuf:"bytes,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname        string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	LatestTimestamp int64                  `protobuf:"varint,4,opt,name=latest_timestamp,json=latestTimestamp,proto3" json:"latest_timestamp,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_registry_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CancelRequest) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetLatestTimestamp() int64 {
+	if x != nil {
+		return x.LatestTimestamp
+	}
+	return 0
+}
+
+type CancelReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instance      *Instance              `protobuf:"bytes,1,opt,name=instance,proto3" json:"instance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelReply) Reset() {
+	*x = CancelReply{}
+	mi := &file_registry_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelReply) ProtoMessage() {}
+
+func (x *CancelReply) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelReply.ProtoReflect.Descriptor instead.
+func (*CancelReply) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CancelReply) GetInstance() *Instance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+type FetchRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Env             string                 `protobuf:"bytes,1,opt,name=env,proto3" json:"env,omitempty"`
+	AppId           string                 `protobuf:"bytes,2,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Status          uint32                 `protobuf:"varint,3,opt,name=status,proto3" json:"status,omitempty"` // 按位匹配，0 表示 StatusUP
+	LatestTimestamp int64                  `protobuf:"varint,4,opt,name=latest_timestamp,json=latestTimestamp,proto3" json:"latest_timestamp,omitempty"`
+	ClientKey       string                 `protobuf:"bytes,5,opt,name=client_key,json=clientKey,proto3" json:"client_key,omitempty"`
+	Scheme          string                 `protobuf:"bytes,6,opt,name=scheme,proto3" json:"scheme,omitempty"`
+	SecureOnly      bool                   `protobuf:"varint,7,opt,name=secure_only,json=secureOnly,proto3" json:"secure_only,omitempty"`
+	Consumer        string                 `protobuf:"bytes,8,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	MinHealthScore  int32                  `protobuf:"varint,9,opt,name=min_health_score,json=minHealthScore,proto3" json:"min_health_score,omitempty"`
+	Limit           int32                  `protobuf:"varint,10,opt,name=limit,proto3" json:"limit,omitempty"`  // 每页实例数，0 表示不分页
+	Cursor          string                 `protobuf:"bytes,11,opt,name=cursor,proto3" json:"cursor,omitempty"` // 上一页返回的 next_cursor
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FetchRequest) Reset() {
+	*x = FetchRequest{}
+	mi := &file_registry_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchRequest) ProtoMessage() {}
+
+func (x *FetchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchRequest.ProtoReflect.Descriptor instead.
+func (*FetchRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *FetchRequest) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *FetchRequest) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *FetchRequest) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *FetchRequest) GetLatestTimestamp() int64 {
+	if x != nil {
+		return x.LatestTimestamp
+	}
+	return 0
+}
+
+func (x *FetchRequest) GetClientKey() string {
+	if x != nil {
+		return x.ClientKey
+	}
+	return ""
+}
+
+func (x *FetchRequest) GetScheme() string {
+	if x != nil {
+		return x.Scheme
+	}
+	return ""
+}
+
+func (x *FetchRequest) GetSecureOnly() bool {
+	if x != nil {
+		return x.SecureOnly
+	}
+	return false
+}
+
+func (x *FetchRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *FetchRequest) GetMinHealthScore() int32 {
+	if x != nil {
+		return x.MinHealthScore
+	}
+	return 0
+}
+
+func (x *FetchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *FetchRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type PollRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fetch         *FetchRequest          `protobuf:"bytes,1,opt,name=fetch,proto3" json:"fetch,omitempty"`
+	TimeoutMs     int64                  `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PollRequest) Reset() {
+	*x = PollRequest{}
+	mi := &file_registry_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PollRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PollRequest) ProtoMessage() {}
+
+func (x *PollRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PollRequest.ProtoReflect.Descriptor instead.
+func (*PollRequest) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *PollRequest) GetFetch() *FetchRequest {
+	if x != nil {
+		return x.Fetch
+	}
+	return nil
+}
+
+func (x *PollRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type FetchData struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Instances       []*Instance            `protobuf:"bytes,1,rep,name=instances,proto3" json:"instances,omitempty"`
+	LatestTimestamp int64                  `protobuf:"varint,2,opt,name=latest_timestamp,json=latestTimestamp,proto3" json:"latest_timestamp,omitempty"`
+	Group           string                 `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"`
+	Shadows         []*Instance            `protobuf:"bytes,4,rep,name=shadows,proto3" json:"shadows,omitempty"`
+	ShadowPercent   int32                  `protobuf:"varint,5,opt,name=shadow_percent,json=shadowPercent,proto3" json:"shadow_percent,omitempty"`
+	Total           int32                  `protobuf:"varint,6,opt,name=total,proto3" json:"total,omitempty"`                            // 分页时满足条件的实例总数
+	NextCursor      string                 `protobuf:"bytes,7,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 为空表示已是最后一页
+	Hints           *CacheHints            `protobuf:"bytes,8,opt,name=hints,proto3" json:"hints,omitempty"`
+	ZoneFailover    *ZoneFailoverHint      `protobuf:"bytes,9,opt,name=zone_failover,json=zoneFailover,proto3" json:"zone_failover,omitempty"` // 应用有可用区故障时的转移提示
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *FetchData) Reset() {
+	*x = FetchData{}
+	mi := &file_registry_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FetchData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FetchData) ProtoMessage() {}
+
+func (x *FetchData) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FetchData.ProtoReflect.Descriptor instead.
+func (*FetchData) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *FetchData) GetInstances() []*Instance {
+	if x != nil {
+		return x.Instances
+	}
+	return nil
+}
+
+func (x *FetchData) GetLatestTimestamp() int64 {
+	if x != nil {
+		return x.LatestTimestamp
+	}
+	return 0
+}
+
+func (x *FetchData) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *FetchData) GetShadows() []*Instance {
+	if x != nil {
+		return x.Shadows
+	}
+	return nil
+}
+
+func (x *FetchData) GetShadowPercent() int32 {
+	if x != nil {
+		return x.ShadowPercent
+	}
+	return 0
+}
+
+func (x *FetchData) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *FetchData) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+func (x *FetchData) GetHints() *CacheHints {
+	if x != nil {
+		return x.Hints
+	}
+	return nil
+}
+
+func (x *FetchData) GetZoneFailover() *ZoneFailoverHint {
+	if x != nil {
+		return x.ZoneFailover
+	}
+	return nil
+}
+
+// CacheHints 客户端缓存提示
+type CacheHints struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PollIntervalMs int64                  `protobuf:"varint,1,opt,name=poll_interval_ms,json=pollIntervalMs,proto3" json:"poll_interval_ms,omitempty"` // 建议的轮询间隔（毫秒）
+	DeltaSupported bool                   `protobuf:"varint,2,opt,name=delta_supported,json=deltaSupported,proto3" json:"delta_supported,omitempty"`
+	Load           string                 `protobuf:"bytes,3,opt,name=load,proto3" json:"load,omitempty"` // normal 或 degraded
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CacheHints) Reset() {
+	*x = CacheHints{}
+	mi := &file_registry_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheHints) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheHints) ProtoMessage() {}
+
+func (x *CacheHints) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheHints.ProtoReflect.Descriptor instead.
+func (*CacheHints) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *CacheHints) GetPollIntervalMs() int64 {
+	if x != nil {
+		return x.PollIntervalMs
+	}
+	return 0
+}
+
+func (x *CacheHints) GetDeltaSupported() bool {
+	if x != nil {
+		return x.DeltaSupported
+	}
+	return false
+}
+
+func (x *CacheHints) GetLoad() string {
+	if x != nil {
+		return x.Load
+	}
+	return ""
+}
+
+// ZoneFailoverHint 所在可用区在 degraded 中时改为从 healthy 中选取实例
+type ZoneFailoverHint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Degraded      []string               `protobuf:"bytes,1,rep,name=degraded,proto3" json:"degraded,omitempty"`
+	Healthy       []string               `protobuf:"bytes,2,rep,name=healthy,proto3" json:"healthy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZoneFailoverHint) Reset() {
+	*x = ZoneFailoverHint{}
+	mi := &file_registry_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZoneFailoverHint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZoneFailoverHint) ProtoMessage() {}
+
+func (x *ZoneFailoverHint) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZoneFailoverHint.ProtoReflect.Descriptor instead.
+func (*ZoneFailoverHint) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ZoneFailoverHint) GetDegraded() []string {
+	if x != nil {
+		return x.Degraded
+	}
+	return nil
+}
+
+func (x *ZoneFailoverHint) GetHealthy() []string {
+	if x != nil {
+		return x.Healthy
+	}
+	return nil
+}
+
+type WatchFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        uint32                 `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"` // 按位匹配，0 表示不限
+	Zone          string                 `protobuf:"bytes,2,opt,name=zone,proto3" json:"zone,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Version       string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchFilter) Reset() {
+	*x = WatchFilter{}
+	mi := &file_registry_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchFilter) ProtoMessage() {}
+
+func (x *WatchFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchFilter.ProtoReflect.Descriptor instead.
+func (*WatchFilter) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *WatchFilter) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *WatchFilter) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *WatchFilter) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *WatchFilter) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type SessionMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Op            string                 `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"` // resume, register, cancel, renew, status, watch
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	SessionId     string                 `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Register      *RegisterRequest       `protobuf:"bytes,4,opt,name=register,proto3" json:"register,omitempty"`
+	Env           string                 `protobuf:"bytes,5,opt,name=env,proto3" json:"env,omitempty"`
+	AppId         string                 `protobuf:"bytes,6,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,7,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Status        uint32                 `protobuf:"varint,8,opt,name=status,proto3" json:"status,omitempty"`
+	Filter        *WatchFilter           `protobuf:"bytes,9,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionMessage) Reset() {
+	*x = SessionMessage{}
+	mi := &file_registry_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionMessage) ProtoMessage() {}
+
+func (x *SessionMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionMessage.ProtoReflect.Descriptor instead.
+func (*SessionMessage) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SessionMessage) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetRegister() *RegisterRequest {
+	if x != nil {
+		return x.Register
+	}
+	return nil
+}
+
+func (x *SessionMessage) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *SessionMessage) GetStatus() uint32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *SessionMessage) GetFilter() *WatchFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type SessionReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Op            string                 `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`
+	RequestId     string                 `protobuf:"bytes,2,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	SessionId     string                 `protobuf:"bytes,3,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	ErrorInfo     *ErrorInfo             `protobuf:"bytes,5,opt,name=error_info,json=errorInfo,proto3" json:"error_info,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionReply) Reset() {
+	*x = SessionReply{}
+	mi := &file_registry_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionReply) ProtoMessage() {}
+
+func (x *SessionReply) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionReply.ProtoReflect.Descriptor instead.
+func (*SessionReply) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SessionReply) GetOp() string {
+	if x != nil {
+		return x.Op
+	}
+	return ""
+}
+
+func (x *SessionReply) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SessionReply) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SessionReply) GetErrorInfo() *ErrorInfo {
+	if x != nil {
+		return x.ErrorInfo
+	}
+	return nil
+}
+
+type LeaseDirective struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServerRenewed bool                   `protobuf:"varint,1,opt,name=server_renewed,json=serverRenewed,proto3" json:"server_renewed,omitempty"`
+	GraceMs       int64                  `protobuf:"varint,2,opt,name=grace_ms,json=graceMs,proto3" json:"grace_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaseDirective) Reset() {
+	*x = LeaseDirective{}
+	mi := &file_registry_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaseDirective) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaseDirective) ProtoMessage() {}
+
+func (x *LeaseDirective) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaseDirective.ProtoReflect.Descriptor instead.
+func (*LeaseDirective) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *LeaseDirective) GetServerRenewed() bool {
+	if x != nil {
+		return x.ServerRenewed
+	}
+	return false
+}
+
+func (x *LeaseDirective) GetGraceMs() int64 {
+	if x != nil {
+		return x.GraceMs
+	}
+	return 0
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Env           string                 `protobuf:"bytes,3,opt,name=env,proto3" json:"env,omitempty"`
+	AppId         string                 `protobuf:"bytes,4,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	Hostname      string                 `protobuf:"bytes,5,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Instance      *Instance              `protobuf:"bytes,7,opt,name=instance,proto3" json:"instance,omitempty"`
+	RequestId     string                 `protobuf:"bytes,8,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Created       bool                   `protobuf:"varint,9,opt,name=created,proto3" json:"created,omitempty"`
+	Source        *RegistrationSource    `protobuf:"bytes,10,opt,name=source,proto3" json:"source,omitempty"`
+	Zone          string                 `protobuf:"bytes,11,opt,name=zone,proto3" json:"zone,omitempty"` // 可用区故障事件的可用区
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_registry_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Event) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *Event) GetAppId() string {
+	if x != nil {
+		return x.AppId
+	}
+	return ""
+}
+
+func (x *Event) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Event) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Event) GetInstance() *Instance {
+	if x != nil {
+		return x.Instance
+	}
+	return nil
+}
+
+func (x *Event) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *Event) GetCreated() bool {
+	if x != nil {
+		return x.Created
+	}
+	return false
+}
+
+func (x *Event) GetSource() *RegistrationSource {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *Event) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+// SessionFrame 应答与推送共用一个流
+type SessionFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*SessionFrame_Reply
+	//	*SessionFrame_Lease
+	//	*SessionFrame_Event
+	//	*SessionFrame_Reset_
+	Frame         isSessionFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionFrame) Reset() {
+	*x = SessionFrame{}
+	mi := &file_registry_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionFrame) ProtoMessage() {}
+
+func (x *SessionFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_registry_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionFrame.ProtoReflect.Descriptor instead.
+func (*SessionFrame) Descriptor() ([]byte, []int) {
+	return file_registry_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *SessionFrame) GetFrame() isSessionFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *SessionFrame) GetReply() *SessionReply {
+	if x != nil {
+		if x, ok := x.Frame.(*SessionFrame_Reply); ok {
+			return x.Reply
+		}
+	}
+	return nil
+}
+
+func (x *SessionFrame) GetLease() *LeaseDirective {
+	if x != nil {
+		if x, ok := x.Frame.(*SessionFrame_Lease); ok {
+			return x.Lease
+		}
+	}
+	return nil
+}
+
+func (x *SessionFrame) GetEvent() *Event {
+	if x != nil {
+		if x, ok := x.Frame.(*SessionFrame_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+func (x *SessionFrame) GetReset_() bool {
+	if x != nil {
+		if x, ok := x.Frame.(*SessionFrame_Reset_); ok {
+			return x.Reset_
+		}
+	}
+	return false
+}
+
+type isSessionFrame_Frame interface {
+	isSessionFrame_Frame()
+}
+
+type SessionFrame_Reply struct {
+	Reply *SessionReply `protobuf:"bytes,1,opt,name=reply,proto3,oneof"`
+}
+
+type SessionFrame_Lease struct {
+	Lease *LeaseDirective `protobuf:"bytes,2,opt,name=lease,proto3,oneof"`
+}
+
+type SessionFrame_Event struct {
+	Event *Event `protobuf:"bytes,3,opt,name=event,proto3,oneof"`
+}
+
+type SessionFrame_Reset_ struct {
+	Reset_ bool `protobuf:"varint,4,opt,name=reset,proto3,oneof"` // 推送中断，客户端需要重新全量拉取订阅的应用
+}
+
+func (*SessionFrame_Reply) isSessionFrame_Frame() {}
+
+func (*SessionFrame_Lease) isSessionFrame_Frame() {}
+
+func (*SessionFrame_Event) isSessionFrame_Frame() {}
+
+func (*SessionFrame_Reset_) isSessionFrame_Frame() {}
+
+var File_registry_proto protoreflect.FileDescriptor
+
+const file_registry_proto_rawDesc = "" +
+	"\n" +
+	"\x0eregistry.proto\x12\vregistry.v1\"\xd2\x01\n" +
+	"\tErrorInfo\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1c\n" +
+	"\tretryable\x18\x03 \x01(\bR\tretryable\x12=\n" +
+	"\adetails\x18\x04 \x03(\v2#.registry.v1.ErrorInfo.DetailsEntryR\adetails\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"b\n" +
+	"\bEndpoint\x12\x16\n" +
+	"\x06scheme\x18\x01 \x01(\tR\x06scheme\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\tR\x04addr\x12\x16\n" +
+	"\x06secure\x18\x03 \x01(\bR\x06secure\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"P\n" +
+	"\x03HLC\x12\x1b\n" +
+	"\twall_time\x18\x01 \x01(\x03R\bwallTime\x12\x18\n" +
+	"\alogical\x18\x02 \x01(\rR\alogical\x12\x12\n" +
+	"\x04node\x18\x03 \x01(\tR\x04node\"\xa8\x06\n" +
+	"\bInstance\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x14\n" +
+	"\x05addrs\x18\x04 \x03(\tR\x05addrs\x123\n" +
+	"\tendpoints\x18\x05 \x03(\v2\x15.registry.v1.EndpointR\tendpoints\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\x12\x16\n" +
+	"\x06status\x18\a \x01(\rR\x06status\x12\x16\n" +
+	"\x06weight\x18\b \x01(\rR\x06weight\x12\x14\n" +
+	"\x05group\x18\t \x01(\tR\x05group\x12!\n" +
+	"\fhealth_score\x18\n" +
+	" \x01(\x05R\vhealthScore\x12?\n" +
+	"\bmetadata\x18\v \x03(\v2#.registry.v1.Instance.MetadataEntryR\bmetadata\x12\x1b\n" +
+	"\tspiffe_id\x18\f \x01(\tR\bspiffeId\x12'\n" +
+	"\x0fspiffe_verified\x18\r \x01(\bR\x0espiffeVerified\x12&\n" +
+	"\x05clock\x18\x0e \x01(\v2\x10.registry.v1.HLCR\x05clock\x12#\n" +
+	"\rreg_timestamp\x18\x0f \x01(\x03R\fregTimestamp\x12!\n" +
+	"\fup_timestamp\x18\x10 \x01(\x03R\vupTimestamp\x12'\n" +
+	"\x0frenew_timestamp\x18\x11 \x01(\x03R\x0erenewTimestamp\x12'\n" +
+	"\x0fdirty_timestamp\x18\x12 \x01(\x03R\x0edirtyTimestamp\x12)\n" +
+	"\x10latest_timestamp\x18\x13 \x01(\x03R\x0flatestTimestamp\x127\n" +
+	"\x06source\x18\x14 \x01(\v2\x1f.registry.v1.RegistrationSourceR\x06source\x12\x1f\n" +
+	"\vinstance_id\x18\x15 \x01(\tR\n" +
+	"instanceId\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"z\n" +
+	"\x12RegistrationSource\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x1b\n" +
+	"\tclient_ip\x18\x02 \x01(\tR\bclientIp\x12\x1f\n" +
+	"\vsdk_version\x18\x03 \x01(\tR\n" +
+	"sdkVersion\x12\x12\n" +
+	"\x04node\x18\x04 \x01(\tR\x04node\"J\n" +
+	"\x13HeartbeatCredential\x12\x16\n" +
+	"\x06secret\x18\x01 \x01(\tR\x06secret\x12\x1b\n" +
+	"\tissued_at\x18\x02 \x01(\x03R\bissuedAt\"\x90\x05\n" +
+	"\x0fRegisterRequest\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x14\n" +
+	"\x05addrs\x18\x04 \x03(\tR\x05addrs\x12!\n" +
+	"\fsecure_addrs\x18\x05 \x03(\tR\vsecureAddrs\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\rR\x06status\x12\x16\n" +
+	"\x06weight\x18\a \x01(\rR\x06weight\x12\x18\n" +
+	"\aversion\x18\b \x01(\tR\aversion\x12\x14\n" +
+	"\x05group\x18\t \x01(\tR\x05group\x12\x1b\n" +
+	"\tspiffe_id\x18\n" +
+	" \x01(\tR\bspiffeId\x12F\n" +
+	"\bmetadata\x18\v \x03(\v2*.registry.v1.RegisterRequest.MetadataEntryR\bmetadata\x12)\n" +
+	"\x10latest_timestamp\x18\f \x01(\x03R\x0flatestTimestamp\x12'\n" +
+	"\x0fdirty_timestamp\x18\r \x01(\x03R\x0edirtyTimestamp\x12\x1f\n" +
+	"\vinstance_id\x18\x0e \x01(\tR\n" +
+	"instanceId\x12J\n" +
+	"\n" +
+	"port_names\x18\x0f \x03(\v2+.registry.v1.RegisterRequest.PortNamesEntryR\tportNames\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a<\n" +
+	"\x0ePortNamesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x84\x01\n" +
+	"\rRegisterReply\x121\n" +
+	"\binstance\x18\x01 \x01(\v2\x15.registry.v1.InstanceR\binstance\x12@\n" +
+	"\n" +
+	"credential\x18\x02 \x01(\v2 .registry.v1.HeartbeatCredentialR\n" +
+	"credential\"\x9e\x01\n" +
+	"\fRenewRequest\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x16\n" +
+	"\x06secret\x18\x04 \x01(\tR\x06secret\x121\n" +
+	"\bcapacity\x18\x05 \x01(\v2\x15.registry.v1.CapacityR\bcapacity\"p\n" +
+	"\bCapacity\x12%\n" +
+	"\x0emax_concurrent\x18\x01 \x01(\x03R\rmaxConcurrent\x12\x1b\n" +
+	"\tin_flight\x18\x02 \x01(\x03R\binFlight\x12 \n" +
+	"\vutilization\x18\x03 \x01(\x01R\vutilization\"\x81\x01\n" +
+	"\n" +
+	"RenewReply\x121\n" +
+	"\binstance\x18\x01 \x01(\v2\x15.registry.v1.InstanceR\binstance\x12@\n" +
+	"\n" +
+	"credential\x18\x02 \x01(\v2 .registry.v1.HeartbeatCredentialR\n" +
+	"credential\"\x7f\n" +
+	"\rCancelRequest\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12)\n" +
+	"\x10latest_timestamp\x18\x04 \x01(\x03R\x0flatestTimestamp\"@\n" +
+	"\vCancelReply\x121\n" +
+	"\binstance\x18\x01 \x01(\v2\x15.registry.v1.InstanceR\binstance\"\xc6\x02\n" +
+	"\fFetchRequest\x12\x10\n" +
+	"\x03env\x18\x01 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x02 \x01(\tR\x05appId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\rR\x06status\x12)\n" +
+	"\x10latest_timestamp\x18\x04 \x01(\x03R\x0flatestTimestamp\x12\x1d\n" +
+	"\n" +
+	"client_key\x18\x05 \x01(\tR\tclientKey\x12\x16\n" +
+	"\x06scheme\x18\x06 \x01(\tR\x06scheme\x12\x1f\n" +
+	"\vsecure_only\x18\a \x01(\bR\n" +
+	"secureOnly\x12\x1a\n" +
+	"\bconsumer\x18\b \x01(\tR\bconsumer\x12(\n" +
+	"\x10min_health_score\x18\t \x01(\x05R\x0eminHealthScore\x12\x14\n" +
+	"\x05limit\x18\n" +
+	" \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\v \x01(\tR\x06cursor\"]\n" +
+	"\vPollRequest\x12/\n" +
+	"\x05fetch\x18\x01 \x01(\v2\x19.registry.v1.FetchRequestR\x05fetch\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x03R\ttimeoutMs\"\x83\x03\n" +
+	"\tFetchData\x123\n" +
+	"\tinstances\x18\x01 \x03(\v2\x15.registry.v1.InstanceR\tinstances\x12)\n" +
+	"\x10latest_timestamp\x18\x02 \x01(\x03R\x0flatestTimestamp\x12\x14\n" +
+	"\x05group\x18\x03 \x01(\tR\x05group\x12/\n" +
+	"\ashadows\x18\x04 \x03(\v2\x15.registry.v1.InstanceR\ashadows\x12%\n" +
+	"\x0eshadow_percent\x18\x05 \x01(\x05R\rshadowPercent\x12\x14\n" +
+	"\x05total\x18\x06 \x01(\x05R\x05total\x12\x1f\n" +
+	"\vnext_cursor\x18\a \x01(\tR\n" +
+	"nextCursor\x12-\n" +
+	"\x05hints\x18\b \x01(\v2\x17.registry.v1.CacheHintsR\x05hints\x12B\n" +
+	"\rzone_failover\x18\t \x01(\v2\x1d.registry.v1.ZoneFailoverHintR\fzoneFailover\"s\n" +
+	"\n" +
+	"CacheHints\x12(\n" +
+	"\x10poll_interval_ms\x18\x01 \x01(\x03R\x0epollIntervalMs\x12'\n" +
+	"\x0fdelta_supported\x18\x02 \x01(\bR\x0edeltaSupported\x12\x12\n" +
+	"\x04load\x18\x03 \x01(\tR\x04load\"H\n" +
+	"\x10ZoneFailoverHint\x12\x1a\n" +
+	"\bdegraded\x18\x01 \x03(\tR\bdegraded\x12\x18\n" +
+	"\ahealthy\x18\x02 \x03(\tR\ahealthy\"\xcc\x01\n" +
+	"\vWatchFilter\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\rR\x06status\x12\x12\n" +
+	"\x04zone\x18\x02 \x01(\tR\x04zone\x12<\n" +
+	"\x06labels\x18\x03 \x03(\v2$.registry.v1.WatchFilter.LabelsEntryR\x06labels\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa7\x02\n" +
+	"\x0eSessionMessage\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\tR\x02op\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x03 \x01(\tR\tsessionId\x128\n" +
+	"\bregister\x18\x04 \x01(\v2\x1c.registry.v1.RegisterRequestR\bregister\x12\x10\n" +
+	"\x03env\x18\x05 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x06 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\a \x01(\tR\bhostname\x12\x16\n" +
+	"\x06status\x18\b \x01(\rR\x06status\x120\n" +
+	"\x06filter\x18\t \x01(\v2\x18.registry.v1.WatchFilterR\x06filter\"\xa9\x01\n" +
+	"\fSessionReply\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\tR\x02op\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x02 \x01(\tR\trequestId\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x03 \x01(\tR\tsessionId\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x125\n" +
+	"\n" +
+	"error_info\x18\x05 \x01(\v2\x16.registry.v1.ErrorInfoR\terrorInfo\"R\n" +
+	"\x0eLeaseDirective\x12%\n" +
+	"\x0eserver_renewed\x18\x01 \x01(\bR\rserverRenewed\x12\x19\n" +
+	"\bgrace_ms\x18\x02 \x01(\x03R\agraceMs\"\xc9\x02\n" +
+	"\x05Event\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x10\n" +
+	"\x03env\x18\x03 \x01(\tR\x03env\x12\x15\n" +
+	"\x06app_id\x18\x04 \x01(\tR\x05appId\x12\x1a\n" +
+	"\bhostname\x18\x05 \x01(\tR\bhostname\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\x121\n" +
+	"\binstance\x18\a \x01(\v2\x15.registry.v1.InstanceR\binstance\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\b \x01(\tR\trequestId\x12\x18\n" +
+	"\acreated\x18\t \x01(\bR\acreated\x127\n" +
+	"\x06source\x18\n" +
+	" \x01(\v2\x1f.registry.v1.RegistrationSourceR\x06source\x12\x12\n" +
+	"\x04zone\x18\v \x01(\tR\x04zone\"\xc3\x01\n" +
+	"\fSessionFrame\x121\n" +
+	"\x05reply\x18\x01 \x01(\v2\x19.registry.v1.SessionReplyH\x00R\x05reply\x123\n" +
+	"\x05lease\x18\x02 \x01(\v2\x1b.registry.v1.LeaseDirectiveH\x00R\x05lease\x12*\n" +
+	"\x05event\x18\x03 \x01(\v2\x12.registry.v1.EventH\x00R\x05event\x12\x16\n" +
+	"\x05reset\x18\x04 \x01(\bH\x00R\x05resetB\a\n" +
+	"\x05frame2\x91\x03\n" +
+	"\x0fRegistryService\x12D\n" +
+	"\bRegister\x12\x1c.registry.v1.RegisterRequest\x1a\x1a.registry.v1.RegisterReply\x12;\n" +
+	"\x05Renew\x12\x19.registry.v1.RenewRequest\x1a\x17.registry.v1.RenewReply\x12>\n" +
+	"\x06Cancel\x12\x1a.registry.v1.CancelRequest\x1a\x18.registry.v1.CancelReply\x12:\n" +
+	"\x05Fetch\x12\x19.registry.v1.FetchRequest\x1a\x16.registry.v1.FetchData\x128\n" +
+	"\x04Poll\x12\x18.registry.v1.PollRequest\x1a\x16.registry.v1.FetchData\x12E\n" +
+	"\aSession\x12\x1b.registry.v1.SessionMessage\x1a\x19.registry.v1.SessionFrame(\x010\x01B6Z4github.com/junaozun/registry-center/proto;registrypbb\x06proto3"
+
+var (
+	file_registry_proto_rawDescOnce sync.Once
+	file_registry_proto_rawDescData []byte
+)
+
+func file_registry_proto_rawDescGZIP() []byte {
+	file_registry_proto_rawDescOnce.Do(func() {
+		file_registry_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_registry_proto_rawDesc), len(file_registry_proto_rawDesc)))
+	})
+	return file_registry_proto_rawDescData
+}
+
+var file_registry_proto_msgTypes = make([]protoimpl.MessageInfo, 29)
+var file_registry_proto_goTypes = []any{
+	(*ErrorInfo)(nil),           // 0: registry.v1.ErrorInfo
+	(*Endpoint)(nil),            // 1: registry.v1.Endpoint
+	(*HLC)(nil),                 // 2: registry.v1.HLC
+	(*Instance)(nil),            // 3: registry.v1.Instance
+	(*RegistrationSource)(nil),  // 4: registry.v1.RegistrationSource
+	(*HeartbeatCredential)(nil), // 5: registry.v1.HeartbeatCredential
+	(*RegisterRequest)(nil),     // 6: registry.v1.RegisterRequest
+	(*RegisterReply)(nil),       // 7: registry.v1.RegisterReply
+	(*RenewRequest)(nil),        // 8: registry.v1.RenewRequest
+	(*Capacity)(nil),            // 9: registry.v1.Capacity
+	(*RenewReply)(nil),          // 10: registry.v1.RenewReply
+	(*CancelRequest)(nil),       // 11: registry.v1.CancelRequest
+	(*CancelReply)(nil),         // 12: registry.v1.CancelReply
+	(*FetchRequest)(nil),        // 13: registry.v1.FetchRequest
+	(*PollRequest)(nil),         // 14: registry.v1.PollRequest
+	(*FetchData)(nil),           // 15: registry.v1.FetchData
+	(*CacheHints)(nil),          // 16: registry.v1.CacheHints
+	(*ZoneFailoverHint)(nil),    // 17: registry.v1.ZoneFailoverHint
+	(*WatchFilter)(nil),         // 18: registry.v1.WatchFilter
+	(*SessionMessage)(nil),      // 19: registry.v1.SessionMessage
+	(*SessionReply)(nil),        // 20: registry.v1.SessionReply
+	(*LeaseDirective)(nil),      // 21: registry.v1.LeaseDirective
+	(*Event)(nil),               // 22: registry.v1.Event
+	(*SessionFrame)(nil),        // 23: registry.v1.SessionFrame
+	nil,                         // 24: registry.v1.ErrorInfo.DetailsEntry
+	nil,                         // 25: registry.v1.Instance.MetadataEntry
+	nil,                         // 26: registry.v1.RegisterRequest.MetadataEntry
+	nil,                         // 27: registry.v1.RegisterRequest.PortNamesEntry
+	nil,                         // 28: registry.v1.WatchFilter.LabelsEntry
+}
+var file_registry_proto_depIdxs = []int32{
+	24, // 0: registry.v1.ErrorInfo.details:type_name -> registry.v1.ErrorInfo.DetailsEntry
+	1,  // 1: registry.v1.Instance.endpoints:type_name -> registry.v1.Endpoint
+	25, // 2: registry.v1.Instance.metadata:type_name -> registry.v1.Instance.MetadataEntry
+	2,  // 3: registry.v1.Instance.clock:type_name -> registry.v1.HLC
+	4,  // 4: registry.v1.Instance.source:type_name -> registry.v1.RegistrationSource
+	26, // 5: registry.v1.RegisterRequest.metadata:type_name -> registry.v1.RegisterRequest.MetadataEntry
+	27, // 6: registry.v1.RegisterRequest.port_names:type_name -> registry.v1.RegisterRequest.PortNamesEntry
+	3,  // 7: registry.v1.RegisterReply.instance:type_name -> registry.v1.Instance
+	5,  // 8: registry.v1.RegisterReply.credential:type_name -> registry.v1.HeartbeatCredential
+	9,  // 9: registry.v1.RenewRequest.capacity:type_name -> registry.v1.Capacity
+	3,  // 10: registry.v1.RenewReply.instance:type_name -> registry.v1.Instance
+	5,  // 11: registry.v1.RenewReply.credential:type_name -> registry.v1.HeartbeatCredential
+	3,  // 12: registry.v1.CancelReply.instance:type_name -> registry.v1.Instance
+	13, // 13: registry.v1.PollRequest.fetch:type_name -> registry.v1.FetchRequest
+	3,  // 14: registry.v1.FetchData.instances:type_name -> registry.v1.Instance
+	3,  // 15: registry.v1.FetchData.shadows:type_name -> registry.v1.Instance
+	16, // 16: registry.v1.FetchData.hints:type_name -> registry.v1.CacheHints
+	17, // 17: registry.v1.FetchData.zone_failover:type_name -> registry.v1.ZoneFailoverHint
+	28, // 18: registry.v1.WatchFilter.labels:type_name -> registry.v1.WatchFilter.LabelsEntry
+	6,  // 19: registry.v1.SessionMessage.register:type_name -> registry.v1.RegisterRequest
+	18, // 20: registry.v1.SessionMessage.filter:type_name -> registry.v1.WatchFilter
+	0,  // 21: registry.v1.SessionReply.error_info:type_name -> registry.v1.ErrorInfo
+	3,  // 22: registry.v1.Event.instance:type_name -> registry.v1.Instance
+	4,  // 23: registry.v1.Event.source:type_name -> registry.v1.RegistrationSource
+	20, // 24: registry.v1.SessionFrame.reply:type_name -> registry.v1.SessionReply
+	21, // 25: registry.v1.SessionFrame.lease:type_name -> registry.v1.LeaseDirective
+	22, // 26: registry.v1.SessionFrame.event:type_name -> registry.v1.Event
+	6,  // 27: registry.v1.RegistryService.Register:input_type -> registry.v1.RegisterRequest
+	8,  // 28: registry.v1.RegistryService.Renew:input_type -> registry.v1.RenewRequest
+	11, // 29: registry.v1.RegistryService.Cancel:input_type -> registry.v1.CancelRequest
+	13, // 30: registry.v1.RegistryService.Fetch:input_type -> registry.v1.FetchRequest
+	14, // 31: registry.v1.RegistryService.Poll:input_type -> registry.v1.PollRequest
+	19, // 32: registry.v1.RegistryService.Session:input_type -> registry.v1.SessionMessage
+	7,  // 33: registry.v1.RegistryService.Register:output_type -> registry.v1.RegisterReply
+	10, // 34: registry.v1.RegistryService.Renew:output_type -> registry.v1.RenewReply
+	12, // 35: registry.v1.RegistryService.Cancel:output_type -> registry.v1.CancelReply
+	15, // 36: registry.v1.RegistryService.Fetch:output_type -> registry.v1.FetchData
+	15, // 37: registry.v1.RegistryService.Poll:output_type -> registry.v1.FetchData
+	23, // 38: registry.v1.RegistryService.Session:output_type -> registry.v1.SessionFrame
+	33, // [33:39] is the sub-list for method output_type
+	27, // [27:33] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
+}
+
+func init() { file_registry_proto_init() }
+func file_registry_proto_init() {
+	if File_registry_proto != nil {
+		return
+	}
+	file_registry_proto_msgTypes[23].OneofWrappers = []any{
+		(*SessionFrame_Reply)(nil),
+		(*SessionFrame_Lease)(nil),
+		(*SessionFrame_Event)(nil),
+		(*SessionFrame_Reset_)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_registry_proto_rawDesc), len(file_registry_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   29,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_registry_proto_goTypes,
+		DependencyIndexes: file_registry_proto_depIdxs,
+		MessageInfos:      file_registry_proto_msgTypes,
+	}.Build()
+	File_registry_proto = out.File
+	file_registry_proto_goTypes = nil
+	file_registry_proto_depIdxs = nil
+}
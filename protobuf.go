@@ -0,0 +1,376 @@
+package registry_center
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ContentTypeProtobuf Fetch 接口的 protobuf 响应类型，消息定义见 proto/registry.proto 中的 FetchData
+const ContentTypeProtobuf = "application/x-protobuf"
+
+var errProtobuf = errors.New("invalid protobuf message")
+
+// protobuf 线路类型
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// pbEncoder 按 proto3 规则编码，零值字段省略
+type pbEncoder struct {
+	buf []byte
+}
+
+func (e *pbEncoder) tag(field int, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+func (e *pbEncoder) uint(field int, v uint64) {
+	if v != 0 {
+		e.tag(field, wireVarint)
+		e.buf = binary.AppendUvarint(e.buf, v)
+	}
+}
+
+func (e *pbEncoder) int(field int, v int64) {
+	e.uint(field, uint64(v))
+}
+
+func (e *pbEncoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+func (e *pbEncoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *pbEncoder) string(field int, s string) {
+	if s != "" {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// message 编码嵌套消息，fn 写入消息的字段
+func (e *pbEncoder) message(field int, fn func(m *pbEncoder)) {
+	var m pbEncoder
+	fn(&m)
+	e.bytes(field, m.buf)
+}
+
+// stringMap 编码 map<string, string>，按 key 排序保证输出稳定
+func (e *pbEncoder) stringMap(field int, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.message(field, func(entry *pbEncoder) {
+			entry.string(1, k)
+			entry.string(2, m[k])
+		})
+	}
+}
+
+// MarshalFetchData 把 FetchData 编码为 protobuf
+func MarshalFetchData(d *FetchData) []byte {
+	if d.payload != nil {
+		return d.payload.pb
+	}
+	var e pbEncoder
+	for _, in := range d.Instances {
+		e.message(1, func(m *pbEncoder) { marshalInstance(m, in) })
+	}
+	e.int(2, d.LatestTimestamp)
+	e.string(3, d.Group)
+	for _, in := range d.Shadows {
+		e.message(4, func(m *pbEncoder) { marshalInstance(m, in) })
+	}
+	e.int(5, int64(d.ShadowPercent))
+	e.int(6, int64(d.Total))
+	e.string(7, d.NextCursor)
+	if h := d.Hints; h != nil {
+		e.message(8, func(m *pbEncoder) {
+			m.int(1, h.PollInterval.Milliseconds())
+			m.bool(2, h.DeltaSupported)
+			m.string(3, h.Load)
+		})
+	}
+	if z := d.ZoneFailover; z != nil {
+		e.message(9, func(m *pbEncoder) {
+			for _, zone := range z.Degraded {
+				m.bytes(1, []byte(zone))
+			}
+			for _, zone := range z.Healthy {
+				m.bytes(2, []byte(zone))
+			}
+		})
+	}
+	return e.buf
+}
+
+func marshalInstance(e *pbEncoder, in *Instance) {
+	e.string(1, in.Env)
+	e.string(2, in.AppId)
+	e.string(3, in.Hostname)
+	for _, addr := range in.Addrs {
+		e.bytes(4, []byte(addr))
+	}
+	for _, ep := range in.Endpoints {
+		e.message(5, func(m *pbEncoder) {
+			m.string(1, ep.Scheme)
+			m.string(2, ep.Addr)
+			m.bool(3, ep.Secure)
+			m.string(4, ep.Name)
+		})
+	}
+	e.string(6, in.Version)
+	e.uint(7, uint64(in.Status))
+	e.uint(8, uint64(in.Weight))
+	e.string(9, in.Group)
+	e.int(10, int64(in.HealthScore))
+	e.stringMap(11, in.Metadata)
+	e.string(12, in.SpiffeID)
+	e.bool(13, in.SpiffeVerified)
+	if !in.Clock.IsZero() {
+		e.message(14, func(m *pbEncoder) {
+			m.int(1, in.Clock.WallTime)
+			m.uint(2, uint64(in.Clock.Logical))
+			m.string(3, in.Clock.Node)
+		})
+	}
+	e.int(15, in.RegTimestamp)
+	e.int(16, in.UpTimestamp)
+	e.int(17, in.RenewTimestamp)
+	e.int(18, in.DirtyTimestamp)
+	e.int(19, in.LatestTimestamp)
+	if src := in.Source; src != nil {
+		e.message(20, func(m *pbEncoder) {
+			m.string(1, src.Kind)
+			m.string(2, src.ClientIP)
+			m.string(3, src.SDKVersion)
+			m.string(4, src.Node)
+		})
+	}
+	e.string(21, in.InstanceID)
+}
+
+// pbField 解码出的一个字段，varint 字段使用 n，长度前缀字段使用 b
+type pbField struct {
+	num int
+	n   uint64
+	b   []byte
+}
+
+// decodeFields 依次解码消息的字段，fn 返回错误时停止
+func decodeFields(buf []byte, fn func(f pbField) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return errProtobuf
+		}
+		buf = buf[n:]
+		f := pbField{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			if f.n, n = binary.Uvarint(buf); n <= 0 {
+				return errProtobuf
+			}
+			buf = buf[n:]
+		case wireBytes:
+			l, n := binary.Uvarint(buf)
+			if n <= 0 || l > uint64(len(buf)-n) {
+				return errProtobuf
+			}
+			f.b, buf = buf[n:n+int(l)], buf[n+int(l):]
+		case 1: // 64 位定长，跳过
+			if len(buf) < 8 {
+				return errProtobuf
+			}
+			buf = buf[8:]
+		case 5: // 32 位定长，跳过
+			if len(buf) < 4 {
+				return errProtobuf
+			}
+			buf = buf[4:]
+		default:
+			return errProtobuf
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalFetchData 解码 protobuf 格式的 FetchData，未知字段被忽略
+func UnmarshalFetchData(buf []byte) (*FetchData, error) {
+	d := &FetchData{Instances: make([]*Instance, 0)}
+	err := decodeFields(buf, func(f pbField) error {
+		switch f.num {
+		case 1, 4:
+			in, err := unmarshalInstance(f.b)
+			if err != nil {
+				return err
+			}
+			if f.num == 1 {
+				d.Instances = append(d.Instances, in)
+			} else {
+				d.Shadows = append(d.Shadows, in)
+			}
+		case 2:
+			d.LatestTimestamp = int64(f.n)
+		case 3:
+			d.Group = string(f.b)
+		case 5:
+			d.ShadowPercent = int(int64(f.n))
+		case 6:
+			d.Total = int(int64(f.n))
+		case 7:
+			d.NextCursor = string(f.b)
+		case 8:
+			d.Hints = &CacheHints{}
+			return decodeFields(f.b, func(h pbField) error {
+				switch h.num {
+				case 1:
+					d.Hints.PollInterval = time.Duration(int64(h.n)) * time.Millisecond
+				case 2:
+					d.Hints.DeltaSupported = h.n != 0
+				case 3:
+					d.Hints.Load = string(h.b)
+				}
+				return nil
+			})
+		case 9:
+			d.ZoneFailover = &ZoneFailoverHint{}
+			return decodeFields(f.b, func(z pbField) error {
+				switch z.num {
+				case 1:
+					d.ZoneFailover.Degraded = append(d.ZoneFailover.Degraded, string(z.b))
+				case 2:
+					d.ZoneFailover.Healthy = append(d.ZoneFailover.Healthy, string(z.b))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func unmarshalInstance(buf []byte) (*Instance, error) {
+	in := &Instance{}
+	err := decodeFields(buf, func(f pbField) error {
+		switch f.num {
+		case 1:
+			in.Env = string(f.b)
+		case 2:
+			in.AppId = string(f.b)
+		case 3:
+			in.Hostname = string(f.b)
+		case 4:
+			in.Addrs = append(in.Addrs, string(f.b))
+		case 5:
+			var ep Endpoint
+			if err := decodeFields(f.b, func(m pbField) error {
+				switch m.num {
+				case 1:
+					ep.Scheme = string(m.b)
+				case 2:
+					ep.Addr = string(m.b)
+				case 3:
+					ep.Secure = m.n != 0
+				case 4:
+					ep.Name = string(m.b)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			in.Endpoints = append(in.Endpoints, ep)
+		case 6:
+			in.Version = string(f.b)
+		case 7:
+			in.Status = uint32(f.n)
+		case 8:
+			in.Weight = uint32(f.n)
+		case 9:
+			in.Group = string(f.b)
+		case 10:
+			in.HealthScore = int(int64(f.n))
+		case 11:
+			var k, v string
+			if err := decodeFields(f.b, func(m pbField) error {
+				if m.num == 1 {
+					k = string(m.b)
+				} else if m.num == 2 {
+					v = string(m.b)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if in.Metadata == nil {
+				in.Metadata = make(map[string]string)
+			}
+			in.Metadata[k] = v
+		case 12:
+			in.SpiffeID = string(f.b)
+		case 13:
+			in.SpiffeVerified = f.n != 0
+		case 14:
+			return decodeFields(f.b, func(m pbField) error {
+				switch m.num {
+				case 1:
+					in.Clock.WallTime = int64(m.n)
+				case 2:
+					in.Clock.Logical = uint32(m.n)
+				case 3:
+					in.Clock.Node = string(m.b)
+				}
+				return nil
+			})
+		case 15:
+			in.RegTimestamp = int64(f.n)
+		case 16:
+			in.UpTimestamp = int64(f.n)
+		case 17:
+			in.RenewTimestamp = int64(f.n)
+		case 18:
+			in.DirtyTimestamp = int64(f.n)
+		case 19:
+			in.LatestTimestamp = int64(f.n)
+		case 20:
+			in.Source = &RegistrationSource{}
+			return decodeFields(f.b, func(m pbField) error {
+				switch m.num {
+				case 1:
+					in.Source.Kind = string(m.b)
+				case 2:
+					in.Source.ClientIP = string(m.b)
+				case 3:
+					in.Source.SDKVersion = string(m.b)
+				case 4:
+					in.Source.Node = string(m.b)
+				}
+				return nil
+			})
+		case 21:
+			in.InstanceID = string(f.b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
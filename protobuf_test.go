@@ -0,0 +1,55 @@
+package registry_center
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProtobufFetchData(t *testing.T) {
+	data := &FetchData{
+		LatestTimestamp: time.Now().UnixNano(),
+		Group:           "canary",
+		ShadowPercent:   5,
+		Total:           200,
+		NextCursor:      "aDE5OQ",
+		Hints:           &CacheHints{PollInterval: 30 * time.Second, DeltaSupported: true, Load: "normal"},
+	}
+	for i := 0; i < 200; i++ {
+		data.Instances = append(data.Instances, &Instance{
+			Env:             "prod",
+			AppId:           "com.xx.pb",
+			Hostname:        fmt.Sprintf("h%d", i),
+			Addrs:           []string{fmt.Sprintf("grpc://10.0.0.%d:9000", i)},
+			Endpoints:       []Endpoint{{Scheme: "grpc", Addr: fmt.Sprintf("10.0.0.%d:9000", i)}},
+			Version:         "v1.2.3",
+			Status:          StatusUP,
+			Weight:          DefaultWeight,
+			HealthScore:     100,
+			Metadata:        map[string]string{"zone": "sh", "cluster": "c1"},
+			Clock:           HLC{WallTime: int64(i) + 1, Logical: 2, Node: "n1"},
+			Source:          &RegistrationSource{Kind: "sdk", ClientIP: "10.0.0.1"},
+			RegTimestamp:    int64(i),
+			LatestTimestamp: int64(i) + 1,
+		})
+	}
+	data.Shadows = data.Instances[:1]
+
+	b := MarshalFetchData(data)
+	got, err := UnmarshalFetchData(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, data) {
+		t.Fatalf("round trip mismatch:\n%+v\n%+v", got, data)
+	}
+	js, _ := json.Marshal(data)
+	if len(b) >= len(js)/2 {
+		t.Fatalf("protobuf %d bytes, json %d bytes", len(b), len(js))
+	}
+	if _, err := UnmarshalFetchData(b[:len(b)-1]); err == nil {
+		t.Fatal("truncated message should fail")
+	}
+}
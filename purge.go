@@ -0,0 +1,204 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPurgeNotConfirmed 清除应用数据时确认信息不匹配
+var ErrPurgeNotConfirmed = errors.New("purge not confirmed")
+
+// EventPurge 应用数据被清除
+const EventPurge EventType = "purge"
+
+// AuditAppPurged 清除了应用的所有数据
+const AuditAppPurged = "app_purged"
+
+// Purge 清除应用的所有数据：实例、签发的证书和续约凭证、健康信号、灰度/实验/影子流量/限流配置、
+// 墓碑、地址占用、维护窗口、冻结及可见性配置、Fetch 缓存、审计记录以及事件日志中该应用的历史事件。
+// confirm 必须与 appid 相同以防误操作。清除后记录一条 purge 事件，变更订阅者据此删除下游数据，并复制给对端节点
+func (r *Registry) Purge(env, appid, confirm string) (int, error) {
+	return r.PurgeContext(context.Background(), env, appid, confirm)
+}
+
+// PurgeContext 同 Purge，ctx 中的调用方记入审计日志
+func (r *Registry) PurgeContext(ctx context.Context, env, appid, confirm string) (int, error) {
+	if confirm != appid {
+		return 0, ErrPurgeNotConfirmed
+	}
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := r.checkFrozen(env, appid); err != nil {
+		return 0, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityAdmin)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	n := r.purge(ctx, env, appid)
+	r.replicate(ctx, ReplicationOp{Action: ActionPurge, Env: env, AppId: appid})
+	return n, nil
+}
+
+// purge 清除应用的所有数据，返回删除的实例数
+func (r *Registry) purge(ctx context.Context, env, appid string) int {
+	key := getKey(appid, env)
+	r.lock.Lock()
+	app, ok := r.apps[key]
+	delete(r.apps, key)
+	r.lock.Unlock()
+
+	var instances []*Instance
+	if ok {
+		instances = app.GetAllInstances()
+	}
+	for _, in := range instances {
+		r.workloadCerts.remove(env, appid, in.Hostname)
+		if r.credentials != nil {
+			r.credentials.remove(env, appid, in.Hostname)
+		}
+	}
+	r.StopRollout(env, appid)
+	r.DeleteExperiment(env, appid)
+	r.SetShadowTargets(ShadowTargets{Env: env, AppId: appid})
+	r.SetQuota(AppQuota{Env: env, AppId: appid})
+	r.SetVisibility(AppVisibility{Env: env, AppId: appid})
+	r.fetchLimiter.purge(key)
+	r.health.purge(key)
+	r.capacity.purge(key)
+	r.tombstones.purge(env, appid)
+	r.addrs.purge(env, appid)
+	r.maintenance.purge(env, appid)
+	r.freezes.purge(key)
+	if r.overload != nil {
+		r.overload.purge(key)
+	}
+	r.events.purge(env, appid)
+	r.events.append(Event{Type: EventPurge, Env: env, AppId: appid, RequestID: RequestIDFromContext(ctx), Source: r.requestSource(ctx)})
+	r.auditLog.purge(env, appid)
+	caller, _ := CallerFromContext(ctx)
+	r.audit(ctx, AuditEntry{Action: AuditAppPurged, Caller: caller, Env: env, AppId: appid, Reason: fmt.Sprintf("%d instances purged", len(instances))})
+	return len(instances)
+}
+
+// purge 删除应用的所有限流配置
+func (l *fetchLimiter) purge(appKey AppKey) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for key := range l.limits {
+		if key.app == appKey {
+			delete(l.limits, key)
+		}
+	}
+	l.resetBuckets(appKey, "")
+}
+
+// purge 删除应用的 Fetch 缓存
+func (d *overloadDetector) purge(appKey AppKey) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for key := range d.cache {
+		if key.app == appKey {
+			delete(d.cache, key)
+		}
+	}
+}
+
+// purge 删除应用的历史事件，其余事件的序号不变
+func (l *eventLog) purge(env, appid string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	kept := 0
+	for i := 0; i < l.n; i++ {
+		if e := l.at(i); e.Env != env || e.AppId != appid {
+			*l.at(kept) = *e
+			kept++
+		}
+	}
+	for i := kept; i < l.n; i++ {
+		*l.at(i) = Event{}
+	}
+	l.n = kept
+}
+
+// purge 删除应用实例的墓碑，清除后重新注册的实例不受之前下线的影响
+func (t *tombstones) purge(env, appid string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, ts := range t.items {
+		if ts.Env == env && ts.AppId == appid {
+			delete(t.items, key)
+		}
+	}
+}
+
+// purge 释放应用实例占用的地址，删除与该应用有关的冲突记录
+func (x *addrIndex) purge(env, appid string) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	for addr, o := range x.owners {
+		if o.Env == env && o.AppId == appid {
+			delete(x.owners, addr)
+		}
+	}
+	kept := x.conflicts[:0]
+	for _, c := range x.conflicts {
+		if (c.Env != env || c.AppId != appid) && (c.Owner.Env != env || c.Owner.AppId != appid) {
+			kept = append(kept, c)
+		}
+	}
+	x.conflicts = kept
+}
+
+// purge 删除只针对该应用的维护窗口，其他窗口不再恢复该应用实例的权重
+func (c *maintenanceController) purge(env, appid string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for id, w := range c.windows {
+		if w.Selector.Env == env && w.Selector.AppId == appid {
+			delete(c.windows, id)
+			continue
+		}
+		for key := range w.weights {
+			if key.app == getKey(appid, env) {
+				delete(w.weights, key)
+			}
+		}
+		w.Drained = len(w.weights)
+	}
+}
+
+// purge 删除应用的冻结记录，Purge 只在应用未冻结时执行，这里删除的是已过期的记录
+func (c *freezeController) purge(key AppKey) {
+	c.lock.Lock()
+	delete(c.frozen, key)
+	c.lock.Unlock()
+}
+
+// purge 删除应用的审计记录，其余记录保持顺序
+func (a *auditLog) purge(env, appid string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	var entries []AuditEntry
+	if a.full {
+		entries = append(append(entries, a.entries[a.next:]...), a.entries[:a.next]...)
+	} else {
+		entries = append(entries, a.entries[:a.next]...)
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.Env != env || e.AppId != appid {
+			kept = append(kept, e)
+		}
+	}
+	n := copy(a.entries, kept)
+	for i := n; i < len(a.entries); i++ {
+		a.entries[i] = AuditEntry{}
+	}
+	a.full = n == len(a.entries)
+	a.next = n % len(a.entries)
+}
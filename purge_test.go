@@ -0,0 +1,72 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurge(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.old", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.keep", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	r.SetExperiment(Experiment{Env: "test", AppId: "com.xx.old", Name: "exp"})
+
+	if _, err := r.Purge("test", "com.xx.old", "yes"); err != ErrPurgeNotConfirmed {
+		t.Fatalf("expect not confirmed, got %v", err)
+	}
+	n, err := r.Purge("test", "com.xx.old", "com.xx.old")
+	if err != nil || n != 1 {
+		t.Fatalf("unexpected purge result %d %v", n, err)
+	}
+	if _, err := r.Fetch("test", "com.xx.old", StatusUP, 0); err == nil {
+		t.Fatal("purged app should not be found")
+	}
+	if _, ok := r.GetExperiment("test", "com.xx.old"); ok {
+		t.Fatal("experiment should be purged")
+	}
+	events, _ := r.Replay(0, 0)
+	if len(events) != 2 || events[0].AppId != "com.xx.keep" || events[1].Type != EventPurge {
+		t.Fatalf("unexpected events after purge: %+v", events)
+	}
+}
+
+func TestPurgeClearsAppStateAndReplicates(t *testing.T) {
+	standby := NewRegistry(WithStandby(FailoverConfig{}))
+	r := NewRegistry(WithReplicator(applyReplicator{peer: standby}))
+	r.SetUniquenessPolicy(UniqueAddrPerEnv)
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.old", Hostname: "a", Addrs: []string{"http://10.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.old", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	r.Cancel("test", "com.xx.old", "b", time.Now().UnixNano())
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.old", Visibility: VisibilityRestricted})
+	r.audit(context.Background(), AuditEntry{Action: AuditFetchDenied, Env: "test", AppId: "com.xx.old", Reason: "app is restricted"})
+	if _, err := r.ScheduleMaintenance(MaintenanceWindow{Selector: InstanceSelector{Env: "test", AppId: "com.xx.old"},
+		Start: time.Now().Add(time.Hour).UnixNano(), End: time.Now().Add(2 * time.Hour).UnixNano()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Purge("test", "com.xx.old", "com.xx.old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := standby.Fetch("test", "com.xx.old", StatusUP, 0); err == nil {
+		t.Fatal("purge should be replicated")
+	}
+	if v := r.Visibility("test", "com.xx.old"); v.Visibility != VisibilityPublic {
+		t.Fatalf("visibility should be reset, got %+v", v)
+	}
+	if ws := r.MaintenanceWindows(); len(ws) != 0 {
+		t.Fatalf("maintenance windows should be removed, got %+v", ws)
+	}
+	for _, e := range r.AuditLog() {
+		if e.AppId == "com.xx.old" && e.Action != AuditAppPurged {
+			t.Fatalf("audit entries of the app should be removed, got %+v", e)
+		}
+	}
+	// 墓碑及地址占用已清除，旧的注册请求和其他应用都可以使用
+	if _, err := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.old", Hostname: "b", Status: StatusUP}), 1); err != nil {
+		t.Fatalf("tombstone should be cleared, got %v", err)
+	}
+	if _, err := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.new", Hostname: "c", Addrs: []string{"http://10.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano()); err != nil {
+		t.Fatalf("address claim should be released, got %v", err)
+	}
+}
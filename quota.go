@@ -0,0 +1,206 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrQuotaExceeded 应用的实例数已达到配额，新实例的注册被拒绝
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// DefaultSoftQuotaRatio 用量达到硬上限的该比例时发出预警
+const DefaultSoftQuotaRatio = 0.8
+
+// 配额资源
+const (
+	QuotaInstances = "instances"  // 应用的实例数
+	QuotaFetchRate = "fetch_rate" // 调用方的 Fetch 限流，用量为令牌桶中已消耗的令牌数
+)
+
+// AppQuota 应用的配额
+type AppQuota struct {
+	Env          string `json:"env"`
+	AppId        string `json:"appId"`
+	MaxInstances int    `json:"max_instances"` // 实例数硬上限，已存在的实例重新注册不受限制
+}
+
+// QuotaUsage 应用配额及当前用量
+type QuotaUsage struct {
+	AppQuota
+	Instances int  `json:"instances"`
+	Warning   bool `json:"warning"` // 用量已达到软阈值
+}
+
+// QuotaWarning 用量达到软阈值的预警，注册尚未失败，调用方应尽快扩容配额或清理实例
+type QuotaWarning struct {
+	Env      string  `json:"env"`
+	AppId    string  `json:"appId"`
+	Resource string  `json:"resource"`
+	Consumer string  `json:"consumer,omitempty"` // Fetch 限流的调用方
+	Used     float64 `json:"used"`
+	Limit    float64 `json:"limit"`
+}
+
+func (w QuotaWarning) String() string {
+	s := fmt.Sprintf("%s %s/%s %s of %s", w.Resource, w.Env, w.AppId,
+		strconv.FormatFloat(w.Used, 'f', -1, 64), strconv.FormatFloat(w.Limit, 'f', -1, 64))
+	if w.Consumer != "" {
+		s += " consumer=" + w.Consumer
+	}
+	return s
+}
+
+// QuotaError 超出配额
+type QuotaError struct {
+	Quota AppQuota
+	Used  int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: %s has %d of %d instances", ErrQuotaExceeded, getKey(e.Quota.AppId, e.Quota.Env), e.Used, e.Quota.MaxInstances)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type quotaController struct {
+	lock      sync.RWMutex
+	quotas    map[AppKey]AppQuota
+	softRatio float64
+}
+
+func newQuotaController() *quotaController {
+	return &quotaController{quotas: make(map[AppKey]AppQuota), softRatio: DefaultSoftQuotaRatio}
+}
+
+// soft 用量是否达到软阈值
+func (c *quotaController) soft(used, limit float64) bool {
+	return limit > 0 && used >= limit*c.softRatio
+}
+
+// SetQuota 设置应用的配额，MaxInstances 为 0 时删除配额
+func (r *Registry) SetQuota(q AppQuota) error {
+	if q.Env == "" || q.AppId == "" || q.MaxInstances < 0 {
+		return errors.New("invalid quota")
+	}
+	c := r.quotas
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if q.MaxInstances == 0 {
+		delete(c.quotas, getKey(q.AppId, q.Env))
+		return nil
+	}
+	c.quotas[getKey(q.AppId, q.Env)] = q
+	return nil
+}
+
+// Quotas 返回所有配额及当前用量，按环境、应用排序
+func (r *Registry) Quotas() []QuotaUsage {
+	c := r.quotas
+	c.lock.RLock()
+	rs := make([]QuotaUsage, 0, len(c.quotas))
+	for _, q := range c.quotas {
+		rs = append(rs, QuotaUsage{AppQuota: q})
+	}
+	c.lock.RUnlock()
+	for i := range rs {
+		if app, ok := r.getApplication(rs[i].AppId, rs[i].Env); ok {
+			rs[i].Instances = app.instanceCount()
+		}
+		rs[i].Warning = c.soft(float64(rs[i].Instances), float64(rs[i].MaxInstances))
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Env != rs[j].Env {
+			return rs[i].Env < rs[j].Env
+		}
+		return rs[i].AppId < rs[j].AppId
+	})
+	return rs
+}
+
+func (app *Application) instanceCount() int {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return len(app.instances)
+}
+
+// checkQuota 新实例注册前检查实例数配额，达到软阈值时预警。
+// 检查与写入之间不加锁，并发注册时可能短暂超出硬上限
+func (r *Registry) checkQuota(ctx context.Context, in *Instance) error {
+	c := r.quotas
+	c.lock.RLock()
+	q, ok := c.quotas[in.Key()]
+	c.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+	var used int
+	if app, ok := r.getApplication(in.AppId, in.Env); ok {
+		app.lock.RLock()
+		_, exists := app.instances[in.Hostname]
+		used = len(app.instances)
+		app.lock.RUnlock()
+		if exists {
+			return nil
+		}
+	}
+	if used >= q.MaxInstances {
+		return &QuotaError{Quota: q, Used: used}
+	}
+	if c.soft(float64(used+1), float64(q.MaxInstances)) {
+		r.warnQuota(ctx, QuotaWarning{Env: in.Env, AppId: in.AppId, Resource: QuotaInstances, Used: float64(used + 1), Limit: float64(q.MaxInstances)})
+	}
+	return nil
+}
+
+// warnQuota 发出配额预警：放入 ctx 供 API 层写入响应头，并发送告警
+func (r *Registry) warnQuota(ctx context.Context, w QuotaWarning) {
+	if ws, ok := ctx.Value(quotaWarningsKey{}).(*quotaWarnings); ok {
+		ws.lock.Lock()
+		ws.list = append(ws.list, w)
+		ws.lock.Unlock()
+	}
+	labels := map[string]string{"env": w.Env, "appid": w.AppId, "resource": w.Resource}
+	if w.Consumer != "" {
+		labels["consumer"] = w.Consumer
+	}
+	r.raiseAlert(Alert{Name: AlertQuotaWarning, Summary: "quota warning: " + w.String(), Labels: labels})
+}
+
+type quotaWarningsKey struct{}
+
+type quotaWarnings struct {
+	lock sync.Mutex
+	list []QuotaWarning
+}
+
+// ContextWithQuotaWarnings 在 ctx 中收集本次请求触发的配额预警，API 层据此设置响应头
+func ContextWithQuotaWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quotaWarningsKey{}, &quotaWarnings{})
+}
+
+// QuotaWarningsFromContext 返回本次请求触发的配额预警
+func QuotaWarningsFromContext(ctx context.Context) []QuotaWarning {
+	ws, ok := ctx.Value(quotaWarningsKey{}).(*quotaWarnings)
+	if !ok {
+		return nil
+	}
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	return append([]QuotaWarning(nil), ws.list...)
+}
+
+// allowFetch 按应用+调用方限流，令牌桶的消耗达到软阈值时预警
+func (r *Registry) allowFetch(ctx context.Context, env, appid, consumer string) error {
+	consumer = fetchConsumer(ctx, consumer)
+	used, burst, err := r.fetchLimiter.allow(env, appid, consumer)
+	if err == nil && r.quotas.soft(used, burst) {
+		r.warnQuota(ctx, QuotaWarning{Env: env, AppId: appid, Resource: QuotaFetchRate, Consumer: consumer, Used: used, Limit: burst})
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInstanceQuota(t *testing.T) {
+	sink := make(chanSink, 10)
+	r := NewRegistry(WithAlerts(AlertConfig{Sinks: []AlertSink{sink}, MinInterval: time.Hour}), WithSoftQuotaRatio(0.5))
+	if err := r.SetQuota(AppQuota{Env: "test", AppId: "com.xx.pay", MaxInstances: 4}); err != nil {
+		t.Fatal(err)
+	}
+	register := func(ctx context.Context, host string) error {
+		_, err := r.RegisterContext(ctx, NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: host, Status: StatusUP}), time.Now().UnixNano())
+		return err
+	}
+	ctx := ContextWithQuotaWarnings(context.Background())
+	if err := register(ctx, "host-1"); err != nil {
+		t.Fatal(err)
+	}
+	if ws := QuotaWarningsFromContext(ctx); len(ws) != 0 {
+		t.Fatalf("unexpected warnings %v", ws)
+	}
+	ctx = ContextWithQuotaWarnings(context.Background())
+	if err := register(ctx, "host-2"); err != nil {
+		t.Fatal(err)
+	}
+	ws := QuotaWarningsFromContext(ctx)
+	if len(ws) != 1 || ws[0].Resource != QuotaInstances || ws[0].Used != 2 || ws[0].Limit != 4 {
+		t.Fatalf("expect soft warning at 2 of 4, got %v", ws)
+	}
+	select {
+	case a := <-sink:
+		if a.Name != AlertQuotaWarning || a.Labels["appid"] != "com.xx.pay" {
+			t.Fatalf("unexpected alert %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("alert not sent")
+	}
+	for i := 3; i <= 4; i++ {
+		if err := register(context.Background(), "host-"+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := register(context.Background(), "host-5")
+	var qe *QuotaError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &qe) || qe.Used != 4 {
+		t.Fatalf("expect quota exceeded, got %v", err)
+	}
+	// 已存在的实例重新注册不受配额限制
+	if err := register(context.Background(), "host-1"); err != nil {
+		t.Fatal(err)
+	}
+	qs := r.Quotas()
+	if len(qs) != 1 || qs[0].Instances != 4 || !qs[0].Warning {
+		t.Fatalf("unexpected quota usage %+v", qs)
+	}
+	if err := r.SetQuota(AppQuota{Env: "test", AppId: "com.xx.pay"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := register(context.Background(), "host-5"); err != nil {
+		t.Fatalf("quota removed, got %v", err)
+	}
+}
+
+func TestFetchRateQuotaWarning(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	if err := r.SetFetchRateLimit("test", "com.xx.pay", "batch-job", FetchRateLimit{Rate: 0.01, Burst: 5}); err != nil {
+		t.Fatal(err)
+	}
+	var warned int
+	for i := 1; i <= 5; i++ {
+		ctx := ContextWithQuotaWarnings(context.Background())
+		if _, err := r.FetchContext(ctx, "test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "batch-job"}); err != nil {
+			t.Fatal(err)
+		}
+		if ws := QuotaWarningsFromContext(ctx); len(ws) > 0 {
+			if ws[0].Resource != QuotaFetchRate || ws[0].Consumer != "batch-job" {
+				t.Fatalf("unexpected warning %v", ws)
+			}
+			warned++
+		}
+	}
+	// 用量 4、5 达到 80%
+	if warned != 2 {
+		t.Fatalf("expect 2 warnings, got %d", warned)
+	}
+}
@@ -0,0 +1,169 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 调用方超过了应用的 Fetch 限流
+var ErrRateLimited = errors.New("fetch rate limited")
+
+// fetchBucketIdle 令牌桶闲置超过该时长后回收，调用方再次请求时按配置重新创建
+const fetchBucketIdle = 10 * time.Minute
+
+// FetchRateLimit 调用方获取某个应用的限流配置，令牌桶算法
+type FetchRateLimit struct {
+	Rate  float64 `json:"rate"`  // 每秒允许的请求数
+	Burst int     `json:"burst"` // 允许的突发请求数
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	limit  FetchRateLimit
+	tokens float64
+	last   time.Time
+}
+
+// take 取一个令牌，失败时返回还需等待的时长
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.limit.Rate
+		if max := float64(b.limit.Burst); b.tokens > max {
+			b.tokens = max
+		}
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if b.limit.Rate <= 0 {
+		return false, time.Minute
+	}
+	return false, time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// fetchLimiter 按 应用+调用方 维度的 Fetch 限流，独立于全局限流
+type fetchLimiter struct {
+	limits  map[limitKey]FetchRateLimit // consumer 为空表示该应用的默认限流
+	buckets map[limitKey]*tokenBucket
+	swept   time.Time // 上次回收闲置令牌桶的时间
+	lock    sync.Mutex
+}
+
+type limitKey struct {
+	app      AppKey
+	consumer string
+}
+
+func newFetchLimiter() *fetchLimiter {
+	return &fetchLimiter{
+		limits:  make(map[limitKey]FetchRateLimit),
+		buckets: make(map[limitKey]*tokenBucket),
+	}
+}
+
+// SetFetchRateLimit 设置调用方 consumer 获取应用的限流，consumer 为空时对该应用所有未单独配置的调用方生效。
+// 经 API 层的请求按认证后的身份限流，consumer 为作用域令牌的 subject、客户端证书的 SPIFFE ID 或客户端 IP，见 fetchConsumer
+func (r *Registry) SetFetchRateLimit(env, appid, consumer string, limit FetchRateLimit) error {
+	if limit.Rate < 0 || limit.Burst < 1 {
+		return errors.New("invalid fetch rate limit")
+	}
+	l := r.fetchLimiter
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.limits[limitKey{getKey(appid, env), consumer}] = limit
+	l.resetBuckets(getKey(appid, env), consumer)
+	return nil
+}
+
+// RemoveFetchRateLimit 删除调用方 consumer 获取应用的限流
+func (r *Registry) RemoveFetchRateLimit(env, appid, consumer string) {
+	l := r.fetchLimiter
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.limits, limitKey{getKey(appid, env), consumer})
+	l.resetBuckets(getKey(appid, env), consumer)
+}
+
+// resetBuckets 配置变化后丢弃受影响的令牌桶，默认限流变化时影响该应用所有调用方
+func (l *fetchLimiter) resetBuckets(appKey AppKey, consumer string) {
+	if consumer != "" {
+		delete(l.buckets, limitKey{appKey, consumer})
+		return
+	}
+	for key := range l.buckets {
+		if key.app == appKey {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// allow 判断调用方本次获取是否被允许，被限流时返回背压响应。
+// 允许时返回令牌桶已消耗的令牌数及容量，没有限流时都为 0
+func (l *fetchLimiter) allow(env, appid, consumer string) (used, burst float64, err error) {
+	appKey := getKey(appid, env)
+	key := limitKey{appKey, consumer}
+	now := time.Now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.limits) == 0 {
+		return 0, 0, nil
+	}
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		limit, ok := l.limits[key]
+		if !ok {
+			if limit, ok = l.limits[limitKey{app: appKey}]; !ok {
+				return 0, 0, nil
+			}
+		}
+		b = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	if ok, wait := b.take(now); !ok {
+		bp := &BackpressureError{Err: ErrRateLimited, RetryAfter: wait}
+		// 建议调用方按限流速率轮询
+		if b.limit.Rate > 0 {
+			bp.PollInterval = time.Duration(float64(time.Second) / b.limit.Rate)
+		}
+		return 0, 0, bp
+	}
+	// 部分恢复的令牌按已消耗计
+	burst = float64(b.limit.Burst)
+	return math.Ceil(burst - b.tokens), burst, nil
+}
+
+// sweep 回收闲置的令牌桶，避免按客户端 IP 等身份创建的令牌桶无限增长，每个闲置周期最多扫描一次
+func (l *fetchLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < fetchBucketIdle {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fetchBucketIdle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// fetchConsumer 限流使用的调用方身份。经 API 层的请求自报的 consumer 无法校验，
+// 依次使用作用域令牌的 subject、客户端证书的 SPIFFE ID、客户端 IP；进程内调用使用 declared
+func fetchConsumer(ctx context.Context, declared string) string {
+	if t, ok := TokenFromContext(ctx); ok {
+		return t.Subject
+	}
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return declared
+	}
+	if caller != "" {
+		return caller
+	}
+	src, _ := SourceFromContext(ctx)
+	return src.ClientIP
+}
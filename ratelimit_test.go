@@ -0,0 +1,66 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFetchRateLimit(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	if err := r.SetFetchRateLimit("test", "com.xx.pay", "batch-job", FetchRateLimit{Rate: 0.01, Burst: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := r.FetchWithOptions("test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "batch-job"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := r.FetchWithOptions("test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "batch-job"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expect rate limited, got %v", err)
+	}
+	bp, ok := AsBackpressure(err)
+	if !ok || bp.RetryAfter <= 0 || bp.PollInterval != 100*time.Second {
+		t.Fatalf("unexpected backpressure response: %+v", bp)
+	}
+	if got := StretchInterval(time.Second, time.Second, 30*time.Second, err); got != 30*time.Second {
+		t.Fatalf("interval should be stretched to max, got %s", got)
+	}
+	// 其他调用方不受影响
+	if _, err := r.FetchWithOptions("test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "gateway"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFetchRateLimitUsesAuthenticatedCaller(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.pay", Hostname: "webapi", Status: StatusUP}), time.Now().UnixNano())
+	if err := r.SetFetchRateLimit("test", "com.xx.pay", "", FetchRateLimit{Rate: 0.01, Burst: 1}); err != nil {
+		t.Fatal(err)
+	}
+	anon := ContextWithSource(ContextWithCaller(context.Background(), ""), RegistrationSource{Kind: SourceDirect, ClientIP: "192.0.2.1"})
+	if _, err := r.FetchContext(anon, "test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// 换一个自报的 consumer 不能绕过限流
+	if _, err := r.FetchContext(anon, "test", "com.xx.pay", StatusUP, 0, FetchOptions{Consumer: "b"}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("self-declared consumer must not get a new bucket, got %v", err)
+	}
+	peer := ContextWithSource(ContextWithCaller(context.Background(), "spiffe://example.org/gateway"), RegistrationSource{Kind: SourceDirect, ClientIP: "192.0.2.1"})
+	if _, err := r.FetchContext(peer, "test", "com.xx.pay", StatusUP, 0, FetchOptions{}); err != nil {
+		t.Fatalf("authenticated caller has its own bucket, got %v", err)
+	}
+
+	// 闲置的令牌桶被回收
+	l := r.fetchLimiter
+	l.lock.Lock()
+	l.sweep(time.Now().Add(2 * fetchBucketIdle))
+	n := len(l.buckets)
+	l.lock.Unlock()
+	if n != 0 {
+		t.Fatalf("idle buckets should be evicted, %d left", n)
+	}
+}
@@ -0,0 +1,125 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPeerNotFound 对端节点不在集群节点列表中
+var ErrPeerNotFound = errors.New("peer not found")
+
+// SnapshotFetcher 获取对端节点的全量快照，用于对账
+type SnapshotFetcher interface {
+	FetchSnapshot(ctx context.Context, peer Node) (*Snapshot, error)
+}
+
+// ReconcileReport 本节点与对端节点的对账结果，只读，不修改任何一方的数据
+type ReconcileReport struct {
+	Peer           string         `json:"peer"`
+	LocalTimestamp int64          `json:"local_timestamp"` // 本节点快照时间
+	PeerTimestamp  int64          `json:"peer_timestamp"`  // 对端快照时间
+	LocalSeq       uint64         `json:"local_seq"`
+	PeerSeq        uint64         `json:"peer_seq"`
+	LocalInstances int            `json:"local_instances"`
+	PeerInstances  int            `json:"peer_instances"`
+	Consistent     bool           `json:"consistent"` // 两边实例完全一致
+	Apps           []AppReconcile `json:"apps,omitempty"`
+}
+
+// AppReconcile 单个应用在两个节点间的差异
+type AppReconcile struct {
+	Env       string             `json:"env"`
+	AppId     string             `json:"appId"`
+	OnlyLocal []*Instance        `json:"only_local,omitempty"` // 只在本节点存在的实例
+	OnlyPeer  []*Instance        `json:"only_peer,omitempty"`  // 只在对端存在的实例
+	Differing []InstanceMismatch `json:"differing,omitempty"`
+}
+
+// InstanceMismatch 两个节点上同一实例不一致的字段
+type InstanceMismatch struct {
+	Hostname string    `json:"hostname"`
+	Fields   []string  `json:"fields"`
+	Local    *Instance `json:"local"`
+	Peer     *Instance `json:"peer"`
+}
+
+// Reconcile 对比本节点与对端的快照。除业务字段外还比较复制时应当一致的
+// dirty_timestamp 和 clock，续约时间各节点独立更新，不做比较
+func Reconcile(local, peer *Snapshot) *ReconcileReport {
+	rp := &ReconcileReport{
+		LocalTimestamp: local.Timestamp,
+		PeerTimestamp:  peer.Timestamp,
+		LocalSeq:       local.Seq,
+		PeerSeq:        peer.Seq,
+		LocalInstances: countInstances(local),
+		PeerInstances:  countInstances(peer),
+	}
+	for _, d := range diffSnapshots(local, peer, reconcileFields) {
+		app := AppReconcile{Env: d.Env, AppId: d.AppId, OnlyLocal: d.Removed, OnlyPeer: d.Added}
+		for _, c := range d.Changed {
+			app.Differing = append(app.Differing, InstanceMismatch{Hostname: c.Hostname, Fields: c.Fields, Local: c.Old, Peer: c.New})
+		}
+		rp.Apps = append(rp.Apps, app)
+	}
+	rp.Consistent = len(rp.Apps) == 0
+	return rp
+}
+
+func reconcileFields(a, b *Instance) []string {
+	fields := changedFields(a, b)
+	if a.DirtyTimestamp != b.DirtyTimestamp {
+		fields = append(fields, "dirty_timestamp")
+	}
+	if a.Clock != b.Clock {
+		fields = append(fields, "clock")
+	}
+	return fields
+}
+
+func countInstances(s *Snapshot) int {
+	var n int
+	for _, app := range s.Apps {
+		n += len(app.Instances)
+	}
+	return n
+}
+
+// ReconcileWith 获取对端节点的快照并与本节点对账，用于维护前确认集群数据一致。
+// peer 为节点 ID 或地址，需开启 WithSnapshotFetcher。两边都只比较对 ctx 中调用方可见的应用，
+// 对端快照需以管理员身份获取，否则受限应用会被误报为本节点独有
+func (r *Registry) ReconcileWith(ctx context.Context, peer string) (*ReconcileReport, error) {
+	if r.snapshotFetcher == nil {
+		return nil, errors.New("reconcile requires a snapshot fetcher")
+	}
+	var node *Node
+	for _, n := range r.Nodes()[1:] {
+		if n.ID == peer || n.Addr == peer {
+			node = &n
+			break
+		}
+	}
+	if node == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPeerNotFound, peer)
+	}
+	release, err := r.AdmitContext(ctx, PriorityAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	ps, err := r.snapshotFetcher.FetchSnapshot(ctx, *node)
+	r.nodes.observe(node.ID, err)
+	if err != nil {
+		return nil, fmt.Errorf("fetch snapshot from %s: %w", node.ID, err)
+	}
+	visible := ps.Apps[:0:0]
+	for _, app := range ps.Apps {
+		if r.Visible(ctx, app.Env, app.AppId) {
+			visible = append(visible, app)
+		}
+	}
+	ps.Apps = visible
+	rp := Reconcile(r.SnapshotContext(ctx), ps)
+	rp.Peer = node.ID
+	return rp, nil
+}
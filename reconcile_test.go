@@ -0,0 +1,88 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type snapshotFunc func(ctx context.Context, peer Node) (*Snapshot, error)
+
+func (f snapshotFunc) FetchSnapshot(ctx context.Context, peer Node) (*Snapshot, error) {
+	return f(ctx, peer)
+}
+
+func TestReconcileWith(t *testing.T) {
+	ctx := context.Background()
+	peer := NewRegistry()
+	r := NewRegistry(WithSnapshotFetcher(snapshotFunc(func(ctx context.Context, n Node) (*Snapshot, error) {
+		if n.ID != "n2" {
+			return nil, errors.New("unexpected peer " + n.ID)
+		}
+		return peer.Snapshot(), nil
+	})))
+	r.SetPeers([]Node{{ID: "n2", Addr: "http://10.0.0.2:7171"}})
+
+	register := func(hostname string, status uint32) *Instance {
+		app, err := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.rec", Hostname: hostname, Status: status}), time.Now().UnixNano())
+		if err != nil {
+			t.Fatal(err)
+		}
+		app.lock.RLock()
+		defer app.lock.RUnlock()
+		return copyInstance(app.instances[hostname])
+	}
+	for _, in := range []*Instance{register("a", StatusUP), register("b", StatusUP)} {
+		peer.ApplyReplication(ReplicationOp{Action: ActionRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, LatestTimestamp: in.LatestTimestamp})
+	}
+	rp, err := r.ReconcileWith(ctx, "n2")
+	if err != nil || !rp.Consistent || rp.Peer != "n2" || rp.LocalInstances != 2 || rp.PeerInstances != 2 {
+		t.Fatalf("expect consistent report, got %+v %v", rp, err)
+	}
+
+	register("b", StatusDown)
+	register("c", StatusUP)
+	peer.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.rec", Hostname: "d", Status: StatusUP}), time.Now().UnixNano())
+	rp, err = r.ReconcileWith(ctx, "http://10.0.0.2:7171")
+	if err != nil || rp.Consistent || len(rp.Apps) != 1 {
+		t.Fatalf("unexpected report %+v %v", rp, err)
+	}
+	app := rp.Apps[0]
+	if len(app.OnlyLocal) != 1 || app.OnlyLocal[0].Hostname != "c" || len(app.OnlyPeer) != 1 || app.OnlyPeer[0].Hostname != "d" {
+		t.Fatalf("unexpected only local/peer %+v", app)
+	}
+	if len(app.Differing) != 1 || app.Differing[0].Hostname != "b" || app.Differing[0].Fields[0] != "status" {
+		t.Fatalf("unexpected differing %+v", app.Differing)
+	}
+	// 对账不修改任何一方
+	if got := len(peer.Snapshot().Apps[0].Instances); got != 3 {
+		t.Fatalf("peer should be untouched, got %d instances", got)
+	}
+
+	// 受限应用不出现在对其不可见的调用方的报告中
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.secret", Hostname: "s", Status: StatusUP}), time.Now().UnixNano())
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.secret", Visibility: VisibilityRestricted})
+	secret := func(ctx context.Context) bool {
+		rp, err := r.ReconcileWith(ctx, "n2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, app := range rp.Apps {
+			if app.AppId == "com.xx.secret" {
+				return true
+			}
+		}
+		return false
+	}
+	if secret(ContextWithCaller(ctx, "")) {
+		t.Fatal("restricted app leaked to an anonymous caller")
+	}
+	if !secret(ContextWithAdmin(ContextWithCaller(ctx, ""))) {
+		t.Fatal("admin should see the restricted app")
+	}
+
+	if _, err := r.ReconcileWith(ctx, "n3"); !errors.Is(err, ErrPeerNotFound) {
+		t.Fatalf("expect peer not found, got %v", err)
+	}
+}
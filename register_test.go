@@ -1,6 +1,8 @@
 package registry_center
 
 import (
+	"errors"
+	"math"
 	"testing"
 	"time"
 )
@@ -24,12 +26,50 @@ func TestRegister(t *testing.T) {
 	app, _ := r.Register(instance, req.LatestTimestamp)
 	t.Log(app)
 	// test Fetch
-	instances, _ := r.Fetch("test", "com.xx.testapp", 1, 0)
-	t.Log(instances)
+	c, _ := r.Fetch("test", "com.xx.testapp", 1, 0)
+	t.Log(c)
 	// test cancle
 	in, _ := r.Cancel("test", "com.xx.testapp", "webapi", time.Now().Unix())
 	t.Log(in)
 	// test fetch
-	instances2, _ := r.Fetch("test", "com.xx.testapp", 1, 0)
-	t.Log(instances2)
+	c2, _ := r.Fetch("test", "com.xx.testapp", 1, 0)
+	t.Log(c2)
+}
+
+func TestLatestTimestampMonotonic(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP}), 100)
+	// 乱序到达的旧请求
+	app, _ := r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Status: StatusUP}), 50)
+	if app.TimestampConflicts() != 1 {
+		t.Fatalf("expect one conflict, got %d", app.TimestampConflicts())
+	}
+	c, err := r.Fetch("test", "com.xx.app", StatusUP, 100)
+	if err != nil || c.LatestTimestamp != 101 || len(c.Instances) != 2 {
+		t.Fatalf("out-of-order write should still advance the version: %+v %v", c, err)
+	}
+}
+
+func TestCancelTimestampBounds(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ts", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.ts", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	if _, err := r.Cancel("test", "com.xx.ts", "a", time.Now().Add(time.Hour).UnixNano()); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("far-future timestamp should be rejected, got %v", err)
+	}
+	before := time.Now().UnixNano()
+	if _, err := r.Cancel("test", "com.xx.ts", "a", 0); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := r.getApplication("com.xx.ts", "test")
+	if app.latestTimestamp < before {
+		t.Fatalf("missing timestamp should use the server clock, got %d", app.latestTimestamp)
+	}
+
+	// 版本已到上限时不再加一溢出
+	app.latestTimestamp = math.MaxInt64
+	app.upLatestTimestamp(1)
+	if app.latestTimestamp != math.MaxInt64 {
+		t.Fatalf("latest timestamp overflowed: %d", app.latestTimestamp)
+	}
 }
@@ -1,17 +1,69 @@
 package registry_center
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"math"
 	"sync"
 	"time"
 )
 
+var (
+	ErrAppNotFound = errors.New("app not found")
+	ErrNotModified = errors.New("latest timestamp is not latest") // 调用方持有的数据已是最新
+	ErrNoInstances = errors.New("not exist condition instance")   // 没有满足条件的实例
+)
+
 type Registry struct {
-	apps map[string]*Application // key: (appId+env) 应用服务唯一标识
-	lock sync.RWMutex
+	apps        map[AppKey]*Application // 应用服务唯一标识
+	lock        sync.RWMutex
+	rollouts    *rolloutController    // 版本灰度发布控制器
+	experiments *experimentController // A/B 实验分组
+	shadows     *shadowController     // 影子流量目标
+	visibility  *visibilityController // 应用可见性
+
+	requireSpiffeVerification bool // 携带 SpiffeID 的注册必须提供匹配的客户端证书
+
+	ca              *CertificateAuthority // 为服务实例签发证书的 CA，为空时不签发
+	workloadCerts   *workloadCerts        // 已签发的服务实例证书
+	certBindings    []CertificateBinding  // 可以为未声明 SpiffeID 的实例申请证书的调用方
+	credentials     *heartbeatCredentials // 续约凭证，为空时续约不校验凭证
+	fetchLimiter    *fetchLimiter         // 按 应用+调用方 的 Fetch 限流
+	admission       *admission            // 按优先级排队的准入控制，为空时不限制
+	overload        *overloadDetector     // 过载检测，为空时不检测
+	events          *eventLog             // 注册表变更事件日志
+	failover        *failover             // 主备角色
+	replicator      Replicator            // 写操作复制，为空时不复制
+	snapshotFetcher SnapshotFetcher       // 获取对端快照用于对账，为空时不支持对账
+	tasks           *taskManager          // 后台周期任务
+	metrics         *metrics              // 请求及复制指标
+	tracer          Tracer                // 分布式追踪，为空时只传播 trace 上下文
+	health          *healthController     // 实例健康分
+
+	evictionStrategy EvictionStrategy       // 过期实例剔除策略
+	clock            *hlcClock              // 混合逻辑时钟
+	sessions         *sessionManager        // 长连接会话
+	alerter          *alerter               // 告警，为空时不告警
+	tombstones       *tombstones            // 已下线实例的墓碑
+	appRetention     time.Duration          // 空应用的保留时长，0 表示最后一个实例下线时立即删除
+	pollHints        *pollHints             // 下发给客户端的轮询间隔
+	nodes            *nodeSet               // 集群节点
+	capacity         *capacityTracker       // 实例上报的容量
+	addrs            *addrIndex             // 地址唯一性约束
+	maintenance      *maintenanceController // 维护窗口
+	storage          *storageMonitor        // 外部存储的可用状态
+	peerAuth         PeerAuthConfig         // 复制请求的对端校验
+	auditLog         *auditLog              // 审计记录
+	warm             *warmCache             // 热点应用的 Fetch 缓存，为空时不预热
+	tokens           *tokenIssuer           // 只读令牌
+	activity         *activityTracker       // 最近的续约、注册、下线次数
+	keys             KeyEncoder             // 应用标识的字符串编码
+	quotas           *quotaController       // 应用配额
+	zones            *zoneDetector          // 可用区故障检测，未开启时为空
+	freezes          *freezeController      // 冻结的应用
+	schema           *schemaFlag            // API 默认输出的实例结构
 }
 
 type Application struct {
@@ -19,15 +71,36 @@ type Application struct {
 	instances       map[string]*Instance // 记录服务实例instance信息，key为实例hostname（服务实例唯一标识）, value为实例结构类型
 	latestTimestamp int64                // 记录更新时间
 	lock            sync.RWMutex
+
+	timestampConflicts uint64         // 乱序请求试图让 latestTimestamp 回退的次数
+	env                string         // 应用所在环境
+	statusCounts       map[uint32]int // 各状态的实例数，随实例增删维护
+	emptySince         int64          // 最后一个实例下线的时间，有实例时为 0
 }
 
 type Instance struct {
-	Env      string   `json:"env"`      // Env 服务环境标识，如 online、dev、test
-	AppId    string   `json:"appId"`    // AppId 应用服务唯一标识
-	Hostname string   `json:"hostname"` // 服务实例唯一标识
-	Addrs    []string `json:"addrs"`    // 服务实例地址,可以是http或rpc地址
-	Version  string   `json:"version"`  // 服务实例版本
-	Status   uint32   `json:"status"`   // 服务实例状态
+	Env       string     `json:"env"`       // Env 服务环境标识，如 online、dev、test
+	AppId     string     `json:"appId"`     // AppId 应用服务唯一标识
+	Hostname  string     `json:"hostname"`  // 服务实例唯一标识
+	Addrs     []string   `json:"addrs"`     // 服务实例地址,可以是http或rpc地址
+	Endpoints []Endpoint `json:"endpoints"` // 结构化的服务实例地址，由 Addrs 解析得到
+	Version   string     `json:"version"`   // 服务实例版本
+	Status    uint32     `json:"status"`    // 服务实例状态
+	Weight    uint32     `json:"weight"`    // 服务实例流量权重
+	Group     string     `json:"group"`     // 服务实例所属实验分组，空表示对照组
+
+	HealthScore int `json:"health_score"` // 健康分 0-100，Fetch 时计算
+
+	InstanceID string `json:"instance_id,omitempty"` // v2 结构的实例 ID，为空时使用 env/appid/hostname
+
+	Metadata map[string]string `json:"metadata,omitempty"` // 服务实例元数据
+
+	SpiffeID       string `json:"spiffe_id,omitempty"` // 服务实例的 SPIFFE 身份标识
+	SpiffeVerified bool   `json:"spiffe_verified"`     // SpiffeID 是否已通过 mTLS 客户端证书校验
+
+	Clock HLC `json:"clock"` // 最后一次注册的混合逻辑时钟，复制冲突时时钟大的一方胜出
+
+	Source *RegistrationSource `json:"source,omitempty"` // 最后一次注册的来源，复制时保留原始来源
 
 	RegTimestamp    int64 `json:"reg_timestamp"`    // 注册时间
 	UpTimestamp     int64 `json:"up_timestamp"`     // 更新时间
@@ -36,29 +109,95 @@ type Instance struct {
 	LatestTimestamp int64 `json:"latest_timestamp"` // 最后更新时间
 }
 
-func NewRegistry() *Registry {
+// 服务实例状态，Fetch 时按位匹配
+const (
+	StatusUP   uint32 = 1 // 可用
+	StatusDown uint32 = 2 // 不可用
+)
+
+// DefaultWeight 注册时未指定权重的默认值
+const DefaultWeight uint32 = 100
+
+func NewRegistry(opts ...Option) *Registry {
 	registry := &Registry{
-		apps: make(map[string]*Application),
-	}
-	// 启动goroutine 检查并剔除没有续约的服务实例
-	go registry.evictTask()
-	return registry
-}
+		apps:        make(map[AppKey]*Application),
+		rollouts:    newRolloutController(),
+		experiments: newExperimentController(),
+		shadows:     newShadowController(),
+		visibility:  newVisibilityController(),
+		health:      newHealthController(),
 
-func (r *Registry) evictTask() {
-	tick := time.NewTicker(time.Second * 60)
-	for {
-		select {
-		case <-tick.C:
-			r.evict()
-		}
+		workloadCerts: newWorkloadCerts(),
+		metrics:       newMetrics(),
+		fetchLimiter:  newFetchLimiter(),
+		events:        newEventLog(DefaultEventLogCapacity),
+		failover:      newFailover(),
+		tasks:         newTaskManager(),
+
+		evictionStrategy: HealthAwareEviction{},
+		clock:            newHLCClock(""),
+		sessions:         newSessionManager(),
+		tombstones:       newTombstones(),
+		pollHints:        newPollHints(),
+		nodes:            newNodeSet(),
+		capacity:         newCapacityTracker(),
+		addrs:            newAddrIndex(),
+		maintenance:      newMaintenanceController(),
+		storage:          newStorageMonitor(),
+		auditLog:         newAuditLog(DefaultAuditCapacity),
+		tokens:           newTokenIssuer(nil),
+		activity:         newActivityTracker(DefaultHeatmapBucket, DefaultHeatmapWindow),
+		keys:             DefaultKeyEncoder,
+		quotas:           newQuotaController(),
+		freezes:          newFreezeController(),
+		schema:           &schemaFlag{schema: InstanceSchemaV1},
+	}
+	for _, opt := range opts {
+		opt(registry)
+	}
+	// 检查并剔除没有续约的服务实例
+	registry.AddTask(TaskEvict, 60*time.Second, func(ctx context.Context) error {
+		registry.evict()
+		return nil
+	})
+	// 检查灰度发布中目标版本的健康状况
+	registry.AddTask(TaskRollout, 10*time.Second, func(ctx context.Context) error {
+		registry.checkRollouts()
+		return nil
+	})
+	// 为长连接会话内的实例续约
+	registry.AddTask(TaskSessions, 30*time.Second, registry.renewSessions)
+	// 清理过期的墓碑
+	registry.AddTask(TaskTombstones, time.Minute, registry.tombstones.expire)
+	// 维护窗口开始时摘流量，结束时恢复
+	registry.AddTask(TaskMaintenance, 10*time.Second, registry.checkMaintenance)
+	// 删除超过保留时长的空应用
+	if registry.appRetention > 0 {
+		registry.AddTask(TaskAppGC, time.Minute, registry.collectEmptyApps)
+	}
+	// 备节点检查主节点健康状况
+	if cfg := registry.failover.cfg; cfg.CheckActive != nil {
+		registry.AddTask(TaskFailover, cfg.Interval, registry.checkActive)
 	}
+	// 检测应用可用区整体故障
+	if registry.zones != nil {
+		registry.AddTask(TaskZoneOutage, registry.zones.cfg.Interval, registry.detectZoneOutages)
+	}
+	// 预热热点应用的 Fetch 缓存
+	if registry.warm != nil {
+		registry.startWarmCache()
+	}
+	return registry
 }
 
 // 遍历注册表的所有 apps，然后再遍历其中的 instances，如果当前时间减去实例上一次续约时间
 // instance.RenewTimestamp 达到阈值（默认 90 秒），那么将其加入过期队列中。这里并没有直接将过期队列所有实例都取消，
-// 考虑 GC 以及 本地时间漂移的因素，设定了一个剔除的上限 evictionLimit，随机剔除一些过期实例。
+// 考虑 GC 以及 本地时间漂移的因素，设定了一个剔除的上限 evictionLimit，由剔除策略选出本轮要剔除的过期实例。
 func (r *Registry) evict() {
+	// 备节点的实例由主节点剔除后复制过来
+	if r.Role() != RoleActive {
+		return
+	}
 	now := time.Now().UnixNano()
 	var expiredInstances []*Instance
 	apps := r.getAllApplications()
@@ -67,6 +206,10 @@ func (r *Registry) evict() {
 	for _, app := range apps {
 		allInstances := app.GetAllInstances()
 		registryLen += len(allInstances)
+		// 冻结的应用不剔除
+		if r.checkFrozen(app.env, app.appId) != nil {
+			continue
+		}
 		for _, instance := range allInstances {
 			if now-instance.RenewTimestamp > int64(90*time.Second) {
 				expiredInstances = append(expiredInstances, instance)
@@ -75,19 +218,48 @@ func (r *Registry) evict() {
 	}
 	// 剔除上限数量
 	evictionLimit := registryLen - int(float64(registryLen)*0.85)
-	expiredLen := len(expiredInstances)
-	if expiredLen > evictionLimit {
-		expiredLen = evictionLimit
-	}
-
-	if expiredLen == 0 {
+	if len(expiredInstances) == 0 || evictionLimit == 0 {
 		return
 	}
-	for i := 0; i < expiredLen; i++ {
-		j := i + rand.Intn(len(expiredInstances)-i)
-		expiredInstances[i], expiredInstances[j] = expiredInstances[j], expiredInstances[i]
-		expiredInstance := expiredInstances[i]
-		r.Cancel(expiredInstance.Env, expiredInstance.AppId, expiredInstance.Hostname, now)
+	candidates := make([]EvictionCandidate, len(expiredInstances))
+	for i, in := range expiredInstances {
+		s, _ := r.health.signalScore(in)
+		candidates[i] = EvictionCandidate{Instance: in, SignalScore: int(s*MaxHealthScore + 0.5)}
+	}
+	selected := r.evictionStrategy.Select(candidates, EvictionStats{
+		Now:     now,
+		Total:   registryLen,
+		Expired: len(expiredInstances),
+		Limit:   evictionLimit,
+	})
+	if len(selected) > evictionLimit {
+		selected = selected[:evictionLimit]
+	}
+	// 维护窗口内的实例过期是预期的，不计入告警
+	if unexpected := r.outsideMaintenance(expiredInstances); unexpected > evictionLimit {
+		r.raiseAlert(Alert{
+			Name:    AlertSelfPreservation,
+			Summary: fmt.Sprintf("%d of %d instances expired, only %d evicted", len(expiredInstances), registryLen, evictionLimit),
+		})
+	}
+	if r.alerter != nil && r.alerter.cfg.MassEviction > 0 && len(selected) >= r.alerter.cfg.MassEviction {
+		evicted := make([]*Instance, len(selected))
+		for i, c := range selected {
+			evicted[i] = c.Instance
+		}
+		if n := r.outsideMaintenance(evicted); n >= r.alerter.cfg.MassEviction {
+			r.raiseAlert(Alert{
+				Name:    AlertMassEviction,
+				Summary: fmt.Sprintf("%d instances evicted in one round", n),
+			})
+		}
+	}
+	for _, c := range selected {
+		expiredInstance := c.Instance
+		clock := r.clock.now()
+		if _, err := r.cancel(context.Background(), expiredInstance.Env, expiredInstance.AppId, expiredInstance.Hostname, now, clock, EventEvict); err == nil {
+			r.replicate(context.Background(), ReplicationOp{Action: ActionCancel, Env: expiredInstance.Env, AppId: expiredInstance.AppId, Hostname: expiredInstance.Hostname, LatestTimestamp: now, Clock: clock})
+		}
 	}
 }
 
@@ -103,66 +275,335 @@ func (r *Registry) getAllApplications() []*Application {
 
 // Register 服务注册
 func (r *Registry) Register(instance *Instance, latestTimestamp int64) (*Application, error) {
-	key := getKey(instance.AppId, instance.Env)
+	return r.RegisterContext(context.Background(), instance, latestTimestamp)
+}
+
+// RegisterContext 服务注册，ctx 中没有请求 ID 时自动生成，请求 ID 会记入日志、事件及复制数据
+func (r *Registry) RegisterContext(ctx context.Context, instance *Instance, latestTimestamp int64) (app *Application, err error) {
+	defer r.observeRequest(EndpointRegister, time.Now(), &err)
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if err := r.checkFrozen(instance.Env, instance.AppId); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityRegister)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageStore); err != nil {
+		return nil, err
+	}
+	app, in, err := r.register(ctx, instance, latestTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	r.replicate(ctx, ReplicationOp{Action: ActionRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, LatestTimestamp: latestTimestamp})
+	return app, nil
+}
+
+func (r *Registry) register(ctx context.Context, instance *Instance, latestTimestamp int64) (*Application, *Instance, error) {
+	app, in, isNew, err := r.store(ctx, instance, latestTimestamp)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 注册即签发新的续约凭证
+	if r.credentials != nil {
+		r.credentials.issue(in.Env, in.AppId, in.Hostname)
+	}
+	// 签发工作负载证书
+	r.issueCertificate(ctx, in, isNew)
+	return app, in, nil
+}
+
+// store 把实例写入注册表并记录事件，不签发凭证和证书
+func (r *Registry) store(ctx context.Context, instance *Instance, latestTimestamp int64) (*Application, *Instance, bool, error) {
+	// 本地请求分配新的时钟，复制或快照带来的时钟用于推进本地时钟
+	if instance.Clock.IsZero() {
+		instance.Clock = r.clock.now()
+	} else {
+		r.clock.update(instance.Clock)
+	}
+	src := r.requestSource(ctx)
+	if instance.Source == nil {
+		instance.Source = src
+	}
+	// 拒绝早于最近一次下线的注册，防止已下线的实例被延迟的请求复活
+	if err := r.tombstones.check(instance); err != nil {
+		logf(ctx, "reject stale register of %s/%s: %v", instance.Key(), instance.Hostname, err)
+		return nil, nil, false, err
+	}
+	// 对端复制的实例已在对端通过配额检查
+	if src.Kind != SourceReplication {
+		if err := r.checkQuota(ctx, instance); err != nil {
+			logf(ctx, "reject register of %s/%s: %v", instance.Key(), instance.Hostname, err)
+			return nil, nil, false, err
+		}
+	}
+	if err := r.claimAddrs(instance, src.Kind == SourceReplication); err != nil {
+		logf(ctx, "reject register of %s/%s: %v", instance.Key(), instance.Hostname, err)
+		r.raiseAlert(Alert{
+			Name:    AlertAddrConflict,
+			Summary: err.Error(),
+			Labels:  map[string]string{"env": instance.Env, "appid": instance.AppId, "hostname": instance.Hostname},
+		})
+		return nil, nil, false, err
+	}
+	key := instance.Key()
 	r.lock.RLock()
 	app, ok := r.apps[key]
 	r.lock.RUnlock()
 	if !ok { // new app
 		app = NewApplication(instance.AppId)
+		app.env = instance.Env
 	}
 	// add instance
-	_, isNew := app.AddInstance(instance, latestTimestamp)
-	if isNew {
-		// todo
-	}
-	// add into registry apps
+	in, isNew := app.AddInstance(instance, latestTimestamp)
+	// add into registry apps，事件发布前应用已可见
 	r.lock.Lock()
 	r.apps[key] = app
 	r.lock.Unlock()
-	return app, nil
+	r.events.append(Event{Type: EventRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, Created: isNew, RequestID: RequestIDFromContext(ctx), Source: src})
+	r.activity.record(in.Env, in.AppId, activityRegister)
+	return app, in, isNew, nil
+}
+
+// Fetch 服务获取，返回的 LatestTimestamp 用于下次增量拉取
+func (r *Registry) Fetch(env, appid string, status uint32, latestTimestamp int64) (*FetchData, error) {
+	return r.FetchWithOptions(env, appid, status, latestTimestamp, FetchOptions{})
+}
+
+// FetchOptions 服务获取的可选条件
+type FetchOptions struct {
+	ClientKey  string // 调用方标识（如用户ID），用于 A/B 实验分组
+	Scheme     string // 只返回该协议的地址，如 grpc
+	SecureOnly bool   // 只返回 TLS 加密地址
+	Consumer   string // 调用方身份，用于按调用方限流，只对进程内调用生效，API 层的请求按认证后的身份限流
+
+	MinHealthScore int // 只返回健康分不低于该值的实例
+
+	AffinityKey string // 粘性路由的 key（如用户ID），非空时实例按会合哈希排序，调用方选第一个
+
+	Limit  int    // 每页实例数，0 表示不分页，最大 MaxFetchLimit。分页时实例按 hostname 排序
+	Cursor string // 上一页返回的 NextCursor，第一页为空
+}
+
+// FetchWithOptions 按附加条件获取服务
+func (r *Registry) FetchWithOptions(env, appid string, status uint32, latestTimestamp int64, opts FetchOptions) (*FetchData, error) {
+	return r.FetchContext(context.Background(), env, appid, status, latestTimestamp, opts)
+}
+
+// FetchContext 同 FetchWithOptions，ctx 的 deadline 在排队及读取前检查
+func (r *Registry) FetchContext(ctx context.Context, env, appid string, status uint32, latestTimestamp int64, opts FetchOptions) (c *FetchData, err error) {
+	defer r.observeRequest(EndpointFetch, time.Now(), &err)
+	if err := r.CheckVisible(ctx, env, appid); err != nil {
+		return nil, err
+	}
+	if err := r.allowFetch(ctx, env, appid, opts.Consumer); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityFetch)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageCache); err != nil {
+		return nil, err
+	}
+	return r.fetch(env, appid, status, latestTimestamp, opts)
+}
+
+// fetch 获取应用实例并按附加条件处理，调用方负责限流和准入控制
+func (r *Registry) fetch(env, appid string, status uint32, latestTimestamp int64, opts FetchOptions) (*FetchData, error) {
+	// 可用区故障期间的提示不在预热缓存中
+	if r.warm != nil && r.warm.hot(getKey(appid, env)) && status == StatusUP && opts == (FetchOptions{Consumer: opts.Consumer}) && r.zoneFailover(getKey(appid, env)) == nil {
+		return r.warmFetch(env, appid, latestTimestamp)
+	}
+	return r.buildFetch(env, appid, status, latestTimestamp, opts)
+}
+
+// buildFetch 从注册表构建 Fetch 结果
+func (r *Registry) buildFetch(env, appid string, status uint32, latestTimestamp int64, opts FetchOptions) (*FetchData, error) {
+	c, err := r.fetchInstances(env, appid, status, latestTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	// 按协议过滤地址
+	if opts.Scheme != "" {
+		c.Instances = filterScheme(c.Instances, opts.Scheme)
+	}
+	if opts.SecureOnly {
+		c.Instances = filterSecure(c.Instances)
+	}
+	c.Instances = r.applyHealthScores(c.Instances, opts.MinHealthScore)
+	if len(c.Instances) == 0 {
+		return nil, ErrNoInstances
+	}
+	// 影子实例单独返回，不参与正常流量
+	c.ShadowPercent, c.Shadows, c.Instances = r.shadows.split(env, appid, c.Instances)
+	// 按实验分组筛选实例
+	if opts.ClientKey != "" {
+		c.Group, c.Instances = r.experiments.selectGroup(env, appid, opts.ClientKey, c.Instances)
+	}
+	// 按灰度发布进度重新计算各版本实例权重
+	r.rollouts.applyWeights(env, appid, c.Instances)
+	if opts.AffinityKey != "" {
+		c.Instances = OrderByAffinity(c.Instances, opts.AffinityKey)
+	}
+	if limit := pageLimit(opts.Limit); limit > 0 || opts.Cursor != "" {
+		c.Total = len(c.Instances)
+		if c.Instances, c.NextCursor, err = paginate(c.Instances, limit, opts.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	c.Hints = r.cacheHints()
+	c.ZoneFailover = r.zoneFailover(getKey(appid, env))
+	return c, nil
 }
 
-// Fetch 服务获取
-func (r *Registry) Fetch(env, appid string, status uint32, latestTimestamp int64) ([]*Instance, error) {
+// fetchInstances 获取应用实例，过载降级时只使用缓存数据
+func (r *Registry) fetchInstances(env, appid string, status uint32, latestTimestamp int64) (*FetchData, error) {
+	key := getKey(appid, env)
+	if r.Degraded() {
+		c, ok := r.overload.loadFetch(key, status)
+		if !ok {
+			return nil, &BackpressureError{Err: ErrOverloaded, RetryAfter: time.Second}
+		}
+		if latestTimestamp >= c.LatestTimestamp {
+			return nil, ErrNotModified
+		}
+		return c, nil
+	}
 	app, ok := r.getApplication(appid, env)
 	if !ok {
-		return nil, errors.New("app not found")
+		return nil, ErrAppNotFound
 	}
 	c, err := app.GetInstance(status, latestTimestamp)
-	return c.Instances, err
+	if err != nil {
+		return nil, err
+	}
+	if r.overload != nil {
+		r.overload.storeFetch(key, status, c)
+	}
+	return c, nil
 }
 
-// Cancel 服务下线
+// Cancel 服务下线，latestTimestamp 为 0 时使用服务端时间，超前当前时间 MaxTimestampSkew 以上时拒绝
 func (r *Registry) Cancel(env, appid, hostname string, latestTimestamp int64) (*Instance, error) {
-	log.Println("action cancel...")
+	return r.CancelContext(context.Background(), env, appid, hostname, latestTimestamp)
+}
+
+// CancelContext 服务下线，请求 ID 的处理同 RegisterContext
+func (r *Registry) CancelContext(ctx context.Context, env, appid, hostname string, latestTimestamp int64) (in *Instance, err error) {
+	defer r.observeRequest(EndpointCancel, time.Now(), &err)
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if err := r.checkFrozen(env, appid); err != nil {
+		return nil, err
+	}
+	// 超前太多的时间戳会让应用版本跳到未来，之后正常的请求都被当作乱序；未传时使用服务端时间
+	now := time.Now()
+	if latestTimestamp > now.Add(MaxTimestampSkew).UnixNano() {
+		return nil, &InvalidRequestError{Violations: []FieldViolation{{Field: "latest_timestamp", Reason: "is too far in the future"}}}
+	}
+	if latestTimestamp == 0 {
+		latestTimestamp = now.UnixNano()
+	}
+	release, err := r.AdmitContext(ctx, PriorityCancel)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageStore); err != nil {
+		return nil, err
+	}
+	clock := r.clock.now()
+	in, err = r.cancel(ctx, env, appid, hostname, latestTimestamp, clock, EventCancel)
+	if err != nil {
+		return nil, err
+	}
+	r.replicate(ctx, ReplicationOp{Action: ActionCancel, Env: env, AppId: appid, Hostname: hostname, LatestTimestamp: latestTimestamp, Clock: clock})
+	return in, nil
+}
+
+func (r *Registry) cancel(ctx context.Context, env, appid, hostname string, latestTimestamp int64, clock HLC, eventType EventType) (*Instance, error) {
+	logf(ctx, "action %s %s/%s", eventType, getKey(appid, env), hostname)
 	// find app
 	app, ok := r.getApplication(appid, env)
 	if !ok {
-		return nil, errors.New("app not found")
+		return nil, ErrAppNotFound
 	}
 	instance, ok, insLen := app.Cancel(hostname, latestTimestamp)
 	if !ok {
-		return nil, errors.New("instance not found")
+		return nil, ErrInstanceNotFound
+	}
+	r.events.append(Event{Type: eventType, Env: env, AppId: appid, Hostname: hostname, Instance: instance, RequestID: RequestIDFromContext(ctx), Source: r.requestSource(ctx)})
+	if eventType == EventEvict {
+		r.activity.record(env, appid, activityEvict)
+	} else {
+		r.activity.record(env, appid, activityCancel)
+	}
+	r.tombstones.add(Tombstone{Env: env, AppId: appid, Hostname: hostname, DeletedAt: time.Now().UnixNano(), Clock: clock})
+	r.workloadCerts.remove(env, appid, hostname)
+	r.health.remove(getInstanceKey(appid, env, hostname))
+	r.capacity.remove(getInstanceKey(appid, env, hostname))
+	if r.credentials != nil {
+		r.credentials.remove(env, appid, hostname)
 	}
 	// if instances is empty, delete app from apps
-	if insLen == 0 {
-		r.lock.Lock()
-		delete(r.apps, getKey(appid, env))
-		r.lock.Unlock()
+	if insLen == 0 && r.appRetention <= 0 {
+		r.removeEmptyApp(app, 0)
 	}
 	return instance, nil
 }
 
 // Renew 服务续约
 func (r *Registry) Renew(env, appid, hostname string) (*Instance, error) {
+	return r.RenewContext(context.Background(), env, appid, hostname)
+}
+
+// RenewContext 服务续约，请求 ID 的处理同 RegisterContext，ctx 中带有容量时一起记录
+func (r *Registry) RenewContext(ctx context.Context, env, appid, hostname string) (in *Instance, err error) {
+	defer r.observeRequest(EndpointRenew, time.Now(), &err)
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityRenew)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageStore); err != nil {
+		return nil, err
+	}
+	in, err = r.renew(ctx, env, appid, hostname)
+	if err != nil {
+		return nil, err
+	}
+	r.replicate(ctx, ReplicationOp{Action: ActionRenew, Env: env, AppId: appid, Hostname: hostname, Capacity: capacityFromContext(ctx)})
+	return in, nil
+}
+
+func (r *Registry) renew(ctx context.Context, env, appid, hostname string) (*Instance, error) {
 	app, ok := r.getApplication(appid, env)
 	if !ok {
-		return nil, errors.New("app not found")
+		return nil, ErrAppNotFound
 	}
 	in, ok := app.Renew(hostname)
 	if !ok {
-		return nil, errors.New("instance not found")
+		return nil, ErrInstanceNotFound
+	}
+	r.activity.record(env, appid, activityRenew)
+	if c := capacityFromContext(ctx); c != nil && c.validate() == nil {
+		r.capacity.report(getInstanceKey(appid, env, hostname), *c)
 	}
+	// 证书临近过期时续签
+	r.renewCertificate(ctx, in)
 	return in, nil
 }
 
@@ -174,14 +615,26 @@ func (r *Registry) getApplication(appid, env string) (*Application, bool) {
 	return app, ok
 }
 
-func getKey(appid, env string) string {
-	return fmt.Sprintf("%s-%s", appid, env)
+// getInstance 返回实例的副本
+func (r *Registry) getInstance(env, appid, hostname string) (*Instance, bool) {
+	app, ok := r.getApplication(appid, env)
+	if !ok {
+		return nil, false
+	}
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	in, ok := app.instances[hostname]
+	if !ok {
+		return nil, false
+	}
+	return copyInstance(in), true
 }
 
 func NewApplication(appid string) *Application {
 	return &Application{
-		appId:     appid,
-		instances: make(map[string]*Instance),
+		appId:        appid,
+		instances:    make(map[string]*Instance),
+		statusCounts: make(map[uint32]int),
 	}
 }
 func (app *Application) AddInstance(in *Instance, latestTimestamp int64) (*Instance, bool) {
@@ -190,35 +643,74 @@ func (app *Application) AddInstance(in *Instance, latestTimestamp int64) (*Insta
 	appIns, ok := app.instances[in.Hostname]
 	if ok { // exist
 		in.UpTimestamp = appIns.UpTimestamp
-		// dirtytimestamp
-		if in.DirtyTimestamp < appIns.DirtyTimestamp {
+		// 双方都带有混合逻辑时钟时按时钟比较，否则退回到 dirtytimestamp
+		if olderThan(in, appIns) {
 			log.Println("register exist dirty timestamp")
 			in = appIns
 		}
 	}
 	// add or update instances
+	if ok {
+		app.statusCounts[appIns.Status]--
+	}
+	app.statusCounts[in.Status]++
 	app.instances[in.Hostname] = in
+	app.emptySince = 0
 	app.upLatestTimestamp(latestTimestamp)
 	returnIns := new(Instance)
 	*returnIns = *in
 	return returnIns, !ok
 }
 
-// update app latest_timestamp
+// olderThan 判断注册请求 in 是否比已有实例 exist 旧
+func olderThan(in, exist *Instance) bool {
+	if !in.Clock.IsZero() && !exist.Clock.IsZero() {
+		return in.Clock.Compare(exist.Clock) < 0
+	}
+	return in.DirtyTimestamp < exist.DirtyTimestamp
+}
+
+// update app latest_timestamp，保证单调不减。乱序到达的旧请求不能让版本回退，
+// 此时版本在当前值上加一，使增量拉取的客户端仍能感知到这次变更
 func (app *Application) upLatestTimestamp(latestTimestamp int64) {
+	if latestTimestamp < app.latestTimestamp {
+		log.Printf("app %s latest timestamp conflict: %d < %d", app.appId, latestTimestamp, app.latestTimestamp)
+		app.timestampConflicts++
+		latestTimestamp = app.latestTimestamp
+		if latestTimestamp < math.MaxInt64 {
+			latestTimestamp++
+		}
+	}
 	app.latestTimestamp = latestTimestamp
 }
 
+// TimestampConflicts 乱序请求导致版本回退被拦截的次数
+func (app *Application) TimestampConflicts() uint64 {
+	app.lock.RLock()
+	defer app.lock.RUnlock()
+	return app.timestampConflicts
+}
+
 type FetchData struct {
 	Instances       []*Instance `json:"instances"`
 	LatestTimestamp int64       `json:"latest_timestamp"`
+	Group           string      `json:"group,omitempty"`          // 调用方被分配到的实验分组
+	Shadows         []*Instance `json:"shadows,omitempty"`        // 影子流量目标实例
+	ShadowPercent   int         `json:"shadow_percent,omitempty"` // 镜像到影子实例的流量百分比
+	Hints           *CacheHints `json:"hints,omitempty"`          // 客户端缓存提示
+	Total           int         `json:"total,omitempty"`          // 分页时满足条件的实例总数
+	NextCursor      string      `json:"next_cursor,omitempty"`    // 下一页的游标，为空表示已是最后一页
+
+	ZoneFailover *ZoneFailoverHint `json:"zone_failover,omitempty"` // 应用有可用区故障时的转移提示
+
+	payload *fetchPayload // 来自热点应用缓存时预先序列化的结果
 }
 
 func (app *Application) GetInstance(status uint32, latestTime int64) (*FetchData, error) {
 	app.lock.RLock()
 	defer app.lock.RUnlock()
 	if latestTime >= app.latestTimestamp {
-		return nil, errors.New("latest timestamp is not latest")
+		return nil, ErrNotModified
 	}
 	fetchData := FetchData{
 		Instances:       make([]*Instance, 0),
@@ -233,7 +725,7 @@ func (app *Application) GetInstance(status uint32, latestTime int64) (*FetchData
 		}
 	}
 	if !exists {
-		return nil, errors.New("not exist condition instance")
+		return nil, ErrNoInstances
 	}
 	return &fetchData, nil
 }
@@ -248,9 +740,13 @@ func (app *Application) Cancel(hostname string, latestTimestamp int64) (*Instanc
 	}
 	// delete hostname
 	delete(app.instances, hostname)
+	app.statusCounts[appIn.Status]--
 	appIn.LatestTimestamp = latestTimestamp
 	app.upLatestTimestamp(latestTimestamp)
 	*newInstance = *appIn
+	if len(app.instances) == 0 {
+		app.emptySince = time.Now().UnixNano()
+	}
 	return newInstance, true, len(app.instances)
 }
 
@@ -287,35 +783,65 @@ func copyInstance(src *Instance) *Instance {
 	for i, addr := range src.Addrs {
 		dst.Addrs[i] = addr
 	}
+	// copy metadata
+	if src.Metadata != nil {
+		dst.Metadata = make(map[string]string, len(src.Metadata))
+		for k, v := range src.Metadata {
+			dst.Metadata[k] = v
+		}
+	}
+	// copy endpoints
+	dst.Endpoints = make([]Endpoint, len(src.Endpoints))
+	copy(dst.Endpoints, src.Endpoints)
 	return dst
 }
 
 type RequestRegister struct {
-	Env             string   `form:"env"`
-	AppId           string   `form:"appid"`
-	Hostname        string   `form:"hostname"`
-	Addrs           []string `form:"addrs[]"`
-	Status          uint32   `form:"status"`
-	Version         string   `form:"version"`
-	LatestTimestamp int64    `form:"latest_timestamp"`
-	DirtyTimestamp  int64    `form:"dirty_timestamp"` // other node send
-	Replication     bool     `form:"replication"`     // other node send
+	Env             string            `form:"env"`
+	AppId           string            `form:"appid"`
+	Hostname        string            `form:"hostname"`
+	Addrs           []string          `form:"addrs[]"`
+	SecureAddrs     []string          `form:"secure_addrs[]"` // TLS 加密地址
+	Status          uint32            `form:"status"`
+	Weight          uint32            `form:"weight"`
+	Version         string            `form:"version"`
+	Group           string            `form:"group"`
+	SpiffeID        string            `form:"spiffe_id"`
+	InstanceID      string            `form:"instance_id"`
+	PortNames       map[string]string `form:"port_names"` // key: 地址，value: 端口名
+	Metadata        map[string]string `form:"metadata"`
+	LatestTimestamp int64             `form:"latest_timestamp"`
+	DirtyTimestamp  int64             `form:"dirty_timestamp"` // other node send
+	Replication     bool              `form:"replication"`     // other node send
 }
 
 func NewInstance(req *RequestRegister) *Instance {
 	now := time.Now().UnixNano()
+	addrs, endpoints := parseEndpoints(req.Addrs, req.SecureAddrs)
 	instance := &Instance{
 		Env:             req.Env,
 		AppId:           req.AppId,
 		Hostname:        req.Hostname,
-		Addrs:           req.Addrs,
+		Addrs:           addrs,
+		Endpoints:       endpoints,
 		Version:         req.Version,
 		Status:          req.Status,
+		Weight:          req.Weight,
+		Group:           req.Group,
+		SpiffeID:        req.SpiffeID,
+		InstanceID:      req.InstanceID,
+		Metadata:        req.Metadata,
 		RegTimestamp:    now,
 		UpTimestamp:     now,
 		RenewTimestamp:  now,
 		DirtyTimestamp:  now,
 		LatestTimestamp: now,
 	}
+	if instance.Weight == 0 {
+		instance.Weight = DefaultWeight
+	}
+	for i := range endpoints {
+		endpoints[i].Name = req.PortNames[addrs[i]]
+	}
 	return instance
 }
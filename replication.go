@@ -0,0 +1,153 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 复制操作类型
+const (
+	ActionRegister   = "register"   // 服务注册
+	ActionRenew      = "renew"      // 服务续约
+	ActionCancel     = "cancel"     // 服务下线
+	ActionCredential = "credential" // 续约凭证轮换
+	ActionPurge      = "purge"      // 清除应用的所有数据
+	ActionFreeze     = "freeze"     // 冻结应用
+	ActionUnfreeze   = "unfreeze"   // 解除应用的冻结
+)
+
+// ReplicationOp 一次需要复制到对端节点的写操作
+type ReplicationOp struct {
+	Action          string                    `json:"action"`
+	Env             string                    `json:"env"`
+	AppId           string                    `json:"appId"`
+	Hostname        string                    `json:"hostname"`
+	Instance        *Instance                 `json:"instance,omitempty"` // 注册时的实例
+	LatestTimestamp int64                     `json:"latest_timestamp"`
+	Clock           HLC                       `json:"clock"`                 // 下线操作的混合逻辑时钟，注册操作使用 Instance.Clock
+	RequestID       string                    `json:"request_id,omitempty"`  // 原始请求的 ID，用于跨节点追踪
+	Traceparent     string                    `json:"traceparent,omitempty"` // W3C Trace Context，对端的应用与原始请求在同一 trace 中
+	Tracestate      string                    `json:"tracestate,omitempty"`
+	Origin          string                    `json:"origin,omitempty"`     // 最初处理请求的节点
+	Capacity        *Capacity                 `json:"capacity,omitempty"`   // 续约时上报的容量
+	Credential      *HeartbeatCredentialState `json:"credential,omitempty"` // 注册及凭证轮换后的续约凭证状态，对端沿用同一凭证
+	Freeze          *AppFreeze                `json:"freeze,omitempty"`     // 冻结应用时的冻结配置
+}
+
+// Replicator 把本节点成功处理的写操作发送给对端节点，实现方不应阻塞调用方
+type Replicator interface {
+	Replicate(op ReplicationOp)
+}
+
+// replicate 主节点处理写操作成功后复制给对端，备节点不向外复制
+func (r *Registry) replicate(ctx context.Context, op ReplicationOp) {
+	if r.replicator == nil || r.Role() != RoleActive {
+		return
+	}
+	op.RequestID = RequestIDFromContext(ctx)
+	op.Origin = r.requestSource(ctx).Node
+	ctx, end := r.startSpan(ctx, "registry.replicate")
+	defer end(nil)
+	if tc, ok := TraceFromContext(ctx); ok {
+		op.Traceparent, op.Tracestate = tc.Traceparent(), tc.State
+	}
+	if op.Instance != nil {
+		op.Instance = copyInstance(op.Instance)
+	}
+	if op.Action == ActionRegister && op.Credential == nil && r.credentials != nil {
+		op.Credential = r.credentials.state(op.Env, op.AppId, op.Hostname)
+	}
+	// 写操作已在本节点生效，复制超时只记录，不影响请求结果
+	if cr, ok := r.replicator.(ContextReplicator); ok {
+		if err := cr.ReplicateContext(ctx, op); err != nil {
+			if ctx.Err() != nil {
+				err = r.stageTimeout(StageReplication, err)
+			}
+			logf(ctx, "replicate %s %s/%s failed: %v", op.Action, op.Key(), op.Hostname, err)
+		}
+		return
+	}
+	r.replicator.Replicate(op)
+}
+
+// ApplyReplication 应用对端节点复制过来的写操作，不经过准入控制和只读检查，也不会再次复制。
+// 应用冻结时拒绝改变实例集合及状态的注册、下线和清除，与本节点处理请求时一致
+func (r *Registry) ApplyReplication(op ReplicationOp) (err error) {
+	ctx := ContextWithSource(context.Background(), RegistrationSource{Kind: SourceReplication, Node: op.Origin})
+	if op.RequestID != "" {
+		ctx = ContextWithRequestID(ctx, op.RequestID)
+	}
+	if tc, err := ParseTraceparent(op.Traceparent, op.Tracestate); err == nil {
+		ctx = ContextWithTrace(ctx, tc)
+	}
+	ctx, end := r.startSpan(ctx, "registry.apply_replication")
+	defer func() { end(err) }()
+	switch op.Action {
+	case ActionRegister, ActionCancel, ActionPurge:
+		if err := r.checkFrozen(op.Env, op.AppId); err != nil {
+			return err
+		}
+	}
+	switch op.Action {
+	case ActionRegister:
+		if op.Instance == nil {
+			return errors.New("replication register without instance")
+		}
+		if _, _, err := r.register(ctx, copyInstance(op.Instance), op.LatestTimestamp); err != nil {
+			return err
+		}
+		r.applyCredential(op)
+		return nil
+	case ActionCredential:
+		if op.Credential == nil {
+			return errors.New("replication credential without state")
+		}
+		if _, ok := r.getInstance(op.Env, op.AppId, op.Hostname); !ok {
+			return ErrInstanceNotFound
+		}
+		r.applyCredential(op)
+		return nil
+	case ActionRenew:
+		if op.Capacity != nil {
+			ctx = ContextWithCapacity(ctx, *op.Capacity)
+		}
+		_, err := r.renew(ctx, op.Env, op.AppId, op.Hostname)
+		return err
+	case ActionPurge:
+		r.purge(ctx, op.Env, op.AppId)
+		return nil
+	case ActionFreeze:
+		if op.Freeze == nil {
+			return errors.New("replication freeze without freeze")
+		}
+		r.freeze(ctx, *op.Freeze)
+		return nil
+	case ActionUnfreeze:
+		r.unfreeze(ctx, op.Env, op.AppId)
+		return nil
+	case ActionCancel:
+		if !op.Clock.IsZero() {
+			r.clock.update(op.Clock)
+			// 乱序到达的下线请求不能删除之后重新注册的实例
+			if in, ok := r.getInstance(op.Env, op.AppId, op.Hostname); ok && in.Clock.Compare(op.Clock) > 0 {
+				logf(ctx, "ignore stale replicated cancel of %s/%s", op.Key(), op.Hostname)
+				return nil
+			}
+		}
+		clock := op.Clock
+		if clock.IsZero() {
+			clock = r.clock.now()
+		}
+		_, err := r.cancel(ctx, op.Env, op.AppId, op.Hostname, op.LatestTimestamp, clock, EventCancel)
+		return err
+	}
+	return fmt.Errorf("unknown replication action %q", op.Action)
+}
+
+// applyCredential 使用主节点复制的凭证状态，对端未开启续约凭证或旧版本主节点未携带时保留本节点签发的凭证
+func (r *Registry) applyCredential(op ReplicationOp) {
+	if r.credentials != nil && op.Credential != nil {
+		r.credentials.set(op.Env, op.AppId, op.Hostname, *op.Credential)
+	}
+}
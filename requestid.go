@@ -0,0 +1,48 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+type requestIDKey struct{}
+
+// NewRequestID 生成随机的请求 ID
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID 把请求 ID 放入 ctx，API 层收到调用方传入的请求 ID 时使用
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext 返回 ctx 中的请求 ID，没有时返回空
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ensureRequestID ctx 中没有请求 ID 时生成一个
+func ensureRequestID(ctx context.Context) context.Context {
+	if RequestIDFromContext(ctx) != "" {
+		return ctx
+	}
+	return ContextWithRequestID(ctx, NewRequestID())
+}
+
+// logf 输出带请求 ID 及 trace ID 的日志
+func logf(ctx context.Context, format string, args ...interface{}) {
+	if tc, ok := TraceFromContext(ctx); ok {
+		format = fmt.Sprintf("[trace=%s] %s", tc.TraceID, format)
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		format = fmt.Sprintf("[%s] %s", id, format)
+	}
+	log.Printf(format, args...)
+}
@@ -0,0 +1,30 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	standby := NewRegistry(WithStandby(FailoverConfig{}))
+	active := NewRegistry(WithReplicator(applyReplicator{peer: standby}))
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := active.RegisterContext(ctx, in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*Registry{active, standby} {
+		events, _ := r.Replay(0, 0)
+		if len(events) != 1 || events[0].RequestID != "req-1" {
+			t.Fatalf("request id should be recorded on every node: %+v", events)
+		}
+	}
+
+	active.Cancel("test", "com.xx.app", "a", time.Now().UnixNano())
+	events, _ := active.Replay(1, 0)
+	if len(events) != 1 || events[0].RequestID == "" {
+		t.Fatalf("request id should be generated when missing: %+v", events)
+	}
+}
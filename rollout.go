@@ -0,0 +1,216 @@
+package registry_center
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// Rollout 版本灰度发布计划：在 Duration 时间内把 TargetVersion 的流量占比
+// 从 StartPercent 线性提升到 EndPercent，目标版本健康度下降时自动暂停
+type Rollout struct {
+	Env             string        `json:"env"`
+	AppId           string        `json:"appId"`
+	TargetVersion   string        `json:"target_version"`    // 灰度目标版本
+	StartPercent    float64       `json:"start_percent"`     // 起始流量占比，0~100
+	EndPercent      float64       `json:"end_percent"`       // 最终流量占比，0~100
+	Duration        time.Duration `json:"duration"`          // 从起始占比到最终占比的时长
+	MinHealthyRatio float64       `json:"min_healthy_ratio"` // 目标版本 UP 实例占比低于该值时暂停，0 表示不检查
+
+	StartTimestamp int64  `json:"start_timestamp"` // 开始时间
+	Paused         bool   `json:"paused"`          // 是否暂停
+	PauseReason    string `json:"pause_reason"`    // 暂停原因
+	pausedAt       int64  // 本次暂停开始时间
+	pausedTotal    int64  // 累计暂停时长
+}
+
+// Percent 返回 now 时刻目标版本的流量占比
+func (ro *Rollout) Percent(now int64) float64 {
+	if ro.Duration <= 0 {
+		return ro.EndPercent
+	}
+	end := now
+	if ro.Paused {
+		end = ro.pausedAt
+	}
+	elapsed := end - ro.StartTimestamp - ro.pausedTotal
+	if elapsed <= 0 {
+		return ro.StartPercent
+	}
+	if elapsed >= int64(ro.Duration) {
+		return ro.EndPercent
+	}
+	return ro.StartPercent + (ro.EndPercent-ro.StartPercent)*float64(elapsed)/float64(ro.Duration)
+}
+
+type rolloutController struct {
+	rollouts map[AppKey]*Rollout
+	lock     sync.RWMutex
+}
+
+func newRolloutController() *rolloutController {
+	return &rolloutController{
+		rollouts: make(map[AppKey]*Rollout),
+	}
+}
+
+// StartRollout 开始灰度发布，同一应用已有的灰度计划会被覆盖
+func (r *Registry) StartRollout(ro Rollout) (*Rollout, error) {
+	if ro.Env == "" || ro.AppId == "" || ro.TargetVersion == "" {
+		return nil, errors.New("rollout env, appId and target version are required")
+	}
+	if ro.StartPercent < 0 || ro.EndPercent > 100 || ro.StartPercent > ro.EndPercent {
+		return nil, errors.New("rollout percent out of range")
+	}
+	ro.StartTimestamp = time.Now().UnixNano()
+	ro.Paused = false
+	ro.PauseReason = ""
+	ro.pausedAt = 0
+	ro.pausedTotal = 0
+	c := r.rollouts
+	c.lock.Lock()
+	c.rollouts[getKey(ro.AppId, ro.Env)] = &ro
+	c.lock.Unlock()
+	out := ro
+	return &out, nil
+}
+
+// GetRollout 获取应用当前的灰度发布计划
+func (r *Registry) GetRollout(env, appid string) (*Rollout, bool) {
+	c := r.rollouts
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	ro, ok := c.rollouts[getKey(appid, env)]
+	if !ok {
+		return nil, false
+	}
+	out := *ro
+	return &out, true
+}
+
+// PauseRollout 手动暂停灰度发布
+func (r *Registry) PauseRollout(env, appid, reason string) error {
+	c := r.rollouts
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ro, ok := c.rollouts[getKey(appid, env)]
+	if !ok {
+		return errors.New("rollout not found")
+	}
+	ro.pause(time.Now().UnixNano(), reason)
+	return nil
+}
+
+// ResumeRollout 恢复被暂停的灰度发布，暂停期间不计入灰度进度
+func (r *Registry) ResumeRollout(env, appid string) error {
+	c := r.rollouts
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	ro, ok := c.rollouts[getKey(appid, env)]
+	if !ok {
+		return errors.New("rollout not found")
+	}
+	if ro.Paused {
+		ro.pausedTotal += time.Now().UnixNano() - ro.pausedAt
+		ro.Paused = false
+		ro.PauseReason = ""
+		ro.pausedAt = 0
+	}
+	return nil
+}
+
+// StopRollout 结束灰度发布，实例权重恢复为注册时的权重
+func (r *Registry) StopRollout(env, appid string) {
+	c := r.rollouts
+	c.lock.Lock()
+	delete(c.rollouts, getKey(appid, env))
+	c.lock.Unlock()
+}
+
+func (ro *Rollout) pause(now int64, reason string) {
+	if ro.Paused {
+		return
+	}
+	ro.Paused = true
+	ro.PauseReason = reason
+	ro.pausedAt = now
+}
+
+// applyWeights 按灰度进度重新分配权重：目标版本实例共享 percent% 的总权重，
+// 其余实例共享剩余权重，组内保持注册时的权重比例
+func (c *rolloutController) applyWeights(env, appid string, instances []*Instance) {
+	c.lock.RLock()
+	ro, ok := c.rollouts[getKey(appid, env)]
+	var percent float64
+	var target string
+	if ok {
+		percent = ro.Percent(time.Now().UnixNano())
+		target = ro.TargetVersion
+	}
+	c.lock.RUnlock()
+	if !ok {
+		return
+	}
+	var total, targetTotal uint64
+	for _, in := range instances {
+		total += uint64(in.Weight)
+		if in.Version == target {
+			targetTotal += uint64(in.Weight)
+		}
+	}
+	otherTotal := total - targetTotal
+	// 只有一个版本时无需调整
+	if targetTotal == 0 || otherTotal == 0 {
+		return
+	}
+	targetShare := float64(total) * percent / 100
+	otherShare := float64(total) - targetShare
+	for _, in := range instances {
+		if in.Version == target {
+			in.Weight = uint32(math.Round(targetShare * float64(in.Weight) / float64(targetTotal)))
+		} else {
+			in.Weight = uint32(math.Round(otherShare * float64(in.Weight) / float64(otherTotal)))
+		}
+	}
+}
+
+// checkRollouts 检查灰度中目标版本的健康状况，UP 实例占比低于阈值时暂停灰度
+func (r *Registry) checkRollouts() {
+	c := r.rollouts
+	c.lock.RLock()
+	rollouts := make([]Rollout, 0, len(c.rollouts))
+	for _, ro := range c.rollouts {
+		if !ro.Paused && ro.MinHealthyRatio > 0 {
+			rollouts = append(rollouts, *ro)
+		}
+	}
+	c.lock.RUnlock()
+
+	for _, ro := range rollouts {
+		app, ok := r.getApplication(ro.AppId, ro.Env)
+		if !ok {
+			continue
+		}
+		var total, up int
+		for _, in := range app.GetAllInstances() {
+			if in.Version != ro.TargetVersion {
+				continue
+			}
+			total++
+			if in.Status&StatusUP > 0 {
+				up++
+			}
+		}
+		if total == 0 || float64(up)/float64(total) >= ro.MinHealthyRatio {
+			continue
+		}
+		log.Printf("rollout %s paused: %d/%d instances of %s are up", getKey(ro.AppId, ro.Env), up, total, ro.TargetVersion)
+		c.lock.Lock()
+		if cur, ok := c.rollouts[getKey(ro.AppId, ro.Env)]; ok && cur.StartTimestamp == ro.StartTimestamp {
+			cur.pause(time.Now().UnixNano(), "target version health regression")
+		}
+		c.lock.Unlock()
+	}
+}
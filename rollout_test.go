@@ -0,0 +1,42 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollout(t *testing.T) {
+	r := NewRegistry()
+	for _, h := range []string{"v1-a", "v1-b", "v2-a"} {
+		in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.rollout", Hostname: h, Status: StatusUP, Version: h[:2]})
+		r.Register(in, time.Now().UnixNano())
+	}
+	if _, err := r.StartRollout(Rollout{Env: "test", AppId: "com.xx.rollout", TargetVersion: "v2", StartPercent: 10, EndPercent: 100, Duration: time.Hour, MinHealthyRatio: 1}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Fetch("test", "com.xx.rollout", StatusUP, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := c.Instances
+	var v1, v2 uint32
+	for _, in := range instances {
+		if in.Version == "v2" {
+			v2 += in.Weight
+		} else {
+			v1 += in.Weight
+		}
+	}
+	if v2 != 30 || v1 != 270 {
+		t.Fatalf("unexpected weights v1=%d v2=%d", v1, v2)
+	}
+
+	// 目标版本全部下线，灰度应自动暂停
+	down := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.rollout", Hostname: "v2-a", Status: StatusDown, Version: "v2"})
+	r.Register(down, time.Now().UnixNano())
+	r.checkRollouts()
+	ro, _ := r.GetRollout("test", "com.xx.rollout")
+	if !ro.Paused {
+		t.Fatal("rollout should be paused on health regression")
+	}
+}
@@ -0,0 +1,117 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrUnauthenticated 续约凭证校验失败
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// InstanceUpdate 实例可自行修改的字段，为空的字段保持不变
+type InstanceUpdate struct {
+	Addrs       []string          `json:"addrs,omitempty"`
+	SecureAddrs []string          `json:"secure_addrs,omitempty"`
+	Status      *uint32           `json:"status,omitempty"`
+	Weight      *uint32           `json:"weight,omitempty"`
+	Version     *string           `json:"version,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"` // 整体替换
+}
+
+// authenticate 校验续约凭证，不触发轮换。未开启续约凭证时没有可以校验的凭证，一律拒绝
+func (h *heartbeatCredentials) authenticate(env, appid, hostname, secret string) error {
+	if h == nil {
+		return ErrUnauthenticated
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	st, ok := h.states[getInstanceKey(appid, env, hostname)]
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if secretEqual(st.current.Secret, secret) ||
+		(st.previous != "" && time.Now().UnixNano() < st.previousExpiry && secretEqual(st.previous, secret)) {
+		return nil
+	}
+	return ErrUnauthenticated
+}
+
+// WhoAmI 实例使用自己的续约凭证查询注册中心眼中的自身记录，用于发现本地状态与注册表的偏差，需要开启续约凭证
+func (r *Registry) WhoAmI(env, appid, hostname, secret string) (*InstanceDetail, error) {
+	if err := r.credentials.authenticate(env, appid, hostname, secret); err != nil {
+		return nil, err
+	}
+	return r.GetInstance(env, appid, hostname)
+}
+
+// UpdateSelf 实例使用自己的续约凭证修改自身可变字段。修改按重新注册处理并复制给其他节点，
+// 会签发新凭证，客户端应改用返回的凭证。字段的校验规则与注册相同
+func (r *Registry) UpdateSelf(ctx context.Context, env, appid, hostname, secret string, u InstanceUpdate) (*InstanceDetail, *HeartbeatCredential, error) {
+	if err := r.credentials.authenticate(env, appid, hostname, secret); err != nil {
+		return nil, nil, err
+	}
+	if err := u.Validate(); err != nil {
+		return nil, nil, err
+	}
+	in, ok := r.getInstance(env, appid, hostname)
+	if !ok {
+		return nil, nil, ErrInstanceNotFound
+	}
+	if u.Addrs != nil || u.SecureAddrs != nil {
+		in.Addrs, in.Endpoints = parseEndpoints(u.Addrs, u.SecureAddrs)
+	}
+	if u.Status != nil {
+		in.Status = *u.Status
+	}
+	if u.Weight != nil {
+		in.Weight = *u.Weight
+	}
+	if u.Version != nil {
+		in.Version = *u.Version
+	}
+	if u.Metadata != nil {
+		in.Metadata = u.Metadata
+	}
+	now := time.Now().UnixNano()
+	in.DirtyTimestamp = now
+	in.Clock = HLC{}
+	if _, err := r.RegisterContext(ctx, in, now); err != nil {
+		return nil, nil, err
+	}
+	d, err := r.GetInstance(env, appid, hostname)
+	if err != nil {
+		return nil, nil, err
+	}
+	cred, _ := r.HeartbeatCredential(env, appid, hostname)
+	return d, cred, nil
+}
+
+// Validate 校验修改的字段：地址格式正确且修改地址时至少一个，状态为可用或不可用，元数据没有空 key
+func (u *InstanceUpdate) Validate() error {
+	e := &InvalidRequestError{}
+	if (u.Addrs != nil || u.SecureAddrs != nil) && len(u.Addrs)+len(u.SecureAddrs) == 0 {
+		e.add("addrs", "must not be empty")
+	}
+	for _, f := range []struct {
+		name  string
+		addrs []string
+	}{{"addrs", u.Addrs}, {"secure_addrs", u.SecureAddrs}} {
+		for _, addr := range f.addrs {
+			if !ValidAddr(addr) {
+				e.add(f.name, "must be scheme://host[:port] or host:port")
+				break
+			}
+		}
+	}
+	if u.Status != nil && *u.Status != StatusUP && *u.Status != StatusDown {
+		e.add("status", "must be one of 1, 2")
+	}
+	if _, ok := u.Metadata[""]; ok {
+		e.add("metadata", "must not have empty keys")
+	}
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
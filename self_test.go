@@ -0,0 +1,69 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWhoAmIAndUpdateSelf(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Version: "v1", Status: StatusUP}), time.Now().UnixNano())
+	cred, _ := r.HeartbeatCredential("test", "com.xx.app", "a")
+
+	if _, err := r.WhoAmI("test", "com.xx.app", "a", "wrong"); err != ErrUnauthenticated {
+		t.Fatalf("expect unauthenticated, got %v", err)
+	}
+	d, err := r.WhoAmI("test", "com.xx.app", "a", cred.Secret)
+	if err != nil || d.Instance.Version != "v1" {
+		t.Fatalf("unexpected whoami %+v %v", d, err)
+	}
+
+	version, status := "v2", StatusDown
+	d, newCred, err := r.UpdateSelf(context.Background(), "test", "com.xx.app", "a", cred.Secret, InstanceUpdate{Version: &version, Status: &status, Addrs: []string{"grpc://10.0.0.1:9000"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Instance.Version != "v2" || d.Instance.Status != StatusDown || d.Instance.Endpoints[0].Scheme != "grpc" {
+		t.Fatalf("unexpected updated instance %+v", d.Instance)
+	}
+	if newCred == nil {
+		t.Fatal("update should return the current credential")
+	}
+	if _, err := r.WhoAmI("test", "com.xx.app", "a", newCred.Secret); err != nil {
+		t.Fatalf("new credential should authenticate, got %v", err)
+	}
+}
+
+func TestUpdateSelfRequiresCredentialsAndValidates(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Version: "v1", Status: StatusUP}), time.Now().UnixNano())
+	if _, err := r.WhoAmI("test", "com.xx.app", "a", ""); err != ErrUnauthenticated {
+		t.Fatalf("whoami without credentials should be unauthenticated, got %v", err)
+	}
+	status := uint32(1)
+	if _, _, err := r.UpdateSelf(context.Background(), "test", "com.xx.app", "a", "", InstanceUpdate{Status: &status}); err != ErrUnauthenticated {
+		t.Fatalf("update without credentials should be unauthenticated, got %v", err)
+	}
+
+	r = NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Version: "v1", Status: StatusUP}), time.Now().UnixNano())
+	cred, _ := r.HeartbeatCredential("test", "com.xx.app", "a")
+	for _, u := range []InstanceUpdate{
+		{Status: uint32Ptr(7)},
+		{Addrs: []string{"bad addr"}},
+		{Addrs: []string{}},
+		{Metadata: map[string]string{"": "x"}},
+	} {
+		if _, _, err := r.UpdateSelf(context.Background(), "test", "com.xx.app", "a", cred.Secret, u); !errors.Is(err, ErrInvalidRequest) {
+			t.Fatalf("update %+v should be rejected, got %v", u, err)
+		}
+	}
+	d, _ := r.WhoAmI("test", "com.xx.app", "a", cred.Secret)
+	if d.Instance.Status != StatusUP {
+		t.Fatalf("rejected update must not change the instance, got status %d", d.Instance.Status)
+	}
+}
+
+func uint32Ptr(v uint32) *uint32 { return &v }
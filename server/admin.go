@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PathPurge 清除应用的所有数据，需要管理员认证
+const PathPurge = "/api/purge"
+
+// AdminConfig 管理接口的认证，客户端证书或管理令牌满足其一即可
+type AdminConfig struct {
+	Identities []string // 管理员客户端证书的 SPIFFE ID，末尾为 * 时按前缀匹配
+	Token      string   // 管理令牌，请求头为 Authorization: Bearer <Token>，为空时不接受令牌
+}
+
+// PurgeReply 清除应用的返回数据
+type PurgeReply struct {
+	Purged int `json:"purged"` // 删除的实例数
+}
+
+// isAdmin 请求是否来自管理员，未配置 AdminConfig 时没有管理员
+func (s *HTTPServer) isAdmin(r *http.Request) bool {
+	cfg := s.cfg.Admin
+	if cfg == nil {
+		return false
+	}
+	if cfg.Token != "" {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Token)) == 1 {
+			return true
+		}
+	}
+	caller := callerIdentity(r)
+	if caller == "" {
+		return false
+	}
+	for _, id := range cfg.Identities {
+		if id == caller || strings.HasSuffix(id, "*") && strings.HasPrefix(caller, strings.TrimSuffix(id, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// admin 只允许管理员访问的接口，管理员可见所有应用。没有出示任何身份时返回 401，身份不是管理员时返回 403
+func (s *HTTPServer) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.isAdmin(r) {
+			h(w, r.WithContext(registry.ContextWithAdmin(r.Context())))
+			return
+		}
+		if r.Header.Get("Authorization") == "" && callerIdentity(r) == "" {
+			writeError(w, r, registry.ErrUnauthenticated)
+			return
+		}
+		writeError(w, r, registry.ErrPermissionDenied)
+	}
+}
+
+func (s *HTTPServer) purge(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	if err := validateParams(purgeParamList, r.Form); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	n, err := s.reg.PurgeContext(r.Context(), r.Form.Get("env"), r.Form.Get("appid"), r.Form.Get("confirm"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, PurgeReply{Purged: n})
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestPurgeRequiresAdmin(t *testing.T) {
+	r := registry.NewRegistry()
+	s := NewHTTPServer(r, ServerConfig{Admin: &AdminConfig{Token: "s3cret"}})
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "h1", Status: registry.StatusUP}, 1)
+
+	do := func(auth, confirm string) int {
+		req := httptest.NewRequest(http.MethodPost, PathPurge, strings.NewReader("env=test&appid=com.xx.a&confirm="+confirm))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, c := range []struct {
+		auth, confirm string
+		code          int
+	}{
+		{"", "com.xx.a", http.StatusUnauthorized},
+		{"wrong", "com.xx.a", http.StatusForbidden},
+		{"s3cret", "yes", http.StatusBadRequest},
+		{"s3cret", "com.xx.a", http.StatusOK},
+	} {
+		if code := do(c.auth, c.confirm); code != c.code {
+			t.Fatalf("auth %q confirm %q: expect %d, got %d", c.auth, c.confirm, c.code, code)
+		}
+	}
+	if _, err := r.Fetch("test", "com.xx.a", registry.StatusUP, 0); err == nil {
+		t.Fatal("app should be purged")
+	}
+}
+
+func TestSnapshotHidesRestrictedApps(t *testing.T) {
+	r := registry.NewRegistry()
+	s := NewHTTPServer(r, ServerConfig{Admin: &AdminConfig{Token: "s3cret"}})
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "h1", Status: registry.StatusUP}, 1)
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.private", Hostname: "h1", Status: registry.StatusUP}, 1)
+	r.SetVisibility(registry.AppVisibility{Env: "test", AppId: "com.xx.private", Visibility: registry.VisibilityRestricted})
+
+	apps := func(auth string) int {
+		req := httptest.NewRequest(http.MethodGet, PathSnapshot, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		var resp struct {
+			Data registry.Snapshot `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return len(resp.Data.Apps)
+	}
+	if n := apps(""); n != 1 {
+		t.Fatalf("anonymous snapshot should hide the restricted app, got %d apps", n)
+	}
+	if n := apps("s3cret"); n != 2 {
+		t.Fatalf("admin snapshot should include every app, got %d apps", n)
+	}
+}
+
+func TestAdminRoutes(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{Admin: &AdminConfig{Token: "s3cret"}})
+	for _, c := range []struct {
+		method, path string
+	}{
+		{http.MethodPost, PathSet},
+		{http.MethodPost, PathTransfer},
+		{http.MethodPost, PathMaintenance},
+		{http.MethodPost, PathMaintenanceCancel},
+		{http.MethodGet, PathFreeze},
+		{http.MethodPost, PathFreeze},
+		{http.MethodPost, PathUnfreeze},
+		{http.MethodGet, PathReconcile},
+		{http.MethodGet, PathAudit},
+	} {
+		for auth, code := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusForbidden} {
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader("{}"))
+			if auth != "" {
+				req.Header.Set("Authorization", "Bearer "+auth)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != code {
+				t.Fatalf("%s %s with auth %q: expect %d, got %d", c.method, c.path, auth, code, rec.Code)
+			}
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PathMaintenance, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("listing maintenance windows should stay public, got %d", rec.Code)
+	}
+}
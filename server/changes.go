@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// 变更订阅接口，消费位置按订阅者保存在 ServerConfig.ChangeOffsets 中，需要管理员认证。
+// 新订阅者或返回 410 的订阅者先全量同步，再调用 reset 从最新位置开始消费
+const (
+	PathChanges       = "/api/changes"        // 返回已提交位置之后的事件
+	PathChangesCommit = "/api/changes/commit" // 提交消费位置
+	PathChangesReset  = "/api/changes/reset"  // 跳到最新位置并提交
+)
+
+// 变更订阅每批事件数的默认值及上限
+const (
+	defaultChangesMax = 100
+	maxChangesMax     = 1000
+)
+
+// ChangesReply 变更订阅返回的数据，没有新事件时 Events 为空
+type ChangesReply struct {
+	Events []registry.Event `json:"events"`
+	Offset registry.Offset  `json:"offset"` // 已提交的消费位置
+}
+
+// changeOffset 订阅者已提交的位置，位置不属于当前事件日志（新订阅者或注册中心已重启）时返回 ErrEventsTruncated
+func (s *HTTPServer) changeOffset(consumer string) (registry.Offset, error) {
+	if consumer == "" {
+		return registry.Offset{}, badRequest(errors.New("consumer is required"))
+	}
+	offset, err := s.cfg.ChangeOffsets.Load(consumer)
+	if err != nil {
+		return offset, err
+	}
+	if offset.Epoch != s.reg.EventEpoch() {
+		return offset, registry.ErrEventsTruncated
+	}
+	return offset, nil
+}
+
+// changes 长轮询已提交位置之后的事件，超时没有新事件时返回空列表。不移动消费位置，处理完后需调用 commit
+func (s *HTTPServer) changes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	max := defaultChangesMax
+	if v := r.Form.Get("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, badRequest(errors.New("invalid max")))
+			return
+		}
+		max = min(n, maxChangesMax)
+	}
+	var timeout time.Duration
+	if v := r.Form.Get("timeout"); v != "" {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil {
+			writeError(w, r, badRequest(errors.New("invalid timeout")))
+			return
+		}
+	}
+	if timeout <= 0 || timeout > registry.MaxLongPollTimeout {
+		timeout = registry.DefaultLongPollTimeout
+	}
+	offset, err := s.changeOffset(r.Form.Get("consumer"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + s.cfg.WriteTimeout))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	events, err := s.reg.WaitEvents(ctx, offset.Seq, max)
+	if err != nil && ctx.Err() != nil && r.Context().Err() == nil {
+		events, err = []registry.Event{}, nil
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, ChangesReply{Events: events, Offset: offset})
+}
+
+// commitChanges 提交消费位置，seq 之前（含）的事件不会再返回
+func (s *HTTPServer) commitChanges(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	seq, err := strconv.ParseUint(r.Form.Get("seq"), 10, 64)
+	if err != nil {
+		writeError(w, r, badRequest(errors.New("invalid seq")))
+		return
+	}
+	consumer := r.Form.Get("consumer")
+	offset, err := s.changeOffset(consumer)
+	if err == nil && seq > s.reg.LastEventSeq() {
+		err = registry.ErrEventsTruncated
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if seq > offset.Seq {
+		offset.Seq = seq
+		if err := s.cfg.ChangeOffsets.Save(consumer, offset); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	writeOK(w, r, offset)
+}
+
+// resetChanges 把消费位置移到最新事件，调用前应已全量同步
+func (s *HTTPServer) resetChanges(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	consumer := r.Form.Get("consumer")
+	if consumer == "" {
+		writeError(w, r, badRequest(errors.New("consumer is required")))
+		return
+	}
+	offset := registry.Offset{Epoch: s.reg.EventEpoch(), Seq: s.reg.LastEventSeq()}
+	if err := s.cfg.ChangeOffsets.Save(consumer, offset); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, offset)
+}
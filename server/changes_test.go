@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestChanges(t *testing.T) {
+	store, err := registry.NewFileOffsetStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := registry.NewRegistry()
+	cfg := ServerConfig{Admin: &AdminConfig{Token: "s3cret"}, ChangeOffsets: store}
+	s := NewHTTPServer(r, cfg)
+	admin := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Header.Set("Authorization", "Bearer s3cret")
+		s.ServeHTTP(w, req)
+	})
+	register := func(r *registry.Registry, hostname string) {
+		r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.changes", Hostname: hostname, Addrs: []string{"http://127.0.0.1:80"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	}
+	poll := func(h http.Handler) (int, ChangesReply) {
+		code, resp := call(t, h, http.MethodGet, PathChanges+"?consumer=cmdb&timeout=10ms", nil)
+		var reply ChangesReply
+		if code == http.StatusOK {
+			json.Unmarshal(*resp.Data.(*json.RawMessage), &reply)
+		}
+		return code, reply
+	}
+
+	if code, _ := call(t, s, http.MethodGet, PathChanges+"?consumer=cmdb", nil); code != http.StatusUnauthorized {
+		t.Fatalf("changes should require admin, got %d", code)
+	}
+	// 新订阅者需要先全量同步再 reset
+	if code, _ := poll(admin); code != http.StatusGone {
+		t.Fatalf("unknown consumer should get 410, got %d", code)
+	}
+	register(r, "a")
+	if code, _ := call(t, admin, http.MethodPost, PathChangesReset, url.Values{"consumer": {"cmdb"}}); code != http.StatusOK {
+		t.Fatalf("reset failed: %d", code)
+	}
+	register(r, "b")
+	register(r, "c")
+	code, reply := poll(admin)
+	if code != http.StatusOK || len(reply.Events) != 2 || reply.Events[0].Hostname != "b" {
+		t.Fatalf("unexpected changes %d %+v", code, reply)
+	}
+	// 未提交时重复返回，提交后从提交位置继续
+	if _, again := poll(admin); len(again.Events) != 2 {
+		t.Fatalf("uncommitted events should be returned again, got %+v", again)
+	}
+	seq := strconv.FormatUint(reply.Events[0].Seq, 10)
+	if code, _ := call(t, admin, http.MethodPost, PathChangesCommit, url.Values{"consumer": {"cmdb"}, "seq": {seq}}); code != http.StatusOK {
+		t.Fatalf("commit failed: %d", code)
+	}
+	if _, reply = poll(admin); len(reply.Events) != 1 || reply.Events[0].Hostname != "c" {
+		t.Fatalf("should resume after the committed offset, got %+v", reply)
+	}
+	call(t, admin, http.MethodPost, PathChangesCommit, url.Values{"consumer": {"cmdb"}, "seq": {strconv.FormatUint(reply.Events[0].Seq, 10)}})
+	if code, reply := poll(admin); code != http.StatusOK || len(reply.Events) != 0 || reply.Offset.Seq != r.LastEventSeq() {
+		t.Fatalf("timeout without new events should return an empty batch, got %d %+v", code, reply)
+	}
+
+	// 重启后事件序号从头开始，保存的位置已失效
+	r2 := registry.NewRegistry()
+	s2 := NewHTTPServer(r2, cfg)
+	admin2 := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.Header.Set("Authorization", "Bearer s3cret")
+		s2.ServeHTTP(w, req)
+	})
+	register(r2, "d")
+	if code, _ := poll(admin2); code != http.StatusGone {
+		t.Fatalf("offset from before the restart should get 410, got %d", code)
+	}
+	if code, _ := call(t, admin2, http.MethodPost, PathChangesCommit, url.Values{"consumer": {"cmdb"}, "seq": {"1"}}); code != http.StatusGone {
+		t.Fatalf("commit of a stale offset should get 410, got %d", code)
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig 浏览器跨域访问的配置，供管理后台等网页直接调用接口
+type CORSConfig struct {
+	// AllowedOrigins 允许的来源，如 https://admin.example.com，支持 https://*.example.com 匹配子域名，
+	// "*" 允许任意来源，但只能发起不带凭证的请求。为空时不开启跨域
+	AllowedOrigins   []string
+	AllowedMethods   []string      // 默认 GET、POST
+	AllowedHeaders   []string      // 默认 Content-Type、Authorization 及本服务定义的请求头
+	ExposedHeaders   []string      // 浏览器脚本可读取的响应头，默认请求 ID、接口版本、ETag、Retry-After
+	AllowCredentials bool          // 是否允许携带 Cookie 等凭证，只对明确列出或按子域名匹配的来源生效
+	MaxAge           time.Duration // 预检结果的缓存时长，默认 10 分钟
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", HeaderRequestID, HeaderTraceparent, HeaderTracestate, HeaderClient, HeaderAPIVersion}
+	defaultCORSExposed = []string{HeaderRequestID, HeaderAPIVersion, HeaderStorage, "ETag", "Retry-After"}
+)
+
+// allowOrigin 返回 Access-Control-Allow-Origin 的取值：来源明确列出或按子域名匹配时为来源本身，
+// 只因 "*" 允许时为 "*"，不在允许列表中时为空
+func (c *CORSConfig) allowOrigin(origin string) string {
+	allowed := ""
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			allowed = "*"
+			continue
+		}
+		if o == origin {
+			return origin
+		}
+		if prefix, suffix, ok := strings.Cut(o, "*"); ok &&
+			len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return origin
+		}
+	}
+	return allowed
+}
+
+func orDefault(v, def []string) string {
+	if len(v) == 0 {
+		v = def
+	}
+	return strings.Join(v, ", ")
+}
+
+// cors 为允许的跨域请求设置响应头，处理了预检请求时返回 true
+func (s *HTTPServer) cors(w http.ResponseWriter, r *http.Request) bool {
+	c := s.cfg.CORS
+	origin := r.Header.Get("Origin")
+	if c == nil || len(c.AllowedOrigins) == 0 || origin == "" {
+		return false
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	allowed := c.allowOrigin(origin)
+	if allowed == "" {
+		if preflight {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		return preflight
+	}
+	h.Set("Access-Control-Allow-Origin", allowed)
+	// 任意来源都不能携带凭证，否则任何网页都能以用户身份调用接口
+	if c.AllowCredentials && allowed != "*" {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		h.Set("Access-Control-Expose-Headers", orDefault(c.ExposedHeaders, defaultCORSExposed))
+		return false
+	}
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", orDefault(c.AllowedMethods, defaultCORSMethods))
+	h.Set("Access-Control-Allow-Headers", orDefault(c.AllowedHeaders, defaultCORSHeaders))
+	maxAge := c.MaxAge
+	if maxAge <= 0 {
+		maxAge = 10 * time.Minute
+	}
+	h.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
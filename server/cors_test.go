@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestCORS(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{CORS: &CORSConfig{AllowedOrigins: []string{"https://*.example.com"}}})
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		return corsRequest(s, method, origin)
+	}
+
+	rec := do(http.MethodOptions, "https://admin.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST" ||
+		!strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "Authorization") {
+		t.Fatalf("unexpected preflight response %d %v", rec.Code, rec.Header())
+	}
+	rec = do(http.MethodGet, "https://admin.example.com")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") == "" || rec.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := do(http.MethodOptions, "https://evil.com"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expect preflight from unknown origin rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "https://evil.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("unknown origin should not be allowed")
+	}
+}
+
+func TestCORSAnyOriginWithoutCredentials(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{CORS: &CORSConfig{AllowedOrigins: []string{"*", "https://admin.example.com"}, AllowCredentials: true}})
+	rec := corsRequest(s, http.MethodGet, "https://evil.com")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("any origin must get a literal * without credentials: %v", rec.Header())
+	}
+	rec = corsRequest(s, http.MethodOptions, "https://admin.example.com")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "https://admin.example.com" || rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("listed origin should be allowed with credentials: %v", rec.Header())
+	}
+}
+
+func corsRequest(s http.Handler, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, PathNodes, nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// requestError 请求参数错误
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(err error) error {
+	return &requestError{err: err}
+}
+
+// StatusCode 把注册中心返回的错误映射为 HTTP 状态码
+func StatusCode(err error) int {
+	var re *requestError
+	if errors.As(err, &re) || errors.Is(err, registry.ErrInvalidCursor) || errors.Is(err, registry.ErrInvalidRequest) ||
+		errors.Is(err, registry.ErrPurgeNotConfirmed) {
+		return http.StatusBadRequest
+	}
+	if bp, ok := registry.AsBackpressure(err); ok {
+		if errors.Is(bp.Err, registry.ErrRateLimited) {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusServiceUnavailable
+	}
+	switch {
+	case errors.Is(err, registry.ErrNotModified):
+		return http.StatusNotModified
+	case errors.Is(err, registry.ErrAppNotFound),
+		errors.Is(err, registry.ErrInstanceNotFound),
+		errors.Is(err, registry.ErrNoInstances),
+		errors.Is(err, registry.ErrMaintenanceNotFound), errors.Is(err, registry.ErrTokenNotFound),
+		errors.Is(err, registry.ErrPeerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, registry.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, registry.ErrPeerRejected), errors.Is(err, registry.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, registry.ErrStaleRegistration), errors.Is(err, registry.ErrAddrConflict),
+		errors.Is(err, registry.ErrInstanceExists), errors.Is(err, registry.ErrAppFrozen):
+		return http.StatusConflict
+	case errors.Is(err, registry.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, registry.ErrEventsTruncated):
+		return http.StatusGone
+	case errors.Is(err, registry.ErrReadOnly):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	recordError(r, err)
+	code := StatusCode(err)
+	if bp, ok := registry.AsBackpressure(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(bp.RetryAfter.Seconds()))))
+	}
+	if code == http.StatusNotModified {
+		setQuotaWarnings(w, r)
+		w.WriteHeader(code)
+		return
+	}
+	if code == http.StatusInternalServerError {
+		log.Printf("%s %s failed: %v", r.Method, r.URL.Path, err)
+	}
+	resp := Response{Message: err.Error(), Error: errorInfo(err)}
+	var ie *registry.InvalidRequestError
+	if errors.As(err, &ie) {
+		resp.Data = ie
+	}
+	writeJSON(w, r, code, resp)
+}
+
+// errorInfo 在注册中心的错误码之外，把请求参数错误映射为 INVALID_ARGUMENT
+func errorInfo(err error) *registry.ErrorInfo {
+	info := registry.ErrorInfoOf(err)
+	var re *requestError
+	if errors.As(err, &re) || errors.Is(err, registry.ErrInvalidCursor) {
+		info.Code, info.Retryable = registry.CodeInvalidArgument, false
+	}
+	return info
+}
+
+func writeOK(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, r, http.StatusOK, Response{Data: data})
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, resp Response) {
+	resp.Code = code
+	resp.RequestID = registry.RequestIDFromContext(r.Context())
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if resp.RequestID != "" {
+		w.Header().Set(HeaderRequestID, resp.RequestID)
+	}
+	setQuotaWarnings(w, r)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// setQuotaWarnings 把本次请求触发的配额预警写入响应头
+func setQuotaWarnings(w http.ResponseWriter, r *http.Request) {
+	for _, qw := range registry.QuotaWarningsFromContext(r.Context()) {
+		w.Header().Add(HeaderQuotaWarning, qw.String())
+	}
+}
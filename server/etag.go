@@ -0,0 +1,65 @@
+package server
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// fetch 与 poll 的 ETag 为 "<latestTimestamp>-<variant>"，variant 区分查询参数、编码、接口版本和实例结构不同的表示。
+// If-None-Match 匹配时等同于带上该 latest_timestamp，应用没有变化则返回 304
+
+// fetchVariant 计算请求的表示标识，latest_timestamp、timeout 不影响返回内容，不参与计算
+func fetchVariant(w http.ResponseWriter, r *http.Request, schema string) string {
+	q := r.URL.Query()
+	q.Del("latest_timestamp")
+	q.Del("timeout")
+	h := fnv.New32a()
+	h.Write([]byte(q.Encode()))
+	if acceptsProtobuf(r) {
+		h.Write([]byte("|pb"))
+	} else if schema == registry.InstanceSchemaV2 {
+		h.Write([]byte("|is2"))
+	}
+	if _, ok := w.(*v2Writer); ok {
+		h.Write([]byte("|v2"))
+	}
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+func fetchETag(latestTimestamp int64, variant string) string {
+	return `"` + strconv.FormatInt(latestTimestamp, 10) + "-" + variant + `"`
+}
+
+// ifNoneMatch 返回 If-None-Match 中与 variant 匹配的最大 latestTimestamp，没有时返回 0
+func ifNoneMatch(r *http.Request, variant string) int64 {
+	var latest int64
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(tag), "W/"), `"`)
+		ts, v, ok := strings.Cut(tag, "-")
+		if !ok || v != variant {
+			continue
+		}
+		if n, err := strconv.ParseInt(ts, 10, 64); err == nil && n > latest {
+			latest = n
+		}
+	}
+	return latest
+}
+
+// conditionalFetch 按 If-None-Match 调整 q.latestTimestamp，匹配时先设置 ETag 以便 304 响应携带
+func conditionalFetch(w http.ResponseWriter, r *http.Request, q *fetchQuery, schema string) string {
+	variant := fetchVariant(w, r, schema)
+	if ts := ifNoneMatch(r, variant); ts > 0 {
+		if ts > q.latestTimestamp {
+			q.latestTimestamp = ts
+		}
+		w.Header().Set("ETag", fetchETag(ts, variant))
+	}
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", HeaderInstanceSchema)
+	return variant
+}
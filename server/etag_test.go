@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestFetchETag(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	reg := url.Values{"env": {"test"}, "appid": {"com.xx.etag"}, "hostname": {"h1"}, "addrs": {"http://127.0.0.1:80"}}
+	call(t, s, http.MethodPost, PathRegister, reg)
+
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	path := PathFetch + "?env=test&appid=com.xx.etag"
+	rec := get(path, "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expect etag, got %d %q", rec.Code, etag)
+	}
+	if rec := get(path, etag); rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != etag {
+		t.Fatalf("expect 304 with etag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	// 查询参数不同的表示不共用 ETag
+	if rec := get(path+"&status=3", etag); rec.Code != http.StatusOK {
+		t.Fatalf("etag of another representation should not match, got %d", rec.Code)
+	}
+
+	reg.Set("hostname", "h2")
+	call(t, s, http.MethodPost, PathRegister, reg)
+	if rec := get(path, etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expect new etag after change, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
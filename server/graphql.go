@@ -0,0 +1,760 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PathGraphQL GraphQL 查询接口
+const PathGraphQL = "/api/graphql"
+
+// GraphQLSchema 支持的查询，只支持 query 操作，不支持 fragment 和内省。Long 为 64 位整数
+const GraphQLSchema = `
+enum Status { UP DOWN }
+
+type Query {
+  apps(env: String!, appId: String): [App!]!
+  app(env: String!, appId: String!): App
+  instances(env: String!, appId: String, status: Status, zone: String, version: String, hostname: String): [Instance!]!
+  nodes: [Node!]!
+}
+
+type App {
+  env: String!
+  appId: String!
+  latestTimestamp: Long!
+  instanceCount(status: Status): Int!
+  instances(status: Status, zone: String, version: String, hostname: String): [Instance!]!
+}
+
+type Instance {
+  env: String!
+  appId: String!
+  hostname: String!
+  addrs: [String!]!
+  version: String!
+  status: Status
+  weight: Int!
+  group: String!
+  zone: String!
+  healthScore: Int!
+  metadata(key: String): JSON
+  source: Source
+  regTimestamp: Long!
+  renewTimestamp: Long!
+  latestTimestamp: Long!
+}
+
+type Source { kind: String! clientIp: String! sdkVersion: String! node: String! }
+
+type Node { id: String! addr: String! self: Boolean! status: String! role: String! mode: String! lastSeen: Long! }
+`
+
+// GraphQLRequest GraphQL 请求
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// GraphQLResponse GraphQL 响应，格式遵循 GraphQL over HTTP
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError GraphQL 错误
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (s *HTTPServer) graphql(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQL(w, r, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "invalid variables"}}})
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGraphQL(w, r, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}})
+			return
+		}
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeGraphQL(w, r, http.StatusMethodNotAllowed, GraphQLResponse{Errors: []GraphQLError{{Message: "method not allowed"}}})
+		return
+	}
+	sel, err := parseGraphQL(req.Query, req.OperationName)
+	if err != nil {
+		writeGraphQL(w, r, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}})
+		return
+	}
+	ex := &gqlExecutor{reg: s.reg, ctx: r.Context(), vars: req.Variables, apps: make(map[string]map[string][]*registry.Instance)}
+	data := ex.object(sel, nil, ex.query)
+	writeGraphQL(w, r, http.StatusOK, GraphQLResponse{Data: data, Errors: ex.errs})
+}
+
+func writeGraphQL(w http.ResponseWriter, r *http.Request, code int, resp GraphQLResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if id := registry.RequestIDFromContext(r.Context()); id != "" {
+		w.Header().Set(HeaderRequestID, id)
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// gqlObject 按查询中字段的顺序输出的对象
+type gqlObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// gqlField 查询中的一个字段
+type gqlField struct {
+	alias string
+	name  string
+	args  map[string]interface{} // 值为 string、int64、float64、bool、nil、gqlEnum、gqlVar 或 []interface{}
+	sel   []*gqlField
+}
+
+func (f *gqlField) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type gqlEnum string
+
+type gqlVar string
+
+// gqlParser 递归下降解析 GraphQL 查询文档
+type gqlParser struct {
+	src string
+	pos int
+}
+
+// parseGraphQL 解析查询文档，返回要执行的操作的选择集
+func parseGraphQL(src, operation string) ([]*gqlField, error) {
+	p := &gqlParser{src: src}
+	var ops [][]*gqlField
+	var names []string
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			break
+		}
+		var name string
+		if !p.peek('{') {
+			kw := p.name()
+			if kw == "mutation" || kw == "subscription" {
+				return nil, fmt.Errorf("%s is not supported", kw)
+			}
+			if kw != "query" {
+				return nil, p.errorf("expected query")
+			}
+			if p.skip(); !p.peek('{') && !p.peek('(') {
+				name = p.name()
+			}
+			// 变量定义只用于校验，值直接从 variables 取
+			if p.skip(); p.peek('(') {
+				if err := p.skipParens(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		sel, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, sel)
+		names = append(names, name)
+	}
+	switch {
+	case len(ops) == 0:
+		return nil, errors.New("empty query")
+	case len(ops) == 1 && operation == "":
+		return ops[0], nil
+	}
+	for i, n := range names {
+		if n != "" && n == operation {
+			return ops[i], nil
+		}
+	}
+	return nil, fmt.Errorf("operation %q not found", operation)
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skip 跳过空白、逗号和注释
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *gqlParser) peek(c byte) bool {
+	return p.pos < len(p.src) && p.src[p.pos] == c
+}
+
+func (p *gqlParser) expect(c byte) error {
+	p.skip()
+	if !p.peek(c) {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func (p *gqlParser) name() string {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *gqlParser) skipParens() error {
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				p.pos++
+				return nil
+			}
+		}
+	}
+	return p.errorf("unclosed parenthesis")
+}
+
+func (p *gqlParser) selectionSet() ([]*gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []*gqlField
+	for {
+		p.skip()
+		if p.peek('}') {
+			p.pos++
+			if len(fields) == 0 {
+				return nil, p.errorf("empty selection set")
+			}
+			return fields, nil
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+}
+
+func (p *gqlParser) field() (*gqlField, error) {
+	f := &gqlField{name: p.name()}
+	if f.name == "" {
+		return nil, p.errorf("expected field name")
+	}
+	if p.skip(); p.peek(':') {
+		p.pos++
+		f.alias, f.name = f.name, p.name()
+		if f.name == "" {
+			return nil, p.errorf("expected field name")
+		}
+	}
+	if p.skip(); p.peek('(') {
+		p.pos++
+		f.args = make(map[string]interface{})
+		for {
+			if p.skip(); p.peek(')') {
+				p.pos++
+				break
+			}
+			name := p.name()
+			if name == "" {
+				return nil, p.errorf("expected argument name")
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.args[name] = v
+		}
+	}
+	if p.skip(); p.peek('{') {
+		sel, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		f.sel = sel
+	}
+	return f, nil
+}
+
+func (p *gqlParser) value() (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected value")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '$':
+		p.pos++
+		return gqlVar(p.name()), nil
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return nil, p.errorf("unterminated string")
+		}
+		s, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return nil, p.errorf("invalid string")
+		}
+		p.pos = end + 1
+		return s, nil
+	case c == '[':
+		p.pos++
+		var list []interface{}
+		for {
+			if p.skip(); p.peek(']') {
+				p.pos++
+				return list, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		num := p.src[start:p.pos]
+		if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(num, 64); err == nil {
+			return f, nil
+		}
+		return nil, p.errorf("invalid number %q", num)
+	}
+	switch name := p.name(); name {
+	case "":
+		return nil, p.errorf("expected value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return gqlEnum(name), nil
+	}
+}
+
+// gqlExecutor 执行查询，同一请求内各环境的实例只读取一次
+type gqlExecutor struct {
+	reg  *registry.Registry
+	ctx  context.Context
+	vars map[string]interface{}
+	apps map[string]map[string][]*registry.Instance // key: env
+	errs []GraphQLError
+}
+
+type gqlResolver func(f *gqlField, path []interface{}) (interface{}, error)
+
+// object 按选择集解析对象，字段出错时该字段为 null 并记录错误
+func (ex *gqlExecutor) object(sel []*gqlField, path []interface{}, resolve gqlResolver) *gqlObject {
+	rs := &gqlObject{}
+	for _, f := range sel {
+		fpath := append(append([]interface{}(nil), path...), f.key())
+		v, err := resolve(f, fpath)
+		if err != nil {
+			ex.errs = append(ex.errs, GraphQLError{Message: err.Error(), Path: fpath})
+			v = nil
+		}
+		rs.keys = append(rs.keys, f.key())
+		rs.values = append(rs.values, v)
+	}
+	return rs
+}
+
+func (ex *gqlExecutor) list(f *gqlField, path []interface{}, n int, resolve func(i int) gqlResolver) (interface{}, error) {
+	if f.sel == nil {
+		return nil, fmt.Errorf("field %q requires a selection set", f.name)
+	}
+	rs := make([]interface{}, n)
+	for i := range rs {
+		rs[i] = ex.object(f.sel, append(path, i), resolve(i))
+	}
+	return rs, nil
+}
+
+func scalar(f *gqlField, v interface{}) (interface{}, error) {
+	if f.sel != nil {
+		return nil, fmt.Errorf("field %q must not have a selection set", f.name)
+	}
+	return v, nil
+}
+
+// arg 返回字符串或枚举参数，变量从 variables 取值
+func (ex *gqlExecutor) arg(f *gqlField, name string) (string, error) {
+	v, ok := f.args[name]
+	if vn, isVar := v.(gqlVar); isVar {
+		v, ok = ex.vars[string(vn)]
+	}
+	if !ok || v == nil {
+		return "", nil
+	}
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case gqlEnum:
+		return string(s), nil
+	}
+	return "", fmt.Errorf("argument %q must be a string", name)
+}
+
+func (ex *gqlExecutor) requiredArg(f *gqlField, name string) (string, error) {
+	s, err := ex.arg(f, name)
+	if err == nil && s == "" {
+		err = fmt.Errorf("argument %q is required", name)
+	}
+	return s, err
+}
+
+// filter 由 status、zone、version 参数生成实例过滤条件
+func (ex *gqlExecutor) filter(f *gqlField) (registry.WatchFilter, string, error) {
+	var wf registry.WatchFilter
+	status, err := ex.arg(f, "status")
+	if err != nil {
+		return wf, "", err
+	}
+	switch status {
+	case "":
+	case "UP":
+		wf.Status = registry.StatusUP
+	case "DOWN":
+		wf.Status = registry.StatusDown
+	default:
+		return wf, "", fmt.Errorf("invalid status %q", status)
+	}
+	if wf.Zone, err = ex.arg(f, "zone"); err != nil {
+		return wf, "", err
+	}
+	if wf.Version, err = ex.arg(f, "version"); err != nil {
+		return wf, "", err
+	}
+	if err := wf.Validate(); err != nil {
+		return wf, "", err
+	}
+	hostname, err := ex.arg(f, "hostname")
+	return wf, hostname, err
+}
+
+func (ex *gqlExecutor) env(env string) (map[string][]*registry.Instance, error) {
+	if apps, ok := ex.apps[env]; ok {
+		return apps, nil
+	}
+	apps, err := ex.reg.FetchAllContext(ex.ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	ex.apps[env] = apps
+	return apps, nil
+}
+
+func (ex *gqlExecutor) query(f *gqlField, path []interface{}) (interface{}, error) {
+	switch f.name {
+	case "__typename":
+		return scalar(f, "Query")
+	case "nodes":
+		nodes := ex.reg.Nodes()
+		return ex.list(f, path, len(nodes), func(i int) gqlResolver {
+			return nodeResolver(&nodes[i])
+		})
+	}
+	env, err := ex.requiredArg(f, "env")
+	if err != nil {
+		return nil, err
+	}
+	appid, err := ex.arg(f, "appId")
+	if err != nil {
+		return nil, err
+	}
+	apps, err := ex.env(env)
+	if err != nil {
+		return nil, err
+	}
+	switch f.name {
+	case "app":
+		if appid == "" {
+			return nil, errors.New(`argument "appId" is required`)
+		}
+		instances, ok := apps[appid]
+		if !ok {
+			return nil, nil
+		}
+		if f.sel == nil {
+			return nil, fmt.Errorf("field %q requires a selection set", f.name)
+		}
+		return ex.object(f.sel, path, ex.appResolver(env, appid, instances)), nil
+	case "apps":
+		ids := make([]string, 0, len(apps))
+		for id := range apps {
+			if appid == "" || id == appid {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		return ex.list(f, path, len(ids), func(i int) gqlResolver {
+			return ex.appResolver(env, ids[i], apps[ids[i]])
+		})
+	case "instances":
+		var all []*registry.Instance
+		for id, instances := range apps {
+			if appid == "" || id == appid {
+				all = append(all, instances...)
+			}
+		}
+		return ex.instances(f, path, all)
+	}
+	return nil, fmt.Errorf("unknown field %q on Query", f.name)
+}
+
+// instances 过滤并按 appId、hostname 排序
+func (ex *gqlExecutor) instances(f *gqlField, path []interface{}, all []*registry.Instance) (interface{}, error) {
+	wf, hostname, err := ex.filter(f)
+	if err != nil {
+		return nil, err
+	}
+	var rs []*registry.Instance
+	for _, in := range all {
+		if wf.Match(in) && (hostname == "" || in.Hostname == hostname) {
+			rs = append(rs, in)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].AppId != rs[j].AppId {
+			return rs[i].AppId < rs[j].AppId
+		}
+		return rs[i].Hostname < rs[j].Hostname
+	})
+	return ex.list(f, path, len(rs), func(i int) gqlResolver {
+		return ex.instanceResolver(rs[i])
+	})
+}
+
+func (ex *gqlExecutor) appResolver(env, appid string, instances []*registry.Instance) gqlResolver {
+	return func(f *gqlField, path []interface{}) (interface{}, error) {
+		switch f.name {
+		case "__typename":
+			return scalar(f, "App")
+		case "env":
+			return scalar(f, env)
+		case "appId":
+			return scalar(f, appid)
+		case "latestTimestamp":
+			var latest int64
+			for _, in := range instances {
+				if in.LatestTimestamp > latest {
+					latest = in.LatestTimestamp
+				}
+			}
+			return scalar(f, latest)
+		case "instanceCount":
+			wf, _, err := ex.filter(f)
+			if err != nil {
+				return nil, err
+			}
+			n := 0
+			for _, in := range instances {
+				if wf.Match(in) {
+					n++
+				}
+			}
+			return scalar(f, n)
+		case "instances":
+			return ex.instances(f, path, instances)
+		}
+		return nil, fmt.Errorf("unknown field %q on App", f.name)
+	}
+}
+
+func statusName(status uint32) interface{} {
+	switch status {
+	case registry.StatusUP:
+		return "UP"
+	case registry.StatusDown:
+		return "DOWN"
+	}
+	return nil
+}
+
+func (ex *gqlExecutor) instanceResolver(in *registry.Instance) gqlResolver {
+	return func(f *gqlField, path []interface{}) (interface{}, error) {
+		switch f.name {
+		case "__typename":
+			return scalar(f, "Instance")
+		case "env":
+			return scalar(f, in.Env)
+		case "appId":
+			return scalar(f, in.AppId)
+		case "hostname":
+			return scalar(f, in.Hostname)
+		case "addrs":
+			addrs := in.Addrs
+			if addrs == nil {
+				addrs = []string{}
+			}
+			return scalar(f, addrs)
+		case "version":
+			return scalar(f, in.Version)
+		case "status":
+			return scalar(f, statusName(in.Status))
+		case "weight":
+			return scalar(f, in.Weight)
+		case "group":
+			return scalar(f, in.Group)
+		case "zone":
+			return scalar(f, in.Metadata[registry.MetadataZone])
+		case "healthScore":
+			return scalar(f, in.HealthScore)
+		case "metadata":
+			key, err := ex.arg(f, "key")
+			if err != nil {
+				return nil, err
+			}
+			if key != "" {
+				v, ok := in.Metadata[key]
+				if !ok {
+					return scalar(f, nil)
+				}
+				return scalar(f, v)
+			}
+			return scalar(f, in.Metadata)
+		case "source":
+			if in.Source == nil {
+				return nil, nil
+			}
+			if f.sel == nil {
+				return nil, fmt.Errorf("field %q requires a selection set", f.name)
+			}
+			return ex.object(f.sel, path, sourceResolver(in.Source)), nil
+		case "regTimestamp":
+			return scalar(f, in.RegTimestamp)
+		case "renewTimestamp":
+			return scalar(f, in.RenewTimestamp)
+		case "latestTimestamp":
+			return scalar(f, in.LatestTimestamp)
+		}
+		return nil, fmt.Errorf("unknown field %q on Instance", f.name)
+	}
+}
+
+func sourceResolver(src *registry.RegistrationSource) gqlResolver {
+	return func(f *gqlField, path []interface{}) (interface{}, error) {
+		switch f.name {
+		case "__typename":
+			return scalar(f, "Source")
+		case "kind":
+			return scalar(f, src.Kind)
+		case "clientIp":
+			return scalar(f, src.ClientIP)
+		case "sdkVersion":
+			return scalar(f, src.SDKVersion)
+		case "node":
+			return scalar(f, src.Node)
+		}
+		return nil, fmt.Errorf("unknown field %q on Source", f.name)
+	}
+}
+
+func nodeResolver(n *registry.Node) gqlResolver {
+	return func(f *gqlField, path []interface{}) (interface{}, error) {
+		switch f.name {
+		case "__typename":
+			return scalar(f, "Node")
+		case "id":
+			return scalar(f, n.ID)
+		case "addr":
+			return scalar(f, n.Addr)
+		case "self":
+			return scalar(f, n.Self)
+		case "status":
+			return scalar(f, n.Status)
+		case "role":
+			return scalar(f, n.Role)
+		case "mode":
+			return scalar(f, n.Mode)
+		case "lastSeen":
+			return scalar(f, n.LastSeen)
+		}
+		return nil, fmt.Errorf("unknown field %q on Node", f.name)
+	}
+}
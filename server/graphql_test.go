@@ -0,0 +1,55 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func graphql(t *testing.T, h http.Handler, req GraphQLRequest) (int, string) {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PathGraphQL, bytes.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
+
+func TestGraphQL(t *testing.T) {
+	reg := registry.NewRegistry()
+	defer reg.Close()
+	s := NewHTTPServer(reg, ServerConfig{})
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "a1", Version: "v1.0.0", Status: registry.StatusUP, Metadata: map[string]string{"zone": "z1"}}, 1)
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "a2", Version: "v2.0.0", Status: registry.StatusUP}, 1)
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "a3", Version: "v2.1.0", Status: registry.StatusDown}, 1)
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.b", Hostname: "b1", Version: "v1.0.0", Status: registry.StatusUP}, 1)
+
+	code, body := graphql(t, s, GraphQLRequest{Query: `{ instances(env: "test", appId: "com.xx.a", status: UP, version: ">=2.0.0") { hostname status } }`})
+	if want := `{"data":{"instances":[{"hostname":"a2","status":"UP"}]}}` + "\n"; code != http.StatusOK || body != want {
+		t.Fatalf("unexpected response %d %s", code, body)
+	}
+
+	q := `query Portal($env: String!) {
+		apps(env: $env) { appId up: instanceCount(status: UP) instances(zone: "z1") { hostname zone: metadata(key: "zone") } }
+		missing: app(env: $env, appId: "com.xx.none") { appId }
+	}`
+	code, body = graphql(t, s, GraphQLRequest{Query: q, Variables: map[string]interface{}{"env": "test"}, OperationName: "Portal"})
+	want := `{"data":{"apps":[{"appId":"com.xx.a","up":2,"instances":[{"hostname":"a1","zone":"z1"}]},{"appId":"com.xx.b","up":1,"instances":[]}],"missing":null}}` + "\n"
+	if code != http.StatusOK || body != want {
+		t.Fatalf("unexpected response %d %s", code, body)
+	}
+
+	code, body = graphql(t, s, GraphQLRequest{Query: `{ app(env: "test", appId: "com.xx.a") { appId bogus } }`})
+	if code != http.StatusOK || !bytes.Contains([]byte(body), []byte(`"path":["app","bogus"]`)) {
+		t.Fatalf("expect field error, got %d %s", code, body)
+	}
+	if code, _ := graphql(t, s, GraphQLRequest{Query: `{ apps(env: "test") `}); code != http.StatusBadRequest {
+		t.Fatalf("expect 400 for syntax error, got %d", code)
+	}
+	if code, _ := graphql(t, s, GraphQLRequest{Query: `mutation { x }`}); code != http.StatusBadRequest {
+		t.Fatalf("expect 400 for mutation, got %d", code)
+	}
+}
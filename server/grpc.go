@@ -0,0 +1,437 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	registry "github.com/junaozun/registry-center"
+	registrypb "github.com/junaozun/registry-center/proto"
+)
+
+// GRPCServer 注册中心的 gRPC 接口，定义见 proto/registry.proto。调用经过 ServerConfig 中的中间件，
+// 请求 ID、请求来源、调用方身份及只读令牌的处理与 HTTP 接口一致，对应的 HTTP 请求头以 metadata 传递
+type GRPCServer struct {
+	registrypb.UnimplementedRegistryServiceServer
+	s *HTTPServer
+}
+
+// NewGRPCServer 返回与 s 共用注册中心及中间件的 gRPC 接口，
+// 使用 registrypb.RegisterRegistryServiceServer 注册到 grpc.Server
+func NewGRPCServer(s *HTTPServer) *GRPCServer {
+	return &GRPCServer{s: s}
+}
+
+// grpcTokenMethods 出示只读令牌的调用可以访问的方法，其余方法返回 PermissionDenied
+var grpcTokenMethods = map[string]bool{
+	registrypb.RegistryService_Fetch_FullMethodName: true,
+	registrypb.RegistryService_Poll_FullMethodName:  true,
+}
+
+// grpcCodes HTTP 状态码对应的 gRPC 状态码，未列出的为 Internal
+var grpcCodes = map[int]codes.Code{
+	http.StatusNotModified:        codes.FailedPrecondition,
+	http.StatusBadRequest:         codes.InvalidArgument,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.NotFound,
+	http.StatusConflict:           codes.Aborted,
+	http.StatusGone:               codes.OutOfRange,
+	http.StatusTooManyRequests:    codes.ResourceExhausted,
+	http.StatusServiceUnavailable: codes.Unavailable,
+	http.StatusGatewayTimeout:     codes.DeadlineExceeded,
+}
+
+// GRPCCode 把注册中心返回的错误映射为 gRPC 状态码，与 StatusCode 的 HTTP 映射一一对应
+func GRPCCode(err error) codes.Code {
+	if errors.Is(err, context.Canceled) {
+		return codes.Canceled
+	}
+	if code, ok := grpcCodes[StatusCode(err)]; ok {
+		return code
+	}
+	return codes.Internal
+}
+
+// grpcError 把错误转换为 gRPC 状态，机器可读的 ErrorInfo 放在状态的 details 中
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	st := status.New(GRPCCode(err), err.Error())
+	info := errorInfo(err)
+	if ds, derr := st.WithDetails(&registrypb.ErrorInfo{Code: string(info.Code), Message: info.Message,
+		Retryable: info.Retryable, Details: info.Details}); derr == nil {
+		st = ds
+	}
+	return st.Err()
+}
+
+// callContext 为一次调用设置请求 ID、请求来源、调用方身份、追踪上下文及只读令牌
+func (g *GRPCServer) callContext(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	id := get(HeaderRequestID)
+	if id == "" {
+		id = registry.NewRequestID()
+	}
+	ctx = registry.ContextWithRequestID(ctx, id)
+	grpc.SetHeader(ctx, metadata.Pairs(HeaderRequestID, id))
+	src := registry.RegistrationSource{Kind: registry.SourceDirect, SDKVersion: get(HeaderClient)}
+	if get(HeaderAgent) == "true" {
+		src.Kind = registry.SourceAgent
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			src.ClientIP = host
+		}
+	}
+	ctx = registry.ContextWithSource(ctx, src)
+	ctx = registry.ContextWithCaller(ctx, registry.SpiffeIDFromCertificates(peerCertificates(ctx)))
+	if tp := get(HeaderTraceparent); tp != "" {
+		if tc, err := registry.ParseTraceparent(tp, get(HeaderTracestate)); err == nil {
+			ctx = registry.ContextWithTrace(ctx, tc)
+		}
+	}
+	if token, ok := strings.CutPrefix(get("authorization"), "Bearer "); ok && strings.HasPrefix(token, registry.TokenPrefix) {
+		t, err := g.s.reg.VerifyToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if !grpcTokenMethods[method] {
+			return nil, registry.ErrPermissionDenied
+		}
+		ctx = registry.ContextWithToken(ctx, t)
+	}
+	return ctx, nil
+}
+
+// peerCertificates 返回 TLS 连接上已校验的客户端证书链
+func peerCertificates(ctx context.Context) []*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		return info.State.PeerCertificates
+	}
+	return nil
+}
+
+// call 经过中间件执行 h，错误转换为 gRPC 状态
+func (g *GRPCServer) call(ctx context.Context, method, env, appid string, h func(ctx context.Context) error) error {
+	ctx, err := g.callContext(ctx, method)
+	if err == nil {
+		handler := func(ctx context.Context, call *registry.CallInfo) error { return h(ctx) }
+		if g.s.middleware != nil {
+			handler = g.s.middleware(handler)
+		}
+		err = handler(ctx, &registry.CallInfo{Protocol: registry.ProtocolGRPC, Operation: method, Env: env, AppId: appid})
+	}
+	return grpcError(err)
+}
+
+// Register 服务注册，status 为 0 时按可用注册
+func (g *GRPCServer) Register(ctx context.Context, req *registrypb.RegisterRequest) (*registrypb.RegisterReply, error) {
+	var reply *registrypb.RegisterReply
+	err := g.call(ctx, registrypb.RegistryService_Register_FullMethodName, req.GetEnv(), req.GetAppId(), func(ctx context.Context) error {
+		rr := requestRegister(req)
+		if err := rr.Validate(); err != nil {
+			return err
+		}
+		in := registry.NewInstance(rr)
+		if rr.Status == 0 {
+			in.Status = registry.StatusUP
+		}
+		ts := rr.LatestTimestamp
+		if ts == 0 {
+			ts = time.Now().UnixNano()
+		}
+		if _, err := g.s.reg.RegisterWithPeerCertificatesContext(ctx, in, ts, peerCertificates(ctx)); err != nil {
+			return err
+		}
+		reply = &registrypb.RegisterReply{Instance: pbInstance(in)}
+		if cred, ok := g.s.reg.HeartbeatCredential(in.Env, in.AppId, in.Hostname); ok {
+			reply.Credential = pbCredential(cred)
+		}
+		return nil
+	})
+	return reply, err
+}
+
+// Renew 服务续约，携带 secret 时校验凭证
+func (g *GRPCServer) Renew(ctx context.Context, req *registrypb.RenewRequest) (*registrypb.RenewReply, error) {
+	var reply *registrypb.RenewReply
+	err := g.call(ctx, registrypb.RegistryService_Renew_FullMethodName, req.GetEnv(), req.GetAppId(), func(ctx context.Context) error {
+		if err := requireInstance(req.GetEnv(), req.GetAppId(), req.GetHostname()); err != nil {
+			return err
+		}
+		if c := req.GetCapacity(); c != nil {
+			if c.MaxConcurrent < 0 || c.InFlight < 0 || c.Utilization < 0 {
+				return badRequest(errors.New("invalid capacity"))
+			}
+			ctx = registry.ContextWithCapacity(ctx, registry.Capacity{MaxConcurrent: c.MaxConcurrent, InFlight: c.InFlight, Utilization: c.Utilization})
+		}
+		var (
+			in   *registry.Instance
+			cred *registry.HeartbeatCredential
+			err  error
+		)
+		if req.GetSecret() != "" {
+			in, cred, err = g.s.reg.RenewWithCredentialContext(ctx, req.GetEnv(), req.GetAppId(), req.GetHostname(), req.GetSecret())
+		} else {
+			in, err = g.s.reg.RenewContext(ctx, req.GetEnv(), req.GetAppId(), req.GetHostname())
+		}
+		if err != nil {
+			return err
+		}
+		reply = &registrypb.RenewReply{Instance: pbInstance(in), Credential: pbCredential(cred)}
+		return nil
+	})
+	return reply, err
+}
+
+// Cancel 服务下线
+func (g *GRPCServer) Cancel(ctx context.Context, req *registrypb.CancelRequest) (*registrypb.CancelReply, error) {
+	var reply *registrypb.CancelReply
+	err := g.call(ctx, registrypb.RegistryService_Cancel_FullMethodName, req.GetEnv(), req.GetAppId(), func(ctx context.Context) error {
+		if err := requireInstance(req.GetEnv(), req.GetAppId(), req.GetHostname()); err != nil {
+			return err
+		}
+		in, err := g.s.reg.CancelContext(ctx, req.GetEnv(), req.GetAppId(), req.GetHostname(), req.GetLatestTimestamp())
+		if err != nil {
+			return err
+		}
+		reply = &registrypb.CancelReply{Instance: pbInstance(in)}
+		return nil
+	})
+	return reply, err
+}
+
+// Fetch 服务获取，数据没有变化时返回 FailedPrecondition
+func (g *GRPCServer) Fetch(ctx context.Context, req *registrypb.FetchRequest) (*registrypb.FetchData, error) {
+	var reply *registrypb.FetchData
+	err := g.call(ctx, registrypb.RegistryService_Fetch_FullMethodName, req.GetEnv(), req.GetAppId(), func(ctx context.Context) error {
+		q, err := fetchRequest(req)
+		if err != nil {
+			return err
+		}
+		data, err := g.s.reg.FetchContext(ctx, q.env, q.appid, q.status, q.latestTimestamp, q.opts)
+		if err != nil {
+			return err
+		}
+		reply, err = pbFetchData(data)
+		return err
+	})
+	return reply, err
+}
+
+// Poll 长轮询获取服务，timeout_ms 不合法时使用默认等待时长，数据没有变化时返回 FailedPrecondition
+func (g *GRPCServer) Poll(ctx context.Context, req *registrypb.PollRequest) (*registrypb.FetchData, error) {
+	var reply *registrypb.FetchData
+	f := req.GetFetch()
+	err := g.call(ctx, registrypb.RegistryService_Poll_FullMethodName, f.GetEnv(), f.GetAppId(), func(ctx context.Context) error {
+		q, err := fetchRequest(f)
+		if err != nil {
+			return err
+		}
+		timeout := time.Duration(req.GetTimeoutMs()) * time.Millisecond
+		if timeout <= 0 || timeout > registry.MaxLongPollTimeout {
+			timeout = registry.DefaultLongPollTimeout
+		}
+		data, err := g.s.reg.PollContext(ctx, q.env, q.appid, q.status, q.latestTimestamp, timeout, q.opts)
+		if err != nil {
+			return err
+		}
+		reply, err = pbFetchData(data)
+		return err
+	})
+	return reply, err
+}
+
+func requireInstance(env, appid, hostname string) error {
+	if env == "" || appid == "" || hostname == "" {
+		return badRequest(errors.New("env, app_id and hostname are required"))
+	}
+	return nil
+}
+
+func requestRegister(req *registrypb.RegisterRequest) *registry.RequestRegister {
+	return &registry.RequestRegister{
+		Env:             req.GetEnv(),
+		AppId:           req.GetAppId(),
+		Hostname:        req.GetHostname(),
+		Addrs:           req.GetAddrs(),
+		SecureAddrs:     req.GetSecureAddrs(),
+		Status:          req.GetStatus(),
+		Weight:          req.GetWeight(),
+		Version:         req.GetVersion(),
+		Group:           req.GetGroup(),
+		SpiffeID:        req.GetSpiffeId(),
+		InstanceID:      req.GetInstanceId(),
+		PortNames:       req.GetPortNames(),
+		Metadata:        req.GetMetadata(),
+		LatestTimestamp: req.GetLatestTimestamp(),
+		DirtyTimestamp:  req.GetDirtyTimestamp(),
+	}
+}
+
+func fetchRequest(req *registrypb.FetchRequest) (*fetchQuery, error) {
+	q := &fetchQuery{env: req.GetEnv(), appid: req.GetAppId(), status: req.GetStatus(), latestTimestamp: req.GetLatestTimestamp()}
+	if q.env == "" || q.appid == "" {
+		return nil, badRequest(errors.New("env and app_id are required"))
+	}
+	if q.status == 0 {
+		q.status = registry.StatusUP
+	}
+	q.opts = registry.FetchOptions{
+		ClientKey:      req.GetClientKey(),
+		Scheme:         req.GetScheme(),
+		SecureOnly:     req.GetSecureOnly(),
+		Consumer:       req.GetConsumer(),
+		MinHealthScore: int(req.GetMinHealthScore()),
+		Limit:          int(req.GetLimit()),
+		Cursor:         req.GetCursor(),
+	}
+	return q, nil
+}
+
+// pbFetchData FetchData 的 protobuf 编码与 proto 定义一致，热点应用直接复用预先序列化的结果
+func pbFetchData(data *registry.FetchData) (*registrypb.FetchData, error) {
+	var d registrypb.FetchData
+	if err := proto.Unmarshal(registry.MarshalFetchData(data), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func pbCredential(c *registry.HeartbeatCredential) *registrypb.HeartbeatCredential {
+	if c == nil {
+		return nil
+	}
+	return &registrypb.HeartbeatCredential{Secret: c.Secret, IssuedAt: c.IssuedAt}
+}
+
+func pbInstance(in *registry.Instance) *registrypb.Instance {
+	if in == nil {
+		return nil
+	}
+	p := &registrypb.Instance{
+		Env:             in.Env,
+		AppId:           in.AppId,
+		Hostname:        in.Hostname,
+		Addrs:           in.Addrs,
+		Version:         in.Version,
+		Status:          in.Status,
+		Weight:          in.Weight,
+		Group:           in.Group,
+		HealthScore:     int32(in.HealthScore),
+		Metadata:        in.Metadata,
+		SpiffeId:        in.SpiffeID,
+		SpiffeVerified:  in.SpiffeVerified,
+		RegTimestamp:    in.RegTimestamp,
+		UpTimestamp:     in.UpTimestamp,
+		RenewTimestamp:  in.RenewTimestamp,
+		DirtyTimestamp:  in.DirtyTimestamp,
+		LatestTimestamp: in.LatestTimestamp,
+		InstanceId:      in.InstanceID,
+	}
+	for _, ep := range in.Endpoints {
+		p.Endpoints = append(p.Endpoints, &registrypb.Endpoint{Scheme: ep.Scheme, Addr: ep.Addr, Secure: ep.Secure, Name: ep.Name})
+	}
+	if !in.Clock.IsZero() {
+		p.Clock = &registrypb.HLC{WallTime: in.Clock.WallTime, Logical: in.Clock.Logical, Node: in.Clock.Node}
+	}
+	p.Source = pbSource(in.Source)
+	return p
+}
+
+func pbSource(src *registry.RegistrationSource) *registrypb.RegistrationSource {
+	if src == nil {
+		return nil
+	}
+	return &registrypb.RegistrationSource{Kind: src.Kind, ClientIp: src.ClientIP, SdkVersion: src.SDKVersion, Node: src.Node}
+}
+
+// Session 会话双向流，协议与 TCP 会话一致，每条消息经过中间件，见 HTTPServer.ServeSessionStream。
+// 流结束后会话进入宽限期，客户端应在宽限期内重连并发送 resume
+func (g *GRPCServer) Session(stream registrypb.RegistryService_SessionServer) error {
+	ctx, err := g.callContext(stream.Context(), registrypb.RegistryService_Session_FullMethodName)
+	if err != nil {
+		return grpcError(err)
+	}
+	ctx = registry.ContextWithPeerCertificates(ctx, peerCertificates(stream.Context()))
+	g.s.ServeSessionStream(ctx, &grpcSessionStream{stream: stream})
+	return nil
+}
+
+// grpcSessionStream 把 gRPC 双向流适配为 registry.SessionStream，由会话保证 Send 串行
+type grpcSessionStream struct {
+	stream registrypb.RegistryService_SessionServer
+}
+
+func (s *grpcSessionStream) Recv() (registry.SessionMessage, error) {
+	m, err := s.stream.Recv()
+	if err != nil {
+		return registry.SessionMessage{}, err
+	}
+	msg := registry.SessionMessage{Op: m.GetOp(), RequestID: m.GetRequestId(), SessionID: m.GetSessionId(),
+		Env: m.GetEnv(), AppId: m.GetAppId(), Hostname: m.GetHostname(), Status: m.GetStatus()}
+	if m.Register != nil {
+		msg.Register = requestRegister(m.Register)
+	}
+	if f := m.GetFilter(); f != nil {
+		msg.Filter = &registry.WatchFilter{Status: f.GetStatus(), Zone: f.GetZone(), Labels: f.GetLabels(), Version: f.GetVersion()}
+	}
+	return msg, nil
+}
+
+func (s *grpcSessionStream) Send(frame interface{}) error {
+	var f registrypb.SessionFrame
+	switch v := frame.(type) {
+	case registry.SessionReply:
+		rep := &registrypb.SessionReply{Op: v.Op, RequestId: v.RequestID, SessionId: v.SessionID, Error: v.Error}
+		if info := v.ErrorInfo; info != nil {
+			rep.ErrorInfo = &registrypb.ErrorInfo{Code: string(info.Code), Message: info.Message, Retryable: info.Retryable, Details: info.Details}
+		}
+		f.Frame = &registrypb.SessionFrame_Reply{Reply: rep}
+	case registry.SessionPush:
+		switch v.Type {
+		case registry.SessionFrameLease:
+			f.Frame = &registrypb.SessionFrame_Lease{Lease: &registrypb.LeaseDirective{ServerRenewed: v.Lease.ServerRenewed, GraceMs: v.Lease.Grace.Milliseconds()}}
+		case registry.SessionFrameEvent:
+			f.Frame = &registrypb.SessionFrame_Event{Event: pbEvent(v.Event)}
+		case registry.SessionFrameReset:
+			f.Frame = &registrypb.SessionFrame_Reset_{Reset_: true}
+		default:
+			return fmt.Errorf("unknown session push %q", v.Type)
+		}
+	default:
+		return fmt.Errorf("unknown session frame %T", frame)
+	}
+	return s.stream.Send(&f)
+}
+
+func pbEvent(e *registry.Event) *registrypb.Event {
+	return &registrypb.Event{Seq: e.Seq, Type: string(e.Type), Env: e.Env, AppId: e.AppId, Hostname: e.Hostname,
+		Timestamp: e.Timestamp, Instance: pbInstance(e.Instance), RequestId: e.RequestID, Created: e.Created, Zone: e.Zone,
+		Source: pbSource(e.Source)}
+}
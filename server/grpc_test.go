@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	registry "github.com/junaozun/registry-center"
+	registrypb "github.com/junaozun/registry-center/proto"
+)
+
+// dialGRPC 在内存连接上启动 gRPC 接口并返回客户端
+func dialGRPC(t *testing.T, s *HTTPServer) registrypb.RegistryServiceClient {
+	t.Helper()
+	ln := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	registrypb.RegisterRegistryServiceServer(g, NewGRPCServer(s))
+	go g.Serve(ln)
+	conn, err := grpc.NewClient("passthrough:///bufconn", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		g.Stop()
+	})
+	return registrypb.NewRegistryServiceClient(conn)
+}
+
+func TestGRPCServer(t *testing.T) {
+	var calls []string
+	cfg := ServerConfig{}
+	cfg.Use(func(next registry.Handler) registry.Handler {
+		return func(ctx context.Context, call *registry.CallInfo) error {
+			if call.Protocol == registry.ProtocolGRPC {
+				calls = append(calls, call.Operation)
+			}
+			return next(ctx, call)
+		}
+	})
+	s := NewHTTPServer(registry.NewRegistry(registry.WithHeartbeatCredentials(time.Hour, time.Minute)), cfg)
+	c := dialGRPC(t, s)
+	ctx := context.Background()
+
+	reg, err := c.Register(ctx, &registrypb.RegisterRequest{Env: "test", AppId: "com.xx.grpc", Hostname: "h1", Addrs: []string{"grpc://127.0.0.1:9000"}})
+	if err != nil || reg.GetInstance().GetStatus() != registry.StatusUP || reg.GetCredential().GetSecret() == "" {
+		t.Fatalf("register failed: %v %v", reg, err)
+	}
+	if _, err := c.Renew(ctx, &registrypb.RenewRequest{Env: "test", AppId: "com.xx.grpc", Hostname: "h1", Secret: reg.GetCredential().GetSecret()}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.Fetch(ctx, &registrypb.FetchRequest{Env: "test", AppId: "com.xx.grpc"})
+	if err != nil || len(data.GetInstances()) != 1 || data.GetInstances()[0].GetEndpoints()[0].GetScheme() != "grpc" {
+		t.Fatalf("fetch failed: %v %v", data, err)
+	}
+	_, err = c.Fetch(ctx, &registrypb.FetchRequest{Env: "test", AppId: "com.xx.grpc", LatestTimestamp: data.GetLatestTimestamp()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("unchanged fetch should be FailedPrecondition, got %v", err)
+	}
+	_, err = c.Poll(ctx, &registrypb.PollRequest{Fetch: &registrypb.FetchRequest{Env: "test", AppId: "com.xx.grpc", LatestTimestamp: data.GetLatestTimestamp()}, TimeoutMs: 10})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("poll timeout should be FailedPrecondition, got %v", err)
+	}
+
+	_, err = c.Renew(ctx, &registrypb.RenewRequest{Env: "test", AppId: "com.xx.grpc", Hostname: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("renew of a missing instance should be NotFound, got %v", err)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 || details[0].(*registrypb.ErrorInfo).GetCode() != string(registry.CodeInstanceNotFound) {
+		t.Fatalf("status should carry the error info, got %v", details)
+	}
+	_, err = c.Register(ctx, &registrypb.RegisterRequest{Env: "test", AppId: "com.xx.grpc", Hostname: "h2"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("register without addrs should be InvalidArgument, got %v", err)
+	}
+
+	if _, err := c.Cancel(ctx, &registrypb.CancelRequest{Env: "test", AppId: "com.xx.grpc", Hostname: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Fetch(ctx, &registrypb.FetchRequest{Env: "test", AppId: "com.xx.grpc"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("fetch after cancel should be NotFound, got %v", err)
+	}
+	if len(calls) != 9 || calls[0] != registrypb.RegistryService_Register_FullMethodName {
+		t.Fatalf("every call should pass through the middleware, got %v", calls)
+	}
+}
+
+func TestGRPCSession(t *testing.T) {
+	r := registry.NewRegistry(registry.WithSessionGrace(time.Hour))
+	defer r.Close()
+	c := dialGRPC(t, NewHTTPServer(r, ServerConfig{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Session(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&registrypb.SessionMessage{Op: registry.SessionOpRegister, RequestId: "req-1",
+		Register: &registrypb.RegisterRequest{Env: "test", AppId: "com.xx.session", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}}})
+	if f, err := stream.Recv(); err != nil || !f.GetLease().GetServerRenewed() {
+		t.Fatalf("expect lease directive, got %v %v", f, err)
+	}
+	f, err := stream.Recv()
+	if rep := f.GetReply(); err != nil || rep.GetError() != "" || rep.GetRequestId() != "req-1" || rep.GetSessionId() == "" {
+		t.Fatalf("unexpected reply %v %v", f, err)
+	}
+
+	stream.Send(&registrypb.SessionMessage{Op: registry.SessionOpWatch, Env: "test", AppId: "com.xx.session"})
+	if f, err := stream.Recv(); err != nil || f.GetReply().GetError() != "" {
+		t.Fatalf("watch failed: %v %v", f, err)
+	}
+	r.Register(registry.NewInstance(&registry.RequestRegister{Env: "test", AppId: "com.xx.session", Hostname: "b", Addrs: []string{"http://127.0.0.1:81"}, Status: registry.StatusUP}), time.Now().UnixNano())
+	if f, err := stream.Recv(); err != nil || f.GetEvent().GetHostname() != "b" || f.GetEvent().GetInstance() == nil {
+		t.Fatalf("expect pushed event, got %v %v", f, err)
+	}
+
+	stream.Send(&registrypb.SessionMessage{Op: registry.SessionOpCancel, Env: "test", AppId: "com.xx.session", Hostname: "missing"})
+	f, err = stream.Recv()
+	if err != nil || f.GetReply().GetErrorInfo().GetCode() != string(registry.CodeInstanceNotFound) {
+		t.Fatalf("cancel of a missing instance should carry the error info, got %v %v", f, err)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// gzipMinSize 小于该大小的响应不压缩
+const gzipMinSize = 1024
+
+// gzipPaths 请求带 Accept-Encoding: gzip 时压缩响应的接口
+var gzipPaths = map[string]bool{
+	PathFetch:      true,
+	PathPoll:       true,
+	PathFetchAll:   true,
+	PathFetchBatch: true,
+	PathFetchDelta: true,
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if name, q, _ := strings.Cut(strings.TrimSpace(enc), ";"); name == "gzip" && strings.TrimSpace(q) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter 先缓存响应，超过 gzipMinSize 后改为 gzip 压缩输出，Close 时写出剩余数据
+type gzipWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+	gz   *gzip.Writer
+	done bool // 已写出响应头
+}
+
+func newGzipWriter(w http.ResponseWriter) *gzipWriter {
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipWriter{ResponseWriter: w}
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	if w.done {
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() >= gzipMinSize && w.Header().Get("Content-Encoding") == "" {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(w.code)
+		w.done = true
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gz.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return len(b), err
+	}
+	return len(b), nil
+}
+
+// Close 写出未压缩的小响应或结束 gzip 流
+func (w *gzipWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	if !w.done && w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+		w.done = true
+		_, err := w.ResponseWriter.Write(w.buf.Bytes())
+		return err
+	}
+	return nil
+}
+
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if w.done {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
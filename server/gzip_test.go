@@ -0,0 +1,50 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestGzip(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	for i := 0; i < 20; i++ {
+		call(t, s, http.MethodPost, PathRegister, url.Values{"env": {"test"}, "appid": {"com.xx.gz"},
+			"hostname": {fmt.Sprintf("h%d", i)}, "addrs": {"http://127.0.0.1:80"}})
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{PathFetch + "?env=test&appid=com.xx.gz", "/v2/fetch?env=test&app_id=com.xx.gz"} {
+		rec := get(path)
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("%s: large response should be compressed", path)
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp struct {
+			Data registry.FetchData `json:"data"`
+		}
+		if err := json.NewDecoder(zr).Decode(&resp); err != nil || len(resp.Data.Instances) != 20 {
+			t.Fatalf("%s: unexpected body %+v %v", path, resp, err)
+		}
+	}
+
+	rec := get(PathFetch + "?env=test&appid=com.xx.none")
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("small response should not be compressed: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// errHTTP3Disabled 没有配置 HTTP3Config
+var errHTTP3Disabled = errors.New("http3 is not configured")
+
+// HTTP3Config HTTP/3（QUIC）监听，丢包较多的移动端、边缘节点心跳及获取的往返更快。
+// TCP 上的 HTTP/1.1、HTTP/2 照常服务，响应通过 Alt-Svc 告知客户端可以改用 HTTP/3，
+// 不支持 QUIC 或 UDP 被拦截的客户端继续使用 TCP
+type HTTP3Config struct {
+	Addr      string      // UDP 监听地址，如 :443，用于 HTTPServer 时默认与 ServerConfig.Addr 相同
+	TLSConfig *tls.Config // 必填，QUIC 只能在 TLS 上使用，可与 TCP 监听共用证书及客户端证书校验
+	Port      int         // Alt-Svc 中公布的端口，UDP 经过端口转发时设置，默认为监听的端口
+}
+
+// HTTP3Server 在 UDP 上以 HTTP/3 处理请求，TCP 上的处理经 AltSvc 包装后公布 HTTP/3 端口
+type HTTP3Server struct {
+	srv *http3.Server
+}
+
+// NewHTTP3Server 创建以 HTTP/3 处理 h 的服务，需调用 ListenAndServe 或 Serve 开始监听
+func NewHTTP3Server(cfg HTTP3Config, h http.Handler) *HTTP3Server {
+	return &HTTP3Server{srv: &http3.Server{Addr: cfg.Addr, Port: cfg.Port, Handler: h, TLSConfig: cfg.TLSConfig}}
+}
+
+// advertise 在 TCP 上的响应中公布 HTTP/3 端口，HTTP/3 监听还没开始时不公布
+func (s *HTTP3Server) advertise(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor < 3 {
+		s.srv.SetQUICHeaders(w.Header())
+	}
+}
+
+// AltSvc 包装 TCP 上的处理，响应中带上 Alt-Svc 告知客户端可以切换到 HTTP/3
+func (s *HTTP3Server) AltSvc(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.advertise(w, r)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ListenAndServe 在 HTTP3Config.Addr 上监听 UDP 处理 HTTP/3 请求，直到 Shutdown
+func (s *HTTP3Server) ListenAndServe() error {
+	return s.srv.ListenAndServe()
+}
+
+// Serve 在 conn 上处理 HTTP/3 请求，直到 Shutdown
+func (s *HTTP3Server) Serve(conn net.PacketConn) error {
+	return s.srv.Serve(conn)
+}
+
+// Shutdown 停止 HTTP/3 监听并等待处理中的请求结束
+func (s *HTTP3Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// altSvc 在 TCP 上的响应中公布 HTTP/3 端口，未开启 HTTP/3 时不公布
+func (s *HTTPServer) altSvc(w http.ResponseWriter, r *http.Request) {
+	if s.h3 != nil {
+		s.h3.advertise(w, r)
+	}
+}
+
+// ListenAndServeHTTP3 在 HTTP3Config.Addr 上监听 UDP 处理 HTTP/3 请求，直到 Shutdown。
+// 应与 ListenAndServe 同时运行，客户端先经 TCP 得知 Alt-Svc 后再切换
+func (s *HTTPServer) ListenAndServeHTTP3() error {
+	if s.h3 == nil {
+		return errHTTP3Disabled
+	}
+	return s.h3.ListenAndServe()
+}
+
+// ServeHTTP3 在 conn 上处理 HTTP/3 请求，直到 Shutdown
+func (s *HTTPServer) ServeHTTP3(conn net.PacketConn) error {
+	if s.h3 == nil {
+		return errHTTP3Disabled
+	}
+	return s.h3.Serve(conn)
+}
+
+// shutdownHTTP3 停止 HTTP/3 监听并等待处理中的请求结束
+func (s *HTTPServer) shutdownHTTP3(ctx context.Context) error {
+	if s.h3 == nil {
+		return nil
+	}
+	return s.h3.Shutdown(ctx)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now().Add(-time.Minute), NotAfter: time.Now().Add(time.Hour),
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// waitAltSvc 等待 UDP 监听就绪后 TCP 上的响应公布 HTTP/3 端口
+func waitAltSvc(h http.Handler, path string) string {
+	var altSvc string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && altSvc == ""; time.Sleep(5 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		altSvc = rec.Header().Get("Alt-Svc")
+	}
+	return altSvc
+}
+
+func http3Get(t *testing.T, pool *x509.CertPool, url string) *http.Response {
+	t.Helper()
+	tr := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	defer tr.Close()
+	resp, err := (&http.Client{Transport: tr, Timeout: 5 * time.Second}).Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestHTTP3Server(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	s := NewHTTP3Server(HTTP3Config{TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}}, ok)
+	go s.Serve(conn)
+	defer s.Shutdown(context.Background())
+
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	if altSvc := waitAltSvc(s.AltSvc(ok), "/"); altSvc != `h3=":`+port+`"; ma=2592000` {
+		t.Fatalf("unexpected Alt-Svc %q", altSvc)
+	}
+	if resp := http3Get(t, pool, "https://127.0.0.1:"+port+"/"); resp.StatusCode != http.StatusOK || resp.ProtoMajor != 3 {
+		t.Fatalf("unexpected HTTP/3 response %d %s", resp.StatusCode, resp.Proto)
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{HTTP3: &HTTP3Config{TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}}})
+	go s.ServeHTTP3(conn)
+	defer s.Shutdown(context.Background())
+
+	port := strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port)
+	if altSvc := waitAltSvc(s, PathHealth); altSvc != `h3=":`+port+`"; ma=2592000` {
+		t.Fatalf("unexpected Alt-Svc %q", altSvc)
+	}
+	resp := http3Get(t, pool, "https://127.0.0.1:"+port+PathHealth)
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 3 || resp.Header.Get("Alt-Svc") != "" {
+		t.Fatalf("unexpected HTTP/3 response %d %s alt-svc=%q", resp.StatusCode, resp.Proto, resp.Header.Get("Alt-Svc"))
+	}
+
+	// 未开启 HTTP/3 时不公布
+	rec := httptest.NewRecorder()
+	NewHTTPServer(registry.NewRegistry(), ServerConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PathHealth, nil))
+	if rec.Header().Get("Alt-Svc") != "" {
+		t.Fatal("Alt-Svc should not be set without HTTP/3")
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// RegisterReplyV2 实例结构为 v2 时注册接口返回的数据
+type RegisterReplyV2 struct {
+	Instance    *registry.InstanceV2          `json:"instance"`
+	Credential  *registry.HeartbeatCredential `json:"credential,omitempty"`
+	Certificate *registry.IssuedCertificate   `json:"certificate,omitempty"`
+}
+
+// instanceSchema 确定响应使用的实例结构：请求头指定时使用请求头，否则使用注册中心的默认值
+func (s *HTTPServer) instanceSchema(w http.ResponseWriter, r *http.Request) string {
+	schema := requestSchema(r)
+	if schema == "" {
+		schema = s.reg.InstanceSchema()
+	}
+	w.Header().Set(HeaderInstanceSchema, schema)
+	return schema
+}
+
+// requestSchema 请求头指定的实例结构，未指定或无法识别时为空
+func requestSchema(r *http.Request) string {
+	switch v := r.Header.Get(HeaderInstanceSchema); v {
+	case "1", registry.InstanceSchemaV1:
+		return registry.InstanceSchemaV1
+	case "2", registry.InstanceSchemaV2:
+		return registry.InstanceSchemaV2
+	}
+	return ""
+}
+
+// bindRegister 请求头指定 v2 且为 JSON 请求体时按 registry.InstanceV2 绑定，否则按 v1 绑定。
+// 输入结构只由请求头决定，默认输出 v2 时 v1 客户端仍可照常注册
+func (s *HTTPServer) bindRegister(r *http.Request, req *registry.RequestRegister) error {
+	if requestSchema(r) != registry.InstanceSchemaV2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := bindRegister(r, req); err != nil {
+			return badRequest(err)
+		}
+		return nil
+	}
+	var v registry.InstanceV2
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+		return badRequest(err)
+	}
+	rr, err := v.RequestRegister()
+	if err != nil {
+		return err
+	}
+	*req = *rr
+	return nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestInstanceSchema(t *testing.T) {
+	reg := registry.NewRegistry()
+	s := NewHTTPServer(reg, ServerConfig{})
+	do := func(method, path, schema, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if schema != "" {
+			req.Header.Set(HeaderInstanceSchema, schema)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"instance_id":"pay-1","env":"test","appId":"com.xx.schema","hostname":"h1","status":"UP","zone":"sh-a",
+		"ports":[{"name":"http","scheme":"http","host":"10.0.0.1","port":8080}]}`
+	rec := do(http.MethodPost, PathRegister, registry.InstanceSchemaV2, body)
+	var reply struct {
+		Data RegisterReplyV2 `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &reply); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("register v2: %d %s", rec.Code, rec.Body.String())
+	}
+	if in := reply.Data.Instance; in.InstanceID != "pay-1" || in.Zone != "sh-a" || in.Ports[0].Name != "http" {
+		t.Fatalf("unexpected v2 reply %+v", in)
+	}
+
+	path := PathFetch + "?env=test&appid=com.xx.schema"
+	fetch := func(schema string) (registry.FetchData, registry.FetchDataV2, *httptest.ResponseRecorder) {
+		rec := do(http.MethodGet, path, schema, "")
+		var v1 struct {
+			Data registry.FetchData `json:"data"`
+		}
+		var v2 struct {
+			Data registry.FetchDataV2 `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &v1)
+		json.Unmarshal(rec.Body.Bytes(), &v2)
+		return v1.Data, v2.Data, rec
+	}
+	// 默认 v1，端口名和实例 ID 保存在 v1 结构中
+	d1, _, rec := fetch("")
+	if rec.Header().Get(HeaderInstanceSchema) != registry.InstanceSchemaV1 || d1.Instances[0].InstanceID != "pay-1" ||
+		d1.Instances[0].Endpoints[0].Name != "http" || d1.Instances[0].Metadata[registry.MetadataZone] != "sh-a" {
+		t.Fatalf("unexpected v1 fetch %s", rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	_, d2, rec := fetch(registry.InstanceSchemaV2)
+	if rec.Header().Get(HeaderInstanceSchema) != registry.InstanceSchemaV2 || len(d2.Instances) != 1 || d2.Instances[0].Ports[0].Port != 8080 {
+		t.Fatalf("unexpected v2 fetch %s", rec.Body.String())
+	}
+	if rec.Header().Get("ETag") == etag {
+		t.Fatal("v1 and v2 representations should not share an etag")
+	}
+
+	// 切换默认结构后未指定的请求输出 v2，指定 v1 的客户端不受影响
+	reg.SetInstanceSchema(registry.InstanceSchemaV2)
+	if _, d2, rec := fetch(""); rec.Header().Get(HeaderInstanceSchema) != registry.InstanceSchemaV2 || d2.Instances[0].Status != registry.StatusNameUP {
+		t.Fatalf("default should be v2 now: %s", rec.Body.String())
+	}
+	if d1, _, _ := fetch(registry.InstanceSchemaV1); len(d1.Instances) != 1 || d1.Instances[0].Status != registry.StatusUP {
+		t.Fatalf("explicit v1 should still get v1: %+v", d1)
+	}
+	// 输入结构只由请求头决定
+	body = `{"Env":"test","AppId":"com.xx.schema","Hostname":"h2","Addrs":["http://10.0.0.2:8080"]}`
+	if rec := do(http.MethodPost, PathRegister, "", body); rec.Code != http.StatusOK {
+		t.Fatalf("v1 register should still work: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, PathRegister, registry.InstanceSchemaV2, `{"env":"test","appId":"com.xx.schema","hostname":"h3","status":"BOOT","ports":[{"host":"h3","port":1}]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid v2 status should be rejected, got %d", rec.Code)
+	}
+}
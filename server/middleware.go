@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// Use 添加接口中间件，按添加顺序由外到内包装 HTTP 接口；通过 HTTPServer.ServeSessionStream 接入的
+// 会话消息流（如 gRPC 双向流）经过同样的中间件
+func (c *ServerConfig) Use(mws ...registry.Middleware) {
+	c.Middleware = append(c.Middleware, mws...)
+}
+
+type callStateKey struct{}
+
+// callState 记录接口处理写出的错误，作为中间件中 next 的返回值
+type callState struct {
+	served bool
+	err    error
+}
+
+// serveMux 经过中间件后交给路由处理
+func (s *HTTPServer) serveMux(w http.ResponseWriter, r *http.Request) {
+	if s.middleware == nil {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	st := &callState{}
+	// 表单请求体由 ParseForm 缓存，之后接口处理仍可读取；JSON 请求体不受影响
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	call := &registry.CallInfo{Protocol: registry.ProtocolHTTP, Method: r.Method, Operation: r.URL.Path,
+		Env: r.Form.Get("env"), AppId: r.Form.Get("appid")}
+	err := s.middleware(func(ctx context.Context, call *registry.CallInfo) error {
+		st.served = true
+		s.mux.ServeHTTP(w, r.WithContext(context.WithValue(ctx, callStateKey{}, st)))
+		return st.err
+	})(r.Context(), call)
+	// 接口处理已写出响应时，中间件返回的错误只能由中间件自己记录
+	if err != nil && !st.served {
+		writeError(w, r, err)
+	}
+}
+
+// recordError 把接口处理写出的错误交给中间件
+func recordError(r *http.Request, err error) {
+	if st, ok := r.Context().Value(callStateKey{}).(*callState); ok {
+		st.err = err
+	}
+}
+
+// ServeSessionStream 在消息流上处理一个会话，每条消息经过 ServerConfig 中的中间件，见 registry.ServeSessionStreamContext
+func (s *HTTPServer) ServeSessionStream(ctx context.Context, stream registry.SessionStream) {
+	if s.middleware != nil {
+		ctx = registry.ContextWithMiddleware(ctx, s.middleware)
+	}
+	s.reg.ServeSessionStreamContext(ctx, stream)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []registry.CallInfo
+	var errs []error
+	logging := func(next registry.Handler) registry.Handler {
+		return func(ctx context.Context, call *registry.CallInfo) error {
+			err := next(ctx, call)
+			calls, errs = append(calls, *call), append(errs, err)
+			return err
+		}
+	}
+	auth := func(next registry.Handler) registry.Handler {
+		return func(ctx context.Context, call *registry.CallInfo) error {
+			if call.Operation == PathRegister && call.AppId != "com.xx.allowed" {
+				return registry.ErrUnauthenticated
+			}
+			return next(ctx, call)
+		}
+	}
+	var cfg ServerConfig
+	cfg.Use(logging, auth)
+	s := NewHTTPServer(registry.NewRegistry(), cfg)
+
+	reg := url.Values{"env": {"test"}, "appid": {"com.xx.denied"}, "hostname": {"h1"}, "addrs[]": {"http://127.0.0.1:80"}, "status": {"1"}}
+	if code, resp := call(t, s, http.MethodPost, PathRegister, reg); code != http.StatusUnauthorized || resp.Error.Code != registry.CodeUnauthenticated {
+		t.Fatalf("expect rejected by middleware, got %d %+v", code, resp)
+	}
+	reg.Set("appid", "com.xx.allowed")
+	if code, _ := call(t, s, http.MethodPost, PathRegister, reg); code != http.StatusOK {
+		t.Fatalf("expect register ok, got %d", code)
+	}
+	// 外层中间件收到接口处理返回的错误
+	if code, _ := call(t, s, http.MethodGet, PathFetch+"?env=test&appid=com.xx.none", nil); code != http.StatusNotFound {
+		t.Fatalf("expect not found, got %d", code)
+	}
+	if len(calls) != 3 || calls[0].AppId != "com.xx.denied" || calls[1].Method != http.MethodPost || calls[2].Operation != PathFetch ||
+		!errors.Is(errs[0], registry.ErrUnauthenticated) || errs[1] != nil || !errors.Is(errs[2], registry.ErrAppNotFound) {
+		t.Fatalf("unexpected calls %+v %v", calls, errs)
+	}
+}
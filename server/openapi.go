@@ -0,0 +1,310 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PathOpenAPI OpenAPI 3 接口文档
+const PathOpenAPI = "/api/openapi.json"
+
+//go:generate go run ../cmd/registryctl openapi -o ../api/openapi.json
+
+// apiParam 接口参数，同时用于生成文档和校验请求
+type apiParam struct {
+	Name        string
+	Type        string // string、integer、number、boolean、array（元素为 string）
+	Required    bool
+	Enum        []string
+	Format      string   // addr 为 scheme://host:port 或 host:port 地址，duration 为 Go 时长如 30s
+	Minimum     *float64 // 数值下限
+	Description string
+}
+
+// apiRoute 一个接口。Body 非空时请求体为该类型的 JSON，Result 为成功响应中 data 的类型，
+// 两者都由类型生成 components 中的 schema
+type apiRoute struct {
+	Path    string
+	Methods []string
+	Summary string
+	Params  []apiParam
+	Body    interface{}
+	Result  interface{}
+	handler http.HandlerFunc
+}
+
+var minZero = float64(0)
+
+// 注册及续约的参数，也是请求校验的依据
+var (
+	instanceParamList = []apiParam{
+		{Name: "env", Type: "string", Required: true, Description: "服务环境"},
+		{Name: "appid", Type: "string", Required: true, Description: "应用标识"},
+		{Name: "hostname", Type: "string", Required: true, Description: "实例标识"},
+	}
+	registerParamList = append(append([]apiParam{}, instanceParamList...),
+		apiParam{Name: "addrs", Type: "array", Format: "addr", Description: "实例地址，表单中也可以写作 addrs[]，与 secure_addrs 至少一个"},
+		apiParam{Name: "secure_addrs", Type: "array", Format: "addr", Description: "TLS 加密地址"},
+		apiParam{Name: "status", Type: "integer", Enum: []string{"1", "2"}, Description: "1 可用，2 不可用，默认 1"},
+		apiParam{Name: "weight", Type: "integer", Minimum: &minZero, Description: "流量权重"},
+		apiParam{Name: "version", Type: "string"},
+		apiParam{Name: "group", Type: "string", Description: "实验分组"},
+		apiParam{Name: "spiffe_id", Type: "string"},
+		apiParam{Name: "instance_id", Type: "string", Description: "v2 结构的实例 ID，默认 env/appid/hostname"},
+		apiParam{Name: "port_names", Type: "string", Description: "JSON 格式的端口名，key 为地址"},
+		apiParam{Name: "metadata", Type: "string", Description: "JSON 格式的元数据"},
+		apiParam{Name: "latest_timestamp", Type: "integer"},
+		apiParam{Name: "dirty_timestamp", Type: "integer"},
+	)
+	renewParamList = append(append([]apiParam{}, instanceParamList...),
+		apiParam{Name: "secret", Type: "string", Description: "续约凭证"},
+		apiParam{Name: "max_concurrent", Type: "integer", Minimum: &minZero},
+		apiParam{Name: "in_flight", Type: "integer", Minimum: &minZero},
+		apiParam{Name: "utilization", Type: "number", Minimum: &minZero},
+	)
+	purgeParamList = []apiParam{
+		{Name: "env", Type: "string", Required: true},
+		{Name: "appid", Type: "string", Required: true},
+		{Name: "confirm", Type: "string", Required: true, Description: "与 appid 相同以防误操作"},
+	}
+	fetchParamList = []apiParam{
+		{Name: "env", Type: "string", Required: true},
+		{Name: "appid", Type: "string", Required: true},
+		{Name: "status", Type: "integer", Description: "按位匹配的实例状态，默认 1"},
+		{Name: "latest_timestamp", Type: "integer", Description: "数据没有变化时返回 304"},
+		{Name: "min_health_score", Type: "integer"},
+		{Name: "client_key", Type: "string"},
+		{Name: "scheme", Type: "string"},
+		{Name: "secure_only", Type: "boolean"},
+		{Name: "consumer", Type: "string", Description: "已废弃，限流按令牌、客户端证书或客户端 IP 识别调用方"},
+		{Name: "affinity_key", Type: "string"},
+		{Name: "limit", Type: "integer", Minimum: &minZero, Description: "每页实例数，0 表示不分页"},
+		{Name: "cursor", Type: "string", Description: "上一页返回的 next_cursor"},
+	}
+)
+
+// routes 所有接口，NewHTTPServer 按此注册处理函数，OpenAPI 按此生成文档
+func (s *HTTPServer) routes() []apiRoute {
+	get, post := []string{http.MethodGet}, []string{http.MethodPost}
+	envParam := apiParam{Name: "env", Type: "string", Required: true}
+	appParam := apiParam{Name: "appid", Type: "string", Required: true}
+	return []apiRoute{
+		{Path: PathRegister, Methods: post, Summary: "注册实例，也接受 JSON 请求体，X-Registry-Instance-Schema 为 v2 时请求体及返回的实例为 v2 结构", Params: registerParamList, Result: RegisterReply{},
+			handler: s.post(s.register)},
+		{Path: PathRenew, Methods: post, Summary: "续约，返回 INSTANCE_NOT_FOUND 时应重新注册", Params: renewParamList, Result: RenewReply{},
+			handler: s.post(s.renew)},
+		{Path: PathCancel, Methods: post, Summary: "下线实例", Params: append(append([]apiParam{}, instanceParamList...),
+			apiParam{Name: "latest_timestamp", Type: "integer"}), Result: registry.Instance{}, handler: s.post(s.cancel)},
+		{Path: PathFetch, Methods: get, Summary: "获取应用实例，Accept 为 application/x-protobuf 时返回 protobuf 编码的 FetchData，实例结构见 X-Registry-Instance-Schema", Params: fetchParamList,
+			Result: registry.FetchData{}, handler: s.fetch},
+		{Path: PathFetchDelta, Methods: get, Summary: "获取应用实例的增量变化", Params: []apiParam{envParam, appParam,
+			{Name: "revision", Type: "integer", Minimum: &minZero}}, Result: registry.DeltaData{}, handler: s.fetchDelta},
+		{Path: PathFetchAll, Methods: get, Summary: "获取环境下所有应用的实例，带 limit 或 cursor 时分页返回 FetchAllData", Params: []apiParam{envParam,
+			{Name: "limit", Type: "integer", Minimum: &minZero}, {Name: "cursor", Type: "string"}}, Result: map[string][]*registry.Instance{}, handler: s.fetchAll},
+		{Path: PathFetchBatch, Methods: get, Summary: "批量获取应用实例", Params: []apiParam{envParam,
+			{Name: "appid", Type: "array", Required: true},
+			{Name: "latest_timestamp", Type: "array", Description: "与 appid 按顺序对应"},
+			{Name: "consumer", Type: "string", Description: "已废弃，同 fetch"}}, Result: map[string]*registry.FetchData{}, handler: s.fetchBatch},
+		{Path: PathSet, Methods: post, Summary: "修改实例状态、权重或元数据，需要管理员认证", Body: registry.SetRequest{}, Result: []*registry.Instance{},
+			handler: s.post(s.admin(s.set))},
+		{Path: PathTransfer, Methods: post, Summary: "用新主机原子地替换实例，新实例继承原实例的权重、元数据及人工调整，需要管理员认证", Body: registry.TransferRequest{},
+			Result: RegisterReply{}, handler: s.post(s.admin(s.transfer))},
+		{Path: PathNodes, Methods: get, Summary: "集群节点", Result: []registry.Node{}, handler: s.nodes},
+		{Path: PathCapacity, Methods: get, Summary: "应用容量", Params: []apiParam{envParam, appParam}, Result: registry.AppCapacity{}, handler: s.capacity},
+		{Path: PathConflicts, Methods: get, Summary: "地址冲突，不包含涉及对调用方不可见的应用的冲突", Result: []registry.AddrConflict{}, handler: s.conflicts},
+		{Path: PathPoll, Methods: get, Summary: "长轮询获取应用实例，支持 protobuf 响应，实例结构见 X-Registry-Instance-Schema", Params: append(append([]apiParam{}, fetchParamList...),
+			apiParam{Name: "timeout", Type: "string", Format: "duration"}), Result: registry.FetchData{}, handler: s.poll},
+		{Path: PathMaintenance, Methods: []string{http.MethodGet, http.MethodPost}, Summary: "维护窗口：GET 列出未结束的维护窗口，POST 安排维护窗口，需要管理员认证", Body: registry.MaintenanceWindow{},
+			handler: s.maintenance},
+		{Path: PathMaintenanceCancel, Methods: post, Summary: "取消维护窗口，需要管理员认证", Params: []apiParam{{Name: "id", Type: "string", Required: true}},
+			handler: s.post(s.admin(s.cancelMaintenance))},
+		{Path: PathFreeze, Methods: []string{http.MethodGet, http.MethodPost}, Summary: "应用冻结：GET 列出冻结中的应用，POST 冻结应用并复制给对端，需要管理员认证",
+			Body: registry.AppFreeze{}, Result: []registry.AppFreeze{}, handler: s.admin(s.freeze)},
+		{Path: PathUnfreeze, Methods: post, Summary: "解除应用冻结并复制给对端，需要管理员认证", Params: []apiParam{envParam, appParam},
+			handler: s.post(s.admin(s.unfreeze))},
+		{Path: PathPurge, Methods: post, Summary: "清除应用的所有数据并复制给对端，需要管理员认证", Params: purgeParamList, Result: PurgeReply{},
+			handler: s.post(s.admin(s.purge))},
+		{Path: PathEventStream, Methods: get, Summary: "Server-Sent Events 变更推送", Params: []apiParam{envParam,
+			{Name: "appid", Type: "string"}, {Name: "since", Type: "integer"}}, handler: s.eventStream},
+		{Path: PathChanges, Methods: get, Summary: "变更订阅：返回订阅者已提交位置之后的事件，新订阅者或注册中心重启后返回 410，需全量同步后 reset，需要管理员认证",
+			Params: []apiParam{{Name: "consumer", Type: "string", Required: true}, {Name: "max", Type: "integer", Description: "每批事件数，默认 100，最多 1000"},
+				{Name: "timeout", Type: "string", Format: "duration", Description: "没有新事件时的等待时长"}}, Result: ChangesReply{}, handler: s.admin(s.changes)},
+		{Path: PathChangesCommit, Methods: post, Summary: "提交变更订阅的消费位置，需要管理员认证", Params: []apiParam{{Name: "consumer", Type: "string", Required: true},
+			{Name: "seq", Type: "integer", Required: true, Minimum: &minZero}}, Result: registry.Offset{}, handler: s.post(s.admin(s.commitChanges))},
+		{Path: PathChangesReset, Methods: post, Summary: "变更订阅跳到最新位置，全量同步后调用，需要管理员认证", Params: []apiParam{{Name: "consumer", Type: "string", Required: true}},
+			Result: registry.Offset{}, handler: s.post(s.admin(s.resetChanges))},
+		{Path: PathHealth, Methods: get, Summary: "健康状态", Result: registry.HealthStatus{}, handler: s.health},
+		{Path: PathWebSocket, Methods: get, Summary: "WebSocket 推送通道", handler: s.websocket},
+		{Path: PathGraphQL, Methods: []string{http.MethodGet, http.MethodPost}, Summary: "GraphQL 查询", Body: GraphQLRequest{}, handler: s.graphql},
+		{Path: PathReplicate, Methods: post, Summary: "对端节点复制写操作，需携带 X-Registry-Peer 及签名", Body: registry.ReplicationOp{},
+			handler: s.post(s.replicate)},
+		{Path: PathTokens, Methods: []string{http.MethodGet, http.MethodPost}, Summary: "只读令牌：GET 列出本节点签发的令牌，POST 签发令牌，需要管理员认证",
+			Body: registry.TokenRequest{}, Result: TokenReply{}, handler: s.admin(s.tokens)},
+		{Path: PathTokenRevoke, Methods: post, Summary: "吊销只读令牌，只在本节点生效，需要管理员认证", Params: []apiParam{{Name: "id", Type: "string", Required: true}},
+			handler: s.post(s.admin(s.revokeToken))},
+		{Path: PathAudit, Methods: get, Summary: "审计记录，需要管理员认证", Result: []registry.AuditEntry{}, handler: s.admin(s.auditLog)},
+		{Path: PathSnapshot, Methods: get, Summary: "本节点注册表的全量快照，非管理员的快照不包含对其不可见的受限应用", Result: registry.Snapshot{}, handler: s.snapshot},
+		{Path: PathReconcile, Methods: get, Summary: "获取对端节点的快照与本节点对账，返回两边实例的差异，不修改数据，需要管理员认证", Params: []apiParam{
+			{Name: "peer", Type: "string", Required: true, Description: "节点 ID 或地址"}}, Result: registry.ReconcileReport{}, handler: s.admin(s.reconcile)},
+		{Path: PathQuotas, Methods: get, Summary: "应用的实例数配额及当前用量，warning 表示已达到软阈值", Result: []registry.QuotaUsage{}, handler: s.quotas},
+		{Path: PathZones, Methods: get, Summary: "实例全部不可用或已过期的可用区，需开启可用区故障检测", Result: []registry.ZoneOutage{}, handler: s.zoneOutages},
+		{Path: PathHistory, Methods: get, Summary: "由事件日志重建应用在过去某一时刻的实例列表，超出保留范围时返回 410", Params: []apiParam{envParam, appParam,
+			{Name: "at", Type: "string", Required: true, Description: "RFC 3339 时间或纳秒时间戳"}}, Result: registry.HistoryData{}, handler: s.history},
+		{Path: PathHeatmap, Methods: get, Summary: "按时间分桶的续约、注册、下线及剔除次数，只统计本节点处理的请求", Params: []apiParam{envParam,
+			{Name: "appid", Type: "string"}, {Name: "window", Type: "string", Format: "duration", Description: "最近多长时间，如 5m，默认全部保留的分桶"}},
+			Result: registry.Heatmap{}, handler: s.heatmap},
+		{Path: PathOpenAPI, Methods: get, Summary: "OpenAPI 文档", handler: s.openAPI},
+	}
+}
+
+func (s *HTTPServer) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(s.spec)
+}
+
+// OpenAPI 生成 OpenAPI 3 文档，是其他语言生成客户端的依据，仓库中的 api/openapi.json 由此生成
+func (s *HTTPServer) OpenAPI() map[string]interface{} {
+	c := schemas{
+		"RequestRegister": paramsSchema(registerParamList),
+	}
+	c.add(Response{})
+	c.add(registry.ErrorInfo{})
+	c.add(registry.InvalidRequestError{})
+	c["ErrorResponse"] = map[string]interface{}{"allOf": []interface{}{schemaRef("Response"),
+		map[string]interface{}{"type": "object", "properties": map[string]interface{}{"data": schemaRef("InvalidRequestError")}}}}
+	paths := make(map[string]interface{})
+	for _, rt := range s.routes() {
+		item := make(map[string]interface{})
+		ok := jsonContent("成功", schemaRef("Response"))
+		if rt.Result != nil {
+			ok = jsonContent("成功，结果在 data 中", map[string]interface{}{"allOf": []interface{}{schemaRef("Response"),
+				map[string]interface{}{"type": "object", "properties": map[string]interface{}{"data": c.add(rt.Result)}}}})
+		}
+		for _, m := range rt.Methods {
+			op := map[string]interface{}{
+				"summary":     rt.Summary,
+				"operationId": operationID(m, rt.Path),
+				"responses": map[string]interface{}{
+					"200":     ok,
+					"400":     jsonContent("请求参数错误", schemaRef("ErrorResponse")),
+					"default": jsonContent("错误，error.code 为机器可读的错误码", schemaRef("ErrorResponse")),
+				},
+			}
+			switch {
+			case rt.Body != nil && (m == http.MethodPost || len(rt.Params) == 0):
+				if m == http.MethodPost {
+					op["requestBody"] = map[string]interface{}{"required": true, "content": map[string]interface{}{
+						"application/json": map[string]interface{}{"schema": c.add(rt.Body)},
+					}}
+				}
+			case m == http.MethodPost && len(rt.Params) > 0:
+				content := map[string]interface{}{
+					"application/x-www-form-urlencoded": map[string]interface{}{"schema": paramsSchema(rt.Params)},
+				}
+				if rt.Path == PathRegister {
+					content["application/json"] = map[string]interface{}{"schema": schemaRef("RequestRegister")}
+				}
+				op["requestBody"] = map[string]interface{}{"required": true, "content": content}
+			case len(rt.Params) > 0:
+				params := make([]interface{}, 0, len(rt.Params))
+				for _, p := range rt.Params {
+					params = append(params, map[string]interface{}{
+						"name": p.Name, "in": "query", "required": p.Required,
+						"description": p.Description, "schema": p.schema(),
+					})
+				}
+				op["parameters"] = params
+			}
+			item[strings.ToLower(m)] = op
+		}
+		paths[rt.Path] = item
+	}
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       map[string]interface{}{"title": "registry-center", "version": "1.0"},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": map[string]interface{}(c)},
+	}
+}
+
+// WriteOpenAPI 输出缩进格式的 OpenAPI 文档
+func (s *HTTPServer) WriteOpenAPI(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.OpenAPI())
+}
+
+// operationID 如 POST /api/fetch/delta 为 postFetchDelta
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/api/"), func(r rune) bool {
+		return r == '/' || r == '.' || r == '_'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(desc string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"description": desc, "content": map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}}
+}
+
+func paramsSchema(params []apiParam) map[string]interface{} {
+	props := make(map[string]interface{}, len(params))
+	var required []string
+	for _, p := range params {
+		props[p.Name] = p.schema()
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (p apiParam) schema() map[string]interface{} {
+	schema := map[string]interface{}{"type": p.Type}
+	if p.Type == "array" {
+		item := map[string]interface{}{"type": "string"}
+		if p.Format != "" {
+			item["format"] = p.Format
+		}
+		schema["items"] = item
+	} else if p.Format != "" {
+		schema["format"] = p.Format
+	}
+	if len(p.Enum) > 0 {
+		enum := make([]interface{}, len(p.Enum))
+		for i, v := range p.Enum {
+			enum[i] = v
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil && p.Type == "integer" {
+				enum[i] = n
+			}
+		}
+		schema["enum"] = enum
+	}
+	if p.Minimum != nil {
+		schema["minimum"] = *p.Minimum
+	}
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	return schema
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestOpenAPI(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PathOpenAPI, nil))
+	var spec struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil || spec.OpenAPI == "" {
+		t.Fatalf("invalid spec: %v %s", err, rec.Body.String())
+	}
+	for _, rt := range s.routes() {
+		if _, ok := spec.Paths[rt.Path]; !ok {
+			t.Fatalf("path %s missing from spec", rt.Path)
+		}
+	}
+	if _, ok := spec.Paths[PathRegister]["post"]["requestBody"]; !ok {
+		t.Fatal("register should document its request body")
+	}
+}
+
+// 仓库中的 api/openapi.json 是其他语言客户端的依据，需与代码保持一致
+func TestOpenAPIFile(t *testing.T) {
+	want, err := os.ReadFile("../api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if err := NewHTTPServer(registry.NewRegistry(), ServerConfig{}).WriteOpenAPI(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Fatal("api/openapi.json is out of date, run go generate ./server")
+	}
+}
+
+func TestValidation(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	reg := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "addrs[]": {"http://127.0.0.1:80", "127.0.0.1"}, "status": {"3"}}
+	code, resp := call(t, s, http.MethodPost, PathRegister, reg)
+	var ve registry.InvalidRequestError
+	json.Unmarshal(*resp.Data.(*json.RawMessage), &ve)
+	fields := make(map[string]bool)
+	for _, fe := range ve.Violations {
+		fields[fe.Field] = true
+	}
+	if code != http.StatusBadRequest || !fields["hostname"] || !fields["addrs"] || !fields["status"] || len(ve.Violations) != 3 {
+		t.Fatalf("unexpected validation errors: %d %+v", code, ve)
+	}
+
+	renew := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "hostname": {"h1"}, "in_flight": {"-1"}}
+	code, resp = call(t, s, http.MethodPost, PathRenew, renew)
+	ve = registry.InvalidRequestError{}
+	json.Unmarshal(*resp.Data.(*json.RawMessage), &ve)
+	if code != http.StatusBadRequest || len(ve.Violations) != 1 || ve.Violations[0].Field != "in_flight" {
+		t.Fatalf("unexpected validation errors: %d %+v", code, ve)
+	}
+
+	for addr, ok := range map[string]bool{
+		"http://testapp.com": true, "grpc://10.0.0.1:9000": true, "10.0.0.1:20880": true,
+		"10.0.0.1": false, "http://": false, "10.0.0.1:99999": false,
+	} {
+		if registry.ValidAddr(addr) != ok {
+			t.Fatalf("ValidAddr(%q) should be %v", addr, ok)
+		}
+	}
+}
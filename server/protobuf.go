@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// acceptsProtobuf Accept 请求头包含 protobuf 类型时，fetch 与 poll 直接返回 protobuf 编码的
+// registry.FetchData，不带 Response 外层。错误响应仍为 JSON
+func acceptsProtobuf(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), registry.ContentTypeProtobuf)
+}
+
+// writeFetchData 按 Accept 请求头选择 JSON 或 protobuf 写出实例列表，JSON 按 schema 输出对应结构的实例，
+// protobuf 始终为 v1 结构
+func writeFetchData(w http.ResponseWriter, r *http.Request, data *registry.FetchData, schema string) {
+	if !acceptsProtobuf(r) {
+		if schema == registry.InstanceSchemaV2 {
+			writeOK(w, r, data.V2())
+			return
+		}
+		writeOK(w, r, data)
+		return
+	}
+	body := registry.MarshalFetchData(data)
+	if id := registry.RequestIDFromContext(r.Context()); id != "" {
+		w.Header().Set(HeaderRequestID, id)
+	}
+	w.Header().Set("Content-Type", registry.ContentTypeProtobuf)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	setQuotaWarnings(w, r)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestProtobufFetch(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	reg := url.Values{"env": {"test"}, "appid": {"com.xx.pb"}, "hostname": {"h1"}, "addrs": {"http://127.0.0.1:80"}}
+	if code, resp := call(t, s, http.MethodPost, PathRegister, reg); code != http.StatusOK {
+		t.Fatalf("register failed: %d %+v", code, resp)
+	}
+
+	for _, path := range []string{PathFetch, "/v2/fetch"} {
+		req := httptest.NewRequest(http.MethodGet, path+"?env=test&appid=com.xx.pb&app_id=com.xx.pb", nil)
+		req.Header.Set("Accept", registry.ContentTypeProtobuf)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if ct := rec.Header().Get("Content-Type"); rec.Code != http.StatusOK || ct != registry.ContentTypeProtobuf {
+			t.Fatalf("%s: unexpected response %d %s", path, rec.Code, ct)
+		}
+		data, err := registry.UnmarshalFetchData(rec.Body.Bytes())
+		if err != nil || len(data.Instances) != 1 || data.Instances[0].AppId != "com.xx.pb" {
+			t.Fatalf("%s: unexpected data %+v %v", path, data, err)
+		}
+	}
+
+	// 错误仍返回 JSON
+	req := httptest.NewRequest(http.MethodGet, PathFetch+"?env=test&appid=com.xx.none", nil)
+	req.Header.Set("Accept", registry.ContentTypeProtobuf)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") == registry.ContentTypeProtobuf {
+		t.Fatalf("unexpected error response %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemas 由 Go 类型生成 OpenAPI components 中的 schema，文档中的报文结构与实际编码一致
+type schemas map[string]interface{}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of 返回类型 t 的 schema，结构体登记到 components 并返回引用
+func (c schemas) of(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return c.of(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": c.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": c.of(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if _, ok := c[t.Name()]; !ok {
+			c[t.Name()] = nil // 先占位，结构体引用自身时不会无限递归
+			props := make(map[string]interface{})
+			c.fields(t, props)
+			c[t.Name()] = map[string]interface{}{"type": "object", "properties": props}
+		}
+		return schemaRef(t.Name())
+	}
+	return map[string]interface{}{}
+}
+
+// fields 按 json 标签收集导出字段，匿名嵌入的结构体字段展开到外层
+func (c schemas) fields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			c.fields(f.Type, props)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = c.of(f.Type)
+	}
+}
+
+// add 登记 v 的类型并返回引用
+func (c schemas) add(v interface{}) map[string]interface{} {
+	return c.of(reflect.TypeOf(v))
+}
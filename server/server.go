@@ -0,0 +1,909 @@
+// Package server 通过 HTTP 对外提供注册中心的注册、续约、下线和服务获取接口
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// 接口路径
+const (
+	PathRegister = "/api/register"
+	PathRenew    = "/api/renew"
+	PathCancel   = "/api/cancel"
+	PathFetch    = "/api/fetch"
+
+	PathFetchDelta = "/api/fetch/delta"
+	PathFetchAll   = "/api/fetch/all"
+	PathFetchBatch = "/api/fetch/batch"
+	PathSet        = "/api/set"
+	PathTransfer   = "/api/transfer"
+	PathNodes      = "/api/nodes"
+	PathCapacity   = "/api/capacity"
+	PathConflicts  = "/api/conflicts"
+	PathPoll       = "/api/poll"
+	PathHealth     = "/api/health"
+
+	PathMaintenance       = "/api/maintenance"
+	PathMaintenanceCancel = "/api/maintenance/cancel"
+
+	PathFreeze   = "/api/freeze"   // 冻结应用，冻结期间拒绝注册、下线及状态修改
+	PathUnfreeze = "/api/unfreeze" // 解除冻结，应用未冻结时也返回成功
+
+	PathReplicate = "/api/replicate" // 对端节点复制写操作，请求体为 JSON 格式的 registry.ReplicationOp
+	PathAudit     = "/api/audit"
+	PathHistory   = "/api/history" // 应用在过去某一时刻的实例列表
+	PathHeatmap   = "/api/heatmap" // 最近一段时间按时间分桶的续约、注册、下线次数
+	PathSnapshot  = "/api/snapshot"
+	PathReconcile = "/api/reconcile" // 与对端节点对账，不修改数据
+	PathQuotas    = "/api/quotas"    // 应用的实例数配额及用量
+	PathZones     = "/api/zones"     // 当前故障的可用区
+)
+
+// 请求头
+const (
+	HeaderRequestID   = "X-Request-Id"
+	HeaderTraceparent = "traceparent"
+	HeaderTracestate  = "tracestate"
+
+	HeaderClient = "X-Registry-Client" // 客户端 SDK 及版本，如 registry-go/0.1.0
+	HeaderAgent  = "X-Registry-Agent"  // 为 true 表示由节点代理代为注册
+
+	HeaderStorage = "X-Registry-Storage" // 外部存储不可用时为 degraded，响应数据来自内存
+
+	HeaderQuotaWarning = "X-Registry-Quota-Warning" // 本次请求使配额用量达到软阈值，每条预警一个
+
+	// HeaderInstanceSchema 请求时指定实例结构 v1 或 v2，覆盖服务端的默认值，注册时同时表示 JSON 请求体的结构；
+	// 响应时返回实际使用的结构
+	HeaderInstanceSchema = "X-Registry-Instance-Schema"
+
+	HeaderPeer          = "X-Registry-Peer"      // 复制请求的来源节点 ID
+	HeaderPeerSignature = "X-Registry-Signature" // 复制请求体的签名，见 registry.SignReplication
+)
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Addr         string        // 监听地址，如 :7171
+	ReadTimeout  time.Duration // 默认 10 秒
+	WriteTimeout time.Duration // 默认 10 秒
+	MaxBodyBytes int64         // 请求体大小上限，默认 1MB
+
+	// RequestTimeout 单个请求的处理时限，传递到准入排队、写入、复制等各阶段，0 表示不限。
+	// 超时的请求返回 504，避免慢的对端或磁盘让请求堆积
+	RequestTimeout time.Duration
+
+	CORS *CORSConfig // 浏览器跨域访问，为空时不开启
+
+	Admin *AdminConfig // 管理接口的认证，为空时管理接口一律拒绝
+
+	ChangeOffsets registry.OffsetStore // 变更订阅接口保存消费位置，为空时保存在内存中，重启后订阅者需要重新全量同步
+
+	HTTP3 *HTTP3Config // HTTP/3 监听，为空时不开启，见 ListenAndServeHTTP3
+
+	Middleware []registry.Middleware // 接口中间件，由外到内包装接口处理，见 Use
+}
+
+// Response 接口统一的响应格式，Code 与 HTTP 状态码一致
+type Response struct {
+	Code      int         `json:"code"`
+	Message   string      `json:"message,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+
+	Error *registry.ErrorInfo `json:"error,omitempty"` // 失败时的机器可读错误
+}
+
+// RegisterReply 注册接口返回的数据
+type RegisterReply struct {
+	Instance    *registry.Instance            `json:"instance"`
+	Credential  *registry.HeartbeatCredential `json:"credential,omitempty"`  // 开启续约凭证时下发
+	Certificate *registry.IssuedCertificate   `json:"certificate,omitempty"` // 开启 CA 且身份经过校验时下发
+}
+
+// RenewReply 续约接口返回的数据
+type RenewReply struct {
+	Instance    *registry.Instance            `json:"instance"`
+	Credential  *registry.HeartbeatCredential `json:"credential,omitempty"`  // 凭证轮换时下发的新凭证
+	Certificate *registry.IssuedCertificate   `json:"certificate,omitempty"` // 凭证或客户端证书校验通过时下发当前证书，到期前会自动换发
+}
+
+// HTTPServer 注册中心 HTTP 服务
+type HTTPServer struct {
+	reg  *registry.Registry
+	cfg  ServerConfig
+	mux  *http.ServeMux
+	srv  *http.Server
+	h3   *HTTP3Server // HTTP/3 服务，未开启时为空
+	spec []byte       // OpenAPI 文档
+
+	middleware registry.Middleware // 组合后的接口中间件，为空时不包装
+}
+
+// NewHTTPServer 创建 HTTP 服务，调用 ListenAndServe 或 Serve 开始处理请求，
+// 也可以把它作为 http.Handler 挂到已有的服务上
+func NewHTTPServer(reg *registry.Registry, cfg ServerConfig) *HTTPServer {
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = 10 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 10 * time.Second
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1 << 20
+	}
+	if cfg.ChangeOffsets == nil {
+		cfg.ChangeOffsets = registry.NewMemoryOffsetStore()
+	}
+	s := &HTTPServer{reg: reg, cfg: cfg, mux: http.NewServeMux()}
+	if len(cfg.Middleware) > 0 {
+		s.middleware = registry.Chain(cfg.Middleware...)
+	}
+	for _, rt := range s.routes() {
+		s.mux.HandleFunc(rt.Path, rt.handler)
+	}
+	s.spec, _ = json.Marshal(s.OpenAPI())
+	s.srv = &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      s,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+	if cfg.HTTP3 != nil {
+		h3 := *cfg.HTTP3
+		if h3.Addr == "" {
+			h3.Addr = cfg.Addr
+		}
+		s.h3 = NewHTTP3Server(h3, s)
+	}
+	return s
+}
+
+// ServeHTTP 实现 http.Handler。沿用调用方传入的请求 ID 及 trace 上下文，没有请求 ID 时生成一个；
+// 接口版本由路径前缀 /v1、/v2 或 HeaderAPIVersion、Accept 请求头确定，默认 v1。开启跨域时预检请求直接应答
+func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.altSvc(w, r)
+	if s.cors(w, r) {
+		return
+	}
+	ctx := r.Context()
+	id := r.Header.Get(HeaderRequestID)
+	if id == "" {
+		id = registry.NewRequestID()
+	}
+	ctx = registry.ContextWithRequestID(ctx, id)
+	ctx = registry.ContextWithSource(ctx, requestSource(r))
+	ctx = registry.ContextWithCaller(ctx, callerIdentity(r))
+	ctx = registry.ContextWithQuotaWarnings(ctx)
+	if tp := r.Header.Get(HeaderTraceparent); tp != "" {
+		if tc, err := registry.ParseTraceparent(tp, r.Header.Get(HeaderTracestate)); err == nil {
+			ctx = registry.ContextWithTrace(ctx, tc)
+		}
+	}
+	if s.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.RequestTimeout)
+		defer cancel()
+	}
+	r = r.WithContext(ctx)
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes)
+	if s.reg.StorageDegraded() {
+		w.Header().Set(HeaderStorage, "degraded")
+	}
+	version := apiVersion(r)
+	w.Header().Set(HeaderAPIVersion, version)
+	if t, err := s.bearerToken(r); err != nil {
+		writeError(w, r, err)
+		return
+	} else if t != nil {
+		r = r.WithContext(registry.ContextWithToken(r.Context(), t))
+	}
+	if gzipPaths[r.URL.Path] && acceptsGzip(r) {
+		gw := newGzipWriter(w)
+		defer gw.Close()
+		w = gw
+	}
+	// GraphQL 和 OpenAPI 文档的字段名由各自的规范决定，不随接口版本转换
+	if version == APIVersion2 && r.URL.Path != PathGraphQL && r.URL.Path != PathOpenAPI {
+		if err := v2Request(r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		vw := &v2Writer{ResponseWriter: w}
+		s.serveMux(vw, r)
+		vw.finish()
+		return
+	}
+	s.serveMux(w, r)
+}
+
+// callerIdentity 调用方身份为已校验的客户端证书中的 SPIFFE ID，服务端需要开启客户端证书校验，
+// 没有证书时为空，只能获取公开的应用
+func callerIdentity(r *http.Request) string {
+	if r.TLS == nil {
+		return ""
+	}
+	return registry.SpiffeIDFromCertificates(r.TLS.PeerCertificates)
+}
+
+// requestSource 根据连接地址和请求头识别请求来源
+func requestSource(r *http.Request) registry.RegistrationSource {
+	src := registry.RegistrationSource{Kind: registry.SourceDirect, SDKVersion: r.Header.Get(HeaderClient)}
+	if r.Header.Get(HeaderAgent) == "true" {
+		src.Kind = registry.SourceAgent
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		src.ClientIP = host
+	}
+	return src
+}
+
+// ListenAndServe 监听 cfg.Addr 并处理请求，直到 Shutdown
+func (s *HTTPServer) ListenAndServe() error {
+	return s.srv.ListenAndServe()
+}
+
+// Serve 在 ln 上处理请求，直到 Shutdown
+func (s *HTTPServer) Serve(ln net.Listener) error {
+	return s.srv.Serve(ln)
+}
+
+// Shutdown 停止接收新请求并等待处理中的请求结束，开启 HTTP/3 时同时停止
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return errors.Join(s.srv.Shutdown(ctx), s.shutdownHTTP3(ctx))
+}
+
+// post 只允许 POST 请求
+func (s *HTTPServer) post(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, r, http.StatusMethodNotAllowed, Response{Message: "method not allowed"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *HTTPServer) register(w http.ResponseWriter, r *http.Request) {
+	var req registry.RequestRegister
+	if err := s.bindRegister(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	in := registry.NewInstance(&req)
+	if req.Status == 0 {
+		in.Status = registry.StatusUP
+	}
+	if req.LatestTimestamp == 0 {
+		req.LatestTimestamp = time.Now().UnixNano()
+	}
+	var certs []*x509.Certificate
+	if r.TLS != nil {
+		certs = r.TLS.PeerCertificates
+	}
+	if _, err := s.reg.RegisterWithPeerCertificatesContext(r.Context(), in, req.LatestTimestamp, certs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reply := RegisterReply{Instance: in}
+	if cred, ok := s.reg.HeartbeatCredential(in.Env, in.AppId, in.Hostname); ok {
+		reply.Credential = cred
+	}
+	if cert, ok := s.reg.IssuedCertificate(in.Env, in.AppId, in.Hostname); ok {
+		reply.Certificate = cert
+	}
+	if s.instanceSchema(w, r) == registry.InstanceSchemaV2 {
+		writeOK(w, r, RegisterReplyV2{Instance: in.V2(), Credential: reply.Credential, Certificate: reply.Certificate})
+		return
+	}
+	writeOK(w, r, reply)
+}
+
+func (s *HTTPServer) renew(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	if err := validateParams(renewParamList, r.Form); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	env, appid, hostname := r.Form.Get("env"), r.Form.Get("appid"), r.Form.Get("hostname")
+	ctx := r.Context()
+	c, err := capacityParams(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if c != nil {
+		ctx = registry.ContextWithCapacity(ctx, *c)
+	}
+	var reply RenewReply
+	secret := r.Form.Get("secret")
+	if secret != "" {
+		reply.Instance, reply.Credential, err = s.reg.RenewWithCredentialContext(ctx, env, appid, hostname, secret)
+	} else {
+		reply.Instance, err = s.reg.RenewContext(ctx, env, appid, hostname)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if s.certificateHolder(r, reply.Instance, secret) {
+		reply.Certificate, _ = s.reg.IssuedCertificate(env, appid, hostname)
+	}
+	writeOK(w, r, reply)
+}
+
+func (s *HTTPServer) cancel(w http.ResponseWriter, r *http.Request) {
+	env, appid, hostname, err := instanceParams(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	ts, err := int64Param(r, "latest_timestamp")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	in, err := s.reg.CancelContext(r.Context(), env, appid, hostname, ts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, in)
+}
+
+func (s *HTTPServer) fetch(w http.ResponseWriter, r *http.Request) {
+	q, err := bindFetch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	schema := s.instanceSchema(w, r)
+	variant := conditionalFetch(w, r, q, schema)
+	data, err := s.reg.FetchContext(r.Context(), q.env, q.appid, q.status, q.latestTimestamp, q.opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", fetchETag(data.LatestTimestamp, variant))
+	writeFetchData(w, r, data, schema)
+}
+
+// poll 长轮询，参数同 fetch，timeout 为等待时长（如 30s），数据没有变化时返回 304。
+// 带 If-None-Match 时等待 ETag 对应的数据发生变化
+func (s *HTTPServer) poll(w http.ResponseWriter, r *http.Request) {
+	q, err := bindFetch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var timeout time.Duration
+	if v := r.Form.Get("timeout"); v != "" {
+		if timeout, err = time.ParseDuration(v); err != nil {
+			writeError(w, r, badRequest(errors.New("invalid timeout")))
+			return
+		}
+	}
+	if timeout <= 0 || timeout > registry.MaxLongPollTimeout {
+		timeout = registry.DefaultLongPollTimeout
+	}
+	// 长轮询的等待时长可能超过服务端的写超时
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + s.cfg.WriteTimeout))
+	schema := s.instanceSchema(w, r)
+	variant := conditionalFetch(w, r, q, schema)
+	data, err := s.reg.PollContext(r.Context(), q.env, q.appid, q.status, q.latestTimestamp, timeout, q.opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", fetchETag(data.LatestTimestamp, variant))
+	writeFetchData(w, r, data, schema)
+}
+
+type fetchQuery struct {
+	env, appid      string
+	status          uint32
+	latestTimestamp int64
+	opts            registry.FetchOptions
+}
+
+// bindFetch 绑定服务获取的参数
+func bindFetch(r *http.Request) (*fetchQuery, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, badRequest(err)
+	}
+	q := &fetchQuery{env: r.Form.Get("env"), appid: r.Form.Get("appid"), status: registry.StatusUP}
+	if q.env == "" || q.appid == "" {
+		return nil, badRequest(errors.New("env and appid are required"))
+	}
+	if v := r.Form.Get("status"); v != "" {
+		status, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, badRequest(errors.New("invalid status"))
+		}
+		q.status = uint32(status)
+	}
+	var err error
+	if q.latestTimestamp, err = int64Param(r, "latest_timestamp"); err != nil {
+		return nil, err
+	}
+	minScore, err := int64Param(r, "min_health_score")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := int64Param(r, "limit")
+	if err != nil {
+		return nil, err
+	}
+	q.opts = registry.FetchOptions{
+		ClientKey:      r.Form.Get("client_key"),
+		Scheme:         r.Form.Get("scheme"),
+		SecureOnly:     r.Form.Get("secure_only") == "true",
+		Consumer:       r.Form.Get("consumer"),
+		MinHealthScore: int(minScore),
+		AffinityKey:    r.Form.Get("affinity_key"),
+		Limit:          int(limit),
+		Cursor:         r.Form.Get("cursor"),
+	}
+	return q, nil
+}
+
+func (s *HTTPServer) fetchDelta(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	env, appid := r.Form.Get("env"), r.Form.Get("appid")
+	if env == "" || appid == "" {
+		writeError(w, r, badRequest(errors.New("env and appid are required")))
+		return
+	}
+	var revision uint64
+	if v := r.Form.Get("revision"); v != "" {
+		var err error
+		if revision, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, r, badRequest(errors.New("invalid revision")))
+			return
+		}
+	}
+	data, err := s.reg.FetchDelta(r.Context(), env, appid, revision)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, data)
+}
+
+// fetchAll 带 limit 或 cursor 参数时分页返回 registry.FetchAllData，否则返回 appId 到实例的映射
+func (s *HTTPServer) fetchAll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	env := q.Get("env")
+	if env == "" {
+		writeError(w, r, badRequest(errors.New("env is required")))
+		return
+	}
+	if q.Has("limit") || q.Has("cursor") {
+		limit, err := strconv.Atoi(q.Get("limit"))
+		if err != nil && q.Get("limit") != "" {
+			writeError(w, r, badRequest(errors.New("invalid limit")))
+			return
+		}
+		page, err := s.reg.FetchAllPageContext(r.Context(), env, limit, q.Get("cursor"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeOK(w, r, page)
+		return
+	}
+	all, err := s.reg.FetchAllContext(r.Context(), env)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, all)
+}
+
+// fetchBatch 参数 appid 可重复，latest_timestamp 与 appid 按顺序一一对应，可省略
+func (s *HTTPServer) fetchBatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	env, appids := r.Form.Get("env"), r.Form["appid"]
+	if env == "" || len(appids) == 0 {
+		writeError(w, r, badRequest(errors.New("env and appid are required")))
+		return
+	}
+	latest := make(map[string]int64, len(appids))
+	for i, v := range r.Form["latest_timestamp"] {
+		if i >= len(appids) {
+			break
+		}
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeError(w, r, badRequest(errors.New("invalid latest_timestamp")))
+			return
+		}
+		latest[appids[i]] = ts
+	}
+	opts := registry.FetchOptions{Consumer: r.Form.Get("consumer")}
+	rs, err := s.reg.FetchBatchContext(r.Context(), env, appids, latest, registry.StatusUP, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, rs)
+}
+
+// set 请求体为 JSON 格式的 registry.SetRequest
+func (s *HTTPServer) set(w http.ResponseWriter, r *http.Request) {
+	var req registry.SetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	if req.Env == "" || req.AppId == "" || len(req.Hostnames) == 0 {
+		writeError(w, r, badRequest(errors.New("env, appId and hostnames are required")))
+		return
+	}
+	if req.Status == 0 && req.Weight == nil && len(req.Metadata) == 0 {
+		writeError(w, r, badRequest(errors.New("nothing to change")))
+		return
+	}
+	rs, err := s.reg.Set(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, rs)
+}
+
+// transfer 用新主机替换实例，返回新实例及其续约凭证
+func (s *HTTPServer) transfer(w http.ResponseWriter, r *http.Request) {
+	var req registry.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	in, err := s.reg.Transfer(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reply := &RegisterReply{Instance: in}
+	if cred, ok := s.reg.HeartbeatCredential(in.Env, in.AppId, in.Hostname); ok {
+		reply.Credential = cred
+	}
+	if cert, ok := s.reg.IssuedCertificate(in.Env, in.AppId, in.Hostname); ok {
+		reply.Certificate = cert
+	}
+	writeOK(w, r, reply)
+}
+
+// certificateHolder 续约请求能否取回实例的证书：出示了有效的续约凭证，或客户端证书即实例已校验的身份
+func (s *HTTPServer) certificateHolder(r *http.Request, in *registry.Instance, secret string) bool {
+	if secret != "" {
+		_, ok := s.reg.HeartbeatCredential(in.Env, in.AppId, in.Hostname)
+		return ok
+	}
+	return in.SpiffeVerified && in.SpiffeID != "" && callerIdentity(r) == in.SpiffeID
+}
+
+func (s *HTTPServer) health(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.Health())
+}
+
+func (s *HTTPServer) nodes(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.Nodes())
+}
+
+func (s *HTTPServer) capacity(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	env, appid := q.Get("env"), q.Get("appid")
+	if env == "" || appid == "" {
+		writeError(w, r, badRequest(errors.New("env and appid are required")))
+		return
+	}
+	if err := s.reg.CheckVisible(r.Context(), env, appid); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	c, err := s.reg.AppCapacity(env, appid)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, c)
+}
+
+func (s *HTTPServer) conflicts(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.AddrConflictsContext(r.Context()))
+}
+
+// maintenance GET 返回未结束的维护窗口，POST 以 JSON 安排新的维护窗口，需要管理员认证
+func (s *HTTPServer) maintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeOK(w, r, s.reg.MaintenanceWindows())
+	case http.MethodPost:
+		s.admin(s.scheduleMaintenance)(w, r)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeJSON(w, r, http.StatusMethodNotAllowed, Response{Message: "method not allowed"})
+	}
+}
+
+func (s *HTTPServer) scheduleMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req registry.MaintenanceWindow
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	mw, err := s.reg.ScheduleMaintenance(req)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	writeOK(w, r, mw)
+}
+
+func (s *HTTPServer) cancelMaintenance(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	if err := s.reg.CancelMaintenance(r.Context(), r.Form.Get("id")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, nil)
+}
+
+func (s *HTTPServer) freeze(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeOK(w, r, s.reg.Frozen())
+	case http.MethodPost:
+		var req registry.AppFreeze
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		if err := s.reg.Freeze(r.Context(), req); err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		writeOK(w, r, nil)
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeJSON(w, r, http.StatusMethodNotAllowed, Response{Message: "method not allowed"})
+	}
+}
+
+func (s *HTTPServer) unfreeze(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	s.reg.Unfreeze(r.Context(), r.Form.Get("env"), r.Form.Get("appid"))
+	writeOK(w, r, nil)
+}
+
+// replicate 应用对端节点复制过来的写操作，对端未通过校验时返回 403
+func (s *HTTPServer) replicate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	if err := s.reg.ApplyPeerReplication(r.Context(), r.Header.Get(HeaderPeer), r.Header.Get(HeaderPeerSignature), body); err != nil {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			err = badRequest(err)
+		}
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, nil)
+}
+
+func (s *HTTPServer) auditLog(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.AuditLog())
+}
+
+// snapshot 管理员获取全量快照，其他调用方的快照不包含对其不可见的受限应用
+func (s *HTTPServer) snapshot(w http.ResponseWriter, r *http.Request) {
+	if s.isAdmin(r) {
+		writeOK(w, r, s.reg.Snapshot())
+		return
+	}
+	writeOK(w, r, s.reg.SnapshotContext(r.Context()))
+}
+
+func (s *HTTPServer) quotas(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.Quotas())
+}
+
+func (s *HTTPServer) zoneOutages(w http.ResponseWriter, r *http.Request) {
+	writeOK(w, r, s.reg.ZoneOutages())
+}
+
+func (s *HTTPServer) reconcile(w http.ResponseWriter, r *http.Request) {
+	rp, err := s.reg.ReconcileWith(r.Context(), r.URL.Query().Get("peer"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, rp)
+}
+
+// history 参数 at 为 RFC 3339 时间或纳秒时间戳
+func (s *HTTPServer) history(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	env, appid := q.Get("env"), q.Get("appid")
+	if env == "" || appid == "" {
+		writeError(w, r, badRequest(errors.New("env and appid are required")))
+		return
+	}
+	at, err := parseTime(q.Get("at"))
+	if err != nil {
+		writeError(w, r, badRequest(errors.New("invalid at")))
+		return
+	}
+	data, err := s.reg.FetchAt(r.Context(), env, appid, at)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, data)
+}
+
+// heatmap 环境下各应用最近一段时间的活动，window 为时长如 5m
+func (s *HTTPServer) heatmap(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hq := registry.HeatmapQuery{Env: q.Get("env"), AppId: q.Get("appid")}
+	if hq.Env == "" {
+		writeError(w, r, badRequest(errors.New("env is required")))
+		return
+	}
+	if v := q.Get("window"); v != "" {
+		var err error
+		if hq.Window, err = time.ParseDuration(v); err != nil {
+			writeError(w, r, badRequest(errors.New("invalid window")))
+			return
+		}
+	}
+	writeOK(w, r, s.reg.Heatmap(r.Context(), hq))
+}
+
+// parseTime 解析 RFC 3339 时间或纳秒时间戳
+func parseTime(v string) (int64, error) {
+	if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return ts, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
+// bindRegister 绑定注册请求，支持 JSON 请求体及表单参数，表单中 metadata、port_names 为 JSON 字符串
+func bindRegister(r *http.Request, req *registry.RequestRegister) error {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return json.NewDecoder(r.Body).Decode(req)
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	f := r.Form
+	req.Env = f.Get("env")
+	req.AppId = f.Get("appid")
+	req.Hostname = f.Get("hostname")
+	req.Addrs = formList(f["addrs[]"], f["addrs"])
+	req.SecureAddrs = formList(f["secure_addrs[]"], f["secure_addrs"])
+	req.Version = f.Get("version")
+	req.Group = f.Get("group")
+	req.SpiffeID = f.Get("spiffe_id")
+	req.InstanceID = f.Get("instance_id")
+	for name, dst := range map[string]*uint32{"status": &req.Status, "weight": &req.Weight} {
+		if v := f.Get(name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return fieldError(name, "must be an unsigned integer")
+			}
+			*dst = uint32(n)
+		}
+	}
+	for name, dst := range map[string]*int64{"latest_timestamp": &req.LatestTimestamp, "dirty_timestamp": &req.DirtyTimestamp} {
+		if v := f.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fieldError(name, "must be an integer")
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]*map[string]string{"metadata": &req.Metadata, "port_names": &req.PortNames} {
+		if v := f.Get(name); v != "" {
+			if err := json.Unmarshal([]byte(v), dst); err != nil {
+				return fieldError(name, "must be a JSON object")
+			}
+		}
+	}
+	return nil
+}
+
+func formList(lists ...[]string) []string {
+	var rs []string
+	for _, l := range lists {
+		rs = append(rs, l...)
+	}
+	return rs
+}
+
+// instanceParams 解析定位实例的 env, appid, hostname 参数
+func instanceParams(r *http.Request) (env, appid, hostname string, err error) {
+	if err = r.ParseForm(); err != nil {
+		return "", "", "", badRequest(err)
+	}
+	env, appid, hostname = r.Form.Get("env"), r.Form.Get("appid"), r.Form.Get("hostname")
+	if env == "" || appid == "" || hostname == "" {
+		return "", "", "", badRequest(errors.New("env, appid and hostname are required"))
+	}
+	return env, appid, hostname, nil
+}
+
+// capacityParams 续约时附带的容量参数，都没有时返回 nil
+func capacityParams(r *http.Request) (*registry.Capacity, error) {
+	maxConcurrent, inFlight, utilization := r.Form.Get("max_concurrent"), r.Form.Get("in_flight"), r.Form.Get("utilization")
+	if maxConcurrent == "" && inFlight == "" && utilization == "" {
+		return nil, nil
+	}
+	var c registry.Capacity
+	var err error
+	if c.MaxConcurrent, err = int64Param(r, "max_concurrent"); err != nil {
+		return nil, err
+	}
+	if c.InFlight, err = int64Param(r, "in_flight"); err != nil {
+		return nil, err
+	}
+	if utilization != "" {
+		if c.Utilization, err = strconv.ParseFloat(utilization, 64); err != nil {
+			return nil, badRequest(errors.New("invalid utilization"))
+		}
+	}
+	if c.MaxConcurrent < 0 || c.InFlight < 0 || c.Utilization < 0 {
+		return nil, badRequest(errors.New("invalid capacity"))
+	}
+	return &c, nil
+}
+
+func int64Param(r *http.Request, name string) (int64, error) {
+	v := r.Form.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, badRequest(errors.New("invalid " + name))
+	}
+	return n, nil
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func call(t *testing.T, h http.Handler, method, path string, form url.Values) (int, Response) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp Response
+	resp.Data = &json.RawMessage{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestHTTPServer(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	inst := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "hostname": {"h1"}}
+
+	if code, _ := call(t, s, http.MethodPost, PathRegister, url.Values{"env": {"test"}}); code != http.StatusBadRequest {
+		t.Fatalf("expect 400 for missing params, got %d", code)
+	}
+	reg := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "hostname": {"h1"}, "addrs[]": {"http://127.0.0.1:80"}, "metadata": {`{"zone":"z1"}`}}
+	if code, resp := call(t, s, http.MethodPost, PathRegister, reg); code != http.StatusOK || resp.RequestID == "" {
+		t.Fatalf("register failed: %d %+v", code, resp)
+	}
+	if code, _ := call(t, s, http.MethodGet, PathRegister, reg); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expect 405, got %d", code)
+	}
+	if code, _ := call(t, s, http.MethodPost, PathRenew, inst); code != http.StatusOK {
+		t.Fatalf("renew failed: %d", code)
+	}
+
+	code, resp := call(t, s, http.MethodGet, PathFetch+"?env=test&appid=com.xx.a", nil)
+	data := *resp.Data.(*json.RawMessage)
+	var fetched registry.FetchData
+	json.Unmarshal(data, &fetched)
+	if code != http.StatusOK || len(fetched.Instances) != 1 || fetched.Instances[0].Metadata["zone"] != "z1" {
+		t.Fatalf("unexpected fetch: %d %s", code, data)
+	}
+	latest := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "latest_timestamp": {strconv.FormatInt(fetched.LatestTimestamp, 10)}}
+	if code, _ := call(t, s, http.MethodGet, PathFetch+"?"+latest.Encode(), nil); code != http.StatusNotModified {
+		t.Fatalf("expect 304, got %d", code)
+	}
+
+	if code, _ := call(t, s, http.MethodPost, PathCancel, inst); code != http.StatusOK {
+		t.Fatalf("cancel failed: %d", code)
+	}
+	if code, _ := call(t, s, http.MethodPost, PathRenew, inst); code != http.StatusNotFound {
+		t.Fatalf("expect 404 after cancel, got %d", code)
+	}
+}
+
+func TestQuotaWarningHeader(t *testing.T) {
+	reg := registry.NewRegistry(registry.WithSoftQuotaRatio(0.5))
+	reg.SetQuota(registry.AppQuota{Env: "test", AppId: "com.xx.a", MaxInstances: 2})
+	s := NewHTTPServer(reg, ServerConfig{})
+	register := func(host string) *httptest.ResponseRecorder {
+		form := url.Values{"env": {"test"}, "appid": {"com.xx.a"}, "hostname": {host}, "addrs[]": {"http://" + host + ":80"}}
+		req := httptest.NewRequest(http.MethodPost, PathRegister, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := register("h1")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get(HeaderQuotaWarning), registry.QuotaInstances+" test/com.xx.a 1 of 2") {
+		t.Fatalf("expect quota warning header, got %d %q", rec.Code, rec.Header().Get(HeaderQuotaWarning))
+	}
+	register("h2")
+	if rec := register("h3"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expect 429 over quota, got %d", rec.Code)
+	}
+	code, resp := call(t, s, http.MethodGet, PathQuotas, nil)
+	var qs []registry.QuotaUsage
+	json.Unmarshal(*resp.Data.(*json.RawMessage), &qs)
+	if code != http.StatusOK || len(qs) != 1 || qs[0].Instances != 2 || !qs[0].Warning {
+		t.Fatalf("unexpected quotas: %d %+v", code, qs)
+	}
+}
+
+func TestRegisterReturnsCertificate(t *testing.T) {
+	ca, err := registry.NewCertificateAuthority("example.org", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployer := "spiffe://example.org/deployer/ca"
+	s := NewHTTPServer(registry.NewRegistry(registry.WithCertificateAuthority(ca), registry.WithHeartbeatCredentials(time.Hour, time.Minute),
+		registry.WithCertificateBindings(registry.CertificateBinding{Env: "test", AppId: "com.xx.ca", Callers: []string{deployer}})), ServerConfig{})
+	register := func(hostname, caller string) (int, RegisterReply) {
+		form := url.Values{"env": {"test"}, "appid": {"com.xx.ca"}, "hostname": {hostname}, "addrs[]": {"http://127.0.0.1:80"}}
+		req := httptest.NewRequest(http.MethodPost, PathRegister, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if caller != "" {
+			u, _ := url.Parse(caller)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{URIs: []*url.URL{u}}}}
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		var reply RegisterReply
+		json.Unmarshal(rec.Body.Bytes(), &Response{Data: &reply})
+		return rec.Code, reply
+	}
+	if code, anonymous := register("h0", ""); code != http.StatusOK || anonymous.Certificate != nil {
+		t.Fatalf("anonymous register must not get a certificate: %d %+v", code, anonymous)
+	}
+	if code, unbound := register("h0", "spiffe://example.org/other"); code != http.StatusOK || unbound.Certificate != nil {
+		t.Fatalf("unbound caller must not get a certificate: %d %+v", code, unbound)
+	}
+	code, registered := register("h1", deployer)
+	if code != http.StatusOK || registered.Certificate == nil || len(registered.Certificate.CAPEM) == 0 || registered.Credential == nil {
+		t.Fatalf("register should return the certificate and chain: %d %+v", code, registered)
+	}
+
+	inst := url.Values{"env": {"test"}, "appid": {"com.xx.ca"}, "hostname": {"h1"}}
+	_, resp := call(t, s, http.MethodPost, PathRenew, inst)
+	var renewed RenewReply
+	json.Unmarshal(*resp.Data.(*json.RawMessage), &renewed)
+	if renewed.Certificate != nil {
+		t.Fatal("renew without a credential must not return the certificate")
+	}
+	inst.Set("secret", registered.Credential.Secret)
+	_, resp = call(t, s, http.MethodPost, PathRenew, inst)
+	json.Unmarshal(*resp.Data.(*json.RawMessage), &renewed)
+	if renewed.Certificate == nil || string(renewed.Certificate.CertPEM) != string(registered.Certificate.CertPEM) {
+		t.Fatalf("renew with a credential should return the current certificate: %+v", renewed)
+	}
+}
+
+func TestRegisterVerifiesSpiffeID(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(registry.WithSpiffeVerification()), ServerConfig{})
+	id := "spiffe://example.org/ns/test/sa/webapi"
+	u, _ := url.Parse(id)
+	register := func(hostname string, certs []*x509.Certificate) int {
+		form := url.Values{"env": {"test"}, "appid": {"com.xx.spiffe"}, "hostname": {hostname}, "addrs[]": {"http://127.0.0.1:80"}, "spiffe_id": {id}}
+		req := httptest.NewRequest(http.MethodPost, PathRegister, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if certs != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := register("h1", nil); code != http.StatusUnauthorized {
+		t.Fatalf("register without a client certificate should be 401, got %d", code)
+	}
+	other, _ := url.Parse("spiffe://example.org/other")
+	if code := register("h1", []*x509.Certificate{{URIs: []*url.URL{other}}}); code != http.StatusForbidden {
+		t.Fatalf("register with a mismatched certificate should be 403, got %d", code)
+	}
+	if code := register("h1", []*x509.Certificate{{URIs: []*url.URL{u}}}); code != http.StatusOK {
+		t.Fatalf("register with a matching certificate failed: %d", code)
+	}
+	d, err := s.reg.GetInstance("test", "com.xx.spiffe", "h1")
+	if err != nil || !d.Instance.SpiffeVerified {
+		t.Fatalf("instance should be spiffe verified: %+v %v", d, err)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PathEventStream Server-Sent Events 变更推送
+const PathEventStream = "/api/events/stream"
+
+// sseHeartbeat 没有事件时发送注释行的间隔，防止代理断开空闲连接
+const sseHeartbeat = 15 * time.Second
+
+// SSEEventReset 推送中断的 SSE 事件名，客户端需要重新全量拉取，实例变更的事件名为 registry.EventType
+const SSEEventReset = "reset"
+
+// eventStream 以 SSE 推送 env（及 appid）下的注册、下线、剔除和状态变更事件，
+// 事件 id 为事件序号，断线重连时浏览器通过 Last-Event-ID 从断点继续。
+// 连接受 RequestTimeout 限制，超时后由客户端自动重连
+func (s *HTTPServer) eventStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	env, appid := q.Get("env"), q.Get("appid")
+	if env == "" {
+		writeError(w, r, badRequest(errors.New("env is required")))
+		return
+	}
+	if appid != "" {
+		if err := s.reg.CheckVisible(r.Context(), env, appid); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	seq := s.reg.LastEventSeq()
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = q.Get("since")
+	}
+	if last != "" {
+		v, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			writeError(w, r, badRequest(errors.New("invalid last event id")))
+			return
+		}
+		seq = v
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New("streaming unsupported"))
+		return
+	}
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	for ctx.Err() == nil {
+		wctx, cancel := context.WithTimeout(ctx, sseHeartbeat)
+		events, err := s.reg.WaitEvents(wctx, seq, 100)
+		cancel()
+		switch {
+		case errors.Is(err, registry.ErrEventsTruncated):
+			seq = s.reg.LastEventSeq()
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: {}\n\n", seq, SSEEventReset)
+		case err != nil:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		for i := range events {
+			e := &events[i]
+			seq = e.Seq
+			if e.Env != env || (appid != "" && e.AppId != appid) || !s.reg.Visible(ctx, e.Env, e.AppId) {
+				continue
+			}
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestEventStream(t *testing.T) {
+	reg := registry.NewRegistry()
+	defer reg.Close()
+	srv := httptest.NewServer(NewHTTPServer(reg, ServerConfig{}))
+	defer srv.Close()
+
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "a1", Status: registry.StatusUP}, 1)
+	resp, err := http.Get(srv.URL + PathEventStream + "?env=test&appid=com.xx.b&since=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.b", Hostname: "b1", Status: registry.StatusUP}, 1)
+	reg.Cancel("test", "com.xx.b", "b1", 2)
+
+	sc := bufio.NewScanner(resp.Body)
+	var types []string
+	var e registry.Event
+	for len(types) < 2 && sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			types = append(types, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e)
+			if e.AppId != "com.xx.b" {
+				t.Fatalf("other app should be filtered, got %+v", e)
+			}
+		}
+	}
+	if len(types) != 2 || types[0] != string(registry.EventRegister) || types[1] != string(registry.EventCancel) {
+		t.Fatalf("unexpected events %v", types)
+	}
+
+	if code, _ := call(t, NewHTTPServer(reg, ServerConfig{}), http.MethodGet, PathEventStream, nil); code != http.StatusBadRequest {
+		t.Fatalf("expect 400 without env, got %d", code)
+	}
+}
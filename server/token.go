@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// 只读令牌管理接口，需要管理员认证
+const (
+	PathTokens      = "/api/tokens"        // GET 列出本节点签发的令牌，POST 签发令牌
+	PathTokenRevoke = "/api/tokens/revoke" // 吊销令牌
+)
+
+// tokenPaths 出示只读令牌的请求可以访问的接口，其余接口返回 403
+var tokenPaths = map[string]bool{
+	PathFetch: true, PathFetchDelta: true, PathFetchAll: true, PathFetchBatch: true, PathPoll: true,
+	PathHistory: true, PathHeatmap: true, PathCapacity: true, PathEventStream: true, PathWebSocket: true,
+	PathNodes: true, PathHealth: true, PathOpenAPI: true,
+}
+
+// TokenReply 签发令牌的返回数据，Token 只在签发时返回一次
+type TokenReply struct {
+	Token string                `json:"token"`
+	Scope *registry.ScopedToken `json:"scope"`
+}
+
+// bearerToken 校验 Authorization 请求头中的只读令牌，没有出示令牌时返回 nil。
+// 出示令牌的请求只能访问 tokenPaths 中的读接口，并且只能读取令牌范围内的应用
+func (s *HTTPServer) bearerToken(r *http.Request) (*registry.ScopedToken, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !strings.HasPrefix(token, registry.TokenPrefix) {
+		return nil, nil
+	}
+	t, err := s.reg.VerifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !tokenPaths[r.URL.Path] || r.Method != http.MethodGet {
+		return nil, registry.ErrPermissionDenied
+	}
+	return t, nil
+}
+
+func (s *HTTPServer) tokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeOK(w, r, s.reg.Tokens())
+	case http.MethodPost:
+		var req registry.TokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, badRequest(err))
+			return
+		}
+		token, scope, err := s.reg.IssueToken(r.Context(), req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeOK(w, r, TokenReply{Token: token, Scope: scope})
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeJSON(w, r, http.StatusMethodNotAllowed, Response{Message: "method not allowed"})
+	}
+}
+
+func (s *HTTPServer) revokeToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, badRequest(err))
+		return
+	}
+	id := r.Form.Get("id")
+	if id == "" {
+		writeError(w, r, badRequest(errors.New("id is required")))
+		return
+	}
+	if err := s.reg.RevokeToken(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeOK(w, r, nil)
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestScopedTokenHTTP(t *testing.T) {
+	r := registry.NewRegistry()
+	s := NewHTTPServer(r, ServerConfig{Admin: &AdminConfig{Token: "s3cret"}})
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.a", Hostname: "h1", Status: registry.StatusUP}, 1)
+	r.Register(&registry.Instance{Env: "test", AppId: "com.xx.b", Hostname: "h1", Status: registry.StatusUP}, 1)
+	r.SetVisibility(registry.AppVisibility{Env: "test", AppId: "com.xx.a", Visibility: registry.VisibilityRestricted})
+
+	body, _ := json.Marshal(registry.TokenRequest{Subject: "ci", Env: "test", AppIds: []string{"com.xx.a"}})
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, PathTokens, bytes.NewReader(body)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("issuing a token without admin auth should be 401, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, PathTokens, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var resp struct {
+		Data TokenReply `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+		t.Fatalf("issue token failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	do := func(method, target, token string) int {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for _, c := range []struct {
+		method, target, token string
+		code                  int
+	}{
+		{http.MethodGet, PathFetch + "?env=test&appid=com.xx.a", resp.Data.Token, http.StatusOK},
+		{http.MethodGet, PathFetch + "?env=test&appid=com.xx.a", "", http.StatusNotFound},
+		{http.MethodGet, PathFetch + "?env=test&appid=com.xx.b", resp.Data.Token, http.StatusNotFound},
+		{http.MethodGet, PathCapacity + "?env=test&appid=com.xx.b", resp.Data.Token, http.StatusNotFound},
+		{http.MethodPost, PathCancel + "?env=test&appid=com.xx.a&hostname=h1", resp.Data.Token, http.StatusForbidden},
+		{http.MethodGet, PathTokens, resp.Data.Token, http.StatusForbidden},
+		{http.MethodGet, PathTokens, "s3cret", http.StatusOK},
+		{http.MethodPost, PathTokenRevoke + "?id=x", "", http.StatusUnauthorized},
+		{http.MethodGet, PathFetch + "?env=test&appid=com.xx.a", registry.TokenPrefix + "forged.sig", http.StatusUnauthorized},
+	} {
+		if code := do(c.method, c.target, c.token); code != c.code {
+			t.Fatalf("%s %s: expect %d, got %d", c.method, c.target, c.code, code)
+		}
+	}
+}
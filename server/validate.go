@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func fieldError(field, reason string) error {
+	return &registry.InvalidRequestError{Violations: []registry.FieldViolation{{Field: field, Reason: reason}}}
+}
+
+// validateParams 按接口参数定义校验请求，空值视为未传
+func validateParams(params []apiParam, values url.Values) *registry.InvalidRequestError {
+	ve := &registry.InvalidRequestError{}
+	for _, p := range params {
+		var vs []string
+		for _, v := range values[p.Name] {
+			if v != "" {
+				vs = append(vs, v)
+			}
+		}
+		if len(vs) == 0 {
+			if p.Required {
+				ve.Violations = append(ve.Violations, registry.FieldViolation{Field: p.Name, Reason: "is required"})
+			}
+			continue
+		}
+		for _, v := range vs {
+			if reason := p.check(v); reason != "" {
+				ve.Violations = append(ve.Violations, registry.FieldViolation{Field: p.Name, Reason: reason})
+				break
+			}
+		}
+	}
+	if len(ve.Violations) == 0 {
+		return nil
+	}
+	return ve
+}
+
+// check 校验单个值，通过时返回空
+func (p apiParam) check(v string) string {
+	var n float64
+	switch p.Type {
+	case "integer":
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "must be an integer"
+		}
+		n = float64(i)
+	case "number":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		n = f
+	case "boolean":
+		if _, err := strconv.ParseBool(v); err != nil {
+			return "must be a boolean"
+		}
+	}
+	if p.Minimum != nil && n < *p.Minimum {
+		return "must be at least " + strconv.FormatFloat(*p.Minimum, 'f', -1, 64)
+	}
+	if len(p.Enum) > 0 {
+		ok := false
+		for _, e := range p.Enum {
+			ok = ok || e == v
+		}
+		if !ok {
+			return "must be one of " + strings.Join(p.Enum, ", ")
+		}
+	}
+	switch p.Format {
+	case "addr":
+		if !registry.ValidAddr(v) {
+			return "must be scheme://host[:port] or host:port"
+		}
+	case "duration":
+		if _, err := time.ParseDuration(v); err != nil {
+			return "must be a duration such as 30s"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 接口版本。v1 与不带前缀的 /api 路径完全兼容；v2 统一使用 snake_case 字段名（appId 改为 app_id，
+// 参数 appid 改为 app_id），并在响应中增加 meta。两个版本由同一组处理函数提供，v2 只做报文转换
+const (
+	APIVersion1 = "v1"
+	APIVersion2 = "v2"
+
+	// HeaderAPIVersion 请求时指定 /api 路径使用的版本，响应时返回实际使用的版本
+	HeaderAPIVersion = "X-Registry-API-Version"
+	// MediaTypeV2 Accept 为该类型时 /api 路径使用 v2
+	MediaTypeV2 = "application/vnd.registry.v2+json"
+)
+
+// Meta v2 响应中的附加信息
+type Meta struct {
+	APIVersion string `json:"api_version"`
+	ServerTime int64  `json:"server_time"` // 服务端处理请求的时间
+}
+
+// apiVersion 按路径前缀或协商头确定接口版本，带前缀的路径改写为对应的 /api 路径。
+// /v1/fetch 与 /v2/fetch 都由 /api/fetch 处理
+func apiVersion(r *http.Request) string {
+	for _, v := range []string{APIVersion1, APIVersion2} {
+		if prefix := "/" + v + "/"; strings.HasPrefix(r.URL.Path, prefix) {
+			r.URL.Path = "/api/" + strings.TrimPrefix(r.URL.Path, prefix)
+			r.URL.RawPath = ""
+			return v
+		}
+	}
+	if v := r.Header.Get(HeaderAPIVersion); v == "2" || v == APIVersion2 {
+		return APIVersion2
+	}
+	if strings.Contains(r.Header.Get("Accept"), MediaTypeV2) {
+		return APIVersion2
+	}
+	return APIVersion1
+}
+
+// v2Request 把 v2 请求的参数名和 JSON 字段名转换为处理函数使用的 v1 名称
+func v2Request(r *http.Request) error {
+	if q := r.URL.Query(); q.Has("app_id") {
+		renameParam(q, "app_id", "appid")
+		r.URL.RawQuery = q.Encode()
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	ct := r.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ct, "application/x-www-form-urlencoded"):
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return badRequest(err)
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return badRequest(err)
+		}
+		renameParam(form, "app_id", "appid")
+		r.Body = io.NopCloser(strings.NewReader(form.Encode()))
+	case strings.HasPrefix(ct, "application/json"):
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return badRequest(err)
+		}
+		if body, err = renameJSON(body, "app_id", "appId", nil); err != nil {
+			return badRequest(err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return nil
+}
+
+func renameParam(values url.Values, from, to string) {
+	if vs, ok := values[from]; ok {
+		values[to] = append(values[to], vs...)
+		delete(values, from)
+	}
+}
+
+// renameJSON 重命名 JSON 中所有对象的字段 from，metadata 中的用户数据保持不变。
+// top 不为空时在顶层对象中加入这些字段
+func renameJSON(body []byte, from, to string, top map[string]interface{}) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	v = renameKeys(v, from, to)
+	if obj, ok := v.(map[string]interface{}); ok {
+		for k, val := range top {
+			obj[k] = val
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renameKeys(v interface{}, from, to string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		rs := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if k != "metadata" {
+				val = renameKeys(val, from, to)
+			}
+			if k == from {
+				k = to
+			}
+			rs[k] = val
+		}
+		return rs
+	case []interface{}:
+		for i := range v {
+			v[i] = renameKeys(v[i], from, to)
+		}
+	}
+	return v
+}
+
+// v2Writer 缓存 JSON 响应，处理函数返回后转换为 v2 格式。
+// SSE、WebSocket 等非 JSON 响应原样透传
+type v2Writer struct {
+	http.ResponseWriter
+	code      int
+	buffering bool
+	buf       bytes.Buffer
+}
+
+func (w *v2Writer) WriteHeader(code int) {
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		w.buffering = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *v2Writer) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *v2Writer) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.buffering {
+		f.Flush()
+	}
+}
+
+func (w *v2Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *v2Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish 转换并写出缓存的响应，转换失败时原样写出
+func (w *v2Writer) finish() {
+	if !w.buffering {
+		return
+	}
+	body := w.buf.Bytes()
+	meta := map[string]interface{}{"meta": Meta{APIVersion: APIVersion2, ServerTime: time.Now().UnixNano()}}
+	if out, err := renameJSON(body, "appId", "app_id", meta); err == nil {
+		body = out
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.code)
+	w.ResponseWriter.Write(body)
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func TestAPIVersion(t *testing.T) {
+	s := NewHTTPServer(registry.NewRegistry(), ServerConfig{})
+	reg := url.Values{"env": {"test"}, "app_id": {"com.xx.v2"}, "hostname": {"h1"}, "addrs": {"http://127.0.0.1:80"},
+		"metadata": {`{"appId":"kept"}`}}
+	if code, resp := call(t, s, http.MethodPost, "/v2/register", reg); code != http.StatusOK {
+		t.Fatalf("v2 register failed: %d %+v", code, resp)
+	}
+
+	get := func(path string, header http.Header) (string, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response %q", rec.Body.String())
+		}
+		return rec.Header().Get(HeaderAPIVersion), body
+	}
+	instance := func(body map[string]interface{}) map[string]interface{} {
+		return body["data"].(map[string]interface{})["instances"].([]interface{})[0].(map[string]interface{})
+	}
+
+	version, body := get("/v2/fetch?env=test&app_id=com.xx.v2", nil)
+	in := instance(body)
+	if version != APIVersion2 || in["app_id"] != "com.xx.v2" || in["appId"] != nil || body["meta"] == nil {
+		t.Fatalf("unexpected v2 response %v", body)
+	}
+	if md := in["metadata"].(map[string]interface{}); md["appId"] != "kept" {
+		t.Fatalf("metadata should not be translated: %v", md)
+	}
+
+	for _, path := range []string{"/v1/fetch?env=test&appid=com.xx.v2", "/api/fetch?env=test&appid=com.xx.v2"} {
+		version, body := get(path, nil)
+		if in := instance(body); version != APIVersion1 || in["appId"] != "com.xx.v2" || body["meta"] != nil {
+			t.Fatalf("unexpected v1 response for %s: %v", path, body)
+		}
+	}
+	version, body = get("/api/fetch?env=test&app_id=com.xx.v2", http.Header{"Accept": {MediaTypeV2}})
+	if in := instance(body); version != APIVersion2 || in["app_id"] != "com.xx.v2" {
+		t.Fatalf("accept header should negotiate v2: %v", body)
+	}
+	if _, body := get("/v2/fetch?env=test&app_id=missing", nil); !strings.Contains(body["message"].(string), "not") {
+		t.Fatalf("errors should pass through v2: %v", body)
+	}
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+// PathWebSocket WebSocket 推送通道
+const PathWebSocket = "/api/ws"
+
+// WebSocket 连接参数
+const (
+	wsPingInterval = 30 * time.Second // 服务端发送 ping 的间隔，两个间隔内没有收到 pong 时断开
+	wsMaxMessage   = 64 << 10         // 客户端消息的最大长度
+	wsGUID         = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// WebSocket 帧类型
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// WebSocket 消息
+const (
+	WSOpSubscribe   = "subscribe"   // 订阅应用，订阅后立即推送一次当前数据
+	WSOpUnsubscribe = "unsubscribe" // 取消订阅
+
+	WSModeSnapshot = "snapshot" // 变更时推送 FetchData 全量快照，默认
+	WSModeDelta    = "delta"    // 变更时推送 DeltaData 增量
+
+	WSTypeSnapshot = "snapshot"
+	WSTypeDelta    = "delta"
+	WSTypeError    = "error"
+)
+
+// WSRequest 客户端发送的订阅消息
+type WSRequest struct {
+	Op    string `json:"op"`
+	Env   string `json:"env"`
+	AppId string `json:"appId"`
+	Mode  string `json:"mode,omitempty"`
+}
+
+// WSPush 服务端推送的消息，Data 为 FetchData 或 DeltaData
+type WSPush struct {
+	Type    string      `json:"type"`
+	Env     string      `json:"env,omitempty"`
+	AppId   string      `json:"appId,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// wsSubscription 一个订阅的应用及已推送的位置
+type wsSubscription struct {
+	env, appid string
+	mode       string
+	latest     int64  // 快照模式已推送的 LatestTimestamp
+	revision   uint64 // 增量模式已推送的 Revision
+}
+
+// wsConn 服务端的 WebSocket 连接，只支持不分片的文本消息
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	lock sync.Mutex // 串行化写
+}
+
+// websocket 升级为 WebSocket 连接，客户端订阅应用后在应用变化时收到推送
+func (s *HTTPServer) websocket(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Key") == "" {
+		writeError(w, r, badRequest(errors.New("websocket upgrade required")))
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, r, errors.New("websocket unsupported"))
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// 升级后的连接不受 http.Server 读写超时限制
+	conn.SetDeadline(time.Time{})
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+	// 升级后不再受 RequestTimeout 限制，只保留请求 ID 等上下文
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	s.serveWebSocket(ctx, cancel, &wsConn{conn: conn, rw: rw})
+}
+
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func (s *HTTPServer) serveWebSocket(ctx context.Context, cancel context.CancelFunc, c *wsConn) {
+	var lock sync.Mutex
+	subs := make(map[string]*wsSubscription)
+	changed := make(chan struct{}, 1)
+	var lastPong time.Time
+	pong := func() {
+		lock.Lock()
+		lastPong = time.Now()
+		lock.Unlock()
+	}
+	pong()
+
+	// 读取客户端消息
+	go func() {
+		defer cancel()
+		for {
+			op, payload, err := c.readFrame(true)
+			if err != nil {
+				return
+			}
+			switch op {
+			case wsOpPing:
+				c.writeFrame(wsOpPong, payload, false)
+			case wsOpPong:
+				pong()
+			case wsOpClose:
+				c.writeFrame(wsOpClose, nil, false)
+				return
+			case wsOpText:
+				var req WSRequest
+				if err := json.Unmarshal(payload, &req); err != nil || req.Env == "" || req.AppId == "" {
+					c.writeJSON(WSPush{Type: WSTypeError, Message: "invalid request"})
+					continue
+				}
+				key := req.AppId + "-" + req.Env
+				lock.Lock()
+				switch req.Op {
+				case WSOpSubscribe:
+					mode := req.Mode
+					if mode != WSModeDelta {
+						mode = WSModeSnapshot
+					}
+					subs[key] = &wsSubscription{env: req.Env, appid: req.AppId, mode: mode}
+				case WSOpUnsubscribe:
+					delete(subs, key)
+				default:
+					c.writeJSON(WSPush{Type: WSTypeError, Message: "unknown op " + req.Op})
+				}
+				lock.Unlock()
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	// 应用变化或有新订阅时推送，定期发送 ping
+	seq := s.reg.LastEventSeq()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		wctx, wcancel := context.WithCancel(ctx)
+		events := make(chan struct{})
+		go func() {
+			defer close(events)
+			if es, err := s.reg.WaitEvents(wctx, seq, 0); len(es) > 0 {
+				seq = es[len(es)-1].Seq
+			} else if errors.Is(err, registry.ErrEventsTruncated) {
+				seq = s.reg.LastEventSeq()
+			}
+		}()
+		select {
+		case <-events:
+		case <-changed:
+		case <-ping.C:
+			lock.Lock()
+			dead := time.Since(lastPong) > 2*wsPingInterval
+			lock.Unlock()
+			if dead || c.writeFrame(wsOpPing, nil, false) != nil {
+				cancel()
+			}
+		case <-ctx.Done():
+		}
+		wcancel()
+		<-events
+		if ctx.Err() != nil {
+			c.writeFrame(wsOpClose, nil, false)
+			return
+		}
+		lock.Lock()
+		pending := make([]*wsSubscription, 0, len(subs))
+		for _, sub := range subs {
+			pending = append(pending, sub)
+		}
+		lock.Unlock()
+		for _, sub := range pending {
+			if err := s.pushSubscription(ctx, c, sub); err != nil {
+				cancel()
+			}
+		}
+	}
+}
+
+// pushSubscription 订阅的应用有变化时推送，没有变化时不推送
+func (s *HTTPServer) pushSubscription(ctx context.Context, c *wsConn, sub *wsSubscription) error {
+	push := WSPush{Env: sub.env, AppId: sub.appid}
+	if sub.mode == WSModeDelta {
+		d, err := s.reg.FetchDelta(ctx, sub.env, sub.appid, sub.revision)
+		if err != nil {
+			return c.writeJSON(WSPush{Type: WSTypeError, Env: sub.env, AppId: sub.appid, Message: err.Error()})
+		}
+		if !d.Full && len(d.Changes) == 0 && sub.revision != 0 {
+			sub.revision = d.Revision
+			return nil
+		}
+		sub.revision = d.Revision
+		push.Type, push.Data = WSTypeDelta, d
+		return c.writeJSON(push)
+	}
+	data, err := s.reg.FetchContext(ctx, sub.env, sub.appid, registry.StatusUP, sub.latest, registry.FetchOptions{})
+	switch {
+	case errors.Is(err, registry.ErrNotModified):
+		return nil
+	case errors.Is(err, registry.ErrAppNotFound), errors.Is(err, registry.ErrNoInstances):
+		// 应用没有可用实例时推送空列表，之后有实例注册时再推送
+		if sub.latest < 0 {
+			return nil
+		}
+		sub.latest = -1
+		data = &registry.FetchData{Instances: make([]*registry.Instance, 0)}
+	case err != nil:
+		return c.writeJSON(WSPush{Type: WSTypeError, Env: sub.env, AppId: sub.appid, Message: err.Error()})
+	default:
+		sub.latest = data.LatestTimestamp
+	}
+	push.Type, push.Data = WSTypeSnapshot, data
+	return c.writeJSON(push)
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(wsOpText, b, false)
+}
+
+// writeFrame 写一个完整的帧，客户端发送的帧需要掩码
+func (c *wsConn) writeFrame(op byte, payload []byte, mask bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	hdr := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xFFFF:
+		hdr[1] = 126
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = binary.BigEndian.AppendUint64(hdr, uint64(n))
+	}
+	if mask {
+		hdr[1] |= 0x80
+		key := [4]byte{0x12, 0x34, 0x56, 0x78}
+		hdr = append(hdr, key[:]...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ key[i%4]
+		}
+		payload = masked
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := c.rw.Write(hdr); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readFrame 读一个完整的帧，masked 表示对端必须使用掩码（服务端读客户端的帧）
+func (c *wsConn) readFrame(masked bool) (byte, []byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	if hdr[0]&0x80 == 0 {
+		return 0, nil, errors.New("fragmented websocket frame")
+	}
+	op := hdr[0] & 0x0F
+	if (hdr[1]&0x80 != 0) != masked {
+		return 0, nil, errors.New("invalid websocket mask")
+	}
+	n := uint64(hdr[1] & 0x7F)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.rw, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.rw, b[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > wsMaxMessage {
+		return 0, nil, errors.New("websocket message too large")
+	}
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.rw, key[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return op, payload, nil
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	registry "github.com/junaozun/registry-center"
+)
+
+func dialWS(t *testing.T, url string) *wsConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest(http.MethodGet, url+PathWebSocket, nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Write(conn)
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	resp, err := http.ReadResponse(rw.Reader, req)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake failed: %+v %v", resp, err)
+	}
+	return &wsConn{conn: conn, rw: rw}
+}
+
+func readPush(t *testing.T, c *wsConn, data interface{}) WSPush {
+	t.Helper()
+	op, payload, err := c.readFrame(false)
+	if err != nil || op != wsOpText {
+		t.Fatalf("read push failed: %d %v", op, err)
+	}
+	push := WSPush{Data: data}
+	if err := json.Unmarshal(payload, &push); err != nil {
+		t.Fatal(err)
+	}
+	return push
+}
+
+func TestWebSocket(t *testing.T) {
+	reg := registry.NewRegistry()
+	defer reg.Close()
+	srv := httptest.NewServer(NewHTTPServer(reg, ServerConfig{}))
+	defer srv.Close()
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.ws", Hostname: "a", Status: registry.StatusUP}, 1)
+
+	c := dialWS(t, srv.URL)
+	sub, _ := json.Marshal(WSRequest{Op: WSOpSubscribe, Env: "test", AppId: "com.xx.ws"})
+	c.writeFrame(wsOpText, sub, true)
+	var snap registry.FetchData
+	if push := readPush(t, c, &snap); push.Type != WSTypeSnapshot || len(snap.Instances) != 1 {
+		t.Fatalf("expect initial snapshot, got %+v %+v", push, snap)
+	}
+	reg.Register(&registry.Instance{Env: "test", AppId: "com.xx.ws", Hostname: "b", Status: registry.StatusUP}, 2)
+	snap = registry.FetchData{}
+	if push := readPush(t, c, &snap); push.Type != WSTypeSnapshot || len(snap.Instances) != 2 {
+		t.Fatalf("expect pushed snapshot, got %+v %+v", push, snap)
+	}
+
+	sub, _ = json.Marshal(WSRequest{Op: WSOpSubscribe, Env: "test", AppId: "com.xx.ws", Mode: WSModeDelta})
+	c.writeFrame(wsOpText, sub, true)
+	var d registry.DeltaData
+	if push := readPush(t, c, &d); push.Type != WSTypeDelta || !d.Full || len(d.Changes) != 2 {
+		t.Fatalf("expect full delta, got %+v %+v", push, d)
+	}
+	reg.Cancel("test", "com.xx.ws", "a", 3)
+	d = registry.DeltaData{}
+	if push := readPush(t, c, &d); push.Type != WSTypeDelta || d.Full || len(d.Changes) != 1 || d.Changes[0].Action != registry.DeltaDelete {
+		t.Fatalf("expect delete delta, got %+v %+v", push, d)
+	}
+
+	c.writeFrame(wsOpPing, []byte("hi"), true)
+	if op, payload, err := c.readFrame(false); err != nil || op != wsOpPong || string(payload) != "hi" {
+		t.Fatalf("expect pong, got %d %q %v", op, payload, err)
+	}
+}
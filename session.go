@@ -0,0 +1,177 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound 会话不存在或已过期
+var ErrSessionNotFound = errors.New("session not found")
+
+// DefaultSessionGrace 会话断开后保留注册的默认时长，期间可以恢复会话
+const DefaultSessionGrace = 10 * time.Second
+
+// TaskSessions 为会话持有的实例续约的后台任务
+const TaskSessions = "sessions"
+
+// Session 客户端的长连接会话。连接保持期间，会话内注册的实例由注册中心自动续约，
+// 客户端不再需要发送心跳；连接断开超过宽限期后实例被下线
+type Session struct {
+	ID string
+
+	r         *Registry
+	lock      sync.Mutex
+	instances map[instanceKey]*Instance
+	expire    *time.Timer // 断开后的下线定时器，连接中为空
+}
+
+// sessionManager 管理所有会话
+type sessionManager struct {
+	lock     sync.Mutex
+	sessions map[string]*Session
+	grace    time.Duration
+}
+
+func newSessionManager() *sessionManager {
+	return &sessionManager{sessions: make(map[string]*Session), grace: DefaultSessionGrace}
+}
+
+// OpenSession 建立新会话
+func (r *Registry) OpenSession() *Session {
+	s := &Session{ID: NewRequestID(), r: r, instances: make(map[instanceKey]*Instance)}
+	m := r.sessions
+	m.lock.Lock()
+	m.sessions[s.ID] = s
+	m.lock.Unlock()
+	return s
+}
+
+// ResumeSession 在宽限期内重新连接后恢复会话，会话内的注册保持不变
+func (r *Registry) ResumeSession(id string) (*Session, error) {
+	m := r.sessions
+	m.lock.Lock()
+	s, ok := m.sessions[id]
+	m.lock.Unlock()
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expire != nil && !s.expire.Stop() {
+		// 宽限期已到，正在下线
+		return nil, ErrSessionNotFound
+	}
+	s.expire = nil
+	return s, nil
+}
+
+// Register 在会话内注册实例，SpiffeID 按 ctx 中的客户端证书校验，见 ContextWithPeerCertificates
+func (s *Session) Register(ctx context.Context, instance *Instance, latestTimestamp int64) (*Application, error) {
+	app, err := s.r.RegisterWithPeerCertificatesContext(ctx, instance, latestTimestamp, peerCertificatesFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.instances[getInstanceKey(instance.AppId, instance.Env, instance.Hostname)] = instance
+	s.lock.Unlock()
+	return app, nil
+}
+
+// Cancel 在会话内下线实例
+func (s *Session) Cancel(ctx context.Context, env, appid, hostname string, latestTimestamp int64) (*Instance, error) {
+	s.lock.Lock()
+	delete(s.instances, getInstanceKey(appid, env, hostname))
+	s.lock.Unlock()
+	return s.r.CancelContext(ctx, env, appid, hostname, latestTimestamp)
+}
+
+// SetStatus 修改会话内注册的实例的状态，不在会话内的实例返回 ErrInstanceNotFound。
+// 与 BulkSetStatus 相同，修改不会使实例的续约凭证失效
+func (s *Session) SetStatus(ctx context.Context, env, appid, hostname string, status uint32) error {
+	if status == 0 {
+		return &InvalidRequestError{Violations: []FieldViolation{{Field: "status", Reason: "is required"}}}
+	}
+	if err := checkStatus(status); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	_, owned := s.instances[getInstanceKey(appid, env, hostname)]
+	s.lock.Unlock()
+	if !owned {
+		return ErrInstanceNotFound
+	}
+	if err := s.r.checkWritable(); err != nil {
+		return err
+	}
+	in, ok := s.r.getInstance(env, appid, hostname)
+	if !ok {
+		return ErrInstanceNotFound
+	}
+	in.Status = status
+	_, err := s.r.update(ctx, in)
+	return err
+}
+
+// Disconnect 连接断开，宽限期内未恢复则下线会话内的所有实例
+func (s *Session) Disconnect() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.expire != nil {
+		return
+	}
+	s.expire = time.AfterFunc(s.r.sessions.grace, s.close)
+}
+
+// Close 立即关闭会话并下线会话内的所有实例
+func (s *Session) Close() {
+	s.lock.Lock()
+	if s.expire != nil {
+		s.expire.Stop()
+	}
+	s.lock.Unlock()
+	s.close()
+}
+
+func (s *Session) close() {
+	m := s.r.sessions
+	m.lock.Lock()
+	delete(m.sessions, s.ID)
+	m.lock.Unlock()
+	s.lock.Lock()
+	instances := s.instances
+	s.instances = make(map[instanceKey]*Instance)
+	s.lock.Unlock()
+	ctx := ContextWithRequestID(context.Background(), "session-"+s.ID)
+	for _, in := range instances {
+		s.r.CancelContext(ctx, in.Env, in.AppId, in.Hostname, time.Now().UnixNano())
+	}
+}
+
+// renewSessions 为所有会话内的实例续约，断开中的会话不续约
+func (r *Registry) renewSessions(ctx context.Context) error {
+	m := r.sessions
+	m.lock.Lock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, s)
+	}
+	m.lock.Unlock()
+	for _, s := range sessions {
+		s.lock.Lock()
+		if s.expire != nil {
+			s.lock.Unlock()
+			continue
+		}
+		instances := make([]*Instance, 0, len(s.instances))
+		for _, in := range s.instances {
+			instances = append(instances, in)
+		}
+		s.lock.Unlock()
+		for _, in := range instances {
+			r.RenewContext(ctx, in.Env, in.AppId, in.Hostname)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package registry_center
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// 会话协议的操作类型
+const (
+	SessionOpResume   = "resume"   // 恢复断开的会话，必须是连接上的第一条消息
+	SessionOpRegister = "register" // 注册实例
+	SessionOpCancel   = "cancel"   // 下线实例
+	SessionOpRenew    = "renew"    // 续约实例，会话内的实例由注册中心自动续约，一般不需要
+	SessionOpStatus   = "status"   // 修改实例状态
+	SessionOpWatch    = "watch"    // 订阅应用的变更，变更通过 SessionPush 推送
+)
+
+// 连接上的消息类型，应答与推送共用一个流
+const (
+	SessionFrameReply = "reply" // 对客户端消息的应答
+	SessionFrameLease = "lease" // 租约指令
+	SessionFrameEvent = "event" // 订阅的应用发生变更
+	SessionFrameReset = "reset" // 变更推送中断，客户端需要重新全量拉取订阅的应用
+)
+
+// SessionMessage 客户端在会话连接上发送的消息，每行一个 JSON
+type SessionMessage struct {
+	Op        string           `json:"op"`
+	RequestID string           `json:"request_id,omitempty"`
+	SessionID string           `json:"session_id,omitempty"` // resume 时使用
+	Register  *RequestRegister `json:"register,omitempty"`
+	Env       string           `json:"env,omitempty"`
+	AppId     string           `json:"appId,omitempty"`
+	Hostname  string           `json:"hostname,omitempty"`
+	Status    uint32           `json:"status,omitempty"` // status 时使用
+	Filter    *WatchFilter     `json:"filter,omitempty"` // watch 时使用，为空表示接收应用的所有变更
+}
+
+// SessionReply 注册中心对每条消息的应答
+type SessionReply struct {
+	Type      string `json:"type"` // 固定为 reply
+	Op        string `json:"op"`
+	RequestID string `json:"request_id"`
+	SessionID string `json:"session_id"`
+	Error     string `json:"error,omitempty"`
+
+	ErrorInfo *ErrorInfo `json:"error_info,omitempty"` // 失败时的机器可读错误
+}
+
+// LeaseDirective 注册中心下发的租约指令
+type LeaseDirective struct {
+	ServerRenewed bool          `json:"server_renewed"` // 连接保持期间由注册中心续约，客户端无需心跳
+	Grace         time.Duration `json:"grace"`          // 断开后保留注册的时长，客户端应在此之前重连并 resume
+}
+
+// SessionPush 注册中心主动推送的消息
+type SessionPush struct {
+	Type  string          `json:"type"`
+	Lease *LeaseDirective `json:"lease,omitempty"`
+	Event *Event          `json:"event,omitempty"`
+}
+
+// ServeSessions 在 ln 上接受会话连接，每个连接对应一个会话，连接断开后进入宽限期
+func (r *Registry) ServeSessions(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go r.ServeSessionConn(conn)
+	}
+}
+
+// SessionStream 会话消息流，TCP 连接、gRPC 双向流等传输方式实现它即可复用会话协议
+type SessionStream interface {
+	// Recv 读取客户端的下一条消息，返回错误时会话连接结束
+	Recv() (SessionMessage, error)
+	// Send 发送 SessionReply 或 SessionPush，可能被应答与推送并发调用，由会话保证串行
+	Send(frame interface{}) error
+}
+
+// ServeSessionConn 处理一个 JSON-lines 会话连接直到连接断开
+func (r *Registry) ServeSessionConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	src := RegistrationSource{Kind: SourceSession}
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		src.ClientIP = host
+	}
+	// TLS 连接以客户端证书中的 SPIFFE ID 作为调用方身份，注册时以证书校验实例的 SpiffeID
+	var caller string
+	ctx := context.Background()
+	if tc, ok := conn.(*tls.Conn); ok && tc.Handshake() == nil {
+		certs := tc.ConnectionState().PeerCertificates
+		caller = SpiffeIDFromCertificates(certs)
+		ctx = ContextWithPeerCertificates(ctx, certs)
+	}
+	ctx = ContextWithCaller(ctx, caller)
+	r.serveSessionStream(ctx, &lineStream{scanner: scanner, enc: json.NewEncoder(conn)}, src)
+}
+
+// ServeSessionStream 在消息流上处理一个会话直到流结束，结束后会话进入宽限期。
+// 订阅不受应用可见性限制，需要限制时使用 ServeSessionStreamContext
+func (r *Registry) ServeSessionStream(stream SessionStream) {
+	r.ServeSessionStreamContext(context.Background(), stream)
+}
+
+// ServeSessionStreamContext 同 ServeSessionStream，ctx 携带传输层认证的调用方身份及客户端证书，
+// 见 ContextWithCaller、ContextWithPeerCertificates，
+// 以及处理每条消息的中间件，见 ContextWithMiddleware
+func (r *Registry) ServeSessionStreamContext(ctx context.Context, stream SessionStream) {
+	r.serveSessionStream(ctx, stream, RegistrationSource{Kind: SourceSession})
+}
+
+func (r *Registry) serveSessionStream(ctx context.Context, stream SessionStream, src RegistrationSource) {
+	sc := &sessionConn{r: r, ctx: ctx, stream: stream, source: src, done: make(chan struct{})}
+	defer close(sc.done)
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		sc.handle(msg)
+	}
+	if sc.session != nil {
+		sc.session.Disconnect()
+	}
+}
+
+// lineStream 每行一个 JSON 的会话消息流
+type lineStream struct {
+	scanner *bufio.Scanner
+	enc     *json.Encoder
+	lock    sync.Mutex
+}
+
+func (s *lineStream) Recv() (SessionMessage, error) {
+	for s.scanner.Scan() {
+		var msg SessionMessage
+		if err := json.Unmarshal(s.scanner.Bytes(), &msg); err != nil {
+			s.Send(SessionReply{Type: SessionFrameReply, Error: err.Error(),
+				ErrorInfo: &ErrorInfo{Code: CodeInvalidArgument, Message: err.Error()}})
+			continue
+		}
+		return msg, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return SessionMessage{}, err
+	}
+	return SessionMessage{}, io.EOF
+}
+
+func (s *lineStream) Send(frame interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.enc.Encode(frame)
+}
+
+type sessionConn struct {
+	r       *Registry
+	ctx     context.Context // 携带连接的调用方身份
+	session *Session
+	stream  SessionStream
+	source  RegistrationSource      // 连接的来源
+	lock    sync.Mutex              // 保护 watches 并串行化发送，服务端推送与应答可能并发写
+	watches map[AppKey]*eventFilter // 订阅的应用
+	done    chan struct{}           // 连接断开时关闭
+}
+
+func (sc *sessionConn) reply(rep SessionReply) {
+	rep.Type = SessionFrameReply
+	sc.send(rep)
+}
+
+func (sc *sessionConn) send(v interface{}) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	sc.stream.Send(v)
+}
+
+func (sc *sessionConn) handle(msg SessionMessage) {
+	ctx := ContextWithSource(sc.ctx, sc.source)
+	if msg.RequestID != "" {
+		ctx = ContextWithRequestID(ctx, msg.RequestID)
+	}
+	ctx = ensureRequestID(ctx)
+	rep := SessionReply{Op: msg.Op, RequestID: RequestIDFromContext(ctx)}
+	h := func(ctx context.Context, call *CallInfo) error {
+		return sc.dispatch(ctx, msg)
+	}
+	if mw := middlewareFromContext(sc.ctx); mw != nil {
+		h = mw(h)
+	}
+	call := &CallInfo{Protocol: ProtocolSession, Operation: msg.Op, Env: msg.Env, AppId: msg.AppId}
+	if msg.Register != nil {
+		call.Env, call.AppId = msg.Register.Env, msg.Register.AppId
+	}
+	if err := h(ctx, call); err != nil {
+		rep.Error = err.Error()
+		rep.ErrorInfo = ErrorInfoOf(err)
+	}
+	if sc.session != nil {
+		rep.SessionID = sc.session.ID
+	}
+	sc.reply(rep)
+}
+
+func (sc *sessionConn) dispatch(ctx context.Context, msg SessionMessage) error {
+	if msg.Op == SessionOpResume {
+		if sc.session != nil {
+			return errors.New("session already established")
+		}
+		s, err := sc.r.ResumeSession(msg.SessionID)
+		if err != nil {
+			return err
+		}
+		sc.session = s
+		return nil
+	}
+	if sc.session == nil {
+		sc.session = sc.r.OpenSession()
+		sc.send(SessionPush{Type: SessionFrameLease, Lease: &LeaseDirective{ServerRenewed: true, Grace: sc.r.sessions.grace}})
+	}
+	switch msg.Op {
+	case SessionOpRegister:
+		if msg.Register == nil {
+			return errors.New("register message without instance")
+		}
+		if err := msg.Register.Validate(); err != nil {
+			return err
+		}
+		_, err := sc.session.Register(ctx, NewInstance(msg.Register), time.Now().UnixNano())
+		return err
+	case SessionOpCancel:
+		_, err := sc.session.Cancel(ctx, msg.Env, msg.AppId, msg.Hostname, time.Now().UnixNano())
+		return err
+	case SessionOpRenew:
+		_, err := sc.r.RenewContext(ctx, msg.Env, msg.AppId, msg.Hostname)
+		return err
+	case SessionOpStatus:
+		return sc.session.SetStatus(ctx, msg.Env, msg.AppId, msg.Hostname, msg.Status)
+	case SessionOpWatch:
+		var f WatchFilter
+		if msg.Filter != nil {
+			f = *msg.Filter
+		}
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if err := sc.r.CheckVisible(ctx, msg.Env, msg.AppId); err != nil {
+			return err
+		}
+		sc.watch(getKey(msg.AppId, msg.Env), f)
+		return nil
+	}
+	return fmt.Errorf("unknown session op %q", msg.Op)
+}
+
+// watch 订阅应用变更，首次订阅时启动推送，重复订阅同一应用时替换过滤条件
+func (sc *sessionConn) watch(key AppKey, f WatchFilter) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	if sc.watches == nil {
+		sc.watches = make(map[AppKey]*eventFilter)
+		go sc.push(sc.r.LastEventSeq())
+	}
+	sc.watches[key] = newEventFilter(f)
+}
+
+// push 把订阅应用的变更事件推送给客户端直到连接断开
+func (sc *sessionConn) push(seq uint64) {
+	for {
+		wait := sc.r.events.wait()
+		events, err := sc.r.Replay(seq, 0)
+		if err != nil {
+			// 落后过多，通知客户端重新全量拉取
+			seq = sc.r.LastEventSeq()
+			sc.send(SessionPush{Type: SessionFrameReset})
+			continue
+		}
+		for i := range events {
+			seq = events[i].Seq
+			sc.lock.Lock()
+			f, ok := sc.watches[getKey(events[i].AppId, events[i].Env)]
+			watched := ok && (events[i].Type == EventPurge || f.accept(&events[i])) && sc.r.Visible(sc.ctx, events[i].Env, events[i].AppId)
+			sc.lock.Unlock()
+			if watched {
+				sc.send(SessionPush{Type: SessionFrameEvent, Event: &events[i]})
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-wait:
+		case <-sc.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package registry_center
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type sessionClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	pushes  []SessionPush
+}
+
+func dialSession(r *Registry) *sessionClient {
+	client, server := net.Pipe()
+	go r.ServeSessionConn(server)
+	return &sessionClient{conn: client, scanner: bufio.NewScanner(client)}
+}
+
+func (c *sessionClient) call(t *testing.T, msg SessionMessage) SessionReply {
+	b, _ := json.Marshal(msg)
+	if _, err := c.conn.Write(append(b, '\n')); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if !c.scanner.Scan() {
+			t.Fatal("connection closed")
+		}
+		var rep SessionReply
+		json.Unmarshal(c.scanner.Bytes(), &rep)
+		if rep.Type == SessionFrameReply {
+			return rep
+		}
+		var push SessionPush
+		json.Unmarshal(c.scanner.Bytes(), &push)
+		c.pushes = append(c.pushes, push)
+	}
+}
+
+// nextPush 读取下一条推送
+func (c *sessionClient) nextPush(t *testing.T) SessionPush {
+	if len(c.pushes) > 0 {
+		p := c.pushes[0]
+		c.pushes = c.pushes[1:]
+		return p
+	}
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+	if !c.scanner.Scan() {
+		t.Fatal("no push received")
+	}
+	var push SessionPush
+	json.Unmarshal(c.scanner.Bytes(), &push)
+	return push
+}
+
+func TestSessionLiveness(t *testing.T) {
+	r := NewRegistry(WithSessionGrace(50 * time.Millisecond))
+	c := dialSession(r)
+	rep := c.call(t, SessionMessage{Op: SessionOpRegister, Register: &RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}})
+	if rep.Error != "" || rep.SessionID == "" {
+		t.Fatalf("unexpected reply %+v", rep)
+	}
+
+	// 断开后在宽限期内恢复，注册保持
+	c.conn.Close()
+	time.Sleep(10 * time.Millisecond)
+	c = dialSession(r)
+	if rep := c.call(t, SessionMessage{Op: SessionOpResume, SessionID: rep.SessionID}); rep.Error != "" {
+		t.Fatalf("resume failed: %+v", rep)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if ok, _ := r.Exists("test", "com.xx.app", "a"); !ok {
+		t.Fatal("resumed session should keep registration")
+	}
+
+	// 断开超过宽限期，实例被下线
+	c.conn.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if ok, _ := r.Exists("test", "com.xx.app", "a"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("registration should be cancelled after grace period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := r.ResumeSession(rep.SessionID); err != ErrSessionNotFound {
+		t.Fatalf("expired session should not resume, got %v", err)
+	}
+}
+
+func TestSessionRenew(t *testing.T) {
+	r := NewRegistry()
+	s := r.OpenSession()
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := s.Register(context.Background(), in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := r.getApplication("com.xx.app", "test")
+	app.instances["a"].RenewTimestamp = 0
+	r.renewSessions(context.Background())
+	if _, lease := r.Exists("test", "com.xx.app", "a"); lease == 0 {
+		t.Fatal("session instance should be renewed by the registry")
+	}
+	s.Close()
+	if ok, _ := r.Exists("test", "com.xx.app", "a"); ok {
+		t.Fatal("closed session should cancel its instances")
+	}
+}
+
+func TestSessionSetStatus(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	ctx := context.Background()
+	s := r.OpenSession()
+	s.Register(ctx, NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	cred, _ := r.HeartbeatCredential("test", "com.xx.app", "a")
+
+	if err := s.SetStatus(ctx, "test", "com.xx.app", "a", StatusDown); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.app", "a"); d.Instance.Status != StatusDown {
+		t.Fatal("status should be changed")
+	}
+	if now, _ := r.HeartbeatCredential("test", "com.xx.app", "a"); now.Secret != cred.Secret {
+		t.Fatal("status change should keep the heartbeat credential")
+	}
+	// 会话不能修改或接管其他实例
+	if err := s.SetStatus(ctx, "test", "com.xx.app", "b", StatusDown); !errors.Is(err, ErrInstanceNotFound) {
+		t.Fatalf("instance outside the session should not be changed, got %v", err)
+	}
+	s.Close()
+	if ok, _ := r.Exists("test", "com.xx.app", "b"); !ok {
+		t.Fatal("closing the session should not cancel other instances")
+	}
+}
+
+func TestSessionVerifiesSpiffeID(t *testing.T) {
+	r := NewRegistry(WithSpiffeVerification())
+	id := "spiffe://example.org/ns/test/sa/webapi"
+	c := dialSession(r)
+	defer c.conn.Close()
+	rep := c.call(t, SessionMessage{Op: SessionOpRegister, Register: &RequestRegister{Env: "test", AppId: "com.xx.spiffe", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, SpiffeID: id}})
+	if rep.ErrorInfo == nil || rep.ErrorInfo.Code != CodeUnauthenticated {
+		t.Fatalf("spiffe id without client certificate should be rejected, got %+v", rep)
+	}
+
+	u, _ := url.Parse(id)
+	ctx := ContextWithPeerCertificates(context.Background(), []*x509.Certificate{{URIs: []*url.URL{u}}})
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.spiffe", Hostname: "b", Status: StatusUP, SpiffeID: id})
+	if _, err := r.OpenSession().Register(ctx, in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.spiffe", "b"); !d.Instance.SpiffeVerified {
+		t.Fatal("instance should be verified by the connection certificate")
+	}
+}
+
+func TestSessionStream(t *testing.T) {
+	r := NewRegistry()
+	c := dialSession(r)
+	defer c.conn.Close()
+	c.call(t, SessionMessage{Op: SessionOpWatch, Env: "test", AppId: "com.xx.dep"})
+	if p := c.nextPush(t); p.Type != SessionFrameLease || p.Lease == nil || !p.Lease.ServerRenewed {
+		t.Fatalf("expect lease directive first, got %+v", p)
+	}
+
+	c.call(t, SessionMessage{Op: SessionOpRegister, Register: &RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}})
+	if rep := c.call(t, SessionMessage{Op: SessionOpStatus, Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusDown}); rep.Error != "" {
+		t.Fatalf("status change failed: %+v", rep)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.app", "a"); d.Instance.Status != StatusDown {
+		t.Fatal("status should be changed through the session")
+	}
+
+	// 只推送订阅的应用
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.dep", Hostname: "d", Status: StatusUP}), time.Now().UnixNano())
+	p := c.nextPush(t)
+	if p.Type != SessionFrameEvent || p.Event.AppId != "com.xx.dep" || p.Event.Type != EventRegister {
+		t.Fatalf("expect watched app event, got %+v", p)
+	}
+}
+
+type chanStream struct {
+	in  chan SessionMessage
+	out chan interface{}
+}
+
+func (s *chanStream) Recv() (SessionMessage, error) {
+	msg, ok := <-s.in
+	if !ok {
+		return msg, io.EOF
+	}
+	return msg, nil
+}
+
+func (s *chanStream) Send(frame interface{}) error {
+	s.out <- frame
+	return nil
+}
+
+func TestServeSessionStream(t *testing.T) {
+	r := NewRegistry(WithSessionGrace(time.Hour))
+	defer r.Close()
+	s := &chanStream{in: make(chan SessionMessage), out: make(chan interface{}, 8)}
+	done := make(chan struct{})
+	go func() {
+		r.ServeSessionStream(s)
+		close(done)
+	}()
+	s.in <- SessionMessage{Op: SessionOpRegister, RequestID: "req-1", Register: &RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}}
+	if p, ok := (<-s.out).(SessionPush); !ok || p.Type != SessionFrameLease {
+		t.Fatalf("expect lease directive, got %+v", p)
+	}
+	if rep, ok := (<-s.out).(SessionReply); !ok || rep.Error != "" || rep.RequestID != "req-1" || rep.SessionID == "" {
+		t.Fatalf("unexpected reply %+v", rep)
+	}
+	close(s.in)
+	<-done
+	if ok, _ := r.Exists("test", "com.xx.app", "a"); !ok {
+		t.Fatal("instance should be kept during grace period after stream ends")
+	}
+}
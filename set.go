@@ -0,0 +1,110 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SetRequest 批量修改应用内一组实例，未设置的字段保持不变
+type SetRequest struct {
+	Env       string            `json:"env"`
+	AppId     string            `json:"appId"`
+	Hostnames []string          `json:"hostnames"`
+	Status    uint32            `json:"status,omitempty"`   // 0 表示不修改
+	Weight    *uint32           `json:"weight,omitempty"`   // 为空表示不修改
+	Metadata  map[string]string `json:"metadata,omitempty"` // 合并到实例元数据，值为空时删除该键
+
+	LatestTimestamp int64 `json:"latest_timestamp"` // 为 0 时使用当前时间
+}
+
+// Set 原子地修改应用内一组实例的状态、权重或元数据，任一实例不存在时都不修改，
+// 应用的 latestTimestamp 只推进一次。用于发布时把一批实例同时摘除流量
+func (r *Registry) Set(ctx context.Context, req SetRequest) ([]*Instance, error) {
+	if len(req.Hostnames) == 0 {
+		return nil, errors.New("no hostnames to set")
+	}
+	if req.Status == 0 && req.Weight == nil && len(req.Metadata) == 0 {
+		return nil, errors.New("nothing to change")
+	}
+	if err := checkStatus(req.Status); err != nil {
+		return nil, err
+	}
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if err := r.checkFrozen(req.Env, req.AppId); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageStore); err != nil {
+		return nil, err
+	}
+	app, ok := r.getApplication(req.AppId, req.Env)
+	if !ok {
+		return nil, ErrAppNotFound
+	}
+	if req.LatestTimestamp == 0 {
+		req.LatestTimestamp = time.Now().UnixNano()
+	}
+	updated, err := app.set(req, r.clock)
+	if err != nil {
+		return nil, err
+	}
+	src := r.requestSource(ctx)
+	for _, in := range updated {
+		r.events.append(Event{Type: EventRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, RequestID: RequestIDFromContext(ctx), Source: src})
+	}
+	for _, in := range updated {
+		r.replicate(ctx, ReplicationOp{Action: ActionRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, LatestTimestamp: req.LatestTimestamp})
+	}
+	return updated, nil
+}
+
+// set 在应用锁内修改一组实例，返回修改后的实例副本
+func (app *Application) set(req SetRequest, clock *hlcClock) ([]*Instance, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	for _, host := range req.Hostnames {
+		if _, ok := app.instances[host]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, host)
+		}
+	}
+	now := time.Now().UnixNano()
+	rs := make([]*Instance, 0, len(req.Hostnames))
+	for _, host := range req.Hostnames {
+		old := app.instances[host]
+		in := copyInstance(old)
+		if req.Status != 0 {
+			in.Status = req.Status
+		}
+		if req.Weight != nil {
+			in.Weight = *req.Weight
+		}
+		if len(req.Metadata) > 0 && in.Metadata == nil {
+			in.Metadata = make(map[string]string, len(req.Metadata))
+		}
+		for k, v := range req.Metadata {
+			if v == "" {
+				delete(in.Metadata, k)
+			} else {
+				in.Metadata[k] = v
+			}
+		}
+		in.DirtyTimestamp = now
+		in.LatestTimestamp = req.LatestTimestamp
+		in.Clock = clock.now()
+		app.statusCounts[old.Status]--
+		app.statusCounts[in.Status]++
+		app.instances[host] = in
+		rs = append(rs, copyInstance(in))
+	}
+	app.upLatestTimestamp(req.LatestTimestamp)
+	return rs, nil
+}
@@ -0,0 +1,48 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	for _, host := range []string{"a", "b", "c"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: host, Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), 100)
+	}
+	ctx := context.Background()
+	if _, err := r.Set(ctx, SetRequest{Env: "test", AppId: "com.xx.app", Hostnames: []string{"a", "x"}, Status: StatusDown}); !errors.Is(err, ErrInstanceNotFound) {
+		t.Fatalf("expect instance not found, got %v", err)
+	}
+	if d, _ := r.GetInstance("test", "com.xx.app", "a"); d.Instance.Status != StatusUP {
+		t.Fatal("failed set should not change any instance")
+	}
+	if _, err := r.Set(ctx, SetRequest{Env: "test", AppId: "com.xx.app", Hostnames: []string{"a"}, Status: 7}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("unknown status should be rejected, got %v", err)
+	}
+
+	seq := r.LastEventSeq()
+	weight := uint32(0)
+	rs, err := r.Set(ctx, SetRequest{Env: "test", AppId: "com.xx.app", Hostnames: []string{"a", "b"}, Status: StatusDown, Weight: &weight, Metadata: map[string]string{"deploy": "d-1"}, LatestTimestamp: 200})
+	if err != nil || len(rs) != 2 {
+		t.Fatalf("set failed %v %v", rs, err)
+	}
+	c, _ := r.Fetch("test", "com.xx.app", StatusUP|StatusDown, 100)
+	if c.LatestTimestamp != 200 {
+		t.Fatalf("latest timestamp should be bumped once, got %d", c.LatestTimestamp)
+	}
+	for _, in := range c.Instances {
+		changed := in.Hostname != "c"
+		if (in.Status == StatusDown) != changed || (in.Metadata["deploy"] == "d-1") != changed {
+			t.Fatalf("unexpected instance %+v", in)
+		}
+	}
+	if events, _ := r.Replay(seq, 0); len(events) != 2 {
+		t.Fatalf("expect an event per instance, got %d", len(events))
+	}
+	if counts := r.Counts(); len(counts) != 2 {
+		t.Fatalf("status counts should follow the change, got %+v", counts)
+	}
+}
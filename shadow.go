@@ -0,0 +1,88 @@
+package registry_center
+
+import (
+	"errors"
+	"sync"
+)
+
+// ShadowTargets 应用的影子流量目标：这些实例不出现在正常的实例列表中，
+// 客户端按 Percent 比例把请求镜像一份发给它们，镜像请求的响应被丢弃
+type ShadowTargets struct {
+	Env       string   `json:"env"`
+	AppId     string   `json:"appId"`
+	Hostnames []string `json:"hostnames"` // 影子实例 hostname
+	Percent   int      `json:"percent"`   // 镜像流量百分比，0~100
+}
+
+type shadowController struct {
+	targets map[AppKey]*ShadowTargets
+	lock    sync.RWMutex
+}
+
+func newShadowController() *shadowController {
+	return &shadowController{
+		targets: make(map[AppKey]*ShadowTargets),
+	}
+}
+
+// SetShadowTargets 设置应用的影子流量目标，Hostnames 为空时取消
+func (r *Registry) SetShadowTargets(st ShadowTargets) error {
+	if st.Env == "" || st.AppId == "" {
+		return errors.New("shadow targets env and appId are required")
+	}
+	if st.Percent < 0 || st.Percent > 100 {
+		return errors.New("shadow percent out of range")
+	}
+	c := r.shadows
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := getKey(st.AppId, st.Env)
+	if len(st.Hostnames) == 0 {
+		delete(c.targets, key)
+		return nil
+	}
+	st.Hostnames = append([]string(nil), st.Hostnames...)
+	c.targets[key] = &st
+	return nil
+}
+
+// GetShadowTargets 获取应用的影子流量目标
+func (r *Registry) GetShadowTargets(env, appid string) (*ShadowTargets, bool) {
+	c := r.shadows
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	st, ok := c.targets[getKey(appid, env)]
+	if !ok {
+		return nil, false
+	}
+	out := *st
+	out.Hostnames = append([]string(nil), st.Hostnames...)
+	return &out, true
+}
+
+// split 把影子实例从实例列表中分离出来
+func (c *shadowController) split(env, appid string, instances []*Instance) (int, []*Instance, []*Instance) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	st, ok := c.targets[getKey(appid, env)]
+	if !ok {
+		return 0, nil, instances
+	}
+	hosts := make(map[string]struct{}, len(st.Hostnames))
+	for _, h := range st.Hostnames {
+		hosts[h] = struct{}{}
+	}
+	var shadows []*Instance
+	primary := make([]*Instance, 0, len(instances))
+	for _, in := range instances {
+		if _, ok := hosts[in.Hostname]; ok {
+			shadows = append(shadows, in)
+		} else {
+			primary = append(primary, in)
+		}
+	}
+	if len(shadows) == 0 {
+		return 0, nil, instances
+	}
+	return st.Percent, shadows, primary
+}
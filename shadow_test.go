@@ -0,0 +1,26 @@
+package registry_center
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShadowTargets(t *testing.T) {
+	r := NewRegistry()
+	for _, h := range []string{"primary", "shadow"} {
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.shadow", Hostname: h, Status: StatusUP}), time.Now().UnixNano())
+	}
+	if err := r.SetShadowTargets(ShadowTargets{Env: "test", AppId: "com.xx.shadow", Hostnames: []string{"shadow"}, Percent: 10}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.FetchWithOptions("test", "com.xx.shadow", StatusUP, 0, FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Instances) != 1 || data.Instances[0].Hostname != "primary" {
+		t.Fatalf("unexpected primary instances: %v", data.Instances)
+	}
+	if len(data.Shadows) != 1 || data.Shadows[0].Hostname != "shadow" || data.ShadowPercent != 10 {
+		t.Fatalf("unexpected shadow instances: %v", data.Shadows)
+	}
+}
@@ -0,0 +1,208 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Snapshot 注册表某一时刻的全量快照
+type Snapshot struct {
+	Timestamp int64         `json:"timestamp"` // 快照时间
+	Seq       uint64        `json:"seq"`       // 快照时最新的事件序号
+	Apps      []AppSnapshot `json:"apps"`
+}
+
+// AppSnapshot 单个应用的快照
+type AppSnapshot struct {
+	Env             string      `json:"env"`
+	AppId           string      `json:"appId"`
+	LatestTimestamp int64       `json:"latest_timestamp"`
+	Instances       []*Instance `json:"instances"`
+}
+
+// Snapshot 生成注册表快照，应用和实例按名字排序
+func (r *Registry) Snapshot() *Snapshot {
+	return r.SnapshotContext(context.Background())
+}
+
+// SnapshotContext 同 Snapshot，只包含对 ctx 中调用方可见的应用，见 Visible
+func (r *Registry) SnapshotContext(ctx context.Context) *Snapshot {
+	s := &Snapshot{
+		Timestamp: time.Now().UnixNano(),
+		Seq:       r.LastEventSeq(),
+	}
+	for _, app := range r.getAllApplications() {
+		instances := app.GetAllInstances()
+		if len(instances) == 0 || !r.Visible(ctx, instances[0].Env, instances[0].AppId) {
+			continue
+		}
+		app.lock.RLock()
+		latest := app.latestTimestamp
+		app.lock.RUnlock()
+		sort.Slice(instances, func(i, j int) bool {
+			return instances[i].Hostname < instances[j].Hostname
+		})
+		s.Apps = append(s.Apps, AppSnapshot{
+			Env:             instances[0].Env,
+			AppId:           instances[0].AppId,
+			LatestTimestamp: latest,
+			Instances:       instances,
+		})
+	}
+	sort.Slice(s.Apps, func(i, j int) bool {
+		if s.Apps[i].Env != s.Apps[j].Env {
+			return s.Apps[i].Env < s.Apps[j].Env
+		}
+		return s.Apps[i].AppId < s.Apps[j].AppId
+	})
+	return s
+}
+
+// WriteSnapshot 以 JSON 格式写出快照
+func WriteSnapshot(w io.Writer, s *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSnapshot 读取 JSON 格式的快照
+func ReadSnapshot(rd io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(rd).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// InstanceChange 同一实例在两个快照间的变化
+type InstanceChange struct {
+	Hostname string    `json:"hostname"`
+	Fields   []string  `json:"fields"` // 发生变化的字段
+	Old      *Instance `json:"old"`
+	New      *Instance `json:"new"`
+}
+
+// AppDiff 单个应用在两个快照间的差异
+type AppDiff struct {
+	Env     string           `json:"env"`
+	AppId   string           `json:"appId"`
+	Added   []*Instance      `json:"added,omitempty"`
+	Removed []*Instance      `json:"removed,omitempty"`
+	Changed []InstanceChange `json:"changed,omitempty"`
+}
+
+// DiffSnapshots 对比两个快照，返回有差异的应用。续约等时间戳字段的变化不算差异
+func DiffSnapshots(a, b *Snapshot) []AppDiff {
+	return diffSnapshots(a, b, changedFields)
+}
+
+// diffSnapshots 对比两个快照，fields 返回同一实例在两个快照间不同的字段
+func diffSnapshots(a, b *Snapshot, fields func(a, b *Instance) []string) []AppDiff {
+	type appKey struct{ env, appId string }
+	index := func(s *Snapshot) map[appKey]map[string]*Instance {
+		m := make(map[appKey]map[string]*Instance)
+		for _, app := range s.Apps {
+			ins := make(map[string]*Instance, len(app.Instances))
+			for _, in := range app.Instances {
+				ins[in.Hostname] = in
+			}
+			m[appKey{app.Env, app.AppId}] = ins
+		}
+		return m
+	}
+	ia, ib := index(a), index(b)
+	keys := make(map[appKey]struct{})
+	for k := range ia {
+		keys[k] = struct{}{}
+	}
+	for k := range ib {
+		keys[k] = struct{}{}
+	}
+	var diffs []AppDiff
+	for k := range keys {
+		d := AppDiff{Env: k.env, AppId: k.appId}
+		for host, old := range ia[k] {
+			cur, ok := ib[k][host]
+			if !ok {
+				d.Removed = append(d.Removed, old)
+				continue
+			}
+			if fs := fields(old, cur); len(fs) > 0 {
+				d.Changed = append(d.Changed, InstanceChange{Hostname: host, Fields: fs, Old: old, New: cur})
+			}
+		}
+		for host, cur := range ib[k] {
+			if _, ok := ia[k][host]; !ok {
+				d.Added = append(d.Added, cur)
+			}
+		}
+		if len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+			continue
+		}
+		sort.Slice(d.Added, func(i, j int) bool { return d.Added[i].Hostname < d.Added[j].Hostname })
+		sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Hostname < d.Removed[j].Hostname })
+		sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Hostname < d.Changed[j].Hostname })
+		diffs = append(diffs, d)
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Env != diffs[j].Env {
+			return diffs[i].Env < diffs[j].Env
+		}
+		return diffs[i].AppId < diffs[j].AppId
+	})
+	return diffs
+}
+
+// changedFields 返回两个实例间发生变化的业务字段
+func changedFields(a, b *Instance) []string {
+	var fields []string
+	if !reflect.DeepEqual(a.Addrs, b.Addrs) {
+		fields = append(fields, "addrs")
+	}
+	if a.Version != b.Version {
+		fields = append(fields, "version")
+	}
+	if a.Status != b.Status {
+		fields = append(fields, "status")
+	}
+	if a.Weight != b.Weight {
+		fields = append(fields, "weight")
+	}
+	if a.Group != b.Group {
+		fields = append(fields, "group")
+	}
+	if !reflect.DeepEqual(a.Metadata, b.Metadata) {
+		fields = append(fields, "metadata")
+	}
+	if a.SpiffeID != b.SpiffeID {
+		fields = append(fields, "spiffe_id")
+	}
+	if a.InstanceID != b.InstanceID {
+		fields = append(fields, "instance_id")
+	}
+	if a.RegTimestamp != b.RegTimestamp {
+		fields = append(fields, "reg_timestamp")
+	}
+	return fields
+}
+
+// Restore 把快照中的实例导入注册表，已存在的同名实例按脏数据时间合并，返回导入的实例数。
+// 不经过只读检查，备节点可以用主节点的快照做初始同步
+func (r *Registry) Restore(s *Snapshot) int {
+	var n int
+	for _, app := range s.Apps {
+		for _, in := range app.Instances {
+			_, restored, err := r.register(context.Background(), copyInstance(in), app.LatestTimestamp)
+			if err != nil {
+				continue
+			}
+			r.replicate(context.Background(), ReplicationOp{Action: ActionRegister, Env: restored.Env, AppId: restored.AppId, Hostname: restored.Hostname, Instance: restored, LatestTimestamp: app.LatestTimestamp})
+			n++
+		}
+	}
+	return n
+}
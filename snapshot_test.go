@@ -0,0 +1,34 @@
+package registry_center
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.diff", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.diff", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, r.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Cancel("test", "com.xx.diff", "a", time.Now().UnixNano())
+	r.Renew("test", "com.xx.diff", "b")
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.diff", Hostname: "c", Status: StatusUP}), time.Now().UnixNano())
+
+	diffs := DiffSnapshots(before, r.Snapshot())
+	if len(diffs) != 1 {
+		t.Fatalf("expect 1 app diff, got %+v", diffs)
+	}
+	d := diffs[0]
+	if len(d.Added) != 1 || d.Added[0].Hostname != "c" || len(d.Removed) != 1 || d.Removed[0].Hostname != "a" || len(d.Changed) != 0 {
+		t.Fatalf("unexpected diff: %+v", d)
+	}
+}
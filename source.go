@@ -0,0 +1,45 @@
+package registry_center
+
+import "context"
+
+// 注册来源类型
+const (
+	SourceDirect      = "direct"      // 服务直接调用 API 注册
+	SourceAgent       = "agent"       // 由节点上的代理（sidecar/agent）代为注册
+	SourceSession     = "session"     // 通过长连接会话注册
+	SourceReplication = "replication" // 对端节点复制
+	SourceInProcess   = "inprocess"   // 进程内直接调用 Registry
+)
+
+// RegistrationSource 写操作的来源，用于排查异常或重复的注册来自哪台机器、哪个进程
+type RegistrationSource struct {
+	Kind       string `json:"kind"`
+	ClientIP   string `json:"client_ip,omitempty"`
+	SDKVersion string `json:"sdk_version,omitempty"` // 客户端 SDK 版本
+	Node       string `json:"node,omitempty"`        // 最初处理请求的注册中心节点
+}
+
+type sourceKey struct{}
+
+// ContextWithSource 把请求来源放入 ctx，API 层根据连接信息和请求头设置
+func ContextWithSource(ctx context.Context, src RegistrationSource) context.Context {
+	return context.WithValue(ctx, sourceKey{}, src)
+}
+
+// SourceFromContext 返回 ctx 中的请求来源
+func SourceFromContext(ctx context.Context) (RegistrationSource, bool) {
+	src, ok := ctx.Value(sourceKey{}).(RegistrationSource)
+	return src, ok
+}
+
+// requestSource 本次请求的来源，未设置时视为进程内调用，节点默认为本节点
+func (r *Registry) requestSource(ctx context.Context) *RegistrationSource {
+	src, ok := SourceFromContext(ctx)
+	if !ok {
+		src.Kind = SourceInProcess
+	}
+	if src.Node == "" {
+		src.Node = r.clock.node
+	}
+	return &src
+}
@@ -0,0 +1,39 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegistrationSource(t *testing.T) {
+	r := NewRegistry(WithNodeID("node-1"))
+	defer r.Close()
+	src := RegistrationSource{Kind: SourceAgent, ClientIP: "10.0.0.8", SDKVersion: "registry-go/0.1.0"}
+	ctx := ContextWithSource(context.Background(), src)
+	r.RegisterContext(ctx, NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}), time.Now().UnixNano())
+	d, _ := r.GetInstance("test", "com.xx.app", "a")
+	if s := d.Instance.Source; s == nil || s.Kind != SourceAgent || s.ClientIP != "10.0.0.8" || s.Node != "node-1" {
+		t.Fatalf("unexpected source %+v", s)
+	}
+	r.Cancel("test", "com.xx.app", "a", time.Now().UnixNano())
+	events, _ := r.Replay(0, 0)
+	if s := events[0].Source; s == nil || s.ClientIP != "10.0.0.8" {
+		t.Fatalf("register event should carry the source, got %+v", s)
+	}
+	if s := events[1].Source; s == nil || s.Kind != SourceInProcess {
+		t.Fatalf("cancel event should be marked in-process, got %+v", s)
+	}
+
+	// 复制保留原始来源
+	peer := NewRegistry(WithNodeID("node-2"))
+	defer peer.Close()
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "b", Addrs: []string{"http://127.0.0.1:81"}, Status: StatusUP})
+	in.Source = &src
+	peer.ApplyReplication(ReplicationOp{Action: ActionRegister, Env: "test", AppId: "com.xx.app", Hostname: "b", Instance: in, LatestTimestamp: time.Now().UnixNano(), Origin: "node-1"})
+	d, _ = peer.GetInstance("test", "com.xx.app", "b")
+	events, _ = peer.Replay(0, 0)
+	if d.Instance.Source.ClientIP != "10.0.0.8" || events[0].Source.Kind != SourceReplication || events[0].Source.Node != "node-1" {
+		t.Fatalf("unexpected replicated source %+v %+v", d.Instance.Source, events[0].Source)
+	}
+}
@@ -0,0 +1,96 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseSpiffeID 校验 spiffe://trust-domain/path 格式的 SPIFFE ID
+func ParseSpiffeID(id string) (*url.URL, error) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "spiffe" || u.Host == "" || u.User != nil || u.Port() != "" {
+		return nil, errors.New("invalid spiffe id")
+	}
+	if u.RawQuery != "" || u.Fragment != "" || strings.HasSuffix(u.Path, "/") {
+		return nil, errors.New("invalid spiffe id")
+	}
+	return u, nil
+}
+
+// VerifySpiffeID 校验客户端证书链的叶子证书 URI SAN 中包含指定的 SPIFFE ID，
+// 证书链本身的可信性由 TLS 握手保证
+func VerifySpiffeID(id string, certs []*x509.Certificate) error {
+	if _, err := ParseSpiffeID(id); err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return errors.New("no client certificate presented")
+	}
+	for _, uri := range certs[0].URIs {
+		if uri.String() == id {
+			return nil
+		}
+	}
+	return errors.New("spiffe id does not match client certificate")
+}
+
+// SpiffeIDFromCertificates 返回客户端证书链叶子证书中的 SPIFFE ID，没有时返回空字符串。
+// 证书链需已由 TLS 握手校验
+func SpiffeIDFromCertificates(certs []*x509.Certificate) string {
+	if len(certs) == 0 {
+		return ""
+	}
+	for _, uri := range certs[0].URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String()
+		}
+	}
+	return ""
+}
+
+type peerCertificatesKey struct{}
+
+// ContextWithPeerCertificates 放入传输层校验过的 mTLS 客户端证书链，会话内的注册据此校验 SpiffeID
+func ContextWithPeerCertificates(ctx context.Context, certs []*x509.Certificate) context.Context {
+	return context.WithValue(ctx, peerCertificatesKey{}, certs)
+}
+
+func peerCertificatesFromContext(ctx context.Context) []*x509.Certificate {
+	certs, _ := ctx.Value(peerCertificatesKey{}).([]*x509.Certificate)
+	return certs
+}
+
+// RegisterWithPeerCertificates 服务注册，certs 为注册请求的 mTLS 客户端证书链。
+// 实例声明了 SpiffeID 且提供了证书时，SpiffeID 必须与证书匹配；
+// 开启 WithSpiffeVerification 后未提供证书的 SpiffeID 注册会被拒绝
+func (r *Registry) RegisterWithPeerCertificates(instance *Instance, latestTimestamp int64, certs []*x509.Certificate) (*Application, error) {
+	return r.RegisterWithPeerCertificatesContext(context.Background(), instance, latestTimestamp, certs)
+}
+
+// RegisterWithPeerCertificatesContext 同 RegisterWithPeerCertificates，ctx 的用法同 RegisterContext。
+// 缺少证书返回 ErrUnauthenticated，证书与 SpiffeID 不匹配返回 ErrPermissionDenied
+func (r *Registry) RegisterWithPeerCertificatesContext(ctx context.Context, instance *Instance, latestTimestamp int64, certs []*x509.Certificate) (*Application, error) {
+	instance.SpiffeVerified = false
+	if instance.SpiffeID != "" {
+		if _, err := ParseSpiffeID(instance.SpiffeID); err != nil {
+			return nil, &InvalidRequestError{Violations: []FieldViolation{{Field: "spiffe_id", Reason: "must be spiffe://trust-domain/path"}}}
+		}
+		switch {
+		case len(certs) > 0:
+			if err := VerifySpiffeID(instance.SpiffeID, certs); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrPermissionDenied, err)
+			}
+			instance.SpiffeVerified = true
+		case r.requireSpiffeVerification:
+			return nil, fmt.Errorf("%w: no client certificate presented", ErrUnauthenticated)
+		}
+	}
+	return r.RegisterContext(ctx, instance, latestTimestamp)
+}
@@ -0,0 +1,32 @@
+package registry_center
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRegisterWithPeerCertificates(t *testing.T) {
+	r := NewRegistry(WithSpiffeVerification())
+	id := "spiffe://example.org/ns/test/sa/webapi"
+	u, _ := url.Parse(id)
+	cert := &x509.Certificate{URIs: []*url.URL{u}}
+
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.spiffe", Hostname: "webapi", Status: StatusUP, SpiffeID: id})
+	if _, err := r.RegisterWithPeerCertificates(in, time.Now().UnixNano(), []*x509.Certificate{cert}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := r.Fetch("test", "com.xx.spiffe", StatusUP, 0)
+	if err != nil || len(c.Instances) != 1 || !c.Instances[0].SpiffeVerified {
+		t.Fatalf("instance should be spiffe verified: %v %v", c, err)
+	}
+
+	other := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.spiffe", Hostname: "other", Status: StatusUP, SpiffeID: "spiffe://example.org/other"})
+	if _, err := r.RegisterWithPeerCertificates(other, time.Now().UnixNano(), []*x509.Certificate{cert}); err == nil {
+		t.Fatal("mismatched spiffe id should be rejected")
+	}
+	if _, err := r.RegisterWithPeerCertificates(other, time.Now().UnixNano(), nil); err == nil {
+		t.Fatal("spiffe id without certificate should be rejected")
+	}
+}
@@ -0,0 +1,112 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly 备节点只读，写请求需要发往主节点
+var ErrReadOnly = errors.New("registry is read-only standby")
+
+// 主备模式下的节点角色
+const (
+	RoleActive  = "active"  // 主节点，处理写请求并复制给备节点
+	RoleStandby = "standby" // 备节点，只接收复制，不处理写请求也不剔除实例
+)
+
+// FailoverConfig 备节点的自动切换配置，连续 FailureThreshold 次检查主节点失败后自动升主
+type FailoverConfig struct {
+	CheckActive      func(ctx context.Context) error // 检查主节点是否健康，为空时只能手动升主
+	Interval         time.Duration                   // 检查间隔，默认 5 秒
+	FailureThreshold int                             // 连续失败次数阈值，默认 3 次
+}
+
+// failover 主备角色及切换状态
+type failover struct {
+	lock     sync.RWMutex
+	role     string
+	since    int64 // 切换到当前角色的时间
+	cfg      FailoverConfig
+	failures int
+}
+
+func newFailover() *failover {
+	return &failover{role: RoleActive, since: time.Now().UnixNano()}
+}
+
+func (f *failover) setRole(role string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.role == role {
+		return false
+	}
+	f.role = role
+	f.since = time.Now().UnixNano()
+	f.failures = 0
+	return true
+}
+
+// Role 当前节点角色
+func (r *Registry) Role() string {
+	r.failover.lock.RLock()
+	defer r.failover.lock.RUnlock()
+	return r.failover.role
+}
+
+func (r *Registry) checkWritable() error {
+	if r.Role() != RoleActive {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// Promote 备节点升为主节点。所有实例的续约时间重置为当前时间，
+// 避免复制延迟或切换期间漏掉的续约导致实例被集中剔除
+func (r *Registry) Promote() {
+	if !r.failover.setRole(RoleActive) {
+		return
+	}
+	now := time.Now().UnixNano()
+	for _, app := range r.getAllApplications() {
+		app.lock.Lock()
+		for _, in := range app.instances {
+			in.RenewTimestamp = now
+		}
+		app.lock.Unlock()
+	}
+	log.Println("registry promoted to active")
+}
+
+// Demote 主节点降为备节点，用于故障恢复后的旧主节点重新加入，避免出现双主
+func (r *Registry) Demote() {
+	if r.failover.setRole(RoleStandby) {
+		log.Println("registry demoted to standby")
+	}
+}
+
+// checkActive 备节点检查主节点，连续失败达到阈值后自动升主
+func (r *Registry) checkActive(ctx context.Context) error {
+	if r.Role() != RoleStandby {
+		return nil
+	}
+	f := r.failover
+	ctx, cancel := context.WithTimeout(ctx, f.cfg.Interval)
+	err := f.cfg.CheckActive(ctx)
+	cancel()
+	f.lock.Lock()
+	if err == nil {
+		f.failures = 0
+		f.lock.Unlock()
+		return nil
+	}
+	f.failures++
+	promote := f.failures >= f.cfg.FailureThreshold
+	f.lock.Unlock()
+	if promote {
+		r.Promote()
+	}
+	return err
+}
@@ -0,0 +1,62 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type applyReplicator struct {
+	peer *Registry
+}
+
+func (a applyReplicator) Replicate(op ReplicationOp) {
+	a.peer.ApplyReplication(op)
+}
+
+func TestStandbyReplication(t *testing.T) {
+	standby := NewRegistry(WithStandby(FailoverConfig{}))
+	active := NewRegistry(WithReplicator(applyReplicator{peer: standby}))
+
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := standby.Register(in, time.Now().UnixNano()); err != ErrReadOnly {
+		t.Fatalf("standby should be read-only, got %v", err)
+	}
+	if _, err := active.Register(in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := standby.Fetch("test", "com.xx.app", StatusUP, 0); err != nil || len(c.Instances) != 1 {
+		t.Fatalf("standby should receive replication, got %v %v", c, err)
+	}
+	if _, err := active.Cancel("test", "com.xx.app", "a", time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := standby.Fetch("test", "com.xx.app", StatusUP, 0); err == nil {
+		t.Fatal("cancel should be replicated")
+	}
+
+	standby.Promote()
+	if standby.Role() != RoleActive || standby.Health().Role != RoleActive {
+		t.Fatal("standby should be promoted")
+	}
+	in = NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := standby.Register(in, time.Now().UnixNano()); err != nil {
+		t.Fatalf("promoted registry should accept writes, got %v", err)
+	}
+}
+
+func TestStandbyAutoPromote(t *testing.T) {
+	r := NewRegistry(WithStandby(FailoverConfig{
+		CheckActive:      func(ctx context.Context) error { return errors.New("unreachable") },
+		Interval:         10 * time.Millisecond,
+		FailureThreshold: 2,
+	}))
+	deadline := time.Now().Add(time.Second)
+	for r.Role() != RoleActive {
+		if time.Now().After(deadline) {
+			t.Fatal("standby should promote itself after active failures")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
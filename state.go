@@ -0,0 +1,113 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// State 进程重启时交接给新进程的内存状态：实例租约以及续约凭证
+type State struct {
+	Snapshot    *Snapshot                           `json:"snapshot"`
+	Credentials map[string]HeartbeatCredentialState `json:"credentials,omitempty"` // key: 按 KeyEncoder 编码的应用/hostname
+}
+
+// HeartbeatCredentialState 单个实例的续约凭证状态
+type HeartbeatCredentialState struct {
+	Current        HeartbeatCredential `json:"current"`
+	Previous       string              `json:"previous,omitempty"`
+	PreviousExpiry int64               `json:"previous_expiry,omitempty"`
+}
+
+// SaveState 把实例租约和续约凭证写入状态文件，供重启后的新进程加载，
+// 避免升级注册中心时所有客户端因租约丢失而重新注册
+func (r *Registry) SaveState(path string) error {
+	return r.SaveStateContext(context.Background(), path)
+}
+
+// SaveStateContext 同 SaveState，ctx 结束时放弃写入，不替换原有的状态文件
+func (r *Registry) SaveStateContext(ctx context.Context, path string) error {
+	err := r.saveState(ctx, path)
+	if err != nil {
+		r.storageFailed(path, err, 0)
+		r.raiseAlert(Alert{
+			Name:    AlertStorageFailure,
+			Summary: "save state failed: " + err.Error(),
+			Labels:  map[string]string{"path": path},
+		})
+		return err
+	}
+	r.storageSucceeded(path, 0)
+	return nil
+}
+
+func (r *Registry) saveState(ctx context.Context, path string) error {
+	if err := r.checkDeadline(ctx, StageStorage); err != nil {
+		return err
+	}
+	st := State{Snapshot: r.Snapshot()}
+	if r.credentials != nil {
+		h := r.credentials
+		h.lock.Lock()
+		st.Credentials = make(map[string]HeartbeatCredentialState, len(h.states))
+		for key, cs := range h.states {
+			st.Credentials[encodeInstanceKey(r.keys, key)] = HeartbeatCredentialState{Current: cs.current, Previous: cs.previous, PreviousExpiry: cs.previousExpiry}
+		}
+		h.lock.Unlock()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(tmp).Encode(&st); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := r.checkDeadline(ctx, StageStorage); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadState 加载状态文件，实例保留原有的注册、续约时间，返回加载的实例数
+func (r *Registry) LoadState(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var st State
+	if err := json.NewDecoder(f).Decode(&st); err != nil {
+		return 0, err
+	}
+	var n int
+	if st.Snapshot != nil {
+		n = r.Restore(st.Snapshot)
+	}
+	// 注册时签发的新凭证替换为交接前的凭证，客户端无需感知重启
+	if r.credentials != nil {
+		h := r.credentials
+		h.lock.Lock()
+		for s, cs := range st.Credentials {
+			key, err := decodeInstanceKey(r.keys, s)
+			if err != nil {
+				// 旧版本写出的状态文件
+				if key, err = decodeInstanceKey(LegacyKeyEncoder{}, s); err != nil {
+					log.Printf("skip credential of %q in state file: %v", s, err)
+					continue
+				}
+			}
+			h.states[key] = &credentialState{current: cs.Current, previous: cs.Previous, previousExpiry: cs.PreviousExpiry}
+		}
+		h.lock.Unlock()
+	}
+	return n, nil
+}
@@ -0,0 +1,34 @@
+package registry_center
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadState(t *testing.T) {
+	r := NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.state", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	renewed, _ := r.Renew("test", "com.xx.state", "a")
+	cred, _ := r.HeartbeatCredential("test", "com.xx.state", "a")
+
+	path := filepath.Join(t.TempDir(), "registry.state")
+	if err := r.SaveState(path); err != nil {
+		t.Fatal(err)
+	}
+	successor := NewRegistry(WithHeartbeatCredentials(time.Hour, time.Minute))
+	n, err := successor.LoadState(path)
+	if err != nil || n != 1 {
+		t.Fatalf("unexpected load result %d %v", n, err)
+	}
+	c, err := successor.Fetch("test", "com.xx.state", StatusUP, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Instances[0].RenewTimestamp != renewed.RenewTimestamp {
+		t.Fatal("lease should be preserved across restart")
+	}
+	if _, _, err := successor.RenewWithCredential("test", "com.xx.state", "a", cred.Secret); err != nil {
+		t.Fatalf("credential should survive restart: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package registry_center
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StorageStatus 外部存储的可用状态。注册表以内存数据为准，存储不可用时读写照常处理，
+// 未写入存储的变更保留在事件日志中，恢复后补写
+type StorageStatus struct {
+	Backend     string `json:"backend"` // 存储名，如 CDC 订阅者名或状态文件路径
+	Available   bool   `json:"available"`
+	DownSince   int64  `json:"down_since,omitempty"` // 不可用的开始时间
+	LastError   string `json:"last_error,omitempty"`
+	PendingSeqs uint64 `json:"pending_seqs"` // 还没写入存储的事件数
+}
+
+// storageMonitor 各外部存储的可用状态
+type storageMonitor struct {
+	lock     sync.Mutex
+	backends map[string]*StorageStatus
+}
+
+func newStorageMonitor() *storageMonitor {
+	return &storageMonitor{backends: make(map[string]*StorageStatus)}
+}
+
+// storageFailed 记录存储写入失败，pending 为积压的事件数
+func (r *Registry) storageFailed(backend string, err error, pending uint64) {
+	m := r.storage
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	st, ok := m.backends[backend]
+	if !ok {
+		st = &StorageStatus{Backend: backend, Available: true}
+		m.backends[backend] = st
+	}
+	if st.Available {
+		st.Available = false
+		st.DownSince = time.Now().UnixNano()
+		log.Printf("storage %s unavailable, serving from memory: %v", backend, err)
+	}
+	st.LastError = err.Error()
+	st.PendingSeqs = pending
+}
+
+// storageSucceeded 记录存储写入成功
+func (r *Registry) storageSucceeded(backend string, pending uint64) {
+	m := r.storage
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	st, ok := m.backends[backend]
+	if !ok {
+		st = &StorageStatus{Backend: backend, Available: true}
+		m.backends[backend] = st
+	}
+	if !st.Available {
+		log.Printf("storage %s recovered after %s", backend, time.Duration(time.Now().UnixNano()-st.DownSince))
+	}
+	st.Available = true
+	st.DownSince = 0
+	st.LastError = ""
+	st.PendingSeqs = pending
+}
+
+// StorageStatus 返回各外部存储的可用状态，按存储名排列
+func (r *Registry) StorageStatus() []StorageStatus {
+	m := r.storage
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rs := make([]StorageStatus, 0, len(m.backends))
+	for _, st := range m.backends {
+		rs = append(rs, *st)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Backend < rs[j].Backend
+	})
+	return rs
+}
+
+// StorageDegraded 是否有外部存储不可用，此时的读请求由内存数据提供
+func (r *Registry) StorageDegraded() bool {
+	m := r.storage
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, st := range m.backends {
+		if !st.Available {
+			return true
+		}
+	}
+	return false
+}
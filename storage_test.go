@@ -0,0 +1,69 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakySink 在 down 为 true 时写入失败
+type flakySink struct {
+	memorySink
+	down atomic.Bool
+}
+
+func (s *flakySink) Apply(ctx context.Context, events []Event) error {
+	if s.down.Load() {
+		return errors.New("connection refused")
+	}
+	return s.memorySink.Apply(ctx, events)
+}
+
+func TestStorageOutage(t *testing.T) {
+	r := NewRegistry()
+	defer r.Close()
+	sink := &flakySink{}
+	e, err := r.NewCDCExporter("db", NewMemoryOffsetStore(), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.retry = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink.down.Store(true)
+	go e.Run(ctx)
+
+	// 首次全量同步之后的变更才会写入失败
+	waitFor(t, func() bool {
+		r.Register(&Instance{Env: "test", AppId: "com.xx.db", Hostname: "a", Status: StatusUP}, 1)
+		return r.StorageDegraded()
+	})
+	// 存储不可用时读写照常
+	r.Register(&Instance{Env: "test", AppId: "com.xx.db", Hostname: "b", Status: StatusUP}, 2)
+	if data, err := r.Fetch("test", "com.xx.db", StatusUP, 0); err != nil || len(data.Instances) != 2 {
+		t.Fatalf("fetch should be served from memory: %+v %v", data, err)
+	}
+	hs := r.Health()
+	if !hs.StorageDegraded || len(hs.Storage) != 1 || hs.Storage[0].Backend != "db" || hs.Storage[0].LastError == "" {
+		t.Fatalf("unexpected health %+v", hs)
+	}
+
+	sink.down.Store(false)
+	waitFor(t, func() bool { return !r.StorageDegraded() && sink.len() == 2 })
+	if st := r.StorageStatus(); st[0].PendingSeqs != 0 || st[0].DownSince != 0 {
+		t.Fatalf("unexpected status after recovery %+v", st)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,214 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrTaskNotFound 后台任务不存在
+var ErrTaskNotFound = errors.New("task not found")
+
+// 内置后台任务名
+const (
+	TaskEvict    = "evict"    // 剔除没有续约的服务实例
+	TaskRollout  = "rollout"  // 检查灰度发布中目标版本的健康状况
+	TaskFailover = "failover" // 备节点检查主节点健康状况
+)
+
+// TaskConfig 后台任务配置
+type TaskConfig struct {
+	Disabled bool          // 是否停用
+	Interval time.Duration // 运行间隔，0 表示使用任务的默认间隔
+}
+
+// TaskStatus 后台任务的运行状况
+type TaskStatus struct {
+	Name         string        `json:"name"`
+	Enabled      bool          `json:"enabled"`
+	Interval     time.Duration `json:"interval"`
+	Runs         int           `json:"runs"`          // 已运行次数
+	Panics       int           `json:"panics"`        // 发生 panic 的次数
+	LastRun      int64         `json:"last_run"`      // 上次开始运行的时间
+	LastDuration time.Duration `json:"last_duration"` // 上次运行耗时
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      int64         `json:"next_run"` // 下次运行的时间，停用时为 0
+}
+
+type task struct {
+	status TaskStatus
+	run    func(ctx context.Context) error
+	reset  chan struct{} // 配置变化时通知任务重新计时
+}
+
+// taskManager 统一管理后台周期任务，每个任务独立运行，panic 只影响本次运行
+type taskManager struct {
+	lock    sync.Mutex
+	tasks   map[string]*task
+	configs map[string]TaskConfig // 任务添加前设置的配置
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newTaskManager() *taskManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &taskManager{
+		tasks:   make(map[string]*task),
+		configs: make(map[string]TaskConfig),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// add 添加并启动任务，interval 为默认运行间隔
+func (m *taskManager) add(name string, interval time.Duration, run func(ctx context.Context) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.tasks[name]; ok {
+		return fmt.Errorf("task %s already exists", name)
+	}
+	if m.ctx.Err() != nil {
+		return m.ctx.Err()
+	}
+	t := &task{
+		status: TaskStatus{Name: name, Enabled: true, Interval: interval},
+		run:    run,
+		reset:  make(chan struct{}, 1),
+	}
+	if cfg, ok := m.configs[name]; ok {
+		t.status.Enabled = !cfg.Disabled
+		if cfg.Interval > 0 {
+			t.status.Interval = cfg.Interval
+		}
+	}
+	m.tasks[name] = t
+	m.wg.Add(1)
+	go m.loop(t)
+	return nil
+}
+
+func (m *taskManager) loop(t *task) {
+	defer m.wg.Done()
+	for {
+		m.lock.Lock()
+		enabled, interval := t.status.Enabled, t.status.Interval
+		t.status.NextRun = 0
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if enabled {
+			timer = time.NewTimer(interval)
+			timeout = timer.C
+			t.status.NextRun = time.Now().Add(interval).UnixNano()
+		}
+		m.lock.Unlock()
+
+		var stopped bool
+		select {
+		case <-timeout:
+			m.runOnce(t)
+		case <-t.reset:
+		case <-m.ctx.Done():
+			stopped = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// runOnce 运行一次任务并记录结果，panic 被捕获后记入 LastError
+func (m *taskManager) runOnce(t *task) {
+	start := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("task %s panic: %v\n%s", t.status.Name, p, debug.Stack())
+				m.lock.Lock()
+				t.status.Panics++
+				m.lock.Unlock()
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		return t.run(m.ctx)
+	}()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	t.status.Runs++
+	t.status.LastRun = start.UnixNano()
+	t.status.LastDuration = time.Since(start)
+	t.status.LastError = ""
+	if err != nil {
+		t.status.LastError = err.Error()
+	}
+}
+
+// configure 修改任务配置，任务尚未添加时保存到添加时生效
+func (m *taskManager) configure(name string, cfg TaskConfig) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	t, ok := m.tasks[name]
+	if !ok {
+		m.configs[name] = cfg
+		return
+	}
+	t.status.Enabled = !cfg.Disabled
+	if cfg.Interval > 0 {
+		t.status.Interval = cfg.Interval
+	}
+	select {
+	case t.reset <- struct{}{}:
+	default:
+	}
+}
+
+// stop 停止所有任务并等待正在运行的任务退出
+func (m *taskManager) stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// AddTask 添加后台周期任务，任务在 Close 时随注册中心一起停止
+func (r *Registry) AddTask(name string, interval time.Duration, run func(ctx context.Context) error) error {
+	return r.tasks.add(name, interval, run)
+}
+
+// ConfigureTask 启用/停用任务或修改运行间隔
+func (r *Registry) ConfigureTask(name string, cfg TaskConfig) error {
+	r.tasks.lock.Lock()
+	_, ok := r.tasks.tasks[name]
+	r.tasks.lock.Unlock()
+	if !ok {
+		return ErrTaskNotFound
+	}
+	r.tasks.configure(name, cfg)
+	return nil
+}
+
+// Tasks 返回所有后台任务的运行状况，按任务名排序
+func (r *Registry) Tasks() []TaskStatus {
+	m := r.tasks
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rs := make([]TaskStatus, 0, len(m.tasks))
+	for _, t := range m.tasks {
+		rs = append(rs, t.status)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].Name < rs[j].Name
+	})
+	return rs
+}
+
+// Close 停止所有后台任务
+func (r *Registry) Close() {
+	r.tasks.stop()
+}
@@ -0,0 +1,49 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTaskManager(t *testing.T) {
+	r := NewRegistry(WithTaskConfig(TaskEvict, TaskConfig{Disabled: true}))
+	defer r.Close()
+	if err := r.AddTask("boom", 5*time.Millisecond, func(ctx context.Context) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AddTask("boom", time.Second, nil); err == nil {
+		t.Fatal("duplicate task should be rejected")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		var st TaskStatus
+		for _, s := range r.Tasks() {
+			if s.Name == "boom" {
+				st = s
+			}
+		}
+		if st.Panics >= 2 {
+			if st.Runs < 2 || st.LastError == "" {
+				t.Fatalf("unexpected task status %+v", st)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("panicking task should keep running")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	for _, s := range r.Tasks() {
+		if s.Name == TaskEvict && (s.Enabled || s.NextRun != 0) {
+			t.Fatalf("evict task should be disabled: %+v", s)
+		}
+	}
+	if err := r.ConfigureTask("missing", TaskConfig{}); err != ErrTaskNotFound {
+		t.Fatalf("expect task not found, got %v", err)
+	}
+}
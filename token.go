@@ -0,0 +1,207 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPermissionDenied 调用方的凭证不允许该操作
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrTokenNotFound 令牌不存在或已过期
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// 只读令牌的有效期
+const (
+	DefaultTokenTTL = time.Hour
+	MaxTokenTTL     = 24 * time.Hour
+)
+
+// TokenPrefix 只读令牌的前缀，便于在日志和配置中识别
+const TokenPrefix = "rct_"
+
+// TokenRequest 签发只读令牌的请求
+type TokenRequest struct {
+	Subject string        `json:"subject"` // 令牌的持有者，如流水线名称，记入审计日志
+	Env     string        `json:"env"`
+	AppIds  []string      `json:"appIds"` // 允许读取的应用，末尾为 * 时按前缀匹配
+	TTL     time.Duration `json:"ttl"`    // 有效期，0 表示 DefaultTokenTTL，最长 MaxTokenTTL
+}
+
+// ScopedToken 只读令牌的范围，令牌为签名后的 ScopedToken，任一共用签名密钥的节点都能校验
+type ScopedToken struct {
+	ID        string   `json:"id"`
+	Subject   string   `json:"subject"`
+	Env       string   `json:"env"`
+	AppIds    []string `json:"appIds"`
+	IssuedAt  int64    `json:"issued_at"`
+	ExpiresAt int64    `json:"expires_at"`
+}
+
+// Allows 令牌是否允许读取应用
+func (t *ScopedToken) Allows(env, appid string) bool {
+	if env != t.Env {
+		return false
+	}
+	for _, p := range t.AppIds {
+		if p == appid || strings.HasSuffix(p, "*") && strings.HasPrefix(appid, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenIssuer 签发和校验只读令牌，吊销只在本节点生效
+type tokenIssuer struct {
+	key     []byte
+	lock    sync.Mutex
+	issued  map[string]ScopedToken // 本节点签发的令牌，key: ID
+	revoked map[string]int64       // 已吊销的令牌到其过期时间
+}
+
+func newTokenIssuer(key []byte) *tokenIssuer {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	return &tokenIssuer{key: key, issued: make(map[string]ScopedToken), revoked: make(map[string]int64)}
+}
+
+func (ti *tokenIssuer) sign(payload string) string {
+	mac := hmac.New(sha256.New, ti.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// expire 删除已过期的记录，调用方持有锁
+func (ti *tokenIssuer) expire(now int64) {
+	for id, t := range ti.issued {
+		if t.ExpiresAt <= now {
+			delete(ti.issued, id)
+		}
+	}
+	for id, exp := range ti.revoked {
+		if exp <= now {
+			delete(ti.revoked, id)
+		}
+	}
+}
+
+// IssueToken 签发短期只读令牌，持有者只能通过读接口获取范围内的应用，包括范围内的受限应用
+func (r *Registry) IssueToken(ctx context.Context, req TokenRequest) (string, *ScopedToken, error) {
+	if req.Env == "" || len(req.AppIds) == 0 {
+		return "", nil, &InvalidRequestError{Violations: []FieldViolation{{Field: "appIds", Reason: "env and appIds are required"}}}
+	}
+	if req.TTL <= 0 {
+		req.TTL = DefaultTokenTTL
+	}
+	if req.TTL > MaxTokenTTL {
+		return "", nil, &InvalidRequestError{Violations: []FieldViolation{{Field: "ttl", Reason: "must be at most " + MaxTokenTTL.String()}}}
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	t := ScopedToken{ID: hex.EncodeToString(id), Subject: req.Subject, Env: req.Env, AppIds: append([]string(nil), req.AppIds...),
+		IssuedAt: now.UnixNano(), ExpiresAt: now.Add(req.TTL).UnixNano()}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return "", nil, err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	ti := r.tokens
+	ti.lock.Lock()
+	ti.expire(now.UnixNano())
+	ti.issued[t.ID] = t
+	ti.lock.Unlock()
+	r.audit(ctx, AuditEntry{Action: AuditTokenIssued, Caller: t.Subject, Env: t.Env, AppId: strings.Join(t.AppIds, ","),
+		Reason: fmt.Sprintf("token %s expires in %s", t.ID, req.TTL)})
+	return TokenPrefix + payload + "." + ti.sign(payload), &t, nil
+}
+
+// VerifyToken 校验令牌的签名、有效期及是否被吊销，失败时返回 ErrUnauthenticated
+func (r *Registry) VerifyToken(token string) (*ScopedToken, error) {
+	payload, sig, ok := strings.Cut(strings.TrimPrefix(token, TokenPrefix), ".")
+	if !ok || !strings.HasPrefix(token, TokenPrefix) || !hmac.Equal([]byte(sig), []byte(r.tokens.sign(payload))) {
+		return nil, fmt.Errorf("%w: invalid token", ErrUnauthenticated)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid token", ErrUnauthenticated)
+	}
+	var t ScopedToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("%w: invalid token", ErrUnauthenticated)
+	}
+	if time.Now().UnixNano() >= t.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthenticated)
+	}
+	r.tokens.lock.Lock()
+	_, revoked := r.tokens.revoked[t.ID]
+	r.tokens.lock.Unlock()
+	if revoked {
+		return nil, fmt.Errorf("%w: token revoked", ErrUnauthenticated)
+	}
+	return &t, nil
+}
+
+// RevokeToken 吊销令牌，只在本节点生效，令牌过期后记录自动删除
+func (r *Registry) RevokeToken(ctx context.Context, id string) error {
+	ti := r.tokens
+	ti.lock.Lock()
+	t, ok := ti.issued[id]
+	if ok {
+		delete(ti.issued, id)
+		ti.revoked[id] = t.ExpiresAt
+	}
+	ti.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: token %s", ErrTokenNotFound, id)
+	}
+	r.audit(ctx, AuditEntry{Action: AuditTokenRevoked, Caller: t.Subject, Env: t.Env, Reason: "token " + id + " revoked"})
+	return nil
+}
+
+// Tokens 返回本节点签发且未过期、未吊销的令牌，按签发时间排序
+func (r *Registry) Tokens() []ScopedToken {
+	ti := r.tokens
+	ti.lock.Lock()
+	ti.expire(time.Now().UnixNano())
+	rs := make([]ScopedToken, 0, len(ti.issued))
+	for _, t := range ti.issued {
+		rs = append(rs, t)
+	}
+	ti.lock.Unlock()
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].IssuedAt < rs[j].IssuedAt
+	})
+	return rs
+}
+
+type tokenKey struct{}
+
+// ContextWithToken 放入调用方出示的只读令牌，之后的读取只能看到令牌范围内的应用
+func ContextWithToken(ctx context.Context, t *ScopedToken) context.Context {
+	return context.WithValue(ctx, tokenKey{}, t)
+}
+
+// TokenFromContext 返回调用方出示的只读令牌
+func TokenFromContext(ctx context.Context) (*ScopedToken, bool) {
+	t, ok := ctx.Value(tokenKey{}).(*ScopedToken)
+	return t, ok
+}
@@ -0,0 +1,59 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScopedToken(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(WithTokenKey([]byte("k1")))
+	defer r.Close()
+	for _, appid := range []string{"com.xx.a", "com.xx.b", "com.yy.c"} {
+		r.Register(&Instance{Env: "test", AppId: appid, Hostname: "h1", Status: StatusUP}, 1)
+	}
+	token, scope, err := r.IssueToken(ctx, TokenRequest{Subject: "ci", Env: "test", AppIds: []string{"com.xx.*"}, TTL: time.Minute})
+	if err != nil || !strings.HasPrefix(token, TokenPrefix) || scope.ExpiresAt-scope.IssuedAt != int64(time.Minute) {
+		t.Fatalf("unexpected token %q %+v %v", token, scope, err)
+	}
+	// 共用签名密钥的节点都能校验
+	other := NewRegistry(WithTokenKey([]byte("k1")))
+	defer other.Close()
+	tok, err := other.VerifyToken(token)
+	if err != nil || tok.ID != scope.ID {
+		t.Fatalf("verify on another node failed: %+v %v", tok, err)
+	}
+	if _, err := NewRegistry().VerifyToken(token); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expect invalid signature, got %v", err)
+	}
+
+	tctx := ContextWithToken(ctx, tok)
+	if _, err := r.FetchContext(tctx, "test", "com.xx.a", StatusUP, 0, FetchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.FetchContext(tctx, "test", "com.yy.c", StatusUP, 0, FetchOptions{}); !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("expect app outside scope hidden, got %v", err)
+	}
+	if all, _ := r.FetchAllContext(tctx, "test"); len(all) != 2 {
+		t.Fatalf("unexpected fetch all %v", all)
+	}
+
+	if _, _, err := r.IssueToken(ctx, TokenRequest{Env: "test", AppIds: []string{"com.xx.a"}, TTL: 48 * time.Hour}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expect ttl rejected, got %v", err)
+	}
+	if len(r.Tokens()) != 1 {
+		t.Fatalf("unexpected tokens %+v", r.Tokens())
+	}
+	if err := r.RevokeToken(ctx, scope.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.VerifyToken(token); !errors.Is(err, ErrUnauthenticated) || len(r.Tokens()) != 0 {
+		t.Fatalf("expect revoked token rejected, got %v", err)
+	}
+	if err := r.RevokeToken(ctx, scope.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Fatalf("expect token not found, got %v", err)
+	}
+}
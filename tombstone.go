@@ -0,0 +1,92 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrStaleRegistration 注册请求早于该实例最近一次下线，可能是延迟到达的复制或重试的旧请求
+var ErrStaleRegistration = errors.New("registration older than deletion")
+
+// DefaultTombstoneTTL 墓碑的默认保留时长，应大于复制延迟和客户端重试的最长时间
+const DefaultTombstoneTTL = 10 * time.Minute
+
+// TaskTombstones 清理过期墓碑的后台任务
+const TaskTombstones = "tombstones"
+
+// Tombstone 实例下线后留下的墓碑，用于拒绝早于下线的注册请求
+type Tombstone struct {
+	Env       string `json:"env"`
+	AppId     string `json:"appId"`
+	Hostname  string `json:"hostname"`
+	DeletedAt int64  `json:"deleted_at"` // 下线时间
+	Clock     HLC    `json:"clock"`      // 下线操作的混合逻辑时钟
+}
+
+// ConflictError 注册请求与墓碑冲突，发送方可据此放弃重试或先确认实例的真实状态
+type ConflictError struct {
+	Tombstone Tombstone
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: %s/%s deleted at %d", ErrStaleRegistration, getKey(e.Tombstone.AppId, e.Tombstone.Env), e.Tombstone.Hostname, e.Tombstone.DeletedAt)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrStaleRegistration
+}
+
+// tombstones 已下线实例的墓碑
+type tombstones struct {
+	lock  sync.Mutex
+	ttl   time.Duration
+	items map[instanceKey]Tombstone
+}
+
+func newTombstones() *tombstones {
+	return &tombstones{ttl: DefaultTombstoneTTL, items: make(map[instanceKey]Tombstone)}
+}
+
+func (t *tombstones) add(ts Tombstone) {
+	t.lock.Lock()
+	t.items[getInstanceKey(ts.AppId, ts.Env, ts.Hostname)] = ts
+	t.lock.Unlock()
+}
+
+// check 注册请求早于墓碑时返回冲突，否则删除墓碑
+func (t *tombstones) check(in *Instance) error {
+	key := getInstanceKey(in.AppId, in.Env, in.Hostname)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	ts, ok := t.items[key]
+	if !ok {
+		return nil
+	}
+	var stale bool
+	if !in.Clock.IsZero() && !ts.Clock.IsZero() {
+		stale = in.Clock.Compare(ts.Clock) < 0
+	} else {
+		stale = in.DirtyTimestamp < ts.DeletedAt
+	}
+	if stale {
+		return &ConflictError{Tombstone: ts}
+	}
+	delete(t.items, key)
+	return nil
+}
+
+// expire 删除超过保留时长的墓碑
+func (t *tombstones) expire(ctx context.Context) error {
+	deadline := time.Now().Add(-t.ttl).UnixNano()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for key, ts := range t.items {
+		if ts.DeletedAt < deadline {
+			delete(t.items, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTombstoneRejectsStaleRegister(t *testing.T) {
+	r := NewRegistry()
+	old := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	delayed := copyInstance(old)
+	if _, err := r.Register(old, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	delayed.Clock = old.Clock
+	r.Cancel("test", "com.xx.app", "a", time.Now().UnixNano())
+
+	// 延迟到达的复制注册早于下线，不能复活实例
+	err := r.ApplyReplication(ReplicationOp{Action: ActionRegister, Instance: delayed, LatestTimestamp: time.Now().UnixNano()})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrStaleRegistration) || conflict.Tombstone.Hostname != "a" {
+		t.Fatalf("expect stale registration conflict, got %v", err)
+	}
+	if ok, _ := r.Exists("test", "com.xx.app", "a"); ok {
+		t.Fatal("cancelled instance should not be resurrected")
+	}
+
+	// 下线之后的新注册正常通过
+	fresh := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := r.Register(fresh, time.Now().UnixNano()); err != nil {
+		t.Fatalf("fresh register should be accepted, got %v", err)
+	}
+}
+
+func TestTombstoneExpire(t *testing.T) {
+	r := NewRegistry(WithTombstoneTTL(time.Millisecond))
+	r.tombstones.add(Tombstone{Env: "test", AppId: "com.xx.app", Hostname: "a", DeletedAt: time.Now().Add(-time.Second).UnixNano()})
+	r.tombstones.expire(context.Background())
+	if len(r.tombstones.items) != 0 {
+		t.Fatal("expired tombstone should be removed")
+	}
+}
@@ -0,0 +1,97 @@
+package registry_center
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidTraceparent traceparent 头格式不正确
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext W3C Trace Context 中的 traceparent 信息
+type TraceContext struct {
+	TraceID string // 32 位十六进制
+	SpanID  string // 16 位十六进制，当前 span
+	Flags   string // 2 位十六进制，01 表示采样
+	State   string // tracestate 头，原样透传
+}
+
+// ParseTraceparent 解析 traceparent 头，格式：version-traceid-spanid-flags
+func ParseTraceparent(traceparent, tracestate string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		!isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	// version 00 只有 4 段
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	return TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: parts[3], State: tracestate}, nil
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil && strings.ToLower(s) == s
+}
+
+// Traceparent 返回 traceparent 头
+func (tc TraceContext) Traceparent() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+// Child 在同一 trace 中生成新的 span
+func (tc TraceContext) Child() TraceContext {
+	tc.SpanID = randomHex(8)
+	return tc
+}
+
+// NewTraceContext 开启一个新的采样 trace
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type traceKey struct{}
+
+// ContextWithTrace 把 trace 信息放入 ctx，API 层收到 traceparent 头时使用
+func ContextWithTrace(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey{}, tc)
+}
+
+// TraceFromContext 返回 ctx 中的 trace 信息
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// Tracer 在复制的发送和应用处开启 span，可以适配 OpenTelemetry 等实现。
+// 返回的 ctx 需要通过 ContextWithTrace 携带新 span 的 TraceContext，以便传给对端节点
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, func(err error))
+}
+
+// startSpan 开启 span，未配置 Tracer 时只在 trace 内生成子 span 用于传播
+func (r *Registry) startSpan(ctx context.Context, name string) (context.Context, func(err error)) {
+	if r.tracer != nil {
+		return r.tracer.Start(ctx, name)
+	}
+	if tc, ok := TraceFromContext(ctx); ok {
+		ctx = ContextWithTrace(ctx, tc.Child())
+	}
+	return ctx, func(error) {}
+}
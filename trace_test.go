@@ -0,0 +1,58 @@
+package registry_center
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "congo=t61rcWkgMzE")
+	if err != nil || tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.SpanID != "00f067aa0ba902b7" || tc.State != "congo=t61rcWkgMzE" {
+		t.Fatalf("unexpected trace context %+v %v", tc, err)
+	}
+	if tc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("unexpected traceparent %s", tc.Traceparent())
+	}
+	for _, s := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(s, ""); err != ErrInvalidTraceparent {
+			t.Fatalf("expect invalid traceparent for %q", s)
+		}
+	}
+}
+
+type recordingReplicator struct {
+	ops []ReplicationOp
+}
+
+func (rr *recordingReplicator) Replicate(op ReplicationOp) {
+	rr.ops = append(rr.ops, op)
+}
+
+func TestTracePropagatesThroughReplication(t *testing.T) {
+	rep := &recordingReplicator{}
+	active := NewRegistry(WithReplicator(rep))
+	tc := NewTraceContext()
+	ctx := ContextWithTrace(context.Background(), tc)
+	in := NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: "a", Status: StatusUP})
+	if _, err := active.RegisterContext(ctx, in, time.Now().UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.ops) != 1 {
+		t.Fatalf("expect one replicated op, got %d", len(rep.ops))
+	}
+	sent, err := ParseTraceparent(rep.ops[0].Traceparent, rep.ops[0].Tracestate)
+	if err != nil || sent.TraceID != tc.TraceID || sent.SpanID == tc.SpanID {
+		t.Fatalf("replication should carry a child span of the request trace: %+v %v", sent, err)
+	}
+	standby := NewRegistry(WithStandby(FailoverConfig{}))
+	if err := standby.ApplyReplication(rep.ops[0]); err != nil {
+		t.Fatal(err)
+	}
+}
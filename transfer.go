@@ -0,0 +1,155 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInstanceExists 实例已存在
+var ErrInstanceExists = errors.New("instance already exists")
+
+// TransferRequest 把实例的注册转移给替换它的新主机
+type TransferRequest struct {
+	Env         string   `json:"env"`
+	AppId       string   `json:"appId"`
+	From        string   `json:"from"`                   // 被替换实例的 hostname
+	To          string   `json:"to"`                     // 新主机的 hostname，不能已经注册
+	Addrs       []string `json:"addrs,omitempty"`        // 新主机的地址，与 secure_addrs 都为空时沿用原地址
+	SecureAddrs []string `json:"secure_addrs,omitempty"` // 新主机的 TLS 地址
+
+	LatestTimestamp int64 `json:"latest_timestamp"` // 为 0 时使用当前时间
+}
+
+// Transfer 用新主机原子地替换实例：新实例继承原实例的状态、权重、版本、分组、元数据、SpiffeID 及人工指定的健康分，
+// 原实例在同一次变更中下线并留下墓碑。蓝绿替换主机时应用的实例数不会短暂翻倍，人工调整也不会丢失
+func (r *Registry) Transfer(ctx context.Context, req TransferRequest) (*Instance, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	ctx = ensureRequestID(ctx)
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if err := r.checkFrozen(req.Env, req.AppId); err != nil {
+		return nil, err
+	}
+	release, err := r.AdmitContext(ctx, PriorityAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := r.checkDeadline(ctx, StageStore); err != nil {
+		return nil, err
+	}
+	app, ok := r.getApplication(req.AppId, req.Env)
+	if !ok {
+		return nil, ErrAppNotFound
+	}
+	if req.LatestTimestamp == 0 {
+		req.LatestTimestamp = time.Now().UnixNano()
+	}
+	src := r.requestSource(ctx)
+	clock := r.clock.now()
+	build := func(old *Instance) *Instance {
+		in := copyInstance(old)
+		in.Hostname = req.To
+		if len(req.Addrs)+len(req.SecureAddrs) > 0 {
+			in.Addrs, in.Endpoints = parseEndpoints(req.Addrs, req.SecureAddrs)
+		}
+		// 证书校验结果属于原主机
+		in.SpiffeVerified = false
+		now := time.Now().UnixNano()
+		in.RenewTimestamp = now
+		in.DirtyTimestamp = now
+		in.LatestTimestamp = req.LatestTimestamp
+		in.Clock = clock
+		in.Source = src
+		return in
+	}
+	// 先按当前快照占用地址，同一应用的实例可以共用地址，沿用原地址时不会冲突
+	snapshot, ok := r.getInstance(req.Env, req.AppId, req.From)
+	if !ok {
+		return nil, ErrInstanceNotFound
+	}
+	if err := r.tombstones.check(build(snapshot)); err != nil {
+		return nil, err
+	}
+	if err := r.claimAddrs(build(snapshot), src.Kind == SourceReplication); err != nil {
+		return nil, err
+	}
+	old, in, err := app.transfer(req.From, req.To, build, req.LatestTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	logf(ctx, "action transfer %s/%s -> %s", getKey(req.AppId, req.Env), req.From, req.To)
+	r.events.append(Event{Type: EventCancel, Env: req.Env, AppId: req.AppId, Hostname: req.From, Instance: old, RequestID: RequestIDFromContext(ctx), Source: src})
+	r.events.append(Event{Type: EventRegister, Env: req.Env, AppId: req.AppId, Hostname: req.To, Instance: in, Created: true, RequestID: RequestIDFromContext(ctx), Source: src})
+	r.activity.record(req.Env, req.AppId, activityCancel)
+	r.activity.record(req.Env, req.AppId, activityRegister)
+	r.tombstones.add(Tombstone{Env: req.Env, AppId: req.AppId, Hostname: req.From, DeletedAt: time.Now().UnixNano(), Clock: clock})
+
+	fromKey, toKey := getInstanceKey(req.AppId, req.Env, req.From), getInstanceKey(req.AppId, req.Env, req.To)
+	r.health.move(fromKey, toKey)
+	r.capacity.remove(fromKey)
+	r.workloadCerts.remove(req.Env, req.AppId, req.From)
+	if r.credentials != nil {
+		r.credentials.remove(req.Env, req.AppId, req.From)
+		r.credentials.issue(req.Env, req.AppId, req.To)
+	}
+	r.issueCertificate(ctx, in, true)
+	// 先复制新实例再复制下线，备节点上的实例数同样不会翻倍后回落
+	r.replicate(ctx, ReplicationOp{Action: ActionRegister, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Instance: in, LatestTimestamp: req.LatestTimestamp})
+	r.replicate(ctx, ReplicationOp{Action: ActionCancel, Env: req.Env, AppId: req.AppId, Hostname: req.From, LatestTimestamp: req.LatestTimestamp, Clock: clock})
+	return in, nil
+}
+
+func (req *TransferRequest) validate() error {
+	e := &InvalidRequestError{}
+	for _, f := range []struct{ name, value string }{{"env", req.Env}, {"appId", req.AppId}, {"from", req.From}, {"to", req.To}} {
+		if reason := checkName(f.value); reason != "" {
+			e.add(f.name, reason)
+		}
+	}
+	if req.From != "" && req.From == req.To {
+		e.add("to", "must differ from from")
+	}
+	for _, f := range []struct {
+		name  string
+		addrs []string
+	}{{"addrs", req.Addrs}, {"secure_addrs", req.SecureAddrs}} {
+		for _, addr := range f.addrs {
+			if !ValidAddr(addr) {
+				e.add(f.name, "must be scheme://host[:port] or host:port")
+				break
+			}
+		}
+	}
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// transfer 在应用锁内用 build 生成的新实例替换 from，返回原实例和新实例的副本
+func (app *Application) transfer(from, to string, build func(old *Instance) *Instance, latestTimestamp int64) (*Instance, *Instance, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	old, ok := app.instances[from]
+	if !ok {
+		return nil, nil, ErrInstanceNotFound
+	}
+	if _, ok := app.instances[to]; ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInstanceExists, to)
+	}
+	in := build(old)
+	delete(app.instances, from)
+	app.instances[to] = in
+	app.statusCounts[old.Status]--
+	app.statusCounts[in.Status]++
+	app.upLatestTimestamp(latestTimestamp)
+	old = copyInstance(old)
+	old.LatestTimestamp = latestTimestamp
+	return old, copyInstance(in), nil
+}
@@ -0,0 +1,54 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry(WithHeartbeatCredentials(0, 0))
+	defer r.Close()
+	blue := &Instance{Env: "test", AppId: "com.xx.a", Hostname: "blue", Addrs: []string{"http://10.0.0.1:80"}, Status: StatusUP, Weight: 30, Version: "v1",
+		Metadata: map[string]string{"zone": "z1"}}
+	r.Register(blue, 1)
+	r.Register(&Instance{Env: "test", AppId: "com.xx.a", Hostname: "other", Status: StatusUP}, 2)
+	r.SetHealthOverride("test", "com.xx.a", "blue", 40)
+	weight := uint32(50)
+	r.Set(ctx, SetRequest{Env: "test", AppId: "com.xx.a", Hostnames: []string{"blue"}, Weight: &weight})
+	before, _ := r.Fetch("test", "com.xx.a", StatusUP, 0)
+	seq := r.LastEventSeq()
+
+	in, err := r.Transfer(ctx, TransferRequest{Env: "test", AppId: "com.xx.a", From: "blue", To: "green", Addrs: []string{"http://10.0.0.2:80"}})
+	if err != nil || in.Hostname != "green" || in.Weight != 50 || in.Version != "v1" || in.Metadata["zone"] != "z1" || in.Addrs[0] != "http://10.0.0.2:80" {
+		t.Fatalf("unexpected transfer %+v %v", in, err)
+	}
+	data, _ := r.Fetch("test", "com.xx.a", StatusUP, 0)
+	if len(data.Instances) != 2 || data.LatestTimestamp <= before.LatestTimestamp {
+		t.Fatalf("unexpected instances after transfer %+v", data)
+	}
+	if score, _ := r.HealthScore("test", "com.xx.a", "green"); score != 40 {
+		t.Fatalf("health override should move to new host, got %d", score)
+	}
+	if _, ok := r.HeartbeatCredential("test", "com.xx.a", "green"); !ok {
+		t.Fatal("new host should get a heartbeat credential")
+	}
+	if events, _ := r.Replay(seq, 0); len(events) != 2 || events[0].Type != EventCancel || events[1].Type != EventRegister || events[1].Hostname != "green" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	// 原实例留下墓碑，延迟到达的旧注册不会复活它
+	if err := r.ApplyReplication(ReplicationOp{Action: ActionRegister, Instance: blue, LatestTimestamp: 3}); !errors.Is(err, ErrStaleRegistration) {
+		t.Fatalf("expect stale registration, got %v", err)
+	}
+
+	if _, err := r.Transfer(ctx, TransferRequest{Env: "test", AppId: "com.xx.a", From: "green", To: "other"}); !errors.Is(err, ErrInstanceExists) {
+		t.Fatalf("expect instance exists, got %v", err)
+	}
+	if _, err := r.Transfer(ctx, TransferRequest{Env: "test", AppId: "com.xx.a", From: "blue", To: "green2"}); !errors.Is(err, ErrInstanceNotFound) {
+		t.Fatalf("expect instance not found, got %v", err)
+	}
+	if _, err := r.Transfer(ctx, TransferRequest{Env: "test", AppId: "com.xx.a", From: "green", To: "green"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expect invalid request, got %v", err)
+	}
+}
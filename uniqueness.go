@@ -0,0 +1,184 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrAddrConflict 注册的地址已被其他应用的实例占用
+var ErrAddrConflict = errors.New("address registered by another instance")
+
+// UniquenessPolicy 地址唯一性约束，防止复制粘贴配置错误把同一个后端注册到两个服务下
+type UniquenessPolicy int
+
+const (
+	// UniqueNone 不检查，默认
+	UniqueNone UniquenessPolicy = iota
+	// UniqueAddrPerEnv 同一环境内一个 host:port 同时只能属于一个应用
+	UniqueAddrPerEnv
+	// UniqueAddrGlobal 所有环境内一个 host:port 同时只能属于一个应用
+	UniqueAddrGlobal
+)
+
+// maxAddrConflicts 保留的最近被拒绝的冲突数
+const maxAddrConflicts = 100
+
+// AddrConflict 一次因地址冲突被拒绝的注册
+type AddrConflict struct {
+	Addr      string `json:"addr"`
+	Env       string `json:"env"` // 被拒绝的注册
+	AppId     string `json:"appId"`
+	Hostname  string `json:"hostname"`
+	Owner     Owner  `json:"owner"` // 占用该地址的实例
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Owner 占用地址的实例
+type Owner struct {
+	Env      string `json:"env"`
+	AppId    string `json:"appId"`
+	Hostname string `json:"hostname"`
+}
+
+// AddrConflictError 注册的地址与已有实例冲突
+type AddrConflictError struct {
+	Conflict AddrConflict
+}
+
+func (e *AddrConflictError) Error() string {
+	o := e.Conflict.Owner
+	return fmt.Sprintf("%v: %s owned by %s/%s", ErrAddrConflict, e.Conflict.Addr, getKey(o.AppId, o.Env), o.Hostname)
+}
+
+func (e *AddrConflictError) Unwrap() error {
+	return ErrAddrConflict
+}
+
+// addrIndex 地址到占用实例的索引。实例下线或改地址时不清理，
+// 冲突时再确认占用方是否仍持有该地址，失效的占用直接被新实例取代
+type addrIndex struct {
+	lock      sync.Mutex
+	policy    UniquenessPolicy
+	owners    map[string]Owner // key: 地址，UniqueAddrPerEnv 时带环境前缀
+	conflicts []AddrConflict
+}
+
+func newAddrIndex() *addrIndex {
+	return &addrIndex{owners: make(map[string]Owner)}
+}
+
+func (x *addrIndex) key(env, addr string) string {
+	if x.policy == UniqueAddrPerEnv {
+		return env + "/" + addr
+	}
+	return addr
+}
+
+// instanceAddrs 实例注册的 host:port
+func instanceAddrs(in *Instance) []string {
+	eps := in.Endpoints
+	if len(eps) == 0 {
+		_, eps = parseEndpoints(in.Addrs, nil)
+	}
+	addrs := make([]string, 0, len(eps))
+	for _, ep := range eps {
+		if ep.Addr != "" {
+			addrs = append(addrs, ep.Addr)
+		}
+	}
+	return addrs
+}
+
+// claimAddrs 检查实例的地址是否已被其他应用的实例占用，没有冲突时占用这些地址。
+// 同一应用的其他实例使用相同地址不算冲突；force 时不检查直接占用，用于复制过来的注册
+func (r *Registry) claimAddrs(in *Instance, force bool) error {
+	x := r.addrs
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.policy == UniqueNone {
+		return nil
+	}
+	self := Owner{Env: in.Env, AppId: in.AppId, Hostname: in.Hostname}
+	addrs := instanceAddrs(in)
+	for _, addr := range addrs {
+		o, ok := x.owners[x.key(in.Env, addr)]
+		if force || !ok || o == self || (o.AppId == in.AppId && o.Env == in.Env) || !r.ownsAddr(o, addr) {
+			continue
+		}
+		c := AddrConflict{Addr: addr, Env: in.Env, AppId: in.AppId, Hostname: in.Hostname, Owner: o, Timestamp: time.Now().UnixNano()}
+		if len(x.conflicts) >= maxAddrConflicts {
+			x.conflicts = x.conflicts[1:]
+		}
+		x.conflicts = append(x.conflicts, c)
+		return &AddrConflictError{Conflict: c}
+	}
+	for _, addr := range addrs {
+		x.owners[x.key(in.Env, addr)] = self
+	}
+	return nil
+}
+
+// ownsAddr 实例是否仍在注册表中且持有该地址
+func (r *Registry) ownsAddr(o Owner, addr string) bool {
+	in, ok := r.getInstance(o.Env, o.AppId, o.Hostname)
+	if !ok {
+		return false
+	}
+	for _, a := range instanceAddrs(in) {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// SetUniquenessPolicy 设置地址唯一性约束，只对之后的注册生效，已存在的冲突不会被清理
+func (r *Registry) SetUniquenessPolicy(p UniquenessPolicy) {
+	x := r.addrs
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	if x.policy == p {
+		return
+	}
+	x.policy = p
+	x.owners = make(map[string]Owner)
+	if p == UniqueNone {
+		return
+	}
+	r.lock.RLock()
+	apps := make([]*Application, 0, len(r.apps))
+	for _, app := range r.apps {
+		apps = append(apps, app)
+	}
+	r.lock.RUnlock()
+	for _, app := range apps {
+		for _, in := range app.GetAllInstances() {
+			for _, addr := range instanceAddrs(in) {
+				x.owners[x.key(in.Env, addr)] = Owner{Env: in.Env, AppId: in.AppId, Hostname: in.Hostname}
+			}
+		}
+	}
+}
+
+// AddrConflicts 返回最近因地址冲突被拒绝的注册，按时间先后排列
+func (r *Registry) AddrConflicts() []AddrConflict {
+	return r.AddrConflictsContext(context.Background())
+}
+
+// AddrConflictsContext 同 AddrConflicts，只包含被拒绝的应用及占用方都对 ctx 中调用方可见的冲突，见 Visible
+func (r *Registry) AddrConflictsContext(ctx context.Context) []AddrConflict {
+	x := r.addrs
+	x.lock.Lock()
+	conflicts := append([]AddrConflict(nil), x.conflicts...)
+	x.lock.Unlock()
+	rs := conflicts[:0]
+	for _, c := range conflicts {
+		if r.Visible(ctx, c.Env, c.AppId) && r.Visible(ctx, c.Owner.Env, c.Owner.AppId) {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
@@ -0,0 +1,52 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestUniquenessPolicy(t *testing.T) {
+	r := NewRegistry(WithUniquenessPolicy(UniqueAddrPerEnv))
+	defer r.Close()
+	reg := func(env, appid, hostname, addr string) error {
+		_, err := r.Register(&Instance{Env: env, AppId: appid, Hostname: hostname, Addrs: []string{addr}, Status: StatusUP}, 1)
+		return err
+	}
+	if err := reg("test", "com.xx.a", "a1", "http://10.0.0.1:80"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg("test", "com.xx.a", "a2", "grpc://10.0.0.1:80"); err != nil {
+		t.Fatalf("same app may share address: %v", err)
+	}
+	if err := reg("dev", "com.xx.b", "b1", "http://10.0.0.1:80"); err != nil {
+		t.Fatalf("other env should not conflict: %v", err)
+	}
+
+	err := reg("test", "com.xx.b", "b1", "http://10.0.0.1:80")
+	var conflict *AddrConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrAddrConflict) || conflict.Conflict.Owner.AppId != "com.xx.a" {
+		t.Fatalf("expect address conflict, got %v", err)
+	}
+	if cs := r.AddrConflicts(); len(cs) != 1 || cs[0].AppId != "com.xx.b" || cs[0].Addr != "10.0.0.1:80" {
+		t.Fatalf("unexpected conflicts %+v", cs)
+	}
+	// 涉及受限应用的冲突对其他调用方隐藏
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.a", Visibility: VisibilityRestricted})
+	if cs := r.AddrConflictsContext(ContextWithCaller(context.Background(), "")); len(cs) != 0 {
+		t.Fatalf("conflict with a restricted owner should be hidden, got %+v", cs)
+	}
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.a"})
+
+	// 占用方下线后地址可以被其他应用注册
+	r.Cancel("test", "com.xx.a", "a1", 2)
+	r.Cancel("test", "com.xx.a", "a2", 2)
+	if err := reg("test", "com.xx.b", "b1", "http://10.0.0.1:80"); err != nil {
+		t.Fatalf("released address should be claimable: %v", err)
+	}
+
+	r.SetUniquenessPolicy(UniqueAddrGlobal)
+	if err := reg("prod", "com.xx.c", "c1", "http://10.0.0.1:80"); !errors.Is(err, ErrAddrConflict) {
+		t.Fatalf("expect global conflict with existing instances, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package registry_center
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidRequest 请求参数不合法
+var ErrInvalidRequest = errors.New("invalid request")
+
+// MaxTimestampSkew 请求中的时间戳最多超前当前时间的时长，超出视为时钟错误或单位错误
+const MaxTimestampSkew = 10 * time.Minute
+
+// minTimestamp 2000-01-01，更小的纳秒时间戳通常是误传了秒或毫秒
+const minTimestamp = int64(946684800) * int64(time.Second)
+
+// FieldViolation 一个字段的校验错误，Field 为接口参数名
+type FieldViolation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// InvalidRequestError 请求中不合法的字段，每个字段只报告第一个错误。
+// HTTP 接口返回 400，响应 data.errors 为各字段的错误
+type InvalidRequestError struct {
+	Violations []FieldViolation `json:"errors"`
+}
+
+func (e *InvalidRequestError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Field + " " + v.Reason
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+func (e *InvalidRequestError) Unwrap() error {
+	return ErrInvalidRequest
+}
+
+func (e *InvalidRequestError) add(field, reason string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Reason: reason})
+}
+
+// Validate 校验注册请求：env、appid、hostname 非空且不含空白字符，至少一个地址且格式正确，
+// 状态为可用或不可用，时间戳为纳秒且不超前当前时间 MaxTimestampSkew
+func (req *RequestRegister) Validate() error {
+	e := &InvalidRequestError{}
+	for _, f := range []struct{ name, value string }{{"env", req.Env}, {"appid", req.AppId}, {"hostname", req.Hostname}} {
+		if reason := checkName(f.value); reason != "" {
+			e.add(f.name, reason)
+		}
+	}
+	if len(req.Addrs)+len(req.SecureAddrs) == 0 {
+		e.add("addrs", "is required")
+	}
+	for _, f := range []struct {
+		name  string
+		addrs []string
+	}{{"addrs", req.Addrs}, {"secure_addrs", req.SecureAddrs}} {
+		for _, addr := range f.addrs {
+			if !ValidAddr(addr) {
+				e.add(f.name, "must be scheme://host[:port] or host:port")
+				break
+			}
+		}
+	}
+	if req.Status != 0 && req.Status != StatusUP && req.Status != StatusDown {
+		e.add("status", "must be one of 1, 2")
+	}
+	if req.SpiffeID != "" {
+		if _, err := ParseSpiffeID(req.SpiffeID); err != nil {
+			e.add("spiffe_id", "must be spiffe://trust-domain/path")
+		}
+	}
+	if req.InstanceID != "" {
+		if reason := checkName(req.InstanceID); reason != "" {
+			e.add("instance_id", reason)
+		}
+	}
+	if len(req.PortNames) > 0 {
+		registered := make(map[string]bool, len(req.Addrs)+len(req.SecureAddrs))
+		for _, addr := range append(append([]string{}, req.Addrs...), req.SecureAddrs...) {
+			registered[addr] = true
+		}
+		for addr := range req.PortNames {
+			if !registered[addr] {
+				e.add("port_names", "must name registered addrs")
+				break
+			}
+		}
+	}
+	if _, ok := req.Metadata[""]; ok {
+		e.add("metadata", "must not have empty keys")
+	}
+	now := time.Now()
+	for _, f := range []struct {
+		name string
+		ts   int64
+	}{{"latest_timestamp", req.LatestTimestamp}, {"dirty_timestamp", req.DirtyTimestamp}} {
+		if reason := checkTimestamp(f.ts, now); reason != "" {
+			e.add(f.name, reason)
+		}
+	}
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+// checkStatus 修改实例状态时只允许 StatusUP 和 StatusDown，0 表示不修改
+func checkStatus(status uint32) error {
+	if status != 0 && status != StatusUP && status != StatusDown {
+		return &InvalidRequestError{Violations: []FieldViolation{{Field: "status", Reason: "must be one of 1, 2"}}}
+	}
+	return nil
+}
+
+func checkName(v string) string {
+	if v == "" {
+		return "is required"
+	}
+	if strings.IndexFunc(v, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0 {
+		return "must not contain whitespace or control characters"
+	}
+	return ""
+}
+
+// checkTimestamp 0 表示未传
+func checkTimestamp(ts int64, now time.Time) string {
+	switch {
+	case ts == 0:
+		return ""
+	case ts < minTimestamp:
+		return "must be a unix timestamp in nanoseconds"
+	case ts > now.Add(MaxTimestampSkew).UnixNano():
+		return "is too far in the future"
+	}
+	return ""
+}
+
+// ValidAddr 地址为 scheme://host[:port][/path] 或 host:port
+func ValidAddr(addr string) bool {
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil || u.Scheme == "" || u.Hostname() == "" {
+			return false
+		}
+		return u.Port() == "" || validPort(u.Port())
+	}
+	host, port, err := net.SplitHostPort(addr)
+	return err == nil && host != "" && validPort(port)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.ParseUint(port, 10, 16)
+	return err == nil && n > 0
+}
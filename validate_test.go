@@ -0,0 +1,38 @@
+package registry_center
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateRegister(t *testing.T) {
+	ok := &RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", Addrs: []string{"grpc://10.0.0.1:9000"}, Status: StatusUP,
+		LatestTimestamp: time.Now().UnixNano()}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		req   RequestRegister
+		field string
+	}{
+		"hostname":  {RequestRegister{Env: "test", AppId: "com.xx.a", Addrs: []string{"10.0.0.1:80"}}, "hostname"},
+		"space":     {RequestRegister{Env: "test", AppId: "com.xx a", Hostname: "h1", Addrs: []string{"10.0.0.1:80"}}, "appid"},
+		"no addrs":  {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1"}, "addrs"},
+		"bad addr":  {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", SecureAddrs: []string{"https://"}}, "secure_addrs"},
+		"status":    {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", Addrs: []string{"10.0.0.1:80"}, Status: 3}, "status"},
+		"seconds":   {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", Addrs: []string{"10.0.0.1:80"}, DirtyTimestamp: time.Now().Unix()}, "dirty_timestamp"},
+		"future":    {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", Addrs: []string{"10.0.0.1:80"}, LatestTimestamp: time.Now().Add(time.Hour).UnixNano()}, "latest_timestamp"},
+		"spiffe id": {RequestRegister{Env: "test", AppId: "com.xx.a", Hostname: "h1", Addrs: []string{"10.0.0.1:80"}, SpiffeID: "h1"}, "spiffe_id"},
+	} {
+		err := tc.req.Validate()
+		var ie *InvalidRequestError
+		if !errors.As(err, &ie) || !errors.Is(err, ErrInvalidRequest) || len(ie.Violations) != 1 || ie.Violations[0].Field != tc.field {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		if info := ErrorInfoOf(err); info.Code != CodeInvalidArgument || info.Retryable || info.Details[tc.field] == "" {
+			t.Fatalf("%s: unexpected error info %+v", name, info)
+		}
+	}
+}
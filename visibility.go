@@ -0,0 +1,139 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// 应用的可见性
+const (
+	VisibilityPublic     = "public"     // 环境内所有调用方都可以获取
+	VisibilityRestricted = "restricted" // 只有 Consumers 中的调用方或出示了包含该应用的只读令牌的调用方可以获取
+)
+
+// AppVisibility 应用的可见性配置
+type AppVisibility struct {
+	Env        string   `json:"env"`
+	AppId      string   `json:"appId"`
+	Visibility string   `json:"visibility"`
+	Consumers  []string `json:"consumers,omitempty"` // 允许的调用方身份，如 SPIFFE ID，末尾为 * 时按前缀匹配
+}
+
+type visibilityController struct {
+	apps map[AppKey]*AppVisibility // 只保存 restricted 的应用
+	lock sync.RWMutex
+}
+
+func newVisibilityController() *visibilityController {
+	return &visibilityController{apps: make(map[AppKey]*AppVisibility)}
+}
+
+type callerKey struct{}
+
+// ContextWithCaller 放入经过认证的调用方身份，API 层根据 mTLS 客户端证书等设置，
+// 无法认证时传入空字符串。没有设置调用方的 ctx 视为进程内调用，不做可见性限制
+func ContextWithCaller(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, callerKey{}, identity)
+}
+
+// CallerFromContext 返回调用方身份，ok 为 false 表示进程内调用
+func CallerFromContext(ctx context.Context) (identity string, ok bool) {
+	identity, ok = ctx.Value(callerKey{}).(string)
+	return identity, ok
+}
+
+type adminKey struct{}
+
+// ContextWithAdmin 标记调用方已通过管理员认证，所有应用对其可见
+func ContextWithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// isAdmin 调用方是否已通过管理员认证
+func isAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// SetVisibility 设置应用的可见性，Visibility 为空或 public 时取消限制
+func (r *Registry) SetVisibility(v AppVisibility) error {
+	if v.Env == "" || v.AppId == "" {
+		return errors.New("visibility env and appId are required")
+	}
+	c := r.visibility
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := getKey(v.AppId, v.Env)
+	switch v.Visibility {
+	case "", VisibilityPublic:
+		delete(c.apps, key)
+	case VisibilityRestricted:
+		v.Consumers = append([]string(nil), v.Consumers...)
+		c.apps[key] = &v
+	default:
+		return errors.New("unknown visibility " + v.Visibility)
+	}
+	return nil
+}
+
+// Visibility 返回应用的可见性配置
+func (r *Registry) Visibility(env, appid string) AppVisibility {
+	c := r.visibility
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if v, ok := c.apps[getKey(appid, env)]; ok {
+		rs := *v
+		rs.Consumers = append([]string(nil), v.Consumers...)
+		return rs
+	}
+	return AppVisibility{Env: env, AppId: appid, Visibility: VisibilityPublic}
+}
+
+// visibleTo 应用对调用方是否可见
+func (c *visibilityController) visibleTo(caller string, env, appid string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	v, ok := c.apps[getKey(appid, env)]
+	if !ok {
+		return true
+	}
+	if caller == "" {
+		return false
+	}
+	for _, consumer := range v.Consumers {
+		if consumer == caller || strings.HasSuffix(consumer, "*") && strings.HasPrefix(caller, strings.TrimSuffix(consumer, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Visible 应用对 ctx 中的调用方是否可见，进程内调用及管理员总是可见。调用方出示了只读令牌时以令牌为准，
+// 令牌范围内的应用可见，包括受限应用；没有令牌时受限应用只对 Consumers 中的调用方可见
+func (r *Registry) Visible(ctx context.Context, env, appid string) bool {
+	if isAdmin(ctx) {
+		return true
+	}
+	if t, ok := TokenFromContext(ctx); ok {
+		return t.Allows(env, appid)
+	}
+	caller, ok := CallerFromContext(ctx)
+	return !ok || r.visibility.visibleTo(caller, env, appid)
+}
+
+// CheckVisible 应用不可见时记入审计日志并返回 ErrAppNotFound，调用方无法区分应用是否存在。
+// Fetch 类接口已经检查，推送通道在订阅时调用
+func (r *Registry) CheckVisible(ctx context.Context, env, appid string) error {
+	if r.Visible(ctx, env, appid) {
+		return nil
+	}
+	caller, _ := CallerFromContext(ctx)
+	reason := "app is restricted"
+	if t, ok := TokenFromContext(ctx); ok && !t.Allows(env, appid) {
+		caller, reason = t.Subject, "outside token "+t.ID+" scope"
+	}
+	r.audit(ctx, AuditEntry{Action: AuditFetchDenied, Caller: caller, Env: env, AppId: appid, Reason: reason})
+	return ErrAppNotFound
+}
@@ -0,0 +1,60 @@
+package registry_center
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVisibility(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.private", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.public", Hostname: "b", Status: StatusUP}), time.Now().UnixNano())
+	if err := r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.private", Visibility: VisibilityRestricted,
+		Consumers: []string{"spiffe://td/ns/payments/*"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := ContextWithCaller(context.Background(), "spiffe://td/ns/payments/sa/api")
+	denied := ContextWithCaller(context.Background(), "spiffe://td/ns/web/sa/ui")
+	if _, err := r.FetchContext(allowed, "test", "com.xx.private", StatusUP, 0, FetchOptions{}); err != nil {
+		t.Fatalf("listed consumer should fetch, got %v", err)
+	}
+	if _, err := r.FetchContext(denied, "test", "com.xx.private", StatusUP, 0, FetchOptions{}); !errors.Is(err, ErrAppNotFound) {
+		t.Fatalf("expect app hidden, got %v", err)
+	}
+	if _, err := r.Fetch("test", "com.xx.private", StatusUP, 0); err != nil {
+		t.Fatalf("in-process fetch should not be restricted, got %v", err)
+	}
+	if all, _ := r.FetchAllContext(denied, "test"); len(all) != 1 || all["com.xx.public"] == nil {
+		t.Fatalf("restricted app should be filtered from fetch all: %v", all)
+	}
+	if log := r.AuditLog(); len(log) != 1 || log[0].Action != AuditFetchDenied || log[0].Caller != "spiffe://td/ns/web/sa/ui" {
+		t.Fatalf("unexpected audit log %+v", log)
+	}
+
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.private", Visibility: VisibilityPublic})
+	if _, err := r.FetchContext(denied, "test", "com.xx.private", StatusUP, 0, FetchOptions{}); err != nil {
+		t.Fatalf("public app should be visible, got %v", err)
+	}
+}
+
+func TestVisibilityTokenGrant(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.private", Hostname: "a", Status: StatusUP}), time.Now().UnixNano())
+	r.SetVisibility(AppVisibility{Env: "test", AppId: "com.xx.private", Visibility: VisibilityRestricted})
+
+	anonymous := ContextWithCaller(context.Background(), "")
+	if r.Visible(anonymous, "test", "com.xx.private") {
+		t.Fatal("restricted app should be hidden from callers without a token")
+	}
+	granted := ContextWithToken(anonymous, &ScopedToken{Env: "test", AppIds: []string{"com.xx.private"}})
+	if _, err := r.FetchContext(granted, "test", "com.xx.private", StatusUP, 0, FetchOptions{}); err != nil {
+		t.Fatalf("a token granting the app should fetch it, got %v", err)
+	}
+	other := ContextWithToken(anonymous, &ScopedToken{Env: "test", AppIds: []string{"com.xx.other"}})
+	if r.Visible(other, "test", "com.xx.private") {
+		t.Fatal("a token for other apps must not grant the restricted app")
+	}
+}
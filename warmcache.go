@@ -0,0 +1,241 @@
+package registry_center
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskWarmCache 定期重建热点应用 Fetch 缓存的后台任务
+const TaskWarmCache = "warm_cache"
+
+// HotApp 需要预热 Fetch 缓存的应用
+type HotApp struct {
+	Env   string `json:"env"`
+	AppId string `json:"appId"`
+}
+
+// WarmCacheConfig 热点应用缓存配置。热点应用的默认 Fetch 结果（状态为可用、无附加条件）及其 JSON、protobuf
+// 序列化结果在注册表变化后立即重建，而不是等到失效后的第一个请求，避免大规模发布后的延迟尖刺
+type WarmCacheConfig struct {
+	Apps []HotApp
+	// RefreshInterval 定期重建的间隔，用于刷新健康分、灰度权重等不产生事件的变化，默认 5s
+	RefreshInterval time.Duration
+}
+
+// WarmAppStatus 热点应用缓存的状况
+type WarmAppStatus struct {
+	HotApp
+	LatestTimestamp int64  `json:"latest_timestamp"` // 缓存数据的版本，未缓存时为 0
+	BuiltAt         int64  `json:"built_at"`         // 最近一次构建的时间
+	Hits            uint64 `json:"hits"`
+	Misses          uint64 `json:"misses"`
+}
+
+// fetchPayload 预先序列化的 FetchData
+type fetchPayload struct {
+	json []byte
+	pb   []byte
+}
+
+type warmEntry struct {
+	status WarmAppStatus
+	data   *FetchData
+}
+
+// warmCache 热点应用的 Fetch 缓存
+type warmCache struct {
+	cfg     WarmCacheConfig
+	lock    sync.RWMutex
+	entries map[AppKey]*warmEntry
+}
+
+func newWarmCache(cfg WarmCacheConfig) *warmCache {
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 5 * time.Second
+	}
+	c := &warmCache{cfg: cfg, entries: make(map[AppKey]*warmEntry, len(cfg.Apps))}
+	for _, app := range cfg.Apps {
+		c.entries[getKey(app.AppId, app.Env)] = &warmEntry{status: WarmAppStatus{HotApp: app}}
+	}
+	return c
+}
+
+func (c *warmCache) hot(key AppKey) bool {
+	_, ok := c.entries[key]
+	return ok
+}
+
+// store 序列化并缓存 data，返回带序列化结果的副本。并发构建时不会用旧数据覆盖新数据
+func (c *warmCache) store(key AppKey, data *FetchData) *FetchData {
+	body, err := json.Marshal((*plainFetchData)(data))
+	if err != nil {
+		return data
+	}
+	cached := copyFetchData(data)
+	cached.payload = &fetchPayload{json: body, pb: MarshalFetchData(data)}
+	c.lock.Lock()
+	e := c.entries[key]
+	if e.data != nil && e.data.LatestTimestamp > data.LatestTimestamp {
+		c.lock.Unlock()
+		return cached
+	}
+	e.data = cached
+	e.status.LatestTimestamp = data.LatestTimestamp
+	e.status.BuiltAt = time.Now().UnixNano()
+	c.lock.Unlock()
+	return c.copyOf(cached)
+}
+
+// drop 应用不存在或没有可用实例时删除缓存
+func (c *warmCache) drop(key AppKey) {
+	c.lock.Lock()
+	e := c.entries[key]
+	e.data = nil
+	e.status.LatestTimestamp = 0
+	e.status.BuiltAt = time.Now().UnixNano()
+	c.lock.Unlock()
+}
+
+// load 返回版本为 latestTimestamp 且缓存提示一致的缓存数据
+func (c *warmCache) load(key AppKey, latestTimestamp int64, hints *CacheHints) (*FetchData, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := c.entries[key]
+	if e.data == nil || e.data.LatestTimestamp != latestTimestamp || *e.data.Hints != *hints {
+		e.status.Misses++
+		return nil, false
+	}
+	e.status.Hits++
+	return c.copyOf(e.data), true
+}
+
+func (c *warmCache) copyOf(data *FetchData) *FetchData {
+	dst := copyFetchData(data)
+	dst.payload = data.payload
+	return dst
+}
+
+// WithWarmCache 开启热点应用的 Fetch 缓存预热
+func WithWarmCache(cfg WarmCacheConfig) Option {
+	return func(r *Registry) {
+		r.warm = newWarmCache(cfg)
+	}
+}
+
+// startWarmCache 构建热点应用缓存，并在注册表变化及定期重建
+func (r *Registry) startWarmCache() {
+	seq := r.events.lastSeq()
+	r.rebuildWarm(nil)
+	r.AddTask(TaskWarmCache, r.warm.cfg.RefreshInterval, func(ctx context.Context) error {
+		r.rebuildWarm(nil)
+		return nil
+	})
+	m := r.tasks
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		r.watchWarmApps(m.ctx, seq)
+	}()
+}
+
+// watchWarmApps 按事件日志重建序号 seq 之后发生变化的热点应用
+func (r *Registry) watchWarmApps(ctx context.Context, seq uint64) {
+	for {
+		wait := r.events.wait()
+		events, err := r.events.since(seq, 0)
+		switch {
+		case err != nil: // 落后超过事件日志保留范围，全部重建
+			seq = r.events.lastSeq()
+			r.rebuildWarm(nil)
+		case len(events) > 0:
+			seq = events[len(events)-1].Seq
+			changed := make(map[AppKey]bool)
+			for _, e := range events {
+				if key := getKey(e.AppId, e.Env); r.warm.hot(key) {
+					changed[key] = true
+				}
+			}
+			if len(changed) > 0 {
+				r.rebuildWarm(changed)
+			}
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// rebuildWarm 重建 keys 中的热点应用，keys 为空时重建全部。降级模式下 Fetch 只使用降级缓存，不重建
+func (r *Registry) rebuildWarm(keys map[AppKey]bool) {
+	if r.Degraded() {
+		return
+	}
+	for key, e := range r.warm.entries {
+		if keys != nil && !keys[key] {
+			continue
+		}
+		data, err := r.buildFetch(e.status.Env, e.status.AppId, StatusUP, 0, FetchOptions{})
+		if err != nil {
+			r.warm.drop(key)
+			continue
+		}
+		r.warm.store(key, data)
+	}
+}
+
+// warmFetch 热点应用的默认 Fetch 优先使用缓存，缓存数据落后于注册表时按原流程构建并回填缓存
+func (r *Registry) warmFetch(env, appid string, latestTimestamp int64) (*FetchData, error) {
+	key := getKey(appid, env)
+	app, ok := r.getApplication(appid, env)
+	if ok && !r.Degraded() {
+		app.lock.RLock()
+		current := app.latestTimestamp
+		app.lock.RUnlock()
+		if latestTimestamp < current {
+			if data, ok := r.warm.load(key, current, r.cacheHints()); ok {
+				return data, nil
+			}
+		}
+	}
+	data, err := r.buildFetch(env, appid, StatusUP, latestTimestamp, FetchOptions{})
+	if err != nil || r.Degraded() {
+		return data, err
+	}
+	return r.warm.store(key, data), nil
+}
+
+// WarmCacheStatus 返回各热点应用缓存的状况，未开启时返回空
+func (r *Registry) WarmCacheStatus() []WarmAppStatus {
+	if r.warm == nil {
+		return nil
+	}
+	r.warm.lock.RLock()
+	rs := make([]WarmAppStatus, 0, len(r.warm.entries))
+	for _, e := range r.warm.entries {
+		rs = append(rs, e.status)
+	}
+	r.warm.lock.RUnlock()
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Env != rs[j].Env {
+			return rs[i].Env < rs[j].Env
+		}
+		return rs[i].AppId < rs[j].AppId
+	})
+	return rs
+}
+
+// plainFetchData 不使用预先序列化结果的 FetchData
+type plainFetchData FetchData
+
+// MarshalJSON 来自热点应用缓存的 FetchData 直接返回预先序列化的结果，调用方不应修改这样的 FetchData
+func (d *FetchData) MarshalJSON() ([]byte, error) {
+	if d.payload != nil {
+		return d.payload.json, nil
+	}
+	return json.Marshal((*plainFetchData)(d))
+}
@@ -0,0 +1,53 @@
+package registry_center
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWarmCache(t *testing.T) {
+	r := NewRegistry(WithWarmCache(WarmCacheConfig{Apps: []HotApp{{Env: "test", AppId: "com.xx.hot"}}, RefreshInterval: time.Hour}))
+	defer r.Close()
+	r.Register(&Instance{Env: "test", AppId: "com.xx.hot", Hostname: "a", Addrs: []string{"http://127.0.0.1:80"}, Status: StatusUP}, 1)
+	r.Register(&Instance{Env: "test", AppId: "com.xx.cold", Hostname: "a", Status: StatusUP}, 1)
+
+	// 注册后由后台立即重建，无需等待第一个请求
+	deadline := time.Now().Add(time.Second)
+	for r.WarmCacheStatus()[0].LatestTimestamp == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("hot app was not warmed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	data, err := r.Fetch("test", "com.xx.hot", StatusUP, 0)
+	if err != nil || data.payload == nil || len(data.Instances) != 1 {
+		t.Fatalf("expect cached fetch, got %+v %v", data, err)
+	}
+	body, _ := json.Marshal(data)
+	plain, _ := json.Marshal((*plainFetchData)(data))
+	if string(body) != string(plain) {
+		t.Fatalf("cached payload differs:\n%s\n%s", body, plain)
+	}
+	if pd, err := UnmarshalFetchData(MarshalFetchData(data)); err != nil || pd.Instances[0].Hostname != "a" {
+		t.Fatalf("unexpected protobuf payload %+v %v", pd, err)
+	}
+	if _, err := r.Fetch("test", "com.xx.hot", StatusUP, data.LatestTimestamp); err != ErrNotModified {
+		t.Fatalf("expect not modified, got %v", err)
+	}
+	if cold, _ := r.Fetch("test", "com.xx.cold", StatusUP, 0); cold.payload != nil {
+		t.Fatal("cold app should not be cached")
+	}
+
+	// 缓存落后于注册表时不会返回旧数据
+	r.Register(&Instance{Env: "test", AppId: "com.xx.hot", Hostname: "b", Addrs: []string{"http://127.0.0.2:80"}, Status: StatusUP}, 2)
+	if data, err := r.Fetch("test", "com.xx.hot", StatusUP, 0); err != nil || len(data.Instances) != 2 {
+		t.Fatalf("expect fresh data, got %+v %v", data, err)
+	}
+	if data, _ := r.FetchWithOptions("test", "com.xx.hot", StatusUP, 0, FetchOptions{Scheme: "grpc"}); data != nil {
+		t.Fatal("fetch with options should bypass cache")
+	}
+	if st := r.WarmCacheStatus()[0]; st.Hits == 0 || st.LatestTimestamp != 2 {
+		t.Fatalf("unexpected status %+v", st)
+	}
+}
@@ -0,0 +1,145 @@
+package registry_center
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MetadataZone 实例元数据中表示所在可用区的键
+const MetadataZone = "zone"
+
+// WatchFilter 订阅过滤条件，在服务端计算，各条件同时满足才推送
+type WatchFilter struct {
+	Status  uint32            `json:"status,omitempty"`  // 按位匹配实例状态，0 表示不限
+	Zone    string            `json:"zone,omitempty"`    // 可用区，对应元数据 zone
+	Labels  map[string]string `json:"labels,omitempty"`  // 元数据需包含的键值
+	Version string            `json:"version,omitempty"` // 版本约束，如 ">=1.2.0,<2.0.0"、"1.3.*"、"v1.3.2"
+}
+
+// Validate 检查过滤条件是否合法
+func (f WatchFilter) Validate() error {
+	if f.Version == "" {
+		return nil
+	}
+	for _, c := range strings.Split(f.Version, ",") {
+		if _, v := splitConstraint(strings.TrimSpace(c)); v == "" {
+			return errors.New("invalid version constraint " + strconv.Quote(f.Version))
+		}
+	}
+	return nil
+}
+
+// Match 实例是否满足过滤条件
+func (f WatchFilter) Match(in *Instance) bool {
+	if in == nil {
+		return false
+	}
+	if f.Status != 0 && f.Status&in.Status == 0 {
+		return false
+	}
+	if f.Zone != "" && in.Metadata[MetadataZone] != f.Zone {
+		return false
+	}
+	for k, v := range f.Labels {
+		if in.Metadata[k] != v {
+			return false
+		}
+	}
+	if f.Version != "" {
+		for _, c := range strings.Split(f.Version, ",") {
+			if !matchVersion(strings.TrimSpace(c), in.Version) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func splitConstraint(c string) (op, version string) {
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(c, op) {
+			return op, strings.TrimSpace(c[len(op):])
+		}
+	}
+	return "=", c
+}
+
+// matchVersion 判断版本是否满足单个约束，末尾的 * 表示前缀匹配
+func matchVersion(constraint, version string) bool {
+	op, want := splitConstraint(constraint)
+	if op == "=" && strings.HasSuffix(want, "*") {
+		return strings.HasPrefix(strings.TrimPrefix(version, "v"), strings.TrimPrefix(strings.TrimSuffix(want, "*"), "v"))
+	}
+	c := compareVersion(version, want)
+	switch op {
+	case ">=":
+		return c >= 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case "<":
+		return c < 0
+	case "!=":
+		return c != 0
+	}
+	return c == 0
+}
+
+// compareVersion 按点分段比较版本，忽略前缀 v，数字段按数值比较，其余按字符串比较
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case x == y:
+			continue
+		case x == "":
+			xn, xerr = 0, nil
+		case y == "":
+			yn, yerr = 0, nil
+		}
+		if xerr == nil && yerr == nil {
+			if xn != yn {
+				return cmpInt64(int64(xn), int64(yn))
+			}
+			continue
+		}
+		return strings.Compare(x, y)
+	}
+	return 0
+}
+
+// eventFilter 按过滤条件筛选某个应用的事件。记录已推送过的实例，
+// 实例变更后不再满足条件时仍推送一次，订阅者据此把它移出
+type eventFilter struct {
+	filter  WatchFilter
+	matched map[string]bool // key: hostname
+}
+
+func newEventFilter(f WatchFilter) *eventFilter {
+	return &eventFilter{filter: f, matched: make(map[string]bool)}
+}
+
+func (f *eventFilter) accept(e *Event) bool {
+	match := e.Instance != nil && f.filter.Match(e.Instance)
+	was := f.matched[e.Hostname]
+	removed := e.Type != EventRegister
+	switch {
+	case match && !removed:
+		f.matched[e.Hostname] = true
+	default:
+		delete(f.matched, e.Hostname)
+	}
+	return match || was
+}
@@ -0,0 +1,45 @@
+package registry_center
+
+import "testing"
+
+func TestWatchFilterMatch(t *testing.T) {
+	in := &Instance{Status: StatusUP, Version: "v1.3.2", Metadata: map[string]string{MetadataZone: "sh-a", "team": "pay"}}
+	cases := []struct {
+		filter WatchFilter
+		want   bool
+	}{
+		{WatchFilter{}, true},
+		{WatchFilter{Status: StatusDown}, false},
+		{WatchFilter{Zone: "sh-a", Labels: map[string]string{"team": "pay"}}, true},
+		{WatchFilter{Zone: "sh-b"}, false},
+		{WatchFilter{Version: ">=1.2.0,<2.0.0"}, true},
+		{WatchFilter{Version: "1.3.*"}, true},
+		{WatchFilter{Version: "v1.3.2"}, true},
+		{WatchFilter{Version: ">1.3.10"}, false},
+		{WatchFilter{Version: "!=1.3.2"}, false},
+	}
+	for _, c := range cases {
+		if got := c.filter.Match(in); got != c.want {
+			t.Fatalf("filter %+v: expect %v, got %v", c.filter, c.want, got)
+		}
+	}
+	if err := (WatchFilter{Version: ">="}).Validate(); err == nil {
+		t.Fatal("empty version constraint should be invalid")
+	}
+}
+
+func TestEventFilterLeave(t *testing.T) {
+	f := newEventFilter(WatchFilter{Status: StatusUP})
+	up := &Event{Type: EventRegister, Hostname: "a", Instance: &Instance{Status: StatusUP}}
+	down := &Event{Type: EventRegister, Hostname: "a", Instance: &Instance{Status: StatusDown}}
+	other := &Event{Type: EventRegister, Hostname: "b", Instance: &Instance{Status: StatusDown}}
+	if !f.accept(up) {
+		t.Fatal("matching event should be accepted")
+	}
+	if !f.accept(down) {
+		t.Fatal("instance leaving the filter should be delivered once")
+	}
+	if f.accept(down) || f.accept(other) {
+		t.Fatal("non-matching events should be filtered")
+	}
+}
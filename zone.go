@@ -0,0 +1,211 @@
+package registry_center
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TaskZoneOutage 检测应用可用区整体故障的后台任务
+const TaskZoneOutage = "zone_outage"
+
+// 可用区故障事件，Hostname 为空，Zone 为发生变化的可用区
+const (
+	EventZoneDegraded  EventType = "zone_degraded"
+	EventZoneRecovered EventType = "zone_recovered"
+)
+
+// ZoneOutageConfig 可用区故障检测配置
+type ZoneOutageConfig struct {
+	Interval   time.Duration // 检测间隔，默认 10 秒
+	StaleAfter time.Duration // 超过该时长未续约的实例视为不健康，默认 45 秒
+	Retain     time.Duration // 实例已全部剔除的可用区保持降级的时长，之后视为下线不再提示，默认 10 分钟
+}
+
+// ZoneOutage 应用在某个可用区曾有健康实例，现在全部不可用或已过期剔除
+type ZoneOutage struct {
+	Env       string `json:"env"`
+	AppId     string `json:"appId"`
+	Zone      string `json:"zone"`
+	Since     int64  `json:"since"`     // 检测到故障的时间
+	Instances int    `json:"instances"` // 可用区内仍在注册表中的实例数，全部剔除后为 0
+}
+
+// ZoneFailoverHint 随 Fetch 响应下发的可用区故障转移提示。按可用区亲和选取实例的客户端
+// 所在可用区出现在 Degraded 中时，应立即扩大到 Healthy 中的可用区，而不是继续请求故障的可用区
+type ZoneFailoverHint struct {
+	Degraded []string `json:"degraded"`
+	Healthy  []string `json:"healthy,omitempty"`
+}
+
+type zoneDetector struct {
+	cfg  ZoneOutageConfig
+	lock sync.RWMutex
+	apps map[AppKey]*appZones
+}
+
+// appZones 应用各可用区的状态
+type appZones struct {
+	healthy  map[string]bool        // 上次检测时健康的可用区
+	degraded map[string]*ZoneOutage // 当前故障的可用区
+	hint     *ZoneFailoverHint      // 有故障时下发的提示，只整体替换不修改
+}
+
+func newZoneDetector(cfg ZoneOutageConfig) *zoneDetector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 45 * time.Second
+	}
+	if cfg.Retain <= 0 {
+		cfg.Retain = 10 * time.Minute
+	}
+	return &zoneDetector{cfg: cfg, apps: make(map[AppKey]*appZones)}
+}
+
+// zoneFailover 返回应用的可用区故障转移提示，没有故障或未开启检测时为空
+func (r *Registry) zoneFailover(key AppKey) *ZoneFailoverHint {
+	if r.zones == nil {
+		return nil
+	}
+	r.zones.lock.RLock()
+	defer r.zones.lock.RUnlock()
+	if z, ok := r.zones.apps[key]; ok {
+		return z.hint
+	}
+	return nil
+}
+
+// ZoneOutages 返回当前故障的可用区，按环境、应用、可用区排序，未开启检测时返回空
+func (r *Registry) ZoneOutages() []ZoneOutage {
+	if r.zones == nil {
+		return nil
+	}
+	r.zones.lock.RLock()
+	rs := make([]ZoneOutage, 0)
+	for _, z := range r.zones.apps {
+		for _, o := range z.degraded {
+			rs = append(rs, *o)
+		}
+	}
+	r.zones.lock.RUnlock()
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Env != rs[j].Env {
+			return rs[i].Env < rs[j].Env
+		}
+		if rs[i].AppId != rs[j].AppId {
+			return rs[i].AppId < rs[j].AppId
+		}
+		return rs[i].Zone < rs[j].Zone
+	})
+	return rs
+}
+
+// detectZoneOutages 按元数据 zone 对各应用的实例分组，曾经健康的可用区内没有 UP 且按时续约的实例时标记为故障，
+// 出现健康实例时恢复。状态变化时写入事件并更新应用版本，长轮询的客户端随即拿到新的提示
+func (r *Registry) detectZoneOutages(ctx context.Context) error {
+	d := r.zones
+	now := time.Now().UnixNano()
+	seen := make(map[AppKey]bool)
+	for _, app := range r.getAllApplications() {
+		key := getKey(app.appId, app.env)
+		seen[key] = true
+		counts := make(map[string]int)
+		healthy := make(map[string]bool)
+		for _, in := range app.GetAllInstances() {
+			zone := in.Metadata[MetadataZone]
+			if zone == "" {
+				continue
+			}
+			counts[zone]++
+			if in.Status == StatusUP && now-in.RenewTimestamp <= int64(d.cfg.StaleAfter) {
+				healthy[zone] = true
+			}
+		}
+		var changed []Event
+		d.lock.Lock()
+		z, ok := d.apps[key]
+		if !ok {
+			z = &appZones{degraded: make(map[string]*ZoneOutage)}
+			d.apps[key] = z
+		}
+		for zone := range z.healthy {
+			if !healthy[zone] && z.degraded[zone] == nil {
+				z.degraded[zone] = &ZoneOutage{Env: app.env, AppId: app.appId, Zone: zone, Since: now}
+				changed = append(changed, Event{Type: EventZoneDegraded, Env: app.env, AppId: app.appId, Zone: zone})
+			}
+		}
+		for zone, o := range z.degraded {
+			switch {
+			case healthy[zone]:
+				delete(z.degraded, zone)
+				changed = append(changed, Event{Type: EventZoneRecovered, Env: app.env, AppId: app.appId, Zone: zone})
+			case counts[zone] == 0 && now-o.Since > int64(d.cfg.Retain):
+				// 实例早已全部剔除，视为可用区下线
+				delete(z.degraded, zone)
+				delete(z.healthy, zone)
+			default:
+				o.Instances = counts[zone]
+			}
+		}
+		// 所有可用区同时故障时保留上次健康的可用区，直到有可用区恢复
+		if len(healthy) > 0 {
+			z.healthy = healthy
+		}
+		z.hint = zoneHint(z.degraded, healthy)
+		if len(z.healthy) == 0 && len(z.degraded) == 0 {
+			delete(d.apps, key)
+		}
+		d.lock.Unlock()
+		if len(changed) > 0 {
+			r.zoneChanged(ctx, app, changed)
+		}
+	}
+	d.lock.Lock()
+	for key := range d.apps {
+		if !seen[key] {
+			delete(d.apps, key)
+		}
+	}
+	d.lock.Unlock()
+	return nil
+}
+
+func zoneHint(degraded map[string]*ZoneOutage, healthy map[string]bool) *ZoneFailoverHint {
+	if len(degraded) == 0 {
+		return nil
+	}
+	h := &ZoneFailoverHint{}
+	for zone := range degraded {
+		h.Degraded = append(h.Degraded, zone)
+	}
+	for zone := range healthy {
+		h.Healthy = append(h.Healthy, zone)
+	}
+	sort.Strings(h.Degraded)
+	sort.Strings(h.Healthy)
+	return h
+}
+
+// zoneChanged 记录可用区状态变化，并更新应用版本使客户端重新拉取
+func (r *Registry) zoneChanged(ctx context.Context, app *Application, events []Event) {
+	app.lock.Lock()
+	app.upLatestTimestamp(time.Now().UnixNano())
+	app.lock.Unlock()
+	for _, e := range events {
+		r.events.append(e)
+		if e.Type == EventZoneRecovered {
+			logf(ctx, "zone %s of %s recovered", e.Zone, getKey(e.AppId, e.Env))
+			continue
+		}
+		logf(ctx, "zone %s of %s degraded", e.Zone, getKey(e.AppId, e.Env))
+		r.raiseAlert(Alert{
+			Name:    AlertZoneOutage,
+			Summary: fmt.Sprintf("all instances of %s in zone %s are unhealthy", getKey(e.AppId, e.Env), e.Zone),
+			Labels:  map[string]string{"env": e.Env, "appid": e.AppId, "zone": e.Zone},
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package registry_center
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestZoneOutage(t *testing.T) {
+	sink := make(chanSink, 10)
+	r := NewRegistry(WithZoneOutageDetection(ZoneOutageConfig{Interval: time.Hour}), WithAlerts(AlertConfig{Sinks: []AlertSink{sink}}))
+	defer r.Close()
+	for i, zone := range []string{"a", "a", "b"} {
+		host := zone + strconv.Itoa(i)
+		r.Register(NewInstance(&RequestRegister{Env: "test", AppId: "com.xx.app", Hostname: host, Addrs: []string{"http://" + host + ":80"},
+			Status: StatusUP, Metadata: map[string]string{MetadataZone: zone}}), time.Now().UnixNano())
+	}
+	ctx := context.Background()
+	r.detectZoneOutages(ctx)
+	if outages := r.ZoneOutages(); len(outages) != 0 {
+		t.Fatalf("unexpected outages %+v", outages)
+	}
+
+	// 可用区 a 的实例同时停止续约
+	app, _ := r.getApplication("com.xx.app", "test")
+	app.lock.Lock()
+	for _, in := range app.instances {
+		if in.Metadata[MetadataZone] == "a" {
+			in.RenewTimestamp -= int64(time.Minute)
+		}
+	}
+	app.lock.Unlock()
+	before, _ := r.Fetch("test", "com.xx.app", StatusUP, 0)
+	seq := r.LastEventSeq()
+	r.detectZoneOutages(ctx)
+	outages := r.ZoneOutages()
+	if len(outages) != 1 || outages[0].Zone != "a" || outages[0].Instances != 2 {
+		t.Fatalf("zone a should be degraded: %+v", outages)
+	}
+	events, _ := r.Replay(seq, 0)
+	if len(events) != 1 || events[0].Type != EventZoneDegraded || events[0].Zone != "a" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	select {
+	case a := <-sink:
+		if a.Name != AlertZoneOutage || a.Labels["zone"] != "a" {
+			t.Fatalf("unexpected alert %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("alert not sent")
+	}
+	// 应用版本更新，增量拉取的客户端能拿到提示
+	data, err := r.Fetch("test", "com.xx.app", StatusUP, before.LatestTimestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &ZoneFailoverHint{Degraded: []string{"a"}, Healthy: []string{"b"}}
+	if !reflect.DeepEqual(data.ZoneFailover, want) {
+		t.Fatalf("unexpected hint %+v", data.ZoneFailover)
+	}
+	decoded, err := UnmarshalFetchData(MarshalFetchData(data))
+	if err != nil || !reflect.DeepEqual(decoded.ZoneFailover, want) {
+		t.Fatalf("hint lost in protobuf: %+v %v", decoded.ZoneFailover, err)
+	}
+
+	// 任一实例恢复续约后可用区恢复
+	r.Renew("test", "com.xx.app", "a0")
+	r.detectZoneOutages(ctx)
+	if outages := r.ZoneOutages(); len(outages) != 0 {
+		t.Fatalf("zone a should recover: %+v", outages)
+	}
+	if data, _ := r.Fetch("test", "com.xx.app", StatusUP, 0); data.ZoneFailover != nil {
+		t.Fatalf("hint should be cleared: %+v", data.ZoneFailover)
+	}
+
+	// 可用区 b 的实例全部被剔除
+	r.Cancel("test", "com.xx.app", "b2", time.Now().UnixNano())
+	r.detectZoneOutages(ctx)
+	outages = r.ZoneOutages()
+	if len(outages) != 1 || outages[0].Zone != "b" || outages[0].Instances != 0 {
+		t.Fatalf("zone b should be degraded: %+v", outages)
+	}
+	r.zones.cfg.Retain = time.Nanosecond
+	r.detectZoneOutages(ctx)
+	if outages := r.ZoneOutages(); len(outages) != 0 {
+		t.Fatalf("removed zone should be forgotten: %+v", outages)
+	}
+}